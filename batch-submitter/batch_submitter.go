@@ -6,14 +6,15 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/proposer"
 	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/sequencer"
-	tss "github.com/mantlenetworkio/mantle/batch-submitter/tss-client"
 	bsscore "github.com/mantlenetworkio/mantle/bss-core"
 	"github.com/mantlenetworkio/mantle/bss-core/dial"
 	"github.com/mantlenetworkio/mantle/bss-core/metrics"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 
 	"github.com/getsentry/sentry-go"
@@ -72,41 +73,46 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 
 		log.Root().SetHandler(log.LvlFilterHandler(logLevel, logHandler))
 
-		// Parse sequencer private key and CTC contract address.
-		sequencerPrivKey, ctcAddress, err := bsscore.ParseWalletPrivKeyAndContractAddr(
-			"Sequencer", cfg.Mnemonic, cfg.SequencerHDPath,
-			cfg.SequencerPrivateKey, cfg.CTCAddress,
-		)
+		// Parse the CTC and SCC contract addresses.
+		ctcAddress, err := bsscore.ParseAddress(cfg.CTCAddress)
 		if err != nil {
 			return err
 		}
-
-		// Parse proposer private key and SCC contract address.
-		proposerPrivKey, sccAddress, err := bsscore.ParseWalletPrivKeyAndContractAddr(
-			"Proposer", cfg.Mnemonic, cfg.ProposerHDPath,
-			cfg.ProposerPrivateKey, cfg.SCCAddress,
-		)
+		sccAddress, err := bsscore.ParseAddress(cfg.SCCAddress)
 		if err != nil {
 			return err
 		}
 
-		// Connect to L1 and L2 providers. Perform these last since they are the
-		// most expensive.
-		l1Client, err := dial.L1EthClientWithTimeout(ctx, cfg.L1EthRpc, cfg.DisableHTTP2)
+		// Create the sequencer and proposer signers.
+		sequencerSignerCfg := cfg.sequencerSignerConfig()
+		sequencerSigner, err := signer.NewSigner(ctx, sequencerSignerCfg)
+		if err != nil {
+			return err
+		}
+		proposerSignerCfg := cfg.proposerSignerConfig()
+		proposerSigner, err := signer.NewSigner(ctx, proposerSignerCfg)
 		if err != nil {
 			return err
 		}
+		if err := ValidateSigners(sequencerSigner, proposerSigner); err != nil {
+			return err
+		}
+		log.Info("Configured signers",
+			"sequencer_signer", sequencerSignerCfg.Type, "sequencer_address", sequencerSigner.Address(),
+			"proposer_signer", proposerSignerCfg.Type, "proposer_address", proposerSigner.Address())
 
-		l2Client, err := DialL2EthClientWithTimeout(ctx, cfg.L2EthRpc, cfg.DisableHTTP2)
+		// Connect to L1 and L2 providers. Perform these last since they are the
+		// most expensive.
+		l1RPCClient, err := dial.L1RPCClientWithTimeout(ctx, cfg.L1EthRpc, cfg.DisableHTTP2)
 		if err != nil {
 			return err
 		}
+		l1Client := ethclient.NewClient(l1RPCClient)
 
-		tssClient, err := tss.NewClient(cfg.TssClientUrl, cfg.JwtSecret)
+		l2Client, err := DialL2EthClientWithTimeout(ctx, cfg.L2EthRpc, cfg.DisableHTTP2)
 		if err != nil {
 			return err
 		}
-		log.Info("Configured tss client", "url", cfg.TssClientUrl)
 
 		if cfg.MetricsServerEnable {
 			go metrics.RunServer(cfg.MetricsHostname, cfg.MetricsPort)
@@ -122,27 +128,32 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 			ReceiptQueryInterval:      time.Second,
 			NumConfirmations:          cfg.NumConfirmations,
 			SafeAbortNonceTooLowCount: cfg.SafeAbortNonceTooLowCount,
+			MaxPendingTxs:             cfg.MaxPendingTxs,
 		}
 
 		var services []*bsscore.Service
 		if cfg.RunTxBatchSubmitter {
+			batchTxJournal, err := cfg.openJournal("Sequencer")
+			if err != nil {
+				return err
+			}
+			defer batchTxJournal.Close()
+
 			batchTxDriver, err := sequencer.NewDriver(sequencer.Config{
-				Name:                "Sequencer",
-				L1Client:            l1Client,
-				L2Client:            l2Client,
-				BlockOffset:         cfg.BlockOffset,
-				DaUpgradeBlock:      cfg.DaUpgradeBlock,
-				DAAddr:              common.Address(common.HexToAddress(cfg.DAAddress)),
-				CTCAddr:             ctcAddress,
-				ChainID:             chainID,
-				PrivKey:             sequencerPrivKey,
-				EnableSequencerHsm:  cfg.EnableSequencerHsm,
-				SequencerHsmAddress: cfg.SequencerHsmAddress,
-				SequencerHsmAPIName: cfg.SequencerHsmAPIName,
-				SequencerHsmCreden:  cfg.SequencerHsmCreden,
-				BatchType:           sequencer.BatchTypeFromString(cfg.SequencerBatchType),
-				MaxRollupTxn:        cfg.MaxRollupTxn,
-				MinRollupTxn:        cfg.MinRollupTxn,
+				Name:           "Sequencer",
+				L1Client:       l1Client,
+				L1RPCClient:    l1RPCClient,
+				L2Client:       l2Client,
+				BlockOffset:    cfg.BlockOffset,
+				DaUpgradeBlock: cfg.DaUpgradeBlock,
+				DAAddr:         common.Address(common.HexToAddress(cfg.DAAddress)),
+				CTCAddr:        ctcAddress,
+				ChainID:        chainID,
+				Signer:         sequencerSigner,
+				BatchType:      sequencer.BatchTypeFromString(cfg.SequencerBatchType),
+				MaxRollupTxn:   cfg.MaxRollupTxn,
+				MinRollupTxn:   cfg.MinRollupTxn,
+				Fees:           cfg.feeConfig(),
 			})
 			if err != nil {
 				return err
@@ -155,10 +166,25 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 				ClearPendingTx:  cfg.ClearPendingTxs,
 				L1Client:        l1Client,
 				TxManagerConfig: txManagerConfig,
+				Fees:            batchTxDriver.Fees(),
+				Journal:         batchTxJournal,
+				ReorgDepth:      cfg.ReorgDepth,
 			}))
 		}
 
 		if cfg.RunStateBatchSubmitter {
+			batchStateJournal, err := cfg.openJournal("Proposer")
+			if err != nil {
+				return err
+			}
+			defer batchStateJournal.Close()
+
+			tssClient, err := cfg.newTssClient(ctx, l1Client, sccAddress)
+			if err != nil {
+				return err
+			}
+			defer tssClient.Close()
+
 			batchStateDriver, err := proposer.NewDriver(proposer.Config{
 				Name:                        "Proposer",
 				L1Client:                    l1Client,
@@ -171,17 +197,15 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 				CTCAddr:                     ctcAddress,
 				FPRollupAddr:                common.HexToAddress(cfg.FPRollupAddress),
 				ChainID:                     chainID,
-				PrivKey:                     proposerPrivKey,
+				Signer:                      proposerSigner,
 				SccRollback:                 cfg.EnableSccRollback,
 				RollupTimeout:               cfg.RollupTimeout,
 				PollInterval:                cfg.PollInterval,
 				FinalityConfirmations:       cfg.FinalityConfirmations,
-				EnableProposerHsm:           cfg.EnableProposerHsm,
-				ProposerHsmAddress:          cfg.ProposerHsmAddress,
-				ProposerHsmCreden:           cfg.ProposerHsmCreden,
-				ProposerHsmAPIName:          cfg.ProposerHsmAPIName,
 				AllowL2AutoRollback:         cfg.AllowL2AutoRollback,
 				MinTimeoutStateRootElements: cfg.MinTimeoutStateRootElements,
+				Fees:                        cfg.feeConfig(),
+				TssPrefetch:                 cfg.TssPrefetch,
 			})
 			if err != nil {
 				return err
@@ -194,6 +218,9 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 				ClearPendingTx:  cfg.ClearPendingTxs,
 				L1Client:        l1Client,
 				TxManagerConfig: txManagerConfig,
+				Fees:            batchStateDriver.Fees(),
+				Journal:         batchStateJournal,
+				ReorgDepth:      cfg.ReorgDepth,
 			}))
 		}
 
@@ -204,9 +231,6 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 		}
 
 		log.Info("Starting batch submitter")
-		log.Info("CloudHsm", "enable_sequencer", cfg.EnableSequencerHsm, "enable_proposer",
-			cfg.EnableProposerHsm, "sequencer_address", cfg.SequencerHsmAddress, "proposer_address",
-			cfg.ProposerHsmAddress)
 
 		if err := batchSubmitter.Start(); err != nil {
 			return err
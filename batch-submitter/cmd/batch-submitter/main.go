@@ -37,6 +37,15 @@ func main() {
 		"that synchronize L2 state to L1 contracts"
 
 	app.Action = batchsubmitter.Main(GitVersion)
+	app.Commands = []cli.Command{
+		batchsubmitter.JournalCommand,
+	}
+
+	// The journal command works offline and does not take the service flags,
+	// some of which are required.
+	if len(os.Args) > 1 && os.Args[1] == batchsubmitter.JournalCommand.Name {
+		app.Flags = nil
+	}
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Crit("Application failed", "message", err)
@@ -2,12 +2,17 @@ package batchsubmitter
 
 import (
 	"errors"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/urfave/cli"
 
 	"github.com/mantlenetworkio/mantle/batch-submitter/flags"
+	"github.com/mantlenetworkio/mantle/bss-core/drivers"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 )
 
 var (
@@ -42,6 +47,15 @@ var (
 	// with which to configure Sentry logging.
 	ErrSentryDSNNotSet = errors.New("sentry-dsn must be set if use-sentry " +
 		"is true")
+
+	// ErrInvalidFeeTipPercentile signals that the tip percentile is not a
+	// percentile.
+	ErrInvalidFeeTipPercentile = errors.New("fee-tip-percentile must be " +
+		"between 0 and 100")
+
+	// ErrInvalidFeePriceBump signals that replacement transactions would
+	// not raise their fees enough for nodes to accept them.
+	ErrInvalidFeePriceBump = errors.New("fee-price-bump must be at least 10")
 )
 
 type Config struct {
@@ -60,7 +74,7 @@ type Config struct {
 	// L2EthRpc is the HTTP provider URL for L1.
 	L2EthRpc string
 
-	// Tss manager client Url
+	// Tss manager client Urls, comma separated
 	TssClientUrl string
 
 	// jwt access token
@@ -104,6 +118,10 @@ type Config struct {
 	// appending new batches.
 	NumConfirmations uint64
 
+	// ReorgDepth is the number of L1 blocks during which confirmed batches
+	// are watched for reorgs, disabled if zero.
+	ReorgDepth uint64
+
 	// SafeAbortNonceTooLowCount is the number of ErrNonceTooLowObservations
 	// required to give up on a tx at a particular nonce without receiving
 	// confirmation.
@@ -195,6 +213,61 @@ type Config struct {
 	// DisableHTTP2 disables HTTP2 support.
 	DisableHTTP2 bool
 
+	// MaxPendingTxs is the number of batch transactions each submitter keeps
+	// in flight at consecutive nonces.
+	MaxPendingTxs uint64
+
+	// JournalDir is the directory of the submitter journals, journaling is
+	// disabled if empty.
+	JournalDir string
+
+	// TssMaxRetries is the number of times a failed tss signature request is
+	// retried.
+	TssMaxRetries uint64
+
+	// TssRetryBackoff is the delay before retrying a tss signature request
+	// that failed on every tss manager.
+	TssRetryBackoff time.Duration
+
+	// TssHealthCheckInterval is the interval between health checks of the
+	// tss managers, disabled if zero.
+	TssHealthCheckInterval time.Duration
+
+	// TssPrefetch requests the tss signature of the next state root batch
+	// while the current one confirms.
+	TssPrefetch bool
+
+	// FeeTipPercentile is the percentile of recent priority fees offered as
+	// the tip of batch transactions.
+	FeeTipPercentile float64
+
+	// FeeHistoryBlocks is the number of recent blocks sampled for priority
+	// fees.
+	FeeHistoryBlocks uint64
+
+	// FeeForecastBlocks is the number of full blocks ahead the base fee is
+	// forecast when setting the fee cap.
+	FeeForecastBlocks uint64
+
+	// FeePriceBump is the percentage by which replacement transactions raise
+	// their fees.
+	FeePriceBump uint64
+
+	// FeeMaxTxFeeGwei caps the fee of a single batch transaction, unlimited
+	// if zero.
+	FeeMaxTxFeeGwei uint64
+
+	// FeeMaxHourlySpendGwei caps the fees each submitter spends over an hour,
+	// unlimited if zero.
+	FeeMaxHourlySpendGwei uint64
+
+	// FeeCheapBaseFeeGwei is the base fee at or below which batches are
+	// submitted without waiting.
+	FeeCheapBaseFeeGwei uint64
+
+	// FeeMaxWait is the maximum time a batch waits for cheaper gas.
+	FeeMaxWait time.Duration
+
 	EnableSccRollback bool
 
 	// use cloud-hsm to sign for proposer
@@ -215,6 +288,14 @@ type Config struct {
 
 	SequencerHsmCreden string
 
+	// SequencerSigner is the signer of the sequencer, it takes precedence
+	// over the private key, mnemonic and hsm options.
+	SequencerSigner signer.Config
+
+	// ProposerSigner is the signer of the proposer, it takes precedence
+	// over the private key, mnemonic and hsm options.
+	ProposerSigner signer.Config
+
 	RollupClientHttp string
 
 	// batch submitter rollback
@@ -251,6 +332,7 @@ func NewConfig(ctx *cli.Context) (Config, error) {
 		RollupTimeout:             ctx.GlobalDuration(flags.RollupTimeoutFlag.Name),
 		PollInterval:              ctx.GlobalDuration(flags.PollIntervalFlag.Name),
 		NumConfirmations:          ctx.GlobalUint64(flags.NumConfirmationsFlag.Name),
+		ReorgDepth:                ctx.GlobalUint64(flags.ReorgDepthFlag.Name),
 		SafeAbortNonceTooLowCount: ctx.GlobalUint64(flags.SafeAbortNonceTooLowCountFlag.Name),
 		ResubmissionTimeout:       ctx.GlobalDuration(flags.ResubmissionTimeoutFlag.Name),
 		FinalityConfirmations:     ctx.GlobalUint64(flags.FinalityConfirmationsFlag.Name),
@@ -275,6 +357,20 @@ func NewConfig(ctx *cli.Context) (Config, error) {
 		MetricsHostname:             ctx.GlobalString(flags.MetricsHostnameFlag.Name),
 		MetricsPort:                 ctx.GlobalUint64(flags.MetricsPortFlag.Name),
 		DisableHTTP2:                ctx.GlobalBool(flags.HTTP2DisableFlag.Name),
+		MaxPendingTxs:               ctx.GlobalUint64(flags.MaxPendingTxsFlag.Name),
+		JournalDir:                  ctx.GlobalString(flags.JournalDirFlag.Name),
+		TssMaxRetries:               ctx.GlobalUint64(flags.TssMaxRetriesFlag.Name),
+		TssRetryBackoff:             ctx.GlobalDuration(flags.TssRetryBackoffFlag.Name),
+		TssHealthCheckInterval:      ctx.GlobalDuration(flags.TssHealthCheckIntervalFlag.Name),
+		TssPrefetch:                 ctx.GlobalBool(flags.TssPrefetchFlag.Name),
+		FeeTipPercentile:            ctx.GlobalFloat64(flags.FeeTipPercentileFlag.Name),
+		FeeHistoryBlocks:            ctx.GlobalUint64(flags.FeeHistoryBlocksFlag.Name),
+		FeeForecastBlocks:           ctx.GlobalUint64(flags.FeeForecastBlocksFlag.Name),
+		FeePriceBump:                ctx.GlobalUint64(flags.FeePriceBumpFlag.Name),
+		FeeMaxTxFeeGwei:             ctx.GlobalUint64(flags.FeeMaxTxFeeFlag.Name),
+		FeeMaxHourlySpendGwei:       ctx.GlobalUint64(flags.FeeMaxHourlySpendFlag.Name),
+		FeeCheapBaseFeeGwei:         ctx.GlobalUint64(flags.FeeCheapBaseFeeFlag.Name),
+		FeeMaxWait:                  ctx.GlobalDuration(flags.FeeMaxWaitFlag.Name),
 		EnableSccRollback:           ctx.GlobalBool(flags.SccRollbackFlag.Name),
 		EnableSequencerHsm:          ctx.GlobalBool(flags.EnableSequencerHsmFlag.Name),
 		SequencerHsmAddress:         ctx.GlobalString(flags.SequencerHsmAddressFlag.Name),
@@ -289,6 +385,8 @@ func NewConfig(ctx *cli.Context) (Config, error) {
 		MaxRollupTxn:                ctx.GlobalUint64(flags.MaxRollupTxnFlag.Name),
 		MinRollupTxn:                ctx.GlobalUint64(flags.MinRollupTxnFlag.Name),
 		MinTimeoutStateRootElements: ctx.GlobalUint64(flags.MinTimeoutStateRootElementsFlag.Name),
+		SequencerSigner:             signer.ReadPrefixedCLIConfig(ctx, "sequencer."),
+		ProposerSigner:              signer.ReadPrefixedCLIConfig(ctx, "proposer."),
 	}
 
 	err := ValidateConfig(&cfg)
@@ -309,30 +407,45 @@ func ValidateConfig(cfg *Config) error {
 	}
 
 	// Enforce that either sequencer-private-key or mnemonic + sequencer-hd-path
-	// is enabled, but not both or neither.
+	// is enabled, but not both or neither, unless a sequencer signer is
+	// configured.
+	usingSequencerSigner := cfg.SequencerSigner.Enabled()
 	usingSequencerPrivateKey := cfg.SequencerPrivateKey != ""
 	usingSequencerHDPath := cfg.Mnemonic != "" && cfg.SequencerHDPath != ""
-	if usingSequencerPrivateKey == usingSequencerHDPath {
+	if usingSequencerSigner {
+		if err := cfg.SequencerSigner.Check(); err != nil {
+			return err
+		}
+	} else if usingSequencerPrivateKey == usingSequencerHDPath {
 		return ErrSequencerPrivKeyOrMnemonic
 	}
 
 	// Enforce that either proposer-private-key or mnemonic + proposer-hd-path
-	// is enabled, but not both or neither.
+	// is enabled, but not both or neither, unless a proposer signer is
+	// configured.
+	usingProposerSigner := cfg.ProposerSigner.Enabled()
 	usingProposerPrivateKey := cfg.ProposerPrivateKey != ""
 	usingProposerHDPath := cfg.Mnemonic != "" && cfg.ProposerHDPath != ""
-	if usingProposerPrivateKey == usingProposerHDPath {
+	if usingProposerSigner {
+		if err := cfg.ProposerSigner.Check(); err != nil {
+			return err
+		}
+	} else if usingProposerPrivateKey == usingProposerHDPath {
 		return ErrProposerPrivKeyOrMnemonic
 	}
 
 	// If mnemonic is used, the sequencer-hd-path and proposer-hd-path must
 	// differ to avoid resuing the same wallet for both.
-	if cfg.Mnemonic != "" && cfg.SequencerHDPath == cfg.ProposerHDPath {
+	if !usingSequencerSigner && !usingProposerSigner &&
+		cfg.Mnemonic != "" && cfg.SequencerHDPath == cfg.ProposerHDPath {
 		return ErrSameSequencerAndProposerHDPath
 	}
 
 	// If private keys are used, ensure the keys are different to avoid resuing
-	// the same wallet for both.
-	if usingSequencerPrivateKey && usingProposerPrivateKey &&
+	// the same wallet for both. Signers are checked by ValidateSigners once
+	// they are constructed.
+	if !usingSequencerSigner && !usingProposerSigner &&
+		usingSequencerPrivateKey && usingProposerPrivateKey &&
 		cfg.SequencerPrivateKey == cfg.ProposerPrivateKey {
 
 		return ErrSameSequencerAndProposerPrivKey
@@ -349,5 +462,87 @@ func ValidateConfig(cfg *Config) error {
 		return ErrSentryDSNNotSet
 	}
 
+	// Zero values select the fee strategy defaults.
+	if cfg.FeeTipPercentile < 0 || cfg.FeeTipPercentile > 100 {
+		return ErrInvalidFeeTipPercentile
+	}
+	if cfg.FeePriceBump != 0 && cfg.FeePriceBump < txmgr.DefaultPriceBump {
+		return ErrInvalidFeePriceBump
+	}
+
 	return nil
 }
+
+// feeConfig returns the configuration of the fee strategy pricing the batch
+// transactions of each submitter.
+func (c *Config) feeConfig() *txmgr.FeeConfig {
+	cfg := &txmgr.FeeConfig{
+		TipPercentile:  c.FeeTipPercentile,
+		HistoryBlocks:  c.FeeHistoryBlocks,
+		ForecastBlocks: c.FeeForecastBlocks,
+		MinTipCap:      drivers.FallbackGasTipCap,
+		PriceBump:      c.FeePriceBump,
+		MaxWait:        c.FeeMaxWait,
+	}
+	if c.FeeMaxTxFeeGwei != 0 {
+		cfg.MaxTxFee = gweiToWei(c.FeeMaxTxFeeGwei)
+	}
+	if c.FeeMaxHourlySpendGwei != 0 {
+		cfg.MaxHourlySpend = gweiToWei(c.FeeMaxHourlySpendGwei)
+	}
+	if c.FeeCheapBaseFeeGwei != 0 {
+		cfg.CheapBaseFee = gweiToWei(c.FeeCheapBaseFeeGwei)
+	}
+	return cfg
+}
+
+// ValidateSigners ensures the sequencer and proposer signers do not sign with
+// the same wallet.
+func ValidateSigners(sequencer, proposer signer.Signer) error {
+	if sequencer.Address() == proposer.Address() {
+		return ErrSameSequencerAndProposerPrivKey
+	}
+	return nil
+}
+
+func gweiToWei(gwei uint64) *big.Int {
+	return new(big.Int).Mul(
+		new(big.Int).SetUint64(gwei), big.NewInt(params.GWei),
+	)
+}
+
+// sequencerSignerConfig returns the signer of the sequencer, the legacy hsm,
+// private key and mnemonic options are used if no signer is configured.
+func (c *Config) sequencerSignerConfig() signer.Config {
+	return legacySignerConfig(c.SequencerSigner, c.EnableSequencerHsm,
+		c.SequencerHsmAPIName, c.SequencerHsmAddress, c.SequencerHsmCreden,
+		c.SequencerPrivateKey, c.Mnemonic, c.SequencerHDPath)
+}
+
+// proposerSignerConfig returns the signer of the proposer, the legacy hsm,
+// private key and mnemonic options are used if no signer is configured.
+func (c *Config) proposerSignerConfig() signer.Config {
+	return legacySignerConfig(c.ProposerSigner, c.EnableProposerHsm,
+		c.ProposerHsmAPIName, c.ProposerHsmAddress, c.ProposerHsmCreden,
+		c.ProposerPrivateKey, c.Mnemonic, c.ProposerHDPath)
+}
+
+func legacySignerConfig(cfg signer.Config, enableHsm bool, hsmAPIName,
+	hsmAddress, hsmCreden, privKey, mnemonic, hdPath string) signer.Config {
+
+	switch {
+	case cfg.Enabled():
+		return cfg
+	case enableHsm:
+		return signer.Config{
+			Type:           signer.TypeKMS,
+			KMSKeyName:     hsmAPIName,
+			Address:        hsmAddress,
+			KMSCredentials: hsmCreden,
+		}
+	case privKey != "":
+		return signer.Config{Type: signer.TypeLocal, PrivateKey: privKey}
+	default:
+		return signer.Config{Type: signer.TypeLocal, Mnemonic: mnemonic, HDPath: hdPath}
+	}
+}
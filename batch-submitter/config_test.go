@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	batchsubmitter "github.com/mantlenetworkio/mantle/batch-submitter"
+	bsscore "github.com/mantlenetworkio/mantle/bss-core"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/stretchr/testify/require"
 )
 
@@ -145,6 +148,36 @@ var validateConfigTests = []struct {
 		},
 		expErr: batchsubmitter.ErrSentryDSNNotSet,
 	},
+	{
+		name: "fee tip percentile above 100",
+		cfg: batchsubmitter.Config{
+			LogLevel:            "info",
+			SequencerPrivateKey: "sequencer-privkey",
+			ProposerPrivateKey:  "proposer-privkey",
+			FeeTipPercentile:    101,
+		},
+		expErr: batchsubmitter.ErrInvalidFeeTipPercentile,
+	},
+	{
+		name: "fee price bump below 10",
+		cfg: batchsubmitter.Config{
+			LogLevel:            "info",
+			SequencerPrivateKey: "sequencer-privkey",
+			ProposerPrivateKey:  "proposer-privkey",
+			FeePriceBump:        5,
+		},
+		expErr: batchsubmitter.ErrInvalidFeePriceBump,
+	},
+	{
+		name: "zstd batches",
+		cfg: batchsubmitter.Config{
+			LogLevel:            "info",
+			SequencerPrivateKey: "sequencer-privkey",
+			ProposerPrivateKey:  "proposer-privkey",
+			SequencerBatchType:  "zstd",
+		},
+		expErr: batchsubmitter.ErrInvalidBatchType,
+	},
 	// Valid configs
 	{
 		name: "valid config with privkeys and no sentry",
@@ -192,6 +225,40 @@ var validateConfigTests = []struct {
 		},
 		expErr: nil,
 	},
+	{
+		name: "valid config with signers and no privkeys",
+		cfg: batchsubmitter.Config{
+			LogLevel: "info",
+			SequencerSigner: signer.Config{
+				Type:     signer.TypeRemote,
+				Endpoint: "http://localhost:8550",
+				Address:  "0x01",
+			},
+			ProposerSigner: signer.Config{
+				Type:         signer.TypeKeystore,
+				KeystorePath: "proposer.json",
+			},
+		},
+		expErr: nil,
+	},
+	{
+		name: "sequencer signer unknown type",
+		cfg: batchsubmitter.Config{
+			LogLevel:           "info",
+			SequencerSigner:    signer.Config{Type: "ledger"},
+			ProposerPrivateKey: "proposer-privkey",
+		},
+		expErr: fmt.Errorf("%w: %q", signer.ErrUnknownType, "ledger"),
+	},
+	{
+		name: "proposer signer without privkey",
+		cfg: batchsubmitter.Config{
+			LogLevel:            "info",
+			SequencerPrivateKey: "sequencer-privkey",
+			ProposerSigner:      signer.Config{Type: signer.TypeLocal},
+		},
+		expErr: bsscore.ErrCannotGetPrivateKey,
+	},
 }
 
 // TestValidateConfig asserts the behavior of ValidateConfig by testing expected
@@ -204,3 +271,18 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+// TestValidateSigners asserts that the sequencer and proposer signers must sign
+// with different wallets, whatever their configuration.
+func TestValidateSigners(t *testing.T) {
+	sequencerKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	proposerKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	sequencer := signer.NewLocalSigner(sequencerKey)
+	proposer := signer.NewLocalSigner(proposerKey)
+	require.NoError(t, batchsubmitter.ValidateSigners(sequencer, proposer))
+	require.Equal(t, batchsubmitter.ErrSameSequencerAndProposerPrivKey,
+		batchsubmitter.ValidateSigners(sequencer, signer.NewLocalSigner(sequencerKey)))
+}
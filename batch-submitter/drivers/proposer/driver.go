@@ -3,7 +3,6 @@ package proposer
 import (
 	"bytes"
 	"context"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -17,25 +16,21 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/ctc"
 	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/scc"
 	tssClient "github.com/mantlenetworkio/mantle/batch-submitter/tss-client"
-	bsscore "github.com/mantlenetworkio/mantle/bss-core"
 	"github.com/mantlenetworkio/mantle/bss-core/drivers"
 	"github.com/mantlenetworkio/mantle/bss-core/metrics"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 	fpbindings "github.com/mantlenetworkio/mantle/fraud-proof/bindings"
 	rollupTypes "github.com/mantlenetworkio/mantle/fraud-proof/rollup/types"
 	l2types "github.com/mantlenetworkio/mantle/l2geth/core/types"
 	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
 	tss_types "github.com/mantlenetworkio/mantle/tss/common"
-
-	kms "cloud.google.com/go/kms/apiv1"
-	"google.golang.org/api/option"
 )
 
 // stateRootSize is the size in bytes of a state root.
@@ -44,6 +39,10 @@ const stateRootSize = 32
 // block number buffer for dtl to sync data
 const blockBuffer = 2
 
+// prefetchTimeout bounds fetching the state roots of the next batch to request
+// its tss signature ahead of time.
+const prefetchTimeout = time.Minute
+
 var bigOne = new(big.Int).SetUint64(1) //nolint:unused
 
 type Config struct {
@@ -58,17 +57,21 @@ type Config struct {
 	CTCAddr                     common.Address
 	FPRollupAddr                common.Address
 	ChainID                     *big.Int
-	PrivKey                     *ecdsa.PrivateKey
+	Signer                      signer.Signer
 	SccRollback                 bool
 	RollupTimeout               time.Duration
 	PollInterval                time.Duration
 	FinalityConfirmations       uint64
-	EnableProposerHsm           bool
-	ProposerHsmCreden           string
-	ProposerHsmAddress          string
-	ProposerHsmAPIName          string
 	AllowL2AutoRollback         bool
 	MinTimeoutStateRootElements uint64
+
+	// Fees configures the fee strategy pricing batch transactions, the tip
+	// suggested by the backend is used if nil.
+	Fees *txmgr.FeeConfig
+
+	// TssPrefetch requests the tss signature of the next batch while the
+	// current one confirms.
+	TssPrefetch bool
 }
 
 type Driver struct {
@@ -86,6 +89,8 @@ type Driver struct {
 	lastCommitTime       time.Time
 	lastStart            *big.Int
 	metrics              *metrics.Base
+	fees                 *txmgr.FeeStrategy
+	prefetching          sync.Mutex
 }
 
 func NewDriver(cfg Config) (*Driver, error) {
@@ -151,13 +156,16 @@ func NewDriver(cfg Config) (*Driver, error) {
 		cfg.FPRollupAddr, parsedFP, cfg.L1Client, cfg.L1Client, cfg.L1Client,
 	)
 
-	var walletAddr common.Address
-	if cfg.EnableProposerHsm {
-		walletAddr = common.HexToAddress(cfg.ProposerHsmAddress)
-		log.Info("use proposer hsm", "walletaddr", walletAddr)
-	} else {
-		walletAddr = crypto.PubkeyToAddress(cfg.PrivKey.PublicKey)
-		log.Info("not use proposer hsm", "walletaddr", walletAddr)
+	walletAddr := cfg.Signer.Address()
+	log.Info("proposer signer", "walletaddr", walletAddr)
+
+	driverMetrics := metrics.NewBase("batch_submitter", cfg.Name)
+
+	var fees *txmgr.FeeStrategy
+	if cfg.Fees != nil {
+		fees = txmgr.NewFeeStrategy(
+			cfg.Name, *cfg.Fees, cfg.L1Client, driverMetrics,
+		)
 	}
 
 	return &Driver{
@@ -173,7 +181,8 @@ func NewDriver(cfg Config) (*Driver, error) {
 		rollbackEndStateRoot: [stateRootSize]byte{},
 		once:                 sync.Once{},
 		lastStart:            big.NewInt(0),
-		metrics:              metrics.NewBase("batch_submitter", cfg.Name),
+		metrics:              driverMetrics,
+		fees:                 fees,
 	}, nil
 }
 
@@ -192,6 +201,12 @@ func (d *Driver) Metrics() metrics.Metrics {
 	return d.metrics
 }
 
+// Fees returns the fee strategy pricing batch transactions, or nil if the tip
+// suggested by the backend is used.
+func (d *Driver) Fees() *txmgr.FeeStrategy {
+	return d.fees
+}
+
 // ClearPendingTx a publishes a transaction at the next available nonce in order
 // to clear any transactions in the mempool left over from a prior running
 // instance of the batch submitter.
@@ -202,7 +217,7 @@ func (d *Driver) ClearPendingTx(
 ) error {
 
 	return drivers.ClearPendingTx(
-		d.cfg.Name, ctx, txMgr, l1Client, d.walletAddr, d.cfg.PrivKey,
+		d.cfg.Name, ctx, txMgr, l1Client, d.walletAddr, d.cfg.Signer,
 		d.cfg.ChainID,
 	)
 }
@@ -224,6 +239,18 @@ func (d *Driver) GetBatchBlockRange(
 	}
 	start.Add(start, blockOffset)
 
+	return d.GetBatchBlockRangeFrom(ctx, start)
+}
+
+// GetBatchBlockRangeFrom returns the start and end L2 block heights that need
+// to be processed after the batches ending at start, which may not have been
+// confirmed yet. Note that the end value is *exclusive*, therefore if the
+// returned values are identical nothing needs to be processed.
+func (d *Driver) GetBatchBlockRangeFrom(
+	ctx context.Context, start *big.Int) (*big.Int, *big.Int, error) {
+
+	blockOffset := new(big.Int).SetUint64(d.cfg.BlockOffset)
+
 	currentHeader, err := d.cfg.L1Client.HeaderByNumber(ctx, nil)
 	if err != nil {
 		return nil, nil, err
@@ -238,12 +265,23 @@ func (d *Driver) GetBatchBlockRange(
 		Context:     ctx,
 		BlockNumber: currentNumber,
 	})
+	if err != nil {
+		return nil, nil, err
+	}
 	end.Add(end, blockOffset)
 
 	if start.Cmp(end) > 0 {
 		return nil, nil, fmt.Errorf("invalid range, "+
 			"end(%v) < start(%v)", end, start)
 	}
+
+	// CraftBatchTx submits at most MaxStateRootElements+1 state roots, cap the
+	// range so that a batch crafted after this one starts where it ends.
+	maxEnd := new(big.Int).SetUint64(d.cfg.MaxStateRootElements + 1)
+	maxEnd.Add(maxEnd, start)
+	if end.Cmp(maxEnd) > 0 {
+		end = maxEnd
+	}
 	return start, end, nil
 }
 
@@ -304,35 +342,9 @@ func (d *Driver) CraftBatchTx(
 
 	log.Info(name+" batch constructed", "num_state_roots", len(stateRoots))
 
-	var opts *bind.TransactOpts
-	var err error
-	if d.cfg.EnableProposerHsm {
-		proBytes, err := hex.DecodeString(d.cfg.ProposerHsmCreden)
-		if err != nil {
-			return nil, err
-		}
-		apikey := option.WithCredentialsJSON(proBytes)
-		client, err := kms.NewKeyManagementClient(ctx, apikey)
-		if err != nil {
-			return nil, err
-		}
-		mk := &bsscore.ManagedKey{
-			KeyName:      d.cfg.ProposerHsmAPIName,
-			EthereumAddr: common.HexToAddress(d.cfg.ProposerHsmAddress),
-			Gclient:      client,
-		}
-		opts, err = mk.NewEthereumTransactorrWithChainID(ctx, d.cfg.ChainID)
-		if err != nil {
-			return nil, err
-		}
-		log.Info("proposer", "enable-hsm", true)
-	} else {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			d.cfg.PrivKey, d.cfg.ChainID,
-		)
-		if err != nil {
-			return nil, err
-		}
+	opts, err := signer.NewTransactOpts(ctx, d.cfg.Signer, d.cfg.ChainID)
+	if err != nil {
+		return nil, err
 	}
 	opts.Context = ctx
 	opts.Nonce = nonce
@@ -341,11 +353,14 @@ func (d *Driver) CraftBatchTx(
 	blockOffset := new(big.Int).SetUint64(d.cfg.BlockOffset)
 	offsetStartsAtIndex := new(big.Int).Sub(start, blockOffset)
 	// Assembly data request tss node signature
-	tssResponse, err := d.RequestTssSignature(0, start, offsetStartsAtIndex, "", stateRoots)
+	tssResponse, err := d.RequestTssSignature(ctx, 0, start, offsetStartsAtIndex, "", stateRoots)
 	if err != nil {
 		log.Error(name+" get tss manager signature fail", "err", err)
 		return nil, err
 	}
+	if d.cfg.TssPrefetch && !tssResponse.RollBack {
+		d.prefetchTssSignature(end)
+	}
 	log.Info(name+" append log", "stateRoots size ", len(stateRoots), "offsetStartsAtIndex", offsetStartsAtIndex, "signature", hex.EncodeToString(tssResponse.Signature), "rollback", tssResponse.RollBack)
 	log.Info(name+" signature ", "len", len(tssResponse.Signature))
 	var tx *types.Transaction
@@ -453,7 +468,7 @@ func (d *Driver) CraftBatchTx(
 						return nil, err
 					}
 					if challengeContext.Completed && !alreadyRollback && bytes.Equal(challenger[:], winner[:]) {
-						tssResponse, err = d.RequestTssSignature(1, startInboxSize, offsetStartsAtIndex, challengeContext.ChallengeAddress.String(), nil)
+						tssResponse, err = d.RequestTssSignature(ctx, 1, startInboxSize, offsetStartsAtIndex, challengeContext.ChallengeAddress.String(), nil)
 						if err != nil {
 							return nil, err
 						}
@@ -525,26 +540,7 @@ func (d *Driver) UpdateGasPrice(
 	var finalTx *types.Transaction
 	var err error
 
-	var opts *bind.TransactOpts
-	if d.cfg.EnableProposerHsm {
-		proBytes, err := hex.DecodeString(d.cfg.ProposerHsmCreden)
-		apikey := option.WithCredentialsJSON(proBytes)
-		client, err := kms.NewKeyManagementClient(ctx, apikey)
-		if err != nil {
-			return nil, err
-		}
-		mk := &bsscore.ManagedKey{
-			KeyName:      d.cfg.ProposerHsmAPIName,
-			EthereumAddr: common.HexToAddress(d.cfg.ProposerHsmAddress),
-			Gclient:      client,
-		}
-		opts, err = mk.NewEthereumTransactorrWithChainID(ctx, d.cfg.ChainID)
-		log.Info("proposer", "enable-hsm", true)
-	} else {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			d.cfg.PrivKey, d.cfg.ChainID,
-		)
-	}
+	opts, err := signer.NewTransactOpts(ctx, d.cfg.Signer, d.cfg.ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -552,6 +548,15 @@ func (d *Driver) UpdateGasPrice(
 	opts.Nonce = new(big.Int).SetUint64(tx.Nonce())
 	opts.NoSend = true
 
+	if d.fees != nil {
+		fees, err := d.fees.SuggestFees(ctx, tx.Nonce())
+		if err != nil {
+			return nil, err
+		}
+		opts.GasTipCap = fees.GasTipCap
+		opts.GasFeeCap = fees.GasFeeCap
+	}
+
 	if len(d.cfg.FPRollupAddr.Bytes()) != 0 {
 		// ##### FRAUD-PROOF modify #####
 		log.Info("get RawTransact from Fraud Proof")
@@ -563,6 +568,11 @@ func (d *Driver) UpdateGasPrice(
 	}
 	switch {
 	case err == nil:
+		if d.fees != nil {
+			if err := d.fees.Approve(finalTx); err != nil {
+				return nil, err
+			}
+		}
 		return finalTx, nil
 
 	// If the transaction failed because the backend does not support
@@ -655,7 +665,7 @@ func (d *Driver) FraudProofAppendStateBatch(opts *bind.TransactOpts, batch [][32
 		opts, assertion.VmHash, assertion.InboxSize, batch, shouldStartAtElement, signature)
 }
 
-func (d *Driver) RequestTssSignature(requestType uint64, start, offsetStartsAtIndex *big.Int, challenge string, stateRoots [][stateRootSize]byte) (*tssClient.TssResponse, error) {
+func (d *Driver) RequestTssSignature(ctx context.Context, requestType uint64, start, offsetStartsAtIndex *big.Int, challenge string, stateRoots [][stateRootSize]byte) (*tssClient.TssResponse, error) {
 	var tssResponse tssClient.TssResponse
 	tssReqParams := tss_types.SignStateRequest{
 		Type:                requestType,
@@ -664,7 +674,7 @@ func (d *Driver) RequestTssSignature(requestType uint64, start, offsetStartsAtIn
 		Challenge:           challenge,
 		StateRoots:          stateRoots,
 	}
-	tssReponseBytes, err := d.cfg.TssClient.GetSignStateBatch(tssReqParams)
+	tssReponseBytes, err := d.cfg.TssClient.GetSignStateBatch(ctx, tssReqParams)
 	if err != nil {
 		log.Error("get tss manager signature fail", "err", err)
 		return nil, err
@@ -676,3 +686,58 @@ func (d *Driver) RequestTssSignature(requestType uint64, start, offsetStartsAtIn
 	}
 	return &tssResponse, nil
 }
+
+// prefetchTssSignature requests the tss signature of the batch starting at
+// start in the background, while the batch ending there confirms. At most one
+// prefetch runs at a time.
+func (d *Driver) prefetchTssSignature(start *big.Int) {
+	if !d.prefetching.TryLock() {
+		return
+	}
+	start = new(big.Int).Set(start)
+
+	go func() {
+		defer d.prefetching.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+		defer cancel()
+
+		if err := d.prefetchBatch(ctx, start); err != nil {
+			log.Warn(d.cfg.Name+" unable to prefetch tss signature",
+				"start", start, "err", err)
+		}
+	}()
+}
+
+// prefetchBatch requests the tss signature of the batch starting at start if
+// it is full, a shorter batch grows with the L2 chain before it is crafted and
+// would be signed again.
+func (d *Driver) prefetchBatch(ctx context.Context, start *big.Int) error {
+	start, end, err := d.GetBatchBlockRangeFrom(ctx, start)
+	if err != nil {
+		return err
+	}
+	size := new(big.Int).Sub(end, start)
+	if size.Uint64() < d.cfg.MaxStateRootElements+1 {
+		return nil
+	}
+
+	stateRoots := make([][stateRootSize]byte, 0, size.Uint64())
+	for i := new(big.Int).Set(start); i.Cmp(end) < 0; i.Add(i, bigOne) {
+		header, err := d.cfg.L2Client.HeaderByNumber(ctx, i)
+		if err != nil {
+			return err
+		}
+		stateRoots = append(stateRoots, header.Root)
+	}
+
+	blockOffset := new(big.Int).SetUint64(d.cfg.BlockOffset)
+	d.cfg.TssClient.Prefetch(tss_types.SignStateRequest{
+		StartBlock:          start,
+		OffsetStartsAtIndex: new(big.Int).Sub(start, blockOffset),
+		StateRoots:          stateRoots,
+	})
+	log.Info(d.cfg.Name+" prefetching tss signature", "start", start,
+		"end", end)
+	return nil
+}
@@ -0,0 +1,206 @@
+package sequencer_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/sequencer"
+	l2common "github.com/mantlenetworkio/mantle/l2geth/common"
+	l2types "github.com/mantlenetworkio/mantle/l2geth/core/types"
+)
+
+// testContract is a deployed contract whose ABI and bytecode were loaded from
+// the testdata/contracts artifacts, copied from the mainnet deployments.
+type testContract struct {
+	*bind.BoundContract
+	abi     abi.ABI
+	address common.Address
+}
+
+// ctcTestEnv holds a CanonicalTransactionChain deployed on a simulated L1,
+// along with the AddressManager and ChainStorageContainer it resolves.
+type ctcTestEnv struct {
+	backend *backends.SimulatedBackend
+	opts    *bind.TransactOpts
+	ctc     *testContract
+}
+
+func deployTestContract(
+	t *testing.T,
+	backend *backends.SimulatedBackend,
+	opts *bind.TransactOpts,
+	name string,
+	args ...interface{},
+) *testContract {
+
+	data, err := os.ReadFile(filepath.Join("testdata", "contracts", name+".json"))
+	require.Nil(t, err)
+
+	var artifact struct {
+		ABI      json.RawMessage `json:"abi"`
+		Bytecode hexutil.Bytes   `json:"bytecode"`
+	}
+	require.Nil(t, json.Unmarshal(data, &artifact))
+
+	parsed, err := abi.JSON(strings.NewReader(string(artifact.ABI)))
+	require.Nil(t, err)
+
+	address, _, contract, err := bind.DeployContract(
+		opts, parsed, artifact.Bytecode, backend, args...,
+	)
+	require.Nil(t, err)
+	backend.Commit()
+
+	return &testContract{
+		BoundContract: contract,
+		abi:           parsed,
+		address:       address,
+	}
+}
+
+func newCTCTestEnv(t *testing.T) *ctcTestEnv {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	opts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	require.Nil(t, err)
+
+	balance := new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		opts.From: {Balance: balance},
+	}, 30_000_000)
+	t.Cleanup(func() { backend.Close() })
+
+	addressManager := deployTestContract(
+		t, backend, opts, "Lib_AddressManager",
+	)
+	batches := deployTestContract(
+		t, backend, opts, "ChainStorageContainer",
+		addressManager.address, "CanonicalTransactionChain",
+	)
+	ctc := deployTestContract(
+		t, backend, opts, "CanonicalTransactionChain",
+		addressManager.address, big.NewInt(30_000_000), big.NewInt(32),
+		big.NewInt(60_000),
+	)
+
+	for name, address := range map[string]common.Address{
+		"CanonicalTransactionChain":         ctc.address,
+		"ChainStorageContainer-CTC-batches": batches.address,
+		"BVM_Sequencer":                     opts.From,
+	} {
+		_, err := addressManager.Transact(opts, "setAddress", name, address)
+		require.Nil(t, err)
+	}
+	backend.Commit()
+
+	return &ctcTestEnv{
+		backend: backend,
+		opts:    opts,
+		ctc:     ctc,
+	}
+}
+
+func (e *ctcTestEnv) enqueue(t *testing.T) {
+	_, err := e.ctc.Transact(
+		e.opts, "enqueue", common.Address{0x42}, big.NewInt(200_000),
+		[]byte{0x01},
+	)
+	require.Nil(t, err)
+	e.backend.Commit()
+}
+
+func (e *ctcTestEnv) call(t *testing.T, method string) *big.Int {
+	var out []interface{}
+	err := e.ctc.Call(&bind.CallOpts{}, &out, method)
+	require.Nil(t, err)
+	return out[0].(*big.Int)
+}
+
+// appendSequencerBatch sends the calldata the driver crafts for the encoded
+// params and returns the status of the receipt.
+func (e *ctcTestEnv) appendSequencerBatch(t *testing.T, encoded []byte) uint64 {
+	calldata := append(
+		e.ctc.abi.Methods["appendSequencerBatch"].ID, encoded...,
+	)
+	opts := *e.opts
+	opts.GasLimit = 5_000_000
+	tx, err := e.ctc.RawTransact(&opts, calldata)
+	require.Nil(t, err)
+	e.backend.Commit()
+
+	receipt, err := e.backend.TransactionReceipt(context.Background(), tx.Hash())
+	require.Nil(t, err)
+	return receipt.Status
+}
+
+// TestAppendSequencerBatchCTC asserts that batches of every encoding are
+// accepted by the CanonicalTransactionChain and that the queue index it keeps
+// only counts the queue transactions of the batch, leaving out the marker
+// context of compressed batches.
+func TestAppendSequencerBatchCTC(t *testing.T) {
+	batchTypes := []sequencer.BatchType{
+		sequencer.BatchTypeLegacy,
+		sequencer.BatchTypeZlib,
+	}
+
+	for _, batchType := range batchTypes {
+		t.Run(batchType.String(), func(t *testing.T) {
+			env := newCTCTestEnv(t)
+			env.enqueue(t)
+
+			target := l2common.HexToAddress("0x42")
+			var txs []*sequencer.CachedTx
+			for i := uint64(0); i < 2; i++ {
+				tx := l2types.NewTransaction(
+					i, target, big.NewInt(1), 21_000, big.NewInt(1),
+					[]byte("batch"),
+				)
+				txs = append(txs, sequencer.NewCachedTx(tx))
+			}
+
+			// One sequencer tx followed by the queue tx, then another
+			// sequencer tx so the last context gives the batch timestamp.
+			params := sequencer.AppendSequencerBatchParams{
+				ShouldStartAtElement:  0,
+				TotalElementsToAppend: 3,
+				Contexts: []sequencer.BatchContext{
+					{
+						NumSequencedTxs:       1,
+						NumSubsequentQueueTxs: 1,
+						Timestamp:             100,
+						BlockNumber:           10,
+					},
+					{
+						NumSequencedTxs: 1,
+						Timestamp:       101,
+						BlockNumber:     11,
+					},
+				},
+				Txs: txs,
+			}
+			encoded, err := params.Serialize(batchType, nil, nil)
+			require.Nil(t, err)
+
+			status := env.appendSequencerBatch(t, encoded)
+			require.Equal(t, uint64(1), status)
+			require.Equal(t, uint64(3), env.call(t, "getTotalElements").Uint64())
+			require.Equal(t, uint64(1), env.call(t, "getNextQueueIndex").Uint64())
+			require.Equal(t, uint64(101), env.call(t, "getLastTimestamp").Uint64())
+			require.Equal(t, uint64(11), env.call(t, "getLastBlockNumber").Uint64())
+		})
+	}
+}
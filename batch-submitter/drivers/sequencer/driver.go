@@ -2,8 +2,6 @@ package sequencer
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"encoding/hex"
 	"math/big"
 	"strings"
 
@@ -12,20 +10,17 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/ctc"
 	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/da"
-	bsscore "github.com/mantlenetworkio/mantle/bss-core"
 	"github.com/mantlenetworkio/mantle/bss-core/drivers"
 	"github.com/mantlenetworkio/mantle/bss-core/metrics"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
-
-	kms "cloud.google.com/go/kms/apiv1"
-	"google.golang.org/api/option"
 )
 
 const (
@@ -35,22 +30,27 @@ const (
 var bigOne = new(big.Int).SetUint64(1)
 
 type Config struct {
-	Name                string
-	L1Client            *ethclient.Client
-	L2Client            *l2ethclient.Client
-	BlockOffset         uint64
-	CTCAddr             common.Address
-	DaUpgradeBlock      uint64
-	DAAddr              common.Address
-	ChainID             *big.Int
-	PrivKey             *ecdsa.PrivateKey
-	EnableSequencerHsm  bool
-	SequencerHsmAddress string
-	SequencerHsmAPIName string
-	SequencerHsmCreden  string
-	BatchType           BatchType
-	MaxRollupTxn        uint64
-	MinRollupTxn        uint64
+	Name     string
+	L1Client *ethclient.Client
+
+	// L1RPCClient is the connection L1Client wraps, used to estimate the gas
+	// of batches against the pending state, which ethclient does not allow.
+	L1RPCClient *rpc.Client
+
+	L2Client       *l2ethclient.Client
+	BlockOffset    uint64
+	CTCAddr        common.Address
+	DaUpgradeBlock uint64
+	DAAddr         common.Address
+	ChainID        *big.Int
+	Signer         signer.Signer
+	BatchType      BatchType
+	MaxRollupTxn   uint64
+	MinRollupTxn   uint64
+
+	// Fees configures the fee strategy pricing batch transactions, the tip
+	// suggested by the backend is used if nil.
+	Fees *txmgr.FeeConfig
 }
 
 type Driver struct {
@@ -63,6 +63,7 @@ type Driver struct {
 	ctcABI           *abi.ABI
 	DaABI            *abi.ABI
 	metrics          *Metrics
+	fees             *txmgr.FeeStrategy
 }
 
 func NewDriver(cfg Config) (*Driver, error) {
@@ -119,13 +120,14 @@ func NewDriver(cfg Config) (*Driver, error) {
 		cfg.L1Client,
 	)
 
-	var walletAddr common.Address
-	if cfg.EnableSequencerHsm {
-		walletAddr = common.HexToAddress(cfg.SequencerHsmAddress)
-		log.Info("use sequencer hsm", "walletaddr", walletAddr)
-	} else {
-		walletAddr = crypto.PubkeyToAddress(cfg.PrivKey.PublicKey)
-		log.Info("not use sequencer hsm", "walletaddr", walletAddr)
+	walletAddr := cfg.Signer.Address()
+	log.Info("sequencer signer", "walletaddr", walletAddr)
+
+	metrics := NewMetrics(cfg.Name)
+
+	var fees *txmgr.FeeStrategy
+	if cfg.Fees != nil {
+		fees = txmgr.NewFeeStrategy(cfg.Name, *cfg.Fees, cfg.L1Client, metrics)
 	}
 
 	return &Driver{
@@ -137,7 +139,8 @@ func NewDriver(cfg Config) (*Driver, error) {
 		walletAddr:       walletAddr,
 		ctcABI:           ctcABI,
 		DaABI:            daABI,
-		metrics:          NewMetrics(cfg.Name),
+		metrics:          metrics,
+		fees:             fees,
 	}, nil
 }
 
@@ -156,6 +159,12 @@ func (d *Driver) Metrics() metrics.Metrics {
 	return d.metrics
 }
 
+// Fees returns the fee strategy pricing batch transactions, or nil if the tip
+// suggested by the backend is used.
+func (d *Driver) Fees() *txmgr.FeeStrategy {
+	return d.fees
+}
+
 // ClearPendingTx a publishes a transaction at the next available nonce in order
 // to clear any transactions in the mempool left over from a prior running
 // instance of the batch submitter.
@@ -166,7 +175,7 @@ func (d *Driver) ClearPendingTx(
 ) error {
 
 	return drivers.ClearPendingTx(
-		d.cfg.Name, ctx, txMgr, l1Client, d.walletAddr, d.cfg.PrivKey,
+		d.cfg.Name, ctx, txMgr, l1Client, d.walletAddr, d.cfg.Signer,
 		d.cfg.ChainID,
 	)
 }
@@ -188,6 +197,16 @@ func (d *Driver) GetBatchBlockRange(
 	}
 	start.Add(start, blockOffset)
 
+	return d.GetBatchBlockRangeFrom(ctx, start)
+}
+
+// GetBatchBlockRangeFrom returns the start and end L2 block heights that need
+// to be processed after the batches ending at start, which may not have been
+// confirmed yet. Note that the end value is *exclusive*, therefore if the
+// returned values are identical nothing needs to be processed.
+func (d *Driver) GetBatchBlockRangeFrom(
+	ctx context.Context, start *big.Int) (*big.Int, *big.Int, error) {
+
 	latestHeader, err := d.cfg.L2Client.HeaderByNumber(ctx, nil)
 	if err != nil {
 		return nil, nil, err
@@ -273,39 +292,21 @@ func (d *Driver) CraftBatchTx(
 			"final_size", len(calldata),
 			"batch_type", d.cfg.BatchType)
 
-		var opts *bind.TransactOpts
-		if d.cfg.EnableSequencerHsm {
-			seqBytes, err := hex.DecodeString(d.cfg.SequencerHsmCreden)
-			apikey := option.WithCredentialsJSON(seqBytes)
-			client, err := kms.NewKeyManagementClient(ctx, apikey)
-			if err != nil {
-				log.Info("sequencer", "create signer error", err.Error())
-				return nil, err
-			}
-			mk := &bsscore.ManagedKey{
-				KeyName:      d.cfg.SequencerHsmAPIName,
-				EthereumAddr: common.HexToAddress(d.cfg.SequencerHsmAddress),
-				Gclient:      client,
-			}
-			opts, err = mk.NewEthereumTransactorrWithChainID(ctx, d.cfg.ChainID)
-			if err != nil {
-				log.Info("sequencer", "create signer error", err.Error())
-				return nil, err
-			}
-		} else {
-			opts, err = bind.NewKeyedTransactorWithChainID(
-				d.cfg.PrivKey, d.cfg.ChainID,
-			)
-			if err != nil {
-				log.Info("sequencer", "create signer error", err.Error())
-				return nil, err
-			}
+		opts, err := signer.NewTransactOpts(ctx, d.cfg.Signer, d.cfg.ChainID)
+		if err != nil {
+			log.Info("sequencer", "create signer error", err.Error())
+			return nil, err
 		}
+		// Set the gas limit so that RawTransact does not estimate it
+		// against the latest block, which a pipelined batch reverts on.
+		gasLimit, err := d.estimateGas(ctx, calldata, nil, nil)
 		if err != nil {
 			return nil, err
 		}
+
 		opts.Context = ctx
 		opts.Nonce = nonce
+		opts.GasLimit = gasLimit
 		opts.NoSend = true
 
 		tx, err := d.rawCtcContract.RawTransact(opts, calldata)
@@ -340,6 +341,82 @@ func (d *Driver) UpdateGasPrice(
 	tx *types.Transaction,
 ) (*types.Transaction, error) {
 
+	gasTipCap, gasFeeCap, err := d.suggestFees(ctx, tx.Nonce())
+	if err != nil {
+		return nil, err
+	}
+
+	gasLimit, err := d.estimateGas(ctx, tx.Data(), gasTipCap, gasFeeCap)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := signer.NewTransactOpts(ctx, d.cfg.Signer, d.cfg.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	opts.Context = ctx
+	opts.Nonce = new(big.Int).SetUint64(tx.Nonce())
+	opts.GasTipCap = gasTipCap
+	opts.GasFeeCap = gasFeeCap
+	opts.GasLimit = gasLimit
+	opts.NoSend = true
+
+	finalTx, err := d.rawCtcContract.RawTransact(opts, tx.Data())
+	if err != nil {
+		return nil, err
+	}
+	if d.fees != nil {
+		if err := d.fees.Approve(finalTx); err != nil {
+			return nil, err
+		}
+	}
+	return finalTx, nil
+}
+
+// estimateGas returns the gas limit of a batch transaction with the given
+// calldata, estimated on top of the pending state since the batches in flight
+// before it must be applied for it to succeed.
+func (d *Driver) estimateGas(
+	ctx context.Context,
+	calldata []byte,
+	gasTipCap, gasFeeCap *big.Int,
+) (uint64, error) {
+
+	// The estimated gas limits performed by RawTransact fail semi-regularly
+	// with out of gas exceptions. To remedy this we perform the gas limit
+	// estimation here and add a buffer to account for any network
+	// variability.
+	gasLimit, err := drivers.EstimateGasPending(
+		ctx, d.cfg.L1RPCClient, ethereum.CallMsg{
+			From:      d.walletAddr,
+			To:        &d.cfg.CTCAddr,
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
+			Data:      calldata,
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return 6 * gasLimit / 5, nil // add 20% buffer to gas limit
+}
+
+// suggestFees returns the gas tip and fee caps of the batch transaction at
+// nonce, sampled by the fee strategy if configured.
+func (d *Driver) suggestFees(
+	ctx context.Context,
+	nonce uint64,
+) (*big.Int, *big.Int, error) {
+
+	if d.fees != nil {
+		fees, err := d.fees.SuggestFees(ctx, nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		return fees.GasTipCap, fees.GasFeeCap, nil
+	}
+
 	gasTipCap, err := d.cfg.L1Client.SuggestGasTipCap(ctx)
 	if err != nil {
 		// If the transaction failed because the backend does not support
@@ -349,7 +426,7 @@ func (d *Driver) UpdateGasPrice(
 		// degraded mode of operation. This also applies to our test
 		// environments, as hardhat doesn't support the query either.
 		if !drivers.IsMaxPriorityFeePerGasNotFoundError(err) {
-			return nil, err
+			return nil, nil, err
 		}
 
 		log.Warn(d.cfg.Name + " eth_maxPriorityFeePerGas is unsupported " +
@@ -359,58 +436,10 @@ func (d *Driver) UpdateGasPrice(
 
 	header, err := d.cfg.L1Client.HeaderByNumber(ctx, nil)
 	if err != nil {
-		return nil, err
-	}
-	gasFeeCap := txmgr.CalcGasFeeCap(header.BaseFee, gasTipCap)
-
-	// The estimated gas limits performed by RawTransact fail semi-regularly
-	// with out of gas exceptions. To remedy this we extract the internal calls
-	// to perform gas price/gas limit estimation here and add a buffer to
-	// account for any network variability.
-	gasLimit, err := d.cfg.L1Client.EstimateGas(ctx, ethereum.CallMsg{
-		From:      d.walletAddr,
-		To:        &d.cfg.CTCAddr,
-		GasPrice:  nil,
-		GasTipCap: gasTipCap,
-		GasFeeCap: gasFeeCap,
-		Value:     nil,
-		Data:      tx.Data(),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	var opts *bind.TransactOpts
-	if d.cfg.EnableSequencerHsm {
-		seqBytes, err := hex.DecodeString(d.cfg.SequencerHsmCreden)
-		apikey := option.WithCredentialsJSON(seqBytes)
-		client, err := kms.NewKeyManagementClient(ctx, apikey)
-		if err != nil {
-			return nil, err
-		}
-		mk := &bsscore.ManagedKey{
-			KeyName:      d.cfg.SequencerHsmAPIName,
-			EthereumAddr: common.HexToAddress(d.cfg.SequencerHsmAddress),
-			Gclient:      client,
-		}
-		opts, err = mk.NewEthereumTransactorrWithChainID(ctx, d.cfg.ChainID)
-		log.Info("sequencer", "enable-hsm", true)
-	} else {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			d.cfg.PrivKey, d.cfg.ChainID,
-		)
-	}
-	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	opts.Context = ctx
-	opts.Nonce = new(big.Int).SetUint64(tx.Nonce())
-	opts.GasTipCap = gasTipCap
-	opts.GasFeeCap = gasFeeCap
-	opts.GasLimit = 6 * gasLimit / 5 // add 20% buffer to gas limit
-	opts.NoSend = true
 
-	return d.rawCtcContract.RawTransact(opts, tx.Data())
+	return gasTipCap, txmgr.CalcGasFeeCap(header.BaseFee, gasTipCap), nil
 }
 
 // SendTransaction injects a signed transaction into the pending pool for
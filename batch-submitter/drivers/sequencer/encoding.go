@@ -188,7 +188,7 @@ type AppendSequencerBatchParams struct {
 	Txs []*CachedTx
 }
 
-// WriteNoTxn encodes the AppendSequencerBatchParams using the following format:
+// Write encodes the AppendSequencerBatchParams using the following format:
 //   - should_start_at_element:        5 bytes
 //   - total_elements_to_append:       3 bytes
 //   - num_contexts:                   3 bytes
@@ -210,10 +210,64 @@ type AppendSequencerBatchParams struct {
 //
 // Note that writing to a bytes.Buffer cannot
 // error, so errors are ignored here
+func (p *AppendSequencerBatchParams) Write(
+	w *bytes.Buffer,
+	batchType BatchType,
+) error {
+	// There must be contexts if there are transactions
+	if len(p.Contexts) == 0 && len(p.Txs) != 0 {
+		return ErrMalformedBatch
+	}
+
+	// There must be transactions if there are contexts
+	if len(p.Txs) == 0 && len(p.Contexts) != 0 {
+		return ErrMalformedBatch
+	}
+
+	p.writeHeader(w, batchType.MarkerContext())
+
+	// Define a writer of the length-prefixed txs, compressing them if the
+	// batch type requires it.
+	var cw io.WriteCloser
+	switch batchType {
+	case BatchTypeLegacy:
+		cw = nopWriteCloser{w}
+	case BatchTypeZlib:
+		cw = zlib.NewWriter(w)
+	default:
+		return fmt.Errorf("unknown batch type %d", batchType)
+	}
+	for _, tx := range p.Txs {
+		if err := writeUint64(cw, uint64(tx.Size()), TxLenSize); err != nil {
+			return err
+		}
+		if _, err := cw.Write(tx.RawTx()); err != nil {
+			return err
+		}
+	}
+	return cw.Close()
+}
+
+// WriteNoTxn encodes the AppendSequencerBatchParams in the same format as
+// Write, but omits the transactions, which are made available through the
+// data availability layer instead.
+//
+// Note that writing to a bytes.Buffer cannot
+// error, so errors are ignored here
 func (p *AppendSequencerBatchParams) WriteNoTxn(
 	w *bytes.Buffer,
 	batchType BatchType,
 ) error {
+	p.writeHeader(w, batchType.MarkerContext())
+	return nil
+}
+
+// writeHeader writes every field preceding the transactions, with the marker
+// context, if any, prepended to the contexts.
+func (p *AppendSequencerBatchParams) writeHeader(
+	w *bytes.Buffer,
+	markerContext *BatchContext,
+) {
 	_ = writeUint64(w, p.ShouldStartAtElement, 5)
 	_ = writeUint64(w, p.TotalElementsToAppend, 3)
 
@@ -221,7 +275,6 @@ func (p *AppendSequencerBatchParams) WriteNoTxn(
 	// when it is a typed batch
 	contexts := make([]BatchContext, 0, len(p.Contexts)+1)
 	// Add the marker context, if any, for non-legacy encodings.
-	markerContext := batchType.MarkerContext()
 	if markerContext != nil {
 		contexts = append(contexts, *markerContext)
 	}
@@ -232,18 +285,27 @@ func (p *AppendSequencerBatchParams) WriteNoTxn(
 	for _, context := range contexts {
 		context.Write(w)
 	}
-	return nil
 }
 
 // Serialize performs the same encoding as Write, but returns the resulting
-// bytes slice.
+// bytes slice. Batches of L2 blocks at or after upgradeBlock omit their
+// transactions as in WriteNoTxn, if both are non-nil.
 func (p *AppendSequencerBatchParams) Serialize(
 	batchType BatchType,
 	l2BlockNumber *big.Int,
 	upgradeBlock *big.Int,
 ) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := p.WriteNoTxn(&buf, batchType); err != nil {
+	if l2BlockNumber != nil && upgradeBlock != nil &&
+		l2BlockNumber.Cmp(upgradeBlock) >= 0 {
+
+		if err := p.WriteNoTxn(&buf, batchType); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if err := p.Write(&buf, batchType); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -324,18 +386,22 @@ func (p *AppendSequencerBatchParams) Read(r io.Reader) error {
 		// the batch is well formed.
 		if err == io.EOF {
 			if len(p.Contexts) == 0 && len(p.Txs) != 0 {
+				_ = closeReader()
 				return ErrMalformedBatch
 			}
 			if len(p.Txs) == 0 && len(p.Contexts) != 0 {
+				_ = closeReader()
 				return ErrMalformedBatch
 			}
 			return closeReader()
 		} else if err != nil {
+			_ = closeReader()
 			return err
 		}
 
 		tx := new(l2types.Transaction)
 		if err := tx.DecodeRLP(l2rlp.NewStream(r, txLen)); err != nil {
+			_ = closeReader()
 			return err
 		}
 
@@ -368,9 +434,16 @@ func readUint64(r io.Reader, val *uint64, n uint) error {
 	if n > 8 {
 		return fmt.Errorf("bytes shift out of range")
 	}
-	if _, err := r.Read(buf[8-n:]); err != nil {
+	if _, err := io.ReadFull(r, buf[8-n:]); err != nil {
 		return err
 	}
 	*val = byteOrder.Uint64(buf[:])
 	return nil
 }
+
+// nopWriteCloser adds a no-op Close to an io.Writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -279,3 +279,63 @@ func TestReadUint64(t *testing.T) {
 
 	require.Error(t, readUint64(bytes.NewBuffer(be), &x, 9))
 }
+
+// decodeTestParams decodes the params of the named spec test vector.
+func decodeTestParams(t testing.TB, name string) sequencer.AppendSequencerBatchParams {
+	for _, test := range appendSequencerBatchParamTests.Tests {
+		if test.Name != name {
+			continue
+		}
+
+		rawBytes, err := hex.DecodeString(test.HexEncoding)
+		require.Nil(t, err)
+
+		var params sequencer.AppendSequencerBatchParams
+		require.Nil(t, params.Read(bytes.NewReader(rawBytes)))
+		return params
+	}
+
+	t.Fatalf("unknown test vector %s", name)
+	return sequencer.AppendSequencerBatchParams{}
+}
+
+// FuzzAppendSequencerBatchParamsRead asserts that reading arbitrary bytes
+// never panics, and that any batch that is read successfully serializes back
+// to a batch that reads the same.
+func FuzzAppendSequencerBatchParamsRead(f *testing.F) {
+	batchTypes := []sequencer.BatchType{
+		sequencer.BatchTypeLegacy,
+		sequencer.BatchTypeZlib,
+	}
+
+	for _, test := range appendSequencerBatchParamTests.Tests {
+		rawBytes, err := hex.DecodeString(test.HexEncoding)
+		require.Nil(f, err)
+		f.Add(rawBytes)
+	}
+	params := decodeTestParams(f, "complex")
+	paramsBytes, err := params.Serialize(sequencer.BatchTypeZlib, nil, nil)
+	require.Nil(f, err)
+	f.Add(paramsBytes)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var params sequencer.AppendSequencerBatchParams
+		if err := params.Read(bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		for _, batchType := range batchTypes {
+			paramsBytes, err := params.Serialize(batchType, nil, nil)
+			require.Nil(t, err)
+
+			var decoded sequencer.AppendSequencerBatchParams
+			require.Nil(t, decoded.Read(bytes.NewReader(paramsBytes)))
+			require.Equal(t, params.ShouldStartAtElement,
+				decoded.ShouldStartAtElement)
+			require.Equal(t, params.TotalElementsToAppend,
+				decoded.TotalElementsToAppend)
+			require.Equal(t, params.Contexts, decoded.Contexts)
+			require.Equal(t, len(params.Txs), len(decoded.Txs))
+		}
+	})
+}
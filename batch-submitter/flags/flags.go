@@ -3,6 +3,8 @@ package flags
 import (
 	"time"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 	"github.com/urfave/cli"
 )
 
@@ -41,8 +43,9 @@ var (
 		EnvVar:   "L2_ETH_RPC",
 	}
 	TssClientUrl = cli.StringFlag{
-		Name:     "tss-client-url",
-		Usage:    "HTTP provider URL for tss",
+		Name: "tss-client-url",
+		Usage: "HTTP provider URLs of the tss managers, comma separated " +
+			"and tried in order",
 		Required: true,
 		EnvVar:   "TSS_CLIENT_RPC",
 	}
@@ -116,6 +119,14 @@ var (
 		Required: true,
 		EnvVar:   prefixEnvVar("NUM_CONFIRMATIONS"),
 	}
+	ReorgDepthFlag = cli.Uint64Flag{
+		Name: "reorg-depth",
+		Usage: "Number of L1 blocks during which confirmed batches are " +
+			"watched for reorgs and submitted again if reorged out. " +
+			"Reorg detection is disabled if zero.",
+		Value:  64,
+		EnvVar: prefixEnvVar("REORG_DEPTH"),
+	}
 	SafeAbortNonceTooLowCountFlag = cli.Uint64Flag{
 		Name: "safe-abort-nonce-too-low-count",
 		Usage: "Number of ErrNonceTooLow observations required to " +
@@ -258,6 +269,98 @@ var (
 		Usage:  "Whether or not to disable HTTP/2 support.",
 		EnvVar: prefixEnvVar("HTTP2_DISABLE"),
 	}
+	MaxPendingTxsFlag = cli.Uint64Flag{
+		Name: "max-pending-txs",
+		Usage: "Number of batch transactions each submitter keeps in " +
+			"flight at consecutive nonces",
+		Value:  1,
+		EnvVar: prefixEnvVar("MAX_PENDING_TXS"),
+	}
+	JournalDirFlag = cli.StringFlag{
+		Name: "journal-dir",
+		Usage: "Directory of the journals recording the batches of each " +
+			"submitter, which are resumed after a restart rather than " +
+			"cleared. Journaling is disabled if empty.",
+		EnvVar: prefixEnvVar("JOURNAL_DIR"),
+	}
+	FeeTipPercentileFlag = cli.Float64Flag{
+		Name: "fee-tip-percentile",
+		Usage: "Percentile of the priority fees paid in recent blocks " +
+			"offered as the tip of batch transactions",
+		Value:  txmgr.DefaultTipPercentile,
+		EnvVar: prefixEnvVar("FEE_TIP_PERCENTILE"),
+	}
+	FeeHistoryBlocksFlag = cli.Uint64Flag{
+		Name:   "fee-history-blocks",
+		Usage:  "Number of recent blocks sampled for priority fees",
+		Value:  txmgr.DefaultFeeHistoryBlocks,
+		EnvVar: prefixEnvVar("FEE_HISTORY_BLOCKS"),
+	}
+	FeeForecastBlocksFlag = cli.Uint64Flag{
+		Name: "fee-forecast-blocks",
+		Usage: "Number of full blocks ahead the base fee is forecast " +
+			"when setting the fee cap of batch transactions",
+		Value:  txmgr.DefaultForecastBlocks,
+		EnvVar: prefixEnvVar("FEE_FORECAST_BLOCKS"),
+	}
+	FeePriceBumpFlag = cli.Uint64Flag{
+		Name: "fee-price-bump",
+		Usage: "Percentage by which replacement transactions raise the " +
+			"fees of the transaction they replace, at least 10",
+		Value:  txmgr.DefaultPriceBump,
+		EnvVar: prefixEnvVar("FEE_PRICE_BUMP"),
+	}
+	FeeMaxTxFeeFlag = cli.Uint64Flag{
+		Name: "fee-max-tx-fee-gwei",
+		Usage: "Maximum fee in gwei a single batch transaction may " +
+			"cost, unlimited if zero",
+		EnvVar: prefixEnvVar("FEE_MAX_TX_FEE_GWEI"),
+	}
+	FeeMaxHourlySpendFlag = cli.Uint64Flag{
+		Name: "fee-max-hourly-spend-gwei",
+		Usage: "Maximum fees in gwei each submitter may spend over an " +
+			"hour, unlimited if zero",
+		EnvVar: prefixEnvVar("FEE_MAX_HOURLY_SPEND_GWEI"),
+	}
+	FeeCheapBaseFeeFlag = cli.Uint64Flag{
+		Name: "fee-cheap-base-fee-gwei",
+		Usage: "Base fee in gwei at or below which batches are submitted " +
+			"without waiting, see fee-max-wait",
+		EnvVar: prefixEnvVar("FEE_CHEAP_BASE_FEE_GWEI"),
+	}
+	FeeMaxWaitFlag = cli.DurationFlag{
+		Name: "fee-max-wait",
+		Usage: "Maximum time a batch waits for the base fee to fall to " +
+			"fee-cheap-base-fee-gwei, batches never wait if zero",
+		EnvVar: prefixEnvVar("FEE_MAX_WAIT"),
+	}
+	TssMaxRetriesFlag = cli.Uint64Flag{
+		Name: "tss-max-retries",
+		Usage: "Number of times a failed tss signature request is retried, " +
+			"failing over between the tss managers",
+		Value:  3,
+		EnvVar: prefixEnvVar("TSS_MAX_RETRIES"),
+	}
+	TssRetryBackoffFlag = cli.DurationFlag{
+		Name: "tss-retry-backoff",
+		Usage: "Delay before retrying a tss signature request that failed " +
+			"on every tss manager, doubled after each retry",
+		Value:  time.Second,
+		EnvVar: prefixEnvVar("TSS_RETRY_BACKOFF"),
+	}
+	TssHealthCheckIntervalFlag = cli.DurationFlag{
+		Name: "tss-health-check-interval",
+		Usage: "Interval between health checks of the tss managers, " +
+			"disabled if zero",
+		Value:  10 * time.Second,
+		EnvVar: prefixEnvVar("TSS_HEALTH_CHECK_INTERVAL"),
+	}
+	TssPrefetchFlag = cli.BoolFlag{
+		Name: "tss-prefetch",
+		Usage: "Request the tss signature of the next state root batch " +
+			"while the current one confirms",
+		EnvVar: prefixEnvVar("TSS_PREFETCH"),
+	}
 	SccRollbackFlag = cli.BoolFlag{
 		Name:   "EnableSccRollbackFlag",
 		Usage:  "Whether or not to enable scc rollback.",
@@ -391,6 +494,21 @@ var optionalFlags = []cli.Flag{
 	MetricsHostnameFlag,
 	MetricsPortFlag,
 	HTTP2DisableFlag,
+	MaxPendingTxsFlag,
+	JournalDirFlag,
+	ReorgDepthFlag,
+	TssMaxRetriesFlag,
+	TssRetryBackoffFlag,
+	TssHealthCheckIntervalFlag,
+	TssPrefetchFlag,
+	FeeTipPercentileFlag,
+	FeeHistoryBlocksFlag,
+	FeeForecastBlocksFlag,
+	FeePriceBumpFlag,
+	FeeMaxTxFeeFlag,
+	FeeMaxHourlySpendFlag,
+	FeeCheapBaseFeeFlag,
+	FeeMaxWaitFlag,
 	EnableProposerHsmFlag,
 	ProposerHsmAddressFlag,
 	ProposerHsmAPIName,
@@ -401,5 +519,12 @@ var optionalFlags = []cli.Flag{
 	SequencerHsmCreden,
 }
 
+// signerFlags configure the signers of the sequencer and the proposer, they
+// take precedence over the private key, mnemonic and hsm flags.
+var signerFlags = append(
+	signer.PrefixedCLIFlags("sequencer.", prefixEnvVar("SEQUENCER")),
+	signer.PrefixedCLIFlags("proposer.", prefixEnvVar("PROPOSER"))...,
+)
+
 // Flags contains the list of configuration options available to the binary.
-var Flags = append(requiredFlags, optionalFlags...)
+var Flags = append(append(requiredFlags, optionalFlags...), signerFlags...)
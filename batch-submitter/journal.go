@@ -0,0 +1,144 @@
+package batchsubmitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/mantlenetworkio/mantle/batch-submitter/flags"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
+)
+
+// journalSubmitters are the names of the submitters that keep a journal.
+var journalSubmitters = []string{"Sequencer", "Proposer"}
+
+// journalPath returns the path of the journal of the named submitter in dir.
+func journalPath(dir, name string) string {
+	return filepath.Join(dir, strings.ToLower(name)+".jsonl")
+}
+
+// openJournal opens the journal of the named submitter, nil is returned if
+// journaling is disabled.
+func (c *Config) openJournal(name string) (*journal.Journal, error) {
+	if c.JournalDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(c.JournalDir, 0755); err != nil {
+		return nil, err
+	}
+	return journal.Open(journalPath(c.JournalDir, name))
+}
+
+var (
+	journalSubmitterFlag = cli.StringFlag{
+		Name:  "submitter",
+		Usage: "Only show the journal of this submitter, sequencer or proposer",
+	}
+	journalPendingFlag = cli.BoolFlag{
+		Name:  "pending",
+		Usage: "Only show the batches that have not settled",
+	}
+	journalJSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the entries as JSON lines",
+	}
+)
+
+// JournalCommand is the subcommand inspecting the submitter journals.
+var JournalCommand = cli.Command{
+	Name:  "journal",
+	Usage: "Inspect the batches recorded in the submitter journals",
+	Flags: []cli.Flag{
+		flags.JournalDirFlag,
+		journalSubmitterFlag,
+		journalPendingFlag,
+		journalJSONFlag,
+	},
+	Action: inspectJournal,
+}
+
+// journalEntry is an entry of a submitter journal.
+type journalEntry struct {
+	Submitter string `json:"submitter"`
+	journal.Entry
+}
+
+func inspectJournal(ctx *cli.Context) error {
+	dir := ctx.String(flags.JournalDirFlag.Name)
+	if dir == "" {
+		return fmt.Errorf("%s must be set", flags.JournalDirFlag.Name)
+	}
+
+	submitters := journalSubmitters
+	if submitter := ctx.String(journalSubmitterFlag.Name); submitter != "" {
+		submitters = nil
+		for _, name := range journalSubmitters {
+			if strings.EqualFold(name, submitter) {
+				submitters = append(submitters, name)
+			}
+		}
+		if len(submitters) == 0 {
+			return fmt.Errorf("unknown submitter %s", submitter)
+		}
+	}
+
+	var entries []journalEntry
+	for _, name := range submitters {
+		journalEntries, err := journal.Read(journalPath(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		for _, entry := range journalEntries {
+			if ctx.Bool(journalPendingFlag.Name) && !entry.Status.IsPending() {
+				continue
+			}
+			entries = append(entries, journalEntry{
+				Submitter: name,
+				Entry:     entry,
+			})
+		}
+	}
+
+	if ctx.Bool(journalJSONFlag.Name) {
+		return writeJournalJSON(ctx.App.Writer, entries)
+	}
+	return writeJournalTable(ctx.App.Writer, entries)
+}
+
+func writeJournalJSON(w io.Writer, entries []journalEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJournalTable(w io.Writer, entries []journalEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUBMITTER\tNONCE\tSTATUS\tSTART\tEND\tTXS\tLATEST TX\tUPDATED\tERROR")
+	for _, entry := range entries {
+		latest := "-"
+		if entry.ConfirmedTx != nil {
+			latest = entry.ConfirmedTx.Hex()
+		} else if len(entry.TxHashes) > 0 {
+			latest = entry.TxHashes[len(entry.TxHashes)-1].Hex()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			entry.Submitter, entry.Nonce, entry.Status, entry.Start,
+			entry.End, len(entry.TxHashes), latest,
+			entry.Updated.Format(time.RFC3339), entry.Error)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,330 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+
+	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/ctc"
+	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/scc"
+	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/sequencer"
+	fpbindings "github.com/mantlenetworkio/mantle/fraud-proof/bindings"
+)
+
+// tssGroupManagerName is the name the SCC resolves the TssGroupManager by.
+const tssGroupManagerName = "Proxy__TSS_GroupManager"
+
+// DAAddr is the address given to the sequencer for the data availability
+// contract, which is not deployed as the harness never reaches the DA upgrade
+// block.
+var DAAddr = common.HexToAddress("0x5100000000000000000000000000000000000006")
+
+// SequencerBatch is a batch of L2 transactions appended to the CTC.
+type SequencerBatch struct {
+	// Index is the position of the batch in the CTC.
+	Index uint64
+
+	// BlockNumber is the L1 block the batch was appended in.
+	BlockNumber uint64
+
+	// LastTimestamp and LastBlockNumber are read by the CTC from the last
+	// context of the batch.
+	LastTimestamp   uint64
+	LastBlockNumber uint64
+
+	// Params are the decoded arguments of appendSequencerBatch.
+	Params *sequencer.AppendSequencerBatchParams
+}
+
+// StateBatch is a batch of L2 state roots appended to the SCC through the
+// Rollup contract.
+type StateBatch struct {
+	// Index is the position of the batch in the SCC.
+	Index uint64
+
+	// BlockNumber is the L1 block the batch was appended in.
+	BlockNumber uint64
+
+	// PrevTotalElements is the number of state roots before the batch.
+	PrevTotalElements uint64
+
+	// StateRoots are the state roots of the batch.
+	StateRoots [][32]byte
+
+	// Signature is the tss signature of the batch.
+	Signature []byte
+}
+
+// Assertion is an assertion created by the Rollup contract, the first one is
+// the genesis assertion.
+type Assertion struct {
+	StateHash    [32]byte
+	InboxSize    uint64
+	Parent       uint64
+	Deadline     uint64
+	ProposalTime uint64
+}
+
+// SequencerBatches returns the batches of the canonical chain, decoded from
+// the calldata of the transactions that appended them.
+func (l *L1) SequencerBatches() ([]SequencerBatch, error) {
+	contract, err := ctc.NewCanonicalTransactionChain(
+		l.Contracts.CTC, l.client,
+	)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := contract.FilterTransactionBatchAppended(
+		&bind.FilterOpts{Context: context.Background()}, nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	ctcABI, err := ctc.CanonicalTransactionChainMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	var batches []SequencerBatch
+	for iter.Next() {
+		event := iter.Event
+		tx := l.Transaction(event.Raw.TxHash)
+		if tx == nil {
+			return nil, fmt.Errorf("batch %d: unknown tx %s",
+				event.BatchIndex, event.Raw.TxHash)
+		}
+		params := new(sequencer.AppendSequencerBatchParams)
+		err := params.Read(bytes.NewReader(tx.Data()[4:]))
+		if err != nil {
+			return nil, fmt.Errorf("batch %d: %w", event.BatchIndex, err)
+		}
+
+		// Batches pipelined in the same block overwrite the last context, so
+		// it is read right after the transaction appending the batch.
+		lastTimestamp, err := l.callAfter(
+			event.Raw, ctcABI, l.Contracts.CTC, "getLastTimestamp",
+		)
+		if err != nil {
+			return nil, err
+		}
+		lastBlockNumber, err := l.callAfter(
+			event.Raw, ctcABI, l.Contracts.CTC, "getLastBlockNumber",
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		batches = append(batches, SequencerBatch{
+			Index:           event.BatchIndex.Uint64(),
+			BlockNumber:     event.Raw.BlockNumber,
+			LastTimestamp:   lastTimestamp,
+			LastBlockNumber: lastBlockNumber,
+			Params:          params,
+		})
+	}
+	return batches, iter.Error()
+}
+
+// callAfter calls the uint256 getter method of the contract at to on top of
+// the state right after the transaction that emitted log.
+func (l *L1) callAfter(
+	log types.Log,
+	contractABI *abi.ABI,
+	to common.Address,
+	method string,
+) (uint64, error) {
+
+	chain := l.backend.Blockchain()
+	block := chain.GetBlockByHash(log.BlockHash)
+	if block == nil {
+		return 0, fmt.Errorf("unknown block %s", log.BlockHash)
+	}
+	header := block.Header()
+	parent := chain.GetHeaderByHash(header.ParentHash)
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return 0, err
+	}
+
+	var usedGas uint64
+	gasPool := new(core.GasPool).AddGas(header.GasLimit)
+	for i, tx := range block.Transactions()[:log.TxIndex+1] {
+		statedb.Prepare(tx.Hash(), i)
+		_, err := core.ApplyTransaction(
+			chain.Config(), chain, &header.Coinbase, gasPool, statedb,
+			header, tx, &usedGas, vm.Config{},
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	input, err := contractABI.Pack(method)
+	if err != nil {
+		return 0, err
+	}
+	result, err := l.call(
+		ethereum.CallMsg{To: &to, Data: input}, statedb, header,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if result.Failed() {
+		return 0, fmt.Errorf("%s: %w", method, result.Err)
+	}
+	out, err := contractABI.Unpack(method, result.Return())
+	if err != nil {
+		return 0, err
+	}
+	return out[0].(*big.Int).Uint64(), nil
+}
+
+// TotalElements returns the number of L2 transactions in the CTC.
+func (l *L1) TotalElements() (uint64, error) {
+	contract, err := ctc.NewCanonicalTransactionChain(
+		l.Contracts.CTC, l.client,
+	)
+	if err != nil {
+		return 0, err
+	}
+	total, err := contract.GetTotalElements(&bind.CallOpts{})
+	if err != nil {
+		return 0, err
+	}
+	return total.Uint64(), nil
+}
+
+// StateBatches returns the state batches of the canonical chain, the state
+// roots decoded from the calldata of the transactions that appended them.
+func (l *L1) StateBatches() ([]StateBatch, error) {
+	contract, err := scc.NewStateCommitmentChain(l.Contracts.SCC, l.client)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := contract.FilterStateBatchAppended(
+		&bind.FilterOpts{Context: context.Background()}, nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var batches []StateBatch
+	for iter.Next() {
+		event := iter.Event
+		tx := l.Transaction(event.Raw.TxHash)
+		if tx == nil {
+			return nil, fmt.Errorf("state batch %d: unknown tx %s",
+				event.BatchIndex, event.Raw.TxHash)
+		}
+		roots, err := l.decodeStateRoots(*tx.To(), tx.Data())
+		if err != nil {
+			return nil, fmt.Errorf("state batch %d: %w", event.BatchIndex, err)
+		}
+		if uint64(len(roots)) != event.BatchSize.Uint64() {
+			return nil, fmt.Errorf("state batch %d: %d roots, expected %d",
+				event.BatchIndex, len(roots), event.BatchSize)
+		}
+
+		batches = append(batches, StateBatch{
+			Index:             event.BatchIndex.Uint64(),
+			BlockNumber:       event.Raw.BlockNumber,
+			PrevTotalElements: event.PrevTotalElements.Uint64(),
+			StateRoots:        roots,
+			Signature:         event.Signature,
+		})
+	}
+	return batches, iter.Error()
+}
+
+// decodeStateRoots returns the state roots in the calldata of a transaction
+// to the Rollup or the SCC.
+func (l *L1) decodeStateRoots(
+	to common.Address,
+	data []byte,
+) ([][32]byte, error) {
+
+	contractABI := scc.StateCommitmentChainABI
+	if to == l.Contracts.Rollup {
+		contractABI = fpbindings.RollupABI
+	}
+	parsed, err := abi.JSON(strings.NewReader(contractABI))
+	if err != nil {
+		return nil, err
+	}
+	method, err := parsed.MethodById(data)
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	for i, input := range method.Inputs {
+		if input.Name == "_batch" {
+			return args[i].([][32]byte), nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no state batch", method.Name)
+}
+
+// StateRoots returns the state roots of the canonical chain in order.
+func (l *L1) StateRoots() ([][32]byte, error) {
+	batches, err := l.StateBatches()
+	if err != nil {
+		return nil, err
+	}
+	var roots [][32]byte
+	for _, batch := range batches {
+		roots = append(roots, batch.StateRoots...)
+	}
+	return roots, nil
+}
+
+// Assertions returns the assertions created by the Rollup, starting with the
+// genesis assertion.
+func (l *L1) Assertions() ([]Assertion, error) {
+	rollup, err := fpbindings.NewRollup(l.Contracts.Rollup, l.client)
+	if err != nil {
+		return nil, err
+	}
+	assertionMap, err := fpbindings.NewAssertionMap(
+		l.Contracts.AssertionMap, l.client,
+	)
+	if err != nil {
+		return nil, err
+	}
+	last, err := rollup.LastCreatedAssertionID(&bind.CallOpts{})
+	if err != nil {
+		return nil, err
+	}
+
+	var assertions []Assertion
+	for id := uint64(0); id <= last.Uint64(); id++ {
+		assertion, err := assertionMap.Assertions(
+			&bind.CallOpts{}, new(big.Int).SetUint64(id),
+		)
+		if err != nil {
+			return nil, err
+		}
+		assertions = append(assertions, Assertion{
+			StateHash:    assertion.StateHash,
+			InboxSize:    assertion.InboxSize.Uint64(),
+			Parent:       assertion.Parent.Uint64(),
+			Deadline:     assertion.Deadline.Uint64(),
+			ProposalTime: assertion.ProposalTime.Uint64(),
+		})
+	}
+	return assertions, nil
+}
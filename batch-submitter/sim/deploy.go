@@ -0,0 +1,352 @@
+package sim
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// artifacts are the compiled contracts deployed on the L1, as deployed on
+// mainnet.
+//
+//go:embed testdata/contracts/*.json
+var artifacts embed.FS
+
+const (
+	// ctcMaxTransactionGasLimit, ctcL2GasDiscountDivisor and
+	// ctcEnqueueGasCost are the constructor arguments of the CTC.
+	ctcMaxTransactionGasLimit = 30_000_000
+	ctcL2GasDiscountDivisor   = 32
+	ctcEnqueueGasCost         = 60_000
+
+	// sccFraudProofWindow and sccSequencerPublishWindow are the constructor
+	// arguments of the SCC, as deployed on mainnet.
+	sccFraudProofWindow       = 604_800
+	sccSequencerPublishWindow = 12_592_000
+)
+
+var (
+	// baseStakeAmount is the stake the Rollup requires from the proposer.
+	baseStakeAmount = big.NewInt(1e18)
+
+	// stakingSlashCode is the code of the TssStakingSlashing stand in, which
+	// returns true to every call so that any tss member can operate.
+	stakingSlashCode = common.FromHex("0x600160005260206000f3")
+)
+
+// Contracts are the addresses of the contracts deployed on the L1.
+type Contracts struct {
+	AddressManager  common.Address
+	CTC             common.Address
+	SCC             common.Address
+	Rollup          common.Address
+	AssertionMap    common.Address
+	TssGroupManager common.Address
+}
+
+// contract is a contract deployed from its artifact.
+type contract struct {
+	*bind.BoundContract
+	abi     abi.ABI
+	address common.Address
+}
+
+// loadArtifact returns the ABI and creation code of the named contract.
+func loadArtifact(name string) (abi.ABI, []byte, error) {
+	data, err := artifacts.ReadFile("testdata/contracts/" + name + ".json")
+	if err != nil {
+		return abi.ABI{}, nil, err
+	}
+
+	var artifact struct {
+		ABI      json.RawMessage `json:"abi"`
+		Bytecode hexutil.Bytes   `json:"bytecode"`
+	}
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return abi.ABI{}, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(artifact.ABI)))
+	if err != nil {
+		return abi.ABI{}, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return parsed, artifact.Bytecode, nil
+}
+
+// deployer deploys and sets up the contracts, mining a block after every
+// transaction.
+type deployer struct {
+	backend *backends.SimulatedBackend
+	opts    *bind.TransactOpts
+}
+
+// newTransactor returns the options of the transactions sent by key.
+func newTransactor(key *ecdsa.PrivateKey) *bind.TransactOpts {
+	opts, err := bind.NewKeyedTransactorWithChainID(key, L1ChainID)
+	if err != nil {
+		panic(err)
+	}
+	return opts
+}
+
+// mined mines tx and checks that it succeeded.
+func (d *deployer) mined(tx *types.Transaction, what string) error {
+	d.backend.Commit()
+	receipt, err := d.backend.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("%s reverted", what)
+	}
+	return nil
+}
+
+// deploy deploys the named contract with the constructor args.
+func (d *deployer) deploy(name string, args ...interface{}) (*contract, error) {
+	parsed, code, err := loadArtifact(name)
+	if err != nil {
+		return nil, err
+	}
+	address, tx, bound, err := bind.DeployContract(
+		d.opts, parsed, code, d.backend, args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deploying %s: %w", name, err)
+	}
+	if err := d.mined(tx, "deploying "+name); err != nil {
+		return nil, err
+	}
+	return &contract{
+		BoundContract: bound,
+		abi:           parsed,
+		address:       address,
+	}, nil
+}
+
+// deployProxy deploys the named contract behind a proxy administered by
+// admin, calling the initializer encoded in init from the deployer. The
+// returned contract is the implementation bound at the proxy address.
+func (d *deployer) deployProxy(
+	name string,
+	admin common.Address,
+	init []byte,
+) (*contract, error) {
+
+	impl, err := d.deploy(name)
+	if err != nil {
+		return nil, err
+	}
+	if init == nil {
+		init = []byte{}
+	}
+	proxy, err := d.deploy(
+		"TransparentUpgradeableProxy", impl.address, admin, init,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &contract{
+		BoundContract: bind.NewBoundContract(
+			proxy.address, impl.abi, d.backend, d.backend, d.backend,
+		),
+		abi:     impl.abi,
+		address: proxy.address,
+	}, nil
+}
+
+// transact calls method of c from opts.
+func (d *deployer) transact(
+	opts *bind.TransactOpts,
+	c *contract,
+	method string,
+	args ...interface{},
+) error {
+
+	tx, err := c.Transact(opts, method, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return d.mined(tx, method)
+}
+
+// deployContracts deploys the CTC, the SCC, the Rollup, the AssertionMap and
+// the TssGroupManager along with the contracts they depend on, and sets them
+// up so that the sequencer can append batches, and the proposer state batches
+// signed by the tss group through the Rollup.
+func deployContracts(
+	backend *backends.SimulatedBackend,
+	cfg L1Config,
+) (Contracts, error) {
+
+	d := &deployer{
+		backend: backend,
+		opts:    newTransactor(deployerKey),
+	}
+	proposerOpts := newTransactor(cfg.ProposerKey)
+	proposer := proposerOpts.From
+
+	addressManager, err := d.deploy("Lib_AddressManager")
+	if err != nil {
+		return Contracts{}, err
+	}
+	ctcBatches, err := d.deploy(
+		"ChainStorageContainer", addressManager.address,
+		"CanonicalTransactionChain",
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	ctc, err := d.deploy(
+		"CanonicalTransactionChain", addressManager.address,
+		big.NewInt(ctcMaxTransactionGasLimit),
+		big.NewInt(ctcL2GasDiscountDivisor), big.NewInt(ctcEnqueueGasCost),
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+
+	// The SCC rewards the tss group through the messenger, which enqueues
+	// the reward in the CTC.
+	messenger, err := d.deploy("L1CrossDomainMessenger")
+	if err != nil {
+		return Contracts{}, err
+	}
+	err = d.transact(d.opts, messenger, "initialize", addressManager.address)
+	if err != nil {
+		return Contracts{}, err
+	}
+	sccBatches, err := d.deploy(
+		"ChainStorageContainer", addressManager.address,
+		"StateCommitmentChain",
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	scc, err := d.deploy(
+		"StateCommitmentChain", addressManager.address, messenger.address,
+		big.NewInt(sccFraudProofWindow), big.NewInt(sccSequencerPublishWindow),
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	bondManager, err := d.deploy("BondManager", addressManager.address)
+	if err != nil {
+		return Contracts{}, err
+	}
+
+	initialize := common.FromHex("0x8129fc1c")
+	tssGroupManager, err := d.deployProxy(
+		"TssGroupManager", proxyAdminAddr, initialize,
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	assertionMap, err := d.deployProxy(
+		"AssertionMap", proxyAdminAddr, initialize,
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	// The Rollup is initialized by the proposer, which must own it.
+	rollup, err := d.deployProxy("Rollup", proxyAdminAddr, nil)
+	if err != nil {
+		return Contracts{}, err
+	}
+	stakeToken, err := d.deploy("WETH9")
+	if err != nil {
+		return Contracts{}, err
+	}
+
+	for _, entry := range []struct {
+		name    string
+		address common.Address
+	}{
+		{"CanonicalTransactionChain", ctc.address},
+		{"ChainStorageContainer-CTC-batches", ctcBatches.address},
+		{"BVM_Sequencer", cfg.Sequencer},
+		{"StateCommitmentChain", scc.address},
+		{"ChainStorageContainer-SCC-batches", sccBatches.address},
+		{"BondManager", bondManager.address},
+		{"BVM_Proposer", rollup.address},
+		{"BVM_Rolluper", proposer},
+		{tssGroupManagerName, tssGroupManager.address},
+	} {
+		err := d.transact(
+			d.opts, addressManager, "setAddress", entry.name, entry.address,
+		)
+		if err != nil {
+			return Contracts{}, err
+		}
+	}
+
+	// Elect the tss group, made of a single member.
+	memberOpts := newTransactor(tssMemberKey)
+	memberKey := crypto.FromECDSAPub(&tssMemberKey.PublicKey)[1:]
+	err = d.transact(
+		d.opts, tssGroupManager, "setStakingSlash", stakingSlashAddr,
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	err = d.transact(
+		d.opts, tssGroupManager, "setTssGroupMember", big.NewInt(0),
+		[][]byte{memberKey},
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	err = d.transact(
+		memberOpts, tssGroupManager, "setGroupPublicKey", memberKey,
+		cfg.TssGroupKey,
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+
+	// Stake the proposer, which operates for itself.
+	err = d.transact(
+		proposerOpts, rollup, "initialize", proposer, proxyAdminAddr,
+		stakeToken.address, addressManager.address, assertionMap.address,
+		new(big.Int), baseStakeAmount, common.Hash{},
+		[]common.Address{proposer}, []common.Address{proposer},
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	depositOpts := *proposerOpts
+	depositOpts.Value = baseStakeAmount
+	if err := d.transact(&depositOpts, stakeToken, "deposit"); err != nil {
+		return Contracts{}, err
+	}
+	err = d.transact(
+		proposerOpts, stakeToken, "approve", rollup.address, baseStakeAmount,
+	)
+	if err != nil {
+		return Contracts{}, err
+	}
+	err = d.transact(proposerOpts, rollup, "stake", baseStakeAmount, proposer)
+	if err != nil {
+		return Contracts{}, err
+	}
+
+	return Contracts{
+		AddressManager:  addressManager.address,
+		CTC:             ctc.address,
+		SCC:             scc.address,
+		Rollup:          rollup.address,
+		AssertionMap:    assertionMap.address,
+		TssGroupManager: tssGroupManager.address,
+	}, nil
+}
@@ -0,0 +1,421 @@
+// Package sim runs the sequencer and proposer drivers end to end, against an
+// in-memory L1, an in-memory L2 and a stub tss manager.
+//
+// The L1 is a simulated backend served over in-process JSON-RPC, on which the
+// compiled CTC, SCC, Rollup, AssertionMap and TssGroupManager contracts are
+// deployed, so batches are checked by the contracts themselves. Reverts, burnt
+// nonces, fee spikes and reorgs are scripted through the chain state.
+package sim
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/proposer"
+	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/sequencer"
+	tss "github.com/mantlenetworkio/mantle/batch-submitter/tss-client"
+	bsscore "github.com/mantlenetworkio/mantle/bss-core"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
+)
+
+const (
+	// DefaultBlockTime is the time between L1 blocks if none is configured.
+	DefaultBlockTime = 20 * time.Millisecond
+
+	// DefaultPollInterval is the poll interval of the drivers if none is
+	// configured.
+	DefaultPollInterval = 10 * time.Millisecond
+
+	// DefaultResubmissionTimeout is the time before a batch transaction is
+	// sent again with bumped fees if none is configured.
+	DefaultResubmissionTimeout = 200 * time.Millisecond
+
+	// DefaultBatchSize is the number of L2 blocks of every batch if none is
+	// configured.
+	DefaultBatchSize = 5
+)
+
+var (
+	// L2ChainID is the chain id of the simulated L2.
+	L2ChainID = big.NewInt(17)
+
+	// initialBalance funds the submitter accounts.
+	initialBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+
+	// harnesses numbers the harnesses, so that the drivers of each register
+	// their metrics under a different name.
+	harnesses uint64
+)
+
+// Config configures a Harness.
+type Config struct {
+	// BatchType is the encoding of the sequencer batches.
+	BatchType sequencer.BatchType
+
+	// SequencerBatchSize and StateBatchSize are the number of L2 blocks of
+	// every sequencer and state batch, DefaultBatchSize is used if zero.
+	// Shorter batches are never submitted, so that the batches posted only
+	// depend on the L2 blocks.
+	SequencerBatchSize uint64
+	StateBatchSize     uint64
+
+	// BlockTime is the time between L1 blocks, DefaultBlockTime is used if
+	// zero.
+	BlockTime time.Duration
+
+	// PollInterval is the poll interval of the drivers, DefaultPollInterval
+	// is used if zero.
+	PollInterval time.Duration
+
+	// ResubmissionTimeout is the time before a batch transaction is sent
+	// again, DefaultResubmissionTimeout is used if zero.
+	ResubmissionTimeout time.Duration
+
+	// NumConfirmations is the number of L1 blocks confirming a batch, one if
+	// zero.
+	NumConfirmations uint64
+
+	// ReorgDepth is the number of L1 blocks during which the services watch
+	// confirmed batches for reorgs, disabled if zero.
+	ReorgDepth uint64
+
+	// SafeAbortNonceTooLowCount is the number of nonce too low errors after
+	// which a batch transaction is abandoned, one if zero.
+	SafeAbortNonceTooLowCount uint64
+
+	// MaxPendingTxs is the number of batch transactions each service keeps
+	// in flight.
+	MaxPendingTxs uint64
+
+	// Fees configures the fee strategy of both drivers if set.
+	Fees *txmgr.FeeConfig
+
+	// JournalDir is the directory of the service journals, which lets
+	// Restart resume the batches in flight. Journaling is disabled if empty.
+	JournalDir string
+
+	// ClearPendingTx makes the services clear the pending transactions left
+	// by a previous run that cannot be resumed from the journal.
+	ClearPendingTx bool
+}
+
+// Harness drives the sequencer and proposer services against an L1, an L2 and
+// a tss manager.
+type Harness struct {
+	L1  *L1
+	L2  *L2
+	Tss *TssManager
+
+	L1Client *ethclient.Client
+	L2Client *l2ethclient.Client
+
+	SequencerAddr common.Address
+	ProposerAddr  common.Address
+
+	Sequencer *sequencer.Driver
+	Proposer  *proposer.Driver
+
+	cfg          Config
+	sequencerKey *ecdsa.PrivateKey
+	proposerKey  *ecdsa.PrivateKey
+	tssClient    *tss.Client
+	journals     []*journal.Journal
+	services     []*bsscore.Service
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newKey derives a deterministic private key from name.
+func newKey(name string) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(name)))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// NewHarness creates the chains, the tss manager and the drivers. Nothing is
+// submitted until Start is called.
+func NewHarness(cfg Config) (*Harness, error) {
+	if cfg.SequencerBatchSize == 0 {
+		cfg.SequencerBatchSize = DefaultBatchSize
+	}
+	if cfg.StateBatchSize == 0 {
+		cfg.StateBatchSize = DefaultBatchSize
+	}
+	if cfg.BlockTime == 0 {
+		cfg.BlockTime = DefaultBlockTime
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.ResubmissionTimeout == 0 {
+		cfg.ResubmissionTimeout = DefaultResubmissionTimeout
+	}
+	if cfg.NumConfirmations == 0 {
+		cfg.NumConfirmations = 1
+	}
+	if cfg.SafeAbortNonceTooLowCount == 0 {
+		cfg.SafeAbortNonceTooLowCount = 1
+	}
+
+	sequencerKey := newKey("sim sequencer")
+	proposerKey := newKey("sim proposer")
+	h := &Harness{
+		Tss:           NewTssManager(newKey("sim tss group")),
+		L2:            NewL2(L2ChainID),
+		SequencerAddr: crypto.PubkeyToAddress(sequencerKey.PublicKey),
+		ProposerAddr:  crypto.PubkeyToAddress(proposerKey.PublicKey),
+		cfg:           cfg,
+		sequencerKey:  sequencerKey,
+		proposerKey:   proposerKey,
+	}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+
+	var err error
+	h.L1, err = NewL1(L1Config{
+		Balances: map[common.Address]*big.Int{
+			h.SequencerAddr: initialBalance,
+			h.ProposerAddr:  initialBalance,
+		},
+		Sequencer:   h.SequencerAddr,
+		ProposerKey: proposerKey,
+		TssGroupKey: h.Tss.GroupKey(),
+	})
+	if err != nil {
+		h.Stop()
+		return nil, err
+	}
+	// The proposer reads the CTC a few blocks behind the head.
+	for i := 0; i < 3; i++ {
+		h.L1.Mine()
+	}
+
+	if err := h.newDrivers(); err != nil {
+		h.Stop()
+		return nil, err
+	}
+	return h, nil
+}
+
+// openJournal opens the journal of the named service, nil is returned if
+// journaling is disabled.
+func (h *Harness) openJournal(name string) (*journal.Journal, error) {
+	if h.cfg.JournalDir == "" {
+		return nil, nil
+	}
+	j, err := journal.Open(filepath.Join(h.cfg.JournalDir, name+".journal"))
+	if err != nil {
+		return nil, err
+	}
+	h.journals = append(h.journals, j)
+	return j, nil
+}
+
+// newDrivers creates the clients, the drivers and their services.
+func (h *Harness) newDrivers() error {
+	cfg := h.cfg
+	sequencerKey, proposerKey := h.sequencerKey, h.proposerKey
+
+	var err error
+	l1RPCClient, err := h.L1.RPCClient()
+	if err != nil {
+		return err
+	}
+	h.L1Client = ethclient.NewClient(l1RPCClient)
+	if h.L2Client, err = h.L2.Client(); err != nil {
+		return err
+	}
+
+	verifier, err := tss.NewGroupVerifier(h.L1.Contracts.TssGroupManager, h.L1Client)
+	if err != nil {
+		return err
+	}
+	h.tssClient, err = tss.NewClient(tss.Config{
+		URLs:         []string{h.Tss.URL},
+		MaxRetries:   3,
+		RetryBackoff: cfg.PollInterval,
+		Verifier:     verifier,
+	})
+	if err != nil {
+		return err
+	}
+
+	id := atomic.AddUint64(&harnesses, 1)
+	h.Sequencer, err = sequencer.NewDriver(sequencer.Config{
+		Name:           fmt.Sprintf("SimSequencer%d", id),
+		L1Client:       h.L1Client,
+		L1RPCClient:    l1RPCClient,
+		L2Client:       h.L2Client,
+		BlockOffset:    1,
+		CTCAddr:        h.L1.Contracts.CTC,
+		DaUpgradeBlock: 1 << 62,
+		DAAddr:         DAAddr,
+		ChainID:        L1ChainID,
+		Signer:         signer.NewLocalSigner(sequencerKey),
+		BatchType:      cfg.BatchType,
+		MaxRollupTxn:   cfg.SequencerBatchSize,
+		MinRollupTxn:   cfg.SequencerBatchSize,
+		Fees:           cfg.Fees,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The proposer submits up to MaxStateRootElements+1 state roots.
+	h.Proposer, err = proposer.NewDriver(proposer.Config{
+		Name:                        fmt.Sprintf("SimProposer%d", id),
+		L1Client:                    h.L1Client,
+		L2Client:                    h.L2Client,
+		TssClient:                   h.tssClient,
+		BlockOffset:                 1,
+		MaxStateRootElements:        cfg.StateBatchSize - 1,
+		MinStateRootElements:        cfg.StateBatchSize,
+		SCCAddr:                     h.L1.Contracts.SCC,
+		CTCAddr:                     h.L1.Contracts.CTC,
+		FPRollupAddr:                h.L1.Contracts.Rollup,
+		ChainID:                     L1ChainID,
+		Signer:                      signer.NewLocalSigner(proposerKey),
+		RollupTimeout:               time.Hour,
+		PollInterval:                cfg.PollInterval,
+		MinTimeoutStateRootElements: cfg.StateBatchSize,
+		Fees:                        cfg.Fees,
+	})
+	if err != nil {
+		return err
+	}
+
+	txManagerConfig := txmgr.Config{
+		ResubmissionTimeout:       cfg.ResubmissionTimeout,
+		ReceiptQueryInterval:      cfg.PollInterval,
+		NumConfirmations:          cfg.NumConfirmations,
+		SafeAbortNonceTooLowCount: cfg.SafeAbortNonceTooLowCount,
+		MaxPendingTxs:             cfg.MaxPendingTxs,
+	}
+	for _, driver := range []struct {
+		bsscore.Driver
+		fees    *txmgr.FeeStrategy
+		journal string
+	}{
+		{h.Sequencer, h.Sequencer.Fees(), "sequencer"},
+		{h.Proposer, h.Proposer.Fees(), "proposer"},
+	} {
+		batchJournal, err := h.openJournal(driver.journal)
+		if err != nil {
+			return err
+		}
+
+		txManagerConfig.Name = driver.Name()
+		h.services = append(h.services, bsscore.NewService(bsscore.ServiceConfig{
+			Context:         h.ctx,
+			Driver:          driver.Driver,
+			PollInterval:    cfg.PollInterval,
+			L1Client:        h.L1Client,
+			TxManagerConfig: txManagerConfig,
+			Fees:            driver.fees,
+			ReorgDepth:      cfg.ReorgDepth,
+			ClearPendingTx:  cfg.ClearPendingTx,
+			Journal:         batchJournal,
+		}))
+	}
+	return nil
+}
+
+// Start starts mining L1 blocks and submitting batches.
+func (h *Harness) Start() error {
+	h.wg.Add(1)
+	go h.mine()
+
+	for _, service := range h.services {
+		if err := service.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restart stops the services and starts them again with new drivers and
+// clients, as a restarted batch submitter would. The L1 keeps mining, and the
+// transactions in flight stay in its pool.
+func (h *Harness) Restart() error {
+	h.stopDrivers()
+	if err := h.newDrivers(); err != nil {
+		return err
+	}
+	for _, service := range h.services {
+		if err := service.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mine mines an L1 block every BlockTime.
+func (h *Harness) mine() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(h.cfg.BlockTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.L1.Mine()
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// stopDrivers stops the services, then closes their journals and the clients
+// of the drivers.
+func (h *Harness) stopDrivers() {
+	for _, service := range h.services {
+		_ = service.Stop()
+	}
+	h.services = nil
+
+	for _, j := range h.journals {
+		_ = j.Close()
+	}
+	h.journals = nil
+
+	if h.tssClient != nil {
+		h.tssClient.Close()
+		h.tssClient = nil
+	}
+	if h.L1Client != nil {
+		h.L1Client.Close()
+		h.L1Client = nil
+	}
+	if h.L2Client != nil {
+		h.L2Client.Close()
+		h.L2Client = nil
+	}
+}
+
+// Stop stops the services, the L1 miner and the tss manager.
+func (h *Harness) Stop() {
+	h.stopDrivers()
+	h.cancel()
+	h.wg.Wait()
+	h.Tss.Close()
+	if h.L1 != nil {
+		h.L1.Close()
+	}
+}
@@ -0,0 +1,788 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// l1GasLimit is the gas limit of every L1 block.
+	l1GasLimit = 30_000_000
+
+	// trafficGas is the gas used by other transactions in every block,
+	// which keeps the base fee steady.
+	trafficGas = l1GasLimit / params.ElasticityMultiplier
+
+	// addressGas is the gas limit of the transactions updating the address
+	// manager.
+	addressGas = 200_000
+
+	// priceBump is the percentage by which a replacement must raise both fee
+	// caps of the transaction it replaces, as required by geth.
+	priceBump = 10
+)
+
+var (
+	// L1ChainID is the chain id of the simulated backend.
+	L1ChainID = params.AllEthashProtocolChanges.ChainID
+
+	// DefaultTipCap is the priority fee paid by other L1 transactions if
+	// none is configured.
+	DefaultTipCap = big.NewInt(1_000_000_000)
+
+	// deployerKey deploys the contracts and owns the address manager.
+	deployerKey = newKey("sim deployer")
+
+	// tssMemberKey is the key of the only member of the tss group.
+	tssMemberKey = newKey("sim tss member")
+
+	// trafficKey sends the transactions of other L1 users.
+	trafficKey = newKey("sim l1 traffic")
+
+	// proxyAdminAddr administers the proxies, it never sends transactions
+	// as the proxies do not forward the calls of their admin.
+	proxyAdminAddr = common.HexToAddress("0x5100000000000000000000000000000000000001")
+
+	// stakingSlashAddr holds the TssStakingSlashing stand in.
+	stakingSlashAddr = common.HexToAddress("0x5100000000000000000000000000000000000002")
+
+	// sinkAddr holds a contract that consumes all the gas of a call, which
+	// the transactions of other L1 users are sent to.
+	sinkAddr = common.HexToAddress("0x5100000000000000000000000000000000000003")
+
+	// accountBalance funds the accounts of the L1.
+	accountBalance = new(big.Int).Mul(big.NewInt(1e9), big.NewInt(1e18))
+)
+
+// L1Config configures an L1.
+type L1Config struct {
+	// TipCap is the priority fee paid by other transactions, DefaultTipCap
+	// is used if nil.
+	TipCap *big.Int
+
+	// Balances funds accounts in the genesis block.
+	Balances map[common.Address]*big.Int
+
+	// Sequencer is the only account allowed to append sequencer batches.
+	Sequencer common.Address
+
+	// ProposerKey is the key of the owner of the Rollup contract, the only
+	// account allowed to create assertions.
+	ProposerKey *ecdsa.PrivateKey
+
+	// TssGroupKey is the 64 byte public key of the tss group the state
+	// batches must be signed by.
+	TssGroupKey []byte
+}
+
+// L1 is an in-memory L1 chain running the deployed bytecode of the contracts
+// the batch submitter interacts with on a simulated backend. The simulated
+// backend has no transaction pool, so the L1 keeps the transactions sent to it
+// until they are mined, applying the rules geth uses to accept them.
+//
+// Blocks are only mined by Mine. Every block is half filled by the
+// transactions of other users, keeping the base fee steady, and tests script
+// faults through RevertNext, BurnNonceOnNext, SpikeBaseFeeOnNext, ReorgOnNext
+// and Reorg.
+type L1 struct {
+	Contracts Contracts
+
+	cfg     L1Config
+	backend *backends.SimulatedBackend
+	signer  types.Signer
+	client  *ethclient.Client
+
+	addressManagerABI abi.ABI
+	guards            map[common.Address]guard
+
+	mu       sync.Mutex
+	pool     map[common.Address]map[uint64]*types.Transaction
+	sent     map[common.Address][]*types.Transaction
+	keys     map[common.Address]*ecdsa.PrivateKey
+	tipCap   *big.Int
+	forks    uint64
+	included []*types.Transaction
+
+	// Faults scripted for the next transactions to a contract.
+	reverts  map[common.Address]int
+	reverted map[common.Address]int
+	burns    map[common.Address]bool
+	spikes   map[common.Address]int
+	burnings map[common.Address]*types.Transaction
+	reorgs   map[common.Address]scriptedReorg
+}
+
+// guard is the address manager entry of the only account allowed to call a
+// contract, which RevertNext clears to make the calls revert.
+type guard struct {
+	name    string
+	account common.Address
+}
+
+// scriptedReorg is a reorg scripted by ReorgOnNext.
+type scriptedReorg struct {
+	fork    uint64
+	dropTxs bool
+}
+
+// NewL1 creates an L1 chain and deploys the contracts.
+func NewL1(cfg L1Config) (*L1, error) {
+	if cfg.TipCap == nil {
+		cfg.TipCap = DefaultTipCap
+	}
+
+	alloc := core.GenesisAlloc{
+		crypto.PubkeyToAddress(deployerKey.PublicKey):  {Balance: accountBalance},
+		crypto.PubkeyToAddress(tssMemberKey.PublicKey): {Balance: accountBalance},
+		crypto.PubkeyToAddress(trafficKey.PublicKey):   {Balance: accountBalance},
+		stakingSlashAddr: {Code: stakingSlashCode, Balance: new(big.Int)},
+		sinkAddr:         {Code: []byte{byte(0xfe)}, Balance: new(big.Int)},
+	}
+	for addr, balance := range cfg.Balances {
+		alloc[addr] = core.GenesisAccount{Balance: balance}
+	}
+	backend := backends.NewSimulatedBackend(alloc, l1GasLimit)
+
+	contracts, err := deployContracts(backend, cfg)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+	addressManagerABI, _, err := loadArtifact("Lib_AddressManager")
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	l := &L1{
+		Contracts:         contracts,
+		cfg:               cfg,
+		backend:           backend,
+		signer:            types.LatestSignerForChainID(L1ChainID),
+		addressManagerABI: addressManagerABI,
+		guards: map[common.Address]guard{
+			contracts.CTC: {"BVM_Sequencer", cfg.Sequencer},
+			contracts.Rollup: {
+				"BVM_Rolluper",
+				crypto.PubkeyToAddress(cfg.ProposerKey.PublicKey),
+			},
+		},
+		pool:     make(map[common.Address]map[uint64]*types.Transaction),
+		sent:     make(map[common.Address][]*types.Transaction),
+		keys:     make(map[common.Address]*ecdsa.PrivateKey),
+		tipCap:   new(big.Int).Set(cfg.TipCap),
+		reverts:  make(map[common.Address]int),
+		reverted: make(map[common.Address]int),
+		burns:    make(map[common.Address]bool),
+		spikes:   make(map[common.Address]int),
+		burnings: make(map[common.Address]*types.Transaction),
+		reorgs:   make(map[common.Address]scriptedReorg),
+	}
+	if l.client, err = l.Client(); err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.refill(); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Close closes the simulated backend.
+func (l *L1) Close() {
+	l.client.Close()
+	_ = l.backend.Close()
+}
+
+// head returns the header of the latest block.
+func (l *L1) head() *types.Header {
+	return l.backend.Blockchain().CurrentHeader()
+}
+
+// BlockNumber returns the number of the latest block.
+func (l *L1) BlockNumber() uint64 {
+	return l.head().Number.Uint64()
+}
+
+// Header returns the header of the canonical block at number, or nil if there
+// is none.
+func (l *L1) Header(number uint64) *types.Header {
+	return l.backend.Blockchain().GetHeaderByNumber(number)
+}
+
+// BaseFee returns the base fee of the next block.
+func (l *L1) BaseFee() *big.Int {
+	return misc.CalcBaseFee(params.AllEthashProtocolChanges, l.head())
+}
+
+// Mine mines a block including the pending transactions that pay at least the
+// base fee, in nonce order.
+func (l *L1) Mine() *types.Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	block, err := l.mine()
+	if err == nil {
+		err = l.refill()
+	}
+	if err != nil {
+		log.Error("L1 mining failed", "err", err)
+	}
+	return block
+}
+
+// mine commits the pending block, built by fill, and removes the transactions
+// it includes from the pool. The caller must hold the lock.
+func (l *L1) mine() (*types.Block, error) {
+	hash := l.backend.Commit()
+	block, err := l.backend.BlockByHash(context.Background(), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	for to, n := range l.reverted {
+		l.reverts[to] -= n
+		if l.reverts[to] <= 0 {
+			delete(l.reverts, to)
+		}
+	}
+	for _, tx := range l.included {
+		receipt, err := l.backend.TransactionReceipt(
+			context.Background(), tx.Hash(),
+		)
+		if err == nil && receipt.Status == types.ReceiptStatusFailed {
+			log.Info("L1 transaction reverted", "hash", tx.Hash(),
+				"to", tx.To())
+		}
+	}
+
+	for from, pending := range l.pool {
+		nonce, err := l.backend.PendingNonceAt(context.Background(), from)
+		if err != nil {
+			return nil, err
+		}
+		for n := range pending {
+			if n < nonce {
+				delete(pending, n)
+			}
+		}
+		if len(pending) == 0 {
+			delete(l.pool, from)
+		}
+		if burning, ok := l.burnings[from]; ok && burning.Nonce() < nonce {
+			delete(l.burnings, from)
+		}
+	}
+	return block, nil
+}
+
+// refill rebuilds the pending block on top of the latest block, so that the
+// pending state reflects the pool. The caller must hold the lock.
+func (l *L1) refill() error {
+	l.backend.Rollback()
+	return l.fill(l.head())
+}
+
+// fill adds to the empty pending block built on parent the transactions of
+// other users, then the pending transactions paying at least its base fee in
+// nonce order. The transactions scripted to revert are surrounded by the
+// transactions clearing and restoring the address manager entry they depend
+// on. The caller must hold the lock.
+func (l *L1) fill(parent *types.Header) error {
+	ctx := context.Background()
+	baseFee := misc.CalcBaseFee(params.AllEthashProtocolChanges, parent)
+	l.included = nil
+	l.reverted = make(map[common.Address]int)
+
+	if err := l.sendTraffic(baseFee, trafficGas); err != nil {
+		return err
+	}
+	gasLeft := uint64(l1GasLimit - trafficGas)
+
+	senders := make([]common.Address, 0, len(l.pool))
+	for addr := range l.pool {
+		senders = append(senders, addr)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		return bytes.Compare(senders[i][:], senders[j][:]) < 0
+	})
+
+	for _, from := range senders {
+		nonce, err := l.backend.PendingNonceAt(ctx, from)
+		if err != nil {
+			return err
+		}
+		for ; ; nonce++ {
+			tx, ok := l.pool[from][nonce]
+			if burning, burnt := l.burnings[from]; burnt &&
+				burning.Nonce() == nonce {
+
+				tx, ok = burning, true
+			}
+			if !ok || tx.GasFeeCap().Cmp(baseFee) < 0 {
+				break
+			}
+
+			to := *tx.To()
+			revert := l.reverts[to] > l.reverted[to]
+			gas := tx.Gas()
+			if revert {
+				gas += 2 * addressGas
+			}
+			if gas > gasLeft {
+				break
+			}
+			gasLeft -= gas
+
+			if revert {
+				l.reverted[to]++
+				err = l.setAddress(baseFee, l.guards[to].name,
+					common.Address{})
+				if err != nil {
+					return err
+				}
+			}
+			if err := l.backend.SendTransaction(ctx, tx); err != nil {
+				return err
+			}
+			l.included = append(l.included, tx)
+			if revert {
+				err = l.setAddress(baseFee, l.guards[to].name,
+					l.guards[to].account)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// feeCap returns the fee cap of the transactions the L1 sends itself in a
+// block with baseFee.
+func (l *L1) feeCap(baseFee *big.Int) *big.Int {
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(2))
+	return feeCap.Add(feeCap, l.tipCap)
+}
+
+// sendTraffic adds to the pending block a transaction of other users using
+// gas. The transactions carry the number of reorgs so that the blocks built
+// again after a reorg differ from the ones they replace.
+func (l *L1) sendTraffic(baseFee *big.Int, gas uint64) error {
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(trafficKey.PublicKey)
+	nonce, err := l.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	tx, err := types.SignNewTx(trafficKey, l.signer, &types.DynamicFeeTx{
+		ChainID:   L1ChainID,
+		Nonce:     nonce,
+		GasTipCap: l.tipCap,
+		GasFeeCap: l.feeCap(baseFee),
+		Gas:       gas,
+		To:        &sinkAddr,
+		Data:      new(big.Int).SetUint64(l.forks).Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	return l.backend.SendTransaction(ctx, tx)
+}
+
+// setAddress adds to the pending block a transaction of the owner of the
+// address manager setting the entry name to address.
+func (l *L1) setAddress(
+	baseFee *big.Int,
+	name string,
+	address common.Address,
+) error {
+
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(deployerKey.PublicKey)
+	nonce, err := l.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+	data, err := l.addressManagerABI.Pack("setAddress", name, address)
+	if err != nil {
+		return err
+	}
+
+	tx, err := types.SignNewTx(deployerKey, l.signer, &types.DynamicFeeTx{
+		ChainID:   L1ChainID,
+		Nonce:     nonce,
+		GasTipCap: l.tipCap,
+		GasFeeCap: l.feeCap(baseFee),
+		Gas:       addressGas,
+		To:        &l.Contracts.AddressManager,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	return l.backend.SendTransaction(ctx, tx)
+}
+
+// sendTransaction adds tx to the pool, applying the rules geth uses to accept
+// transactions and their replacements.
+func (l *L1) sendTransaction(tx *types.Transaction) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	from, err := types.Sender(l.signer, tx)
+	if err != nil {
+		return err
+	}
+	if tx.To() == nil {
+		return errors.New("contract creation is not supported")
+	}
+	if tx.GasFeeCap().Cmp(tx.GasTipCap()) < 0 {
+		return core.ErrTipAboveFeeCap
+	}
+	gas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), false, true, true)
+	if err != nil {
+		return err
+	}
+	if tx.Gas() < gas {
+		return core.ErrIntrinsicGas
+	}
+	if tx.Gas() > l1GasLimit-trafficGas {
+		return core.ErrGasLimit
+	}
+
+	ctx := context.Background()
+	nonce, err := l.backend.NonceAt(ctx, from, nil)
+	if err != nil {
+		return err
+	}
+	if tx.Nonce() < nonce {
+		return core.ErrNonceTooLow
+	}
+	balance, err := l.backend.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(tx.Cost()) < 0 {
+		return core.ErrInsufficientFunds
+	}
+
+	pending, ok := l.pool[from]
+	if !ok {
+		pending = make(map[uint64]*types.Transaction)
+		l.pool[from] = pending
+	}
+	if prev, ok := pending[tx.Nonce()]; ok {
+		if prev.Hash() == tx.Hash() {
+			return core.ErrAlreadyKnown
+		}
+		if !isBumped(prev.GasTipCap(), tx.GasTipCap()) ||
+			!isBumped(prev.GasFeeCap(), tx.GasFeeCap()) {
+			return core.ErrReplaceUnderpriced
+		}
+	}
+	pending[tx.Nonce()] = tx
+	l.sent[from] = append(l.sent[from], tx)
+
+	if err := l.trigger(from, tx); err != nil {
+		log.Error("L1 scripted fault failed", "err", err)
+	}
+	return l.refill()
+}
+
+// isBumped returns true if next raises prev by at least priceBump percent.
+func isBumped(prev, next *big.Int) bool {
+	min := new(big.Int).Mul(prev, big.NewInt(100+priceBump))
+	min.Div(min, big.NewInt(100))
+	return next.Cmp(min) >= 0
+}
+
+// trigger applies the faults scripted for the next transaction to a contract
+// to tx, the caller must hold the lock.
+func (l *L1) trigger(from common.Address, tx *types.Transaction) error {
+	to := *tx.To()
+	if factor, ok := l.spikes[to]; ok {
+		delete(l.spikes, to)
+		if err := l.spike(factor); err != nil {
+			return err
+		}
+	}
+	if l.burns[to] {
+		delete(l.burns, to)
+		if err := l.burn(from, tx); err != nil {
+			return err
+		}
+	}
+	if r, ok := l.reorgs[to]; ok {
+		delete(l.reorgs, to)
+		depth := int(l.BlockNumber() + 1 - r.fork)
+		if err := l.reorg(depth, r.dropTxs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spike mines blocks filled by other users until the base fee rose factor
+// times, leaving the pending transactions out.
+func (l *L1) spike(factor int) error {
+	target := new(big.Int).Mul(l.BaseFee(), big.NewInt(int64(factor)))
+	for l.BaseFee().Cmp(target) < 0 {
+		l.backend.Rollback()
+		err := l.sendTraffic(l.BaseFee(), l1GasLimit)
+		if err != nil {
+			return err
+		}
+		l.backend.Commit()
+	}
+	log.Info("L1 base fee spiked", "base_fee", l.BaseFee())
+	return nil
+}
+
+// burn makes another transaction of the sender of tx take its nonce, as if
+// another instance of the submitter had sent it.
+func (l *L1) burn(from common.Address, tx *types.Transaction) error {
+	key, ok := l.keys[from]
+	if !ok {
+		return fmt.Errorf("no key to burn the nonces of %s", from)
+	}
+	burning, err := types.SignNewTx(key, l.signer, &types.DynamicFeeTx{
+		ChainID:   L1ChainID,
+		Nonce:     tx.Nonce(),
+		GasTipCap: tx.GasTipCap(),
+		GasFeeCap: tx.GasFeeCap(),
+		Gas:       params.TxGas,
+		To:        &from,
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("L1 burning nonce", "from", from, "nonce", tx.Nonce(),
+		"tx", tx.Hash())
+	l.burnings[from] = burning
+	return nil
+}
+
+// RevertNext makes the next n transactions to the CTC or the Rollup revert
+// once mined, by clearing the address manager entry of the account allowed to
+// call them for the duration of each transaction.
+func (l *L1) RevertNext(to common.Address, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.guards[to]; !ok {
+		panic(fmt.Sprintf("cannot revert the transactions to %s", to))
+	}
+	l.reverts[to] += n
+	if err := l.refill(); err != nil {
+		log.Error("L1 refill failed", "err", err)
+	}
+}
+
+// BurnNonceOnNext makes another transaction signed by key take the nonce of
+// the next transaction sent to the contract at to, as if another instance of
+// the submitter had sent it. The nonce is consumed by the next block, dropping
+// the transaction, and sending it again fails with a nonce too low error.
+func (l *L1) BurnNonceOnNext(to common.Address, key *ecdsa.PrivateKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.burns[to] = true
+	l.keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+}
+
+// SpikeBaseFeeOnNext makes the base fee rise factor times once the next
+// transaction to the contract at to is sent, by mining blocks filled by other
+// users, leaving the transaction underpriced if its fee cap is below the new
+// base fee.
+func (l *L1) SpikeBaseFeeOnNext(to common.Address, factor int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.spikes[to] = factor
+}
+
+// ReorgOnNext reorgs out the blocks from number fork on once the next
+// transaction to the contract at to is sent, as by Reorg. If dropTxs is set,
+// that transaction is dropped too when it follows a dropped one.
+func (l *L1) ReorgOnNext(to common.Address, fork uint64, dropTxs bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.reorgs[to] = scriptedReorg{
+		fork:    fork,
+		dropTxs: dropTxs,
+	}
+}
+
+// SetTipCap sets the priority fee paid by other users in the next blocks.
+func (l *L1) SetTipCap(tipCap *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tipCap = new(big.Int).Set(tipCap)
+	if err := l.refill(); err != nil {
+		log.Error("L1 refill failed", "err", err)
+	}
+}
+
+// Reorg replaces the latest depth blocks with depth+1 new blocks. The
+// transactions of the replaced blocks are dropped if dropTxs is set, along
+// with the pending transactions of their senders left behind the nonce gap,
+// and are otherwise returned to the pool to be mined again.
+func (l *L1) Reorg(depth int, dropTxs bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.reorg(depth, dropTxs); err != nil {
+		return err
+	}
+	return l.refill()
+}
+
+// reorg implements Reorg, the caller must hold the lock and refill the
+// pending block.
+func (l *L1) reorg(depth int, dropTxs bool) error {
+	head := l.BlockNumber()
+	if depth <= 0 || uint64(depth) > head {
+		return fmt.Errorf("cannot reorg %d blocks of %d", depth, head+1)
+	}
+	ctx := context.Background()
+	fork := head + 1 - uint64(depth)
+	parent := l.Header(fork - 1)
+
+	for number := fork; number <= head; number++ {
+		block, err := l.backend.BlockByNumber(
+			ctx, new(big.Int).SetUint64(number),
+		)
+		if err != nil {
+			return err
+		}
+		for _, tx := range block.Transactions() {
+			from, _ := types.Sender(l.signer, tx)
+			if _, ok := l.sent[from]; !ok {
+				continue
+			}
+			if dropTxs {
+				for nonce := range l.pool[from] {
+					if nonce > tx.Nonce() {
+						delete(l.pool[from], nonce)
+					}
+				}
+				continue
+			}
+			pending, ok := l.pool[from]
+			if !ok {
+				pending = make(map[uint64]*types.Transaction)
+				l.pool[from] = pending
+			}
+			// A replacement sent since takes precedence.
+			if _, ok := pending[tx.Nonce()]; !ok {
+				pending[tx.Nonce()] = tx
+			}
+		}
+	}
+
+	log.Info("L1 reorg", "depth", depth, "fork", fork,
+		"drop_txs", dropTxs)
+	l.forks++
+	l.backend.Rollback()
+	if err := l.backend.Fork(ctx, parent.Hash()); err != nil {
+		return err
+	}
+	for i := 0; i <= depth; i++ {
+		if err := l.fill(parent); err != nil {
+			return err
+		}
+		block, err := l.mine()
+		if err != nil {
+			return err
+		}
+		parent = block.Header()
+	}
+	if l.head().Hash() != parent.Hash() {
+		return errors.New("reorg did not replace the canonical chain")
+	}
+	return nil
+}
+
+// Pending returns the number of pending transactions sent by from.
+func (l *L1) Pending(from common.Address) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.pool[from])
+}
+
+// Sent returns the transactions accepted from from, including replacements
+// and transactions that were never mined, in the order they were sent.
+func (l *L1) Sent(from common.Address) []*types.Transaction {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]*types.Transaction(nil), l.sent[from]...)
+}
+
+// Receipts returns the receipts of the canonical transactions sent by from,
+// in chain order.
+func (l *L1) Receipts(from common.Address) []*types.Receipt {
+	var receipts []*types.Receipt
+	for _, tx := range l.Sent(from) {
+		if receipt := l.Receipt(tx.Hash()); receipt != nil {
+			receipts = append(receipts, receipt)
+		}
+	}
+	sort.Slice(receipts, func(i, j int) bool {
+		if c := receipts[i].BlockNumber.Cmp(receipts[j].BlockNumber); c != 0 {
+			return c < 0
+		}
+		return receipts[i].TransactionIndex < receipts[j].TransactionIndex
+	})
+	return receipts
+}
+
+// Receipt returns the receipt of the canonical transaction with hash, or nil
+// if it is not mined.
+func (l *L1) Receipt(hash common.Hash) *types.Receipt {
+	receipt, err := l.backend.TransactionReceipt(context.Background(), hash)
+	if err != nil {
+		return nil
+	}
+	return receipt
+}
+
+// Transaction returns the canonical transaction with hash, or nil if it is not
+// mined.
+func (l *L1) Transaction(hash common.Hash) *types.Transaction {
+	receipt := l.Receipt(hash)
+	if receipt == nil {
+		return nil
+	}
+	block := l.backend.Blockchain().GetBlockByHash(receipt.BlockHash)
+	if block == nil {
+		return nil
+	}
+	return block.Transactions()[receipt.TransactionIndex]
+}
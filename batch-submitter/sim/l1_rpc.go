@@ -0,0 +1,545 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// callGas is the gas limit of calls that do not set one.
+const callGas = 50_000_000
+
+// Client returns a client connected in-process to the L1.
+func (l *L1) Client() (*ethclient.Client, error) {
+	rpcClient, err := l.RPCClient()
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
+
+// RPCClient returns a raw RPC client connected in-process to the L1.
+func (l *L1) RPCClient() (*rpc.Client, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &l1API{l1: l}); err != nil {
+		return nil, err
+	}
+	return rpc.DialInProc(server), nil
+}
+
+// l1API serves the eth namespace methods used by the batch submitter. The
+// pending state is the one of the pending block of the simulated backend,
+// which the L1 fills with the pool.
+type l1API struct {
+	l1 *L1
+}
+
+// callArgs are the arguments of eth_call and eth_estimateGas.
+type callArgs struct {
+	From                 *common.Address `json:"from"`
+	To                   *common.Address `json:"to"`
+	Gas                  *hexutil.Uint64 `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Data                 *hexutil.Bytes  `json:"data"`
+	Input                *hexutil.Bytes  `json:"input"`
+}
+
+// msg returns the message of the call.
+func (args *callArgs) msg() ethereum.CallMsg {
+	msg := ethereum.CallMsg{
+		To:        args.To,
+		GasPrice:  (*big.Int)(args.GasPrice),
+		GasFeeCap: (*big.Int)(args.MaxFeePerGas),
+		GasTipCap: (*big.Int)(args.MaxPriorityFeePerGas),
+		Value:     (*big.Int)(args.Value),
+	}
+	if args.From != nil {
+		msg.From = *args.From
+	}
+	if args.Gas != nil {
+		msg.Gas = uint64(*args.Gas)
+	}
+	if args.Input != nil {
+		msg.Data = *args.Input
+	} else if args.Data != nil {
+		msg.Data = *args.Data
+	}
+	return msg
+}
+
+// feeHistoryResult is the result of eth_feeHistory.
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// revertError is the error of a reverted call, carrying its revert data as
+// geth does.
+type revertError struct {
+	error
+	reason string
+}
+
+func newRevertError(result *core.ExecutionResult) *revertError {
+	err := errors.New("execution reverted")
+	if reason, errUnpack := abi.UnpackRevert(result.Revert()); errUnpack == nil {
+		err = fmt.Errorf("execution reverted: %v", reason)
+	}
+	return &revertError{
+		error:  err,
+		reason: hexutil.Encode(result.Revert()),
+	}
+}
+
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
+}
+
+// isPending returns true if blockNrOrHash is the pending block.
+func isPending(blockNrOrHash rpc.BlockNumberOrHash) bool {
+	number, ok := blockNrOrHash.Number()
+	return ok && number == rpc.PendingBlockNumber
+}
+
+// header returns the canonical header identified by blockNrOrHash, the
+// pending block is the latest one.
+func (l *L1) header(blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	chain := l.backend.Blockchain()
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := chain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, errors.New("header not found")
+		}
+		return header, nil
+	}
+
+	number, _ := blockNrOrHash.Number()
+	if number < 0 {
+		return chain.CurrentHeader(), nil
+	}
+	header := chain.GetHeaderByNumber(uint64(number))
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	return header, nil
+}
+
+// stateAt returns the state and header of the block identified by
+// blockNrOrHash.
+func (l *L1) stateAt(
+	blockNrOrHash rpc.BlockNumberOrHash,
+) (*state.StateDB, *types.Header, error) {
+
+	header, err := l.header(blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, err := l.backend.Blockchain().StateAt(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+// call executes msg on top of the state of the block with header, without
+// modifying it, as the simulated backend does for the latest block.
+func (l *L1) call(
+	msg ethereum.CallMsg,
+	statedb *state.StateDB,
+	header *types.Header,
+) (*core.ExecutionResult, error) {
+
+	if msg.GasPrice != nil && (msg.GasFeeCap != nil || msg.GasTipCap != nil) {
+		return nil, errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+	}
+	gasPrice, gasFeeCap, gasTipCap := new(big.Int), new(big.Int), new(big.Int)
+	switch {
+	case msg.GasPrice != nil:
+		gasPrice, gasFeeCap, gasTipCap = msg.GasPrice, msg.GasPrice, msg.GasPrice
+	case msg.GasFeeCap != nil || msg.GasTipCap != nil:
+		if msg.GasFeeCap != nil {
+			gasFeeCap = msg.GasFeeCap
+		}
+		if msg.GasTipCap != nil {
+			gasTipCap = msg.GasTipCap
+		}
+		gasPrice = math.BigMin(
+			new(big.Int).Add(gasTipCap, header.BaseFee), gasFeeCap,
+		)
+	}
+	if msg.Gas == 0 {
+		msg.Gas = callGas
+	}
+	if msg.Value == nil {
+		msg.Value = new(big.Int)
+	}
+	statedb.SetBalance(msg.From, math.MaxBig256)
+
+	chain := l.backend.Blockchain()
+	message := types.NewMessage(
+		msg.From, msg.To, 0, msg.Value, msg.Gas, gasPrice, gasFeeCap,
+		gasTipCap, msg.Data, nil, true,
+	)
+	evm := vm.NewEVM(
+		core.NewEVMBlockContext(header, chain, nil),
+		core.NewEVMTxContext(message), statedb, chain.Config(),
+		vm.Config{NoBaseFee: true},
+	)
+	gasPool := new(core.GasPool).AddGas(math.MaxUint64)
+	return core.NewStateTransition(evm, message, gasPool).TransitionDb()
+}
+
+// estimateGas returns the lowest gas limit msg succeeds with on top of the
+// state of the block with header, as the simulated backend does for the
+// pending block.
+func (l *L1) estimateGas(
+	msg ethereum.CallMsg,
+	blockNrOrHash rpc.BlockNumberOrHash,
+) (uint64, error) {
+
+	lo, hi := params.TxGas-1, uint64(l1GasLimit)
+	if msg.Gas >= params.TxGas {
+		hi = msg.Gas
+	}
+	executable := func(gas uint64) (*core.ExecutionResult, error) {
+		statedb, header, err := l.stateAt(blockNrOrHash)
+		if err != nil {
+			return nil, err
+		}
+		msg.Gas = gas
+		return l.call(msg, statedb, header)
+	}
+
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		result, err := executable(mid)
+		switch {
+		case errors.Is(err, core.ErrIntrinsicGas):
+			lo = mid
+		case err != nil:
+			return 0, err
+		case result.Failed():
+			lo = mid
+		default:
+			hi = mid
+		}
+	}
+
+	result, err := executable(hi)
+	if err != nil {
+		return 0, err
+	}
+	if result.Failed() {
+		if len(result.Revert()) > 0 {
+			return 0, newRevertError(result)
+		}
+		return 0, result.Err
+	}
+	return hi, nil
+}
+
+func (api *l1API) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(L1ChainID)
+}
+
+func (api *l1API) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.l1.BlockNumber())
+}
+
+func (api *l1API) GetBlockByNumber(
+	number rpc.BlockNumber,
+	fullTx bool,
+) (map[string]interface{}, error) {
+
+	l := api.l1
+	header, err := l.header(rpc.BlockNumberOrHashWithNumber(number))
+	if err != nil {
+		return nil, nil
+	}
+	block := l.backend.Blockchain().GetBlockByHash(header.Hash())
+	if block == nil {
+		return nil, nil
+	}
+	return marshalBlock(block, fullTx)
+}
+
+// marshalBlock returns the RPC representation of block.
+func marshalBlock(
+	block *types.Block,
+	fullTx bool,
+) (map[string]interface{}, error) {
+
+	var fields map[string]interface{}
+	if err := remarshal(block.Header(), &fields); err != nil {
+		return nil, err
+	}
+
+	txs := make([]interface{}, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		if fullTx {
+			txs = append(txs, tx)
+		} else {
+			txs = append(txs, tx.Hash())
+		}
+	}
+	fields["transactions"] = txs
+	fields["uncles"] = []common.Hash{}
+	fields["size"] = hexutil.Uint64(block.Size())
+	return fields, nil
+}
+
+func (api *l1API) GetBalance(
+	addr common.Address,
+	blockNrOrHash rpc.BlockNumberOrHash,
+) (*hexutil.Big, error) {
+
+	statedb, _, err := api.l1.stateAt(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(statedb.GetBalance(addr)), nil
+}
+
+func (api *l1API) GetTransactionCount(
+	addr common.Address,
+	blockNrOrHash rpc.BlockNumberOrHash,
+) (hexutil.Uint64, error) {
+
+	l := api.l1
+	statedb, _, err := l.stateAt(blockNrOrHash)
+	if err != nil {
+		return 0, err
+	}
+	nonce := statedb.GetNonce(addr)
+	if !isPending(blockNrOrHash) {
+		return hexutil.Uint64(nonce), nil
+	}
+
+	// Like geth, the pending nonce counts every queued transaction without a
+	// gap, whether or not it pays the base fee.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for {
+		if _, ok := l.pool[addr][nonce]; !ok {
+			return hexutil.Uint64(nonce), nil
+		}
+		nonce++
+	}
+}
+
+func (api *l1API) GetCode(
+	addr common.Address,
+	blockNrOrHash rpc.BlockNumberOrHash,
+) (hexutil.Bytes, error) {
+
+	statedb, _, err := api.l1.stateAt(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(addr), nil
+}
+
+func (api *l1API) Call(
+	args callArgs,
+	blockNrOrHash rpc.BlockNumberOrHash,
+) (hexutil.Bytes, error) {
+
+	l := api.l1
+	if isPending(blockNrOrHash) {
+		return l.backend.PendingCallContract(context.Background(), args.msg())
+	}
+
+	statedb, header, err := l.stateAt(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	result, err := l.call(args.msg(), statedb, header)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Revert()) > 0 {
+		return nil, newRevertError(result)
+	}
+	return result.Return(), result.Err
+}
+
+func (api *l1API) EstimateGas(
+	args callArgs,
+	blockNrOrHash *rpc.BlockNumberOrHash,
+) (hexutil.Uint64, error) {
+
+	// Like recent geth versions and most providers, the estimate is made
+	// against the latest block unless another one is given.
+	l := api.l1
+	if blockNrOrHash != nil && isPending(*blockNrOrHash) {
+		gas, err := l.backend.EstimateGas(context.Background(), args.msg())
+		return hexutil.Uint64(gas), err
+	}
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash == nil {
+		blockNrOrHash = &latest
+	}
+	gas, err := l.estimateGas(args.msg(), *blockNrOrHash)
+	return hexutil.Uint64(gas), err
+}
+
+func (api *l1API) GasPrice() *hexutil.Big {
+	l := api.l1
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return (*hexutil.Big)(new(big.Int).Add(l.BaseFee(), l.tipCap))
+}
+
+func (api *l1API) MaxPriorityFeePerGas() *hexutil.Big {
+	l := api.l1
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return (*hexutil.Big)(new(big.Int).Set(l.tipCap))
+}
+
+// FeeHistory reports the priority fees paid in the blocks as geth does, the
+// percentiles weighting the transactions by the gas they use. The last base
+// fee is the one of the next block.
+func (api *l1API) FeeHistory(
+	blockCount rpc.DecimalOrHex,
+	lastBlock rpc.BlockNumber,
+	rewardPercentiles []float64,
+) (*feeHistoryResult, error) {
+
+	l := api.l1
+	chain := l.backend.Blockchain()
+	last, err := l.header(rpc.BlockNumberOrHashWithNumber(lastBlock))
+	if err != nil {
+		return nil, fmt.Errorf("block %d not found", lastBlock)
+	}
+	count := uint64(blockCount)
+	if count > last.Number.Uint64()+1 {
+		count = last.Number.Uint64() + 1
+	}
+	oldest := last.Number.Uint64() + 1 - count
+
+	result := &feeHistoryResult{
+		OldestBlock: (*hexutil.Big)(new(big.Int).SetUint64(oldest)),
+	}
+	for number := oldest; number <= last.Number.Uint64(); number++ {
+		block := chain.GetBlockByNumber(number)
+		receipts := chain.GetReceiptsByHash(block.Hash())
+
+		result.Reward = append(result.Reward,
+			blockRewards(block, receipts, rewardPercentiles))
+		result.BaseFee = append(result.BaseFee,
+			(*hexutil.Big)(block.BaseFee()))
+		result.GasUsedRatio = append(result.GasUsedRatio,
+			float64(block.GasUsed())/float64(block.GasLimit()))
+	}
+
+	next := chain.GetHeaderByNumber(last.Number.Uint64() + 1)
+	if next != nil {
+		result.BaseFee = append(result.BaseFee, (*hexutil.Big)(next.BaseFee))
+	} else {
+		result.BaseFee = append(result.BaseFee, (*hexutil.Big)(
+			misc.CalcBaseFee(params.AllEthashProtocolChanges, last)))
+	}
+	if len(rewardPercentiles) == 0 {
+		result.Reward = nil
+	}
+	return result, nil
+}
+
+// blockRewards returns the priority fees paid in block at the percentiles of
+// its gas used.
+func blockRewards(
+	block *types.Block,
+	receipts types.Receipts,
+	percentiles []float64,
+) []*hexutil.Big {
+
+	type txReward struct {
+		gasUsed uint64
+		reward  *big.Int
+	}
+	rewards := make([]*hexutil.Big, len(percentiles))
+	txs := block.Transactions()
+	if len(txs) == 0 || len(receipts) != len(txs) {
+		for i := range rewards {
+			rewards[i] = (*hexutil.Big)(new(big.Int))
+		}
+		return rewards
+	}
+
+	sorted := make([]txReward, len(txs))
+	for i, tx := range txs {
+		reward, _ := tx.EffectiveGasTip(block.BaseFee())
+		sorted[i] = txReward{gasUsed: receipts[i].GasUsed, reward: reward}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].reward.Cmp(sorted[j].reward) < 0
+	})
+
+	var txIndex int
+	sumGasUsed := sorted[0].gasUsed
+	for i, p := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * p / 100)
+		for sumGasUsed < threshold && txIndex < len(sorted)-1 {
+			txIndex++
+			sumGasUsed += sorted[txIndex].gasUsed
+		}
+		rewards[i] = (*hexutil.Big)(sorted[txIndex].reward)
+	}
+	return rewards
+}
+
+func (api *l1API) SendRawTransaction(input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.l1.sendTransaction(tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+func (api *l1API) GetTransactionReceipt(hash common.Hash) *types.Receipt {
+	return api.l1.Receipt(hash)
+}
+
+func (api *l1API) GetLogs(crit filters.FilterCriteria) ([]types.Log, error) {
+	logs, err := api.l1.backend.FilterLogs(
+		context.Background(), ethereum.FilterQuery(crit),
+	)
+	if logs == nil {
+		logs = []types.Log{}
+	}
+	return logs, err
+}
@@ -0,0 +1,222 @@
+package sim
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/mantlenetworkio/mantle/l2geth/common"
+	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
+	"github.com/mantlenetworkio/mantle/l2geth/core/types"
+	"github.com/mantlenetworkio/mantle/l2geth/crypto"
+	"github.com/mantlenetworkio/mantle/l2geth/ethclient"
+	"github.com/mantlenetworkio/mantle/l2geth/rlp"
+	"github.com/mantlenetworkio/mantle/l2geth/rpc"
+)
+
+const (
+	// genesisTime is the timestamp of the L2 genesis block.
+	genesisTime = 1_700_000_000
+
+	// l2GasLimit is the gas limit of every L2 block.
+	l2GasLimit = 15_000_000
+
+	// l2TxGas is the gas limit of the L2 transactions.
+	l2TxGas = 21_000
+)
+
+// L2 is an in-memory L2 chain of blocks holding a single sequencer
+// transaction, with deterministic transactions and state roots.
+type L2 struct {
+	chainID *big.Int
+	key     *ecdsa.PrivateKey
+	signer  types.Signer
+
+	mu     sync.Mutex
+	blocks []*types.Block
+}
+
+// NewL2 creates an L2 chain made of the genesis block.
+func NewL2(chainID *big.Int) *L2 {
+	genesis := types.NewBlockWithHeader(&types.Header{
+		Difficulty: new(big.Int),
+		Number:     new(big.Int),
+		GasLimit:   l2GasLimit,
+		Time:       genesisTime,
+		Root:       l2StateRoot(0),
+	})
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("sim l2 sender")))
+
+	return &L2{
+		chainID: chainID,
+		key:     key,
+		signer:  types.NewEIP155Signer(chainID),
+		blocks:  []*types.Block{genesis},
+	}
+}
+
+// l2StateRoot returns the state root of the L2 block at number.
+func l2StateRoot(number uint64) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte("sim l2 state root"), new(big.Int).SetUint64(number).Bytes(),
+	)
+}
+
+// AddBlocks appends n blocks to the chain, each holding one sequencer
+// transaction, and returns them.
+func (l *L2) AddBlocks(n int) []*types.Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	added := make([]*types.Block, 0, n)
+	for i := 0; i < n; i++ {
+		parent := l.blocks[len(l.blocks)-1]
+		number := parent.NumberU64() + 1
+
+		to := common.BigToAddress(new(big.Int).SetUint64(number))
+		tx, err := types.SignTx(types.NewTransaction(
+			number-1, to, big.NewInt(1), l2TxGas, big.NewInt(1), nil,
+		), l.signer, l.key)
+		if err != nil {
+			panic(err)
+		}
+		raw, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			panic(err)
+		}
+		index := number - 1
+		tx.SetTransactionMeta(types.NewTransactionMeta(
+			new(big.Int).SetUint64(number), parent.Time()+1, nil,
+			types.QueueOriginSequencer, &index, nil, raw,
+		))
+
+		block := types.NewBlock(&types.Header{
+			ParentHash: parent.Hash(),
+			Difficulty: big.NewInt(1),
+			Number:     new(big.Int).SetUint64(number),
+			GasLimit:   l2GasLimit,
+			GasUsed:    l2TxGas,
+			Time:       parent.Time() + 1,
+			Root:       l2StateRoot(number),
+		}, []*types.Transaction{tx}, nil, nil)
+
+		l.blocks = append(l.blocks, block)
+		added = append(added, block)
+	}
+	return added
+}
+
+// Block returns the block at number, or nil if there is none.
+func (l *L2) Block(number uint64) *types.Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if number >= uint64(len(l.blocks)) {
+		return nil
+	}
+	return l.blocks[number]
+}
+
+// Client returns a client connected in-process to the L2.
+func (l *L2) Client() (*ethclient.Client, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &l2API{l2: l}); err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpc.DialInProc(server)), nil
+}
+
+// l2API serves the eth namespace methods used by the batch submitter, in the
+// format of l2geth.
+type l2API struct {
+	l2 *L2
+}
+
+func (api *l2API) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(api.l2.chainID)
+}
+
+func (api *l2API) BlockNumber() hexutil.Uint64 {
+	l := api.l2
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return hexutil.Uint64(len(l.blocks) - 1)
+}
+
+func (api *l2API) GetBlockByNumber(
+	number rpc.BlockNumber,
+	fullTx bool,
+) (map[string]interface{}, error) {
+
+	l := api.l2
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var block *types.Block
+	switch {
+	case number < 0:
+		block = l.blocks[len(l.blocks)-1]
+	case int(number) < len(l.blocks):
+		block = l.blocks[number]
+	default:
+		return nil, nil
+	}
+	return l.marshalBlock(block, fullTx)
+}
+
+// marshalBlock returns the l2geth RPC representation of block, with the
+// transaction metadata the batch submitter reads.
+func (l *L2) marshalBlock(
+	block *types.Block,
+	fullTx bool,
+) (map[string]interface{}, error) {
+
+	var fields map[string]interface{}
+	if err := remarshal(block.Header(), &fields); err != nil {
+		return nil, err
+	}
+
+	txs := make([]interface{}, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if !fullTx {
+			txs = append(txs, tx.Hash())
+			continue
+		}
+
+		var txFields map[string]interface{}
+		if err := remarshal(tx, &txFields); err != nil {
+			return nil, err
+		}
+		from, err := types.Sender(l.signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		meta := tx.GetMeta()
+		txFields["from"] = from
+		txFields["blockHash"] = block.Hash()
+		txFields["blockNumber"] = (*hexutil.Big)(block.Number())
+		txFields["transactionIndex"] = hexutil.Uint64(i)
+		txFields["l1BlockNumber"] = (*hexutil.Big)(meta.L1BlockNumber)
+		txFields["l1Timestamp"] = hexutil.Uint64(meta.L1Timestamp)
+		txFields["l1MessageSender"] = meta.L1MessageSender
+		txFields["queueOrigin"] = meta.QueueOrigin.String()
+		txFields["index"] = (*hexutil.Uint64)(meta.Index)
+		txFields["queueIndex"] = (*hexutil.Uint64)(meta.QueueIndex)
+		txFields["rawTransaction"] = hexutil.Bytes(meta.RawTransaction)
+		txs = append(txs, txFields)
+	}
+	fields["transactions"] = txs
+	fields["uncles"] = []common.Hash{}
+	return fields, nil
+}
+
+// remarshal converts v to its JSON representation decoded into out.
+func remarshal(v interface{}, out interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, out)
+}
@@ -0,0 +1,381 @@
+package sim
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/batch-submitter/drivers/sequencer"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+)
+
+// submitTimeout bounds the time taken to submit every batch of a test.
+const submitTimeout = 20 * time.Second
+
+func newTestHarness(t *testing.T, cfg Config) *Harness {
+	h, err := NewHarness(cfg)
+	require.Nil(t, err)
+	t.Cleanup(h.Stop)
+	return h
+}
+
+// submitted returns true if numBlocks L2 blocks are covered by both the CTC
+// and the SCC.
+func submitted(h *Harness, numBlocks int) bool {
+	total, err := h.L1.TotalElements()
+	if err != nil || total != uint64(numBlocks) {
+		return false
+	}
+	roots, err := h.L1.StateRoots()
+	return err == nil && len(roots) == numBlocks
+}
+
+// waitSubmitted waits until numBlocks L2 blocks are covered by both the CTC
+// and the SCC.
+func waitSubmitted(t *testing.T, h *Harness, numBlocks int) {
+	require.Eventually(t, func() bool {
+		return submitted(h, numBlocks)
+	}, submitTimeout, 10*time.Millisecond)
+}
+
+// totalElements returns the number of L2 transactions in the CTC.
+func totalElements(t *testing.T, h *Harness) uint64 {
+	total, err := h.L1.TotalElements()
+	require.Nil(t, err)
+	return total
+}
+
+// sequencerBatches returns the batches appended to the CTC.
+func sequencerBatches(t *testing.T, h *Harness) []SequencerBatch {
+	batches, err := h.L1.SequencerBatches()
+	require.Nil(t, err)
+	return batches
+}
+
+// requireBatches asserts that the L2 blocks up to numBlocks were posted in
+// consecutive batches of batchSize blocks, with their exact transactions and
+// state roots.
+func requireBatches(t *testing.T, h *Harness, numBlocks, batchSize int) {
+	batches := sequencerBatches(t, h)
+	require.Len(t, batches, numBlocks/batchSize)
+	for i, batch := range batches {
+		start := i * batchSize
+		require.Equal(t, uint64(i), batch.Index)
+		require.Equal(t, uint64(start), batch.Params.ShouldStartAtElement)
+		require.Equal(t, uint64(batchSize), batch.Params.TotalElementsToAppend)
+		require.Len(t, batch.Params.Txs, batchSize)
+		last := batch.Params.Contexts[len(batch.Params.Contexts)-1]
+		require.Equal(t, last.Timestamp, batch.LastTimestamp)
+		require.Equal(t, last.BlockNumber, batch.LastBlockNumber)
+		for j, tx := range batch.Params.Txs {
+			block := h.L2.Block(uint64(start + j + 1))
+			require.Equal(t, block.Transactions()[0].Hash(), tx.Tx().Hash())
+		}
+	}
+
+	stateBatches, err := h.L1.StateBatches()
+	require.Nil(t, err)
+	require.Len(t, stateBatches, numBlocks/batchSize)
+	for i, batch := range stateBatches {
+		start := i * batchSize
+		require.Equal(t, uint64(i), batch.Index)
+		require.Equal(t, uint64(start), batch.PrevTotalElements)
+		require.Len(t, batch.StateRoots, batchSize)
+		for j, root := range batch.StateRoots {
+			block := h.L2.Block(uint64(start + j + 1))
+			require.Equal(t, [32]byte(block.Root()), root)
+		}
+	}
+
+	assertions, err := h.L1.Assertions()
+	require.Nil(t, err)
+	require.Len(t, assertions, len(stateBatches)+1)
+	last := h.L2.Block(uint64(numBlocks))
+	require.Equal(t, [32]byte(last.Root()), assertions[len(assertions)-1].StateHash)
+	require.Equal(t, uint64(numBlocks), assertions[len(assertions)-1].InboxSize)
+}
+
+// numFailed returns the number of receipts of reverted transactions.
+func numFailed(receipts []*types.Receipt) int {
+	var failed int
+	for _, receipt := range receipts {
+		if receipt.Status == types.ReceiptStatusFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// TestHarnessSubmitsBatches asserts that the sequencer and proposer post every
+// L2 block in full batches, including batches added while they run.
+func TestHarnessSubmitsBatches(t *testing.T) {
+	h := newTestHarness(t, Config{
+		BatchType: sequencer.BatchTypeZlib,
+	})
+	h.L2.AddBlocks(10)
+	require.Nil(t, h.Start())
+	waitSubmitted(t, h, 10)
+
+	// Partial batches are left until they fill up.
+	h.L2.AddBlocks(3)
+	time.Sleep(10 * DefaultBlockTime)
+	require.Equal(t, uint64(10), totalElements(t, h))
+
+	h.L2.AddBlocks(7)
+	waitSubmitted(t, h, 20)
+	requireBatches(t, h, 20, DefaultBatchSize)
+
+	require.Zero(t, numFailed(h.L1.Receipts(h.SequencerAddr)))
+	require.Zero(t, numFailed(h.L1.Receipts(h.ProposerAddr)))
+	require.Len(t, h.Tss.Requests(), 4)
+}
+
+// TestHarnessPipelinesBatches asserts that batches kept in flight at
+// consecutive nonces are posted in order.
+func TestHarnessPipelinesBatches(t *testing.T) {
+	h := newTestHarness(t, Config{
+		BatchType:     sequencer.BatchTypeZlib,
+		MaxPendingTxs: 3,
+	})
+	h.L2.AddBlocks(30)
+	require.Nil(t, h.Start())
+	waitSubmitted(t, h, 30)
+	requireBatches(t, h, 30, DefaultBatchSize)
+}
+
+// TestHarnessPipelinesBatchesInPool asserts that batches after one still in
+// the pool are crafted against the pending state, since they revert on top of
+// the latest block.
+func TestHarnessPipelinesBatchesInPool(t *testing.T) {
+	h := newTestHarness(t, Config{
+		MaxPendingTxs: 3,
+		// Blocks are mined by the test, so the batches stay in the pool.
+		BlockTime: time.Hour,
+	})
+	h.L2.AddBlocks(15)
+	require.Nil(t, h.Start())
+
+	require.Eventually(t, func() bool {
+		return h.L1.Pending(h.SequencerAddr) == 3
+	}, submitTimeout, time.Millisecond)
+	require.Eventually(t, func() bool {
+		h.L1.Mine()
+		return submitted(h, 15)
+	}, submitTimeout, 10*time.Millisecond)
+	requireBatches(t, h, 15, DefaultBatchSize)
+	require.Zero(t, numFailed(h.L1.Receipts(h.SequencerAddr)))
+}
+
+// TestHarnessResubmitsRevertedBatches asserts that reverted batches are crafted
+// and sent again.
+func TestHarnessResubmitsRevertedBatches(t *testing.T) {
+	h := newTestHarness(t, Config{})
+	h.L1.RevertNext(h.L1.Contracts.CTC, 1)
+	h.L1.RevertNext(h.L1.Contracts.Rollup, 1)
+	h.L2.AddBlocks(10)
+	require.Nil(t, h.Start())
+	waitSubmitted(t, h, 10)
+	requireBatches(t, h, 10, DefaultBatchSize)
+
+	require.Equal(t, 1, numFailed(h.L1.Receipts(h.SequencerAddr)))
+	require.Equal(t, 1, numFailed(h.L1.Receipts(h.ProposerAddr)))
+}
+
+// TestHarnessRecoversFromNonceTooLow asserts that a batch whose nonce was taken
+// by another transaction is abandoned and crafted again at the next nonce.
+func TestHarnessRecoversFromNonceTooLow(t *testing.T) {
+	h := newTestHarness(t, Config{
+		SafeAbortNonceTooLowCount: 2,
+	})
+	h.L1.BurnNonceOnNext(h.L1.Contracts.CTC, h.sequencerKey)
+	h.L1.BurnNonceOnNext(h.L1.Contracts.Rollup, h.proposerKey)
+	h.L2.AddBlocks(10)
+	require.Nil(t, h.Start())
+	waitSubmitted(t, h, 10)
+	requireBatches(t, h, 10, DefaultBatchSize)
+
+	// The first batch of each driver was dropped, and its nonce skipped.
+	for _, receipts := range [][]*types.Receipt{
+		h.L1.Receipts(h.SequencerAddr), h.L1.Receipts(h.ProposerAddr),
+	} {
+		require.Len(t, receipts, 2)
+		require.Zero(t, numFailed(receipts))
+	}
+	require.Equal(t, uint64(1), h.L1.Transaction(
+		h.L1.Receipts(h.SequencerAddr)[0].TxHash).Nonce())
+}
+
+// TestHarnessBumpsFeesOnSpike asserts that batches left underpriced by a base
+// fee spike are replaced with bumped fees.
+func TestHarnessBumpsFeesOnSpike(t *testing.T) {
+	h := newTestHarness(t, Config{
+		Fees: &txmgr.FeeConfig{
+			ForecastBlocks: 1,
+		},
+	})
+	h.L1.SpikeBaseFeeOnNext(h.L1.Contracts.CTC, 10)
+	h.L2.AddBlocks(10)
+	require.Nil(t, h.Start())
+	waitSubmitted(t, h, 10)
+	requireBatches(t, h, 10, DefaultBatchSize)
+
+	// The first batch was underpriced by the spike, and sent again with a
+	// fee cap covering the base fee of the block it was mined in.
+	sent := h.L1.Sent(h.SequencerAddr)
+	receipts := h.L1.Receipts(h.SequencerAddr)
+	require.Greater(t, len(sent), len(receipts))
+	first := h.L1.Transaction(receipts[0].TxHash)
+	require.Equal(t, uint64(0), first.Nonce())
+	require.NotEqual(t, sent[0].Hash(), first.Hash())
+	baseFee := h.L1.Header(receipts[0].BlockNumber.Uint64()).BaseFee
+	require.Negative(t, sent[0].GasFeeCap().Cmp(baseFee))
+	require.GreaterOrEqual(t, first.GasFeeCap().Cmp(baseFee), 0)
+}
+
+// TestHarnessResubmitsReorgedBatches asserts that batches dropped by an L1
+// reorg are submitted again.
+func TestHarnessResubmitsReorgedBatches(t *testing.T) {
+	h := newTestHarness(t, Config{})
+	h.L2.AddBlocks(20)
+	require.Nil(t, h.Start())
+	waitSubmitted(t, h, 20)
+
+	// Drop the blocks from the one holding the third sequencer batch.
+	batches := sequencerBatches(t, h)
+	reorged := h.L1.Header(batches[2].BlockNumber)
+	depth := int(h.L1.BlockNumber()-batches[2].BlockNumber) + 1
+	require.Nil(t, h.L1.Reorg(depth, true))
+	require.Less(t, totalElements(t, h), uint64(20))
+
+	waitSubmitted(t, h, 20)
+	requireBatches(t, h, 20, DefaultBatchSize)
+	require.NotEqual(t, reorged.Hash(),
+		h.L1.Header(reorged.Number.Uint64()).Hash())
+}
+
+// reorgOnNextBatch waits for the first sequencer batch to be confirmed, then
+// reorgs its block out, dropping its transaction, once the next batch is sent.
+func reorgOnNextBatch(t *testing.T, h *Harness) {
+	waitSubmitted(t, h, DefaultBatchSize)
+	require.Eventually(t, func() bool {
+		submitted := h.Sequencer.Metrics().BatchesSubmitted()
+		return testutil.ToFloat64(submitted) == 1
+	}, submitTimeout, 10*time.Millisecond)
+
+	batch := sequencerBatches(t, h)[0]
+	h.L1.ReorgOnNext(h.L1.Contracts.CTC, batch.BlockNumber, true)
+}
+
+// TestHarnessResubmitsBatchesReorgedOut asserts that a confirmed batch reorged
+// out of L1 within the reorg depth is detected, abandoning the batch sent after
+// it, and submitted again.
+func TestHarnessResubmitsBatchesReorgedOut(t *testing.T) {
+	h := newTestHarness(t, Config{
+		ReorgDepth: 30,
+	})
+	h.L2.AddBlocks(5)
+	require.Nil(t, h.Start())
+	reorgOnNextBatch(t, h)
+
+	h.L2.AddBlocks(5)
+	waitSubmitted(t, h, 10)
+	requireBatches(t, h, 10, DefaultBatchSize)
+
+	metrics := h.Sequencer.Metrics()
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.BatchReorgs()))
+	require.GreaterOrEqual(t, testutil.ToFloat64(metrics.BatchReorgDepth()), 1.0)
+}
+
+// TestHarnessAbandonsPipelinedBatchesReorgedOut asserts that the batches in
+// flight after a batch reorged out of L1 are abandoned and submitted again.
+func TestHarnessAbandonsPipelinedBatchesReorgedOut(t *testing.T) {
+	h := newTestHarness(t, Config{
+		MaxPendingTxs: 3,
+		ReorgDepth:    30,
+	})
+	h.L2.AddBlocks(5)
+	require.Nil(t, h.Start())
+	reorgOnNextBatch(t, h)
+
+	h.L2.AddBlocks(15)
+	waitSubmitted(t, h, 20)
+	requireBatches(t, h, 20, DefaultBatchSize)
+
+	require.Equal(t, 1.0,
+		testutil.ToFloat64(h.Sequencer.Metrics().BatchReorgs()))
+}
+
+// TestHarnessResubmitsBatchesReorgedBeforeConfirmation asserts that a batch
+// reorged out of L1 while it waits for confirmations is sent again.
+func TestHarnessResubmitsBatchesReorgedBeforeConfirmation(t *testing.T) {
+	h := newTestHarness(t, Config{
+		NumConfirmations: 6,
+		ReorgDepth:       10,
+	})
+	h.L2.AddBlocks(10)
+	require.Nil(t, h.Start())
+
+	require.Eventually(t, func() bool {
+		total, err := h.L1.TotalElements()
+		return err == nil && total > 0
+	}, submitTimeout, time.Millisecond)
+	batch := sequencerBatches(t, h)[0]
+	depth := int(h.L1.BlockNumber()-batch.BlockNumber) + 1
+	require.Nil(t, h.L1.Reorg(depth, true))
+
+	waitSubmitted(t, h, 10)
+	requireBatches(t, h, 10, DefaultBatchSize)
+
+	// The batch was not confirmed before being reorged out.
+	require.Zero(t, testutil.ToFloat64(h.Sequencer.Metrics().BatchReorgs()))
+}
+
+// TestHarnessResumesJournaledBatches asserts that a service restarted while a
+// batch is in flight resumes it from the journal, even when it is not
+// configured to clear pending transactions, instead of crafting it again.
+func TestHarnessResumesJournaledBatches(t *testing.T) {
+	dir := t.TempDir()
+	h := newTestHarness(t, Config{
+		JournalDir:     dir,
+		ClearPendingTx: false,
+		// Blocks are mined by the test, so the first batch stays in the
+		// pool across the restart.
+		BlockTime: time.Hour,
+	})
+	h.L2.AddBlocks(5)
+	require.Nil(t, h.Start())
+
+	require.Eventually(t, func() bool {
+		return h.L1.Pending(h.SequencerAddr) == 1
+	}, submitTimeout, time.Millisecond)
+	journaled := h.L1.Sent(h.SequencerAddr)[0]
+
+	require.Nil(t, h.Restart())
+	require.Eventually(t, func() bool {
+		h.L1.Mine()
+		return submitted(h, 5)
+	}, submitTimeout, 10*time.Millisecond)
+	requireBatches(t, h, 5, DefaultBatchSize)
+
+	// The journaled transaction was watched until it confirmed, and was
+	// neither crafted again nor replaced.
+	require.Eventually(t, func() bool {
+		h.L1.Mine()
+		submitted := h.Sequencer.Metrics().BatchesSubmitted()
+		return testutil.ToFloat64(submitted) == 1
+	}, submitTimeout, 10*time.Millisecond)
+	receipts := h.L1.Receipts(h.SequencerAddr)
+	require.Len(t, receipts, 1)
+	require.Equal(t, journaled.Hash(), receipts[0].TxHash)
+	require.Len(t, h.L1.Sent(h.SequencerAddr), 1)
+
+	entries, err := journal.Read(filepath.Join(dir, "sequencer.journal"))
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, journal.StatusConfirmed, entries[0].Status)
+}
@@ -0,0 +1,96 @@
+package sim
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	tss "github.com/mantlenetworkio/mantle/batch-submitter/tss-client"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+)
+
+// TssManager is a stub tss manager signing state batches with the key of the
+// tss group.
+type TssManager struct {
+	*httptest.Server
+
+	key *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	failures int
+	requests []tsscommon.SignStateRequest
+}
+
+// NewTssManager starts a tss manager signing with key.
+func NewTssManager(key *ecdsa.PrivateKey) *TssManager {
+	m := &TssManager{
+		key: key,
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	return m
+}
+
+// GroupKey returns the public key of the tss group as stored by the
+// TssGroupManager contract, without the uncompressed prefix.
+func (m *TssManager) GroupKey() []byte {
+	return crypto.FromECDSAPub(&m.key.PublicKey)[1:]
+}
+
+// FailNext makes the next n signature requests fail.
+func (m *TssManager) FailNext(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures += n
+}
+
+// Requests returns the signature requests received, including failed ones.
+func (m *TssManager) Requests() []tsscommon.SignStateRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]tsscommon.SignStateRequest(nil), m.requests...)
+}
+
+func (m *TssManager) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ping" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var request tsscommon.SignStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, request)
+	fail := m.failures > 0
+	if fail {
+		m.failures--
+	}
+	m.mu.Unlock()
+	if fail {
+		http.Error(w, "failed to sign state", http.StatusInternalServerError)
+		return
+	}
+
+	digest, err := tss.SignedDigest(request, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signature, err := crypto.Sign(digest, m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The tss network returns recovery ids of 27 and 28.
+	signature[crypto.RecoveryIDOffset] += 27
+	_ = json.NewEncoder(w).Encode(tss.TssResponse{Signature: signature})
+}
@@ -1,28 +1,52 @@
 package tss_client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/go-resty/resty/v2"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
-	"github.com/mantlenetworkio/mantle/tss/common"
-	"github.com/syndtr/goleveldb/leveldb/errors"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
 )
 
 const (
 	JwtSecretLength = 32
+
+	// IdempotencyKeyHeader is the header identifying a signature request to
+	// the tss managers.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// DefaultRetryBackoff is the delay before retrying a request that failed
+	// on every tss manager, doubled after each retry.
+	DefaultRetryBackoff = time.Second
+
+	// maxRetryBackoff caps the delay between retries.
+	maxRetryBackoff = 30 * time.Second
+
+	// healthCheckTimeout bounds each health check of a tss manager.
+	healthCheckTimeout = 5 * time.Second
+
+	// maxCachedSignatures is the number of state batch signatures kept to
+	// answer later requests for the same batch.
+	maxCachedSignatures = 16
 )
 
-var errTssHTTPError = errors.New("tss http error")
+var (
+	errTssHTTPError = errors.New("tss http error")
 
-type TssClient interface {
-	GetSignStateBatch(BatchData common.SignStateRequest) ([]byte, error)
-}
+	// ErrNoEndpoints signals that no tss manager url was configured.
+	ErrNoEndpoints = errors.New("no tss manager url")
+)
 
-type Client struct {
-	client *resty.Client
+type TssClient interface {
+	GetSignStateBatch(ctx context.Context, BatchData tsscommon.SignStateRequest) ([]byte, error)
+	Prefetch(BatchData tsscommon.SignStateRequest)
 }
 
 type TssResponse struct {
@@ -30,57 +54,320 @@ type TssResponse struct {
 	RollBack  bool   `json:"roll_back"`
 }
 
-func NewClient(url string, jwtSecretStr string) (*Client, error) {
-	client := resty.New()
-	client.SetHostURL(url)
-	if len(jwtSecretStr) != 0 {
-		jwtSecret, err := hexutil.Decode(jwtSecretStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid jwt secret %s", err.Error())
+// Config configures a Client.
+type Config struct {
+	// URLs are the tss managers signature requests are sent to, healthy
+	// managers are tried first in the given order.
+	URLs []string
+
+	// JwtSecret is the hex encoded secret authenticating the requests, they
+	// are not authenticated if empty.
+	JwtSecret string
+
+	// MaxRetries is the number of times a failed request is sent again,
+	// failing over to the next tss manager.
+	MaxRetries uint64
+
+	// RetryBackoff is the delay before retrying a request that failed on
+	// every tss manager, DefaultRetryBackoff is used if zero.
+	RetryBackoff time.Duration
+
+	// HealthCheckInterval is how often the tss managers are checked, health
+	// checks are disabled if zero.
+	HealthCheckInterval time.Duration
+
+	// Verifier checks the returned signatures before they are used, they are
+	// not checked if nil.
+	Verifier Verifier
+}
+
+// Client requests state batch signatures from a set of tss managers. Requests
+// are identified by an idempotency key, so that concurrent or repeated requests
+// for the same batch share a single signature.
+type Client struct {
+	cfg       Config
+	endpoints []*endpoint
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	requests map[string]*signRequest
+	signed   []string
+}
+
+// signRequest is a signature request sent to the tss managers.
+type signRequest struct {
+	done     chan struct{}
+	response []byte
+	rollback bool
+	err      error
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+
+	jwtSecret, err := parseJwtSecret(cfg.JwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]*endpoint, 0, len(cfg.URLs))
+	for _, url := range cfg.URLs {
+		endpoints = append(endpoints, newEndpoint(url, jwtSecret))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		cfg:       cfg,
+		endpoints: endpoints,
+		ctx:       ctx,
+		cancel:    cancel,
+		requests:  make(map[string]*signRequest),
+	}
+	if cfg.HealthCheckInterval != 0 {
+		c.wg.Add(1)
+		go c.healthCheckLoop()
+	}
+	return c, nil
+}
+
+// Close stops the health checks and abandons the requests in flight.
+func (c *Client) Close() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// IdempotencyKey identifies a signature request by its type, start block and
+// the merkle root of its state roots.
+func IdempotencyKey(BatchData tsscommon.SignStateRequest) string {
+	var root [32]byte
+	if len(BatchData.StateRoots) > 0 {
+		// GetMerkleRoot modifies the elements it is given.
+		elements := make([][32]byte, len(BatchData.StateRoots))
+		copy(elements, BatchData.StateRoots)
+		root, _ = tsscommon.GetMerkleRoot(elements)
+	}
+
+	key := fmt.Sprintf("%d-%s-%s", BatchData.Type, BatchData.StartBlock,
+		hexutil.Encode(root[:]))
+	if BatchData.Challenge != "" {
+		key += "-" + strings.ToLower(BatchData.Challenge)
+	}
+	return key
+}
+
+// GetSignStateBatch returns the tss manager response signing BatchData. The
+// request is retried with a backoff, failing over between the tss managers,
+// until a verified signature is returned or the retries are exhausted.
+func (c *Client) GetSignStateBatch(
+	ctx context.Context,
+	BatchData tsscommon.SignStateRequest,
+) ([]byte, error) {
+
+	req := c.request(BatchData)
+	select {
+	case <-req.done:
+		return req.response, req.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Prefetch requests the signature of BatchData in the background, so that a
+// later GetSignStateBatch for the same batch returns without waiting.
+func (c *Client) Prefetch(BatchData tsscommon.SignStateRequest) {
+	c.request(BatchData)
+}
+
+// request returns the request in flight or completed for BatchData, sending it
+// if there is none.
+func (c *Client) request(BatchData tsscommon.SignStateRequest) *signRequest {
+	key := IdempotencyKey(BatchData)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req, ok := c.requests[key]; ok {
+		return req
+	}
+	req := &signRequest{
+		done: make(chan struct{}),
+	}
+	c.requests[key] = req
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		req.response, req.rollback, req.err = c.sign(key, BatchData)
+		c.settle(key, req)
+		close(req.done)
+	}()
+	return req
+}
+
+// settle forgets a completed request unless it returned a state batch
+// signature, so that failures and rollback signals are requested again. Only
+// the latest signatures are kept.
+func (c *Client) settle(key string, req *signRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.err != nil || req.rollback {
+		delete(c.requests, key)
+		return
+	}
+	c.signed = append(c.signed, key)
+	if len(c.signed) > maxCachedSignatures {
+		delete(c.requests, c.signed[0])
+		c.signed = c.signed[1:]
+	}
+}
+
+// sign sends a signature request to the tss managers until one of them returns
+// a verified signature, backing off each time every manager failed.
+func (c *Client) sign(
+	key string,
+	BatchData tsscommon.SignStateRequest,
+) ([]byte, bool, error) {
+
+	var (
+		tried   = make(map[*endpoint]bool)
+		backoff = c.cfg.RetryBackoff
+		lastErr error
+	)
+	for attempt := uint64(0); attempt <= c.cfg.MaxRetries; attempt++ {
+		e := c.pick(tried)
+		if e == nil {
+			select {
+			case <-time.After(backoff):
+			case <-c.ctx.Done():
+				return nil, false, c.ctx.Err()
+			}
+			backoff *= 2
+			if backoff > maxRetryBackoff {
+				backoff = maxRetryBackoff
+			}
+			tried = make(map[*endpoint]bool)
+			e = c.pick(tried)
 		}
-		if len(jwtSecret) != JwtSecretLength {
-			return nil, fmt.Errorf("invalid jwt secret length, expected length %d, actual length %d",
-				JwtSecretLength, len(jwtSecret))
+		tried[e] = true
+
+		response, rollback, err := c.signWith(e, key, BatchData)
+		if err == nil {
+			return response, rollback, nil
 		}
-		client.SetAuthScheme("Bearer")
-		client.OnBeforeRequest(func(c *resty.Client, r *resty.Request) error {
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-				"iat": &jwt.NumericDate{Time: time.Now()},
-			})
-			s, err := token.SignedString(jwtSecret[:])
-			if err != nil {
-				return fmt.Errorf("failed to create JWT token: %w", err)
-			}
-			r.Token = s
-			return nil
-		})
-	}
-	client.OnAfterResponse(func(c *resty.Client, r *resty.Response) error {
-		statusCode := r.StatusCode()
-		if statusCode >= 400 {
-			method := r.Request.Method
-			url := r.Request.URL
-			return fmt.Errorf("%d cannot %s %s: %w", statusCode, method, url, errTssHTTPError)
+		if c.ctx.Err() != nil {
+			return nil, false, c.ctx.Err()
+		}
+		log.Warn("tss manager unable to sign state batch", "url", e.url,
+			"key", key, "attempt", attempt+1, "err", err)
+		lastErr = err
+	}
+	return nil, false, fmt.Errorf("cannot get signature after %d "+
+		"attempts: %w", c.cfg.MaxRetries+1, lastErr)
+}
+
+// pick returns the tss manager to send the next attempt of a request to,
+// preferring healthy ones, nil is returned once every manager was tried.
+func (c *Client) pick(tried map[*endpoint]bool) *endpoint {
+	var unhealthy *endpoint
+	for _, e := range c.endpoints {
+		if tried[e] {
+			continue
 		}
-		return nil
-	})
-	return &Client{
-		client: client,
-	}, nil
-}
-
-func (c *Client) GetSignStateBatch(BatchData common.SignStateRequest) ([]byte, error) {
-	var signature []byte
-	response, err := c.client.R().
-		SetBody(map[string]interface{}{"start_block": BatchData.StartBlock, "offset_starts_at_index": BatchData.OffsetStartsAtIndex, "state_roots": BatchData.StateRoots}).
-		SetResult(signature).
+		if e.Healthy() {
+			return e
+		}
+		if unhealthy == nil {
+			unhealthy = e
+		}
+	}
+	return unhealthy
+}
+
+// signWith requests a signature from a single tss manager and verifies it. A
+// manager returning an error or an invalid signature is marked unhealthy.
+func (c *Client) signWith(
+	e *endpoint,
+	key string,
+	BatchData tsscommon.SignStateRequest,
+) ([]byte, bool, error) {
+
+	response, err := e.client.R().
+		SetContext(c.ctx).
+		SetHeader(IdempotencyKeyHeader, key).
+		SetBody(BatchData).
 		Post("/api/v1/sign/state")
+	if err == nil && response.StatusCode() != http.StatusOK {
+		err = fmt.Errorf("unexpected status %d: %w", response.StatusCode(),
+			errTssHTTPError)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("cannot get signature: %w", err)
+		e.record(err)
+		return nil, false, fmt.Errorf("cannot get signature: %w", err)
+	}
+
+	var tssResponse TssResponse
+	if err := json.Unmarshal(response.Body(), &tssResponse); err != nil {
+		e.record(err)
+		return nil, false, fmt.Errorf("cannot decode signature: %w", err)
+	}
+
+	if c.cfg.Verifier != nil {
+		digest, err := SignedDigest(BatchData, tssResponse.RollBack)
+		if err != nil {
+			return nil, false, err
+		}
+		err = c.cfg.Verifier.Verify(c.ctx, digest, tssResponse.Signature)
+		if errors.Is(err, ErrInvalidSignature) {
+			e.record(err)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	e.record(nil)
+	return response.Body(), tssResponse.RollBack, nil
+}
+
+// healthCheckLoop periodically checks every tss manager, so that requests are
+// sent to the healthy ones first.
+func (c *Client) healthCheckLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkHealth()
+		case <-c.ctx.Done():
+			return
+		}
 	}
-	if response.StatusCode() == 200 {
-		return response.Body(), nil
-	} else {
-		return nil, errors.New("fetch tss manager signature faill")
+}
+
+func (c *Client) checkHealth() {
+	for _, e := range c.endpoints {
+		ctx, cancel := context.WithTimeout(c.ctx, healthCheckTimeout)
+		err := e.ping(ctx)
+		cancel()
+
+		switch healthy := e.Healthy(); {
+		case healthy && err != nil:
+			log.Warn("tss manager unhealthy", "url", e.url, "err", err)
+		case !healthy && err == nil:
+			log.Info("tss manager healthy again", "url", e.url)
+		}
+		e.record(err)
 	}
 }
@@ -0,0 +1,286 @@
+package tss_client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/stretchr/testify/require"
+)
+
+// staticVerifier verifies signatures against a fixed signer.
+type staticVerifier common.Address
+
+func (v staticVerifier) Verify(_ context.Context, digest, signature []byte) error {
+	signer, err := RecoverSigner(digest, signature)
+	if err != nil {
+		return err
+	}
+	if signer != common.Address(v) {
+		return fmt.Errorf("%w: signed by %s", ErrInvalidSignature, signer)
+	}
+	return nil
+}
+
+// testManager is a tss manager signing every request with key, after failing
+// the first failures requests.
+type testManager struct {
+	*httptest.Server
+
+	key      *ecdsa.PrivateKey
+	mu       sync.Mutex
+	failures int
+	keys     []string
+}
+
+func newTestManager(t *testing.T, key *ecdsa.PrivateKey, failures int) *testManager {
+	m := &testManager{
+		key:      key,
+		failures: failures,
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *testManager) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ping" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	m.mu.Lock()
+	m.keys = append(m.keys, r.Header.Get(IdempotencyKeyHeader))
+	fail := m.failures > 0
+	m.failures--
+	m.mu.Unlock()
+	if fail {
+		http.Error(w, "failed to sign state", http.StatusInternalServerError)
+		return
+	}
+
+	var request tsscommon.SignStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	digest, err := SignedDigest(request, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signature, err := crypto.Sign(digest, m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	_ = json.NewEncoder(w).Encode(TssResponse{Signature: signature})
+}
+
+func (m *testManager) requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.keys...)
+}
+
+func stateRequest(start int64, roots ...byte) tsscommon.SignStateRequest {
+	stateRoots := make([][32]byte, len(roots))
+	for i, root := range roots {
+		stateRoots[i][31] = root
+	}
+	return tsscommon.SignStateRequest{
+		StartBlock:          big.NewInt(start),
+		OffsetStartsAtIndex: big.NewInt(start - 1),
+		StateRoots:          stateRoots,
+	}
+}
+
+func newTestClient(t *testing.T, cfg Config) *Client {
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Millisecond
+	}
+	client, err := NewClient(cfg)
+	require.Nil(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func decodeResponse(t *testing.T, response []byte) TssResponse {
+	var tssResponse TssResponse
+	require.Nil(t, json.Unmarshal(response, &tssResponse))
+	return tssResponse
+}
+
+// TestClientFailsOver asserts that a request failing on a tss manager is sent
+// to the next one, which is then preferred.
+func TestClientFailsOver(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	down := newTestManager(t, key, 1)
+	up := newTestManager(t, key, 0)
+
+	client := newTestClient(t, Config{
+		URLs:       []string{down.URL, up.URL},
+		MaxRetries: 1,
+		Verifier:   staticVerifier(crypto.PubkeyToAddress(key.PublicKey)),
+	})
+
+	request := stateRequest(10, 1, 2, 3)
+	response, err := client.GetSignStateBatch(context.Background(), request)
+	require.Nil(t, err)
+	require.Len(t, decodeResponse(t, response).Signature, crypto.SignatureLength)
+	require.False(t, client.endpoints[0].Healthy())
+	require.True(t, client.endpoints[1].Healthy())
+
+	idempotencyKey := IdempotencyKey(request)
+	require.Equal(t, []string{idempotencyKey}, down.requests())
+	require.Equal(t, []string{idempotencyKey}, up.requests())
+
+	// The unhealthy manager is tried last.
+	_, err = client.GetSignStateBatch(context.Background(), stateRequest(13, 4))
+	require.Nil(t, err)
+	require.Len(t, down.requests(), 1)
+	require.Len(t, up.requests(), 2)
+}
+
+// TestClientRetries asserts that a request is retried until a tss manager
+// signs it, and fails once the retries are exhausted.
+func TestClientRetries(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	manager := newTestManager(t, key, 2)
+
+	client := newTestClient(t, Config{
+		URLs:       []string{manager.URL},
+		MaxRetries: 2,
+	})
+	_, err = client.GetSignStateBatch(context.Background(), stateRequest(1, 1))
+	require.Nil(t, err)
+	require.Len(t, manager.requests(), 3)
+
+	manager = newTestManager(t, key, 3)
+	client = newTestClient(t, Config{
+		URLs:       []string{manager.URL},
+		MaxRetries: 2,
+	})
+	_, err = client.GetSignStateBatch(context.Background(), stateRequest(1, 1))
+	require.True(t, errors.Is(err, errTssHTTPError))
+	require.Len(t, manager.requests(), 3)
+
+	// A failed request is sent again.
+	_, err = client.GetSignStateBatch(context.Background(), stateRequest(1, 1))
+	require.Nil(t, err)
+	require.Len(t, manager.requests(), 4)
+}
+
+// TestClientSharesRequests asserts that a prefetched signature answers later
+// requests for the same batch.
+func TestClientSharesRequests(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	manager := newTestManager(t, key, 0)
+
+	client := newTestClient(t, Config{
+		URLs: []string{manager.URL},
+	})
+	client.Prefetch(stateRequest(20, 1, 2))
+
+	first, err := client.GetSignStateBatch(context.Background(), stateRequest(20, 1, 2))
+	require.Nil(t, err)
+	second, err := client.GetSignStateBatch(context.Background(), stateRequest(20, 1, 2))
+	require.Nil(t, err)
+	require.Equal(t, first, second)
+	require.Len(t, manager.requests(), 1)
+
+	_, err = client.GetSignStateBatch(context.Background(), stateRequest(20, 1, 3))
+	require.Nil(t, err)
+	require.Len(t, manager.requests(), 2)
+}
+
+// TestClientRejectsInvalidSignature asserts that a signature not made by the
+// tss group is not returned.
+func TestClientRejectsInvalidSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	group, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	manager := newTestManager(t, key, 0)
+
+	client := newTestClient(t, Config{
+		URLs:     []string{manager.URL},
+		Verifier: staticVerifier(crypto.PubkeyToAddress(group.PublicKey)),
+	})
+	_, err = client.GetSignStateBatch(context.Background(), stateRequest(1, 1))
+	require.True(t, errors.Is(err, ErrInvalidSignature))
+	require.False(t, client.endpoints[0].Healthy())
+}
+
+// TestClientHealthChecks asserts that a tss manager is marked unhealthy when it
+// stops answering health checks.
+func TestClientHealthChecks(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	manager := newTestManager(t, key, 0)
+
+	client := newTestClient(t, Config{
+		URLs:                []string{manager.URL},
+		HealthCheckInterval: 10 * time.Millisecond,
+	})
+	require.True(t, client.endpoints[0].Healthy())
+
+	manager.Close()
+	require.Eventually(t, func() bool {
+		return !client.endpoints[0].Healthy()
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestIdempotencyKey asserts that requests are keyed by their type, start
+// block and state roots.
+func TestIdempotencyKey(t *testing.T) {
+	request := stateRequest(5, 1, 2, 3)
+	require.Equal(t, IdempotencyKey(request), IdempotencyKey(stateRequest(5, 1, 2, 3)))
+	require.NotEqual(t, IdempotencyKey(request), IdempotencyKey(stateRequest(6, 1, 2, 3)))
+	require.NotEqual(t, IdempotencyKey(request), IdempotencyKey(stateRequest(5, 1, 2, 4)))
+
+	// The state roots are left untouched.
+	require.Equal(t, stateRequest(5, 1, 2, 3), request)
+
+	rollback := request
+	rollback.Type = 1
+	require.NotEqual(t, IdempotencyKey(request), IdempotencyKey(rollback))
+}
+
+// TestGroupAddress asserts that the address of the tss group is derived from
+// its public key as stored by the TssGroupManager contract.
+func TestGroupAddress(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	want := crypto.PubkeyToAddress(key.PublicKey)
+
+	uncompressed := crypto.FromECDSAPub(&key.PublicKey)
+	for _, groupKey := range [][]byte{
+		uncompressed[1:],
+		uncompressed,
+		crypto.CompressPubkey(&key.PublicKey),
+	} {
+		addr, err := groupAddress(groupKey)
+		require.Nil(t, err)
+		require.Equal(t, want, addr)
+	}
+
+	_, err = groupAddress(uncompressed[:10])
+	require.NotNil(t, err)
+}
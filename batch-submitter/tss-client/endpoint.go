@@ -0,0 +1,95 @@
+package tss_client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
+)
+
+// endpoint is a tss manager the client sends its requests to.
+type endpoint struct {
+	url    string
+	client *resty.Client
+
+	mu      sync.Mutex
+	healthy bool
+}
+
+func newEndpoint(url string, jwtSecret []byte) *endpoint {
+	client := resty.New()
+	client.SetHostURL(url)
+	if len(jwtSecret) != 0 {
+		client.SetAuthScheme("Bearer")
+		client.OnBeforeRequest(func(c *resty.Client, r *resty.Request) error {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"iat": &jwt.NumericDate{Time: time.Now()},
+			})
+			s, err := token.SignedString(jwtSecret[:])
+			if err != nil {
+				return fmt.Errorf("failed to create JWT token: %w", err)
+			}
+			r.Token = s
+			return nil
+		})
+	}
+	client.OnAfterResponse(func(c *resty.Client, r *resty.Response) error {
+		statusCode := r.StatusCode()
+		if statusCode >= 400 {
+			method := r.Request.Method
+			url := r.Request.URL
+			return fmt.Errorf("%d cannot %s %s: %w", statusCode, method, url, errTssHTTPError)
+		}
+		return nil
+	})
+
+	// Endpoints are assumed healthy until a request or health check fails.
+	return &endpoint{
+		url:     url,
+		client:  client,
+		healthy: true,
+	}
+}
+
+// parseJwtSecret decodes the hex encoded secret used to authenticate with the
+// tss managers, nil is returned if none is set.
+func parseJwtSecret(jwtSecretStr string) ([]byte, error) {
+	if len(jwtSecretStr) == 0 {
+		return nil, nil
+	}
+	jwtSecret, err := hexutil.Decode(jwtSecretStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt secret %s", err.Error())
+	}
+	if len(jwtSecret) != JwtSecretLength {
+		return nil, fmt.Errorf("invalid jwt secret length, expected length %d, actual length %d",
+			JwtSecretLength, len(jwtSecret))
+	}
+	return jwtSecret, nil
+}
+
+// Healthy returns true if the last request or health check succeeded.
+func (e *endpoint) Healthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.healthy
+}
+
+// record marks the endpoint healthy if err is nil, and unhealthy otherwise.
+func (e *endpoint) record(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.healthy = err == nil
+}
+
+// ping checks that the tss manager is serving requests.
+func (e *endpoint) ping(ctx context.Context) error {
+	_, err := e.client.R().SetContext(ctx).Get("/ping")
+	return err
+}
@@ -0,0 +1,155 @@
+package tss_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mantlenetworkio/mantle/tss/bindings/tgm"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+)
+
+// ErrInvalidSignature signals that a signature returned by a tss manager was
+// not made by the active tss group, and would be rejected on chain.
+var ErrInvalidSignature = errors.New("invalid tss signature")
+
+// Verifier checks that a signature over digest was made by the tss group.
+type Verifier interface {
+	Verify(ctx context.Context, digest, signature []byte) error
+}
+
+// SignedDigest returns the digest the tss group signs for a request, as checked
+// by the TssGroupManager contract. A rollback signs the start block, a state
+// batch its state roots and offset.
+func SignedDigest(request tsscommon.SignStateRequest, rollback bool) ([]byte, error) {
+	if rollback {
+		return tsscommon.RollBackHash(request.StartBlock)
+	}
+	return tsscommon.StateBatchHash(request.StateRoots, request.OffsetStartsAtIndex)
+}
+
+// RecoverSigner returns the address that made signature over digest. Like the
+// TssGroupManager contract, recovery ids of 27 and 28 are accepted as well.
+func RecoverSigner(digest, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: length %d", ErrInvalidSignature,
+			len(signature))
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// groupAddress returns the address of a tss group public key, which the
+// TssGroupManager contract stores as 64 bytes without the uncompressed prefix.
+func groupAddress(groupKey []byte) (common.Address, error) {
+	switch len(groupKey) {
+	case 64:
+		return common.BytesToAddress(crypto.Keccak256(groupKey)[12:]), nil
+	case 65:
+		pubKey, err := crypto.UnmarshalPubkey(groupKey)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(*pubKey), nil
+	case 33:
+		pubKey, err := crypto.DecompressPubkey(groupKey)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(*pubKey), nil
+	default:
+		return common.Address{}, fmt.Errorf("invalid tss group public key "+
+			"length %d", len(groupKey))
+	}
+}
+
+// GroupVerifier verifies signatures against the public key of the active tss
+// group, read from the TssGroupManager contract.
+type GroupVerifier struct {
+	caller *tgm.TssGroupManagerCaller
+
+	mu     sync.Mutex
+	signer *common.Address
+}
+
+// NewGroupVerifier creates a GroupVerifier reading the tss group from the
+// TssGroupManager contract at addr.
+func NewGroupVerifier(
+	addr common.Address,
+	backend bind.ContractCaller,
+) (*GroupVerifier, error) {
+
+	caller, err := tgm.NewTssGroupManagerCaller(addr, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupVerifier{
+		caller: caller,
+	}, nil
+}
+
+// Verify checks that signature was made over digest by the active tss group.
+// The group public key is cached, and read again before rejecting a signature
+// in case the group was elected again since.
+func (v *GroupVerifier) Verify(
+	ctx context.Context,
+	digest, signature []byte,
+) error {
+
+	signer, err := RecoverSigner(digest, signature)
+	if err != nil {
+		return err
+	}
+
+	for _, refresh := range []bool{false, true} {
+		group, err := v.groupSigner(ctx, refresh)
+		if err != nil {
+			return err
+		}
+		if group == signer {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signed by %s", ErrInvalidSignature, signer)
+}
+
+// groupSigner returns the address of the active tss group public key, read
+// from the contract if refresh is set or it was never read.
+func (v *GroupVerifier) groupSigner(
+	ctx context.Context,
+	refresh bool,
+) (common.Address, error) {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.signer != nil && !refresh {
+		return *v.signer, nil
+	}
+
+	_, _, groupKey, _, err := v.caller.GetTssGroupInfo(&bind.CallOpts{
+		Context: ctx,
+	})
+	if err != nil {
+		return common.Address{}, fmt.Errorf("cannot get tss group info: %w", err)
+	}
+	signer, err := groupAddress(groupKey)
+	if err != nil {
+		return common.Address{}, err
+	}
+	v.signer = &signer
+	return signer, nil
+}
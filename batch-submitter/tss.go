@@ -0,0 +1,70 @@
+package batchsubmitter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mantlenetworkio/mantle/batch-submitter/bindings/scc"
+	tss "github.com/mantlenetworkio/mantle/batch-submitter/tss-client"
+)
+
+// tssGroupManagerName is the name of the TssGroupManager contract in the
+// address manager, the contract which verifies the tss signatures on chain.
+const tssGroupManagerName = "Proxy__TSS_GroupManager"
+
+// tssClientURLs returns the urls of the tss managers.
+func (c *Config) tssClientURLs() []string {
+	var urls []string
+	for _, url := range strings.Split(c.TssClientUrl, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// newTssClient creates the client requesting state batch signatures from the
+// tss managers. The signatures are verified against the tss group of the
+// TssGroupManager contract the SCC at sccAddress resolves.
+func (c *Config) newTssClient(
+	ctx context.Context,
+	l1Client *ethclient.Client,
+	sccAddress common.Address,
+) (*tss.Client, error) {
+
+	sccContract, err := scc.NewStateCommitmentChainCaller(sccAddress, l1Client)
+	if err != nil {
+		return nil, err
+	}
+	tgmAddress, err := sccContract.Resolve(
+		&bind.CallOpts{Context: ctx}, tssGroupManagerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := tss.NewGroupVerifier(tgmAddress, l1Client)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := c.tssClientURLs()
+	client, err := tss.NewClient(tss.Config{
+		URLs:                urls,
+		JwtSecret:           c.JwtSecret,
+		MaxRetries:          c.TssMaxRetries,
+		RetryBackoff:        c.TssRetryBackoff,
+		HealthCheckInterval: c.TssHealthCheckInterval,
+		Verifier:            verifier,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Configured tss client", "urls", urls,
+		"tss_group_manager", tgmAddress)
+	return client, nil
+}
@@ -17,6 +17,20 @@ import (
 func L1EthClientWithTimeout(ctx context.Context, url string, disableHTTP2 bool) (
 	*ethclient.Client, error) {
 
+	rpcClient, err := L1RPCClientWithTimeout(ctx, url, disableHTTP2)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
+
+// L1RPCClientWithTimeout attempts to dial the L1 provider using the provided
+// URL, returning the raw RPC client for the calls ethclient does not expose.
+// If the dial doesn't complete within DefaultTimeout seconds, this method will
+// return an error.
+func L1RPCClientWithTimeout(ctx context.Context, url string, disableHTTP2 bool) (
+	*rpc.Client, error) {
+
 	ctxt, cancel := context.WithTimeout(ctx, DefaultTimeout)
 	defer cancel()
 
@@ -29,13 +43,8 @@ func L1EthClientWithTimeout(ctx context.Context, url string, disableHTTP2 bool)
 			}
 		}
 
-		rpcClient, err := rpc.DialHTTPWithClient(url, httpClient)
-		if err != nil {
-			return nil, err
-		}
-
-		return ethclient.NewClient(rpcClient), nil
+		return rpc.DialHTTPWithClient(url, httpClient)
 	}
 
-	return ethclient.DialContext(ctxt, url)
+	return rpc.DialContext(ctxt, url)
 }
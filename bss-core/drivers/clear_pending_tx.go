@@ -2,7 +2,6 @@ package drivers
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"math/big"
 	"strings"
@@ -12,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 )
 
@@ -30,7 +30,7 @@ func ClearPendingTx(
 	txMgr txmgr.TxManager,
 	l1Client L1Client,
 	walletAddr common.Address,
-	privKey *ecdsa.PrivateKey,
+	s signer.Signer,
 	chainID *big.Int,
 ) error {
 
@@ -54,7 +54,7 @@ func ClearPendingTx(
 		log.Info(name+" clearing pending tx", "nonce", nonce)
 
 		signedTx, err := SignClearingTx(
-			name, ctx, walletAddr, nonce, l1Client, privKey, chainID,
+			name, ctx, walletAddr, nonce, l1Client, s, chainID,
 		)
 		if err != nil {
 			log.Error(name+" unable to sign clearing tx", "nonce", nonce,
@@ -138,7 +138,7 @@ func SignClearingTx(
 	walletAddr common.Address,
 	nonce uint64,
 	l1Client L1Client,
-	privKey *ecdsa.PrivateKey,
+	s signer.Signer,
 	chainID *big.Int,
 ) (*types.Transaction, error) {
 
@@ -180,9 +180,7 @@ func SignClearingTx(
 
 	tx := CraftClearingTx(walletAddr, nonce, gasFeeCap, gasTipCap, gasLimit)
 
-	return types.SignTx(
-		tx, types.LatestSignerForChainID(chainID), privKey,
-	)
+	return s.SignTransaction(ctx, chainID, tx)
 }
 
 // CraftClearingTx creates an unsigned clearing transaction which sends 0 ETH
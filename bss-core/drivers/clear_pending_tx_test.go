@@ -2,7 +2,6 @@ package drivers_test
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"math/big"
 	"testing"
@@ -15,6 +14,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mantlenetworkio/mantle/bss-core/drivers"
 	"github.com/mantlenetworkio/mantle/bss-core/mock"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 	"github.com/stretchr/testify/require"
 )
@@ -24,12 +24,12 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	testPrivKey = privKey
+	testSigner = signer.NewLocalSigner(privKey)
 	testWalletAddr = crypto.PubkeyToAddress(privKey.PublicKey)
 }
 
 var (
-	testPrivKey     *ecdsa.PrivateKey
+	testSigner      signer.Signer
 	testWalletAddr  common.Address
 	testChainID     = big.NewInt(1)
 	testNonce       = uint64(2)
@@ -79,7 +79,7 @@ func TestSignClearingTxEstimateGasSuccess(t *testing.T) {
 
 	tx, err := drivers.SignClearingTx(
 		"TEST", context.Background(), testWalletAddr, testNonce, l1Client,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Nil(t, err)
 	require.NotNil(t, tx)
@@ -109,7 +109,7 @@ func TestSignClearingTxSuggestGasTipCapFail(t *testing.T) {
 
 	tx, err := drivers.SignClearingTx(
 		"TEST", context.Background(), testWalletAddr, testNonce, l1Client,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Equal(t, errSuggestGasTipCap, err)
 	require.Nil(t, tx)
@@ -131,7 +131,7 @@ func TestSignClearingTxHeaderByNumberFail(t *testing.T) {
 
 	tx, err := drivers.SignClearingTx(
 		"TEST", context.Background(), testWalletAddr, testNonce, l1Client,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Equal(t, errHeaderByNumber, err)
 	require.Nil(t, tx)
@@ -158,7 +158,7 @@ func TestSignClearingTxEstimateGasFail(t *testing.T) {
 
 	tx, err := drivers.SignClearingTx(
 		"TEST", context.Background(), testWalletAddr, testNonce, l1Client,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Equal(t, errEstimateGas, err)
 	require.Nil(t, tx)
@@ -238,7 +238,7 @@ func TestClearPendingTxClearingTxConfirms(t *testing.T) {
 
 	err := drivers.ClearPendingTx(
 		"test", context.Background(), h.txMgr, h.l1Client, testWalletAddr,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Nil(t, err)
 }
@@ -255,7 +255,7 @@ func TestClearPendingTxPreviousTxConfirms(t *testing.T) {
 
 	err := drivers.ClearPendingTx(
 		"test", context.Background(), h.txMgr, h.l1Client, testWalletAddr,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Equal(t, drivers.ErrClearPendingRetry, err)
 }
@@ -277,7 +277,7 @@ func TestClearPendingTxTimeout(t *testing.T) {
 	defer cancel()
 
 	err := drivers.ClearPendingTx(
-		"test", ctx, h.txMgr, h.l1Client, testWalletAddr, testPrivKey,
+		"test", ctx, h.txMgr, h.l1Client, testWalletAddr, testSigner,
 		testChainID,
 	)
 	require.Equal(t, context.DeadlineExceeded, err)
@@ -307,7 +307,7 @@ func TestClearPendingTxMultipleConfs(t *testing.T) {
 
 	// The txmgr should timeout waiting for the txn to confirm.
 	err := drivers.ClearPendingTx(
-		"test", ctx, h.txMgr, h.l1Client, testWalletAddr, testPrivKey,
+		"test", ctx, h.txMgr, h.l1Client, testWalletAddr, testSigner,
 		testChainID,
 	)
 	require.Equal(t, context.DeadlineExceeded, err)
@@ -321,7 +321,7 @@ func TestClearPendingTxMultipleConfs(t *testing.T) {
 	// Publishing should succeed.
 	err = drivers.ClearPendingTx(
 		"test", context.Background(), h.txMgr, h.l1Client, testWalletAddr,
-		testPrivKey, testChainID,
+		testSigner, testChainID,
 	)
 	require.Nil(t, err)
 }
@@ -0,0 +1,56 @@
+package drivers
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EstimateGasPending estimates the gas used by msg on top of the pending
+// state. Transactions kept in flight at consecutive nonces build on the ones
+// before them, so estimating them against the latest block, as
+// ethclient.EstimateGas lets most backends do, reverts.
+func EstimateGasPending(
+	ctx context.Context,
+	client *rpc.Client,
+	msg ethereum.CallMsg,
+) (uint64, error) {
+
+	var hex hexutil.Uint64
+	err := client.CallContext(
+		ctx, &hex, "eth_estimateGas", toCallArg(msg), "pending",
+	)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(hex), nil
+}
+
+// toCallArg encodes msg as ethclient does.
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	return arg
+}
@@ -0,0 +1,382 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Status is the state of a batch transaction recorded in the journal.
+type Status string
+
+const (
+	// StatusCrafted is a batch that was crafted but not published yet.
+	StatusCrafted Status = "crafted"
+
+	// StatusPublished is a batch with at least one published transaction
+	// that has not confirmed yet.
+	StatusPublished Status = "published"
+
+	// StatusConfirmed is a batch whose transaction confirmed successfully.
+	StatusConfirmed Status = "confirmed"
+
+	// StatusFailed is a batch whose transaction reverted, or whose nonce
+	// was used by another transaction.
+	StatusFailed Status = "failed"
+
+	// StatusCleared is a batch whose nonce was burned by a clearing
+	// transaction.
+	StatusCleared Status = "cleared"
+)
+
+// IsPending returns true if the batch may still confirm at its nonce.
+func (s Status) IsPending() bool {
+	return s == StatusCrafted || s == StatusPublished
+}
+
+// maxSettledEntries is the number of confirmed, failed or cleared entries kept
+// when the journal is compacted on open.
+const maxSettledEntries = 1000
+
+// ErrUnknownNonce signals that no batch was journaled at a nonce.
+var ErrUnknownNonce = errors.New("no journaled batch at nonce")
+
+// Entry is a batch transaction recorded in the journal. Each update of a batch
+// is appended to the journal file as a full entry, the last one wins.
+type Entry struct {
+	// Nonce is the nonce of the batch transaction.
+	Nonce uint64 `json:"nonce"`
+
+	// Start and End are the L2 block range of the batch, End is exclusive.
+	Start *big.Int `json:"start"`
+	End   *big.Int `json:"end"`
+
+	// Status is the state of the batch.
+	Status Status `json:"status"`
+
+	// TxHashes holds the hash of every published transaction of the batch,
+	// one per gas price bump.
+	TxHashes []common.Hash `json:"txHashes,omitempty"`
+
+	// RawTx is the latest published transaction, used to rebroadcast it.
+	RawTx hexutil.Bytes `json:"rawTx,omitempty"`
+
+	// ConfirmedTx is the transaction that confirmed at the nonce, if any.
+	ConfirmedTx *common.Hash `json:"confirmedTx,omitempty"`
+
+	// Error describes why the batch failed.
+	Error string `json:"error,omitempty"`
+
+	// Updated is when the entry was last written.
+	Updated time.Time `json:"updated"`
+}
+
+// Tx decodes the latest published transaction of the batch, nil is returned
+// if none was published.
+func (e *Entry) Tx() (*types.Transaction, error) {
+	if len(e.RawTx) == 0 {
+		return nil, nil
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(e.RawTx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Journal is a file backed record of the batch transactions of a service,
+// which lets a restarted service resume the batches that were in flight. A nil
+// *Journal records nothing.
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries map[uint64]*Entry
+}
+
+// Open restores the journal at path, creating it if needed. The file is
+// compacted to the latest entry of each batch, keeping the pending batches and
+// the most recent settled ones.
+func Open(path string) (*Journal, error) {
+	entries, err := Read(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read journal %s: %w", path, err)
+	}
+
+	j := &Journal{
+		path:    path,
+		entries: make(map[uint64]*Entry),
+	}
+	settled := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !entry.Status.IsPending() {
+			if settled >= maxSettledEntries {
+				continue
+			}
+			settled++
+		}
+		j.entries[entry.Nonce] = &entry
+	}
+
+	if err := j.rewrite(); err != nil {
+		return nil, fmt.Errorf("cannot compact journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// Read returns the latest entry of each batch in the journal at path, sorted
+// by nonce.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	latest := make(map[uint64]Entry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		// A crash may leave a truncated last line, which is skipped.
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		latest[entry.Nonce] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return sortEntries(latest), nil
+}
+
+// rewrite replaces the journal file with the current entries.
+func (j *Journal) rewrite() error {
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, entry := range j.sorted() {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// Crafted records a batch crafted at nonce for the L2 blocks between start and
+// end, replacing any previous batch at that nonce.
+func (j *Journal) Crafted(nonce uint64, start, end *big.Int) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.write(&Entry{
+		Nonce:  nonce,
+		Start:  new(big.Int).Set(start),
+		End:    new(big.Int).Set(end),
+		Status: StatusCrafted,
+	})
+}
+
+// Published records the publication of tx, the original or a gas price bump of
+// the batch at its nonce.
+func (j *Journal) Published(tx *types.Transaction) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.entry(tx.Nonce())
+	if err != nil {
+		return err
+	}
+	rawTx, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	hash := tx.Hash()
+	known := false
+	for _, txHash := range entry.TxHashes {
+		known = known || txHash == hash
+	}
+	if !known {
+		entry.TxHashes = append(entry.TxHashes, hash)
+	}
+	entry.RawTx = rawTx
+	entry.Status = StatusPublished
+	return j.write(entry)
+}
+
+// Confirmed records the confirmation of the batch at nonce by the transaction
+// of receipt. A reverted transaction fails the batch.
+func (j *Journal) Confirmed(nonce uint64, receipt *types.Receipt) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.entry(nonce)
+	if err != nil {
+		return err
+	}
+	txHash := receipt.TxHash
+	entry.ConfirmedTx = &txHash
+	entry.Status = StatusConfirmed
+	if receipt.Status == types.ReceiptStatusFailed {
+		entry.Status = StatusFailed
+		entry.Error = "transaction reverted"
+	}
+	return j.write(entry)
+}
+
+// Failed records that the batch at nonce will not confirm.
+func (j *Journal) Failed(nonce uint64, reason error) error {
+	return j.settle(nonce, StatusFailed, reason.Error())
+}
+
+// Cleared records that the nonce of the batch was burned by a clearing
+// transaction.
+func (j *Journal) Cleared(nonce uint64) error {
+	return j.settle(nonce, StatusCleared, "")
+}
+
+// settle moves the batch at nonce, if any, to a final status.
+func (j *Journal) settle(nonce uint64, status Status, reason string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.entry(nonce)
+	if err != nil {
+		return err
+	}
+	entry.Status = status
+	entry.Error = reason
+	return j.write(entry)
+}
+
+// Empty returns true if no batch was ever journaled, or the journal was
+// compacted away.
+func (j *Journal) Empty() bool {
+	if j == nil {
+		return true
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return len(j.entries) == 0
+}
+
+// Pending returns the batches that may still confirm, sorted by nonce.
+func (j *Journal) Pending() []Entry {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var pending []Entry
+	for _, entry := range j.sorted() {
+		if entry.Status.IsPending() {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// entry returns a copy of the batch at nonce, the caller must hold mu.
+func (j *Journal) entry(nonce uint64) (*Entry, error) {
+	entry, ok := j.entries[nonce]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownNonce, nonce)
+	}
+	cpy := *entry
+	cpy.TxHashes = append([]common.Hash(nil), entry.TxHashes...)
+	return &cpy, nil
+}
+
+// write appends entry to the journal file, the caller must hold mu.
+func (j *Journal) write(entry *Entry) error {
+	entry.Updated = time.Now().UTC()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	j.entries[entry.Nonce] = entry
+	return nil
+}
+
+// sorted returns the entries sorted by nonce, the caller must hold mu.
+func (j *Journal) sorted() []Entry {
+	entries := make(map[uint64]Entry, len(j.entries))
+	for nonce, entry := range j.entries {
+		entries[nonce] = *entry
+	}
+	return sortEntries(entries)
+}
+
+func sortEntries(entries map[uint64]Entry) []Entry {
+	sorted := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, k int) bool {
+		return sorted[i].Nonce < sorted[k].Nonce
+	})
+	return sorted
+}
@@ -0,0 +1,145 @@
+package journal_test
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
+	"github.com/stretchr/testify/require"
+)
+
+func batchTx(nonce uint64, gasFeeCap int64) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(gasFeeCap),
+		Gas:       21000,
+	})
+}
+
+// TestJournalRecordsBatches asserts that the journal tracks the status and
+// published transactions of each batch, and that they survive a reopen.
+func TestJournalRecordsBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequencer.jsonl")
+
+	j, err := journal.Open(path)
+	require.Nil(t, err)
+	require.True(t, j.Empty())
+
+	require.Nil(t, j.Crafted(5, big.NewInt(10), big.NewInt(20)))
+	require.Nil(t, j.Crafted(6, big.NewInt(20), big.NewInt(30)))
+	require.Nil(t, j.Crafted(7, big.NewInt(30), big.NewInt(40)))
+
+	tx, bumped := batchTx(5, 10), batchTx(5, 12)
+	require.Nil(t, j.Published(tx))
+	require.Nil(t, j.Published(bumped))
+	require.Nil(t, j.Published(bumped))
+	require.Nil(t, j.Published(batchTx(6, 10)))
+
+	require.Nil(t, j.Confirmed(6, &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		TxHash: common.HexToHash("0x06"),
+	}))
+	require.Nil(t, j.Failed(7, errors.New("boom")))
+
+	err = j.Published(batchTx(8, 10))
+	require.True(t, errors.Is(err, journal.ErrUnknownNonce))
+	require.Nil(t, j.Close())
+
+	j, err = journal.Open(path)
+	require.Nil(t, err)
+	defer j.Close()
+	require.False(t, j.Empty())
+
+	pending := j.Pending()
+	require.Len(t, pending, 1)
+	require.Equal(t, uint64(5), pending[0].Nonce)
+	require.Equal(t, journal.StatusPublished, pending[0].Status)
+	require.Equal(t, big.NewInt(10), pending[0].Start)
+	require.Equal(t, big.NewInt(20), pending[0].End)
+	require.Equal(t, []common.Hash{tx.Hash(), bumped.Hash()},
+		pending[0].TxHashes)
+
+	latest, err := pending[0].Tx()
+	require.Nil(t, err)
+	require.Equal(t, bumped.Hash(), latest.Hash())
+
+	entries, err := journal.Read(path)
+	require.Nil(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, journal.StatusConfirmed, entries[1].Status)
+	require.Equal(t, common.HexToHash("0x06"), *entries[1].ConfirmedTx)
+	require.Equal(t, journal.StatusFailed, entries[2].Status)
+	require.Equal(t, "boom", entries[2].Error)
+}
+
+// TestJournalCompactsOnOpen asserts that reopening the journal rewrites it with
+// the latest entry of each batch.
+func TestJournalCompactsOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequencer.jsonl")
+
+	j, err := journal.Open(path)
+	require.Nil(t, err)
+	require.Nil(t, j.Crafted(1, big.NewInt(0), big.NewInt(1)))
+	for i := int64(1); i <= 10; i++ {
+		require.Nil(t, j.Published(batchTx(1, i)))
+	}
+	require.Nil(t, j.Close())
+
+	before, err := os.Stat(path)
+	require.Nil(t, err)
+
+	j, err = journal.Open(path)
+	require.Nil(t, err)
+	require.Nil(t, j.Close())
+
+	after, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Less(t, after.Size(), before.Size())
+
+	entries, err := journal.Read(path)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	require.Len(t, entries[0].TxHashes, 10)
+}
+
+// TestJournalSkipsTruncatedEntries asserts that a partially written last entry,
+// as left by a crash, is ignored.
+func TestJournalSkipsTruncatedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequencer.jsonl")
+
+	j, err := journal.Open(path)
+	require.Nil(t, err)
+	require.Nil(t, j.Crafted(1, big.NewInt(0), big.NewInt(1)))
+	require.Nil(t, j.Close())
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.Nil(t, err)
+	_, err = file.WriteString(`{"nonce":1,"status":"confi`)
+	require.Nil(t, err)
+	require.Nil(t, file.Close())
+
+	j, err = journal.Open(path)
+	require.Nil(t, err)
+	defer j.Close()
+
+	pending := j.Pending()
+	require.Len(t, pending, 1)
+	require.Equal(t, journal.StatusCrafted, pending[0].Status)
+}
+
+// TestNilJournal asserts that a nil journal records nothing.
+func TestNilJournal(t *testing.T) {
+	var j *journal.Journal
+	require.Nil(t, j.Crafted(1, big.NewInt(0), big.NewInt(1)))
+	require.Nil(t, j.Published(batchTx(1, 1)))
+	require.Nil(t, j.Cleared(1))
+	require.True(t, j.Empty())
+	require.Empty(t, j.Pending())
+	require.Nil(t, j.Close())
+}
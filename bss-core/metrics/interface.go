@@ -41,4 +41,25 @@ type Metrics interface {
 
 	// SccRollupTimeDuration state root rollup time duration
 	SccRollupTimeDuration() prometheus.Gauge
+
+	// PendingTxs tracks the number of batch transactions in flight.
+	PendingTxs() prometheus.Gauge
+
+	// FeeSpentWei tracks the total fees paid by confirmed batch transactions.
+	FeeSpentWei() prometheus.Counter
+
+	// FeeBumps tracks the number of fee bumps of replacement transactions.
+	FeeBumps() prometheus.Counter
+
+	// FeeCapHits tracks the number of publications withheld by a fee or
+	// spend cap.
+	FeeCapHits() prometheus.Counter
+
+	// BatchReorgs tracks the number of confirmed batches whose L1 block was
+	// reorged out.
+	BatchReorgs() prometheus.Counter
+
+	// BatchReorgDepth tracks the depth in L1 blocks of the last reorg of a
+	// confirmed batch.
+	BatchReorgDepth() prometheus.Gauge
 }
@@ -53,6 +53,27 @@ type Base struct {
 
 	// sccRollupTimeDuration state root rollup time duration
 	sccRollupTimeDuration prometheus.Gauge
+
+	// pendingTxs tracks the number of batch transactions in flight.
+	pendingTxs prometheus.Gauge
+
+	// feeSpentWei tracks the total fees paid by confirmed batch transactions.
+	feeSpentWei prometheus.Counter
+
+	// feeBumps tracks the number of fee bumps of replacement transactions.
+	feeBumps prometheus.Counter
+
+	// feeCapHits tracks the number of publications withheld by a fee or spend
+	// cap.
+	feeCapHits prometheus.Counter
+
+	// batchReorgs tracks the number of confirmed batches whose L1 block was
+	// reorged out.
+	batchReorgs prometheus.Counter
+
+	// batchReorgDepth tracks the depth in L1 blocks of the last reorg of a
+	// confirmed batch.
+	batchReorgDepth prometheus.Gauge
 }
 
 func NewBase(serviceName, subServiceName string) *Base {
@@ -121,6 +142,36 @@ func NewBase(serviceName, subServiceName string) *Base {
 			Help:      "state root rollup time duration",
 			Subsystem: subsystem,
 		}),
+		pendingTxs: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "pending_txs",
+			Help:      "Number of batch transactions in flight",
+			Subsystem: subsystem,
+		}),
+		feeSpentWei: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "fee_spent_wei",
+			Help:      "Total fees paid by confirmed batch transactions",
+			Subsystem: subsystem,
+		}),
+		feeBumps: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "fee_bumps",
+			Help:      "Number of fee bumps of replacement transactions",
+			Subsystem: subsystem,
+		}),
+		feeCapHits: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "fee_cap_hits",
+			Help:      "Number of publications withheld by a fee or spend cap",
+			Subsystem: subsystem,
+		}),
+		batchReorgs: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "batch_reorgs",
+			Help:      "Number of confirmed batches whose L1 block was reorged out",
+			Subsystem: subsystem,
+		}),
+		batchReorgDepth: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "batch_reorg_depth",
+			Help:      "Depth in L1 blocks of the last reorg of a confirmed batch",
+			Subsystem: subsystem,
+		}),
 	}
 }
 
@@ -187,6 +238,39 @@ func (b *Base) SccRollupTimeDuration() prometheus.Gauge {
 	return b.sccRollupTimeDuration
 }
 
+// PendingTxs tracks the number of batch transactions in flight.
+func (b *Base) PendingTxs() prometheus.Gauge {
+	return b.pendingTxs
+}
+
+// FeeSpentWei tracks the total fees paid by confirmed batch transactions.
+func (b *Base) FeeSpentWei() prometheus.Counter {
+	return b.feeSpentWei
+}
+
+// FeeBumps tracks the number of fee bumps of replacement transactions.
+func (b *Base) FeeBumps() prometheus.Counter {
+	return b.feeBumps
+}
+
+// FeeCapHits tracks the number of publications withheld by a fee or spend
+// cap.
+func (b *Base) FeeCapHits() prometheus.Counter {
+	return b.feeCapHits
+}
+
+// BatchReorgs tracks the number of confirmed batches whose L1 block was
+// reorged out.
+func (b *Base) BatchReorgs() prometheus.Counter {
+	return b.batchReorgs
+}
+
+// BatchReorgDepth tracks the depth in L1 blocks of the last reorg of a
+// confirmed batch.
+func (b *Base) BatchReorgDepth() prometheus.Gauge {
+	return b.batchReorgDepth
+}
+
 // MakeSubsystemName builds the subsystem name for a group of metrics, which
 // prometheus will use to prefix all metrics in the group. If two non-empty
 // strings are provided, they are joined with an underscore. If only one
@@ -0,0 +1,213 @@
+package bsscore
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+)
+
+// errAbandoned signals that a queued batch was abandoned after the failure of
+// a batch at a lower nonce.
+var errAbandoned = errors.New("abandoned after failure at a lower nonce")
+
+// inflightBatch is a batch transaction published by the pipelined event loop
+// that has not confirmed yet.
+type inflightBatch struct {
+	start, end *big.Int
+	txHash     common.Hash
+	published  time.Time
+}
+
+// pipelinedEventLoop is the event loop used when the tx manager keeps several
+// transactions in flight. Each tick crafts the range following the batches
+// still in flight and hands it to the queue, whose outcomes are processed as
+// they arrive.
+func (s *Service) pipelinedEventLoop() {
+	name := s.cfg.Driver.Name()
+	inflight := make(map[uint64]*inflightBatch)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.metrics.PendingTxs().Set(float64(s.queue.Pending()))
+
+			// Record the submitter's current ETH balance. This is done first in
+			// case any of the remaining steps fail, we can at least have an
+			// accurate view of the submitter's balance.
+			balance, err := s.cfg.L1Client.BalanceAt(
+				s.ctx, s.cfg.Driver.WalletAddr(), nil,
+			)
+			if err != nil {
+				log.Error(name+" unable to get current balance", "err", err)
+				continue
+			}
+			s.metrics.BalanceETH().Set(weiToEth64(balance))
+
+			// The batches in flight after a batch reorged out of L1 build
+			// on its range, so they are abandoned and crafted again.
+			if reorged := s.detectReorg(); reorged != nil {
+				s.abandonReorged(inflight, reorged)
+			}
+
+			if s.queue.Full() {
+				log.Info(name+" waiting for pending batch txs",
+					"pending", s.queue.Pending())
+				continue
+			}
+
+			log.Info(name + " fetching current block range")
+			start, end, err := s.pipelinedBlockRange(inflight)
+			if err != nil {
+				log.Error(name+" unable to get block range", "err", err)
+				continue
+			}
+
+			// No new updates.
+			if start.Cmp(end) >= 0 {
+				log.Info(name+" no updates", "start", start, "end", end)
+				continue
+			}
+			log.Info(name+" block range", "start", start, "end", end)
+
+			if s.deferBatch() {
+				continue
+			}
+
+			craftTx := func(ctx context.Context, nonce uint64) (*types.Transaction, error) {
+				tx, err := s.craftBatchTx(
+					ctx, start, end, new(big.Int).SetUint64(nonce),
+				)
+				if tx != nil {
+					s.journalCrafted(nonce, start, end)
+				}
+				return tx, err
+			}
+			tx, err := s.queue.Send(
+				s.ctx, craftTx, s.cfg.Driver.UpdateGasPrice, s.sendBatchTx,
+			)
+			if err != nil {
+				logCraftError(name, err)
+				continue
+			} else if tx == nil {
+				continue
+			}
+
+			s.pendingSince = time.Time{}
+			inflight[tx.Nonce()] = &inflightBatch{
+				start:     start,
+				end:       end,
+				txHash:    tx.Hash(),
+				published: time.Now(),
+			}
+			s.metrics.PendingTxs().Set(float64(s.queue.Pending()))
+			log.Info(name+" batch tx queued", "start", start, "end", end,
+				"nonce", tx.Nonce(), "pending", len(inflight))
+
+		case res := <-s.queue.Results():
+			s.handleQueueResult(inflight, res)
+			s.metrics.PendingTxs().Set(float64(s.queue.Pending()))
+
+		case err := <-s.ctx.Done():
+			log.Error(name+" service shutting down", "err", err)
+			return
+		}
+	}
+}
+
+// handleQueueResult records the outcome of a queued batch transaction. A failed
+// batch drops every batch after it, which the queue has abandoned, so that
+// their ranges are crafted again. The outcome of a batch that was dropped
+// already only has its spend recorded, since its nonce may have been reused
+// by the batch crafted again.
+func (s *Service) handleQueueResult(
+	inflight map[uint64]*inflightBatch,
+	res txmgr.QueueResult,
+) {
+
+	name := s.cfg.Driver.Name()
+
+	batch, ok := inflight[res.Nonce]
+	if !ok || batch.txHash != res.TxHash {
+		log.Info(name+" ignoring outcome of abandoned batch tx",
+			"nonce", res.Nonce, "tx_hash", res.TxHash, "err", res.Err)
+		if res.Receipt != nil {
+			s.recordSpend(res.Nonce, res.Receipt)
+		}
+		return
+	}
+	delete(inflight, res.Nonce)
+
+	// Record the confirmation time and gas used if we receive a receipt, as
+	// this indicates the transaction confirmed, even if it reverted.
+	if res.Receipt != nil {
+		batchConfirmationTime := time.Since(batch.published) /
+			time.Millisecond
+		s.metrics.BatchConfirmationTimeMs().Set(float64(batchConfirmationTime))
+		s.metrics.SubmissionGasUsedWei().Set(float64(res.Receipt.GasUsed))
+	}
+	if res.Receipt != nil {
+		s.recordSpend(res.Nonce, res.Receipt)
+	}
+	s.journalOutcome(res.Nonce, res.Receipt, res.Err)
+
+	if res.Err != nil {
+		log.Error(name+" unable to publish batch tx", "nonce", res.Nonce,
+			"err", res.Err)
+		s.metrics.FailedSubmissions().Inc()
+
+		for nonce := range inflight {
+			if nonce > res.Nonce {
+				delete(inflight, nonce)
+				s.journalOutcome(nonce, nil, errAbandoned)
+			}
+		}
+		return
+	}
+
+	// The transaction was successfully submitted.
+	s.watchConfirmed(res.Nonce, batch.start, batch.end, res.Receipt)
+	log.Info(name+" batch tx successfully published",
+		"tx_hash", res.Receipt.TxHash, "nonce", res.Nonce)
+	s.metrics.BatchesSubmitted().Inc()
+	s.metrics.SubmissionTimestamp().Set(float64(time.Now().UnixNano() / 1e6))
+}
+
+// pipelinedBlockRange returns the range of L2 blocks to submit next, starting
+// after the batches still in flight. Drivers that do not implement
+// PipelinedDriver have their range clipped to start after those batches.
+func (s *Service) pipelinedBlockRange(
+	inflight map[uint64]*inflightBatch,
+) (*big.Int, *big.Int, error) {
+
+	var next *big.Int
+	for _, batch := range inflight {
+		if next == nil || batch.end.Cmp(next) > 0 {
+			next = batch.end
+		}
+	}
+	if next == nil {
+		return s.cfg.Driver.GetBatchBlockRange(s.ctx)
+	}
+
+	if driver, ok := s.cfg.Driver.(PipelinedDriver); ok {
+		return driver.GetBatchBlockRangeFrom(s.ctx, next)
+	}
+
+	start, end, err := s.cfg.Driver.GetBatchBlockRange(s.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if start.Cmp(next) < 0 {
+		start = next
+	}
+	return start, end, nil
+}
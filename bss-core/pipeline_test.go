@@ -0,0 +1,122 @@
+package bsscore
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
+	"github.com/mantlenetworkio/mantle/bss-core/metrics"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+	"github.com/stretchr/testify/require"
+)
+
+// pipelineMetrics is shared by the tests, metrics can only be registered once.
+var pipelineMetrics = metrics.NewBase("test", "pipeline")
+
+// pipelineDriver is the subset of a Driver used to handle queue results.
+type pipelineDriver struct {
+	Driver
+
+	metrics metrics.Metrics
+}
+
+func (d *pipelineDriver) Name() string {
+	return "PIPELINE"
+}
+
+func (d *pipelineDriver) Metrics() metrics.Metrics {
+	return d.metrics
+}
+
+func newPipelineService(t *testing.T) *Service {
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal"))
+	require.NoError(t, err)
+	t.Cleanup(func() { j.Close() })
+
+	cfg := txmgr.Config{NumConfirmations: 1, MaxPendingTxs: 3}
+	return &Service{
+		cfg: ServiceConfig{
+			Driver:  &pipelineDriver{metrics: pipelineMetrics},
+			Journal: j,
+		},
+		ctx:     context.Background(),
+		queue:   txmgr.NewQueue("TEST", cfg, nil, common.Address{}),
+		metrics: pipelineMetrics,
+	}
+}
+
+// craftInflight journals a batch crafted at nonce and tracks it as in flight.
+func craftInflight(
+	s *Service,
+	inflight map[uint64]*inflightBatch,
+	nonce uint64,
+	txHash common.Hash,
+) {
+
+	start, end := big.NewInt(int64(nonce)*10), big.NewInt(int64(nonce+1)*10)
+	s.journalCrafted(nonce, start, end)
+	inflight[nonce] = &inflightBatch{start: start, end: end, txHash: txHash}
+}
+
+func journalStatus(s *Service, nonce uint64) journal.Status {
+	for _, entry := range s.cfg.Journal.Pending() {
+		if entry.Nonce == nonce {
+			return entry.Status
+		}
+	}
+	return journal.StatusFailed
+}
+
+// TestHandleQueueResultIgnoresAbandonedTxs asserts that the outcome of an
+// abandoned batch tx, delivered after its nonce was reused by the batch crafted
+// again, leaves the new batch in flight.
+func TestHandleQueueResultIgnoresAbandonedTxs(t *testing.T) {
+	s := newPipelineService(t)
+	inflight := make(map[uint64]*inflightBatch)
+
+	abandoned, recrafted := common.HexToHash("0x01"), common.HexToHash("0x02")
+	craftInflight(s, inflight, 5, recrafted)
+	craftInflight(s, inflight, 6, common.HexToHash("0x03"))
+
+	s.handleQueueResult(inflight, txmgr.QueueResult{
+		Nonce:  5,
+		TxHash: abandoned,
+		Err:    txmgr.ErrQueueReset,
+	})
+	require.Len(t, inflight, 2)
+	require.Equal(t, journal.StatusCrafted, journalStatus(s, 5))
+	require.Equal(t, journal.StatusCrafted, journalStatus(s, 6))
+
+	s.handleQueueResult(inflight, txmgr.QueueResult{
+		Nonce:  5,
+		TxHash: recrafted,
+		Receipt: &types.Receipt{
+			TxHash:      recrafted,
+			Status:      types.ReceiptStatusSuccessful,
+			BlockNumber: big.NewInt(1),
+		},
+	})
+	require.Len(t, inflight, 1)
+	require.Contains(t, inflight, uint64(6))
+	require.Len(t, s.cfg.Journal.Pending(), 1)
+}
+
+// TestAbandonReorgedDropsInflightBatches asserts that the batches in flight
+// from the nonce of a reorged batch are dropped right away, without waiting
+// for the queue to deliver their outcome.
+func TestAbandonReorgedDropsInflightBatches(t *testing.T) {
+	s := newPipelineService(t)
+	inflight := make(map[uint64]*inflightBatch)
+	for nonce := uint64(3); nonce <= 5; nonce++ {
+		craftInflight(s, inflight, nonce, common.BigToHash(new(big.Int).SetUint64(nonce)))
+	}
+
+	s.abandonReorged(inflight, &confirmedBatch{nonce: 4})
+	require.Len(t, inflight, 1)
+	require.Contains(t, inflight, uint64(3))
+	require.Len(t, s.cfg.Journal.Pending(), 1)
+}
@@ -0,0 +1,216 @@
+package bsscore
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+)
+
+// errBatchReorged signals that a batch was abandoned because a batch it builds
+// on was reorged out of L1.
+var errBatchReorged = errors.New("abandoned after reorg at a lower nonce")
+
+// confirmedBatch is a confirmed batch watched for L1 reorgs until its block is
+// ReorgDepth blocks deep.
+type confirmedBatch struct {
+	nonce       uint64
+	start, end  *big.Int
+	txHash      common.Hash
+	blockNumber uint64
+	blockHash   common.Hash
+}
+
+// watchConfirmed watches the batch at nonce confirmed by receipt for L1
+// reorgs, unless reorg detection is disabled or the batch reverted.
+func (s *Service) watchConfirmed(
+	nonce uint64,
+	start, end *big.Int,
+	receipt *types.Receipt,
+) {
+
+	if s.cfg.ReorgDepth == 0 ||
+		receipt.Status != types.ReceiptStatusSuccessful {
+		return
+	}
+
+	s.confirmed = append(s.confirmed, &confirmedBatch{
+		nonce:       nonce,
+		start:       start,
+		end:         end,
+		txHash:      receipt.TxHash,
+		blockNumber: receipt.BlockNumber.Uint64(),
+		blockHash:   receipt.BlockHash,
+	})
+}
+
+// detectReorg returns the first watched batch that was reorged out of L1, if
+// any. Failures to query L1 are logged and retried on the next call.
+func (s *Service) detectReorg() *confirmedBatch {
+	reorged, err := s.checkReorgs()
+	if err != nil {
+		log.Error(s.cfg.Driver.Name()+" unable to check for L1 reorgs",
+			"err", err)
+		return nil
+	}
+	return reorged
+}
+
+// checkReorgs checks that the L1 blocks of the watched batches are still
+// canonical, and stops watching the batches that are ReorgDepth blocks deep.
+// A batch whose transaction was included again in another block is watched
+// there. The first batch whose transaction is no longer included is returned,
+// and neither it nor the batches after it are watched anymore: the drivers read
+// the range to submit from L1, so that range is crafted and submitted again.
+func (s *Service) checkReorgs() (*confirmedBatch, error) {
+	if len(s.confirmed) == 0 {
+		return nil, nil
+	}
+
+	name := s.cfg.Driver.Name()
+
+	head, err := s.cfg.L1Client.BlockNumber(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	watched := s.confirmed[:0]
+	for _, batch := range s.confirmed {
+		if batch.blockNumber+s.cfg.ReorgDepth > head {
+			watched = append(watched, batch)
+		}
+	}
+	s.confirmed = watched
+
+	for i, batch := range s.confirmed {
+		canonical, err := s.isCanonical(batch)
+		if err != nil {
+			return nil, err
+		} else if canonical {
+			continue
+		}
+
+		receipt, err := s.cfg.L1Client.TransactionReceipt(s.ctx, batch.txHash)
+		if err != nil && err != ethereum.NotFound {
+			return nil, err
+		}
+
+		// The reorg depth counts the blocks from that of the batch to the
+		// head, which may now be below it.
+		depth := uint64(1)
+		if head >= batch.blockNumber {
+			depth = head - batch.blockNumber + 1
+		}
+		s.metrics.BatchReorgs().Inc()
+		s.metrics.BatchReorgDepth().Set(float64(depth))
+
+		if receipt != nil && receipt.Status == types.ReceiptStatusSuccessful {
+			log.Warn(name+" batch tx included again after L1 reorg",
+				"nonce", batch.nonce, "start", batch.start, "end", batch.end,
+				"tx_hash", batch.txHash, "depth", depth,
+				"old_block_number", batch.blockNumber,
+				"old_block_hash", batch.blockHash,
+				"block_number", receipt.BlockNumber,
+				"block_hash", receipt.BlockHash)
+
+			batch.blockNumber = receipt.BlockNumber.Uint64()
+			batch.blockHash = receipt.BlockHash
+			continue
+		}
+
+		log.Error(name+" batch tx reorged out of L1, submitting its range "+
+			"again", "nonce", batch.nonce, "start", batch.start,
+			"end", batch.end, "tx_hash", batch.txHash, "depth", depth,
+			"block_number", batch.blockNumber, "block_hash", batch.blockHash,
+			"reverted", receipt != nil)
+
+		s.confirmed = s.confirmed[:i]
+		return batch, nil
+	}
+	return nil, nil
+}
+
+// isCanonical reports whether the L1 block of a watched batch is still part of
+// the canonical chain.
+func (s *Service) isCanonical(batch *confirmedBatch) (bool, error) {
+	header, err := s.cfg.L1Client.HeaderByNumber(
+		s.ctx, new(big.Int).SetUint64(batch.blockNumber),
+	)
+	if err == ethereum.NotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return header.Hash() == batch.blockHash, nil
+}
+
+// abandonReorged abandons the batches in flight from the nonce of a batch that
+// was reorged out of L1, since they build on its range, and drops them so that
+// their ranges are crafted again. The outcome the queue delivers for them later
+// is ignored.
+func (s *Service) abandonReorged(
+	inflight map[uint64]*inflightBatch,
+	reorged *confirmedBatch,
+) {
+
+	s.queue.Abandon(reorged.nonce)
+
+	for nonce := range inflight {
+		if nonce >= reorged.nonce {
+			delete(inflight, nonce)
+			s.journalOutcome(nonce, nil, errBatchReorged)
+		}
+	}
+}
+
+// sendBatch publishes a batch transaction through the tx manager, watching the
+// confirmed batches for reorgs in the meantime. The transaction builds on the
+// range of those batches, so it is abandoned if one of them is reorged out,
+// and its range is crafted again.
+func (s *Service) sendBatch(
+	updateGasPrice txmgr.UpdateGasPriceFunc,
+) (*types.Receipt, error) {
+
+	if len(s.confirmed) == 0 {
+		return s.txMgr.Send(s.ctx, updateGasPrice, s.sendBatchTx)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	var reorged *confirmedBatch
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if reorged = s.detectReorg(); reorged != nil {
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	receipt, err := s.txMgr.Send(ctx, updateGasPrice, s.sendBatchTx)
+	cancel()
+	<-done
+
+	if receipt == nil && reorged != nil && s.ctx.Err() == nil {
+		err = errBatchReorged
+	}
+	return receipt, err
+}
@@ -0,0 +1,276 @@
+package bsscore
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
+)
+
+var (
+	// errNonceUsed signals that a journaled batch did not confirm and its
+	// nonce was used by another transaction.
+	errNonceUsed = errors.New("nonce used by another transaction")
+
+	// errStaleBatch signals that a journaled batch that was never published
+	// no longer starts at the next L2 block to submit.
+	errStaleBatch = errors.New("batch no longer starts at the next block " +
+		"to submit")
+
+	// errBatchNotNeeded signals that the driver has nothing to submit for a
+	// journaled batch anymore.
+	errBatchNotNeeded = errors.New("batch no longer needed")
+)
+
+// errAlreadyKnown is the message returned by nodes when a transaction is
+// already in their pool.
+const errAlreadyKnown = "already known"
+
+// resumeJournal settles the batches a previous run left pending in the
+// journal, watching their transactions until they confirm, rebroadcasting
+// them if they were dropped and crafting those that were never published
+// again. It returns false if some batch could not be resumed.
+func (s *Service) resumeJournal() bool {
+	name := s.cfg.Driver.Name()
+
+	pending := s.cfg.Journal.Pending()
+	if len(pending) == 0 {
+		return true
+	}
+	log.Info(name+" resuming journaled batches", "pending", len(pending))
+
+	for _, entry := range pending {
+		if err := s.resumeBatch(entry); err != nil {
+			if s.ctx.Err() == nil {
+				log.Error(name+" unable to resume journaled batch",
+					"nonce", entry.Nonce, "start", entry.Start,
+					"end", entry.End, "err", err)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// resumeBatch settles a single journaled batch.
+func (s *Service) resumeBatch(entry journal.Entry) error {
+	name := s.cfg.Driver.Name()
+
+	settled, err := s.settleBatch(entry, false)
+	if err != nil || settled {
+		return err
+	}
+
+	tx, err := entry.Tx()
+	if err != nil {
+		log.Warn(name+" unable to decode journaled batch tx, crafting "+
+			"it again", "nonce", entry.Nonce, "err", err)
+	}
+	if tx == nil {
+		if tx, err = s.recraftBatchTx(entry); err != nil {
+			return s.failBatch(entry, err)
+		}
+	}
+
+	log.Info(name+" resuming batch tx", "nonce", entry.Nonce,
+		"start", entry.Start, "end", entry.End, "tx_hash", tx.Hash())
+
+	// The journaled transaction is rebroadcast first, in case it was
+	// dropped, then bumped like any other batch transaction.
+	var (
+		mu          sync.Mutex
+		rebroadcast = true
+	)
+	updateGasPrice := func(ctx context.Context) (*types.Transaction, error) {
+		mu.Lock()
+		first := rebroadcast
+		rebroadcast = false
+		mu.Unlock()
+
+		if first {
+			return tx, nil
+		}
+		return s.cfg.Driver.UpdateGasPrice(ctx, tx)
+	}
+
+	receipt, err := s.txMgr.Send(s.ctx, updateGasPrice, s.sendBatchTx)
+	if receipt != nil {
+		s.recordSpend(entry.Nonce, receipt)
+		s.logJournalError(s.cfg.Journal.Confirmed(entry.Nonce, receipt))
+		if err == nil {
+			s.metrics.BatchesSubmitted().Inc()
+			s.watchConfirmed(entry.Nonce, entry.Start, entry.End, receipt)
+		}
+		return nil
+	}
+	if err != nil && s.ctx.Err() == nil {
+		// An earlier gas price bump, which is not watched, may have
+		// confirmed instead.
+		entry = s.journalEntry(entry)
+		if settled, settleErr := s.settleBatch(entry, false); settled {
+			return settleErr
+		}
+	}
+	return err
+}
+
+// recraftBatchTx crafts a journaled batch that was never published again,
+// provided it still starts at the next L2 block to submit. The batch contents
+// only depend on its block range, so the same batch is crafted.
+func (s *Service) recraftBatchTx(entry journal.Entry) (*types.Transaction, error) {
+	start, _, err := s.cfg.Driver.GetBatchBlockRange(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	if start.Cmp(entry.Start) != 0 {
+		return nil, errStaleBatch
+	}
+
+	nonce, err := s.cfg.L1Client.NonceAt(
+		s.ctx, s.cfg.Driver.WalletAddr(), nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if nonce != entry.Nonce {
+		return nil, errStaleBatch
+	}
+
+	tx, err := s.craftBatchTx(
+		s.ctx, entry.Start, entry.End, new(big.Int).SetUint64(entry.Nonce),
+	)
+	if err != nil {
+		return nil, err
+	} else if tx == nil {
+		return nil, errBatchNotNeeded
+	}
+	return tx, nil
+}
+
+// failBatch fails a journaled batch that cannot be resumed because it is stale
+// or no longer needed, any other error is returned as is.
+func (s *Service) failBatch(entry journal.Entry, err error) error {
+	if err != errStaleBatch && err != errBatchNotNeeded {
+		return err
+	}
+
+	log.Warn(s.cfg.Driver.Name()+" dropping journaled batch",
+		"nonce", entry.Nonce, "start", entry.Start, "end", entry.End,
+		"reason", err)
+	s.logJournalError(s.cfg.Journal.Failed(entry.Nonce, err))
+	return nil
+}
+
+// settleBatch checks whether one of the published transactions of a journaled
+// batch confirmed, or whether its nonce was used by another transaction, which
+// is a clearing transaction if cleared is set. It returns true if the batch was
+// settled.
+func (s *Service) settleBatch(
+	entry journal.Entry,
+	cleared bool,
+) (bool, error) {
+
+	for _, txHash := range entry.TxHashes {
+		receipt, err := s.cfg.L1Client.TransactionReceipt(s.ctx, txHash)
+		if err == ethereum.NotFound {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+
+		log.Info(s.cfg.Driver.Name()+" journaled batch tx confirmed",
+			"nonce", entry.Nonce, "tx_hash", txHash)
+		s.logJournalError(s.cfg.Journal.Confirmed(entry.Nonce, receipt))
+		return true, nil
+	}
+
+	nonce, err := s.cfg.L1Client.NonceAt(
+		s.ctx, s.cfg.Driver.WalletAddr(), nil,
+	)
+	if err != nil {
+		return false, err
+	}
+	if nonce <= entry.Nonce {
+		return false, nil
+	}
+
+	log.Warn(s.cfg.Driver.Name()+" journaled batch nonce used",
+		"nonce", entry.Nonce, "start", entry.Start, "end", entry.End,
+		"cleared", cleared)
+	if cleared {
+		s.logJournalError(s.cfg.Journal.Cleared(entry.Nonce))
+	} else {
+		s.logJournalError(s.cfg.Journal.Failed(entry.Nonce, errNonceUsed))
+	}
+	return true, nil
+}
+
+// settleCleared settles the journaled batches whose nonce was burned by a
+// clearing transaction.
+func (s *Service) settleCleared() {
+	for _, entry := range s.cfg.Journal.Pending() {
+		if _, err := s.settleBatch(entry, true); err != nil {
+			log.Error(s.cfg.Driver.Name()+" unable to settle journaled "+
+				"batch", "nonce", entry.Nonce, "err", err)
+		}
+	}
+}
+
+// journalEntry returns the latest journaled state of entry.
+func (s *Service) journalEntry(entry journal.Entry) journal.Entry {
+	for _, pending := range s.cfg.Journal.Pending() {
+		if pending.Nonce == entry.Nonce {
+			return pending
+		}
+	}
+	return entry
+}
+
+// sendBatchTx journals a batch transaction and publishes it. The transaction
+// is journaled first, as a shutdown may interrupt the call after the node
+// accepted it, and it is then rebroadcast after the restart rather than
+// crafted again. A transaction already known to the node, as when
+// rebroadcasting, counts as published.
+func (s *Service) sendBatchTx(ctx context.Context, tx *types.Transaction) error {
+	s.logJournalError(s.cfg.Journal.Published(tx))
+	err := s.cfg.Driver.SendTransaction(ctx, tx)
+	if err != nil && !strings.Contains(err.Error(), errAlreadyKnown) {
+		return err
+	}
+	return nil
+}
+
+// journalCrafted journals a batch crafted at nonce.
+func (s *Service) journalCrafted(nonce uint64, start, end *big.Int) {
+	s.logJournalError(s.cfg.Journal.Crafted(nonce, start, end))
+}
+
+// journalOutcome journals the outcome of sending the batch at nonce. Batches
+// interrupted by a shutdown are left pending to be resumed.
+func (s *Service) journalOutcome(
+	nonce uint64,
+	receipt *types.Receipt,
+	err error,
+) {
+
+	switch {
+	case receipt != nil:
+		s.logJournalError(s.cfg.Journal.Confirmed(nonce, receipt))
+	case err != nil && s.ctx.Err() == nil:
+		s.logJournalError(s.cfg.Journal.Failed(nonce, err))
+	}
+}
+
+// logJournalError logs a failure to write the journal, which only affects
+// resuming after a restart.
+func (s *Service) logJournalError(err error) {
+	if err != nil {
+		log.Error(s.cfg.Driver.Name()+" unable to write journal", "err", err)
+	}
+}
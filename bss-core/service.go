@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/bss-core/journal"
 	"github.com/mantlenetworkio/mantle/bss-core/metrics"
 	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
 )
@@ -70,6 +71,20 @@ type Driver interface {
 	SendTransaction(ctx context.Context, tx *types.Transaction) error
 }
 
+// PipelinedDriver is implemented by drivers that can craft a batch while the
+// batches before it are still in flight.
+type PipelinedDriver interface {
+	Driver
+
+	// GetBatchBlockRangeFrom returns the start and end L2 block heights that
+	// need to be processed after the batches ending at start, see
+	// GetBatchBlockRange.
+	GetBatchBlockRangeFrom(
+		ctx context.Context,
+		start *big.Int,
+	) (*big.Int, *big.Int, error)
+}
+
 type ServiceConfig struct {
 	Context         context.Context
 	Driver          Driver
@@ -77,6 +92,22 @@ type ServiceConfig struct {
 	ClearPendingTx  bool
 	L1Client        *ethclient.Client
 	TxManagerConfig txmgr.Config
+
+	// Fees is the fee strategy shared with the driver, if any. It defers
+	// batches while gas is expensive and is told about confirmed spend.
+	Fees *txmgr.FeeStrategy
+
+	// Journal records the crafted batches and their transactions, if set.
+	// The batches left pending by a previous run are resumed on startup,
+	// and pending transactions are only cleared if that fails.
+	Journal *journal.Journal
+
+	// ReorgDepth is the number of L1 blocks, counting its own, during which
+	// a confirmed batch is watched for reorgs. A batch reorged out of L1
+	// within that depth has its range crafted and submitted again. Unlike
+	// TxManagerConfig.NumConfirmations, it does not delay the next batch.
+	// Reorg detection is disabled if zero.
+	ReorgDepth uint64
 }
 
 type Service struct {
@@ -85,8 +116,15 @@ type Service struct {
 	cancel func()
 
 	txMgr   txmgr.TxManager
+	queue   *txmgr.Queue
 	metrics metrics.Metrics
 
+	// pendingSince is when the batch awaiting submission was first seen.
+	pendingSince time.Time
+
+	// confirmed are the confirmed batches watched for L1 reorgs.
+	confirmed []*confirmedBatch
+
 	wg sync.WaitGroup
 }
 
@@ -97,11 +135,21 @@ func NewService(cfg ServiceConfig) *Service {
 		cfg.Driver.Name(), cfg.TxManagerConfig, cfg.L1Client,
 	)
 
+	// Keep several batch transactions in flight if configured to do so.
+	var queue *txmgr.Queue
+	if cfg.TxManagerConfig.MaxPendingTxs > 1 {
+		queue = txmgr.NewQueue(
+			cfg.Driver.Name(), cfg.TxManagerConfig, cfg.L1Client,
+			cfg.Driver.WalletAddr(),
+		)
+	}
+
 	return &Service{
 		cfg:     cfg,
 		ctx:     ctx,
 		cancel:  cancel,
 		txMgr:   txMgr,
+		queue:   queue,
 		metrics: cfg.Driver.Metrics(),
 	}
 }
@@ -140,7 +188,16 @@ func (s *Service) eventLoop() {
 
 	name := s.cfg.Driver.Name()
 
-	if s.cfg.ClearPendingTx {
+	// Resume the batches journaled by a previous run, falling back to
+	// clearing pending transactions if the journal has never been written
+	// or some batch cannot be resumed.
+	clearPendingTx := s.cfg.ClearPendingTx
+	if s.cfg.Journal != nil && !s.cfg.Journal.Empty() {
+		resumed := s.resumeJournal()
+		clearPendingTx = clearPendingTx && !resumed
+	}
+
+	if clearPendingTx {
 		const maxClearRetries = 3
 		for i := 0; i < maxClearRetries; i++ {
 			err := s.cfg.Driver.ClearPendingTx(s.ctx, s.txMgr, s.cfg.L1Client)
@@ -151,6 +208,12 @@ func (s *Service) eventLoop() {
 			}
 			log.Crit("Unable to confirm a clearing transaction", "err", err)
 		}
+		s.settleCleared()
+	}
+
+	if s.queue != nil {
+		s.pipelinedEventLoop()
+		return
 	}
 
 	ticker := time.NewTicker(s.cfg.PollInterval)
@@ -171,6 +234,10 @@ func (s *Service) eventLoop() {
 			}
 			s.metrics.BalanceETH().Set(weiToEth64(balance))
 
+			// A batch reorged out of L1 is no longer reflected by the
+			// block range below, which covers it again.
+			s.detectReorg()
+
 			// Determine the range of L2 blocks that the batch submitter has not
 			// processed, and needs to take action on.
 			log.Info(name + " fetching current block range")
@@ -187,6 +254,10 @@ func (s *Service) eventLoop() {
 			}
 			log.Info(name+" block range", "start", start, "end", end)
 
+			if s.deferBatch() {
+				continue
+			}
+
 			// Query for the submitter's current nonce.
 			nonce64, err := s.cfg.L1Client.NonceAt(
 				s.ctx, s.cfg.Driver.WalletAddr(), nil,
@@ -198,32 +269,14 @@ func (s *Service) eventLoop() {
 			}
 			nonce := new(big.Int).SetUint64(nonce64)
 
-			batchTxBuildStart := time.Now()
-			tx, err := s.cfg.Driver.CraftBatchTx(
-				s.ctx, start, end, nonce,
-			)
+			tx, err := s.craftBatchTx(s.ctx, start, end, nonce)
 			if err != nil {
-				if err.Error() == "malformed batch" {
-					log.Warn(name+" unable to craft batch tx",
-						"err", err)
-				} else {
-					log.Error(name+" unable to craft batch tx",
-						"err", err)
-				}
+				logCraftError(name, err)
 				continue
 			} else if tx == nil {
 				continue
 			}
-			batchTxBuildTime := time.Since(batchTxBuildStart) / time.Millisecond
-			s.metrics.BatchTxBuildTimeMs().Set(float64(batchTxBuildTime))
-
-			// Record the size of the batch transaction.
-			var txBuf bytes.Buffer
-			if err := tx.EncodeRLP(&txBuf); err != nil {
-				log.Error(name+" unable to encode batch tx", "err", err)
-				continue
-			}
-			s.metrics.BatchSizeBytes().Observe(float64(len(txBuf.Bytes())))
+			s.journalCrafted(nonce64, start, end)
 
 			// Construct the transaction submission clousure that will attempt
 			// to send the next transaction at the given nonce and gas price.
@@ -237,9 +290,8 @@ func (s *Service) eventLoop() {
 			// Wait until one of our submitted transactions confirms. If no
 			// receipt is received it's likely our gas price was too low.
 			batchConfirmationStart := time.Now()
-			receipt, err := s.txMgr.Send(
-				s.ctx, updateGasPrice, s.cfg.Driver.SendTransaction,
-			)
+			receipt, err := s.sendBatch(updateGasPrice)
+			s.journalOutcome(nonce64, receipt, err)
 
 			// Record the confirmation time and gas used if we receive a
 			// receipt, as this indicates the transaction confirmed. We record
@@ -250,6 +302,7 @@ func (s *Service) eventLoop() {
 					time.Millisecond
 				s.metrics.BatchConfirmationTimeMs().Set(float64(batchConfirmationTime))
 				s.metrics.SubmissionGasUsedWei().Set(float64(receipt.GasUsed))
+				s.recordSpend(nonce64, receipt)
 			}
 
 			if err != nil {
@@ -260,6 +313,8 @@ func (s *Service) eventLoop() {
 			}
 
 			// The transaction was successfully submitted.
+			s.pendingSince = time.Time{}
+			s.watchConfirmed(nonce64, start, end, receipt)
 			log.Info(name+" batch tx successfully published",
 				"tx_hash", receipt.TxHash)
 			s.metrics.BatchesSubmitted().Inc()
@@ -272,6 +327,68 @@ func (s *Service) eventLoop() {
 	}
 }
 
+// craftBatchTx crafts the batch transaction for the L2 blocks between start
+// and end, recording its build time and size. A nil transaction is returned if
+// the driver has nothing to submit.
+func (s *Service) craftBatchTx(
+	ctx context.Context,
+	start, end, nonce *big.Int,
+) (*types.Transaction, error) {
+
+	batchTxBuildStart := time.Now()
+	tx, err := s.cfg.Driver.CraftBatchTx(ctx, start, end, nonce)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	batchTxBuildTime := time.Since(batchTxBuildStart) / time.Millisecond
+	s.metrics.BatchTxBuildTimeMs().Set(float64(batchTxBuildTime))
+
+	// Record the size of the batch transaction.
+	var txBuf bytes.Buffer
+	if err := tx.EncodeRLP(&txBuf); err != nil {
+		return nil, err
+	}
+	s.metrics.BatchSizeBytes().Observe(float64(len(txBuf.Bytes())))
+
+	return tx, nil
+}
+
+// deferBatch reports whether the pending batch should wait for cheaper gas,
+// starting its wait window if it was just seen.
+func (s *Service) deferBatch() bool {
+	if s.cfg.Fees == nil {
+		return false
+	}
+	if s.pendingSince.IsZero() {
+		s.pendingSince = time.Now()
+	}
+
+	wait, err := s.cfg.Fees.ShouldWait(s.ctx, s.pendingSince)
+	if err != nil {
+		log.Error(s.cfg.Driver.Name()+" unable to check base fee", "err", err)
+		return false
+	}
+	return wait
+}
+
+// recordSpend tells the fee strategy, if any, about the fee paid by the
+// confirmed batch transaction at nonce.
+func (s *Service) recordSpend(nonce uint64, receipt *types.Receipt) {
+	if s.cfg.Fees != nil {
+		s.cfg.Fees.RecordSpend(s.ctx, nonce, receipt)
+	}
+}
+
+// logCraftError logs a failure to craft a batch transaction, a malformed batch
+// is only a warning.
+func logCraftError(name string, err error) {
+	if err.Error() == "malformed batch" {
+		log.Warn(name+" unable to craft batch tx", "err", err)
+	} else {
+		log.Error(name+" unable to craft batch tx", "err", err)
+	}
+}
+
 func weiToEth64(wei *big.Int) float64 {
 	eth := new(big.Float).SetInt(wei)
 	eth.Mul(eth, weiToEth)
@@ -0,0 +1,103 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	bsscore "github.com/mantlenetworkio/mantle/bss-core"
+)
+
+var (
+	// TypeLocal signs with a private key, or a key derived from a mnemonic.
+	TypeLocal = "local"
+	// TypeKeystore signs with the key of an encrypted keystore file.
+	TypeKeystore = "keystore"
+	// TypeRemote signs with a remote signer over JSON-RPC.
+	TypeRemote = "remote"
+	// TypeKMS signs with a key of the GCP Key Management Service.
+	TypeKMS = "kms"
+
+	// ErrUnknownType signals that the configured signer type is not
+	// supported.
+	ErrUnknownType = errors.New("unknown signer type")
+)
+
+// Config selects and configures the signer of a service.
+type Config struct {
+	// Type is one of local, keystore, remote and kms. The signer is not
+	// configured if it is empty.
+	Type string
+
+	// PrivateKey, or Mnemonic and HDPath, are the key of the local signer.
+	PrivateKey string
+	Mnemonic   string
+	HDPath     string
+
+	// KeystorePath and KeystorePassword are the keystore file of the
+	// keystore signer and its password.
+	KeystorePath     string
+	KeystorePassword string
+
+	// Endpoint is the JSON-RPC endpoint of the remote signer.
+	Endpoint string
+
+	// Address is the address of the remote and kms signers.
+	Address string
+
+	// KMSKeyName and KMSCredentials are the key name of the kms signer and
+	// the hex encoded JSON credentials of its GCP account.
+	KMSKeyName     string
+	KMSCredentials string
+}
+
+// Enabled returns whether a signer is configured.
+func (c Config) Enabled() bool {
+	return c.Type != ""
+}
+
+// Check validates the options of the configured signer type.
+func (c Config) Check() error {
+	switch c.Type {
+	case TypeLocal:
+		if c.PrivateKey == "" && (c.Mnemonic == "" || c.HDPath == "") {
+			return bsscore.ErrCannotGetPrivateKey
+		}
+	case TypeKeystore:
+		if c.KeystorePath == "" {
+			return errors.New("keystore signer requires a keystore path")
+		}
+	case TypeRemote:
+		if c.Endpoint == "" || c.Address == "" {
+			return errors.New("remote signer requires an endpoint and an address")
+		}
+	case TypeKMS:
+		if c.KMSKeyName == "" || c.Address == "" || c.KMSCredentials == "" {
+			return errors.New("kms signer requires a key name, an address and credentials")
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownType, c.Type)
+	}
+	return nil
+}
+
+// NewSigner creates the configured signer.
+func NewSigner(ctx context.Context, cfg Config) (Signer, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case TypeLocal:
+		key, err := bsscore.GetConfiguredPrivateKey(cfg.Mnemonic, cfg.HDPath, cfg.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewLocalSigner(key), nil
+	case TypeKeystore:
+		return NewKeystoreSigner(cfg.KeystorePath, cfg.KeystorePassword)
+	case TypeRemote:
+		return NewRemoteSigner(ctx, cfg.Endpoint, cfg.Address)
+	default:
+		return NewKMSSigner(ctx, cfg.KMSKeyName, cfg.Address, cfg.KMSCredentials)
+	}
+}
@@ -0,0 +1,104 @@
+package signer
+
+import (
+	"github.com/urfave/cli"
+)
+
+const (
+	TypeFlagName             = "signer.type"
+	PrivateKeyFlagName       = "signer.private-key"
+	MnemonicFlagName         = "signer.mnemonic"
+	HDPathFlagName           = "signer.hd-path"
+	KeystorePathFlagName     = "signer.keystore"
+	KeystorePasswordFlagName = "signer.password"
+	EndpointFlagName         = "signer.endpoint"
+	AddressFlagName          = "signer.address"
+	KMSKeyNameFlagName       = "signer.kms-key-name"
+	KMSCredentialsFlagName   = "signer.kms-credentials"
+)
+
+// CLIFlags returns the signer flags of a service, their environment variables
+// are prefixed by envPrefix.
+func CLIFlags(envPrefix string) []cli.Flag {
+	return PrefixedCLIFlags("", envPrefix)
+}
+
+// PrefixedCLIFlags returns the signer flags of one of the keys of a service
+// holding several keys, their names are prefixed by namePrefix, such as
+// "sequencer." for sequencer.signer.type.
+func PrefixedCLIFlags(namePrefix, envPrefix string) []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   namePrefix + TypeFlagName,
+			Usage:  "Signer of the transactions: local, keystore, remote or kms",
+			EnvVar: envPrefix + "_SIGNER_TYPE",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + PrivateKeyFlagName,
+			Usage:  "Private key of the local signer",
+			EnvVar: envPrefix + "_SIGNER_PRIVATE_KEY",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + MnemonicFlagName,
+			Usage:  "Mnemonic the key of the local signer is derived from",
+			EnvVar: envPrefix + "_SIGNER_MNEMONIC",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + HDPathFlagName,
+			Usage:  "HD path the key of the local signer is derived with",
+			EnvVar: envPrefix + "_SIGNER_HD_PATH",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + KeystorePathFlagName,
+			Usage:  "Path of the encrypted keystore file of the keystore signer",
+			EnvVar: envPrefix + "_SIGNER_KEYSTORE",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + KeystorePasswordFlagName,
+			Usage:  "Password of the keystore file",
+			EnvVar: envPrefix + "_SIGNER_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + EndpointFlagName,
+			Usage:  "JSON-RPC endpoint of the remote signer",
+			EnvVar: envPrefix + "_SIGNER_ENDPOINT",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + AddressFlagName,
+			Usage:  "Address of the remote or kms signer",
+			EnvVar: envPrefix + "_SIGNER_ADDRESS",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + KMSKeyNameFlagName,
+			Usage:  "Key name of the kms signer",
+			EnvVar: envPrefix + "_SIGNER_KMS_KEY_NAME",
+		},
+		cli.StringFlag{
+			Name:   namePrefix + KMSCredentialsFlagName,
+			Usage:  "Hex encoded JSON credentials of the kms signer",
+			EnvVar: envPrefix + "_SIGNER_KMS_CREDENTIALS",
+		},
+	}
+}
+
+// ReadCLIConfig reads the signer configuration from the flags of CLIFlags.
+func ReadCLIConfig(ctx *cli.Context) Config {
+	return ReadPrefixedCLIConfig(ctx, "")
+}
+
+// ReadPrefixedCLIConfig reads the signer configuration from the flags of
+// PrefixedCLIFlags.
+func ReadPrefixedCLIConfig(ctx *cli.Context, namePrefix string) Config {
+	return Config{
+		Type:             ctx.GlobalString(namePrefix + TypeFlagName),
+		PrivateKey:       ctx.GlobalString(namePrefix + PrivateKeyFlagName),
+		Mnemonic:         ctx.GlobalString(namePrefix + MnemonicFlagName),
+		HDPath:           ctx.GlobalString(namePrefix + HDPathFlagName),
+		KeystorePath:     ctx.GlobalString(namePrefix + KeystorePathFlagName),
+		KeystorePassword: ctx.GlobalString(namePrefix + KeystorePasswordFlagName),
+		Endpoint:         ctx.GlobalString(namePrefix + EndpointFlagName),
+		Address:          ctx.GlobalString(namePrefix + AddressFlagName),
+		KMSKeyName:       ctx.GlobalString(namePrefix + KMSKeyNameFlagName),
+		KMSCredentials:   ctx.GlobalString(namePrefix + KMSCredentialsFlagName),
+	}
+}
@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"google.golang.org/api/option"
+
+	bsscore "github.com/mantlenetworkio/mantle/bss-core"
+)
+
+// KMSSigner signs with a key of the GCP Key Management Service.
+type KMSSigner struct {
+	key *bsscore.ManagedKey
+}
+
+// NewKMSSigner returns the signer of the KMS key keyName of address, the
+// credentials are the hex encoded JSON credentials of the GCP account.
+func NewKMSSigner(ctx context.Context, keyName, address, credentials string) (*KMSSigner, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid kms address: %q", address)
+	}
+	credentialsJSON, err := hex.DecodeString(credentials)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kms credentials: %w", err)
+	}
+	client, err := kms.NewKeyManagementClient(ctx, option.WithCredentialsJSON(credentialsJSON))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create kms client: %w", err)
+	}
+	return &KMSSigner{
+		key: &bsscore.ManagedKey{
+			KeyName:      keyName,
+			EthereumAddr: common.HexToAddress(address),
+			Gclient:      client,
+		},
+	}, nil
+}
+
+func (s *KMSSigner) Address() common.Address {
+	return s.key.EthereumAddr
+}
+
+func (s *KMSSigner) SignTransaction(ctx context.Context, chainID *big.Int, tx *types.Transaction) (*types.Transaction, error) {
+	if chainID == nil {
+		return nil, ErrNoChainID
+	}
+	signer := types.LatestSignerForChainID(chainID)
+	sig, err := s.key.SignHash(ctx, signer.Hash(tx))
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
@@ -0,0 +1,52 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// LocalSigner signs with a private key held in memory.
+type LocalSigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewLocalSigner returns the signer of key.
+func NewLocalSigner(key *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+// NewKeystoreSigner decrypts the keystore file at path with password.
+func NewKeystoreSigner(path, password string) (*LocalSigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read keystore: %w", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt keystore %s: %w", path, err)
+	}
+	return NewLocalSigner(key.PrivateKey), nil
+}
+
+func (s *LocalSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *LocalSigner) SignTransaction(ctx context.Context, chainID *big.Int, tx *types.Transaction) (*types.Transaction, error) {
+	if chainID == nil {
+		return nil, ErrNoChainID
+	}
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
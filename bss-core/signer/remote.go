@@ -0,0 +1,137 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RemoteSigner signs with an Ethereum remote signer, such as clef or
+// web3signer, through its eth_signTransaction JSON-RPC method.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewRemoteSigner dials the remote signer at endpoint, which signs for
+// address.
+func NewRemoteSigner(ctx context.Context, endpoint, address string) (*RemoteSigner, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid remote signer address: %q", address)
+	}
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cannot dial remote signer: %w", err)
+	}
+	return &RemoteSigner{
+		client:  client,
+		address: common.HexToAddress(address),
+	}, nil
+}
+
+// signTransactionArgs are the arguments of eth_signTransaction
+type signTransactionArgs struct {
+	From                 common.Address    `json:"from"`
+	To                   *common.Address   `json:"to,omitempty"`
+	Gas                  hexutil.Uint64    `json:"gas"`
+	GasPrice             *hexutil.Big      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big      `json:"value"`
+	Nonce                hexutil.Uint64    `json:"nonce"`
+	Data                 hexutil.Bytes     `json:"data"`
+	ChainID              *hexutil.Big      `json:"chainId"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+}
+
+func newSignTransactionArgs(from common.Address, chainID *big.Int, tx *types.Transaction) *signTransactionArgs {
+	args := &signTransactionArgs{
+		From:    from,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.AccessListTxType:
+		accessList := tx.AccessList()
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+		args.AccessList = &accessList
+	default:
+		accessList := tx.AccessList()
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		args.AccessList = &accessList
+	}
+	return args
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTransaction asks the remote signer to sign tx and checks that the
+// signed transaction is tx, signed by the address of the signer.
+func (s *RemoteSigner) SignTransaction(ctx context.Context, chainID *big.Int, tx *types.Transaction) (*types.Transaction, error) {
+	if chainID == nil {
+		return nil, ErrNoChainID
+	}
+	var result json.RawMessage
+	args := newSignTransactionArgs(s.address, chainID, tx)
+	if err := s.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+	raw, err := decodeSignTransactionResult(result)
+	if err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %w", err)
+	}
+
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, fmt.Errorf("remote signer signed another transaction %s", signed.Hash())
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid signature: %w", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s instead of %s", sender, s.address)
+	}
+	return signed, nil
+}
+
+// Close closes the connection to the remote signer.
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// decodeSignTransactionResult returns the raw signed transaction of an
+// eth_signTransaction result. Clef and geth return an object with the raw
+// transaction, other signers return the raw transaction itself.
+func decodeSignTransactionResult(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+	var object struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &object); err != nil || len(object.Raw) == 0 {
+		return nil, fmt.Errorf("remote signer returned an invalid result: %s", result)
+	}
+	return object.Raw, nil
+}
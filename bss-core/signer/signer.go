@@ -0,0 +1,52 @@
+// Package signer signs the transactions of the services with a key held in
+// memory, in an encrypted keystore file, by a remote signer or by the Key
+// Management Service (KMS) of the Google Cloud Platform (GCP).
+package signer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrNoChainID signals that a transaction was signed without chain id.
+	ErrNoChainID = errors.New("no chain id")
+)
+
+// Signer signs the transactions sent from one address.
+type Signer interface {
+	// Address returns the address of the transactions signed by the signer.
+	Address() common.Address
+
+	// SignTransaction returns tx signed for the chain chainID.
+	SignTransaction(ctx context.Context, chainID *big.Int, tx *types.Transaction) (*types.Transaction, error)
+}
+
+// NewTransactOpts returns the transactor of s for the chain chainID. Ctx
+// applies to the entire lifespan of the bind.TransactOpts.
+func NewTransactOpts(ctx context.Context, s Signer, chainID *big.Int) (*bind.TransactOpts, error) {
+	if chainID == nil {
+		return nil, bind.ErrNoChainID
+	}
+	return &bind.TransactOpts{
+		Context: ctx,
+		From:    s.Address(),
+		Signer:  NewSignerFn(ctx, s, chainID),
+	}, nil
+}
+
+// NewSignerFn returns the bind.SignerFn of s for the chain chainID. Ctx
+// applies to the entire lifespan of the bind.SignerFn.
+func NewSignerFn(ctx context.Context, s Signer, chainID *big.Int) bind.SignerFn {
+	return func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if addr != s.Address() {
+			return nil, bind.ErrNotAuthorized
+		}
+		return s.SignTransaction(ctx, chainID, tx)
+	}
+}
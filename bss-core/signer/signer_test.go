@@ -0,0 +1,199 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+var (
+	testChainID = big.NewInt(5000)
+	testTo      = common.HexToAddress("0x4200000000000000000000000000000000000005")
+)
+
+func testTx() *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     3,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21000,
+		To:        &testTo,
+		Value:     big.NewInt(1),
+		Data:      []byte{0x01, 0x02},
+	})
+}
+
+func requireSignedBy(t *testing.T, tx *types.Transaction, address common.Address) {
+	sender, err := types.Sender(types.LatestSignerForChainID(testChainID), tx)
+	require.NoError(t, err)
+	require.Equal(t, address, sender)
+}
+
+func TestLocalSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s := NewLocalSigner(key)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), s.Address())
+
+	opts, err := NewTransactOpts(context.Background(), s, testChainID)
+	require.NoError(t, err)
+	signed, err := opts.Signer(s.Address(), testTx())
+	require.NoError(t, err)
+	requireSignedBy(t, signed, s.Address())
+
+	_, err = opts.Signer(testTo, testTx())
+	require.Error(t, err)
+	_, err = s.SignTransaction(context.Background(), nil, testTx())
+	require.ErrorIs(t, err, ErrNoChainID)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "password", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0600))
+
+	_, err = NewSigner(context.Background(), Config{Type: TypeKeystore, KeystorePath: path, KeystorePassword: "wrong"})
+	require.Error(t, err)
+	s, err := NewSigner(context.Background(), Config{Type: TypeKeystore, KeystorePath: path, KeystorePassword: "password"})
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), s.Address())
+
+	signed, err := s.SignTransaction(context.Background(), testChainID, testTx())
+	require.NoError(t, err)
+	requireSignedBy(t, signed, s.Address())
+}
+
+// stubSigner is an eth_signTransaction JSON-RPC stub
+type stubSigner struct {
+	key *ecdsa.PrivateKey
+	// object returns the result as an object, as clef does
+	object bool
+	// tamper changes the nonce of the transaction before signing it
+	tamper bool
+}
+
+func (s *stubSigner) SignTransaction(args signTransactionArgs) (interface{}, error) {
+	nonce := uint64(args.Nonce)
+	if s.tamper {
+		nonce++
+	}
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     nonce,
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	})
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if s.object {
+		return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}, nil
+	}
+	return hexutil.Bytes(raw), nil
+}
+
+func newStubSigner(t *testing.T, stub *stubSigner) string {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", stub))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	stub := &stubSigner{key: key}
+	endpoint := newStubSigner(t, stub)
+
+	s, err := NewSigner(context.Background(), Config{Type: TypeRemote, Endpoint: endpoint, Address: address.Hex()})
+	require.NoError(t, err)
+	require.Equal(t, address, s.Address())
+
+	tx := testTx()
+	signed, err := s.SignTransaction(context.Background(), testChainID, tx)
+	require.NoError(t, err)
+	requireSignedBy(t, signed, address)
+	require.Equal(t, tx.Nonce(), signed.Nonce())
+
+	stub.object = true
+	signed, err = s.SignTransaction(context.Background(), testChainID, tx)
+	require.NoError(t, err)
+	requireSignedBy(t, signed, address)
+
+	// the signed transaction must be the requested one
+	stub.tamper = true
+	_, err = s.SignTransaction(context.Background(), testChainID, tx)
+	require.ErrorContains(t, err, "another transaction")
+	stub.tamper = false
+
+	// and must be signed by the configured address
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	stub.key = other
+	_, err = s.SignTransaction(context.Background(), testChainID, tx)
+	require.ErrorContains(t, err, "instead of")
+}
+
+func TestConfigCheck(t *testing.T) {
+	require.False(t, Config{}.Enabled())
+	require.ErrorIs(t, Config{Type: "ledger"}.Check(), ErrUnknownType)
+	require.Error(t, Config{Type: TypeLocal}.Check())
+	require.NoError(t, Config{Type: TypeLocal, Mnemonic: "mnemonic", HDPath: "m/44'/60'/0'/0/0"}.Check())
+	require.Error(t, Config{Type: TypeKeystore}.Check())
+	require.Error(t, Config{Type: TypeRemote, Endpoint: "http://localhost:8550"}.Check())
+	require.Error(t, Config{Type: TypeKMS, KMSKeyName: "key", Address: "0x01"}.Check())
+}
+
+func TestCLIFlags(t *testing.T) {
+	t.Setenv("TEST_PROPOSER_SIGNER_ADDRESS", "0x01")
+	var cfg, proposerCfg Config
+	app := cli.NewApp()
+	app.Flags = append(CLIFlags("TEST"), PrefixedCLIFlags("proposer.", "TEST_PROPOSER")...)
+	app.Action = func(ctx *cli.Context) error {
+		cfg = ReadCLIConfig(ctx)
+		proposerCfg = ReadPrefixedCLIConfig(ctx, "proposer.")
+		return nil
+	}
+	require.NoError(t, app.Run([]string{"test",
+		"--signer.type", "remote", "--signer.endpoint", "http://localhost:8550",
+		"--proposer.signer.type", "kms", "--proposer.signer.kms-key-name", "key",
+	}))
+	require.Equal(t, Config{Type: TypeRemote, Endpoint: "http://localhost:8550"}, cfg)
+	require.Equal(t, Config{Type: TypeKMS, KMSKeyName: "key", Address: "0x01"}, proposerCfg)
+}
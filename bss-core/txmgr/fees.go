@@ -0,0 +1,427 @@
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultTipPercentile is the percentile of recent priority fees used
+	// as the tip if none is configured.
+	DefaultTipPercentile = 50
+
+	// DefaultFeeHistoryBlocks is the number of recent blocks sampled for
+	// priority fees if none is configured.
+	DefaultFeeHistoryBlocks = 20
+
+	// DefaultForecastBlocks is the number of blocks ahead the base fee is
+	// forecast if none is configured. Six full blocks roughly double the
+	// base fee, matching CalcGasFeeCap.
+	DefaultForecastBlocks = 6
+
+	// DefaultPriceBump is the percentage by which replacements raise both
+	// fee caps if none is configured, the minimum geth accepts.
+	DefaultPriceBump = 10
+)
+
+var (
+	// ErrFeeCapReached signals that a transaction would cost more than
+	// MaxTxFee if it were mined.
+	ErrFeeCapReached = errors.New("transaction fee cap reached")
+
+	// ErrSpendCapReached signals that a transaction could take the spend of
+	// the past hour above MaxHourlySpend.
+	ErrSpendCapReached = errors.New("hourly spend cap reached")
+)
+
+// FeeBackend is the subset of an L1 client used by a FeeStrategy.
+type FeeBackend interface {
+	// HeaderByNumber returns the header at number, or the latest header if
+	// number is nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+
+	// FeeHistory returns the base fees and the requested priority fee
+	// percentiles of the blockCount blocks ending at lastBlock.
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int,
+		rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// FeeMetrics records the spend of a FeeStrategy, it is implemented by
+// metrics.Metrics.
+type FeeMetrics interface {
+	// FeeSpentWei tracks the total fees paid by confirmed transactions.
+	FeeSpentWei() prometheus.Counter
+
+	// FeeBumps tracks the number of fee bumps of replacement transactions.
+	FeeBumps() prometheus.Counter
+
+	// FeeCapHits tracks the number of publications withheld by a cap.
+	FeeCapHits() prometheus.Counter
+}
+
+// FeeConfig houses parameters for altering the behavior of a FeeStrategy. Zero
+// values select the defaults, and disable the caps and the wait window.
+type FeeConfig struct {
+	// TipPercentile is the percentile of the priority fees paid in recent
+	// blocks that is offered as the tip.
+	TipPercentile float64
+
+	// HistoryBlocks is the number of recent blocks sampled for priority
+	// fees.
+	HistoryBlocks uint64
+
+	// ForecastBlocks is the number of blocks ahead the base fee is forecast,
+	// assuming every block is full and raises it by the maximum 12.5%. The
+	// fee cap covers the forecast so that the transaction stays includable
+	// that long.
+	ForecastBlocks uint64
+
+	// MinTipCap is the lowest tip offered, which is also used when recent
+	// blocks paid no priority fees.
+	MinTipCap *big.Int
+
+	// PriceBump is the percentage by which a replacement raises both the tip
+	// and the fee cap of the transaction it replaces.
+	PriceBump uint64
+
+	// MaxTxFee caps the worst case fee of a single transaction, its gas
+	// limit times its fee cap.
+	MaxTxFee *big.Int
+
+	// MaxHourlySpend caps the fees paid over any hour, counting the worst
+	// case fee of the transactions that have not confirmed yet.
+	MaxHourlySpend *big.Int
+
+	// CheapBaseFee is the base fee at or below which batches are submitted
+	// right away.
+	CheapBaseFee *big.Int
+
+	// MaxWait is how long a batch waits for the base fee to fall to
+	// CheapBaseFee before it is submitted regardless.
+	MaxWait time.Duration
+}
+
+// Fees are the EIP-1559 fee parameters of a transaction.
+type Fees struct {
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// publication is the transaction last approved at a nonce.
+type publication struct {
+	fees   Fees
+	maxFee *big.Int
+}
+
+// spend is the fee paid by a confirmed transaction.
+type spend struct {
+	at  time.Time
+	fee *big.Int
+}
+
+// FeeStrategy prices the transactions of a single account. Fees are sampled
+// from recent blocks, replacements are bumped above the transaction they
+// replace so that nodes accept them, and transactions are only approved for
+// publication within the per-transaction and hourly spend caps.
+type FeeStrategy struct {
+	name    string
+	cfg     FeeConfig
+	backend FeeBackend
+	metrics FeeMetrics
+
+	mu        sync.Mutex
+	published map[uint64]publication
+	spends    []spend
+}
+
+// NewFeeStrategy initializes a new FeeStrategy with the passed FeeConfig.
+func NewFeeStrategy(
+	name string,
+	cfg FeeConfig,
+	backend FeeBackend,
+	metrics FeeMetrics,
+) *FeeStrategy {
+
+	if cfg.TipPercentile == 0 {
+		cfg.TipPercentile = DefaultTipPercentile
+	}
+	if cfg.HistoryBlocks == 0 {
+		cfg.HistoryBlocks = DefaultFeeHistoryBlocks
+	}
+	if cfg.ForecastBlocks == 0 {
+		cfg.ForecastBlocks = DefaultForecastBlocks
+	}
+	if cfg.PriceBump == 0 {
+		cfg.PriceBump = DefaultPriceBump
+	}
+
+	return &FeeStrategy{
+		name:      name,
+		cfg:       cfg,
+		backend:   backend,
+		metrics:   metrics,
+		published: make(map[uint64]publication),
+	}
+}
+
+// SuggestFees returns the fees for a transaction at nonce. If a transaction
+// was already approved at nonce, the fees are raised by at least PriceBump
+// percent above it so that the node accepts the replacement.
+func (f *FeeStrategy) SuggestFees(
+	ctx context.Context,
+	nonce uint64,
+) (*Fees, error) {
+
+	history, err := f.backend.FeeHistory(
+		ctx, f.cfg.HistoryBlocks, nil, []float64{f.cfg.TipPercentile},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(history.BaseFee) == 0 {
+		return nil, errors.New("fee history has no base fees")
+	}
+
+	// The last base fee returned is the one of the next block.
+	nextBaseFee := history.BaseFee[len(history.BaseFee)-1]
+	baseFee := ForecastBaseFee(nextBaseFee, f.cfg.ForecastBlocks)
+	gasTipCap := f.sampleTip(history)
+
+	fees := &Fees{
+		GasTipCap: gasTipCap,
+		GasFeeCap: new(big.Int).Add(gasTipCap, baseFee),
+	}
+
+	f.mu.Lock()
+	prev, ok := f.published[nonce]
+	f.mu.Unlock()
+	if ok {
+		fees = BumpFees(&prev.fees, fees, f.cfg.PriceBump)
+	}
+
+	return fees, nil
+}
+
+// sampleTip returns the median across blocks of the TipPercentile priority
+// fee, ignoring empty blocks, and no less than MinTipCap.
+func (f *FeeStrategy) sampleTip(history *ethereum.FeeHistory) *big.Int {
+	var tips []*big.Int
+	for i, rewards := range history.Reward {
+		if len(rewards) == 0 || rewards[0] == nil {
+			continue
+		}
+		if i < len(history.GasUsedRatio) && history.GasUsedRatio[i] == 0 {
+			continue
+		}
+		tips = append(tips, rewards[0])
+	}
+
+	tip := new(big.Int)
+	if len(tips) > 0 {
+		sort.Slice(tips, func(i, j int) bool {
+			return tips[i].Cmp(tips[j]) < 0
+		})
+		tip.Set(tips[len(tips)/2])
+	}
+	if f.cfg.MinTipCap != nil && tip.Cmp(f.cfg.MinTipCap) < 0 {
+		tip.Set(f.cfg.MinTipCap)
+	}
+	return tip
+}
+
+// Approve checks that tx fits within MaxTxFee and MaxHourlySpend and records
+// its fees as the ones a replacement at the same nonce must exceed. Only
+// approved transactions should be published. ErrFeeCapReached or
+// ErrSpendCapReached is returned if tx does not fit.
+func (f *FeeStrategy) Approve(tx *types.Transaction) error {
+	maxFee := new(big.Int).Mul(
+		tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()),
+	)
+	nonce := tx.Nonce()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cfg.MaxTxFee != nil && maxFee.Cmp(f.cfg.MaxTxFee) > 0 {
+		f.metrics.FeeCapHits().Inc()
+		return fmt.Errorf("%w: max fee %v exceeds %v", ErrFeeCapReached,
+			maxFee, f.cfg.MaxTxFee)
+	}
+
+	if f.cfg.MaxHourlySpend != nil {
+		committed := f.committedSpend(nonce)
+		committed.Add(committed, maxFee)
+		if committed.Cmp(f.cfg.MaxHourlySpend) > 0 {
+			f.metrics.FeeCapHits().Inc()
+			return fmt.Errorf("%w: committed spend %v exceeds %v",
+				ErrSpendCapReached, committed, f.cfg.MaxHourlySpend)
+		}
+	}
+
+	if _, ok := f.published[nonce]; ok {
+		f.metrics.FeeBumps().Inc()
+	}
+	f.published[nonce] = publication{
+		fees: Fees{
+			GasTipCap: tx.GasTipCap(),
+			GasFeeCap: tx.GasFeeCap(),
+		},
+		maxFee: maxFee,
+	}
+
+	return nil
+}
+
+// committedSpend returns the fees paid over the past hour plus the worst case
+// fee of the approved transactions that have not confirmed, other than the one
+// at nonce. The caller must hold f.mu.
+func (f *FeeStrategy) committedSpend(nonce uint64) *big.Int {
+	cutoff := time.Now().Add(-time.Hour)
+	i := 0
+	for i < len(f.spends) && !f.spends[i].at.After(cutoff) {
+		i++
+	}
+	f.spends = f.spends[i:]
+
+	committed := new(big.Int)
+	for _, s := range f.spends {
+		committed.Add(committed, s.fee)
+	}
+	for n, p := range f.published {
+		if n != nonce {
+			committed.Add(committed, p.maxFee)
+		}
+	}
+	return committed
+}
+
+// RecordSpend records the fee paid by the confirmed transaction at nonce,
+// which also confirms every lower nonce. The price paid is derived from the
+// base fee of the block that included it and the fees last approved at nonce,
+// or is the approved fee cap if the block cannot be fetched.
+func (f *FeeStrategy) RecordSpend(
+	ctx context.Context,
+	nonce uint64,
+	receipt *types.Receipt,
+) {
+
+	var baseFee *big.Int
+	header, err := f.backend.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		log.Warn(f.name+" unable to fetch block of confirmed tx",
+			"block", receipt.BlockNumber, "err", err)
+	} else {
+		baseFee = header.BaseFee
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fee := new(big.Int)
+	if p, ok := f.published[nonce]; ok {
+		price := p.fees.GasFeeCap
+		if baseFee != nil {
+			price = new(big.Int).Add(baseFee, p.fees.GasTipCap)
+			if price.Cmp(p.fees.GasFeeCap) > 0 {
+				price = p.fees.GasFeeCap
+			}
+		}
+		fee.Mul(new(big.Int).SetUint64(receipt.GasUsed), price)
+	}
+
+	for n := range f.published {
+		if n <= nonce {
+			delete(f.published, n)
+		}
+	}
+	f.spends = append(f.spends, spend{at: time.Now(), fee: fee})
+
+	feeFloat, _ := new(big.Float).SetInt(fee).Float64()
+	f.metrics.FeeSpentWei().Add(feeFloat)
+}
+
+// ShouldWait reports whether a batch pending since the given time should wait
+// for cheaper gas, which is the case while the base fee is above CheapBaseFee
+// and the batch has waited less than MaxWait.
+func (f *FeeStrategy) ShouldWait(
+	ctx context.Context,
+	since time.Time,
+) (bool, error) {
+
+	if f.cfg.CheapBaseFee == nil || f.cfg.MaxWait == 0 {
+		return false, nil
+	}
+	if time.Now().Sub(since) >= f.cfg.MaxWait {
+		return false, nil
+	}
+
+	header, err := f.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	if header.BaseFee == nil || header.BaseFee.Cmp(f.cfg.CheapBaseFee) <= 0 {
+		return false, nil
+	}
+
+	log.Info(f.name+" waiting for cheaper gas", "baseFee", header.BaseFee,
+		"cheapBaseFee", f.cfg.CheapBaseFee, "waited", time.Now().Sub(since))
+	return true, nil
+}
+
+// ForecastBaseFee returns the base fee after the given number of full blocks,
+// each raising it by the maximum 12.5% allowed by EIP-1559.
+func ForecastBaseFee(baseFee *big.Int, blocks uint64) *big.Int {
+	forecast := new(big.Int).Set(baseFee)
+	for i := uint64(0); i < blocks; i++ {
+		forecast.Add(forecast, divCeil(forecast, big.NewInt(8)))
+	}
+	return forecast
+}
+
+// BumpFees returns the higher of the sampled fees and those of prev raised by
+// priceBump percent, rounding up, so that a replacement of prev is accepted.
+// Geth rejects replacements that raise either the tip or the fee cap by less
+// than its price bump, 10% by default.
+func BumpFees(prev, sampled *Fees, priceBump uint64) *Fees {
+	bump := func(prev, sampled *big.Int) *big.Int {
+		min := divCeil(
+			new(big.Int).Mul(prev, new(big.Int).SetUint64(100+priceBump)),
+			big.NewInt(100),
+		)
+		if sampled.Cmp(min) > 0 {
+			return new(big.Int).Set(sampled)
+		}
+		return min
+	}
+
+	return &Fees{
+		GasTipCap: bump(prev.GasTipCap, sampled.GasTipCap),
+		GasFeeCap: bump(prev.GasFeeCap, sampled.GasFeeCap),
+	}
+}
+
+// IsCapError reports whether err signals that a transaction was withheld by a
+// fee or spend cap.
+func IsCapError(err error) bool {
+	return errors.Is(err, ErrFeeCapReached) ||
+		errors.Is(err, ErrSpendCapReached)
+}
+
+// divCeil returns x/y rounded up.
+func divCeil(x, y *big.Int) *big.Int {
+	q, m := new(big.Int).DivMod(x, y, new(big.Int))
+	if m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
@@ -0,0 +1,226 @@
+package txmgr_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// feeBackend implements txmgr.FeeBackend with a fixed fee history.
+type feeBackend struct {
+	history *ethereum.FeeHistory
+	baseFee *big.Int
+}
+
+func (b *feeBackend) HeaderByNumber(
+	ctx context.Context,
+	number *big.Int,
+) (*types.Header, error) {
+
+	return &types.Header{BaseFee: b.baseFee}, nil
+}
+
+func (b *feeBackend) FeeHistory(
+	ctx context.Context,
+	blockCount uint64,
+	lastBlock *big.Int,
+	rewardPercentiles []float64,
+) (*ethereum.FeeHistory, error) {
+
+	return b.history, nil
+}
+
+// feeMetrics implements txmgr.FeeMetrics with unregistered counters.
+type feeMetrics struct {
+	spent, bumps, capHits prometheus.Counter
+}
+
+func newFeeMetrics() *feeMetrics {
+	return &feeMetrics{
+		spent:   prometheus.NewCounter(prometheus.CounterOpts{Name: "spent"}),
+		bumps:   prometheus.NewCounter(prometheus.CounterOpts{Name: "bumps"}),
+		capHits: prometheus.NewCounter(prometheus.CounterOpts{Name: "cap_hits"}),
+	}
+}
+
+func (m *feeMetrics) FeeSpentWei() prometheus.Counter { return m.spent }
+func (m *feeMetrics) FeeBumps() prometheus.Counter    { return m.bumps }
+func (m *feeMetrics) FeeCapHits() prometheus.Counter  { return m.capHits }
+
+// newFeeBackend returns a feeBackend whose history pays tips of 10, 30 and 20
+// wei, with an empty block in between, and a next base fee of 100 wei.
+func newFeeBackend() *feeBackend {
+	return &feeBackend{
+		history: &ethereum.FeeHistory{
+			Reward: [][]*big.Int{
+				{big.NewInt(10)}, {big.NewInt(0)}, {big.NewInt(30)},
+				{big.NewInt(20)},
+			},
+			BaseFee: []*big.Int{
+				big.NewInt(90), big.NewInt(95), big.NewInt(90),
+				big.NewInt(95), big.NewInt(100),
+			},
+			GasUsedRatio: []float64{0.6, 0, 0.7, 0.5},
+		},
+		baseFee: big.NewInt(100),
+	}
+}
+
+func feeTx(nonce uint64, fees *txmgr.Fees, gas uint64) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Gas:       gas,
+	})
+}
+
+// TestForecastBaseFee asserts that the base fee forecast compounds the maximum
+// 12.5% increase per block, rounding up.
+func TestForecastBaseFee(t *testing.T) {
+	require.Equal(t, big.NewInt(100), txmgr.ForecastBaseFee(big.NewInt(100), 0))
+	require.Equal(t, big.NewInt(900), txmgr.ForecastBaseFee(big.NewInt(800), 1))
+	require.Equal(t, big.NewInt(206), txmgr.ForecastBaseFee(big.NewInt(100), 6))
+}
+
+// TestBumpFees asserts that a replacement raises both caps by at least the
+// price bump, keeping sampled fees that are already higher.
+func TestBumpFees(t *testing.T) {
+	prev := &txmgr.Fees{GasTipCap: big.NewInt(15), GasFeeCap: big.NewInt(200)}
+
+	fees := txmgr.BumpFees(prev, &txmgr.Fees{
+		GasTipCap: big.NewInt(10),
+		GasFeeCap: big.NewInt(300),
+	}, 10)
+	require.Equal(t, big.NewInt(17), fees.GasTipCap)
+	require.Equal(t, big.NewInt(300), fees.GasFeeCap)
+}
+
+// TestFeeStrategySuggestsFees asserts that the tip is the median of the
+// sampled percentile over non-empty blocks and that the fee cap covers the
+// forecast base fee.
+func TestFeeStrategySuggestsFees(t *testing.T) {
+	f := txmgr.NewFeeStrategy(
+		"TEST", txmgr.FeeConfig{}, newFeeBackend(), newFeeMetrics(),
+	)
+
+	fees, err := f.SuggestFees(context.Background(), 0)
+	require.Nil(t, err)
+	require.Equal(t, big.NewInt(20), fees.GasTipCap)
+	require.Equal(t, big.NewInt(226), fees.GasFeeCap)
+
+	f = txmgr.NewFeeStrategy("TEST", txmgr.FeeConfig{
+		MinTipCap: big.NewInt(25),
+	}, newFeeBackend(), newFeeMetrics())
+
+	fees, err = f.SuggestFees(context.Background(), 0)
+	require.Nil(t, err)
+	require.Equal(t, big.NewInt(25), fees.GasTipCap)
+}
+
+// TestFeeStrategyBumpsReplacements asserts that fees suggested at a nonce that
+// already has an approved transaction are bumped above it.
+func TestFeeStrategyBumpsReplacements(t *testing.T) {
+	m := newFeeMetrics()
+	f := txmgr.NewFeeStrategy("TEST", txmgr.FeeConfig{}, newFeeBackend(), m)
+	ctx := context.Background()
+
+	fees, err := f.SuggestFees(ctx, 3)
+	require.Nil(t, err)
+	require.Nil(t, f.Approve(feeTx(3, fees, 21000)))
+
+	bumped, err := f.SuggestFees(ctx, 3)
+	require.Nil(t, err)
+	require.Equal(t, big.NewInt(22), bumped.GasTipCap)
+	require.Equal(t, big.NewInt(249), bumped.GasFeeCap)
+
+	// Other nonces are not bumped.
+	other, err := f.SuggestFees(ctx, 4)
+	require.Nil(t, err)
+	require.Equal(t, fees, other)
+
+	require.Nil(t, f.Approve(feeTx(3, bumped, 21000)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.bumps))
+}
+
+// TestFeeStrategyEnforcesCaps asserts that transactions exceeding the per-tx
+// cap or the hourly spend, including unconfirmed transactions, are withheld.
+func TestFeeStrategyEnforcesCaps(t *testing.T) {
+	m := newFeeMetrics()
+	backend := newFeeBackend()
+	f := txmgr.NewFeeStrategy("TEST", txmgr.FeeConfig{
+		MaxTxFee:       big.NewInt(1000),
+		MaxHourlySpend: big.NewInt(1500),
+	}, backend, m)
+
+	fees := &txmgr.Fees{GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10)}
+
+	err := f.Approve(feeTx(0, fees, 101))
+	require.True(t, errors.Is(err, txmgr.ErrFeeCapReached))
+	require.True(t, txmgr.IsCapError(err))
+
+	require.Nil(t, f.Approve(feeTx(0, fees, 100)))
+
+	// A replacement at the same nonce does not count its predecessor.
+	require.Nil(t, f.Approve(feeTx(0, fees, 100)))
+
+	// The unconfirmed transaction at nonce 0 takes up 1000 wei.
+	err = f.Approve(feeTx(1, fees, 60))
+	require.True(t, errors.Is(err, txmgr.ErrSpendCapReached))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.capHits))
+
+	// Once confirmed only the fee actually paid counts, the base fee of the
+	// block plus the tip.
+	backend.baseFee = big.NewInt(4)
+	f.RecordSpend(context.Background(), 0, &types.Receipt{
+		GasUsed:     80,
+		BlockNumber: big.NewInt(1),
+	})
+	require.Equal(t, 400.0, testutil.ToFloat64(m.spent))
+	require.Nil(t, f.Approve(feeTx(1, fees, 60)))
+	require.Nil(t, f.Approve(feeTx(2, fees, 50)))
+
+	err = f.Approve(feeTx(3, fees, 1))
+	require.True(t, errors.Is(err, txmgr.ErrSpendCapReached))
+}
+
+// TestFeeStrategyShouldWait asserts that batches wait for the base fee to fall
+// to CheapBaseFee for at most MaxWait.
+func TestFeeStrategyShouldWait(t *testing.T) {
+	backend := newFeeBackend()
+	ctx := context.Background()
+
+	f := txmgr.NewFeeStrategy(
+		"TEST", txmgr.FeeConfig{}, backend, newFeeMetrics(),
+	)
+	wait, err := f.ShouldWait(ctx, time.Now())
+	require.Nil(t, err)
+	require.False(t, wait)
+
+	f = txmgr.NewFeeStrategy("TEST", txmgr.FeeConfig{
+		CheapBaseFee: big.NewInt(50),
+		MaxWait:      time.Minute,
+	}, backend, newFeeMetrics())
+
+	wait, err = f.ShouldWait(ctx, time.Now())
+	require.Nil(t, err)
+	require.True(t, wait)
+
+	wait, err = f.ShouldWait(ctx, time.Now().Add(-time.Minute))
+	require.Nil(t, err)
+	require.False(t, wait)
+
+	backend.baseFee = big.NewInt(50)
+	wait, err = f.ShouldWait(ctx, time.Now())
+	require.Nil(t, err)
+	require.False(t, wait)
+}
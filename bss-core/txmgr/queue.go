@@ -0,0 +1,434 @@
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Send when MaxPendingTxs transactions
+	// are already in flight.
+	ErrQueueFull = errors.New("too many pending transactions")
+
+	// ErrQueueReset signals that a transaction was abandoned because one at a
+	// lower nonce failed, which invalidates every transaction after it.
+	ErrQueueReset = errors.New("transaction abandoned after lower nonce failed")
+
+	// ErrReplacementUnderpriced signals that a transaction was withheld
+	// because it does not raise the fees of the abandoned transaction still
+	// pending at its nonce by DefaultPriceBump percent.
+	ErrReplacementUnderpriced = errors.New(
+		"replacement of abandoned transaction underpriced")
+)
+
+// CraftTxFunc builds a transaction at the given nonce. Implementations may
+// return a nil transaction if there is nothing to send.
+type CraftTxFunc = func(ctx context.Context, nonce uint64) (*types.Transaction, error)
+
+// UpdateTxGasPriceFunc signs an otherwise identical txn to the one provided but
+// with updated gas prices sampled from the existing network conditions.
+type UpdateTxGasPriceFunc = func(
+	ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+
+// QueueBackend is the subset of an L1 client used by a Queue.
+type QueueBackend interface {
+	ReceiptSource
+
+	// NonceAt returns the account nonce of the given account at
+	// blockNumber, or at the latest block if blockNumber is nil.
+	NonceAt(ctx context.Context, account common.Address,
+		blockNumber *big.Int) (uint64, error)
+
+	// PendingNonceAt returns the next nonce of the given account, counting
+	// the transactions in the mempool.
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// QueueResult is the outcome of a transaction published through a Queue.
+type QueueResult struct {
+	// Nonce is the nonce of the transaction.
+	Nonce uint64
+
+	// TxHash is the hash of the transaction as crafted and returned by Send.
+	// It tells the outcome of an abandoned transaction apart from that of the
+	// transaction crafted again at its nonce.
+	TxHash common.Hash
+
+	// Receipt is the receipt of the confirmed transaction, if any.
+	Receipt *types.Receipt
+
+	// Err is non-nil if the transaction reverted or was abandoned.
+	Err error
+}
+
+// queuedTx tracks a transaction published through a Queue.
+type queuedTx struct {
+	cancel    context.CancelFunc
+	kick      chan struct{}
+	abandoned bool
+
+	// published is the last transaction accepted by the backend, if any.
+	published *types.Transaction
+}
+
+// Queue keeps up to MaxPendingTxs transactions in flight at consecutive
+// nonces. Each transaction is bumped and confirmed independently by a
+// SimpleTxManager, except that only the lowest pending transaction bumps its
+// fee: nothing after a stuck nonce can be mined, so the transaction right after
+// it bumps the stuck one instead of itself.
+//
+// The next nonce is read from the chain when the queue starts and after any
+// transaction fails, skipping the nonces that are still in flight. A failed
+// transaction abandons every transaction after it, since those were built on
+// top of it. Abandoned transactions that were published stay in the mempool, so
+// the transaction crafted again at their nonce is only published once it
+// raises their fees enough to replace them, or once they were mined or dropped.
+type Queue struct {
+	name    string
+	mgr     *SimpleTxManager
+	backend QueueBackend
+	from    common.Address
+	limit   int
+
+	results chan QueueResult
+
+	mu         sync.Mutex
+	synced     bool
+	nonce      uint64
+	generation uint64
+	inflight   int
+	pending    map[uint64]*queuedTx
+	stale      map[uint64]*types.Transaction
+
+	wg sync.WaitGroup
+}
+
+// NewQueue initializes a new Queue publishing transactions from the given
+// address with the passed Config.
+func NewQueue(
+	name string, cfg Config, backend QueueBackend, from common.Address) *Queue {
+
+	limit := int(cfg.MaxPendingTxs)
+	if limit < 1 {
+		limit = 1
+	}
+
+	return &Queue{
+		name:    name,
+		mgr:     NewSimpleTxManager(name, cfg, backend),
+		backend: backend,
+		from:    from,
+		limit:   limit,
+		results: make(chan QueueResult, limit),
+		pending: make(map[uint64]*queuedTx),
+		stale:   make(map[uint64]*types.Transaction),
+	}
+}
+
+// Results delivers the outcome of every transaction accepted by Send.
+func (q *Queue) Results() <-chan QueueResult {
+	return q.results
+}
+
+// Pending returns the number of transactions whose outcome has not been
+// delivered yet.
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.inflight
+}
+
+// Full reports whether Send would return ErrQueueFull.
+func (q *Queue) Full() bool {
+	return q.Pending() >= q.limit
+}
+
+// Send crafts a transaction at the next nonce and publishes it in the
+// background with incrementally higher gas prices until it confirms. The
+// outcome is delivered on Results. The crafted transaction is returned, or nil
+// if craftTx had nothing to send.
+//
+// NOTE: Send should be called by AT MOST one caller at a time.
+func (q *Queue) Send(
+	ctx context.Context,
+	craftTx CraftTxFunc,
+	updateGasPrice UpdateTxGasPriceFunc,
+	sendTx SendTransactionFunc,
+) (*types.Transaction, error) {
+
+	q.mu.Lock()
+	if q.inflight >= q.limit {
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	if !q.synced {
+		if err := q.sync(ctx); err != nil {
+			q.mu.Unlock()
+			return nil, err
+		}
+	}
+	nonce, generation := q.nonce, q.generation
+	q.mu.Unlock()
+
+	tx, err := craftTx(ctx, nonce)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	if tx.Nonce() != nonce {
+		return nil, fmt.Errorf("crafted tx has nonce %d, expected %d",
+			tx.Nonce(), nonce)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// A transaction failed while this one was crafted, so the nonce or the
+	// data it builds on may no longer be valid.
+	if generation != q.generation {
+		return nil, ErrQueueReset
+	}
+
+	txCtx, cancel := context.WithCancel(ctx)
+	p := &queuedTx{
+		cancel: cancel,
+		kick:   make(chan struct{}, 1),
+	}
+	q.pending[nonce] = p
+	q.nonce = nonce + 1
+	q.inflight++
+
+	q.wg.Add(1)
+	go q.run(txCtx, ctx.Done(), nonce, p, tx, updateGasPrice, sendTx)
+
+	return tx, nil
+}
+
+// Wait blocks until every transaction accepted by Send has finished. Results
+// must be drained, or the contexts passed to Send canceled, for Wait to
+// return.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// run publishes tx until it confirms or fails and reports the outcome, unless
+// done is closed first.
+func (q *Queue) run(
+	ctx context.Context,
+	done <-chan struct{},
+	nonce uint64,
+	p *queuedTx,
+	tx *types.Transaction,
+	updateGasPrice UpdateTxGasPriceFunc,
+	sendTx SendTransactionFunc,
+) {
+
+	defer q.wg.Done()
+	defer p.cancel()
+
+	gate := &bumpGate{
+		kick: p.kick,
+		allow: func() bool {
+			return q.allowBump(nonce)
+		},
+	}
+	receipt, err := q.mgr.send(
+		ctx,
+		func(ctx context.Context) (*types.Transaction, error) {
+			newTx, err := updateGasPrice(ctx, tx)
+			if err != nil {
+				return nil, err
+			}
+			if err := q.checkReplacement(ctx, newTx); err != nil {
+				return nil, err
+			}
+			return newTx, nil
+		},
+		func(ctx context.Context, tx *types.Transaction) error {
+			if err := sendTx(ctx, tx); err != nil {
+				return err
+			}
+
+			q.mu.Lock()
+			p.published = tx
+			if p.abandoned {
+				q.stale[nonce] = tx
+			} else {
+				delete(q.stale, nonce)
+			}
+			q.mu.Unlock()
+			return nil
+		},
+		gate,
+	)
+
+	q.mu.Lock()
+	switch {
+	case p.abandoned && err != nil:
+		err = ErrQueueReset
+	case p.abandoned:
+	default:
+		delete(q.pending, nonce)
+		if err != nil {
+			q.reset(nonce)
+		}
+	}
+	q.mu.Unlock()
+
+	if err != nil {
+		log.Warn(q.name+" queued transaction failed", "nonce", nonce,
+			"err", err)
+	}
+
+	select {
+	case q.results <- QueueResult{
+		Nonce:   nonce,
+		TxHash:  tx.Hash(),
+		Receipt: receipt,
+		Err:     err,
+	}:
+	case <-done:
+	}
+
+	q.mu.Lock()
+	q.inflight--
+	q.mu.Unlock()
+}
+
+// sync reads the next nonce from the chain, skipping the nonces of the
+// transactions that are still in flight. The caller must hold q.mu.
+func (q *Queue) sync(ctx context.Context) error {
+	nonce, err := q.backend.NonceAt(ctx, q.from, nil)
+	if err != nil {
+		return err
+	}
+	for n := range q.pending {
+		if n >= nonce {
+			nonce = n + 1
+		}
+	}
+
+	log.Info(q.name+" synced queue nonce", "nonce", nonce,
+		"pending", len(q.pending))
+
+	q.nonce = nonce
+	q.synced = true
+	return nil
+}
+
+// Abandon abandons every transaction in flight from nonce on, as when a
+// confirmed transaction they build on was reorged out. Their outcome is
+// delivered on Results with ErrQueueReset, and the next nonce is resynchronized
+// with the chain on the next Send.
+func (q *Queue) Abandon(nonce uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.abandon(nonce)
+}
+
+// reset abandons every transaction after the failed nonce and resynchronizes
+// the next nonce with the chain on the next Send. The caller must hold q.mu.
+func (q *Queue) reset(failed uint64) {
+	q.abandon(failed + 1)
+}
+
+// abandon abandons every transaction from nonce on and resynchronizes the next
+// nonce with the chain on the next Send. The published transactions are kept
+// as stale until they are replaced or mined. The caller must hold q.mu.
+func (q *Queue) abandon(nonce uint64) {
+	for n, p := range q.pending {
+		if n >= nonce {
+			p.abandoned = true
+			p.cancel()
+			delete(q.pending, n)
+			if p.published != nil {
+				q.stale[n] = p.published
+			}
+		}
+	}
+	q.synced = false
+	q.generation++
+}
+
+// checkReplacement returns ErrReplacementUnderpriced if tx would not replace
+// the stale transaction at its nonce, so that it is withheld until the next
+// resubmission. Stale transactions that were mined or dropped from the mempool
+// are forgotten; if mined, tx is then rejected as nonce too low and the queue
+// resyncs.
+func (q *Queue) checkReplacement(
+	ctx context.Context, tx *types.Transaction) error {
+
+	q.mu.Lock()
+	stale, ok := q.stale[tx.Nonce()]
+	q.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	nonce, err := q.backend.NonceAt(ctx, q.from, nil)
+	if err != nil {
+		return err
+	}
+	pendingNonce, err := q.backend.PendingNonceAt(ctx, q.from)
+	if err != nil {
+		return err
+	}
+	if nonce > tx.Nonce() || pendingNonce <= tx.Nonce() {
+		q.mu.Lock()
+		for n := range q.stale {
+			if n < nonce || n >= pendingNonce {
+				delete(q.stale, n)
+			}
+		}
+		q.mu.Unlock()
+		return nil
+	}
+
+	min := BumpFees(
+		&Fees{GasTipCap: stale.GasTipCap(), GasFeeCap: stale.GasFeeCap()},
+		&Fees{GasTipCap: tx.GasTipCap(), GasFeeCap: tx.GasFeeCap()},
+		DefaultPriceBump,
+	)
+	if tx.GasTipCap().Cmp(min.GasTipCap) < 0 ||
+		tx.GasFeeCap().Cmp(min.GasFeeCap) < 0 {
+
+		return fmt.Errorf("%w: nonce %d needs gasTipCap %v and gasFeeCap %v",
+			ErrReplacementUnderpriced, tx.Nonce(), min.GasTipCap,
+			min.GasFeeCap)
+	}
+	return nil
+}
+
+// allowBump reports whether the transaction at nonce may bump its fee, which is
+// the case only if no lower nonce is pending. The transaction right after the
+// lowest pending one kicks it instead, so that a stuck nonce is bumped first.
+func (q *Queue) allowBump(nonce uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var lower []uint64
+	for n := range q.pending {
+		if n < nonce {
+			lower = append(lower, n)
+		}
+	}
+	if len(lower) == 0 {
+		return true
+	}
+
+	if len(lower) == 1 {
+		select {
+		case q.pending[lower[0]].kick <- struct{}{}:
+		default:
+		}
+	}
+	return false
+}
@@ -0,0 +1,435 @@
+package txmgr_test
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mantlenetworkio/mantle/bss-core/txmgr"
+	"github.com/stretchr/testify/require"
+)
+
+// queueBackend extends mockBackend with the account nonce used by a Queue.
+type queueBackend struct {
+	*mockBackend
+
+	nonceMu      sync.Mutex
+	nonce        uint64
+	pendingNonce uint64
+}
+
+func (b *queueBackend) NonceAt(
+	ctx context.Context,
+	account common.Address,
+	blockNumber *big.Int,
+) (uint64, error) {
+
+	b.nonceMu.Lock()
+	defer b.nonceMu.Unlock()
+
+	return b.nonce, nil
+}
+
+// PendingNonceAt returns the pending nonce if set, or the account nonce.
+func (b *queueBackend) PendingNonceAt(
+	ctx context.Context,
+	account common.Address,
+) (uint64, error) {
+
+	b.nonceMu.Lock()
+	defer b.nonceMu.Unlock()
+
+	if b.pendingNonce > b.nonce {
+		return b.pendingNonce, nil
+	}
+	return b.nonce, nil
+}
+
+func (b *queueBackend) setPendingNonce(nonce uint64) {
+	b.nonceMu.Lock()
+	defer b.nonceMu.Unlock()
+
+	b.pendingNonce = nonce
+}
+
+func (b *queueBackend) setNonce(nonce uint64) {
+	b.nonceMu.Lock()
+	defer b.nonceMu.Unlock()
+
+	b.nonce = nonce
+}
+
+// queueHarness houses the resources needed to test a Queue.
+type queueHarness struct {
+	queue   *txmgr.Queue
+	backend *queueBackend
+
+	mu      sync.Mutex
+	updates map[uint64]int
+}
+
+func newQueueHarness(cfg txmgr.Config, nonce uint64) *queueHarness {
+	backend := &queueBackend{
+		mockBackend: newMockBackend(),
+		nonce:       nonce,
+	}
+
+	return &queueHarness{
+		queue:   txmgr.NewQueue("TEST", cfg, backend, common.Address{}),
+		backend: backend,
+		updates: make(map[uint64]int),
+	}
+}
+
+func (h *queueHarness) craftTx(
+	ctx context.Context, nonce uint64) (*types.Transaction, error) {
+
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+	}), nil
+}
+
+func (h *queueHarness) updateGasPrice(
+	ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.updates[tx.Nonce()]++
+	return tx, nil
+}
+
+func (h *queueHarness) numUpdates(nonce uint64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.updates[nonce]
+}
+
+func (h *queueHarness) sendTx(ctx context.Context, tx *types.Transaction) error {
+	return nil
+}
+
+func (h *queueHarness) send(
+	t *testing.T, ctx context.Context) *types.Transaction {
+
+	tx, err := h.queue.Send(ctx, h.craftTx, h.updateGasPrice, h.sendTx)
+	require.Nil(t, err)
+	require.NotNil(t, tx)
+	return tx
+}
+
+// waitResults reads n results from the queue, keyed by nonce.
+func (h *queueHarness) waitResults(
+	t *testing.T, n int) map[uint64]txmgr.QueueResult {
+
+	results := make(map[uint64]txmgr.QueueResult)
+	for len(results) < n {
+		select {
+		case res := <-h.queue.Results():
+			results[res.Nonce] = res
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for results, got %d", len(results))
+		}
+	}
+	return results
+}
+
+func queueConfig(maxPendingTxs uint64) txmgr.Config {
+	cfg := configWithNumConfs(1)
+	cfg.MaxPendingTxs = maxPendingTxs
+	return cfg
+}
+
+// TestQueueSendsConsecutiveNonces asserts that a Queue keeps up to
+// MaxPendingTxs transactions in flight at consecutive nonces, starting at the
+// on-chain nonce.
+func TestQueueSendsConsecutiveNonces(t *testing.T) {
+	t.Parallel()
+
+	h := newQueueHarness(queueConfig(2), 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tx1 := h.send(t, ctx)
+	tx2 := h.send(t, ctx)
+	require.Equal(t, uint64(5), tx1.Nonce())
+	require.Equal(t, uint64(6), tx2.Nonce())
+	require.True(t, h.queue.Full())
+
+	_, err := h.queue.Send(ctx, h.craftTx, h.updateGasPrice, h.sendTx)
+	require.Equal(t, txmgr.ErrQueueFull, err)
+
+	tx1Hash, tx2Hash := tx1.Hash(), tx2.Hash()
+	h.backend.mine(&tx2Hash, tx2.GasFeeCap())
+	h.backend.mine(&tx1Hash, tx1.GasFeeCap())
+
+	results := h.waitResults(t, 2)
+	for nonce, tx := range map[uint64]*types.Transaction{5: tx1, 6: tx2} {
+		require.Nil(t, results[nonce].Err)
+		require.Equal(t, tx.Hash(), results[nonce].TxHash)
+		require.NotNil(t, results[nonce].Receipt)
+		require.Equal(t, tx.Hash(), results[nonce].Receipt.TxHash)
+	}
+
+	require.Eventually(t, func() bool {
+		return h.queue.Pending() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	tx3 := h.send(t, ctx)
+	require.Equal(t, uint64(7), tx3.Nonce())
+
+	cancel()
+	h.queue.Wait()
+}
+
+// TestQueueResetsAfterFailure asserts that a failed transaction abandons the
+// transactions after it and that the queue resyncs its nonce with the chain.
+func TestQueueResetsAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	h := newQueueHarness(queueConfig(3), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tx0 := h.send(t, ctx)
+	h.send(t, ctx)
+	h.send(t, ctx)
+
+	// The reverted transaction consumes its nonce.
+	tx0Hash := tx0.Hash()
+	h.backend.setNonce(1)
+	h.backend.mineWithStatus(&tx0Hash, tx0.GasFeeCap(), true)
+
+	results := h.waitResults(t, 3)
+	require.Equal(t, txmgr.ErrReverted, results[0].Err)
+	require.NotNil(t, results[0].Receipt)
+	require.Equal(t, txmgr.ErrQueueReset, results[1].Err)
+	require.Equal(t, txmgr.ErrQueueReset, results[2].Err)
+
+	tx := h.send(t, ctx)
+	require.Equal(t, uint64(1), tx.Nonce())
+
+	cancel()
+	h.queue.Wait()
+}
+
+// TestQueueAbandonsReorgedNonces asserts that Abandon abandons the transactions
+// from the given nonce on and that the queue resyncs its nonce with the chain.
+func TestQueueAbandonsReorgedNonces(t *testing.T) {
+	t.Parallel()
+
+	h := newQueueHarness(queueConfig(3), 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h.send(t, ctx)
+	h.send(t, ctx)
+
+	// The transaction at nonce 3, which both build on, was reorged out.
+	h.backend.setNonce(3)
+	h.queue.Abandon(3)
+
+	results := h.waitResults(t, 2)
+	require.Equal(t, txmgr.ErrQueueReset, results[4].Err)
+	require.Nil(t, results[4].Receipt)
+	require.Equal(t, txmgr.ErrQueueReset, results[5].Err)
+
+	tx := h.send(t, ctx)
+	require.Equal(t, uint64(3), tx.Nonce())
+
+	cancel()
+	h.queue.Wait()
+}
+
+// TestQueueBumpsStuckNonceFirst asserts that a transaction only bumps its fee
+// once every lower nonce is mined, bumping the stuck lower nonce instead.
+func TestQueueBumpsStuckNonceFirst(t *testing.T) {
+	t.Parallel()
+
+	cfg := queueConfig(2)
+	cfg.ResubmissionTimeout = 100 * time.Millisecond
+	h := newQueueHarness(cfg, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tx0 := h.send(t, ctx)
+	tx1 := h.send(t, ctx)
+
+	time.Sleep(time.Second)
+	require.Equal(t, 1, h.numUpdates(1))
+	require.GreaterOrEqual(t, h.numUpdates(0), 3)
+
+	tx0Hash := tx0.Hash()
+	h.backend.mine(&tx0Hash, tx0.GasFeeCap())
+	results := h.waitResults(t, 1)
+	require.Nil(t, results[0].Err)
+
+	require.Eventually(t, func() bool {
+		return h.numUpdates(1) > 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tx1Hash := tx1.Hash()
+	h.backend.mine(&tx1Hash, tx1.GasFeeCap())
+	results = h.waitResults(t, 1)
+	require.Nil(t, results[1].Err)
+
+	cancel()
+	h.queue.Wait()
+}
+
+// TestQueueReplacesAbandonedTxs asserts that a transaction crafted again at the
+// nonce of an abandoned transaction still in the mempool is only published once
+// its fees replace it, or once the abandoned transaction was mined.
+func TestQueueReplacesAbandonedTxs(t *testing.T) {
+	t.Parallel()
+
+	cfg := queueConfig(3)
+	cfg.ResubmissionTimeout = 100 * time.Millisecond
+	h := newQueueHarness(cfg, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		gasTip  = big.NewInt(1)
+		gasFee  = big.NewInt(2)
+		sentTxs = make(map[uint64][]*types.Transaction)
+	)
+	updateGasPrice := func(
+		ctx context.Context,
+		tx *types.Transaction,
+	) (*types.Transaction, error) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		return types.NewTx(&types.DynamicFeeTx{
+			Nonce:     tx.Nonce(),
+			GasTipCap: gasTip,
+			GasFeeCap: gasFee,
+		}), nil
+	}
+	sendTx := func(ctx context.Context, tx *types.Transaction) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		sentTxs[tx.Nonce()] = append(sentTxs[tx.Nonce()], tx)
+		return nil
+	}
+	numSent := func(nonce uint64) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(sentTxs[nonce])
+	}
+	send := func() *types.Transaction {
+		tx, err := h.queue.Send(ctx, h.craftTx, updateGasPrice, sendTx)
+		require.Nil(t, err)
+		require.NotNil(t, tx)
+		return tx
+	}
+
+	tx0 := send()
+	send()
+	send()
+	require.Eventually(t, func() bool {
+		return numSent(0) == 1 && numSent(1) == 1 && numSent(2) == 1
+	}, time.Second, 10*time.Millisecond)
+	h.backend.setPendingNonce(3)
+
+	// The reverted transaction consumes its nonce, leaving the ones after it
+	// in the mempool.
+	tx0Hash := tx0.Hash()
+	h.backend.setNonce(1)
+	h.backend.mineWithStatus(&tx0Hash, tx0.GasFeeCap(), true)
+	h.waitResults(t, 3)
+
+	// The transaction crafted again at nonce 1 pays the same fees as the
+	// abandoned one, so it is withheld.
+	require.Equal(t, uint64(1), send().Nonce())
+	time.Sleep(500 * time.Millisecond)
+	require.Equal(t, 1, numSent(1))
+
+	// Fees raised by the price bump replace it.
+	mu.Lock()
+	gasTip, gasFee = big.NewInt(2), big.NewInt(3)
+	mu.Unlock()
+	require.Eventually(t, func() bool {
+		return numSent(1) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The abandoned transaction at nonce 2 is mined before it is replaced,
+	// so the transaction crafted again at nonce 2 is no longer held back.
+	mu.Lock()
+	gasTip, gasFee = big.NewInt(1), big.NewInt(2)
+	mu.Unlock()
+	h.backend.setNonce(3)
+	require.Equal(t, uint64(2), send().Nonce())
+	require.Eventually(t, func() bool {
+		return numSent(2) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	h.queue.Wait()
+}
+
+// TestQueueForgetsDroppedAbandonedTxs asserts that a transaction crafted again
+// at the nonce of an abandoned transaction is published right away once the
+// abandoned transaction was dropped from the mempool.
+func TestQueueForgetsDroppedAbandonedTxs(t *testing.T) {
+	t.Parallel()
+
+	h := newQueueHarness(queueConfig(2), 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		numSent int
+	)
+	sendTx := func(ctx context.Context, tx *types.Transaction) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if tx.Nonce() == 4 {
+			numSent++
+		}
+		return nil
+	}
+	send := func() *types.Transaction {
+		tx, err := h.queue.Send(ctx, h.craftTx, h.updateGasPrice, sendTx)
+		require.Nil(t, err)
+		require.NotNil(t, tx)
+		return tx
+	}
+
+	send()
+	send()
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return numSent == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The transactions at nonces 3 and 4 were reorged out and dropped.
+	h.queue.Abandon(3)
+	h.waitResults(t, 2)
+
+	require.Equal(t, uint64(3), send().Nonce())
+	require.Equal(t, uint64(4), send().Nonce())
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return numSent == 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	h.queue.Wait()
+}
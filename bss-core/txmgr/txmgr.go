@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
@@ -47,6 +48,10 @@ type Config struct {
 	// are required to give up on a tx at a particular nonce without receiving
 	// confirmation.
 	SafeAbortNonceTooLowCount uint64
+
+	// MaxPendingTxs is the number of transactions a Queue keeps in flight at
+	// consecutive nonces. Values below two submit one transaction at a time.
+	MaxPendingTxs uint64
 }
 
 // TxManager is an interface that allows callers to reliably publish txs,
@@ -57,7 +62,8 @@ type TxManager interface {
 	// until an invocation of sendTx returns (called with differing gas
 	// prices). The method may be canceled using the passed context.
 	//
-	// NOTE: Send should be called by AT MOST one caller at a time.
+	// NOTE: Send should be called by AT MOST one caller at a time, use a
+	// Queue to keep several transactions in flight.
 	Send(
 		ctx context.Context,
 		updateGasPrice UpdateGasPriceFunc,
@@ -75,7 +81,8 @@ type ReceiptSource interface {
 
 	// TransactionReceipt queries the backend for a receipt associated with
 	// txHash. If lookup does not fail, but the transaction is not found,
-	// nil should be returned for both values.
+	// nil should be returned for both values, or ethereum.NotFound as
+	// returned by ethclient.
 	TransactionReceipt(
 		ctx context.Context, txHash common.Hash) (*types.Receipt, error)
 }
@@ -115,6 +122,28 @@ func (m *SimpleTxManager) Send(
 	sendTx SendTransactionFunc,
 ) (*types.Receipt, error) {
 
+	return m.send(ctx, updateGasPrice, sendTx, nil)
+}
+
+// bumpGate lets a Queue coordinate the fee bumps of transactions sent at
+// consecutive nonces.
+type bumpGate struct {
+	// kick requests a resubmission before the next ResubmissionTimeout.
+	kick <-chan struct{}
+
+	// allow reports whether a resubmission at ResubmissionTimeout may
+	// proceed.
+	allow func() bool
+}
+
+// send implements Send, consulting gate, if non-nil, before each fee bump.
+func (m *SimpleTxManager) send(
+	ctx context.Context,
+	updateGasPrice UpdateGasPriceFunc,
+	sendTx SendTransactionFunc,
+	gate *bumpGate,
+) (*types.Receipt, error) {
+
 	name := m.name
 
 	// Initialize a wait group to track any spawned goroutines, and ensure
@@ -146,6 +175,19 @@ func (m *SimpleTxManager) Send(
 				log.Warn(name+" unable to update txn gas price", "err", err)
 				return
 			}
+			// A capped transaction is retried at the next resubmission,
+			// leaving any transaction already published in place.
+			if IsCapError(err) {
+				log.Warn(name+" transaction withheld by fee cap", "err", err)
+				return
+			}
+			// Likewise for a transaction that cannot replace yet the
+			// abandoned one pending at its nonce.
+			if errors.Is(err, ErrReplacementUnderpriced) {
+				log.Warn(name+" transaction withheld until it replaces "+
+					"abandoned one", "err", err)
+				return
+			}
 			log.Error(name+" unable to update txn gas price", "err", err)
 			cancel()
 			return
@@ -208,6 +250,12 @@ func (m *SimpleTxManager) Send(
 	go sendTxAsync()
 
 	tick := time.NewTicker(m.cfg.ResubmissionTimeout)
+	defer tick.Stop()
+
+	var kick <-chan struct{}
+	if gate != nil {
+		kick = gate.kick
+	}
 
 	for {
 		select {
@@ -223,10 +271,28 @@ func (m *SimpleTxManager) Send(
 				continue
 			}
 
+			// Leave the bump to a transaction at a lower nonce if it is
+			// the one holding this transaction back.
+			if gate != nil && !gate.allow() {
+				continue
+			}
+
 			// Submit and wait for the bumped traction to confirm.
 			wg.Add(1)
 			go sendTxAsync()
 
+		// A transaction at a higher nonce is waiting on this one, bump
+		// it now and restart the resubmission timeout.
+		case <-kick:
+			if sendState.IsWaitingForConfirmation() {
+				continue
+			}
+
+			log.Info(name + " bumping transaction holding back higher nonces")
+			tick.Reset(m.cfg.ResubmissionTimeout)
+			wg.Add(1)
+			go sendTxAsync()
+
 		// The passed context has been canceled, i.e. in the event of a
 		// shutdown.
 		case <-ctxc.Done():
@@ -273,7 +339,12 @@ func waitMined(
 	txHash := tx.Hash()
 
 	for {
+		// A receipt that is not found, including one that was found before
+		// and reorged out, means the transaction is not mined.
 		receipt, err := backend.TransactionReceipt(ctx, txHash)
+		if err == ethereum.NotFound {
+			err = nil
+		}
 		switch {
 		case receipt != nil:
 			if sendState != nil {
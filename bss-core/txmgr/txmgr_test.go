@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -111,6 +112,10 @@ type mockBackend struct {
 
 	// minedTxs maps the hash of a mined transaction to its details.
 	minedTxs map[common.Hash]minedTxInfo
+
+	// notFound reports missing receipts with ethereum.NotFound, like
+	// ethclient, instead of nil.
+	notFound bool
 }
 
 // newMockBackend initializes a new mockBackend.
@@ -151,6 +156,15 @@ func (b *mockBackend) mineWithStatus(
 	}
 }
 
+// reorg removes a mined txHash, as if its block was reorged out. Subsequent
+// calls to TransactionReceipt with a matching txHash will not find a receipt.
+func (b *mockBackend) reorg(txHash common.Hash) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.minedTxs, txHash)
+}
+
 // BlockNumber returns the most recent block number.
 func (b *mockBackend) BlockNumber(ctx context.Context) (uint64, error) {
 	b.mu.RLock()
@@ -172,7 +186,9 @@ func (b *mockBackend) TransactionReceipt(
 	defer b.mu.RUnlock()
 
 	txInfo, ok := b.minedTxs[txHash]
-	if !ok {
+	if !ok && b.notFound {
+		return nil, ethereum.NotFound
+	} else if !ok {
 		return nil, nil
 	}
 
@@ -381,6 +397,37 @@ func TestTxMgrOnlyOnePublicationSucceeds(t *testing.T) {
 	require.Equal(t, h.gasPricer.expGasFeeCap().Uint64(), receipt.GasUsed)
 }
 
+// TestTxMgrRetriesCappedPublications asserts that a publication withheld by a
+// fee cap does not abort the send, and is retried at the next resubmission.
+func TestTxMgrRetriesCappedPublications(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHarness()
+
+	updateGasPrice := func(ctx context.Context) (*types.Transaction, error) {
+		gasTipCap, gasFeeCap := h.gasPricer.sample()
+		if !h.gasPricer.shouldMine(gasFeeCap) {
+			return nil, txmgr.ErrSpendCapReached
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
+		}), nil
+	}
+
+	sendTx := func(ctx context.Context, tx *types.Transaction) error {
+		txHash := tx.Hash()
+		h.backend.mine(&txHash, tx.GasFeeCap())
+		return nil
+	}
+
+	ctx := context.Background()
+	receipt, err := h.mgr.Send(ctx, updateGasPrice, sendTx)
+	require.Nil(t, err)
+	require.NotNil(t, receipt)
+	require.Equal(t, h.gasPricer.expGasFeeCap().Uint64(), receipt.GasUsed)
+}
+
 // TestTxMgrConfirmsMinGasPriceAfterBumping delays the mining of the initial tx
 // with the minimum gas price, and asserts that it's receipt is returned even
 // though if the gas price has been bumped in other goroutines.
@@ -460,6 +507,58 @@ func TestTxMgrDoesntAbortNonceTooLowAfterMiningTx(t *testing.T) {
 	require.Equal(t, h.gasPricer.expGasFeeCap().Uint64(), receipt.GasUsed)
 }
 
+// TestTxMgrResubmitsReorgedTxn asserts that Send publishes a bumped txn if the
+// one that was mined is reorged out before it confirms.
+func TestTxMgrResubmitsReorgedTxn(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHarnessWithConfig(configWithNumConfs(2))
+	h.backend.notFound = true
+
+	updateGasPrice := func(ctx context.Context) (*types.Transaction, error) {
+		gasTipCap, gasFeeCap := h.gasPricer.sample()
+		return types.NewTx(&types.DynamicFeeTx{
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
+		}), nil
+	}
+
+	var (
+		mu        sync.Mutex
+		published int
+	)
+	sendTx := func(ctx context.Context, tx *types.Transaction) error {
+		mu.Lock()
+		published++
+		first := published == 1
+		mu.Unlock()
+
+		txHash := tx.Hash()
+		h.backend.mine(&txHash, tx.GasFeeCap())
+
+		// Reorg the first txn out before its second confirmation, and
+		// confirm the bumped one.
+		if first {
+			time.AfterFunc(100*time.Millisecond, func() {
+				h.backend.reorg(txHash)
+			})
+		} else {
+			h.backend.mine(nil, nil)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receipt, err := h.mgr.Send(ctx, updateGasPrice, sendTx)
+	require.Nil(t, err)
+	require.NotNil(t, receipt)
+
+	_, gasFeeCap := h.gasPricer.feesForEpoch(2)
+	require.Equal(t, gasFeeCap.Uint64(), receipt.GasUsed)
+}
+
 // TestWaitMinedReturnsReceiptOnFirstSuccess insta-mines a transaction and
 // asserts that WaitMined returns the appropriate receipt.
 func TestWaitMinedReturnsReceiptOnFirstSuccess(t *testing.T) {
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/fraud-proof/rollup/services"
 	"github.com/mantlenetworkio/mantle/l2geth/cmd/utils"
 	"github.com/mantlenetworkio/mantle/l2geth/common"
@@ -79,8 +80,83 @@ var (
 		Usage:  "the creden of hsm key",
 		EnvVar: "HSM_CREDEN",
 	}
+
+	// SignerFlags configure the signer of the L1 transactions, they take
+	// precedence over the keystore account and hsm flags.
+	SignerFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.TypeFlagName,
+			Usage:  "Signer of the L1 transactions: local, keystore, remote or kms",
+			EnvVar: "FP_SIGNER_TYPE",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.PrivateKeyFlagName,
+			Usage:  "Private key of the local signer",
+			EnvVar: "FP_SIGNER_PRIVATE_KEY",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.MnemonicFlagName,
+			Usage:  "Mnemonic the key of the local signer is derived from",
+			EnvVar: "FP_SIGNER_MNEMONIC",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.HDPathFlagName,
+			Usage:  "HD path the key of the local signer is derived with",
+			EnvVar: "FP_SIGNER_HD_PATH",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.KeystorePathFlagName,
+			Usage:  "Path of the encrypted keystore file of the keystore signer",
+			EnvVar: "FP_SIGNER_KEYSTORE",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.KeystorePasswordFlagName,
+			Usage:  "Password of the keystore file",
+			EnvVar: "FP_SIGNER_PASSWORD",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.EndpointFlagName,
+			Usage:  "JSON-RPC endpoint of the remote signer",
+			EnvVar: "FP_SIGNER_ENDPOINT",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.AddressFlagName,
+			Usage:  "Address of the remote or kms signer",
+			EnvVar: "FP_SIGNER_ADDRESS",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.KMSKeyNameFlagName,
+			Usage:  "Key name of the kms signer",
+			EnvVar: "FP_SIGNER_KMS_KEY_NAME",
+		},
+		&cli.StringFlag{
+			Name:   signerFlagPrefix + signer.KMSCredentialsFlagName,
+			Usage:  "Hex encoded JSON credentials of the kms signer",
+			EnvVar: "FP_SIGNER_KMS_CREDENTIALS",
+		},
+	}
 )
 
+// signerFlagPrefix namespaces the signer flags with the other fraud proof
+// flags, geth already has a signer flag
+const signerFlagPrefix = "fp."
+
+// makeSignerConfig reads the signer configuration from SignerFlags
+func makeSignerConfig(ctx *cli.Context) signer.Config {
+	return signer.Config{
+		Type:             ctx.GlobalString(signerFlagPrefix + signer.TypeFlagName),
+		PrivateKey:       ctx.GlobalString(signerFlagPrefix + signer.PrivateKeyFlagName),
+		Mnemonic:         ctx.GlobalString(signerFlagPrefix + signer.MnemonicFlagName),
+		HDPath:           ctx.GlobalString(signerFlagPrefix + signer.HDPathFlagName),
+		KeystorePath:     ctx.GlobalString(signerFlagPrefix + signer.KeystorePathFlagName),
+		KeystorePassword: ctx.GlobalString(signerFlagPrefix + signer.KeystorePasswordFlagName),
+		Endpoint:         ctx.GlobalString(signerFlagPrefix + signer.EndpointFlagName),
+		Address:          ctx.GlobalString(signerFlagPrefix + signer.AddressFlagName),
+		KMSKeyName:       ctx.GlobalString(signerFlagPrefix + signer.KMSKeyNameFlagName),
+		KMSCredentials:   ctx.GlobalString(signerFlagPrefix + signer.KMSCredentialsFlagName),
+	}
+}
+
 //// RegisterEthService adds an Ethereum client to the stack.
 //// The second return value is the full node instance, which may be nil if the
 //// node is running as a light client.
@@ -108,10 +184,12 @@ var (
 func MakeFraudProofConfig(ctx *cli.Context) *services.Config {
 	//utils.CheckExclusive(ctx, FraudProofNodeFlag, utils.MiningEnabledFlag)
 	//utils.CheckExclusive(ctx, FraudProofNodeFlag, utils.DeveloperFlag)
+	// the keystore account is only unlocked without signer
+	signerCfg := makeSignerConfig(ctx)
 	var passphrase string
 	if list := utils.MakePasswordList(ctx); len(list) > 0 {
 		passphrase = list[0]
-	} else {
+	} else if !signerCfg.Enabled() {
 		utils.Fatalf("Failed to register the Rollup service: coinbase account locked")
 	}
 	cfg := &services.Config{
@@ -129,6 +207,7 @@ func MakeFraudProofConfig(ctx *cli.Context) *services.Config {
 		HsmAddress:      ctx.GlobalString(HsmAddressFlag.Name),
 		HsmAPIName:      ctx.GlobalString(HsmAPINameFlag.Name),
 		HsmCreden:       ctx.GlobalString(HsmCredenFlag.Name),
+		Signer:          signerCfg,
 	}
 	return cfg
 }
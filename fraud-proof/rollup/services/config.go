@@ -1,6 +1,7 @@
 package services
 
 import (
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/l2geth/common"
 )
 
@@ -25,4 +26,5 @@ type Config struct {
 	HsmAPIName      string
 	HsmCreden       string
 	HsmAddress      string
+	Signer          signer.Config // Takes precedence over the keystore account and the hsm
 }
@@ -1,23 +1,17 @@
 package rollup
 
 import (
-	"bytes"
 	"context"
-	"encoding/hex"
+	"errors"
 	"math/big"
 
-	kms "cloud.google.com/go/kms/apiv1"
-	"github.com/ethereum/go-ethereum/common"
-	bsscore "github.com/mantlenetworkio/mantle/bss-core"
-	"google.golang.org/api/option"
-
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/fraud-proof/rollup/services"
 	"github.com/mantlenetworkio/mantle/fraud-proof/rollup/services/sequencer"
 	"github.com/mantlenetworkio/mantle/fraud-proof/rollup/services/validator"
 	"github.com/mantlenetworkio/mantle/l2geth/accounts"
 	"github.com/mantlenetworkio/mantle/l2geth/accounts/keystore"
+	"github.com/mantlenetworkio/mantle/l2geth/common"
 	"github.com/mantlenetworkio/mantle/l2geth/eth"
 	"github.com/mantlenetworkio/mantle/l2geth/log"
 	"github.com/mantlenetworkio/mantle/l2geth/node"
@@ -26,44 +20,24 @@ import (
 // RegisterFraudProofService registers rollup service configured by ctx
 // Either a sequncer service or a validator service will be registered
 func RegisterFraudProofService(stack *node.Node, cfg *services.Config) {
-	// Unlock account for L1 transaction signer
-	var ks *keystore.KeyStore
-	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
-		ks = keystores[0].(*keystore.KeyStore)
-	}
-	if ks == nil {
-		log.Crit("Failed to register the Rollup service: keystore not found")
-	}
 	chainID := big.NewInt(int64(cfg.L1ChainID))
-	log.Info("fault-proof register", "EnableHsm",
+	log.Info("fault-proof register", "signer", cfg.Signer.Type, "EnableHsm",
 		cfg.EnableHsm, "HsmAPIName", cfg.HsmAPIName)
 
-	var auth *bind.TransactOpts
-	if !cfg.EnableHsm {
-		json, err := ks.Export(accounts.Account{Address: cfg.StakeAddr}, cfg.Passphrase, cfg.Passphrase)
-		if err != nil {
-			log.Crit("Failed to register the Rollup service", "err", err)
-		}
-		auth, err = bind.NewTransactorWithChainID(bytes.NewReader(json), cfg.Passphrase, chainID)
-		if err != nil {
-			log.Crit("Failed to register the Rollup service", "err", err)
-		}
-	} else {
-		seqBytes, err := hex.DecodeString(cfg.HsmCreden)
-		apikey := option.WithCredentialsJSON(seqBytes)
-		client, err := kms.NewKeyManagementClient(context.Background(), apikey)
-		if err != nil {
-			log.Crit("sequencer", "create signer error", err.Error())
-		}
-		mk := &bsscore.ManagedKey{
-			KeyName:      cfg.HsmAPIName,
-			EthereumAddr: common.HexToAddress(cfg.HsmAddress),
-			Gclient:      client,
-		}
-		auth, err = mk.NewEthereumTransactorrWithChainID(context.Background(), chainID)
-		if err != nil {
-			log.Crit("sequencer", "create signer error", err.Error())
-		}
+	txSigner, err := newSigner(stack, cfg)
+	if err != nil {
+		log.Crit("Failed to register the Rollup service", "err", err)
+	}
+	// the stake address defaults to the address of the signer
+	if cfg.StakeAddr == (common.Address{}) {
+		cfg.StakeAddr = common.Address(txSigner.Address())
+	} else if cfg.StakeAddr != common.Address(txSigner.Address()) {
+		log.Crit("Failed to register the Rollup service: signer is not the stake address",
+			"signer", txSigner.Address(), "stake", cfg.StakeAddr)
+	}
+	auth, err := signer.NewTransactOpts(context.Background(), txSigner, chainID)
+	if err != nil {
+		log.Crit("Failed to register the Rollup service", "err", err)
 	}
 
 	var ethService *eth.Ethereum
@@ -85,3 +59,32 @@ func RegisterFraudProofService(stack *node.Node, cfg *services.Config) {
 		log.Crit("Failed to register the Rollup service: Node type unkown", "type", cfg.Node)
 	}
 }
+
+// newSigner creates the signer of the L1 transactions. Without signer
+// configuration, the stake account is unlocked from the node keystore unless
+// the hsm is enabled.
+func newSigner(stack *node.Node, cfg *services.Config) (signer.Signer, error) {
+	switch {
+	case cfg.Signer.Enabled():
+		return signer.NewSigner(context.Background(), cfg.Signer)
+	case cfg.EnableHsm:
+		return signer.NewKMSSigner(context.Background(), cfg.HsmAPIName, cfg.HsmAddress, cfg.HsmCreden)
+	}
+
+	var ks *keystore.KeyStore
+	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
+		ks = keystores[0].(*keystore.KeyStore)
+	}
+	if ks == nil {
+		return nil, errors.New("keystore not found")
+	}
+	keyJSON, err := ks.Export(accounts.Account{Address: cfg.StakeAddr}, cfg.Passphrase, cfg.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJSON, cfg.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(key.PrivateKey), nil
+}
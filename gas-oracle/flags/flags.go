@@ -1,6 +1,9 @@
 package flags
 
 import (
+	"time"
+
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/urfave/cli"
 )
 
@@ -87,6 +90,22 @@ var (
 		Usage:  "Enable updating the da gas price",
 		EnvVar: "GAS_PRICE_ORACLE_ENABLE_DA_FEE",
 	}
+	StateDBPathFlag = cli.StringFlag{
+		Name:   "state-db-path",
+		Value:  "gas-oracle-data",
+		Usage:  "Path of the database persisting the gas oracle state, in memory if empty",
+		EnvVar: "GAS_PRICE_ORACLE_STATE_DB_PATH",
+	}
+	DryRunFlag = cli.BoolFlag{
+		Name:   "dry-run",
+		Usage:  "Run the updaters without sending transactions",
+		EnvVar: "GAS_PRICE_ORACLE_DRY_RUN",
+	}
+	AuditLogFlag = cli.StringFlag{
+		Name:   "audit-log",
+		Usage:  "Path of the JSONL file the fee decisions are appended to",
+		EnvVar: "GAS_PRICE_ORACLE_AUDIT_LOG",
+	}
 	BatchSizeCap = cli.IntFlag{
 		Name:   "set-batch-size-cap",
 		Value:  1000,
@@ -123,6 +142,35 @@ var (
 		Usage:  "Setup DataRollupGasUsed",
 		EnvVar: "GAS_PRICE_ORACLE_DATA_ROLLUP_GAS_USED",
 	}
+	L1OverheadModeFlag = cli.StringFlag{
+		Name:   "l1-overhead-mode",
+		Value:  "jump-table",
+		Usage:  "How the L1 overhead is computed: jump-table or receipts",
+		EnvVar: "GAS_PRICE_ORACLE_L1_OVERHEAD_MODE",
+	}
+	DaRollupContractAddressFlag = cli.StringFlag{
+		Name:   "da-rollup-contract-address",
+		Usage:  "Address of the L1 contract DA batches are submitted to, included in the receipts overhead if set",
+		EnvVar: "GAS_PRICE_ORACLE_DA_ROLLUP_CONTRACT_ADDRESS",
+	}
+	L1OverheadWindowBlocksFlag = cli.Uint64Flag{
+		Name:   "l1-overhead-window-blocks",
+		Value:  900,
+		Usage:  "Number of L1 blocks of batch submissions the receipts overhead is averaged over",
+		EnvVar: "GAS_PRICE_ORACLE_L1_OVERHEAD_WINDOW_BLOCKS",
+	}
+	L1OverheadTargetMarginFlag = cli.Float64Flag{
+		Name:   "l1-overhead-target-margin",
+		Value:  0.1,
+		Usage:  "Margin over the L1 gas spent per L2 transaction the receipts overhead recovers",
+		EnvVar: "GAS_PRICE_ORACLE_L1_OVERHEAD_TARGET_MARGIN",
+	}
+	L1OverheadSignificanceFactorFlag = cli.Float64Flag{
+		Name:   "l1-overhead-significant-factor",
+		Value:  0.05,
+		Usage:  "only update when the receipts overhead changes by more than this factor",
+		EnvVar: "GAS_PRICE_ORACLE_L1_OVERHEAD_SIGNIFICANT_FACTOR",
+	}
 	LogLevelFlag = cli.IntFlag{
 		Name:   "loglevel",
 		Value:  3,
@@ -135,6 +183,52 @@ var (
 		Usage:  "gas price floor",
 		EnvVar: "GAS_PRICE_ORACLE_FLOOR_PRICE",
 	}
+	CeilingPriceFlag = cli.Uint64Flag{
+		Name:   "ceiling-price",
+		Usage:  "gas price ceiling, 0 disables it",
+		EnvVar: "GAS_PRICE_ORACLE_CEILING_PRICE",
+	}
+	L2GasPricePolicyFlag = cli.StringFlag{
+		Name:   "l2-gas-price-policy",
+		Value:  "linear",
+		Usage:  "L2 gas price policy: linear, eip1559 or pid",
+		EnvVar: "GAS_PRICE_ORACLE_L2_GAS_PRICE_POLICY",
+	}
+	EIP1559TargetFullnessFlag = cli.Float64Flag{
+		Name:   "eip1559-target-fullness",
+		Value:  0.5,
+		Usage:  "block fullness targeted by the eip1559 policy",
+		EnvVar: "GAS_PRICE_ORACLE_EIP1559_TARGET_FULLNESS",
+	}
+	EIP1559ChangeDenominatorFlag = cli.Float64Flag{
+		Name:   "eip1559-change-denominator",
+		Value:  8,
+		Usage:  "change denominator of the eip1559 policy",
+		EnvVar: "GAS_PRICE_ORACLE_EIP1559_CHANGE_DENOMINATOR",
+	}
+	PIDKpFlag = cli.Float64Flag{
+		Name:   "pid-kp",
+		Value:  0.5,
+		Usage:  "proportional gain of the pid policy",
+		EnvVar: "GAS_PRICE_ORACLE_PID_KP",
+	}
+	PIDKiFlag = cli.Float64Flag{
+		Name:   "pid-ki",
+		Value:  0.05,
+		Usage:  "integral gain of the pid policy",
+		EnvVar: "GAS_PRICE_ORACLE_PID_KI",
+	}
+	PIDKdFlag = cli.Float64Flag{
+		Name:   "pid-kd",
+		Usage:  "derivative gain of the pid policy",
+		EnvVar: "GAS_PRICE_ORACLE_PID_KD",
+	}
+	PIDIntegralLimitFlag = cli.Float64Flag{
+		Name:   "pid-integral-limit",
+		Value:  5,
+		Usage:  "bound of the integral term of the pid policy, 0 disables it",
+		EnvVar: "GAS_PRICE_ORACLE_PID_INTEGRAL_LIMIT",
+	}
 	TargetGasPerSecondFlag = cli.Uint64Flag{
 		Name:   "target-gas-per-second",
 		Value:  11_000_000,
@@ -207,6 +301,11 @@ var (
 		Usage:  "token pricer update frequency",
 		EnvVar: "TOKEN_PRICER_UPDATE_FREQUENCY",
 	}
+	PriceSourcesConfigFlag = cli.StringFlag{
+		Name:   "price-sources-config",
+		Usage:  "path to a JSON file configuring the token price sources, replaces the price backend and uniswap url sources",
+		EnvVar: "PRICE_SOURCES_CONFIG",
+	}
 	TokenRatioMode = cli.Uint64Flag{
 		Name:   "token-ratio-mode",
 		Value:  0,
@@ -270,6 +369,39 @@ var (
 		Value:  "test",
 		EnvVar: "GAS_PRICE_ORACLE_METRICS_INFLUX_DB_PASSWORD",
 	}
+	AdminEnabledFlag = cli.BoolFlag{
+		Name:   "admin",
+		Usage:  "Enable the admin API",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_ENABLE",
+	}
+	AdminHTTPFlag = cli.StringFlag{
+		Name:   "admin.addr",
+		Usage:  "Admin API HTTP server listening interface",
+		Value:  "127.0.0.1",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_HTTP",
+	}
+	AdminPortFlag = cli.IntFlag{
+		Name:   "admin.port",
+		Usage:  "Admin API HTTP server listening port",
+		Value:  9108,
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_PORT",
+	}
+	AdminTokenFlag = cli.StringFlag{
+		Name:   "admin.token",
+		Usage:  "Bearer token required by the admin API",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_TOKEN",
+	}
+	AdminJournalFlag = cli.StringFlag{
+		Name:   "admin.journal",
+		Usage:  "Path of the JSONL file the overrides are journaled to, in memory if empty",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_JOURNAL",
+	}
+	AdminMaxOverrideDurationFlag = cli.DurationFlag{
+		Name:   "admin.max-override-duration",
+		Usage:  "Longest duration of a pause or a pinned value",
+		Value:  6 * time.Hour,
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_MAX_OVERRIDE_DURATION",
+	}
 	EnableHsmFlag = cli.BoolFlag{
 		Name:   "enable-hsm",
 		Usage:  "Enalbe the hsm",
@@ -292,7 +424,7 @@ var (
 	}
 )
 
-var Flags = []cli.Flag{
+var Flags = append([]cli.Flag{
 	EthereumHttpUrlFlag,
 	EthereumWssUrlFlag,
 	LayerTwoHttpUrlFlag,
@@ -306,6 +438,14 @@ var Flags = []cli.Flag{
 	TransactionGasPriceFlag,
 	LogLevelFlag,
 	FloorPriceFlag,
+	CeilingPriceFlag,
+	L2GasPricePolicyFlag,
+	EIP1559TargetFullnessFlag,
+	EIP1559ChangeDenominatorFlag,
+	PIDKpFlag,
+	PIDKiFlag,
+	PIDKdFlag,
+	PIDIntegralLimitFlag,
 	TargetGasPerSecondFlag,
 	MaxPercentChangePerEpochFlag,
 	AverageBlockGasLimitPerEpochFlag,
@@ -316,13 +456,22 @@ var Flags = []cli.Flag{
 	PriceBackendURL,
 	PriceBackendUniswapURL,
 	TokenPricerUpdateFrequencySecond,
+	PriceSourcesConfigFlag,
 	TokenRatioMode,
 	TokenPairMNTMode,
 	WaitForReceiptFlag,
 	EnableL1BaseFeeFlag,
 	EnableL1OverheadFlag,
+	L1OverheadModeFlag,
+	DaRollupContractAddressFlag,
+	L1OverheadWindowBlocksFlag,
+	L1OverheadTargetMarginFlag,
+	L1OverheadSignificanceFactorFlag,
 	EnableL2GasPriceFlag,
 	EnableDaFeeFlag,
+	StateDBPathFlag,
+	DryRunFlag,
+	AuditLogFlag,
 	SCCContractAddressFlag,
 	CTCContractAddressFlag,
 	BatchSizeBottom,
@@ -343,4 +492,10 @@ var Flags = []cli.Flag{
 	MetricsInfluxDBDatabaseFlag,
 	MetricsInfluxDBUsernameFlag,
 	MetricsInfluxDBPasswordFlag,
-}
+	AdminEnabledFlag,
+	AdminHTTPFlag,
+	AdminPortFlag,
+	AdminTokenFlag,
+	AdminJournalFlag,
+	AdminMaxOverrideDurationFlag,
+}, signer.CLIFlags("GAS_PRICE_ORACLE")...)
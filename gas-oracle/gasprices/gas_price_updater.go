@@ -81,9 +81,15 @@ func (g *GasPriceUpdater) UpdateGasPrice() error {
 	}
 
 	averageGasPerSecond := float64(totalGasUsed) / float64(g.epochLengthSeconds)
+	blocks := latestBlockNumber - g.epochStartBlockNumber
 
 	log.Debug("UpdateGasPrice", "average-gas-per-second", averageGasPerSecond, "current-price", g.gasPricer.curPrice)
-	_, err = g.gasPricer.CompleteEpoch(averageGasPerSecond)
+	_, err = g.gasPricer.CompleteEpochWithStats(EpochStats{
+		AvgGasPerSecond: averageGasPerSecond,
+		GasUsed:         totalGasUsed,
+		Blocks:          blocks,
+		GasLimit:        blocks * g.averageBlockGasLimit,
+	})
 	if err != nil {
 		return err
 	}
@@ -14,10 +14,22 @@ type GetTargetGasPerSecond func() float64
 type GasPricer struct {
 	curPrice                 uint64
 	avgGasPerSecondLastEpoch float64
+	lastEpoch                EpochStats
 	floorPrice               uint64
+	ceilingPrice             uint64
 	tokenPricer              *tokenprice.Client
 	getTargetGasPerSecond    GetTargetGasPerSecond
 	maxChangePerEpoch        float64
+	// policy defaults to the LinearPolicy of getTargetGasPerSecond
+	policy PricingPolicy
+}
+
+// PriceLimits clamps the prices computed by a PricingPolicy
+type PriceLimits struct {
+	FloorPrice uint64
+	// CeilingPrice is the maximum price, 0 is unbounded
+	CeilingPrice             uint64
+	MaxPercentChangePerEpoch float64
 }
 
 // LinearInterpolation can be used to dynamically update target gas per second
@@ -29,50 +41,88 @@ func GetLinearInterpolationFn(getX func() float64, x1 float64, x2 float64, y1 fl
 
 // NewGasPricer creates a GasPricer and checks its config beforehand
 func NewGasPricer(curPrice, floorPrice uint64, tokenPricer *tokenprice.Client, getTargetGasPerSecond GetTargetGasPerSecond, maxPercentChangePerEpoch float64) (*GasPricer, error) {
-	if floorPrice < 1 {
+	gp, err := NewGasPricerWithPolicy(curPrice, tokenPricer, &LinearPolicy{getTargetGasPerSecond}, PriceLimits{
+		FloorPrice:               floorPrice,
+		MaxPercentChangePerEpoch: maxPercentChangePerEpoch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	gp.getTargetGasPerSecond = getTargetGasPerSecond
+	return gp, nil
+}
+
+// NewGasPricerWithPolicy creates a GasPricer using policy and checks its config
+// beforehand. A nil tokenPricer disables the token ratio.
+func NewGasPricerWithPolicy(curPrice uint64, tokenPricer *tokenprice.Client, policy PricingPolicy, limits PriceLimits) (*GasPricer, error) {
+	if limits.FloorPrice < 1 {
 		return nil, errors.New("floorPrice must be greater than or equal to 1")
 	}
-	if maxPercentChangePerEpoch <= 0 {
+	if limits.CeilingPrice != 0 && limits.CeilingPrice < limits.FloorPrice {
+		return nil, errors.New("ceilingPrice must be greater than or equal to floorPrice")
+	}
+	if limits.MaxPercentChangePerEpoch <= 0 {
 		return nil, errors.New("maxPercentChangePerEpoch must be between (0,100]")
 	}
+	if policy == nil {
+		return nil, errors.New("no pricing policy")
+	}
 	return &GasPricer{
-		tokenPricer:           tokenPricer,
-		curPrice:              max(curPrice, floorPrice),
-		floorPrice:            floorPrice,
-		getTargetGasPerSecond: getTargetGasPerSecond,
-		maxChangePerEpoch:     maxPercentChangePerEpoch,
+		tokenPricer:       tokenPricer,
+		curPrice:          max(curPrice, limits.FloorPrice),
+		floorPrice:        limits.FloorPrice,
+		ceilingPrice:      limits.CeilingPrice,
+		maxChangePerEpoch: limits.MaxPercentChangePerEpoch,
+		policy:            policy,
 	}, nil
 }
 
+func (p *GasPricer) pricingPolicy() PricingPolicy {
+	if p.policy == nil {
+		return &LinearPolicy{p.getTargetGasPerSecond}
+	}
+	return p.policy
+}
+
 // CalcNextEpochGasPrice calculates the next gas price given some average
 // gas per second over the last epoch
 func (p *GasPricer) CalcNextEpochGasPrice(avgGasPerSecondLastEpoch float64) (uint64, error) {
-	targetGasPerSecond := p.getTargetGasPerSecond()
-	if avgGasPerSecondLastEpoch < 0 {
-		return 0.0, fmt.Errorf("avgGasPerSecondLastEpoch cannot be negative, got %f", avgGasPerSecondLastEpoch)
+	return p.CalcNextEpochGasPriceWithStats(EpochStats{AvgGasPerSecond: avgGasPerSecondLastEpoch})
+}
+
+// CalcNextEpochGasPriceWithStats calculates the next gas price given the gas
+// usage of the last epoch
+func (p *GasPricer) CalcNextEpochGasPriceWithStats(stats EpochStats) (uint64, error) {
+	// The percent that we should adjust the gas price to reach our target gas
+	proportionOfTarget, err := p.pricingPolicy().Adjustment(stats)
+	if err != nil {
+		return 0.0, err
 	}
-	if targetGasPerSecond < 1 {
-		return 0.0, fmt.Errorf("gasPerSecond cannot be less than 1, got %f", targetGasPerSecond)
+	if math.IsNaN(proportionOfTarget) || math.IsInf(proportionOfTarget, 0) {
+		return 0.0, fmt.Errorf("invalid adjustment %f", proportionOfTarget)
 	}
-	// The percent difference between our current average gas & our target gas
-	proportionOfTarget := avgGasPerSecondLastEpoch / targetGasPerSecond
 
 	log.Trace("Calculating next epoch gas price", "proportionOfTarget", proportionOfTarget,
-		"avgGasPerSecondLastEpoch", avgGasPerSecondLastEpoch, "targetGasPerSecond", targetGasPerSecond)
+		"avgGasPerSecondLastEpoch", stats.AvgGasPerSecond, "gasUsed", stats.GasUsed, "gasLimit", stats.GasLimit)
 
-	// The percent that we should adjust the gas price to reach our target gas
 	proportionToChangeBy := 0.0
 	if proportionOfTarget >= 1 { // If average avgGasPerSecondLastEpoch is GREATER than our target
 		proportionToChangeBy = math.Min(proportionOfTarget, 1+p.maxChangePerEpoch)
 	} else {
 		proportionToChangeBy = math.Max(proportionOfTarget, 1-p.maxChangePerEpoch)
 	}
-	ratio, err := p.tokenPricer.PriceRatioWithMode()
-	if err != nil {
-		return 0.0, err
+	ratio := float64(1)
+	if p.tokenPricer != nil {
+		ratio, err = p.tokenPricer.PriceRatioWithMode()
+		if err != nil {
+			return 0.0, err
+		}
 	}
 	updated := float64(max(1, p.curPrice)) * proportionToChangeBy * ratio
 	result := max(p.floorPrice, uint64(math.Ceil(updated)))
+	if p.ceilingPrice != 0 && result > p.ceilingPrice {
+		result = p.ceilingPrice
+	}
 
 	log.Debug("Calculated next epoch gas price", "proportionToChangeBy", proportionToChangeBy,
 		"proportionOfTarget", proportionOfTarget, "result", result)
@@ -82,15 +132,36 @@ func (p *GasPricer) CalcNextEpochGasPrice(avgGasPerSecondLastEpoch float64) (uin
 
 // CompleteEpoch ends the current epoch and updates the current gas price for the next epoch
 func (p *GasPricer) CompleteEpoch(avgGasPerSecondLastEpoch float64) (uint64, error) {
-	gp, err := p.CalcNextEpochGasPrice(avgGasPerSecondLastEpoch)
+	return p.CompleteEpochWithStats(EpochStats{AvgGasPerSecond: avgGasPerSecondLastEpoch})
+}
+
+// CompleteEpochWithStats ends the current epoch given its gas usage and updates
+// the current gas price for the next epoch
+func (p *GasPricer) CompleteEpochWithStats(stats EpochStats) (uint64, error) {
+	gp, err := p.CalcNextEpochGasPriceWithStats(stats)
 	if err != nil {
 		return gp, err
 	}
+	if completer, ok := p.pricingPolicy().(EpochCompleter); ok {
+		completer.CompleteEpoch(stats)
+	}
 	p.curPrice = gp
-	p.avgGasPerSecondLastEpoch = avgGasPerSecondLastEpoch
+	p.avgGasPerSecondLastEpoch = stats.AvgGasPerSecond
+	p.lastEpoch = stats
 	return gp, nil
 }
 
+// AvgGasPerSecondLastEpoch returns the average gas per second of the last
+// completed epoch
+func (p *GasPricer) AvgGasPerSecondLastEpoch() float64 {
+	return p.avgGasPerSecondLastEpoch
+}
+
+// LastEpoch returns the gas usage of the last completed epoch
+func (p *GasPricer) LastEpoch() EpochStats {
+	return p.lastEpoch
+}
+
 func max(a, b uint64) uint64 {
 	if a >= b {
 		return a
@@ -0,0 +1,163 @@
+package gasprices
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// LinearPolicyName is the name of the LinearPolicy
+	LinearPolicyName = "linear"
+	// EIP1559PolicyName is the name of the EIP1559Policy
+	EIP1559PolicyName = "eip1559"
+	// PIDPolicyName is the name of the PIDPolicy
+	PIDPolicyName = "pid"
+
+	// DefaultEIP1559TargetFullness is the block fullness at which the price
+	// does not change
+	DefaultEIP1559TargetFullness = 0.5
+	// DefaultEIP1559ChangeDenominator bounds the change of the price to 1/8
+	// per epoch, as the EIP-1559 base fee
+	DefaultEIP1559ChangeDenominator = float64(8)
+)
+
+// EpochStats is the gas usage of the blocks of an epoch
+type EpochStats struct {
+	AvgGasPerSecond float64
+	GasUsed         uint64
+	Blocks          uint64
+	// GasLimit is the sum of the gas limits of the blocks
+	GasLimit uint64
+}
+
+// PricingPolicy computes how the L2 gas price must change after an epoch. The
+// GasPricer clamps the result to its per-epoch change and price limits.
+type PricingPolicy interface {
+	// Adjustment returns the proportion to change the gas price by, 1 keeps
+	// the price unchanged. It must not modify the state of the policy.
+	Adjustment(stats EpochStats) (float64, error)
+}
+
+// EpochCompleter is implemented by stateful policies, CompleteEpoch is called
+// when the epoch that was priced by Adjustment ends
+type EpochCompleter interface {
+	CompleteEpoch(stats EpochStats)
+}
+
+// LinearPolicy changes the price proportionally to the ratio of the average
+// gas per second to the target
+type LinearPolicy struct {
+	GetTargetGasPerSecond GetTargetGasPerSecond
+}
+
+func (p *LinearPolicy) Adjustment(stats EpochStats) (float64, error) {
+	targetGasPerSecond := p.GetTargetGasPerSecond()
+	if stats.AvgGasPerSecond < 0 {
+		return 0, fmt.Errorf("avgGasPerSecondLastEpoch cannot be negative, got %f", stats.AvgGasPerSecond)
+	}
+	if targetGasPerSecond < 1 {
+		return 0, fmt.Errorf("gasPerSecond cannot be less than 1, got %f", targetGasPerSecond)
+	}
+	// The percent difference between our current average gas & our target gas
+	return stats.AvgGasPerSecond / targetGasPerSecond, nil
+}
+
+// EIP1559Policy changes the price as the EIP-1559 base fee, proportionally to
+// the distance of the block fullness to a target
+type EIP1559Policy struct {
+	targetFullness    float64
+	changeDenominator float64
+}
+
+// NewEIP1559Policy creates an EIP1559Policy, targetFullness is in (0, 1]
+func NewEIP1559Policy(targetFullness, changeDenominator float64) (*EIP1559Policy, error) {
+	if targetFullness <= 0 || targetFullness > 1 {
+		return nil, fmt.Errorf("targetFullness must be between (0,1], got %f", targetFullness)
+	}
+	if changeDenominator <= 0 {
+		return nil, fmt.Errorf("changeDenominator must be positive, got %f", changeDenominator)
+	}
+	return &EIP1559Policy{
+		targetFullness:    targetFullness,
+		changeDenominator: changeDenominator,
+	}, nil
+}
+
+func (p *EIP1559Policy) Adjustment(stats EpochStats) (float64, error) {
+	if stats.GasLimit == 0 {
+		return 0, errors.New("gas limit of the epoch cannot be zero")
+	}
+	fullness := float64(stats.GasUsed) / float64(stats.GasLimit)
+	return 1 + (fullness-p.targetFullness)/p.targetFullness/p.changeDenominator, nil
+}
+
+// PIDPolicy is a PID controller of the average gas per second, the error is
+// the relative distance to the target
+type PIDPolicy struct {
+	getTargetGasPerSecond GetTargetGasPerSecond
+	kp, ki, kd            float64
+	// integralLimit bounds the absolute value of the integral term, 0 is
+	// unbounded
+	integralLimit float64
+
+	integral  float64
+	lastError float64
+	started   bool
+}
+
+// NewPIDPolicy creates a PIDPolicy with the gains kp, ki and kd
+func NewPIDPolicy(getTargetGasPerSecond GetTargetGasPerSecond, kp, ki, kd, integralLimit float64) (*PIDPolicy, error) {
+	if kp < 0 || ki < 0 || kd < 0 {
+		return nil, fmt.Errorf("gains cannot be negative, got kp=%f ki=%f kd=%f", kp, ki, kd)
+	}
+	if integralLimit < 0 {
+		return nil, fmt.Errorf("integralLimit cannot be negative, got %f", integralLimit)
+	}
+	return &PIDPolicy{
+		getTargetGasPerSecond: getTargetGasPerSecond,
+		kp:                    kp,
+		ki:                    ki,
+		kd:                    kd,
+		integralLimit:         integralLimit,
+	}, nil
+}
+
+// terms returns the error and integral after the epoch
+func (p *PIDPolicy) terms(stats EpochStats) (float64, float64, error) {
+	targetGasPerSecond := p.getTargetGasPerSecond()
+	if stats.AvgGasPerSecond < 0 {
+		return 0, 0, fmt.Errorf("avgGasPerSecondLastEpoch cannot be negative, got %f", stats.AvgGasPerSecond)
+	}
+	if targetGasPerSecond < 1 {
+		return 0, 0, fmt.Errorf("gasPerSecond cannot be less than 1, got %f", targetGasPerSecond)
+	}
+	e := (stats.AvgGasPerSecond - targetGasPerSecond) / targetGasPerSecond
+	integral := p.integral + e
+	if p.integralLimit > 0 {
+		integral = math.Max(-p.integralLimit, math.Min(p.integralLimit, integral))
+	}
+	return e, integral, nil
+}
+
+func (p *PIDPolicy) Adjustment(stats EpochStats) (float64, error) {
+	e, integral, err := p.terms(stats)
+	if err != nil {
+		return 0, err
+	}
+	var derivative float64
+	if p.started {
+		derivative = e - p.lastError
+	}
+	return 1 + p.kp*e + p.ki*integral + p.kd*derivative, nil
+}
+
+func (p *PIDPolicy) CompleteEpoch(stats EpochStats) {
+	e, integral, err := p.terms(stats)
+	if err != nil {
+		return
+	}
+	p.integral = integral
+	p.lastError = e
+	p.started = true
+}
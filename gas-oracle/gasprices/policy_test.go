@@ -0,0 +1,155 @@
+package gasprices
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorded gas used by the blocks of each epoch, the blocks have a gas limit
+// of 10M and the epochs last 10 seconds, the target is 3 half full blocks
+var blockGasSeries = map[string][][]uint64{
+	"congested": {
+		{9000000, 9000000, 9000000},
+		{9000000, 9000000, 9000000},
+		{9000000, 9000000, 9000000},
+		{9000000, 9000000, 9000000},
+		{9000000, 9000000, 9000000},
+	},
+	"idle then recovering": {
+		{0, 0, 0},
+		{1000000, 1000000, 1000000},
+		{5000000, 5000000, 5000000},
+		{8000000, 8000000, 8000000},
+	},
+	"at target": {
+		{5000000, 5000000, 5000000},
+		{5000000, 5000000, 5000000},
+		{5000000, 5000000, 5000000},
+	},
+	"fewer full blocks": {
+		{5000000, 5000000},
+		{10000000},
+		{10000000, 10000000},
+	},
+}
+
+// runBlockGasSeries runs the epochs of a series through a GasPriceUpdater
+// and returns the price after each epoch
+func runBlockGasSeries(t *testing.T, gasPricer *GasPricer, epochs [][]uint64) []uint64 {
+	var (
+		latest  uint64
+		gasUsed = make(map[uint64]uint64)
+		prices  []uint64
+	)
+	updater, err := NewGasPriceUpdater(
+		gasPricer,
+		0,
+		10000000,
+		10,
+		func() (uint64, error) { return latest, nil },
+		func(number *big.Int) (uint64, error) { return gasUsed[number.Uint64()], nil },
+		func(price uint64) error {
+			prices = append(prices, price)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	for _, epoch := range epochs {
+		for _, gas := range epoch {
+			latest++
+			gasUsed[latest] = gas
+		}
+		require.NoError(t, updater.UpdateGasPrice())
+	}
+	return prices
+}
+
+func TestPricingPolicies(t *testing.T) {
+	target := returnConstFn(1500000)
+	limits := PriceLimits{FloorPrice: 1000, CeilingPrice: 5000, MaxPercentChangePerEpoch: 0.25}
+	newPolicy := map[string]func() PricingPolicy{
+		LinearPolicyName: func() PricingPolicy {
+			return &LinearPolicy{target}
+		},
+		EIP1559PolicyName: func() PricingPolicy {
+			policy, err := NewEIP1559Policy(DefaultEIP1559TargetFullness, DefaultEIP1559ChangeDenominator)
+			require.NoError(t, err)
+			return policy
+		},
+		PIDPolicyName: func() PricingPolicy {
+			policy, err := NewPIDPolicy(target, 0.2, 0.05, 0.1, 2)
+			require.NoError(t, err)
+			return policy
+		},
+	}
+
+	tests := []struct {
+		series   string
+		policy   string
+		expected []uint64
+	}{
+		// bounded by the max change per epoch and the ceiling
+		{"congested", LinearPolicyName, []uint64{2500, 3125, 3907, 4884, 5000}},
+		{"congested", EIP1559PolicyName, []uint64{2200, 2420, 2662, 2929, 3222}},
+		{"congested", PIDPolicyName, []uint64{2401, 2978, 3723, 4654, 5000}},
+		// bounded by the floor
+		{"idle then recovering", LinearPolicyName, []uint64{1500, 1125, 1125, 1407}},
+		{"idle then recovering", EIP1559PolicyName, []uint64{1750, 1575, 1575, 1694}},
+		{"idle then recovering", PIDPolicyName, []uint64{1500, 1155, 1144, 1282}},
+		{"at target", LinearPolicyName, []uint64{2000, 2000, 2000}},
+		{"at target", EIP1559PolicyName, []uint64{2000, 2000, 2000}},
+		{"at target", PIDPolicyName, []uint64{2000, 2000, 2000}},
+		// the linear policy prices gas per second, eip1559 block fullness
+		{"fewer full blocks", LinearPolicyName, []uint64{1500, 1125, 1407}},
+		{"fewer full blocks", EIP1559PolicyName, []uint64{2000, 2250, 2532}},
+		{"fewer full blocks", PIDPolicyName, []uint64{1834, 1651, 1844}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.series+"/"+tt.policy, func(t *testing.T) {
+			gasPricer, err := NewGasPricerWithPolicy(2000, nil, newPolicy[tt.policy](), limits)
+			require.NoError(t, err)
+			prices := runBlockGasSeries(t, gasPricer, blockGasSeries[tt.series])
+			require.Equal(t, tt.expected, prices)
+		})
+	}
+}
+
+func TestPricingPolicyErrors(t *testing.T) {
+	_, err := NewEIP1559Policy(0, 8)
+	require.Error(t, err)
+	_, err = NewEIP1559Policy(0.5, 0)
+	require.Error(t, err)
+	_, err = NewPIDPolicy(returnConstFn(1), -1, 0, 0, 0)
+	require.Error(t, err)
+
+	policy, err := NewEIP1559Policy(0.5, 8)
+	require.NoError(t, err)
+	_, err = policy.Adjustment(EpochStats{})
+	require.Error(t, err)
+
+	_, err = NewGasPricerWithPolicy(1, nil, policy, PriceLimits{FloorPrice: 10, CeilingPrice: 5, MaxPercentChangePerEpoch: 0.1})
+	require.Error(t, err)
+	_, err = NewGasPricerWithPolicy(1, nil, nil, PriceLimits{FloorPrice: 1, MaxPercentChangePerEpoch: 0.1})
+	require.Error(t, err)
+}
+
+func TestPIDPolicyAdjustmentIsPure(t *testing.T) {
+	policy, err := NewPIDPolicy(returnConstFn(10), 0.5, 0.5, 0.5, 0)
+	require.NoError(t, err)
+	stats := EpochStats{AvgGasPerSecond: 20}
+	first, err := policy.Adjustment(stats)
+	require.NoError(t, err)
+	second, err := policy.Adjustment(stats)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	policy.CompleteEpoch(stats)
+	third, err := policy.Adjustment(stats)
+	require.NoError(t, err)
+	// the integral grew, the derivative is zero
+	require.Equal(t, first+0.5, third)
+}
@@ -37,6 +37,30 @@ func main() {
 		return nil
 	}
 
+	app.Commands = []cli.Command{
+		{
+			Name:      "replay",
+			Usage:     "Replay an audit log with the configured pricing policy",
+			ArgsUsage: "<audit-log>",
+			Description: "Feeds the decisions of an audit log through the gas pricer and the overhead " +
+				"jump table configured by the global options, and prints the results as JSON lines.",
+			Action: func(ctx *cli.Context) error {
+				if ctx.NArg() != 1 {
+					return fmt.Errorf("expected the path of an audit log")
+				}
+				// replaying never sends transactions, no key is required
+				if err := ctx.GlobalSet(flags.DryRunFlag.Name, "true"); err != nil {
+					return err
+				}
+				decisions, err := oracle.ReadAuditLog(ctx.Args().First())
+				if err != nil {
+					return err
+				}
+				return oracle.Replay(oracle.NewConfig(ctx), decisions, os.Stdout)
+			},
+		},
+	}
+
 	// Define the functionality of the application
 	app.Action = func(ctx *cli.Context) error {
 		if args := ctx.Args(); len(args) > 0 {
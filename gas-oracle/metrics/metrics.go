@@ -26,6 +26,12 @@ var (
 		OverHeadGauge metrics.Gauge
 		// OverHeadGauge over_head, amortized cost of batch submission per transaction
 		OverHeadUpdateGauge metrics.Gauge
+		// OverHeadL1GasPerTxGauge L1 gas spent per L2 transaction by the batch submissions
+		OverHeadL1GasPerTxGauge metrics.Gauge
+		// OverHeadComputedCostGauge L1 cost of the batch submissions of the overhead window, in gwei
+		OverHeadComputedCostGauge metrics.Gauge
+		// OverHeadChargedCostGauge cost charged by the overhead for the L2 transactions of the window, in gwei
+		OverHeadChargedCostGauge metrics.Gauge
 		// L1GasPriceGauge l1_base_fee + l1_priority_fee
 		L1GasPriceGauge metrics.Gauge
 
@@ -52,8 +58,22 @@ func InitAndRegisterStats(r metrics.Registry) {
 	GasOracleStats.DaFeeGauge = metrics.NewRegisteredGauge("da_fee", r)
 	GasOracleStats.OverHeadGauge = metrics.NewRegisteredGauge("over_head", r)
 	GasOracleStats.OverHeadUpdateGauge = metrics.NewRegisteredGauge("over_head_update", r)
+	GasOracleStats.OverHeadL1GasPerTxGauge = metrics.NewRegisteredGauge("over_head/l1_gas_per_tx", r)
+	GasOracleStats.OverHeadComputedCostGauge = metrics.NewRegisteredGauge("over_head/computed_cost", r)
+	GasOracleStats.OverHeadChargedCostGauge = metrics.NewRegisteredGauge("over_head/charged_cost", r)
 	GasOracleStats.L1GasPriceGauge = metrics.NewRegisteredGauge("l1_gas_price", r)
 
 	// stats for gas oracle version
 	GasOracleStats.PublishVersionGauge = metrics.NewRegisteredGauge("publish_version", r)
 }
+
+// UpdatePriceSource records the last price quoted by a token price source and
+// whether the source is healthy
+func UpdatePriceSource(name string, price float64, healthy bool) {
+	metrics.GetOrRegisterGaugeFloat64("token_price/source/"+name+"/price", DefaultRegistry).Update(price)
+	var health int64
+	if healthy {
+		health = 1
+	}
+	metrics.GetOrRegisterGauge("token_price/source/"+name+"/healthy", DefaultRegistry).Update(health)
+}
@@ -0,0 +1,297 @@
+package oracle
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// gasPriceOracleCaller reads the parameters of the BVM_GasPriceOracle
+type gasPriceOracleCaller interface {
+	GasPrice(opts *bind.CallOpts) (*big.Int, error)
+	L1BaseFee(opts *bind.CallOpts) (*big.Int, error)
+	DaGasPrice(opts *bind.CallOpts) (*big.Int, error)
+	Overhead(opts *bind.CallOpts) (*big.Int, error)
+	Scalar(opts *bind.CallOpts) (*big.Int, error)
+}
+
+// tokenPrices returns the last ETH and MNT prices and token ratio
+type tokenPrices interface {
+	LastPrices() (float64, float64, float64)
+}
+
+// ParamStatus is the state of a BVM_GasPriceOracle parameter
+type ParamStatus struct {
+	Param   string `json:"param"`
+	Updater string `json:"updater"`
+	Enabled bool   `json:"enabled"`
+	// Computed is the value of the last decision of the updater
+	Computed   *big.Int   `json:"computed,omitempty"`
+	ComputedAt *time.Time `json:"computed_at,omitempty"`
+	OnChain    *big.Int   `json:"on_chain"`
+	Paused     *Override  `json:"paused,omitempty"`
+	Pinned     *Override  `json:"pinned,omitempty"`
+}
+
+// AdminStatus is the response of the status endpoint of the admin API
+type AdminStatus struct {
+	DryRun     bool          `json:"dry_run"`
+	Params     []ParamStatus `json:"params"`
+	Scalar     *big.Int      `json:"scalar"`
+	EthPrice   float64       `json:"eth_price"`
+	MntPrice   float64       `json:"mnt_price"`
+	TokenRatio float64       `json:"token_ratio"`
+}
+
+// overrideRequest is the body of the pause, resume, pin and unpin endpoints
+type overrideRequest struct {
+	Updater  string   `json:"updater"`
+	Param    string   `json:"param"`
+	Value    *big.Int `json:"value"`
+	Duration string   `json:"duration"`
+	Reason   string   `json:"reason"`
+}
+
+// adminServer is the admin API, it shows the computed and on-chain values and
+// lets operators pause updaters and pin parameters
+type adminServer struct {
+	contract  gasPriceOracleCaller
+	audit     *AuditLog
+	prices    tokenPrices
+	overrides *overrides
+	// updaters are the enabled updaters
+	updaters map[string]bool
+	token    string
+	dryRun   bool
+	now      func() time.Time
+}
+
+func newAdminServer(contract gasPriceOracleCaller, audit *AuditLog, prices tokenPrices,
+	overrides *overrides, updaters []string, cfg *Config) *adminServer {
+	enabled := make(map[string]bool, len(updaters))
+	for _, updater := range updaters {
+		enabled[updater] = true
+	}
+	return &adminServer{
+		contract:  contract,
+		audit:     audit,
+		prices:    prices,
+		overrides: overrides,
+		updaters:  enabled,
+		token:     cfg.adminToken,
+		dryRun:    cfg.dryRun,
+		now:       time.Now,
+	}
+}
+
+// Handler returns the routes of the admin API, all of them require the admin
+// token as a bearer token
+func (s *adminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.method(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/overrides", s.method(http.MethodGet, s.handleOverrides))
+	mux.HandleFunc("/journal", s.method(http.MethodGet, s.handleJournal))
+	mux.HandleFunc("/updaters/pause", s.method(http.MethodPost, s.handlePause))
+	mux.HandleFunc("/updaters/resume", s.method(http.MethodPost, s.handleResume))
+	mux.HandleFunc("/params/pin", s.method(http.MethodPost, s.handlePin))
+	mux.HandleFunc("/params/unpin", s.method(http.MethodPost, s.handleUnpin))
+	return s.auth(mux)
+}
+
+func (s *adminServer) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			log.Info("blocked unauthorized admin request", "path", r.URL.Path, "remote", r.RemoteAddr)
+			writeAdminError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *adminServer) method(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeAdminError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *adminServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.status(r.Context())
+	if err != nil {
+		log.Error("cannot get gas oracle status", "err", err)
+		writeAdminError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, status)
+}
+
+func (s *adminServer) status(ctx context.Context) (*AdminStatus, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	onChain := []struct {
+		param string
+		call  func(*bind.CallOpts) (*big.Int, error)
+	}{
+		{paramGasPrice, s.contract.GasPrice},
+		{paramL1BaseFee, s.contract.L1BaseFee},
+		{paramDaGasPrice, s.contract.DaGasPrice},
+		{paramOverhead, s.contract.Overhead},
+	}
+	pauses, pins := s.overrides.Pauses(), s.overrides.Pins()
+
+	status := &AdminStatus{DryRun: s.dryRun}
+	for _, p := range onChain {
+		value, err := p.call(opts)
+		if err != nil {
+			return nil, err
+		}
+		updater := paramUpdaters[p.param]
+		param := ParamStatus{
+			Param:   p.param,
+			Updater: updater,
+			Enabled: s.updaters[updater],
+			OnChain: value,
+		}
+		if d, ok := s.audit.LastDecision(p.param); ok {
+			computedAt := d.Time
+			param.Computed, param.ComputedAt = d.Value, &computedAt
+		}
+		if pause, ok := pauses[updater]; ok {
+			param.Paused = &pause
+		}
+		if pin, ok := pins[p.param]; ok {
+			param.Pinned = &pin
+		}
+		status.Params = append(status.Params, param)
+	}
+
+	scalar, err := s.contract.Scalar(opts)
+	if err != nil {
+		return nil, err
+	}
+	status.Scalar = scalar
+	status.EthPrice, status.MntPrice, status.TokenRatio = s.prices.LastPrices()
+	return status, nil
+}
+
+func (s *adminServer) handleOverrides(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, map[string]map[string]Override{
+		"pauses": s.overrides.Pauses(),
+		"pins":   s.overrides.Pins(),
+	})
+}
+
+func (s *adminServer) handleJournal(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, s.overrides.Entries())
+}
+
+func (s *adminServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	req, duration, ok := s.readOverride(w, r, true)
+	if !ok || !s.checkUpdater(w, req.Updater) {
+		return
+	}
+	if err := s.overrides.Pause(req.Updater, duration, req.Reason, r.RemoteAddr, s.now()); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.handleOverrides(w, r)
+}
+
+func (s *adminServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readOverride(w, r, false)
+	if !ok || !s.checkUpdater(w, req.Updater) {
+		return
+	}
+	if err := s.overrides.Resume(req.Updater, r.RemoteAddr, s.now()); err != nil {
+		writeAdminError(w, http.StatusNotFound, err)
+		return
+	}
+	s.handleOverrides(w, r)
+}
+
+func (s *adminServer) handlePin(w http.ResponseWriter, r *http.Request) {
+	req, duration, ok := s.readOverride(w, r, true)
+	if !ok || !s.checkParam(w, req.Param) {
+		return
+	}
+	if err := s.overrides.Pin(req.Param, req.Value, duration, req.Reason, r.RemoteAddr, s.now()); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.handleOverrides(w, r)
+}
+
+func (s *adminServer) handleUnpin(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readOverride(w, r, false)
+	if !ok || !s.checkParam(w, req.Param) {
+		return
+	}
+	if err := s.overrides.Unpin(req.Param, r.RemoteAddr, s.now()); err != nil {
+		writeAdminError(w, http.StatusNotFound, err)
+		return
+	}
+	s.handleOverrides(w, r)
+}
+
+// readOverride decodes an override request, the duration and reason are
+// required when bounded is set
+func (s *adminServer) readOverride(w http.ResponseWriter, r *http.Request, bounded bool) (*overrideRequest, time.Duration, bool) {
+	var req overrideRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return nil, 0, false
+	}
+	if !bounded {
+		return &req, 0, true
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return nil, 0, false
+	}
+	if req.Reason == "" {
+		writeAdminError(w, http.StatusBadRequest, errors.New("reason is required"))
+		return nil, 0, false
+	}
+	return &req, duration, true
+}
+
+func (s *adminServer) checkUpdater(w http.ResponseWriter, updater string) bool {
+	if !s.updaters[updater] {
+		writeAdminError(w, http.StatusNotFound, errors.New("unknown or disabled updater "+updater))
+		return false
+	}
+	return true
+}
+
+func (s *adminServer) checkParam(w http.ResponseWriter, param string) bool {
+	if _, ok := paramUpdaters[param]; !ok {
+		writeAdminError(w, http.StatusNotFound, errors.New("unknown param "+param))
+		return false
+	}
+	return true
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("error writing admin response", "err", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, code int, err error) {
+	writeAdminJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,166 @@
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
+)
+
+type fakeGasPriceOracle struct{}
+
+func (fakeGasPriceOracle) GasPrice(opts *bind.CallOpts) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (fakeGasPriceOracle) L1BaseFee(opts *bind.CallOpts) (*big.Int, error) {
+	return big.NewInt(2), nil
+}
+
+func (fakeGasPriceOracle) DaGasPrice(opts *bind.CallOpts) (*big.Int, error) {
+	return big.NewInt(3), nil
+}
+
+func (fakeGasPriceOracle) Overhead(opts *bind.CallOpts) (*big.Int, error) {
+	return big.NewInt(4), nil
+}
+
+func (fakeGasPriceOracle) Scalar(opts *bind.CallOpts) (*big.Int, error) {
+	return big.NewInt(5), nil
+}
+
+type fakeTokenPrices struct{}
+
+func (fakeTokenPrices) LastPrices() (float64, float64, float64) {
+	return 1800, 0.45, 4000
+}
+
+func adminRequest(t *testing.T, handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminServer(t *testing.T) {
+	audit, err := NewAuditLog("", false)
+	require.NoError(t, err)
+	audit.Record(Decision{Param: paramGasPrice, Value: big.NewInt(10), Current: big.NewInt(1), Significant: true})
+	overrides, err := newOverrides("", time.Hour)
+	require.NoError(t, err)
+	admin := newAdminServer(fakeGasPriceOracle{}, audit, fakeTokenPrices{}, overrides,
+		[]string{"l2GasPrice", "overhead"}, &Config{adminToken: "secret"})
+	handler := admin.Handler()
+
+	require.Equal(t, http.StatusUnauthorized, adminRequest(t, handler, http.MethodGet, "/status", "", "").Code)
+	require.Equal(t, http.StatusUnauthorized, adminRequest(t, handler, http.MethodGet, "/status", "wrong", "").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, adminRequest(t, handler, http.MethodPost, "/status", "secret", "").Code)
+
+	// overrides are bounded, justified and only target enabled updaters
+	for _, body := range []string{
+		`{"updater": "overhead", "duration": "2h", "reason": "incident"}`,
+		`{"updater": "overhead", "duration": "10m"}`,
+		`{"updater": "overhead", "reason": "incident"}`,
+	} {
+		rec := adminRequest(t, handler, http.MethodPost, "/updaters/pause", "secret", body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	rec := adminRequest(t, handler, http.MethodPost, "/updaters/pause", "secret",
+		`{"updater": "daFee", "duration": "10m", "reason": "incident"}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	rec = adminRequest(t, handler, http.MethodPost, "/updaters/resume", "secret", `{"updater": "overhead"}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = adminRequest(t, handler, http.MethodPost, "/updaters/pause", "secret",
+		`{"updater": "overhead", "duration": "10m", "reason": "batch submitter down"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = adminRequest(t, handler, http.MethodPost, "/params/pin", "secret",
+		`{"param": "gasPrice", "value": 1000, "duration": "30m", "reason": "token price incident"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = adminRequest(t, handler, http.MethodGet, "/status", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status AdminStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	require.Len(t, status.Params, 4)
+	gasPrice := status.Params[0]
+	require.Equal(t, paramGasPrice, gasPrice.Param)
+	require.True(t, gasPrice.Enabled)
+	require.Equal(t, big.NewInt(10), gasPrice.Computed)
+	require.Equal(t, big.NewInt(1), gasPrice.OnChain)
+	require.Equal(t, big.NewInt(1000), gasPrice.Pinned.Value)
+	require.Equal(t, "token price incident", gasPrice.Pinned.Reason)
+	overhead := status.Params[3]
+	require.Equal(t, paramOverhead, overhead.Param)
+	require.Nil(t, overhead.Computed)
+	require.Equal(t, "batch submitter down", overhead.Paused.Reason)
+	require.False(t, status.Params[2].Enabled)
+	require.Equal(t, big.NewInt(5), status.Scalar)
+	require.Equal(t, float64(4000), status.TokenRatio)
+
+	rec = adminRequest(t, handler, http.MethodPost, "/params/unpin", "secret", `{"param": "gasPrice"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = adminRequest(t, handler, http.MethodGet, "/journal", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entries []OverrideEntry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entries))
+	require.Equal(t, []string{"pause overhead", "pin gasPrice", "unpin gasPrice"}, journalActions(entries))
+	require.NotEmpty(t, entries[0].Remote)
+}
+
+type fixedPriceSource float64
+
+func (p fixedPriceSource) Quote(ctx context.Context) (tokenprice.Quote, error) {
+	return tokenprice.Quote{Price: float64(p), Timestamp: time.Now()}, nil
+}
+
+// TestAdminServerStatusWhileUpdatingPrices reads the status while the token
+// prices are updated, run it with -race
+func TestAdminServerStatusWhileUpdatingPrices(t *testing.T) {
+	sources := tokenprice.NewRegistry(tokenprice.DefaultMaxDeviation)
+	require.NoError(t, sources.Register(fixedPriceSource(2000),
+		tokenprice.SourceOptions{Name: "eth", Token: tokenprice.ETH}))
+	require.NoError(t, sources.Register(fixedPriceSource(0.5),
+		tokenprice.SourceOptions{Name: "mnt", Token: tokenprice.MNT}))
+	prices := tokenprice.NewClientWithSources(sources, 0, uint64(tokenprice.RealTokenRatioMode))
+
+	audit, err := NewAuditLog("", false)
+	require.NoError(t, err)
+	overrides, err := newOverrides("", time.Hour)
+	require.NoError(t, err)
+	handler := newAdminServer(fakeGasPriceOracle{}, audit, prices, overrides,
+		[]string{"l2GasPrice"}, &Config{adminToken: "secret"}).Handler()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := prices.PriceRatioWithMode(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		rec := adminRequest(t, handler, http.MethodGet, "/status", "secret", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	wg.Wait()
+
+	ethPrice, mntPrice, _ := prices.LastPrices()
+	require.Equal(t, float64(2000), ethPrice)
+	require.Equal(t, 0.5, mntPrice)
+}
@@ -0,0 +1,153 @@
+package oracle
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Decision is a fee decision of an updater, written to the audit log
+type Decision struct {
+	Time   time.Time `json:"time"`
+	Param  string    `json:"param"`
+	DryRun bool      `json:"dry_run"`
+	// Value is the computed value of the parameter
+	Value *big.Int `json:"value"`
+	// Current is the on-chain value of the parameter
+	Current *big.Int `json:"current"`
+	// Significant is whether the difference is large enough to be sent
+	Significant bool           `json:"significant"`
+	Inputs      DecisionInputs `json:"inputs"`
+}
+
+// DecisionInputs are the inputs a Decision is computed from, only the ones
+// of its parameter are set
+type DecisionInputs struct {
+	// gasPrice
+	AvgGasPerSecond float64 `json:"avg_gas_per_second,omitempty"`
+	GasUsed         uint64  `json:"gas_used,omitempty"`
+	Blocks          uint64  `json:"blocks,omitempty"`
+	GasLimit        uint64  `json:"gas_limit,omitempty"`
+	// gasPrice and l1BaseFee
+	EthPrice   float64 `json:"eth_price,omitempty"`
+	MntPrice   float64 `json:"mnt_price,omitempty"`
+	TokenRatio float64 `json:"token_ratio,omitempty"`
+	// l1BaseFee, L1BaseFee is the base fee of the L1 header and
+	// L1BestBaseFee the highest base fee of the recent blocks
+	Scalar        *big.Int `json:"scalar,omitempty"`
+	L1BaseFee     *big.Int `json:"l1_base_fee,omitempty"`
+	L1BestBaseFee *big.Int `json:"l1_best_base_fee,omitempty"`
+	L1GasTipCap   *big.Int `json:"l1_gas_tip_cap,omitempty"`
+	// l1BaseFee and overhead
+	L1BlockNumber uint64 `json:"l1_block_number,omitempty"`
+	// overhead, jump table
+	CtcBatches *big.Int `json:"ctc_batches,omitempty"`
+	BatchSize  *big.Int `json:"batch_size,omitempty"`
+	// overhead, receipts
+	L1GasUsed      uint64   `json:"l1_gas_used,omitempty"`
+	L1Cost         *big.Int `json:"l1_cost,omitempty"`
+	L2Transactions uint64   `json:"l2_transactions,omitempty"`
+	// daGasPrice, the rollup fee of the DA fee contract
+	DaRollupFee *big.Int `json:"da_rollup_fee,omitempty"`
+}
+
+// AuditLog writes the fee decisions as JSON lines
+type AuditLog struct {
+	mtx    sync.Mutex
+	file   *os.File
+	dryRun bool
+	// last is the last decision of every parameter
+	last map[string]Decision
+}
+
+// NewAuditLog appends the decisions to the file at path, they are only
+// logged if path is empty
+func NewAuditLog(path string, dryRun bool) (*AuditLog, error) {
+	a := &AuditLog{dryRun: dryRun, last: make(map[string]Decision)}
+	if path == "" {
+		return a, nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open audit log %s: %w", path, err)
+	}
+	a.file = file
+	return a, nil
+}
+
+// Record writes a decision, errors are logged as the audit log must not stop
+// the updates
+func (a *AuditLog) Record(d Decision) {
+	if a == nil {
+		return
+	}
+	if d.Time.IsZero() {
+		d.Time = time.Now()
+	}
+	d.DryRun = a.dryRun
+	log.Info("fee decision", "param", d.Param, "value", d.Value, "current", d.Current,
+		"significant", d.Significant, "dry-run", d.DryRun)
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.last[d.Param] = d
+	if a.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(d)
+	if err != nil {
+		log.Error("cannot encode fee decision", "err", err)
+		return
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		log.Error("cannot write audit log", "err", err)
+	}
+}
+
+// LastDecision returns the last decision recorded for param
+func (a *AuditLog) LastDecision(param string) (Decision, bool) {
+	if a == nil {
+		return Decision{}, false
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	d, ok := a.last[param]
+	return d, ok
+}
+
+func (a *AuditLog) Close() error {
+	if a == nil || a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// ReadAuditLog reads the decisions of an audit log
+func ReadAuditLog(path string) ([]Decision, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var decisions []Decision
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var d Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, scanner.Err()
+}
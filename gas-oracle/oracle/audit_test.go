@@ -0,0 +1,54 @@
+package oracle
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := NewAuditLog(path, true)
+	require.NoError(t, err)
+	audit.Record(Decision{
+		Param:       paramOverhead,
+		Value:       big.NewInt(2000),
+		Current:     big.NewInt(1500),
+		Significant: true,
+		Inputs: DecisionInputs{
+			L1BlockNumber: 100,
+			CtcBatches:    big.NewInt(3),
+			BatchSize:     big.NewInt(50),
+		},
+	})
+	audit.Record(Decision{
+		Param:   paramGasPrice,
+		Value:   big.NewInt(1),
+		Current: big.NewInt(1),
+		Inputs:  DecisionInputs{AvgGasPerSecond: 1e6, EthPrice: 1800, MntPrice: 0.45, TokenRatio: 4000},
+	})
+	require.NoError(t, audit.Close())
+
+	// decisions are appended
+	audit, err = NewAuditLog(path, false)
+	require.NoError(t, err)
+	audit.Record(Decision{Param: paramDaGasPrice, Value: big.NewInt(5), Current: big.NewInt(4)})
+	require.NoError(t, audit.Close())
+
+	decisions, err := ReadAuditLog(path)
+	require.NoError(t, err)
+	require.Len(t, decisions, 3)
+	require.True(t, decisions[0].DryRun)
+	require.False(t, decisions[0].Time.IsZero())
+	require.Equal(t, big.NewInt(2000), decisions[0].Value)
+	require.Equal(t, big.NewInt(50), decisions[0].Inputs.BatchSize)
+	require.Equal(t, 1800.0, decisions[1].Inputs.EthPrice)
+	require.False(t, decisions[2].DryRun)
+
+	// a nil audit log is a no-op
+	var nop *AuditLog
+	nop.Record(Decision{Param: paramGasPrice})
+	require.NoError(t, nop.Close())
+}
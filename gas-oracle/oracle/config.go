@@ -1,14 +1,13 @@
 package oracle
 
 import (
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
-	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/gas-oracle/flags"
 	"github.com/urfave/cli"
 )
@@ -24,10 +23,18 @@ type Config struct {
 	daFeeContractAddress             common.Address
 	sccContractAddress               common.Address
 	ctcContractAddress               common.Address
-	privateKey                       *ecdsa.PrivateKey
+	signer                           signer.Config
 	gasPrice                         *big.Int
 	waitForReceipt                   bool
 	floorPrice                       uint64
+	ceilingPrice                     uint64
+	l2GasPricePolicy                 string
+	eip1559TargetFullness            float64
+	eip1559ChangeDenominator         float64
+	pidKp                            float64
+	pidKi                            float64
+	pidKd                            float64
+	pidIntegralLimit                 float64
 	targetGasPerSecond               uint64
 	maxPercentChangePerEpoch         float64
 	averageBlockGasLimitPerEpoch     uint64
@@ -37,6 +44,7 @@ type Config struct {
 	l2GasPriceSignificanceFactor     float64
 	PriceBackendURL                  string
 	PriceBackendUniswapURL           string
+	priceSourcesConfig               string
 	tokenPricerUpdateFrequencySecond uint64
 	tokenRatioMode                   uint64
 	tokenPairMNTMode                 bool
@@ -46,6 +54,9 @@ type Config struct {
 	enableL1Overhead                 bool
 	enableL2GasPrice                 bool
 	enableDaFee                      bool
+	stateDBPath                      string
+	dryRun                           bool
+	auditLogPath                     string
 	// hsm config
 	EnableHsm  bool
 	HsmAPIName string
@@ -58,6 +69,12 @@ type Config struct {
 	stateRollupGasUsed *big.Int
 	stateHashGasUsed   *big.Int
 	dataRollupGasUsed  *big.Int
+	// receipts overhead
+	l1OverheadMode               string
+	daRollupContractAddress      common.Address
+	l1OverheadWindowBlocks       uint64
+	l1OverheadTargetMargin       float64
+	l1OverheadSignificanceFactor float64
 	// Metrics config
 	MetricsEnabled          bool
 	MetricsHTTP             string
@@ -67,6 +84,13 @@ type Config struct {
 	MetricsInfluxDBDatabase string
 	MetricsInfluxDBUsername string
 	MetricsInfluxDBPassword string
+	// admin API config
+	adminEnabled             bool
+	adminHTTP                string
+	adminPort                int
+	adminToken               string
+	adminJournalPath         string
+	adminMaxOverrideDuration time.Duration
 }
 
 // NewConfig creates a new Config
@@ -90,16 +114,28 @@ func NewConfig(ctx *cli.Context) *Config {
 	cfg.l2GasPriceSignificanceFactor = ctx.GlobalFloat64(flags.L2GasPriceSignificanceFactorFlag.Name)
 	cfg.PriceBackendURL = ctx.GlobalString(flags.PriceBackendURL.Name)
 	cfg.PriceBackendUniswapURL = ctx.GlobalString(flags.PriceBackendUniswapURL.Name)
+	cfg.priceSourcesConfig = ctx.GlobalString(flags.PriceSourcesConfigFlag.Name)
 	cfg.tokenPricerUpdateFrequencySecond = ctx.GlobalUint64(flags.TokenPricerUpdateFrequencySecond.Name)
 	cfg.tokenRatioMode = ctx.GlobalUint64(flags.TokenRatioMode.Name)
 	cfg.tokenPairMNTMode = ctx.GlobalBool(flags.TokenPairMNTMode.Name)
 	cfg.floorPrice = ctx.GlobalUint64(flags.FloorPriceFlag.Name)
+	cfg.ceilingPrice = ctx.GlobalUint64(flags.CeilingPriceFlag.Name)
+	cfg.l2GasPricePolicy = ctx.GlobalString(flags.L2GasPricePolicyFlag.Name)
+	cfg.eip1559TargetFullness = ctx.GlobalFloat64(flags.EIP1559TargetFullnessFlag.Name)
+	cfg.eip1559ChangeDenominator = ctx.GlobalFloat64(flags.EIP1559ChangeDenominatorFlag.Name)
+	cfg.pidKp = ctx.GlobalFloat64(flags.PIDKpFlag.Name)
+	cfg.pidKi = ctx.GlobalFloat64(flags.PIDKiFlag.Name)
+	cfg.pidKd = ctx.GlobalFloat64(flags.PIDKdFlag.Name)
+	cfg.pidIntegralLimit = ctx.GlobalFloat64(flags.PIDIntegralLimitFlag.Name)
 	cfg.l1BaseFeeSignificanceFactor = ctx.GlobalFloat64(flags.L1BaseFeeSignificanceFactorFlag.Name)
 	cfg.daFeeSignificanceFactor = ctx.GlobalFloat64(flags.DaFeeSignificanceFactorFlag.Name)
 	cfg.enableL1BaseFee = ctx.GlobalBool(flags.EnableL1BaseFeeFlag.Name)
 	cfg.enableL1Overhead = ctx.GlobalBool(flags.EnableL1OverheadFlag.Name)
 	cfg.enableL2GasPrice = ctx.GlobalBool(flags.EnableL2GasPriceFlag.Name)
 	cfg.enableDaFee = ctx.GlobalBool(flags.EnableDaFeeFlag.Name)
+	cfg.stateDBPath = ctx.GlobalString(flags.StateDBPathFlag.Name)
+	cfg.dryRun = ctx.GlobalBool(flags.DryRunFlag.Name)
+	cfg.auditLogPath = ctx.GlobalString(flags.AuditLogFlag.Name)
 	cfg.EnableHsm = ctx.GlobalBool(flags.EnableHsmFlag.Name)
 	cfg.HsmAddress = ctx.GlobalString(flags.HsmAddressFlag.Name)
 	cfg.HsmAPIName = ctx.GlobalString(flags.HsmAPINameFlag.Name)
@@ -111,21 +147,37 @@ func NewConfig(ctx *cli.Context) *Config {
 	cfg.stateRollupGasUsed = big.NewInt(ctx.GlobalInt64(flags.StateRollupGasUsed.Name))
 	cfg.stateHashGasUsed = big.NewInt(ctx.GlobalInt64(flags.StateHashGasUsed.Name))
 	cfg.dataRollupGasUsed = big.NewInt(ctx.GlobalInt64(flags.DataRollupGasUsed.Name))
+	cfg.l1OverheadMode = ctx.GlobalString(flags.L1OverheadModeFlag.Name)
+	cfg.daRollupContractAddress = common.HexToAddress(ctx.GlobalString(flags.DaRollupContractAddressFlag.Name))
+	cfg.l1OverheadWindowBlocks = ctx.GlobalUint64(flags.L1OverheadWindowBlocksFlag.Name)
+	cfg.l1OverheadTargetMargin = ctx.GlobalFloat64(flags.L1OverheadTargetMarginFlag.Name)
+	cfg.l1OverheadSignificanceFactor = ctx.GlobalFloat64(flags.L1OverheadSignificanceFactorFlag.Name)
 
-	if cfg.EnableHsm {
+	// the signer flags take precedence over the legacy private key and hsm
+	// flags
+	cfg.signer = signer.ReadCLIConfig(ctx)
+	switch {
+	case cfg.signer.Enabled():
+	case cfg.EnableHsm:
 		log.Info("gasoracle", "enable hsm", cfg.EnableHsm,
 			"hsm address", cfg.HsmAddress)
-	} else {
-		if ctx.GlobalIsSet(flags.PrivateKeyFlag.Name) {
-			hex := ctx.GlobalString(flags.PrivateKeyFlag.Name)
-			hex = strings.TrimPrefix(hex, "0x")
-			key, err := crypto.HexToECDSA(hex)
-			if err != nil {
-				log.Error(fmt.Sprintf("Option %q: %v", flags.PrivateKeyFlag.Name, err))
-			}
-			cfg.privateKey = key
-		} else {
-			log.Crit("No private key configured")
+		cfg.signer = signer.Config{
+			Type:           signer.TypeKMS,
+			KMSKeyName:     cfg.HsmAPIName,
+			Address:        cfg.HsmAddress,
+			KMSCredentials: cfg.HsmCreden,
+		}
+	case ctx.GlobalIsSet(flags.PrivateKeyFlag.Name):
+		cfg.signer = signer.Config{
+			Type:       signer.TypeLocal,
+			PrivateKey: ctx.GlobalString(flags.PrivateKeyFlag.Name),
+		}
+	case !cfg.dryRun:
+		log.Crit("No signer configured")
+	}
+	if cfg.signer.Enabled() {
+		if err := cfg.signer.Check(); err != nil {
+			log.Error(fmt.Sprintf("Option %q: %v", signer.TypeFlagName, err))
 		}
 	}
 
@@ -155,6 +207,12 @@ func NewConfig(ctx *cli.Context) *Config {
 	cfg.MetricsInfluxDBDatabase = ctx.GlobalString(flags.MetricsInfluxDBDatabaseFlag.Name)
 	cfg.MetricsInfluxDBUsername = ctx.GlobalString(flags.MetricsInfluxDBUsernameFlag.Name)
 	cfg.MetricsInfluxDBPassword = ctx.GlobalString(flags.MetricsInfluxDBPasswordFlag.Name)
+	cfg.adminEnabled = ctx.GlobalBool(flags.AdminEnabledFlag.Name)
+	cfg.adminHTTP = ctx.GlobalString(flags.AdminHTTPFlag.Name)
+	cfg.adminPort = ctx.GlobalInt(flags.AdminPortFlag.Name)
+	cfg.adminToken = ctx.GlobalString(flags.AdminTokenFlag.Name)
+	cfg.adminJournalPath = ctx.GlobalString(flags.AdminJournalFlag.Name)
+	cfg.adminMaxOverrideDuration = ctx.GlobalDuration(flags.AdminMaxOverrideDurationFlag.Name)
 
 	return &cfg
 }
@@ -2,28 +2,24 @@ package oracle
 
 import (
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
 	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
 	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
 	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
-	"github.com/mantlenetworkio/mantle/l2geth/core/rawdb"
-	"github.com/mantlenetworkio/mantle/l2geth/ethdb"
 )
 
-const GAS_ORACLE_SYNC_HEIGHT = "GAS_ORACLE_SYNC_HEIGHT"
-
 var (
 	// errInvalidSigningKey represents the error when the signing key used
 	// is not the Owner of the contract and therefore cannot update the gasprice
@@ -31,15 +27,18 @@ var (
 	// errNoChainID represents the error when the chain id is not provided
 	// and it cannot be remotely fetched
 	errNoChainID = errors.New("no chain id provided")
-	// errNoPrivateKey represents the error when the private key is not provided to
+	// errNoSigner represents the error when the signer is not provided to
 	// the application
-	errNoPrivateKey = errors.New("no private key provided")
+	errNoSigner = errors.New("no signer provided")
 	// errWrongChainID represents the error when the configured chain id is not
 	// correct
 	errWrongChainID = errors.New("wrong chain id provided")
 	// errNoBaseFee represents the error when the base fee is not found on the
 	// block. This means that the block being queried is pre eip1559
 	errNoBaseFee = errors.New("base fee not found on block")
+	// errNoAdminToken represents the error when the admin API is enabled
+	// without a token
+	errNoAdminToken = errors.New("no admin token provided")
 )
 
 // GasPriceOracle manages a hot key that can update the L2 Gas Price
@@ -47,14 +46,27 @@ type GasPriceOracle struct {
 	l1ChainID       *big.Int
 	l2ChainID       *big.Int
 	ctx             context.Context
+	cancel          context.CancelFunc
 	stop            chan struct{}
+	done            chan struct{}
 	contract        *bindings.BVMGasPriceOracle
 	l2Backend       DeployContractBackend
 	l1Backend       bind.ContractTransactor
+	l1BaseFees      baseFeeBackend
+	l1Receipts      receiptBackend
 	daBackend       *bindings.BVMEigenDataLayrFee
 	sccBackend      *bindings.StateCommitmentChain
 	ctcBackend      *bindings.CanonicalTransactionChain
+	gasPricer       *gasprices.GasPricer
 	gasPriceUpdater *gasprices.GasPriceUpdater
+	tokenPricer     *tokenprice.Client
+	l2GasPriceQueue *pendingUpdates
+	signer          signer.Signer
+	sender          updateSender
+	store           StateStore
+	audit           *AuditLog
+	overrides       *overrides
+	admin           *http.Server
 	config          *Config
 }
 
@@ -66,18 +78,16 @@ func (g *GasPriceOracle) Start() error {
 	if g.config.l2ChainID == nil {
 		return fmt.Errorf("layer-two: %w", errNoChainID)
 	}
+	if g.signer == nil && !g.config.dryRun {
+		return errNoSigner
+	}
 	var address common.Address
-	if !g.config.EnableHsm {
-		if g.config.privateKey == nil {
-			return errNoPrivateKey
-		}
-		address = crypto.PubkeyToAddress(g.config.privateKey.PublicKey)
-	} else {
-		address = common.HexToAddress(g.config.HsmAddress)
+	if g.signer != nil {
+		address = g.signer.Address()
 	}
 
 	log.Info("Starting Gas Price Oracle", "l1-chain-id", g.l1ChainID,
-		"l2-chain-id", g.l2ChainID, "address", address.Hex())
+		"l2-chain-id", g.l2ChainID, "address", address.Hex(), "dry-run", g.config.dryRun)
 
 	price, err := g.contract.GasPrice(&bind.CallOpts{
 		Context: context.Background(),
@@ -90,23 +100,117 @@ func (g *GasPriceOracle) Start() error {
 	log.Info("Starting Gas Price Oracle enableL1BaseFee", "enableL1BaseFee",
 		g.config.enableL1BaseFee, "enableL2GasPrice", g.config.enableL2GasPrice, "enableDaFee", g.config.enableDaFee)
 
+	sched := newScheduler(g.sender, g.overrides)
 	if g.config.enableL1BaseFee {
-		go g.BaseFeeLoop()
+		updateBaseFee, err := wrapUpdateBaseFee(g.l1BaseFees, g.contract, g.config, g.audit)
+		if err != nil {
+			return err
+		}
+		sched.addJob(&updateJob{
+			name:     "l1BaseFee",
+			interval: time.Duration(g.config.l1BaseFeeEpochLengthSeconds) * time.Second,
+			run:      updateBaseFee,
+		})
 	}
 	if g.config.enableL1Overhead {
-		go g.OverHeadLoop()
+		job := &updateJob{
+			name:     "overhead",
+			interval: overheadEpochLength,
+		}
+		switch g.config.l1OverheadMode {
+		case "", overheadModeJumpTable:
+			overhead, err := newOverheadUpdater(g.l1Backend, g.sccBackend, g.ctcBackend, g.contract, g.store, g.config, g.audit)
+			if err != nil {
+				return err
+			}
+			job.run, job.commit = overhead.run, overhead.commit
+		case overheadModeReceipts:
+			overhead, err := newReceiptOverheadUpdater(g.l1Receipts, g.sccBackend, g.contract, g.config, g.audit)
+			if err != nil {
+				return err
+			}
+			job.run, job.commit = overhead.run, overhead.commit
+		default:
+			return fmt.Errorf("unknown l1 overhead mode %s", g.config.l1OverheadMode)
+		}
+		log.Info("Starting L1 overhead updater", "mode", g.config.l1OverheadMode)
+		sched.addJob(job)
 	}
 	if g.config.enableDaFee {
-		go g.DaFeeLoop()
+		sched.addJob(&updateJob{
+			name:     "daFee",
+			interval: time.Duration(g.config.daFeeEpochLengthSeconds) * time.Second,
+			run:      wrapUpdateDaFee(g.daBackend, g.contract, g.config, g.audit),
+		})
 	}
 	if g.config.enableL2GasPrice {
-		go g.Loop()
+		sched.addJob(&updateJob{
+			name:     "l2GasPrice",
+			interval: time.Duration(g.config.epochLengthSeconds) * time.Second,
+			run:      g.updateL2GasPrice,
+		})
 	}
 
+	if g.config.adminEnabled {
+		if err := g.startAdmin(sched); err != nil {
+			return err
+		}
+	}
+
+	g.done = make(chan struct{})
+	go func() {
+		defer close(g.done)
+		sched.Run(g.ctx)
+	}()
+
 	return nil
 }
 
+// startAdmin serves the admin API for the updaters of sched
+func (g *GasPriceOracle) startAdmin(sched *scheduler) error {
+	if g.config.adminToken == "" {
+		return errNoAdminToken
+	}
+	updaters := make([]string, 0, len(sched.jobs))
+	for _, job := range sched.jobs {
+		updaters = append(updaters, job.name)
+	}
+	admin := newAdminServer(g.contract, g.audit, g.tokenPricer, g.overrides, updaters, g.config)
+	g.admin = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", g.config.adminHTTP, g.config.adminPort),
+		Handler: admin.Handler(),
+	}
+	log.Info("Starting admin API", "addr", g.admin.Addr)
+	go func() {
+		if err := g.admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("Failure in running admin API", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops the updates and the admin API and closes the state store
 func (g *GasPriceOracle) Stop() {
+	if g.admin != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := g.admin.Shutdown(ctx); err != nil {
+			log.Error("cannot stop admin API", "err", err)
+		}
+		cancel()
+	}
+	g.cancel()
+	if g.done != nil {
+		<-g.done
+	}
+	if err := g.store.Close(); err != nil {
+		log.Error("cannot close state store", "err", err)
+	}
+	if err := g.audit.Close(); err != nil {
+		log.Error("cannot close audit log", "err", err)
+	}
+	if err := g.overrides.Close(); err != nil {
+		log.Error("cannot close override journal", "err", err)
+	}
 	close(g.stop)
 }
 
@@ -124,12 +228,7 @@ func (g *GasPriceOracle) ensure() error {
 	if err != nil {
 		return err
 	}
-	var address common.Address
-	if g.config.EnableHsm {
-		address = common.HexToAddress(g.config.HsmAddress)
-	} else {
-		address = crypto.PubkeyToAddress(g.config.privateKey.PublicKey)
-	}
+	address := g.signer.Address()
 	if address != owner {
 		log.Error("Signing key does not match contract owner", "signer", address.Hex(), "owner", owner.Hex())
 		return errInvalidSigningKey
@@ -137,172 +236,50 @@ func (g *GasPriceOracle) ensure() error {
 	return nil
 }
 
-// Loop is the main logic of the gas-oracle
-func (g *GasPriceOracle) Loop() {
-	timer := time.NewTicker(time.Duration(g.config.epochLengthSeconds) * time.Second)
-	defer timer.Stop()
-
-	for {
-		select {
-		case <-timer.C:
-			log.Trace("polling", "time", time.Now())
-			if err := g.Update(); err != nil {
-				log.Error("cannot update gas price", "message", err)
-			}
-
-		case <-g.ctx.Done():
-			g.Stop()
-		}
-	}
-}
-
-func (g *GasPriceOracle) BaseFeeLoop() {
-	timer := time.NewTicker(time.Duration(g.config.l1BaseFeeEpochLengthSeconds) * time.Second)
-	defer timer.Stop()
-
-	updateBaseFee, err := wrapUpdateBaseFee(g.l1Backend, g.l2Backend, g.config)
-	if err != nil {
-		panic(err)
-	}
-	for {
-		select {
-		case <-timer.C:
-			if err := updateBaseFee(); err != nil {
-				log.Error("cannot update l1 base fee", "message", err)
-			}
-		case <-g.ctx.Done():
-			g.Stop()
-		}
-	}
-}
-
-func (g *GasPriceOracle) DaFeeLoop() {
-	timer := time.NewTicker(time.Duration(g.config.daFeeEpochLengthSeconds) * time.Second)
-	defer timer.Stop()
-
-	updateDaFee, err := wrapUpdateDaFee(g.daBackend, g.l2Backend, g.config)
-	if err != nil {
-		panic(err)
-	}
-
-	for {
-		select {
-		case <-timer.C:
-			if err := updateDaFee(); err != nil {
-				log.Error("cannot update da fee", "messgae", err)
-			}
-
-		case <-g.ctx.Done():
-			g.Stop()
-		}
-	}
-}
-
-func (g *GasPriceOracle) OverHeadLoop() {
-	// set ticker
-	ticker := time.NewTicker(5 * time.Second)
-
-	// read gas-oracle synced height
-	db, height := readGasOracleSyncHeight()
-	log.Info("ReadGasOracleSyncHeight", "height", height)
-	// set channel
-	stateBatchAppendChan := make(chan *bindings.StateCommitmentChainStateBatchAppended, 1)
-
-	// set context
-	ctcTotalBatches, err := g.ctcBackend.GetTotalBatches(&bind.CallOpts{})
-	if err != nil {
-		panic(err)
-	}
-	updateOverhead, err := wrapUpdateOverhead(g.l2Backend, g.config)
-	if err != nil {
-		panic(err)
-	}
-
-	var end uint64
-	for {
-		select {
-		case <-ticker.C:
-			log.Info("OverHeadLoop is living, HeartBeat It!")
-			latestHeader, err := g.l1Backend.HeaderByNumber(g.ctx, nil)
-			if err != nil {
-				log.Warn("get latest header in error", "error", err)
-				continue
-			}
-			// repeat query latest block is not allowed
-			if height != nil && height.Uint64() != 0 && height.Uint64() >= latestHeader.Number.Uint64() {
-				continue
-			}
-			if height == nil || height.Uint64() == 0 {
-				height = latestHeader.Number
-			}
-			end = latestHeader.Number.Uint64()
-
-			iter, err := g.sccBackend.FilterStateBatchAppended(&bind.FilterOpts{
-				Start:   height.Uint64(),
-				End:     &end,
-				Context: g.ctx,
-			}, nil)
-			for iter.Next() {
-				select {
-				case stateBatchAppendChan <- iter.Event:
-					log.Info("write event into channel", "channel length is", len(stateBatchAppendChan))
-				default:
-					// discard additional event to process the last one
-					<-stateBatchAppendChan
-					stateBatchAppendChan <- iter.Event
-					log.Warn("write additional event into channel, will ignore prev events")
-				}
-			}
-			_ = writeGasOracleSyncHeight(db, latestHeader.Number)
-			height = latestHeader.Number.Add(latestHeader.Number, big.NewInt(1))
-			log.Info("Update synced height", "height", height)
-		case ev := <-stateBatchAppendChan:
-			currentCtcBatches, err := g.ctcBackend.GetTotalBatches(&bind.CallOpts{})
-			if err != nil {
-				continue
-			}
-			log.Info("current scc batch size", "size", ev.BatchSize)
-			log.Info("CTC circle num in SCC circle", "count", new(big.Int).Sub(currentCtcBatches, ctcTotalBatches))
-			if err := updateOverhead(new(big.Int).Sub(currentCtcBatches, ctcTotalBatches), ev.BatchSize); err != nil {
-				log.Error("cannot update overhead", "message", err)
-			}
-			ctcTotalBatches = currentCtcBatches
-		case <-g.ctx.Done():
-			db.Close()
-			g.Stop()
-		}
-	}
-}
-
-// Update will update the gas price
-func (g *GasPriceOracle) Update() error {
-	l2GasPrice, err := g.contract.GasPrice(&bind.CallOpts{
-		Context: g.ctx,
-	})
-	if err != nil {
-		return fmt.Errorf("cannot get gas price: %w", err)
-	}
-
+// updateL2GasPrice runs the GasPriceUpdater and returns the L2 gas price
+// update it decided
+func (g *GasPriceOracle) updateL2GasPrice(ctx context.Context) ([]paramUpdate, error) {
 	if err := g.gasPriceUpdater.UpdateGasPrice(); err != nil {
-		return fmt.Errorf("cannot update gas price: %w", err)
+		g.l2GasPriceQueue.drain()
+		return nil, fmt.Errorf("cannot update gas price: %w", err)
 	}
-
-	newGasPrice, err := g.contract.GasPrice(&bind.CallOpts{
-		Context: g.ctx,
-	})
-	if err != nil {
-		return fmt.Errorf("cannot get gas price: %w", err)
-	}
-
 	local := g.gasPriceUpdater.GetGasPrice()
-	log.Info("Update", "original", l2GasPrice, "current", newGasPrice, "local", local)
-	return nil
+	log.Info("Update", "local", local)
+
+	updates := g.l2GasPriceQueue.drain()
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	ethPrice, mntPrice, ratio := g.tokenPricer.LastPrices()
+	epoch := g.gasPricer.LastEpoch()
+	return decideL2GasPrice(ctx, g.contract, g.config, g.audit, updates[len(updates)-1], DecisionInputs{
+		AvgGasPerSecond: epoch.AvgGasPerSecond,
+		GasUsed:         epoch.GasUsed,
+		Blocks:          epoch.Blocks,
+		GasLimit:        epoch.GasLimit,
+		EthPrice:        ethPrice,
+		MntPrice:        mntPrice,
+		TokenRatio:      ratio,
+	})
 }
 
 // NewGasPriceOracle creates a new GasPriceOracle based on a Config
 func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
-	tokenPricer := tokenprice.NewClient(cfg.PriceBackendURL, cfg.PriceBackendUniswapURL,
-		cfg.tokenPricerUpdateFrequencySecond, cfg.tokenRatioMode, cfg.tokenPairMNTMode)
+	var tokenPricer *tokenprice.Client
+	if cfg.priceSourcesConfig != "" {
+		sourcesConfig, err := tokenprice.LoadSourcesConfig(cfg.priceSourcesConfig)
+		if err != nil {
+			return nil, err
+		}
+		sources, err := tokenprice.NewRegistryFromConfig(sourcesConfig, cfg.PriceBackendUniswapURL)
+		if err != nil {
+			return nil, err
+		}
+		tokenPricer = tokenprice.NewClientWithSources(sources, cfg.tokenPricerUpdateFrequencySecond, cfg.tokenRatioMode)
+	} else {
+		tokenPricer = tokenprice.NewClient(cfg.PriceBackendURL, cfg.PriceBackendUniswapURL,
+			cfg.tokenPricerUpdateFrequencySecond, cfg.tokenRatioMode, cfg.tokenPairMNTMode)
+	}
 	if tokenPricer == nil {
 		return nil, fmt.Errorf("invalid token price client")
 	}
@@ -355,19 +332,11 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 	}
 
 	// Create a gas pricer for the gas price updater
-	log.Info("Creating GasPricer", "currentPrice", currentPrice,
-		"floorPrice", cfg.floorPrice, "targetGasPerSecond", cfg.targetGasPerSecond,
+	log.Info("Creating GasPricer", "currentPrice", currentPrice, "policy", cfg.l2GasPricePolicy,
+		"floorPrice", cfg.floorPrice, "ceilingPrice", cfg.ceilingPrice, "targetGasPerSecond", cfg.targetGasPerSecond,
 		"maxPercentChangePerEpoch", cfg.maxPercentChangePerEpoch)
 
-	gasPricer, err := gasprices.NewGasPricer(
-		currentPrice.Uint64(),
-		cfg.floorPrice,
-		tokenPricer,
-		func() float64 {
-			return float64(cfg.targetGasPerSecond)
-		},
-		cfg.maxPercentChangePerEpoch,
-	)
+	gasPricer, err := newGasPricer(cfg, currentPrice.Uint64(), tokenPricer)
 	if err != nil {
 		return nil, err
 	}
@@ -399,8 +368,15 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 		cfg.l1ChainID = l1ChainID
 	}
 
-	if !cfg.EnableHsm && cfg.privateKey == nil {
-		return nil, errNoPrivateKey
+	// the signer is optional in dry-run mode
+	var txSigner signer.Signer
+	if cfg.signer.Enabled() {
+		txSigner, err = signer.NewSigner(context.Background(), cfg.signer)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create signer: %w", err)
+		}
+	} else if !cfg.dryRun {
+		return nil, errNoSigner
 	}
 
 	tip, err := l2Client.HeaderByNumber(context.Background(), nil)
@@ -414,11 +390,9 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 	// to get the latest block number
 	getLatestBlockNumberFn := wrapGetLatestBlockNumberFn(l2Client)
 	// updateL2GasPriceFn is used by the GasPriceUpdater to
-	// update the gas price
-	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(l2Client, cfg)
-	if err != nil {
-		return nil, err
-	}
+	// queue the gas price update, the scheduler sends it
+	l2GasPriceQueue := new(pendingUpdates)
+	updateL2GasPriceFn := wrapUpdateL2GasPriceFn(l2GasPriceQueue)
 	// getGasUsedByBlockFn is used by the GasPriceUpdater
 	// to fetch the amount of gas that a block has used
 	getGasUsedByBlockFn := wrapGetGasUsedByBlock(l2Client)
@@ -441,22 +415,74 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 		return nil, err
 	}
 
+	// sender is the only user of the owner key, nothing is sent in dry-run
+	// mode and the state is kept in memory not to interfere with a running
+	// gas oracle
+	var sender updateSender
+	stateDBPath := cfg.stateDBPath
+	if cfg.dryRun {
+		log.Warn("Dry-run mode, no transaction will be sent")
+		sender = dryRunSender{}
+		stateDBPath = ""
+	} else {
+		sender, err = newTxSender(l2Client, txSigner, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	audit, err := NewAuditLog(cfg.auditLogPath, cfg.dryRun)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Opening state store", "path", stateDBPath)
+	store, err := NewStateStore(stateDBPath)
+	if err != nil {
+		audit.Close()
+		return nil, err
+	}
+	overrides, err := newOverrides(cfg.adminJournalPath, cfg.adminMaxOverrideDuration)
+	if err != nil {
+		audit.Close()
+		store.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	gpo := GasPriceOracle{
 		l2ChainID:       l2ChainID,
 		l1ChainID:       l1ChainID,
-		ctx:             context.Background(),
+		ctx:             ctx,
+		cancel:          cancel,
 		stop:            make(chan struct{}),
 		contract:        contract,
+		gasPricer:       gasPricer,
 		gasPriceUpdater: gasPriceUpdater,
+		tokenPricer:     tokenPricer,
+		l2GasPriceQueue: l2GasPriceQueue,
+		signer:          txSigner,
+		sender:          sender,
+		store:           store,
+		audit:           audit,
+		overrides:       overrides,
 		config:          cfg,
 		l2Backend:       l2Client,
 		l1Backend:       l1Client,
+		l1BaseFees:      l1Client,
+		l1Receipts:      l1Client.Client,
 		daBackend:       daFeeClient,
 		sccBackend:      sccBackend,
 		ctcBackend:      ctcBackend,
 	}
 
+	if cfg.dryRun {
+		return &gpo, nil
+	}
 	if err := gpo.ensure(); err != nil {
+		cancel()
+		store.Close()
+		audit.Close()
+		overrides.Close()
 		return nil, err
 	}
 
@@ -481,44 +507,3 @@ func ensureConnection(client *ethclient.Client) error {
 	}
 	return nil
 }
-
-func readGasOracleSyncHeight() (ethdb.Database, *big.Int) {
-	// read synced height
-	db, err := rawdb.NewLevelDBDatabase("gas-oracle-data", 0, 0, "")
-	if err != nil {
-		log.Error("NewLevelDBDatabase in error", "err", err)
-		panic(err)
-	}
-	// will close connection at once
-	//defer db.Close()
-
-	has, err := db.Has([]byte(GAS_ORACLE_SYNC_HEIGHT))
-	if err != nil {
-		log.Error("check db has GAS_ORACLE_SYNC_HEIGHT in error", "err", err)
-		panic(err)
-	}
-	if !has {
-		return db, nil
-	}
-
-	height, err := db.Get([]byte(GAS_ORACLE_SYNC_HEIGHT))
-	if err != nil {
-		log.Error("check db Get GAS_ORACLE_SYNC_HEIGHT in error", "err", err)
-		panic(err)
-	}
-	return db, big.NewInt(0).SetUint64(binary.BigEndian.Uint64(height))
-}
-
-func writeGasOracleSyncHeight(db ethdb.Database, height *big.Int) error {
-	// will close connection at once
-	//defer db.Close()
-
-	var indexBz = make([]byte, 8)
-	binary.BigEndian.PutUint64(indexBz, height.Uint64())
-	err := db.Put([]byte(GAS_ORACLE_SYNC_HEIGHT), indexBz)
-	if err != nil {
-		log.Error("put GAS_ORACLE_SYNC_HEIGHT in error", "err", err)
-		return err
-	}
-	return nil
-}
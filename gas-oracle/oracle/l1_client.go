@@ -6,7 +6,6 @@ import (
 	"math/big"
 	"sync"
 
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 
@@ -30,31 +29,50 @@ func NewL1Client(ethereumHttpUrl string, tokenPricer *tokenprice.Client) (*L1Cli
 	}, nil
 }
 
-func (c *L1Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+// BaseFeeInputs returns the L1 values the L1 base fee is computed from: the
+// base fee of the tip, the highest base fee of the last 20 blocks, the priority
+// fee and the token prices
+func (c *L1Client) BaseFeeInputs(ctx context.Context) (DecisionInputs, error) {
 	ratio, err := c.tokenPricer.PriceRatioWithMode()
 	if err != nil {
 		ratio = tokenprice.DefaultTokenRatio
 	}
-	tip, err := c.Client.HeaderByNumber(ctx, number)
+	ethPrice, mntPrice, _ := c.tokenPricer.LastPrices()
+	tip, err := c.Client.HeaderByNumber(ctx, nil)
 	if err != nil {
-		return nil, err
+		return DecisionInputs{}, err
 	}
 	if tip == nil {
-		return nil, fmt.Errorf("get tip is nil")
+		return DecisionInputs{}, fmt.Errorf("get tip is nil")
+	}
+	if tip.BaseFee == nil {
+		return DecisionInputs{}, errNoBaseFee
 	}
 	log.Info("show base fee original", "tip.BaseFee", tip.BaseFee, "number", tip.Number, "ratio", ratio)
 	// get tip
 	gasTipCap, err := c.SuggestGasTipCap(ctx)
 	if err != nil {
-		return nil, err
+		return DecisionInputs{}, err
 	}
 	// get history 20 block best base
 	bestBaseFee := c.getHistoryBestPrice(tip.Number, tip.BaseFee, 20)
-	tip.BaseFee = new(big.Int).Mul(new(big.Int).Add(bestBaseFee, gasTipCap), big.NewInt(int64(ratio)))
 	log.Info("show base fee context", "bestBaseFee", bestBaseFee, "gasTipCap", gasTipCap, "ratio", ratio)
 	ometrics.GasOracleStats.L1GasPriceGauge.Update(new(big.Int).Add(bestBaseFee, gasTipCap).Int64())
 	ometrics.GasOracleStats.TokenRatioGauge.Update(ratio)
-	return tip, nil
+	return DecisionInputs{
+		EthPrice:      ethPrice,
+		MntPrice:      mntPrice,
+		TokenRatio:    ratio,
+		L1BlockNumber: tip.Number.Uint64(),
+		L1BaseFee:     tip.BaseFee,
+		L1BestBaseFee: bestBaseFee,
+		L1GasTipCap:   gasTipCap,
+	}, nil
+}
+
+// l1BaseFee is the L1 base fee set on L2, the L1 gas price converted to MNT
+func l1BaseFee(bestBaseFee, gasTipCap *big.Int, ratio float64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).Add(bestBaseFee, gasTipCap), big.NewInt(int64(ratio)))
 }
 
 func (c *L1Client) getHistoryBestPrice(endHeight *big.Int, lastBaseFee *big.Int, countWindow int) *big.Int {
@@ -2,134 +2,166 @@ package oracle
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"math/big"
 	"sort"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 
-	bsscore "github.com/mantlenetworkio/mantle/bss-core"
 	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
 	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
-
-	kms "cloud.google.com/go/kms/apiv1"
-	"google.golang.org/api/option"
 )
 
 var jumpTable = make(map[int]*big.Int, 0)
 var orderedSizes = make([]int, 0)
 
-func wrapUpdateOverhead(l2Backend DeployContractBackend, cfg *Config) (func(*big.Int, *big.Int) error, error) {
-	if cfg.l2ChainID == nil {
-		return nil, errNoChainID
-	}
+// overheadEpochLength is how often the SCC is scanned for new state batches
+var overheadEpochLength = 5 * time.Second
 
-	var opts *bind.TransactOpts
-	var err error
-	if !cfg.EnableHsm {
-		if cfg.privateKey == nil {
-			return nil, errNoPrivateKey
-		}
-		if cfg.l2ChainID == nil {
-			return nil, errNoChainID
-		}
+// overheadUpdater decides the overhead from the state batches appended to
+// the SCC since the last synced L1 height
+type overheadUpdater struct {
+	l1Backend  bind.ContractTransactor
+	sccBackend *bindings.StateCommitmentChain
+	ctcBackend *bindings.CanonicalTransactionChain
+	contract   *bindings.BVMGasPriceOracle
+	store      StateStore
+	cfg        *Config
+	audit      *AuditLog
 
-		opts, err = bind.NewKeyedTransactorWithChainID(cfg.privateKey, cfg.l2ChainID)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		seqBytes, err := hex.DecodeString(cfg.HsmCreden)
-		apikey := option.WithCredentialsJSON(seqBytes)
-		client, err := kms.NewKeyManagementClient(context.Background(), apikey)
-		if err != nil {
-			log.Crit("gasoracle", "create signer error", err.Error())
-		}
-		mk := &bsscore.ManagedKey{
-			KeyName:      cfg.HsmAPIName,
-			EthereumAddr: common.HexToAddress(cfg.HsmAddress),
-			Gclient:      client,
-		}
-		opts, err = mk.NewEthereumTransactorrWithChainID(context.Background(), cfg.l2ChainID)
+	// height is the next L1 height to scan, 0 before the first run
+	height          uint64
+	ctcTotalBatches *big.Int
+
+	// state of the last run, saved by commit once its updates are sent
+	pendingHeight          uint64
+	pendingCtcTotalBatches *big.Int
+}
+
+func newOverheadUpdater(l1Backend bind.ContractTransactor, sccBackend *bindings.StateCommitmentChain,
+	ctcBackend *bindings.CanonicalTransactionChain, contract *bindings.BVMGasPriceOracle,
+	store StateStore, cfg *Config, audit *AuditLog) (*overheadUpdater, error) {
+	height, ok, err := store.SyncHeight()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sync height: %w", err)
+	}
+	log.Info("ReadGasOracleSyncHeight", "height", height, "found", ok)
+	u := &overheadUpdater{
+		l1Backend:  l1Backend,
+		sccBackend: sccBackend,
+		ctcBackend: ctcBackend,
+		contract:   contract,
+		store:      store,
+		cfg:        cfg,
+		audit:      audit,
+	}
+	if ok {
+		u.height = height + 1
+	}
+	return u, nil
+}
+
+func (u *overheadUpdater) run(ctx context.Context) ([]paramUpdate, error) {
+	if u.ctcTotalBatches == nil {
+		ctcTotalBatches, err := u.ctcBackend.GetTotalBatches(&bind.CallOpts{Context: ctx})
 		if err != nil {
-			log.Crit("gasoracle", "create signer error", err.Error())
-			return nil, err
+			return nil, fmt.Errorf("cannot get ctc total batches: %w", err)
 		}
+		u.ctcTotalBatches = ctcTotalBatches
+	}
+	u.pendingHeight, u.pendingCtcTotalBatches = u.height, u.ctcTotalBatches
+
+	latestHeader, err := u.l1Backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get latest header: %w", err)
+	}
+	end := latestHeader.Number.Uint64()
+	start := u.height
+	if start == 0 {
+		start = end
+	}
+	// repeat query latest block is not allowed
+	if start > end {
+		return nil, nil
+	}
+
+	iter, err := u.sccBackend.FilterStateBatchAppended(&bind.FilterOpts{
+		Start:   start,
+		End:     &end,
+		Context: ctx,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot filter state batches: %w", err)
+	}
+	defer iter.Close()
+	// only the last state batch is used, prev events are ignored
+	var last *bindings.StateCommitmentChainStateBatchAppended
+	for iter.Next() {
+		last = iter.Event
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("cannot filter state batches: %w", err)
+	}
+	u.pendingHeight = end + 1
+	if last == nil {
+		return nil, nil
 	}
 
-	// Once https://github.com/ethereum/go-ethereum/pull/23062 is released
-	// then we can remove setting the context here
-	if opts.Context == nil {
-		opts.Context = context.Background()
+	currentCtcBatches, err := u.ctcBackend.GetTotalBatches(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get ctc total batches: %w", err)
 	}
-	// Don't send the transaction using the `contract` so that we can inspect
-	// it beforehand
-	opts.NoSend = true
+	diff := new(big.Int).Sub(currentCtcBatches, u.ctcTotalBatches)
+	log.Info("current scc batch size", "size", last.BatchSize)
+	log.Info("CTC circle num in SCC circle", "count", diff)
+	u.pendingCtcTotalBatches = currentCtcBatches
 
-	// Create a new contract bindings in scope of the updateL2GasPriceFn
-	// that is returned from this function
-	contract, err := bindings.NewBVMGasPriceOracle(cfg.gasPriceOracleAddress, l2Backend)
+	calculateJumpTable(diff, u.cfg)
+	newOverheadLevel, err := getOverheadLevelInJumpTable(last.BatchSize)
 	if err != nil {
 		return nil, err
 	}
-	return func(diff *big.Int, size *big.Int) error {
-		calculateJumpTable(diff, cfg)
-		newOverheadLevel, err := getOverheadLevelInJumpTable(size)
-		if err != nil {
-			return err
-		}
-		overhead, err := contract.Overhead(&bind.CallOpts{
-			Context: context.Background(),
-		})
-		if err != nil {
-			return err
-		}
-		ometrics.GasOracleStats.OverHeadUpdateGauge.Inc(1)
-		// skip update if overhead is not changed
-		if overhead.Cmp(newOverheadLevel) == 0 {
-			log.Info("skip update overhead", "overhead", overhead)
-			return nil
-		}
-		// Use the configured gas price if it is set,
-		// otherwise use gas estimation
-		if cfg.gasPrice != nil {
-			opts.GasPrice = cfg.gasPrice
-		} else {
-			gasPrice, err := l2Backend.SuggestGasPrice(opts.Context)
-			if err != nil {
-				return err
-			}
-			opts.GasPrice = gasPrice
-		}
-		// set L1BaseFee to base fee + tip cap, to cover rollup tip cap
-		tx, err := contract.SetOverhead(opts, newOverheadLevel)
-		if err != nil {
-			return err
-		}
-		log.Info("updating L1 overhead", "tx.gasPrice", tx.GasPrice(), "tx.gasLimit", tx.Gas(),
-			"tx.data", hexutil.Encode(tx.Data()), "tx.to", tx.To().Hex(), "tx.nonce", tx.Nonce())
-		if err := l2Backend.SendTransaction(context.Background(), tx); err != nil {
-			return fmt.Errorf("cannot update base fee: %w", err)
-		}
-		log.Info("L2 overhead transaction sent", "hash", tx.Hash().Hex(), "old overhead", overhead, "new overhead", newOverheadLevel)
-		ometrics.GasOracleStats.OverHeadGauge.Update(newOverheadLevel.Int64())
-
-		if cfg.waitForReceipt {
-			// Wait for the receipt
-			receipt, err := waitForReceipt(l2Backend, tx)
-			if err != nil {
-				return err
-			}
-
-			log.Info("overhead transaction confirmed", "hash", tx.Hash().Hex(),
-				"gas-used", receipt.GasUsed, "blocknumber", receipt.BlockNumber)
-		}
+	overhead, err := u.contract.Overhead(&bind.CallOpts{
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ometrics.GasOracleStats.OverHeadUpdateGauge.Inc(1)
+	changed := overhead.Cmp(newOverheadLevel) != 0
+	u.audit.Record(Decision{
+		Param:       paramOverhead,
+		Value:       newOverheadLevel,
+		Current:     overhead,
+		Significant: changed,
+		Inputs: DecisionInputs{
+			L1BlockNumber: end,
+			CtcBatches:    diff,
+			BatchSize:     last.BatchSize,
+		},
+	})
+	// skip update if overhead is not changed
+	if !changed {
+		log.Info("skip update overhead", "overhead", overhead)
+		return nil, nil
+	}
+	log.Info("updating L1 overhead", "old overhead", overhead, "new overhead", newOverheadLevel)
+	return []paramUpdate{{param: paramOverhead, value: newOverheadLevel}}, nil
+}
+
+// commit saves the state of the last run once its updates are sent
+func (u *overheadUpdater) commit() error {
+	if u.pendingHeight == u.height {
 		return nil
-	}, nil
+	}
+	if err := u.store.SetSyncHeight(u.pendingHeight - 1); err != nil {
+		return fmt.Errorf("cannot write sync height: %w", err)
+	}
+	u.height, u.ctcTotalBatches = u.pendingHeight, u.pendingCtcTotalBatches
+	log.Info("Update synced height", "height", u.height)
+	return nil
 }
 
 func calculateJumpTable(diff *big.Int, cfg *Config) {
@@ -138,6 +170,7 @@ func calculateJumpTable(diff *big.Int, cfg *Config) {
 	// sum up
 	var OverheadGasUsedOnL1 = new(big.Int).Add(cfg.stateRollupGasUsed, sequencerOverhead)
 	// calculate jump table
+	orderedSizes = orderedSizes[:0]
 	for levelSize := cfg.batchSizeBottom; levelSize <= cfg.batchSizeCap; {
 		orderedSizes = append(orderedSizes, levelSize)
 		jumpTable[levelSize] = new(big.Int).Add(new(big.Int).Div(OverheadGasUsedOnL1, new(big.Int).SetUint64(uint64(levelSize))), cfg.stateHashGasUsed)
@@ -0,0 +1,303 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
+	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+var (
+	// overheadModeJumpTable derives the overhead from the configured gas usage
+	// of the batch submissions
+	overheadModeJumpTable = "jump-table"
+	// overheadModeReceipts derives the overhead from the receipts of the batch
+	// submissions
+	overheadModeReceipts = "receipts"
+
+	// rollupStoreConfirmedTopic is the topic of the event emitted by the DA
+	// rollup contract when a batch is confirmed
+	rollupStoreConfirmedTopic = crypto.Keccak256Hash(
+		[]byte("RollupStoreConfirmed(uint256,uint32,uint256,uint256)"))
+)
+
+// receiptBackend is the part of the L1 client used to fetch the batch
+// submissions
+type receiptBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// overheadCaller reads the overhead of the BVM_GasPriceOracle
+type overheadCaller interface {
+	Overhead(opts *bind.CallOpts) (*big.Int, error)
+}
+
+// batchSubmission is the L1 cost of a batch submission transaction
+type batchSubmission struct {
+	block   uint64
+	gasUsed uint64
+	// cost is the gas used times the effective gas price, in wei
+	cost *big.Int
+	// l2Txs is the number of L2 transactions of the state batches appended
+	// by the transaction
+	l2Txs uint64
+}
+
+// receiptOverheadUpdater sets the overhead to the L1 gas spent per L2
+// transaction by the CTC, SCC and DA submissions of a sliding window of L1
+// blocks, plus a target margin
+type receiptOverheadUpdater struct {
+	backend    receiptBackend
+	sccBackend *bindings.StateCommitmentChain
+	contract   overheadCaller
+	cfg        *Config
+	audit      *AuditLog
+	// addresses are the contracts the batches are submitted to, and topics
+	// the events of the batch submissions, the other transactions sent to
+	// these contracts are not counted
+	addresses               []common.Address
+	topics                  []common.Hash
+	stateBatchAppendedTopic common.Hash
+
+	// height is the next L1 height to scan, 0 before the first run
+	height uint64
+	window []batchSubmission
+
+	// state of the last run, saved by commit once its updates are sent
+	pendingHeight uint64
+	pendingWindow []batchSubmission
+}
+
+func newReceiptOverheadUpdater(backend receiptBackend, sccBackend *bindings.StateCommitmentChain,
+	contract overheadCaller, cfg *Config, audit *AuditLog) (*receiptOverheadUpdater, error) {
+	if cfg.l1OverheadWindowBlocks == 0 {
+		return nil, fmt.Errorf("l1 overhead window cannot be 0")
+	}
+	sccABI, err := bindings.StateCommitmentChainMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	ctcABI, err := bindings.CanonicalTransactionChainMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	addresses := []common.Address{cfg.ctcContractAddress, cfg.sccContractAddress}
+	topics := []common.Hash{
+		ctcABI.Events["SequencerBatchAppended"].ID,
+		sccABI.Events["StateBatchAppended"].ID,
+	}
+	if cfg.daRollupContractAddress != (common.Address{}) {
+		addresses = append(addresses, cfg.daRollupContractAddress)
+		topics = append(topics, rollupStoreConfirmedTopic)
+	}
+	return &receiptOverheadUpdater{
+		backend:                 backend,
+		sccBackend:              sccBackend,
+		contract:                contract,
+		cfg:                     cfg,
+		audit:                   audit,
+		addresses:               addresses,
+		topics:                  topics,
+		stateBatchAppendedTopic: sccABI.Events["StateBatchAppended"].ID,
+	}, nil
+}
+
+func (u *receiptOverheadUpdater) run(ctx context.Context) ([]paramUpdate, error) {
+	u.pendingHeight, u.pendingWindow = u.height, u.window
+
+	latestHeader, err := u.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get latest header: %w", err)
+	}
+	end := latestHeader.Number.Uint64()
+	var windowStart uint64
+	if end >= u.cfg.l1OverheadWindowBlocks {
+		windowStart = end - u.cfg.l1OverheadWindowBlocks + 1
+	}
+	// the window is filled again after a restart
+	start := u.height
+	if start < windowStart {
+		start = windowStart
+	}
+	if start > end {
+		return nil, nil
+	}
+
+	submissions, err := u.fetchSubmissions(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	window := make([]batchSubmission, 0, len(u.window)+len(submissions))
+	for _, s := range u.window {
+		if s.block >= windowStart {
+			window = append(window, s)
+		}
+	}
+	window = append(window, submissions...)
+	u.pendingHeight, u.pendingWindow = end+1, window
+
+	var (
+		gasUsed uint64
+		l2Txs   uint64
+		cost    = new(big.Int)
+	)
+	for _, s := range window {
+		gasUsed += s.gasUsed
+		l2Txs += s.l2Txs
+		cost.Add(cost, s.cost)
+	}
+	log.Info("l1 batch submission costs", "from", windowStart, "to", end, "submissions", len(window),
+		"gas-used", gasUsed, "cost", cost, "l2-txs", l2Txs)
+	// nothing to amortize the submissions over
+	if l2Txs == 0 {
+		return nil, nil
+	}
+
+	newOverhead := receiptOverhead(gasUsed, l2Txs, u.cfg.l1OverheadTargetMargin)
+	overhead, err := u.contract.Overhead(&bind.CallOpts{
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordOverheadCost(gasUsed, l2Txs, cost, overhead)
+
+	significant := isDifferenceSignificant(overhead.Uint64(), newOverhead.Uint64(), u.cfg.l1OverheadSignificanceFactor)
+	u.audit.Record(Decision{
+		Param:       paramOverhead,
+		Value:       newOverhead,
+		Current:     overhead,
+		Significant: significant,
+		Inputs: DecisionInputs{
+			L1BlockNumber:  end,
+			L1GasUsed:      gasUsed,
+			L1Cost:         cost,
+			L2Transactions: l2Txs,
+		},
+	})
+	if !significant {
+		log.Info("skip update overhead", "overhead", overhead, "computed", newOverhead)
+		return nil, nil
+	}
+	log.Info("updating L1 overhead", "old overhead", overhead, "new overhead", newOverhead)
+	return []paramUpdate{{param: paramOverhead, value: newOverhead}}, nil
+}
+
+// fetchSubmissions returns the batch submissions between the L1 heights start
+// and end included
+func (u *receiptOverheadUpdater) fetchSubmissions(ctx context.Context, start, end uint64) ([]batchSubmission, error) {
+	logs, err := u.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+		Addresses: u.addresses,
+		Topics:    [][]common.Hash{u.topics},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot filter batch submissions: %w", err)
+	}
+
+	// a transaction can emit several logs, keep the order of the first one
+	var hashes []common.Hash
+	l2Txs := make(map[common.Hash]uint64)
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		if _, ok := l2Txs[l.TxHash]; !ok {
+			hashes = append(hashes, l.TxHash)
+			l2Txs[l.TxHash] = 0
+		}
+		if l.Address != u.cfg.sccContractAddress || len(l.Topics) == 0 || l.Topics[0] != u.stateBatchAppendedTopic {
+			continue
+		}
+		event, err := u.sccBackend.ParseStateBatchAppended(l)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse state batch: %w", err)
+		}
+		l2Txs[l.TxHash] += event.BatchSize.Uint64()
+	}
+
+	submissions := make([]batchSubmission, 0, len(hashes))
+	baseFees := make(map[uint64]*big.Int)
+	for _, hash := range hashes {
+		receipt, err := u.backend.TransactionReceipt(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get receipt %s: %w", hash, err)
+		}
+		tx, _, err := u.backend.TransactionByHash(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get transaction %s: %w", hash, err)
+		}
+		block := receipt.BlockNumber.Uint64()
+		baseFee, ok := baseFees[block]
+		if !ok {
+			header, err := u.backend.HeaderByNumber(ctx, receipt.BlockNumber)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get header %d: %w", block, err)
+			}
+			baseFee = header.BaseFee
+			baseFees[block] = baseFee
+		}
+		gasPrice := effectiveGasPrice(tx, baseFee)
+		submissions = append(submissions, batchSubmission{
+			block:   block,
+			gasUsed: receipt.GasUsed,
+			cost:    new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), gasPrice),
+			l2Txs:   l2Txs[hash],
+		})
+	}
+	return submissions, nil
+}
+
+// effectiveGasPrice is the gas price paid by tx in a block of base fee baseFee
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return tx.GasFeeCap()
+	}
+	return price
+}
+
+// commit saves the state of the last run once its updates are sent
+func (u *receiptOverheadUpdater) commit() error {
+	u.height, u.window = u.pendingHeight, u.pendingWindow
+	return nil
+}
+
+// receiptOverhead is the L1 gas spent per L2 transaction increased by margin,
+// rounded to the nearest unit
+func receiptOverhead(gasUsed, l2Txs uint64, margin float64) *big.Int {
+	overhead := math.Round(float64(gasUsed) / float64(l2Txs) * (1 + margin))
+	return new(big.Int).SetUint64(uint64(overhead))
+}
+
+// recordOverheadCost reports the L1 cost of the window and the cost charged
+// for its L2 transactions by the current overhead, at the average gas price
+// of the submissions
+func recordOverheadCost(gasUsed, l2Txs uint64, cost, overhead *big.Int) {
+	gwei := big.NewInt(1e9)
+	charged := new(big.Int).Mul(overhead, new(big.Int).SetUint64(l2Txs))
+	if gasUsed > 0 {
+		charged.Mul(charged, cost)
+		charged.Div(charged, new(big.Int).SetUint64(gasUsed))
+	}
+	ometrics.GasOracleStats.OverHeadL1GasPerTxGauge.Update(int64(gasUsed / l2Txs))
+	ometrics.GasOracleStats.OverHeadComputedCostGauge.Update(new(big.Int).Div(cost, gwei).Int64())
+	ometrics.GasOracleStats.OverHeadChargedCostGauge.Update(new(big.Int).Div(charged, gwei).Int64())
+}
@@ -0,0 +1,216 @@
+package oracle
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
+	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+type fakeReceiptBackend struct {
+	latest   uint64
+	baseFee  *big.Int
+	logs     []types.Log
+	receipts map[common.Hash]*types.Receipt
+	txs      map[common.Hash]*types.Transaction
+}
+
+func (b *fakeReceiptBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = new(big.Int).SetUint64(b.latest)
+	}
+	return &types.Header{Number: number, BaseFee: b.baseFee}, nil
+}
+
+// FilterLogs matches the block range, addresses and first topic of q like a
+// node does
+func (b *fakeReceiptBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, l := range b.logs {
+		if l.BlockNumber < q.FromBlock.Uint64() || l.BlockNumber > q.ToBlock.Uint64() {
+			continue
+		}
+		if len(q.Addresses) > 0 && !containsAddress(q.Addresses, l.Address) {
+			continue
+		}
+		if len(q.Topics) > 0 && len(q.Topics[0]) > 0 &&
+			(len(l.Topics) == 0 || !containsHash(q.Topics[0], l.Topics[0])) {
+			continue
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *fakeReceiptBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return b.receipts[txHash], nil
+}
+
+func (b *fakeReceiptBackend) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	return b.txs[hash], false, nil
+}
+
+// submit adds a batch submission transaction emitting log
+func (b *fakeReceiptBackend) submit(log types.Log, gasUsed uint64, gasPrice *big.Int) {
+	tx := types.NewTx(&types.LegacyTx{Nonce: uint64(len(b.txs)), GasPrice: gasPrice})
+	log.TxHash = tx.Hash()
+	b.logs = append(b.logs, log)
+	b.txs[tx.Hash()] = tx
+	b.receipts[tx.Hash()] = &types.Receipt{
+		GasUsed:     gasUsed,
+		BlockNumber: new(big.Int).SetUint64(log.BlockNumber),
+	}
+}
+
+type fakeOverheadCaller struct {
+	overhead *big.Int
+}
+
+func (c *fakeOverheadCaller) Overhead(opts *bind.CallOpts) (*big.Int, error) {
+	return c.overhead, nil
+}
+
+func stateBatchAppendedLog(t *testing.T, scc common.Address, block uint64, batchSize int64) types.Log {
+	sccABI, err := bindings.StateCommitmentChainMetaData.GetAbi()
+	require.NoError(t, err)
+	event := sccABI.Events["StateBatchAppended"]
+	data, err := event.Inputs.NonIndexed().Pack([32]byte{}, big.NewInt(batchSize), big.NewInt(0), []byte{}, []byte{})
+	require.NoError(t, err)
+	return types.Log{
+		Address:     scc,
+		Topics:      []common.Hash{event.ID, common.BigToHash(big.NewInt(1))},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+// ctcLog returns a log of the CTC event name, whose fields are left empty
+func ctcLog(t *testing.T, ctc common.Address, block uint64, name string) types.Log {
+	ctcABI, err := bindings.CanonicalTransactionChainMetaData.GetAbi()
+	require.NoError(t, err)
+	return types.Log{
+		Address:     ctc,
+		Topics:      []common.Hash{ctcABI.Events[name].ID},
+		BlockNumber: block,
+	}
+}
+
+func sequencerBatchAppendedLog(t *testing.T, ctc common.Address, block uint64) types.Log {
+	return ctcLog(t, ctc, block, "SequencerBatchAppended")
+}
+
+func stateBatchDeletedLog(t *testing.T, scc common.Address, block uint64) types.Log {
+	sccABI, err := bindings.StateCommitmentChainMetaData.GetAbi()
+	require.NoError(t, err)
+	return types.Log{
+		Address:     scc,
+		Topics:      []common.Hash{sccABI.Events["StateBatchDeleted"].ID, common.BigToHash(big.NewInt(1))},
+		BlockNumber: block,
+	}
+}
+
+func TestReceiptOverheadUpdater(t *testing.T) {
+	ometrics.InitAndRegisterStats(metrics.NewRegistry())
+
+	cfg := &Config{
+		ctcContractAddress:           common.HexToAddress("0x01"),
+		sccContractAddress:           common.HexToAddress("0x02"),
+		l1OverheadWindowBlocks:       100,
+		l1OverheadTargetMargin:       0.1,
+		l1OverheadSignificanceFactor: 0.05,
+	}
+	backend := &fakeReceiptBackend{
+		latest:   1000,
+		receipts: make(map[common.Hash]*types.Receipt),
+		txs:      make(map[common.Hash]*types.Transaction),
+	}
+	gasPrice := big.NewInt(10e9)
+	// outside of the window
+	backend.submit(sequencerBatchAppendedLog(t, cfg.ctcContractAddress, 800), 1_000_000, gasPrice)
+	backend.submit(sequencerBatchAppendedLog(t, cfg.ctcContractAddress, 950), 500_000, gasPrice)
+	backend.submit(stateBatchAppendedLog(t, cfg.sccContractAddress, 960, 100), 100_000, gasPrice)
+	// enqueues and state batch deletions are sent to the same contracts, but
+	// are not batch submissions
+	backend.submit(ctcLog(t, cfg.ctcContractAddress, 970, "TransactionEnqueued"), 300_000, gasPrice)
+	backend.submit(stateBatchDeletedLog(t, cfg.sccContractAddress, 980), 50_000, gasPrice)
+
+	scc, err := bindings.NewStateCommitmentChain(cfg.sccContractAddress, nil)
+	require.NoError(t, err)
+	contract := &fakeOverheadCaller{overhead: big.NewInt(2000)}
+	u, err := newReceiptOverheadUpdater(backend, scc, contract, cfg, nil)
+	require.NoError(t, err)
+
+	// (500000 + 100000) / 100 * 1.1
+	updates, err := u.run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []paramUpdate{{param: paramOverhead, value: big.NewInt(6600)}}, updates)
+	require.Equal(t, int64(6000), ometrics.GasOracleStats.OverHeadL1GasPerTxGauge.Value())
+	// 600000 gas at 10 gwei
+	require.Equal(t, int64(6_000_000), ometrics.GasOracleStats.OverHeadComputedCostGauge.Value())
+	// 2000 gas for 100 transactions at 10 gwei
+	require.Equal(t, int64(2_000_000), ometrics.GasOracleStats.OverHeadChargedCostGauge.Value())
+	require.NoError(t, u.commit())
+	contract.overhead = big.NewInt(6600)
+
+	// only the new blocks are scanned, the batch of block 950 leaves the window
+	backend.latest = 1055
+	backend.submit(sequencerBatchAppendedLog(t, cfg.ctcContractAddress, 1052), 200_000, gasPrice)
+	backend.submit(stateBatchAppendedLog(t, cfg.sccContractAddress, 1054, 200), 100_000, gasPrice)
+	updates, err = u.run(context.Background())
+	require.NoError(t, err)
+	// (100000 + 200000 + 100000) / 300 * 1.1
+	require.Equal(t, []paramUpdate{{param: paramOverhead, value: big.NewInt(1467)}}, updates)
+	require.NoError(t, u.commit())
+	contract.overhead = big.NewInt(1467)
+
+	// not significant
+	backend.latest = 1059
+	backend.submit(sequencerBatchAppendedLog(t, cfg.ctcContractAddress, 1058), 10_000, gasPrice)
+	updates, err = u.run(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, updates)
+	require.NoError(t, u.commit())
+
+	// no state batch in the window
+	backend.latest = 1200
+	updates, err = u.run(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, updates)
+}
+
+func TestEffectiveGasPrice(t *testing.T) {
+	legacy := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(30)})
+	require.Equal(t, big.NewInt(30), effectiveGasPrice(legacy, nil))
+	require.Equal(t, big.NewInt(30), effectiveGasPrice(legacy, big.NewInt(10)))
+
+	dynamic := types.NewTx(&types.DynamicFeeTx{GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(20)})
+	require.Equal(t, big.NewInt(12), effectiveGasPrice(dynamic, big.NewInt(10)))
+	require.Equal(t, big.NewInt(20), effectiveGasPrice(dynamic, big.NewInt(19)))
+}
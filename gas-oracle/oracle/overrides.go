@@ -0,0 +1,346 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// overrideJournalSize is the number of journal entries kept in memory
+	overrideJournalSize = 1000
+
+	// actions of the override journal
+	actionPause  = "pause"
+	actionResume = "resume"
+	actionPin    = "pin"
+	actionUnpin  = "unpin"
+	// the updater l1BaseFee and the parameter l1BaseFee share their name
+	actionPauseExpired = "pause-expired"
+	actionPinExpired   = "pin-expired"
+
+	// paramUpdaters are the updaters deciding each parameter
+	paramUpdaters = map[string]string{
+		paramGasPrice:   "l2GasPrice",
+		paramL1BaseFee:  "l1BaseFee",
+		paramDaGasPrice: "daFee",
+		paramOverhead:   "overhead",
+	}
+
+	errNotOverridden = errors.New("not overridden")
+)
+
+// Override is a pause of an updater or a value pinned for a parameter
+type Override struct {
+	// Value is the pinned value, nil for a pause
+	Value  *big.Int  `json:"value,omitempty"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// OverrideEntry is a change of the overrides, written to the override journal
+type OverrideEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	// Target is the updater of a pause or resume and the parameter otherwise
+	Target string     `json:"target"`
+	Value  *big.Int   `json:"value,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+	// Remote is the address of the operator
+	Remote string `json:"remote,omitempty"`
+}
+
+// pin is a pinned value and whether it was sent
+type pin struct {
+	Override
+	sent bool
+}
+
+// overrides are the pauses and pinned values set by the operators. Every
+// change is journaled and every override expires after at most maxDuration.
+// A nil *overrides has no override.
+type overrides struct {
+	mtx         sync.Mutex
+	maxDuration time.Duration
+	pauses      map[string]Override
+	pins        map[string]*pin
+	entries     []OverrideEntry
+	journal     *os.File
+}
+
+// newOverrides restores the overrides of the journal at path, that have not
+// expired, and appends the next changes to it. The journal is only kept in
+// memory if path is empty.
+func newOverrides(path string, maxDuration time.Duration) (*overrides, error) {
+	if maxDuration <= 0 {
+		return nil, fmt.Errorf("max override duration must be positive, got %s", maxDuration)
+	}
+	o := &overrides{
+		maxDuration: maxDuration,
+		pauses:      make(map[string]Override),
+		pins:        make(map[string]*pin),
+	}
+	if path == "" {
+		return o, nil
+	}
+
+	entries, err := readOverrideJournal(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read override journal %s: %w", path, err)
+	}
+	// the restored pins are sent again
+	for _, entry := range entries {
+		o.apply(entry)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open override journal %s: %w", path, err)
+	}
+	o.journal = file
+	o.expire(time.Now())
+	log.Info("restored overrides", "pauses", len(o.pauses), "pins", len(o.pins))
+	return o, nil
+}
+
+// apply updates the overrides with a journal entry, the caller must hold mtx
+func (o *overrides) apply(entry OverrideEntry) {
+	switch entry.Action {
+	case actionPause:
+		o.pauses[entry.Target] = Override{Until: *entry.Until, Reason: entry.Reason}
+	case actionPin:
+		o.pins[entry.Target] = &pin{Override: Override{Value: entry.Value, Until: *entry.Until, Reason: entry.Reason}}
+	case actionResume, actionPauseExpired:
+		delete(o.pauses, entry.Target)
+	case actionUnpin, actionPinExpired:
+		delete(o.pins, entry.Target)
+	}
+	o.entries = append(o.entries, entry)
+	if len(o.entries) > overrideJournalSize {
+		o.entries = o.entries[len(o.entries)-overrideJournalSize:]
+	}
+}
+
+// record applies and journals an entry, the caller must hold mtx
+func (o *overrides) record(entry OverrideEntry) {
+	o.apply(entry)
+	log.Info("gas oracle override", "action", entry.Action, "target", entry.Target, "value", entry.Value,
+		"until", entry.Until, "reason", entry.Reason, "remote", entry.Remote)
+	if o.journal == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Error("cannot encode override", "err", err)
+		return
+	}
+	if _, err := o.journal.Write(append(line, '\n')); err != nil {
+		log.Error("cannot write override journal", "err", err)
+	}
+}
+
+func (o *overrides) until(now time.Time, duration time.Duration) (*time.Time, error) {
+	if duration <= 0 || duration > o.maxDuration {
+		return nil, fmt.Errorf("duration must be between 0 and %s, got %s", o.maxDuration, duration)
+	}
+	until := now.Add(duration)
+	return &until, nil
+}
+
+// Pause stops the runs of updater for duration
+func (o *overrides) Pause(updater string, duration time.Duration, reason, remote string, now time.Time) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	until, err := o.until(now, duration)
+	if err != nil {
+		return err
+	}
+	o.record(OverrideEntry{Time: now, Action: actionPause, Target: updater, Until: until, Reason: reason, Remote: remote})
+	return nil
+}
+
+// Resume resumes a paused updater
+func (o *overrides) Resume(updater, remote string, now time.Time) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if _, ok := o.pauses[updater]; !ok {
+		return fmt.Errorf("updater %s: %w", updater, errNotOverridden)
+	}
+	o.record(OverrideEntry{Time: now, Action: actionResume, Target: updater, Remote: remote})
+	return nil
+}
+
+// Pin sets param to value for duration, the computed values of param are not
+// sent until then
+func (o *overrides) Pin(param string, value *big.Int, duration time.Duration, reason, remote string, now time.Time) error {
+	if value == nil || value.Sign() < 0 {
+		return fmt.Errorf("invalid value %v", value)
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	until, err := o.until(now, duration)
+	if err != nil {
+		return err
+	}
+	o.record(OverrideEntry{Time: now, Action: actionPin, Target: param, Value: value, Until: until, Reason: reason, Remote: remote})
+	return nil
+}
+
+// Unpin lets the computed values of param be sent again
+func (o *overrides) Unpin(param, remote string, now time.Time) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if _, ok := o.pins[param]; !ok {
+		return fmt.Errorf("param %s: %w", param, errNotOverridden)
+	}
+	o.record(OverrideEntry{Time: now, Action: actionUnpin, Target: param, Remote: remote})
+	return nil
+}
+
+// expire removes the overrides ended at now
+func (o *overrides) expire(now time.Time) {
+	if o == nil {
+		return
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	var pauses, pins []string
+	for updater, pause := range o.pauses {
+		if !now.Before(pause.Until) {
+			pauses = append(pauses, updater)
+		}
+	}
+	for param, pin := range o.pins {
+		if !now.Before(pin.Until) {
+			pins = append(pins, param)
+		}
+	}
+	sort.Strings(pauses)
+	sort.Strings(pins)
+	for _, updater := range pauses {
+		o.record(OverrideEntry{Time: now, Action: actionPauseExpired, Target: updater})
+	}
+	for _, param := range pins {
+		o.record(OverrideEntry{Time: now, Action: actionPinExpired, Target: param})
+	}
+}
+
+// paused returns whether updater is paused
+func (o *overrides) paused(updater string) bool {
+	if o == nil {
+		return false
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	_, ok := o.pauses[updater]
+	return ok
+}
+
+// pinned returns whether param is pinned
+func (o *overrides) pinned(param string) bool {
+	if o == nil {
+		return false
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	_, ok := o.pins[param]
+	return ok
+}
+
+// pendingPins returns the updates of the pinned values not sent yet
+func (o *overrides) pendingPins() []paramUpdate {
+	if o == nil {
+		return nil
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	var updates []paramUpdate
+	for param, pin := range o.pins {
+		if !pin.sent {
+			updates = append(updates, paramUpdate{param: param, value: pin.Value})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].param < updates[j].param
+	})
+	return updates
+}
+
+// pinsSent marks the pinned values of updates as sent
+func (o *overrides) pinsSent(updates []paramUpdate) {
+	if o == nil {
+		return
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	for _, update := range updates {
+		if pin, ok := o.pins[update.param]; ok && pin.Value.Cmp(update.value) == 0 {
+			pin.sent = true
+		}
+	}
+}
+
+// Pauses returns the paused updaters
+func (o *overrides) Pauses() map[string]Override {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	pauses := make(map[string]Override, len(o.pauses))
+	for updater, pause := range o.pauses {
+		pauses[updater] = pause
+	}
+	return pauses
+}
+
+// Pins returns the pinned parameters
+func (o *overrides) Pins() map[string]Override {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	pins := make(map[string]Override, len(o.pins))
+	for param, pin := range o.pins {
+		pins[param] = pin.Override
+	}
+	return pins
+}
+
+// Entries returns the last entries of the journal
+func (o *overrides) Entries() []OverrideEntry {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return append([]OverrideEntry(nil), o.entries...)
+}
+
+func (o *overrides) Close() error {
+	if o == nil || o.journal == nil {
+		return nil
+	}
+	return o.journal.Close()
+}
+
+// readOverrideJournal reads the entries of an override journal
+func readOverrideJournal(path string) ([]OverrideEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []OverrideEntry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var entry OverrideEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, err
+		}
+		if (entry.Action == actionPause || entry.Action == actionPin) && entry.Until == nil {
+			return nil, fmt.Errorf("%s of %s without end", entry.Action, entry.Target)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,66 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.jsonl")
+	overrides, err := newOverrides(path, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.Error(t, overrides.Pause("overhead", 2*time.Hour, "too long", "", now))
+	require.Error(t, overrides.Pin(paramGasPrice, big.NewInt(-1), time.Minute, "negative", "", now))
+	require.True(t, errors.Is(overrides.Resume("overhead", "", now), errNotOverridden))
+
+	require.NoError(t, overrides.Pause("overhead", time.Minute, "batch submitter down", "10.0.0.1", now))
+	require.NoError(t, overrides.Pause("l1BaseFee", time.Hour, "l1 congestion", "10.0.0.1", now))
+	require.NoError(t, overrides.Pin(paramGasPrice, big.NewInt(1000), time.Hour, "token price incident", "10.0.0.1", now))
+	require.NoError(t, overrides.Pin(paramL1BaseFee, big.NewInt(2000), time.Minute, "token price incident", "10.0.0.1", now))
+	require.True(t, overrides.paused("overhead"))
+	require.True(t, overrides.pinned(paramGasPrice))
+
+	// the pause and pin of l1BaseFee are distinct
+	overrides.expire(now.Add(time.Minute))
+	require.False(t, overrides.paused("overhead"))
+	require.True(t, overrides.paused("l1BaseFee"))
+	require.False(t, overrides.pinned(paramL1BaseFee))
+	require.True(t, overrides.pinned(paramGasPrice))
+	require.NoError(t, overrides.Unpin(paramGasPrice, "10.0.0.2", now.Add(2*time.Minute)))
+	require.NoError(t, overrides.Pin(paramOverhead, big.NewInt(3000), time.Hour, "restart", "10.0.0.2", time.Now()))
+	overrides.pinsSent([]paramUpdate{{paramOverhead, big.NewInt(3000)}})
+	require.Empty(t, overrides.pendingPins())
+
+	require.Equal(t, []string{
+		"pause overhead", "pause l1BaseFee", "pin gasPrice", "pin l1BaseFee",
+		"pause-expired overhead", "pin-expired l1BaseFee", "unpin gasPrice", "pin overhead",
+	}, journalActions(overrides.Entries()))
+	require.Equal(t, "10.0.0.1", overrides.Entries()[0].Remote)
+	require.NoError(t, overrides.Close())
+
+	// the overrides that have not expired are restored and the pins sent again
+	restored, err := newOverrides(path, time.Hour)
+	require.NoError(t, err)
+	defer restored.Close()
+	require.Equal(t, journalActions(overrides.Entries()), journalActions(restored.Entries()))
+	pauses := restored.Pauses()
+	require.Len(t, pauses, 1)
+	require.True(t, pauses["l1BaseFee"].Until.Equal(now.Add(time.Hour)))
+	require.Equal(t, "l1 congestion", pauses["l1BaseFee"].Reason)
+	require.Equal(t, []paramUpdate{{paramOverhead, big.NewInt(3000)}}, restored.pendingPins())
+}
+
+func journalActions(entries []OverrideEntry) []string {
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action+" "+entry.Target)
+	}
+	return actions
+}
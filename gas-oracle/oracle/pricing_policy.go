@@ -0,0 +1,41 @@
+package oracle
+
+import (
+	"fmt"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
+	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
+)
+
+// newGasPricer creates the GasPricer of the configured L2 gas price policy
+func newGasPricer(cfg *Config, currentPrice uint64, tokenPricer *tokenprice.Client) (*gasprices.GasPricer, error) {
+	getTargetGasPerSecond := func() float64 {
+		return float64(cfg.targetGasPerSecond)
+	}
+
+	var policy gasprices.PricingPolicy
+	switch cfg.l2GasPricePolicy {
+	case "", gasprices.LinearPolicyName:
+		policy = &gasprices.LinearPolicy{GetTargetGasPerSecond: getTargetGasPerSecond}
+	case gasprices.EIP1559PolicyName:
+		eip1559, err := gasprices.NewEIP1559Policy(cfg.eip1559TargetFullness, cfg.eip1559ChangeDenominator)
+		if err != nil {
+			return nil, err
+		}
+		policy = eip1559
+	case gasprices.PIDPolicyName:
+		pid, err := gasprices.NewPIDPolicy(getTargetGasPerSecond, cfg.pidKp, cfg.pidKi, cfg.pidKd, cfg.pidIntegralLimit)
+		if err != nil {
+			return nil, err
+		}
+		policy = pid
+	default:
+		return nil, fmt.Errorf("unknown l2 gas price policy %s", cfg.l2GasPricePolicy)
+	}
+
+	return gasprices.NewGasPricerWithPolicy(currentPrice, tokenPricer, policy, gasprices.PriceLimits{
+		FloorPrice:               cfg.floorPrice,
+		CeilingPrice:             cfg.ceilingPrice,
+		MaxPercentChangePerEpoch: cfg.maxPercentChangePerEpoch,
+	})
+}
@@ -0,0 +1,47 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
+)
+
+func TestNewGasPricer(t *testing.T) {
+	cfg := &Config{
+		floorPrice:               1000,
+		ceilingPrice:             1200,
+		maxPercentChangePerEpoch: 0.5,
+		targetGasPerSecond:       1_000_000,
+		l2GasPricePolicy:         gasprices.EIP1559PolicyName,
+		eip1559TargetFullness:    0.5,
+		eip1559ChangeDenominator: 8,
+	}
+	full := gasprices.EpochStats{GasUsed: 10_000_000, Blocks: 1, GasLimit: 10_000_000}
+
+	gasPricer, err := newGasPricer(cfg, 1000, nil)
+	require.NoError(t, err)
+	// full blocks raise the price by 1/8 per epoch up to the ceiling
+	price, err := gasPricer.CompleteEpochWithStats(full)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1125), price)
+	price, err = gasPricer.CompleteEpochWithStats(full)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1200), price)
+
+	cfg.l2GasPricePolicy = gasprices.LinearPolicyName
+	gasPricer, err = newGasPricer(cfg, 1000, nil)
+	require.NoError(t, err)
+	price, err = gasPricer.CompleteEpochWithStats(gasprices.EpochStats{AvgGasPerSecond: 1_100_000})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1100), price)
+
+	cfg.l2GasPricePolicy = gasprices.PIDPolicyName
+	_, err = newGasPricer(cfg, 1000, nil)
+	require.NoError(t, err)
+
+	cfg.l2GasPricePolicy = "unknown"
+	_, err = newGasPricer(cfg, 1000, nil)
+	require.Error(t, err)
+}
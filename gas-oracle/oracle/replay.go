@@ -0,0 +1,159 @@
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
+	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
+)
+
+// ReplayResult compares a recorded decision with the decision of the
+// configured policy
+type ReplayResult struct {
+	Time                time.Time `json:"time"`
+	Param               string    `json:"param"`
+	Recorded            *big.Int  `json:"recorded"`
+	RecordedSignificant bool      `json:"recorded_significant"`
+	Replayed            *big.Int  `json:"replayed"`
+	// Current is the simulated on-chain value, it is updated by the
+	// significant replayed decisions
+	Current     *big.Int `json:"current"`
+	Significant bool     `json:"significant"`
+}
+
+// replayPriceSource quotes the recorded price of a token
+type replayPriceSource struct {
+	price float64
+}
+
+func (s *replayPriceSource) Quote(ctx context.Context) (tokenprice.Quote, error) {
+	return tokenprice.Quote{Price: s.price, Timestamp: time.Now()}, nil
+}
+
+// Replay feeds recorded decisions through the GasPricer of the configured
+// policy, the overhead jump table or target margin and the token ratio mode
+// configured by cfg, and writes the results as JSON lines to out. The DA fee
+// is the rollup fee of the DA fee contract, it is replayed with the configured
+// significance factor.
+func Replay(cfg *Config, decisions []Decision, out io.Writer) error {
+	ethSource, mntSource := new(replayPriceSource), new(replayPriceSource)
+	sources := tokenprice.NewRegistry(0)
+	if err := sources.Register(ethSource, tokenprice.SourceOptions{Name: "replay_eth", Token: tokenprice.ETH}); err != nil {
+		return err
+	}
+	if err := sources.Register(mntSource, tokenprice.SourceOptions{Name: "replay_mnt", Token: tokenprice.MNT}); err != nil {
+		return err
+	}
+	tokenPricer := tokenprice.NewClientWithSources(sources, 0, cfg.tokenRatioMode)
+
+	var (
+		gasPricer *gasprices.GasPricer
+		current   = make(map[string]*big.Int)
+		recorded  = make(map[string]int)
+		replayed  = make(map[string]int)
+		enc       = json.NewEncoder(out)
+	)
+	for i, d := range decisions {
+		if _, ok := current[d.Param]; !ok && d.Current != nil {
+			current[d.Param] = new(big.Int).Set(d.Current)
+		}
+		if current[d.Param] == nil || d.Value == nil {
+			return fmt.Errorf("decision %d: missing value", i)
+		}
+		result := ReplayResult{
+			Time:                d.Time,
+			Param:               d.Param,
+			Recorded:            d.Value,
+			RecordedSignificant: d.Significant,
+			Current:             current[d.Param],
+		}
+
+		switch d.Param {
+		case paramGasPrice:
+			if gasPricer == nil {
+				var err error
+				gasPricer, err = newGasPricer(cfg, current[d.Param].Uint64(), tokenPricer)
+				if err != nil {
+					return err
+				}
+			}
+			ethSource.price, mntSource.price = d.Inputs.EthPrice, d.Inputs.MntPrice
+			price, err := gasPricer.CompleteEpochWithStats(gasprices.EpochStats{
+				AvgGasPerSecond: d.Inputs.AvgGasPerSecond,
+				GasUsed:         d.Inputs.GasUsed,
+				Blocks:          d.Inputs.Blocks,
+				GasLimit:        d.Inputs.GasLimit,
+			})
+			if err != nil {
+				return fmt.Errorf("decision %d: %w", i, err)
+			}
+			result.Replayed = new(big.Int).SetUint64(price)
+			result.Significant = price != result.Current.Uint64() &&
+				isDifferenceSignificant(result.Current.Uint64(), price, cfg.l2GasPriceSignificanceFactor)
+		case paramOverhead:
+			if d.Inputs.L2Transactions != 0 {
+				overhead := receiptOverhead(d.Inputs.L1GasUsed, d.Inputs.L2Transactions, cfg.l1OverheadTargetMargin)
+				result.Replayed = overhead
+				result.Significant = isDifferenceSignificant(result.Current.Uint64(), overhead.Uint64(), cfg.l1OverheadSignificanceFactor)
+				break
+			}
+			if d.Inputs.CtcBatches == nil || d.Inputs.BatchSize == nil {
+				return fmt.Errorf("decision %d: missing overhead inputs", i)
+			}
+			calculateJumpTable(d.Inputs.CtcBatches, cfg)
+			overhead, err := getOverheadLevelInJumpTable(d.Inputs.BatchSize)
+			if err != nil {
+				return fmt.Errorf("decision %d: %w", i, err)
+			}
+			result.Replayed = overhead
+			result.Significant = overhead.Cmp(result.Current) != 0
+		case paramL1BaseFee:
+			if d.Inputs.L1BestBaseFee == nil || d.Inputs.L1GasTipCap == nil {
+				return fmt.Errorf("decision %d: missing l1 base fee inputs", i)
+			}
+			ratio := d.Inputs.TokenRatio
+			if d.Inputs.EthPrice != 0 && d.Inputs.MntPrice != 0 {
+				ethSource.price, mntSource.price = d.Inputs.EthPrice, d.Inputs.MntPrice
+				var err error
+				if ratio, err = tokenPricer.PriceRatioWithMode(); err != nil {
+					return fmt.Errorf("decision %d: %w", i, err)
+				}
+			}
+			baseFee := l1BaseFee(d.Inputs.L1BestBaseFee, d.Inputs.L1GasTipCap, ratio)
+			result.Replayed = baseFee
+			result.Significant = isDifferenceSignificant(result.Current.Uint64(), baseFee.Uint64(), cfg.l1BaseFeeSignificanceFactor)
+		case paramDaGasPrice:
+			if d.Inputs.DaRollupFee == nil {
+				return fmt.Errorf("decision %d: missing da fee inputs", i)
+			}
+			result.Replayed = d.Inputs.DaRollupFee
+			result.Significant = isDifferenceSignificant(result.Current.Uint64(), d.Inputs.DaRollupFee.Uint64(), cfg.daFeeSignificanceFactor)
+		default:
+			return fmt.Errorf("decision %d: unknown parameter %s", i, d.Param)
+		}
+
+		if d.Significant {
+			recorded[d.Param]++
+		}
+		if result.Significant {
+			replayed[d.Param]++
+			current[d.Param] = result.Replayed
+		}
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+
+	for _, param := range []string{paramGasPrice, paramL1BaseFee, paramDaGasPrice, paramOverhead} {
+		log.Info("replayed decisions", "param", param, "recorded-updates", recorded[param],
+			"replayed-updates", replayed[param], "last-value", current[param])
+	}
+	return nil
+}
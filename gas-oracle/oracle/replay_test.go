@@ -0,0 +1,134 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
+)
+
+func readReplayResults(t *testing.T, out *bytes.Buffer) []ReplayResult {
+	var results []ReplayResult
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var r ReplayResult
+		require.NoError(t, dec.Decode(&r))
+		results = append(results, r)
+	}
+	return results
+}
+
+func TestReplayOverhead(t *testing.T) {
+	cfg := &Config{
+		batchSizeBottom:    100,
+		batchSizeCap:       1000,
+		sizeGap:            100,
+		stateRollupGasUsed: big.NewInt(100000),
+		stateHashGasUsed:   big.NewInt(2000),
+		dataRollupGasUsed:  big.NewInt(300000),
+	}
+	decisions := []Decision{
+		{Param: paramOverhead, Value: big.NewInt(1), Current: big.NewInt(2000),
+			Inputs: DecisionInputs{CtcBatches: big.NewInt(1), BatchSize: big.NewInt(100)}},
+		{Param: paramOverhead, Value: big.NewInt(1), Current: big.NewInt(2000),
+			Inputs: DecisionInputs{CtcBatches: big.NewInt(1), BatchSize: big.NewInt(150)}},
+		{Param: paramOverhead, Value: big.NewInt(1), Current: big.NewInt(2000),
+			Inputs: DecisionInputs{CtcBatches: big.NewInt(3), BatchSize: big.NewInt(1000)}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Replay(cfg, decisions, &out))
+	results := readReplayResults(t, &out)
+	require.Len(t, results, 3)
+
+	// (100000 + 1 * 300000) / 100 + 2000
+	require.Equal(t, big.NewInt(6000), results[0].Replayed)
+	require.True(t, results[0].Significant)
+	// same level, the simulated on-chain value was updated
+	require.Equal(t, big.NewInt(6000), results[1].Current)
+	require.Equal(t, big.NewInt(6000), results[1].Replayed)
+	require.False(t, results[1].Significant)
+	// (100000 + 3 * 300000) / 1000 + 2000
+	require.Equal(t, big.NewInt(3000), results[2].Replayed)
+	require.True(t, results[2].Significant)
+}
+
+func TestReplaySignificance(t *testing.T) {
+	cfg := &Config{l1BaseFeeSignificanceFactor: 0.1, daFeeSignificanceFactor: 0.5}
+	baseFeeInputs := func(bestBaseFee int64) DecisionInputs {
+		return DecisionInputs{L1BestBaseFee: big.NewInt(bestBaseFee), L1GasTipCap: big.NewInt(0), TokenRatio: 1}
+	}
+	decisions := []Decision{
+		{Param: paramL1BaseFee, Value: big.NewInt(105), Current: big.NewInt(100), Significant: true, Inputs: baseFeeInputs(105)},
+		{Param: paramL1BaseFee, Value: big.NewInt(120), Current: big.NewInt(105), Inputs: baseFeeInputs(120)},
+		{Param: paramL1BaseFee, Value: big.NewInt(125), Current: big.NewInt(105), Inputs: baseFeeInputs(125)},
+		{Param: paramDaGasPrice, Value: big.NewInt(150), Current: big.NewInt(100), Significant: true,
+			Inputs: DecisionInputs{DaRollupFee: big.NewInt(150)}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Replay(cfg, decisions, &out))
+	results := readReplayResults(t, &out)
+	require.Len(t, results, 4)
+	require.False(t, results[0].Significant)
+	require.True(t, results[1].Significant)
+	require.Equal(t, big.NewInt(120), results[2].Current)
+	require.False(t, results[2].Significant)
+	require.False(t, results[3].Significant)
+
+	require.Error(t, Replay(cfg, []Decision{{Param: "scalar", Value: big.NewInt(1), Current: big.NewInt(1)}}, &out))
+}
+
+func TestReplayReceiptOverhead(t *testing.T) {
+	cfg := &Config{l1OverheadTargetMargin: 0.2, l1OverheadSignificanceFactor: 0.05}
+	decisions := []Decision{
+		{Param: paramOverhead, Value: big.NewInt(6600), Current: big.NewInt(2000),
+			Inputs: DecisionInputs{L1GasUsed: 600_000, L2Transactions: 100}},
+		{Param: paramOverhead, Value: big.NewInt(6700), Current: big.NewInt(2000),
+			Inputs: DecisionInputs{L1GasUsed: 610_000, L2Transactions: 100}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Replay(cfg, decisions, &out))
+	results := readReplayResults(t, &out)
+	require.Len(t, results, 2)
+
+	// 600000 / 100 * 1.2
+	require.Equal(t, big.NewInt(7200), results[0].Replayed)
+	require.True(t, results[0].Significant)
+	require.Equal(t, big.NewInt(7320), results[1].Replayed)
+	require.False(t, results[1].Significant)
+}
+
+func TestReplayL1BaseFee(t *testing.T) {
+	cfg := &Config{l1BaseFeeSignificanceFactor: 0.05, tokenRatioMode: uint64(tokenprice.OneDollarTokenRatioMode)}
+	decisions := []Decision{
+		// recorded with the real token ratio, eth_price / mnt_price
+		{Param: paramL1BaseFee, Value: big.NewInt(48000), Current: big.NewInt(40000), Significant: true,
+			Inputs: DecisionInputs{
+				L1BaseFee: big.NewInt(9), L1BestBaseFee: big.NewInt(10), L1GasTipCap: big.NewInt(2),
+				EthPrice: 2000, MntPrice: 0.5, TokenRatio: 4000,
+			}},
+		// without prices the recorded token ratio is used
+		{Param: paramL1BaseFee, Value: big.NewInt(36000), Current: big.NewInt(40000), Significant: true,
+			Inputs: DecisionInputs{L1BestBaseFee: big.NewInt(10), L1GasTipCap: big.NewInt(2), TokenRatio: 3000}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Replay(cfg, decisions, &out))
+	results := readReplayResults(t, &out)
+	require.Len(t, results, 2)
+
+	// (10 + 2) * 2000, mnt is worth one dollar
+	require.Equal(t, big.NewInt(24000), results[0].Replayed)
+	require.True(t, results[0].Significant)
+	require.Equal(t, big.NewInt(24000), results[1].Current)
+	require.Equal(t, big.NewInt(36000), results[1].Replayed)
+	require.True(t, results[1].Significant)
+
+	require.Error(t, Replay(cfg, []Decision{{Param: paramL1BaseFee, Value: big.NewInt(1), Current: big.NewInt(1)}}, &out))
+}
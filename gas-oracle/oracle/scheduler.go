@@ -0,0 +1,156 @@
+package oracle
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// schedulerTickInterval is how often the scheduler checks for due jobs
+	schedulerTickInterval = time.Second
+	// updateRetryMinInterval is the delay before retrying a failed job
+	updateRetryMinInterval = time.Second
+	// updateRetryMaxInterval caps the exponential backoff of a failing job
+	updateRetryMaxInterval = 2 * time.Minute
+)
+
+// updateJob decides the parameter updates of one part of the gas oracle
+type updateJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) ([]paramUpdate, error)
+	// commit is called, if set, after the updates of a run have been sent
+	commit func() error
+
+	next     time.Time
+	failures int
+}
+
+// backoff returns the delay before retrying the job after a failure
+func (j *updateJob) backoff() time.Duration {
+	delay := updateRetryMinInterval
+	for i := 1; i < j.failures && delay < updateRetryMaxInterval; i++ {
+		delay *= 2
+	}
+	if delay > updateRetryMaxInterval {
+		delay = updateRetryMaxInterval
+	}
+	return delay
+}
+
+func (j *updateJob) succeed(now time.Time) {
+	j.failures = 0
+	j.next = now.Add(j.interval)
+}
+
+func (j *updateJob) fail(now time.Time, err error) {
+	j.failures++
+	j.next = now.Add(j.backoff())
+	log.Error("gas oracle update failed", "job", j.name, "failures", j.failures,
+		"retry", j.next, "err", err)
+}
+
+// scheduler runs the update jobs serially and sends the updates decided in the
+// same tick together through a single sender
+type scheduler struct {
+	jobs      []*updateJob
+	sender    updateSender
+	overrides *overrides
+}
+
+// newScheduler creates a scheduler applying the pauses and pinned values of
+// overrides, which can be nil
+func newScheduler(sender updateSender, overrides *overrides) *scheduler {
+	return &scheduler{sender: sender, overrides: overrides}
+}
+
+// addJob registers a job, it first runs on the next tick
+func (s *scheduler) addJob(job *updateJob) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run runs the jobs until the context is done
+func (s *scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.tick(ctx, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// tick runs the jobs due at now. Updates of the same parameter are merged,
+// the value of the last job wins. Paused jobs do not run and the computed
+// values of pinned parameters are replaced by the pinned values, sent once.
+func (s *scheduler) tick(ctx context.Context, now time.Time) {
+	var (
+		updates []paramUpdate
+		index   = make(map[string]int)
+		ran     []*updateJob
+		sending = make(map[*updateJob]bool)
+	)
+	s.overrides.expire(now)
+	for _, job := range s.jobs {
+		if now.Before(job.next) {
+			continue
+		}
+		if s.overrides.paused(job.name) {
+			log.Trace("gas oracle update paused", "job", job.name)
+			job.next = now.Add(job.interval)
+			continue
+		}
+		log.Trace("running gas oracle update", "job", job.name)
+		jobUpdates, err := job.run(ctx)
+		if err != nil {
+			job.fail(now, err)
+			continue
+		}
+		for _, update := range jobUpdates {
+			if s.overrides.pinned(update.param) {
+				log.Info("skip update of pinned parameter", "param", update.param, "value", update.value)
+				continue
+			}
+			if i, ok := index[update.param]; ok {
+				updates[i] = update
+				continue
+			}
+			index[update.param] = len(updates)
+			updates = append(updates, update)
+		}
+		ran = append(ran, job)
+		sending[job] = len(jobUpdates) > 0
+	}
+	pins := s.overrides.pendingPins()
+	if len(ran) == 0 && len(pins) == 0 {
+		return
+	}
+	updates = append(updates, pins...)
+
+	// jobs without updates are not failed by an error of the sender
+	sendErr := s.sender.Send(ctx, updates)
+	if sendErr == nil {
+		s.overrides.pinsSent(pins)
+	} else if len(pins) > 0 {
+		log.Error("cannot send pinned values", "err", sendErr)
+	}
+	for _, job := range ran {
+		if sendErr != nil && sending[job] {
+			job.fail(now, sendErr)
+			continue
+		}
+		if job.commit != nil {
+			if err := job.commit(); err != nil {
+				job.fail(now, err)
+				continue
+			}
+		}
+		job.succeed(now)
+	}
+}
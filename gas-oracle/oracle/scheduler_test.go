@@ -0,0 +1,151 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSender struct {
+	sent [][]paramUpdate
+	err  error
+}
+
+func (s *fakeSender) Send(ctx context.Context, updates []paramUpdate) error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(updates) > 0 {
+		s.sent = append(s.sent, updates)
+	}
+	return nil
+}
+
+func staticJob(name string, interval time.Duration, updates ...paramUpdate) *updateJob {
+	return &updateJob{
+		name:     name,
+		interval: interval,
+		run: func(ctx context.Context) ([]paramUpdate, error) {
+			return updates, nil
+		},
+	}
+}
+
+func TestSchedulerBatchesUpdates(t *testing.T) {
+	sender := new(fakeSender)
+	sched := newScheduler(sender, nil)
+	sched.addJob(staticJob("a", 10*time.Second, paramUpdate{paramL1BaseFee, big.NewInt(1)}))
+	sched.addJob(staticJob("b", 20*time.Second, paramUpdate{paramDaGasPrice, big.NewInt(2)}))
+	sched.addJob(staticJob("c", 10*time.Second, paramUpdate{paramL1BaseFee, big.NewInt(3)}))
+	sched.addJob(staticJob("d", 10*time.Second))
+
+	now := time.Now()
+	sched.tick(context.Background(), now)
+	// updates of the same tick are sent together, the last value wins
+	require.Equal(t, [][]paramUpdate{{
+		{paramL1BaseFee, big.NewInt(3)},
+		{paramDaGasPrice, big.NewInt(2)},
+	}}, sender.sent)
+
+	// no job is due
+	sched.tick(context.Background(), now.Add(5*time.Second))
+	require.Len(t, sender.sent, 1)
+
+	// only the jobs with a 10s interval are due
+	sched.tick(context.Background(), now.Add(10*time.Second))
+	require.Len(t, sender.sent, 2)
+	require.Equal(t, []paramUpdate{{paramL1BaseFee, big.NewInt(3)}}, sender.sent[1])
+}
+
+func TestSchedulerBackoff(t *testing.T) {
+	var runs int
+	job := &updateJob{
+		name:     "failing",
+		interval: time.Hour,
+		run: func(ctx context.Context) ([]paramUpdate, error) {
+			runs++
+			return nil, errors.New("boom")
+		},
+	}
+	sched := newScheduler(new(fakeSender), nil)
+	sched.addJob(job)
+
+	now := time.Now()
+	for i, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		sched.tick(context.Background(), now)
+		require.Equal(t, i+1, runs)
+		require.Equal(t, now.Add(delay), job.next)
+		now = job.next
+	}
+
+	job.failures = 100
+	require.Equal(t, updateRetryMaxInterval, job.backoff())
+
+	// a successful run resets the backoff
+	job.run = func(ctx context.Context) ([]paramUpdate, error) { return nil, nil }
+	sched.tick(context.Background(), now)
+	require.Zero(t, job.failures)
+	require.Equal(t, now.Add(time.Hour), job.next)
+}
+
+func TestSchedulerCommit(t *testing.T) {
+	sender := &fakeSender{err: errors.New("boom")}
+	sched := newScheduler(sender, nil)
+
+	var commits int
+	withUpdates := staticJob("updates", time.Minute, paramUpdate{paramOverhead, big.NewInt(1)})
+	withUpdates.commit = func() error {
+		commits++
+		return nil
+	}
+	withoutUpdates := staticJob("no-updates", time.Minute)
+	sched.addJob(withUpdates)
+	sched.addJob(withoutUpdates)
+
+	// updates that are not sent are not committed and retried
+	now := time.Now()
+	sched.tick(context.Background(), now)
+	require.Zero(t, commits)
+	require.Equal(t, 1, withUpdates.failures)
+	require.Equal(t, now.Add(updateRetryMinInterval), withUpdates.next)
+	require.Zero(t, withoutUpdates.failures)
+	require.Equal(t, now.Add(time.Minute), withoutUpdates.next)
+
+	sender.err = nil
+	sched.tick(context.Background(), now.Add(updateRetryMinInterval))
+	require.Equal(t, 1, commits)
+	require.Zero(t, withUpdates.failures)
+	require.Len(t, sender.sent, 1)
+}
+
+func TestSchedulerOverrides(t *testing.T) {
+	overrides, err := newOverrides("", time.Hour)
+	require.NoError(t, err)
+	sender := new(fakeSender)
+	sched := newScheduler(sender, overrides)
+	sched.addJob(staticJob("l1BaseFee", 10*time.Second, paramUpdate{paramL1BaseFee, big.NewInt(1)}))
+	sched.addJob(staticJob("l2GasPrice", 10*time.Second, paramUpdate{paramGasPrice, big.NewInt(2)}))
+
+	now := time.Now()
+	require.NoError(t, overrides.Pause("l1BaseFee", time.Minute, "incident", "", now))
+	require.NoError(t, overrides.Pin(paramGasPrice, big.NewInt(5), 30*time.Second, "incident", "", now))
+
+	// the paused job does not run and the pinned value replaces the computed one
+	sched.tick(context.Background(), now)
+	require.Equal(t, [][]paramUpdate{{{paramGasPrice, big.NewInt(5)}}}, sender.sent)
+
+	// the pinned value is only sent once
+	sched.tick(context.Background(), now.Add(10*time.Second))
+	require.Len(t, sender.sent, 1)
+
+	// the pin expires first
+	sched.tick(context.Background(), now.Add(30*time.Second))
+	require.Equal(t, []paramUpdate{{paramGasPrice, big.NewInt(2)}}, sender.sent[1])
+
+	sched.tick(context.Background(), now.Add(time.Minute))
+	require.Equal(t, []paramUpdate{{paramL1BaseFee, big.NewInt(1)}, {paramGasPrice, big.NewInt(2)}}, sender.sent[2])
+}
@@ -0,0 +1,202 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
+	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
+	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+var (
+	// paramGasPrice is the L2 gas price
+	paramGasPrice = "gasPrice"
+	// paramL1BaseFee is the L1 base fee multiplied by the token ratio
+	paramL1BaseFee = "l1BaseFee"
+	// paramDaGasPrice is the DA fee
+	paramDaGasPrice = "daGasPrice"
+	// paramOverhead is the amortized cost of batch submission per transaction
+	paramOverhead = "overhead"
+
+	// receiptTimeout bounds the wait for the receipts of the updates of a
+	// tick
+	receiptTimeout = 2 * time.Minute
+)
+
+// paramUpdate is a new value of a BVM_GasPriceOracle parameter
+type paramUpdate struct {
+	param string
+	value *big.Int
+}
+
+// updateSender sends parameter updates to the BVM_GasPriceOracle
+type updateSender interface {
+	Send(ctx context.Context, updates []paramUpdate) error
+}
+
+// dryRunSender logs the updates instead of sending them
+type dryRunSender struct{}
+
+func (dryRunSender) Send(ctx context.Context, updates []paramUpdate) error {
+	for _, update := range updates {
+		log.Info("dry run, not updating gas price oracle", "param", update.param, "value", update.value)
+	}
+	return nil
+}
+
+// txSender is the only user of the BVM_GasPriceOracle owner key. It tracks
+// the nonce locally so that the updates of a tick can be sent back to back.
+type txSender struct {
+	backend  DeployContractBackend
+	contract *bindings.BVMGasPriceOracle
+	opts     *bind.TransactOpts
+	cfg      *Config
+	// nonce is the next nonce to use, nil after an error
+	nonce *uint64
+}
+
+func newTxSender(backend DeployContractBackend, s signer.Signer, cfg *Config) (*txSender, error) {
+	opts, err := newTransactOpts(s, cfg)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := bindings.NewBVMGasPriceOracle(cfg.gasPriceOracleAddress, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &txSender{
+		backend:  backend,
+		contract: contract,
+		opts:     opts,
+		cfg:      cfg,
+	}, nil
+}
+
+// newTransactOpts creates the transactor of the BVM_GasPriceOracle owner
+func newTransactOpts(s signer.Signer, cfg *Config) (*bind.TransactOpts, error) {
+	if cfg.l2ChainID == nil {
+		return nil, errNoChainID
+	}
+	if s == nil {
+		return nil, errNoSigner
+	}
+	opts, err := signer.NewTransactOpts(context.Background(), s, cfg.l2ChainID)
+	if err != nil {
+		return nil, err
+	}
+	// Don't send the transaction using the `contract` so that we can inspect
+	// it beforehand
+	opts.NoSend = true
+	return opts, nil
+}
+
+// Send sends a transaction for every update with consecutive nonces, and waits
+// for their receipts if configured to, up to receiptTimeout. The nonce is
+// synced with the pending nonce of the backend at every tick, in case
+// transactions were dropped or the key was used elsewhere. Updates after a
+// failed one are not sent.
+func (s *txSender) Send(ctx context.Context, updates []paramUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	// Use the configured gas price if it is set,
+	// otherwise use gas estimation
+	gasPrice := s.cfg.gasPrice
+	if gasPrice == nil {
+		var err error
+		gasPrice, err = s.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot fetch gas price: %w", err)
+		}
+	}
+	nonce, err := s.backend.PendingNonceAt(ctx, s.opts.From)
+	if err != nil {
+		return fmt.Errorf("cannot fetch nonce: %w", err)
+	}
+	if s.nonce != nil && *s.nonce != nonce {
+		log.Warn("resyncing gas price oracle nonce", "nonce", *s.nonce, "pending", nonce)
+	}
+	s.nonce = &nonce
+
+	txs := make([]*types.Transaction, 0, len(updates))
+	for _, update := range updates {
+		opts := *s.opts
+		opts.Context = ctx
+		opts.GasPrice = gasPrice
+		opts.Nonce = new(big.Int).SetUint64(*s.nonce)
+
+		tx, err := s.transact(&opts, update)
+		if err != nil {
+			s.nonce = nil
+			return fmt.Errorf("cannot update %s: %w", update.param, err)
+		}
+		log.Info("updating gas price oracle", "param", update.param, "value", update.value,
+			"tx.gasPrice", tx.GasPrice(), "tx.gasLimit", tx.Gas(),
+			"tx.data", hexutil.Encode(tx.Data()), "tx.to", tx.To().Hex(), "tx.nonce", tx.Nonce())
+		pre := time.Now()
+		if err := s.backend.SendTransaction(ctx, tx); err != nil {
+			s.nonce = nil
+			return fmt.Errorf("cannot update %s: %w", update.param, err)
+		}
+		ometrics.GasOracleStats.TxSendTimer.Update(time.Since(pre))
+		ometrics.GasOracleStats.TxSendCounter.Inc(1)
+		*s.nonce++
+		log.Info("gas price oracle transaction sent", "param", update.param, "hash", tx.Hash().Hex())
+		recordUpdate(update)
+		txs = append(txs, tx)
+	}
+
+	if s.cfg.waitForReceipt {
+		// Keep track of the time it takes to confirm the transactions
+		pre := time.Now()
+		ctx, cancel := context.WithTimeout(ctx, receiptTimeout)
+		defer cancel()
+		for _, tx := range txs {
+			receipt, err := waitForReceipt(ctx, s.backend, tx)
+			if err != nil {
+				s.nonce = nil
+				return err
+			}
+			log.Info("gas price oracle transaction confirmed", "hash", tx.Hash().Hex(),
+				"gas-used", receipt.GasUsed, "blocknumber", receipt.BlockNumber)
+		}
+		ometrics.GasOracleStats.TxConfTimer.Update(time.Since(pre))
+	}
+	return nil
+}
+
+func (s *txSender) transact(opts *bind.TransactOpts, update paramUpdate) (*types.Transaction, error) {
+	switch update.param {
+	case paramGasPrice:
+		return s.contract.SetGasPrice(opts, update.value)
+	case paramL1BaseFee:
+		return s.contract.SetL1BaseFee(opts, update.value)
+	case paramDaGasPrice:
+		return s.contract.SetDAGasPrice(opts, update.value)
+	case paramOverhead:
+		return s.contract.SetOverhead(opts, update.value)
+	default:
+		return nil, fmt.Errorf("unknown parameter %s", update.param)
+	}
+}
+
+func recordUpdate(update paramUpdate) {
+	switch update.param {
+	case paramGasPrice:
+		ometrics.GasOracleStats.L2GasPriceGauge.Update(update.value.Int64())
+	case paramL1BaseFee:
+		ometrics.GasOracleStats.L1BaseFeeGauge.Update(update.value.Int64())
+	case paramDaGasPrice:
+		ometrics.GasOracleStats.DaFeeGauge.Update(update.value.Int64())
+	case paramOverhead:
+		ometrics.GasOracleStats.OverHeadGauge.Update(update.value.Int64())
+	}
+}
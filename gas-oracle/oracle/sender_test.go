@@ -0,0 +1,140 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
+	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+// fakeSenderBackend accepts every transaction and never mines them unless
+// their receipts are added
+type fakeSenderBackend struct {
+	DeployContractBackend
+
+	mtx          sync.Mutex
+	pendingNonce uint64
+	sent         []*types.Transaction
+	receipts     map[common.Hash]*types.Receipt
+}
+
+func (b *fakeSenderBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.pendingNonce, nil
+}
+
+func (b *fakeSenderBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func (b *fakeSenderBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return 50_000, nil
+}
+
+func (b *fakeSenderBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func (b *fakeSenderBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	receipt, ok := b.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (b *fakeSenderBackend) lastNonce() uint64 {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.sent[len(b.sent)-1].Nonce()
+}
+
+func newTestTxSender(t *testing.T, backend *fakeSenderBackend, waitForReceipt bool) *txSender {
+	ometrics.InitAndRegisterStats(metrics.NewRegistry())
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	cfg := &Config{
+		l2ChainID:             big.NewInt(17),
+		gasPriceOracleAddress: common.HexToAddress("0x420000000000000000000000000000000000000F"),
+		gasPrice:              big.NewInt(1),
+		waitForReceipt:        waitForReceipt,
+	}
+	s, err := newTxSender(backend, signer.NewLocalSigner(key), cfg)
+	require.NoError(t, err)
+	return s
+}
+
+func TestTxSenderResyncsNonce(t *testing.T) {
+	backend := &fakeSenderBackend{pendingNonce: 5}
+	s := newTestTxSender(t, backend, false)
+	update := paramUpdate{param: paramGasPrice, value: big.NewInt(10)}
+
+	require.NoError(t, s.Send(context.Background(), []paramUpdate{update, update}))
+	require.Equal(t, uint64(6), backend.lastNonce())
+
+	// the key was used elsewhere
+	backend.pendingNonce = 9
+	require.NoError(t, s.Send(context.Background(), []paramUpdate{update}))
+	require.Equal(t, uint64(9), backend.lastNonce())
+
+	// the transactions were dropped from the pool
+	backend.pendingNonce = 7
+	require.NoError(t, s.Send(context.Background(), []paramUpdate{update}))
+	require.Equal(t, uint64(7), backend.lastNonce())
+}
+
+func TestTxSenderReceiptTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { receiptTimeout = timeout }(receiptTimeout)
+	receiptTimeout = 500 * time.Millisecond
+
+	backend := &fakeSenderBackend{receipts: make(map[common.Hash]*types.Receipt)}
+	s := newTestTxSender(t, backend, true)
+	update := paramUpdate{param: paramGasPrice, value: big.NewInt(10)}
+
+	// the receipt is never found
+	err := s.Send(context.Background(), []paramUpdate{update})
+	require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	require.Nil(t, s.nonce)
+
+	// the tick is stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = s.Send(ctx, []paramUpdate{update})
+	require.True(t, errors.Is(err, context.Canceled), err)
+
+	// the receipt is found
+	backend.pendingNonce = 1
+	done := make(chan error)
+	go func() {
+		done <- s.Send(context.Background(), []paramUpdate{update})
+	}()
+	require.Eventually(t, func() bool {
+		backend.mtx.Lock()
+		defer backend.mtx.Unlock()
+		if len(backend.sent) < 3 {
+			return false
+		}
+		tx := backend.sent[len(backend.sent)-1]
+		backend.receipts[tx.Hash()] = &types.Receipt{BlockNumber: big.NewInt(1)}
+		return true
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,62 @@
+package oracle
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mantlenetworkio/mantle/l2geth/core/rawdb"
+	"github.com/mantlenetworkio/mantle/l2geth/ethdb"
+)
+
+const GAS_ORACLE_SYNC_HEIGHT = "GAS_ORACLE_SYNC_HEIGHT"
+
+// StateStore persists the state of the gas oracle across restarts
+type StateStore interface {
+	// SyncHeight returns the last L1 height scanned for state batches, false
+	// if none was stored
+	SyncHeight() (uint64, bool, error)
+	SetSyncHeight(height uint64) error
+	Close() error
+}
+
+type dbStateStore struct {
+	db ethdb.Database
+}
+
+// NewStateStore opens the LevelDB state store at path, the state is kept in
+// memory if path is empty
+func NewStateStore(path string) (StateStore, error) {
+	if path == "" {
+		return &dbStateStore{db: rawdb.NewMemoryDatabase()}, nil
+	}
+	db, err := rawdb.NewLevelDBDatabase(path, 0, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot open state db %s: %w", path, err)
+	}
+	return &dbStateStore{db: db}, nil
+}
+
+func (s *dbStateStore) SyncHeight() (uint64, bool, error) {
+	has, err := s.db.Has([]byte(GAS_ORACLE_SYNC_HEIGHT))
+	if err != nil || !has {
+		return 0, false, err
+	}
+	height, err := s.db.Get([]byte(GAS_ORACLE_SYNC_HEIGHT))
+	if err != nil {
+		return 0, false, err
+	}
+	if len(height) != 8 {
+		return 0, false, fmt.Errorf("invalid sync height %x", height)
+	}
+	return binary.BigEndian.Uint64(height), true, nil
+}
+
+func (s *dbStateStore) SetSyncHeight(height uint64) error {
+	var indexBz = make([]byte, 8)
+	binary.BigEndian.PutUint64(indexBz, height)
+	return s.db.Put([]byte(GAS_ORACLE_SYNC_HEIGHT), indexBz)
+}
+
+func (s *dbStateStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,30 @@
+package oracle
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gas-oracle-data")
+	store, err := NewStateStore(path)
+	require.NoError(t, err)
+
+	_, ok, err := store.SyncHeight()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, store.SetSyncHeight(1234))
+	require.NoError(t, store.Close())
+
+	// the height survives a restart
+	store, err = NewStateStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	height, ok, err := store.SyncHeight()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(1234), height)
+}
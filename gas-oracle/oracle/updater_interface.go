@@ -2,24 +2,18 @@ package oracle
 
 import (
 	"context"
-	"encoding/hex"
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 
-	bsscore "github.com/mantlenetworkio/mantle/bss-core"
 	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
 	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
-
-	kms "cloud.google.com/go/kms/apiv1"
-	"google.golang.org/api/option"
 )
 
 // getLatestBlockNumberFn is used by the GasPriceUpdater
@@ -56,129 +50,75 @@ type DeployContractBackend interface {
 	bind.ContractBackend
 }
 
-// updateL2GasPriceFn is used by the GasPriceUpdater
-// to update the L2 gas price
-// perhaps this should take an options struct along with the backend?
-// how can this continue to be decomposed?
-func wrapUpdateL2GasPriceFn(backend DeployContractBackend, cfg *Config) (func(uint64) error, error) {
-	var opts *bind.TransactOpts
-	var err error
-	if !cfg.EnableHsm {
-		if cfg.privateKey == nil {
-			return nil, errNoPrivateKey
-		}
-		if cfg.l2ChainID == nil {
-			return nil, errNoChainID
-		}
-
-		opts, err = bind.NewKeyedTransactorWithChainID(cfg.privateKey, cfg.l2ChainID)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		seqBytes, err := hex.DecodeString(cfg.HsmCreden)
-		apikey := option.WithCredentialsJSON(seqBytes)
-		client, err := kms.NewKeyManagementClient(context.Background(), apikey)
-		if err != nil {
-			log.Crit("gasoracle", "create signer error", err.Error())
-		}
-		mk := &bsscore.ManagedKey{
-			KeyName:      cfg.HsmAPIName,
-			EthereumAddr: common.HexToAddress(cfg.HsmAddress),
-			Gclient:      client,
-		}
-		opts, err = mk.NewEthereumTransactorrWithChainID(context.Background(), cfg.l2ChainID)
-		if err != nil {
-			log.Crit("gasoracle", "create signer error", err.Error())
-			return nil, err
-		}
-	}
+// pendingUpdates collects the updates decided by the callbacks of the
+// GasPriceUpdater until the scheduler sends them
+type pendingUpdates struct {
+	updates []paramUpdate
+}
 
-	// Don't send the transaction using the `contract` so that we can inspect
-	// it beforehand
-	opts.NoSend = true
+func (p *pendingUpdates) add(update paramUpdate) {
+	p.updates = append(p.updates, update)
+}
 
-	// Create a new contract bindings in scope of the updateL2GasPriceFn
-	// that is returned from this function
-	contract, err := bindings.NewBVMGasPriceOracle(cfg.gasPriceOracleAddress, backend)
-	if err != nil {
-		return nil, err
-	}
+func (p *pendingUpdates) drain() []paramUpdate {
+	updates := p.updates
+	p.updates = nil
+	return updates
+}
 
+// updateL2GasPriceFn is used by the GasPriceUpdater
+// to update the L2 gas price. The price is queued in
+// pending, decideL2GasPrice decides whether to send it.
+func wrapUpdateL2GasPriceFn(pending *pendingUpdates) func(uint64) error {
 	return func(updatedGasPrice uint64) error {
 		log.Trace("UpdateL2GasPriceFn", "gas-price", updatedGasPrice)
-		if cfg.gasPrice == nil {
-			// Set the gas price manually to use legacy transactions
-			gasPrice, err := backend.SuggestGasPrice(context.Background())
-			if err != nil {
-				log.Error("cannot fetch gas price", "message", err)
-				return err
-			}
-			log.Trace("fetched L2 tx.gasPrice", "gas-price", gasPrice)
-			opts.GasPrice = gasPrice
-		} else {
-			// Allow a configurable gas price to be set
-			opts.GasPrice = cfg.gasPrice
-		}
-
-		// Query the current L2 gas price
-		currentPrice, err := contract.GasPrice(&bind.CallOpts{
-			Context: context.Background(),
-		})
-		if err != nil {
-			log.Error("cannot fetch current gas price", "message", err)
-			return err
-		}
+		pending.add(paramUpdate{param: paramGasPrice, value: new(big.Int).SetUint64(updatedGasPrice)})
+		return nil
+	}
+}
 
-		// no need to update when they are the same
-		if currentPrice.Uint64() == updatedGasPrice {
-			log.Info("gas price did not change", "gas-price", updatedGasPrice)
-			ometrics.GasOracleStats.TxNotSignificantCounter.Inc(1)
-			return nil
-		}
+// decideL2GasPrice returns the update of the L2 gas price if it differs
+// significantly from the current one
+func decideL2GasPrice(ctx context.Context, contract *bindings.BVMGasPriceOracle, cfg *Config, audit *AuditLog,
+	update paramUpdate, inputs DecisionInputs) ([]paramUpdate, error) {
+	updatedGasPrice := update.value.Uint64()
 
-		// Only update the gas price when it must be changed by at least
-		// a paramaterizable amount.
-		if !isDifferenceSignificant(currentPrice.Uint64(), updatedGasPrice, cfg.l2GasPriceSignificanceFactor) {
-			log.Info("gas price did not significantly change", "min-factor", cfg.l2GasPriceSignificanceFactor,
-				"current-price", currentPrice, "next-price", updatedGasPrice)
-			ometrics.GasOracleStats.TxNotSignificantCounter.Inc(1)
-			return nil
-		}
+	// Query the current L2 gas price
+	currentPrice, err := contract.GasPrice(&bind.CallOpts{
+		Context: ctx,
+	})
+	if err != nil {
+		log.Error("cannot fetch current gas price", "message", err)
+		return nil, err
+	}
 
-		// Set the gas price by sending a transaction
-		tx, err := contract.SetGasPrice(opts, new(big.Int).SetUint64(updatedGasPrice))
-		if err != nil {
-			return err
-		}
+	// Only update the gas price when it must be changed by at least
+	// a paramaterizable amount.
+	significant := currentPrice.Uint64() != updatedGasPrice &&
+		isDifferenceSignificant(currentPrice.Uint64(), updatedGasPrice, cfg.l2GasPriceSignificanceFactor)
+	audit.Record(Decision{
+		Param:       paramGasPrice,
+		Value:       update.value,
+		Current:     currentPrice,
+		Significant: significant,
+		Inputs:      inputs,
+	})
+
+	// no need to update when they are the same
+	if currentPrice.Uint64() == updatedGasPrice {
+		log.Info("gas price did not change", "gas-price", updatedGasPrice)
+		ometrics.GasOracleStats.TxNotSignificantCounter.Inc(1)
+		return nil, nil
+	}
+	if !significant {
+		log.Info("gas price did not significantly change", "min-factor", cfg.l2GasPriceSignificanceFactor,
+			"current-price", currentPrice, "next-price", updatedGasPrice)
+		ometrics.GasOracleStats.TxNotSignificantCounter.Inc(1)
+		return nil, nil
+	}
 
-		log.Debug("updating L2 gas price", "tx.gasPrice", tx.GasPrice(), "tx.gasLimit", tx.Gas(),
-			"tx.data", hexutil.Encode(tx.Data()), "tx.to", tx.To().Hex(), "tx.nonce", tx.Nonce())
-		pre := time.Now()
-		if err := backend.SendTransaction(context.Background(), tx); err != nil {
-			return err
-		}
-		ometrics.GasOracleStats.TxSendTimer.Update(time.Since(pre))
-		log.Info("L2 gas price transaction sent", "hash", tx.Hash().Hex())
-
-		ometrics.GasOracleStats.L2GasPriceGauge.Update(int64(updatedGasPrice))
-		ometrics.GasOracleStats.TxSendCounter.Inc(1)
-
-		if cfg.waitForReceipt {
-			// Keep track of the time it takes to confirm the transaction
-			pre := time.Now()
-			// Wait for the receipt
-			receipt, err := waitForReceipt(backend, tx)
-			if err != nil {
-				return err
-			}
-			ometrics.GasOracleStats.TxConfTimer.Update(time.Since(pre))
-
-			log.Info("L2 gas price transaction confirmed", "hash", tx.Hash().Hex(),
-				"gas-used", receipt.GasUsed, "blocknumber", receipt.BlockNumber)
-		}
-		return nil
-	}, nil
+	log.Info("updating L2 gas price", "current-price", currentPrice, "next-price", updatedGasPrice)
+	return []paramUpdate{update}, nil
 }
 
 // Only update the gas price when it must be changed by at least
@@ -192,13 +132,17 @@ func isDifferenceSignificant(a, b uint64, c float64) bool {
 	return c <= factor
 }
 
-// Wait for the receipt by polling the backend
-func waitForReceipt(backend DeployContractBackend, tx *types.Transaction) (*types.Receipt, error) {
+// Wait for the receipt by polling the backend until ctx is done
+func waitForReceipt(ctx context.Context, backend DeployContractBackend, tx *types.Transaction) (*types.Receipt, error) {
 	t := time.NewTicker(300 * time.Millisecond)
-	receipt := new(types.Receipt)
-	var err error
-	for range t.C {
-		receipt, err = backend.TransactionReceipt(context.Background(), tx.Hash())
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("cannot get receipt %s: %w", tx.Hash().Hex(), ctx.Err())
+		}
+		receipt, err := backend.TransactionReceipt(ctx, tx.Hash())
 		if errors.Is(err, ethereum.NotFound) {
 			continue
 		}
@@ -206,11 +150,9 @@ func waitForReceipt(backend DeployContractBackend, tx *types.Transaction) (*type
 			return nil, err
 		}
 		if receipt != nil {
-			t.Stop()
-			break
+			return receipt, nil
 		}
 	}
-	return receipt, nil
 }
 
 func max(a, b uint64) uint64 {
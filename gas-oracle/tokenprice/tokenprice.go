@@ -1,9 +1,10 @@
 package tokenprice
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"sort"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -17,6 +18,7 @@ type TokenRatioMode uint64
 type Client struct {
 	client               *resty.Client
 	uniswapQuoterClient  *uniswapClient
+	sources              *Registry
 	frequency            time.Duration
 	lastRatio            float64
 	lastEthPrice         float64
@@ -24,6 +26,10 @@ type Client struct {
 	lastUpdate           time.Time
 	tokenRatioMode       TokenRatioMode
 	tokenPairForMNTPrice string
+
+	// mtx guards the last prices, read by the admin server while
+	// PriceRatioWithMode updates them
+	mtx sync.RWMutex
 }
 
 var (
@@ -88,7 +94,7 @@ func NewClient(url, uniswapURL string, frequency uint64, tokenRatioMode uint64,
 
 	tokenPairForMNTPrice := determineTokenPairForMNT(tokenPairMNTMode)
 
-	return &Client{
+	c := &Client{
 		client:               client,
 		uniswapQuoterClient:  uniswapQuoterClient,
 		frequency:            time.Duration(frequency) * time.Second,
@@ -98,37 +104,75 @@ func NewClient(url, uniswapURL string, frequency uint64, tokenRatioMode uint64,
 		tokenRatioMode:       TokenRatioMode(tokenRatioMode),
 		tokenPairForMNTPrice: tokenPairForMNTPrice,
 	}
+	c.sources = c.defaultSources()
+	return c
+}
+
+// NewClientWithSources create a new Client that aggregates the prices of the given sources
+func NewClientWithSources(sources *Registry, frequency uint64, tokenRatioMode uint64) *Client {
+	return &Client{
+		sources:        sources,
+		frequency:      time.Duration(frequency) * time.Second,
+		lastRatio:      DefaultTokenRatio,
+		lastEthPrice:   DefaultETHPrice,
+		lastMntPrice:   DefaultMNTPrice,
+		tokenRatioMode: TokenRatioMode(tokenRatioMode),
+	}
+}
+
+// defaultSources are the price backend (cex) and the uniswap quoter (dex).
+// The cex is weighted twice, as it used to be queried twice.
+func (c *Client) defaultSources() *Registry {
+	sources := NewRegistry(DefaultMaxDeviation)
+	_ = sources.Register(&uniswapQuoterSource{
+		c:         c,
+		fromToken: c.uniswapQuoterClient.ethAddress,
+		toToken:   c.uniswapQuoterClient.usdttAddress,
+		decimals:  c.uniswapQuoterClient.usdtDecimals,
+	}, SourceOptions{Name: "dex_eth", Token: ETH})
+	_ = sources.Register(&uniswapQuoterSource{
+		c:         c,
+		fromToken: c.uniswapQuoterClient.ethAddress,
+		toToken:   c.uniswapQuoterClient.mntAddress,
+		decimals:  c.uniswapQuoterClient.mntDecimals,
+		invert:    true,
+	}, SourceOptions{Name: "dex_mnt", Token: MNT, Quote: ETH})
+	_ = sources.Register(&v5Source{c, ETHUSDT}, SourceOptions{Name: "cex_eth", Token: ETH, Weight: 2})
+	_ = sources.Register(&v5Source{c, c.tokenPairForMNTPrice}, SourceOptions{Name: "cex_mnt", Token: MNT, Weight: 2})
+	return sources
+}
+
+// Sources returns the price sources of the client
+func (c *Client) Sources() *Registry {
+	return c.sources
+}
+
+// LastPrices returns the eth_price, mnt_price and token_ratio of the last
+// PriceRatioWithMode update
+func (c *Client) LastPrices() (float64, float64, float64) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.lastEthPrice, c.lastMntPrice, c.lastRatio
 }
 
 func (c *Client) PriceRatioWithMode() (float64, error) {
-	if time.Now().Sub(c.lastUpdate) < c.frequency {
-		return c.lastRatio, nil
+	c.mtx.RLock()
+	lastUpdate, lastRatio := c.lastUpdate, c.lastRatio
+	c.mtx.RUnlock()
+	if time.Now().Sub(lastUpdate) < c.frequency {
+		return lastRatio, nil
 	}
 
-	// Todo query token prices concurrent
-	var mntPrices, ethPrices []float64
-	// get token price from oracle1(dex)
-	mntPrice1, ethPrice1 := c.getTokenPricesFromUniswap()
-	mntPrices = append(mntPrices, mntPrice1)
-	ethPrices = append(ethPrices, ethPrice1)
-	log.Info("query prices from oracle1", "mnt_price", mntPrice1, "eth_price", ethPrice1)
-
-	// get token price from oracle2(cex)
-	mntPrice2, ethPrice2 := c.getTokenPricesFromCex()
-	mntPrices = append(mntPrices, mntPrice2)
-	ethPrices = append(ethPrices, ethPrice2)
-	log.Info("query prices from oracle2", "mnt_price", mntPrice2, "eth_price", ethPrice2)
-
-	// get token price from oracle3(cex)
-	// Todo add a third oracle to query prices
-	mntPrice3, ethPrice3 := c.getTokenPricesFromCex()
-	mntPrices = append(mntPrices, mntPrice3)
-	ethPrices = append(ethPrices, ethPrice3)
-	log.Info("query prices from oracle3", "mnt_price", mntPrice3, "eth_price", ethPrice3)
-
-	// median price for eth & mnt
-	medianMNTPrice := getMedian(mntPrices)
-	medianETHPrice := getMedian(ethPrices)
+	medianETHPrice, medianMNTPrice, err := c.sources.Prices(context.Background())
+	if err != nil {
+		log.Warn("query token prices", "err", err)
+	}
+	log.Info("median token prices", "mnt_price", medianMNTPrice, "eth_price", medianETHPrice)
+
+	// the prices are queried without the lock, which is only held to
+	// derive the new prices from the last ones
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 
 	// determine mnt_price, eth_price
 	mntPrice := c.determineMNTPrice(medianMNTPrice)
@@ -159,21 +203,6 @@ func (c *Client) PriceRatioWithMode() (float64, error) {
 	return ratio, nil
 }
 
-func (c *Client) getTokenPricesFromCex() (float64, float64) {
-	ethPrice, err := c.queryV5(ETHUSDT)
-	if err != nil {
-		log.Warn("get token prices", "query eth price error", err)
-		return 0, 0
-	}
-	mntPrice, err := c.queryV5(c.tokenPairForMNTPrice)
-	if err != nil {
-		log.Warn("get token prices", "query mnt price error", err)
-		return 0, ethPrice
-	}
-
-	return mntPrice, ethPrice
-}
-
 func (c *Client) determineMNTPrice(price float64) float64 {
 	if price > MNTPriceMax || price < MNTPriceMin {
 		return c.lastMntPrice
@@ -214,20 +243,6 @@ func determineTokenPairForMNT(tokenPairMNTMode bool) string {
 	}
 }
 
-func getMedian(nums []float64) float64 {
-	nonZeros := make([]float64, 0)
-	for _, num := range nums {
-		if num != 0 {
-			nonZeros = append(nonZeros, num)
-		}
-	}
-	sort.Float64s(nonZeros)
-	if len(nonZeros) == 0 {
-		return 0
-	}
-	return nonZeros[len(nonZeros)/2]
-}
-
 func getMax(a, b float64) float64 {
 	if a > b {
 		return a
@@ -0,0 +1,80 @@
+package tokenprice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const chainlinkAggregatorABI = `[
+	{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"latestRoundData","outputs":[{"name":"roundId","type":"uint80"},{"name":"answer","type":"int256"},{"name":"startedAt","type":"uint256"},{"name":"updatedAt","type":"uint256"},{"name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"}
+]`
+
+// ChainlinkSource reads the latest answer of a Chainlink-style price feed
+// aggregator through eth_call
+type ChainlinkSource struct {
+	contract *bind.BoundContract
+
+	mtx      sync.Mutex
+	decimals *uint8
+}
+
+func NewChainlinkSource(caller bind.ContractCaller, address common.Address) (*ChainlinkSource, error) {
+	parsed, err := abi.JSON(strings.NewReader(chainlinkAggregatorABI))
+	if err != nil {
+		return nil, err
+	}
+	return &ChainlinkSource{
+		contract: bind.NewBoundContract(address, parsed, caller, nil, nil),
+	}, nil
+}
+
+func (s *ChainlinkSource) Quote(ctx context.Context) (Quote, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	decimals, err := s.getDecimals(opts)
+	if err != nil {
+		return Quote{}, fmt.Errorf("cannot fetch decimals: %w", err)
+	}
+
+	var out []interface{}
+	if err := s.contract.Call(opts, &out, "latestRoundData"); err != nil {
+		return Quote{}, fmt.Errorf("cannot fetch latest round: %w", err)
+	}
+	answer := out[1].(*big.Int)
+	updatedAt := out[3].(*big.Int)
+	return Quote{
+		Price:     scaleDown(answer, int(decimals)),
+		Timestamp: time.Unix(updatedAt.Int64(), 0),
+	}, nil
+}
+
+// getDecimals fetches the decimals of the aggregator once
+func (s *ChainlinkSource) getDecimals(opts *bind.CallOpts) (uint8, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.decimals != nil {
+		return *s.decimals, nil
+	}
+	var out []interface{}
+	if err := s.contract.Call(opts, &out, "decimals"); err != nil {
+		return 0, err
+	}
+	decimals := out[0].(uint8)
+	s.decimals = &decimals
+	return decimals, nil
+}
+
+// scaleDown returns value / 10^decimals
+func scaleDown(value *big.Int, decimals int) float64 {
+	result, _ := new(big.Float).Quo(new(big.Float).SetInt(value), big.NewFloat(math.Pow10(decimals))).Float64()
+	return result
+}
@@ -0,0 +1,149 @@
+package tokenprice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	// SourceKindREST reads a price from a JSON HTTP endpoint
+	SourceKindREST = "rest"
+	// SourceKindChainlink reads a Chainlink-style aggregator
+	SourceKindChainlink = "chainlink"
+	// SourceKindUniswapV2 reads the reserves of a Uniswap V2 pair
+	SourceKindUniswapV2 = "uniswap_v2"
+	// SourceKindUniswapV3 reads the slot0 of a Uniswap V3 pool
+	SourceKindUniswapV3 = "uniswap_v3"
+)
+
+// SourceConfig is the configuration of a single price source
+type SourceConfig struct {
+	Name                string  `json:"name"`
+	Kind                string  `json:"kind"`
+	Token               string  `json:"token"`
+	Quote               string  `json:"quote"`
+	Weight              float64 `json:"weight"`
+	TimeoutSeconds      uint64  `json:"timeout_seconds"`
+	MaxStalenessSeconds uint64  `json:"max_staleness_seconds"`
+
+	// rest sources
+	URL           string `json:"url"`
+	PricePath     string `json:"price_path"`
+	TimestampPath string `json:"timestamp_path"`
+
+	// on-chain sources, RPCURL defaults to the uniswap url
+	RPCURL    string `json:"rpc_url"`
+	Address   string `json:"address"`
+	Decimals0 int    `json:"decimals0"`
+	Decimals1 int    `json:"decimals1"`
+	Invert    bool   `json:"invert"`
+}
+
+// SourcesConfig is the content of the price sources config file
+type SourcesConfig struct {
+	MaxDeviation float64        `json:"max_deviation"`
+	Sources      []SourceConfig `json:"sources"`
+}
+
+// LoadSourcesConfig reads a JSON price sources config file
+func LoadSourcesConfig(path string) (*SourcesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg SourcesConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse price sources config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// NewRegistryFromConfig creates the configured price sources, defaultRPCURL
+// is used by on-chain sources without a rpc_url
+func NewRegistryFromConfig(cfg *SourcesConfig, defaultRPCURL string) (*Registry, error) {
+	if len(cfg.Sources) == 0 {
+		return nil, fmt.Errorf("no price sources configured")
+	}
+	registry := NewRegistry(cfg.MaxDeviation)
+	clients := make(map[string]*ethclient.Client)
+	dial := func(url string) (*ethclient.Client, error) {
+		if url == "" {
+			url = defaultRPCURL
+		}
+		if client, ok := clients[url]; ok {
+			return client, nil
+		}
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return nil, err
+		}
+		clients[url] = client
+		return client, nil
+	}
+
+	for _, sc := range cfg.Sources {
+		source, err := newSource(sc, dial)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", sc.Name, err)
+		}
+		err = registry.Register(source, SourceOptions{
+			Name:         sc.Name,
+			Token:        sc.Token,
+			Quote:        sc.Quote,
+			Weight:       sc.Weight,
+			Timeout:      time.Duration(sc.TimeoutSeconds) * time.Second,
+			MaxStaleness: time.Duration(sc.MaxStalenessSeconds) * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+func newSource(sc SourceConfig, dial func(url string) (*ethclient.Client, error)) (PriceSource, error) {
+	if sc.Kind == SourceKindREST {
+		if sc.URL == "" || sc.PricePath == "" {
+			return nil, fmt.Errorf("url and price_path are required")
+		}
+		return NewRESTSource(sc.URL, sc.PricePath, sc.TimestampPath), nil
+	}
+
+	if !common.IsHexAddress(sc.Address) {
+		return nil, fmt.Errorf("invalid address %s", sc.Address)
+	}
+	address := common.HexToAddress(sc.Address)
+	pool := PoolConfig{
+		Address:   address,
+		Decimals0: sc.Decimals0,
+		Decimals1: sc.Decimals1,
+		Invert:    sc.Invert,
+	}
+	switch sc.Kind {
+	case SourceKindChainlink:
+		client, err := dial(sc.RPCURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewChainlinkSource(client, address)
+	case SourceKindUniswapV2:
+		client, err := dial(sc.RPCURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewUniswapV2Source(client, pool)
+	case SourceKindUniswapV3:
+		client, err := dial(sc.RPCURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewUniswapV3Source(client, pool)
+	default:
+		return nil, fmt.Errorf("unknown kind %s", sc.Kind)
+	}
+}
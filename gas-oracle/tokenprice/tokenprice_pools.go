@@ -0,0 +1,109 @@
+package tokenprice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	uniswapV2PairABI = `[{"inputs":[],"name":"getReserves","outputs":[{"name":"reserve0","type":"uint112"},{"name":"reserve1","type":"uint112"},{"name":"blockTimestampLast","type":"uint32"}],"stateMutability":"view","type":"function"}]`
+	uniswapV3PoolABI = `[{"inputs":[],"name":"slot0","outputs":[{"name":"sqrtPriceX96","type":"uint160"},{"name":"tick","type":"int24"},{"name":"observationIndex","type":"uint16"},{"name":"observationCardinality","type":"uint16"},{"name":"observationCardinalityNext","type":"uint16"},{"name":"feeProtocol","type":"uint8"},{"name":"unlocked","type":"bool"}],"stateMutability":"view","type":"function"}]`
+)
+
+var q96 = new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 96))
+
+// PoolConfig describes the tokens of a Uniswap pool. Pools quote token0 in
+// token1, Invert quotes token1 in token0 instead.
+type PoolConfig struct {
+	Address   common.Address
+	Decimals0 int
+	Decimals1 int
+	Invert    bool
+}
+
+// UniswapV2Source reads the spot price of a Uniswap V2 pair from its reserves
+type UniswapV2Source struct {
+	contract *bind.BoundContract
+	pool     PoolConfig
+}
+
+func NewUniswapV2Source(caller bind.ContractCaller, pool PoolConfig) (*UniswapV2Source, error) {
+	parsed, err := abi.JSON(strings.NewReader(uniswapV2PairABI))
+	if err != nil {
+		return nil, err
+	}
+	return &UniswapV2Source{
+		contract: bind.NewBoundContract(pool.Address, parsed, caller, nil, nil),
+		pool:     pool,
+	}, nil
+}
+
+func (s *UniswapV2Source) Quote(ctx context.Context) (Quote, error) {
+	var out []interface{}
+	if err := s.contract.Call(&bind.CallOpts{Context: ctx}, &out, "getReserves"); err != nil {
+		return Quote{}, fmt.Errorf("cannot fetch reserves: %w", err)
+	}
+	price := uniswapV2Price(out[0].(*big.Int), out[1].(*big.Int), s.pool)
+	return Quote{Price: price, Timestamp: time.Now()}, nil
+}
+
+// UniswapV3Source reads the spot price of a Uniswap V3 pool from its slot0
+type UniswapV3Source struct {
+	contract *bind.BoundContract
+	pool     PoolConfig
+}
+
+func NewUniswapV3Source(caller bind.ContractCaller, pool PoolConfig) (*UniswapV3Source, error) {
+	parsed, err := abi.JSON(strings.NewReader(uniswapV3PoolABI))
+	if err != nil {
+		return nil, err
+	}
+	return &UniswapV3Source{
+		contract: bind.NewBoundContract(pool.Address, parsed, caller, nil, nil),
+		pool:     pool,
+	}, nil
+}
+
+func (s *UniswapV3Source) Quote(ctx context.Context) (Quote, error) {
+	var out []interface{}
+	if err := s.contract.Call(&bind.CallOpts{Context: ctx}, &out, "slot0"); err != nil {
+		return Quote{}, fmt.Errorf("cannot fetch slot0: %w", err)
+	}
+	price := uniswapV3Price(out[0].(*big.Int), s.pool)
+	return Quote{Price: price, Timestamp: time.Now()}, nil
+}
+
+// uniswapV2Price returns the price of token0 in token1 given the reserves
+func uniswapV2Price(reserve0, reserve1 *big.Int, pool PoolConfig) float64 {
+	if reserve0.Sign() == 0 || reserve1.Sign() == 0 {
+		return 0
+	}
+	price := scaleDown(reserve1, pool.Decimals1) / scaleDown(reserve0, pool.Decimals0)
+	if pool.Invert {
+		return 1 / price
+	}
+	return price
+}
+
+// uniswapV3Price returns the price of token0 in token1 given the square root
+// of the raw price as a Q64.96
+func uniswapV3Price(sqrtPriceX96 *big.Int, pool PoolConfig) float64 {
+	if sqrtPriceX96.Sign() == 0 {
+		return 0
+	}
+	sqrtPrice := new(big.Float).Quo(new(big.Float).SetInt(sqrtPriceX96), q96)
+	raw, _ := new(big.Float).Mul(sqrtPrice, sqrtPrice).Float64()
+	price := raw * math.Pow10(pool.Decimals0-pool.Decimals1)
+	if pool.Invert {
+		return 1 / price
+	}
+	return price
+}
@@ -0,0 +1,100 @@
+package tokenprice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RESTSource reads a price from a JSON HTTP endpoint, such as a CEX ticker
+type RESTSource struct {
+	client        *resty.Client
+	url           string
+	pricePath     []string
+	timestampPath []string
+}
+
+// NewRESTSource creates a RESTSource. The paths are dot separated object keys
+// and array indexes, for example "result.list.0.indexPrice". Timestamps may
+// be in seconds or milliseconds, quotes are timestamped with the query time if
+// timestampPath is empty.
+func NewRESTSource(url, pricePath, timestampPath string) *RESTSource {
+	s := &RESTSource{
+		client:    resty.New(),
+		url:       url,
+		pricePath: strings.Split(pricePath, "."),
+	}
+	if timestampPath != "" {
+		s.timestampPath = strings.Split(timestampPath, ".")
+	}
+	return s
+}
+
+func (s *RESTSource) Quote(ctx context.Context) (Quote, error) {
+	response, err := s.client.R().SetContext(ctx).Get(s.url)
+	if err != nil {
+		return Quote{}, fmt.Errorf("cannot fetch token price result: %w", err)
+	}
+	if response.StatusCode() >= 400 {
+		return Quote{}, fmt.Errorf("%d cannot GET %s: %w", response.StatusCode(), s.url, errHTTPError)
+	}
+	var body interface{}
+	if err := json.Unmarshal(response.Body(), &body); err != nil {
+		return Quote{}, fmt.Errorf("cannot parse result: %w", err)
+	}
+
+	price, err := lookupJSONNumber(body, s.pricePath)
+	if err != nil {
+		return Quote{}, fmt.Errorf("price: %w", err)
+	}
+	quote := Quote{Price: price, Timestamp: time.Now()}
+	if s.timestampPath != nil {
+		ts, err := lookupJSONNumber(body, s.timestampPath)
+		if err != nil {
+			return Quote{}, fmt.Errorf("timestamp: %w", err)
+		}
+		// timestamps past 2286 in seconds are taken as milliseconds
+		if ts > 1e10 {
+			quote.Timestamp = time.UnixMilli(int64(ts))
+		} else {
+			quote.Timestamp = time.Unix(int64(ts), 0)
+		}
+	}
+	return quote, nil
+}
+
+// lookupJSONNumber returns the number at the path of a decoded JSON value.
+// Numbers encoded as strings are supported.
+func lookupJSONNumber(value interface{}, path []string) (float64, error) {
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return 0, fmt.Errorf("missing key %s", key)
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return 0, fmt.Errorf("invalid index %s", key)
+			}
+			value = v[idx]
+		default:
+			return 0, fmt.Errorf("cannot lookup %s in %T", key, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected %T", value)
+	}
+}
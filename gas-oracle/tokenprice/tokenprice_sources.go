@@ -0,0 +1,277 @@
+package tokenprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+var (
+	// ETH is the token of the eth_price
+	ETH = "ETH"
+	// MNT is the token of the mnt_price
+	MNT = "MNT"
+	// USD is the default quote currency of price sources
+	USD = "USD"
+
+	// DefaultSourceTimeout bounds a single query of a price source
+	DefaultSourceTimeout = 5 * time.Second
+	// DefaultMaxDeviation rejects prices deviating more than 10% from the median
+	DefaultMaxDeviation = 0.1
+
+	errNoPrices = errors.New("no valid prices")
+)
+
+// Quote is a price reported by a PriceSource
+type Quote struct {
+	Price float64
+	// Timestamp is when the price was last updated by the source, it is the
+	// query time for sources that don't report it
+	Timestamp time.Time
+}
+
+// PriceSource quotes the price of a token
+type PriceSource interface {
+	Quote(ctx context.Context) (Quote, error)
+}
+
+// SourceOptions describes how the quotes of a source are used
+type SourceOptions struct {
+	Name  string
+	Token string
+	// Quote is the currency of the quotes, USD or ETH. ETH quotes are
+	// converted to USD using the aggregated eth_price.
+	Quote  string
+	Weight float64
+	// Timeout of a single query, DefaultSourceTimeout if not set
+	Timeout time.Duration
+	// MaxStaleness rejects quotes older than this, 0 accepts any quote
+	MaxStaleness time.Duration
+}
+
+// SourceStatus is the last price and health of a source
+type SourceStatus struct {
+	Name       string    `json:"name"`
+	Token      string    `json:"token"`
+	Price      float64   `json:"price"`
+	Healthy    bool      `json:"healthy"`
+	Error      string    `json:"error,omitempty"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+type registeredSource struct {
+	source PriceSource
+	opts   SourceOptions
+
+	mtx    sync.Mutex
+	status SourceStatus
+}
+
+func (s *registeredSource) setStatus(price float64, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.status.Healthy = err == nil
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+	if price != 0 {
+		s.status.Price = price
+		s.status.LastUpdate = time.Now()
+	}
+	metrics.UpdatePriceSource(s.opts.Name, s.status.Price, s.status.Healthy)
+}
+
+// Registry queries the registered sources of a token concurrently, rejects
+// outliers and aggregates the remaining prices by weighted median
+type Registry struct {
+	sources      []*registeredSource
+	maxDeviation float64
+}
+
+// NewRegistry creates an empty Registry, quotes deviating from the median by
+// more than maxDeviation are rejected
+func NewRegistry(maxDeviation float64) *Registry {
+	if maxDeviation <= 0 {
+		maxDeviation = DefaultMaxDeviation
+	}
+	return &Registry{
+		maxDeviation: maxDeviation,
+	}
+}
+
+// Register adds a price source
+func (r *Registry) Register(source PriceSource, opts SourceOptions) error {
+	if opts.Token != ETH && opts.Token != MNT {
+		return fmt.Errorf("source %s: unsupported token %s", opts.Name, opts.Token)
+	}
+	if opts.Quote == "" {
+		opts.Quote = USD
+	}
+	if opts.Quote != USD && opts.Quote != ETH {
+		return fmt.Errorf("source %s: unsupported quote %s", opts.Name, opts.Quote)
+	}
+	if opts.Token == ETH && opts.Quote == ETH {
+		return fmt.Errorf("source %s: eth_price must be quoted in %s", opts.Name, USD)
+	}
+	if opts.Weight <= 0 {
+		opts.Weight = 1
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultSourceTimeout
+	}
+	for _, s := range r.sources {
+		if s.opts.Name == opts.Name {
+			return fmt.Errorf("duplicate source %s", opts.Name)
+		}
+	}
+	r.sources = append(r.sources, &registeredSource{
+		source: source,
+		opts:   opts,
+		status: SourceStatus{Name: opts.Name, Token: opts.Token},
+	})
+	return nil
+}
+
+// Prices returns the aggregated eth_price and mnt_price in USD
+func (r *Registry) Prices(ctx context.Context) (float64, float64, error) {
+	ethPrice, err := r.price(ctx, ETH, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("eth price: %w", err)
+	}
+	mntPrice, err := r.price(ctx, MNT, ethPrice)
+	if err != nil {
+		return 0, ethPrice, fmt.Errorf("mnt price: %w", err)
+	}
+	return ethPrice, mntPrice, nil
+}
+
+// Status returns the last price and health of every source
+func (r *Registry) Status() []SourceStatus {
+	statuses := make([]SourceStatus, 0, len(r.sources))
+	for _, s := range r.sources {
+		s.mtx.Lock()
+		statuses = append(statuses, s.status)
+		s.mtx.Unlock()
+	}
+	return statuses
+}
+
+type weightedPrice struct {
+	source *registeredSource
+	price  float64
+	weight float64
+}
+
+func (r *Registry) price(ctx context.Context, token string, ethPrice float64) (float64, error) {
+	var (
+		wg     sync.WaitGroup
+		mtx    sync.Mutex
+		prices []weightedPrice
+	)
+	for _, s := range r.sources {
+		if s.opts.Token != token {
+			continue
+		}
+		wg.Add(1)
+		go func(s *registeredSource) {
+			defer wg.Done()
+			price, err := r.query(ctx, s, ethPrice)
+			if err != nil {
+				log.Warn("query token price", "source", s.opts.Name, "token", token, "err", err)
+				s.setStatus(price, err)
+				return
+			}
+			mtx.Lock()
+			prices = append(prices, weightedPrice{s, price, s.opts.Weight})
+			mtx.Unlock()
+		}(s)
+	}
+	wg.Wait()
+
+	accepted, rejected := rejectOutliers(prices, r.maxDeviation)
+	for _, p := range rejected {
+		log.Warn("reject outlier token price", "source", p.source.opts.Name, "token", token, "price", p.price)
+		p.source.setStatus(p.price, fmt.Errorf("price deviates more than %v from the median", r.maxDeviation))
+	}
+	for _, p := range accepted {
+		log.Info("query token price", "source", p.source.opts.Name, "token", token, "price", p.price)
+		p.source.setStatus(p.price, nil)
+	}
+	if len(accepted) == 0 {
+		return 0, errNoPrices
+	}
+	return weightedMedian(accepted), nil
+}
+
+func (r *Registry) query(ctx context.Context, s *registeredSource, ethPrice float64) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	quote, err := s.source.Quote(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if quote.Price <= 0 || math.IsNaN(quote.Price) || math.IsInf(quote.Price, 0) {
+		return 0, fmt.Errorf("invalid price %v", quote.Price)
+	}
+	if s.opts.MaxStaleness != 0 && time.Since(quote.Timestamp) > s.opts.MaxStaleness {
+		return quote.Price, fmt.Errorf("stale price, last updated at %v", quote.Timestamp)
+	}
+	if s.opts.Quote == ETH {
+		if ethPrice == 0 {
+			return 0, fmt.Errorf("eth price unavailable")
+		}
+		return quote.Price * ethPrice, nil
+	}
+	return quote.Price, nil
+}
+
+// rejectOutliers splits the prices into the ones within maxDeviation of the
+// weighted median and the others
+func rejectOutliers(prices []weightedPrice, maxDeviation float64) ([]weightedPrice, []weightedPrice) {
+	if len(prices) == 0 {
+		return nil, nil
+	}
+	median := weightedMedian(prices)
+	var accepted, rejected []weightedPrice
+	for _, p := range prices {
+		if math.Abs(p.price-median)/median > maxDeviation {
+			rejected = append(rejected, p)
+		} else {
+			accepted = append(accepted, p)
+		}
+	}
+	return accepted, rejected
+}
+
+// weightedMedian returns the price at which the cumulative weight passes half
+// of the total weight, the upper median for equal weights
+func weightedMedian(prices []weightedPrice) float64 {
+	sorted := make([]weightedPrice, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].price < sorted[j].price
+	})
+
+	var total float64
+	for _, p := range sorted {
+		total += p.weight
+	}
+	var cumulative float64
+	for _, p := range sorted {
+		cumulative += p.weight
+		if cumulative > total/2 {
+			return p.price
+		}
+	}
+	return sorted[len(sorted)-1].price
+}
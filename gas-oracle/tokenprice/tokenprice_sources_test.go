@@ -0,0 +1,212 @@
+package tokenprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	quote Quote
+	err   error
+	delay time.Duration
+}
+
+func (s *fakeSource) Quote(ctx context.Context) (Quote, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return Quote{}, ctx.Err()
+	}
+	return s.quote, s.err
+}
+
+func fresh(price float64) *fakeSource {
+	return &fakeSource{quote: Quote{Price: price, Timestamp: time.Now()}}
+}
+
+func TestRegistryPrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources map[string]*fakeSource
+		opts    map[string]SourceOptions
+		eth     float64
+		mnt     float64
+		healthy map[string]bool
+		err     bool
+	}{
+		{
+			name:    "median",
+			sources: map[string]*fakeSource{"a": fresh(1800), "b": fresh(1810), "c": fresh(1790), "m": fresh(0.5)},
+			eth:     1800,
+			mnt:     0.5,
+			healthy: map[string]bool{"a": true, "b": true, "c": true, "m": true},
+		},
+		{
+			name:    "weighted median",
+			sources: map[string]*fakeSource{"a": fresh(1800), "b": fresh(1810), "m": fresh(0.5)},
+			opts:    map[string]SourceOptions{"a": {Weight: 3}},
+			eth:     1800,
+			mnt:     0.5,
+			healthy: map[string]bool{"a": true, "b": true, "m": true},
+		},
+		{
+			name:    "outlier rejected",
+			sources: map[string]*fakeSource{"a": fresh(1800), "b": fresh(1810), "c": fresh(3000), "m": fresh(0.5)},
+			eth:     1810,
+			mnt:     0.5,
+			healthy: map[string]bool{"a": true, "b": true, "c": false, "m": true},
+		},
+		{
+			name: "errors and timeouts ignored",
+			sources: map[string]*fakeSource{
+				"a": fresh(1800),
+				"b": {err: errors.New("boom")},
+				"c": {quote: Quote{Price: 1900, Timestamp: time.Now()}, delay: time.Second},
+				"m": fresh(0.5),
+			},
+			opts:    map[string]SourceOptions{"c": {Timeout: 10 * time.Millisecond}},
+			eth:     1800,
+			mnt:     0.5,
+			healthy: map[string]bool{"a": true, "b": false, "c": false, "m": true},
+		},
+		{
+			name: "stale price ignored",
+			sources: map[string]*fakeSource{
+				"a": fresh(1800),
+				"b": {quote: Quote{Price: 1900, Timestamp: time.Now().Add(-time.Hour)}},
+				"m": fresh(0.5),
+			},
+			opts:    map[string]SourceOptions{"b": {MaxStaleness: time.Minute}},
+			eth:     1800,
+			mnt:     0.5,
+			healthy: map[string]bool{"a": true, "b": false, "m": true},
+		},
+		{
+			name:    "eth quoted mnt price",
+			sources: map[string]*fakeSource{"a": fresh(2000), "m": fresh(0.0003)},
+			opts:    map[string]SourceOptions{"m": {Quote: ETH}},
+			eth:     2000,
+			mnt:     0.6,
+			healthy: map[string]bool{"a": true, "m": true},
+		},
+		{
+			name:    "no eth price",
+			sources: map[string]*fakeSource{"a": {err: errors.New("boom")}, "m": fresh(0.5)},
+			err:     true,
+			healthy: map[string]bool{"a": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry(0)
+			for name, source := range tt.sources {
+				opts := tt.opts[name]
+				opts.Name = name
+				opts.Token = ETH
+				if name == "m" {
+					opts.Token = MNT
+				}
+				require.NoError(t, registry.Register(source, opts))
+			}
+
+			eth, mnt, err := registry.Prices(context.Background())
+			if tt.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.eth, eth)
+				require.InDelta(t, tt.mnt, mnt, 1e-9)
+			}
+			for _, status := range registry.Status() {
+				if healthy, ok := tt.healthy[status.Name]; ok {
+					require.Equal(t, healthy, status.Healthy, status.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryMedian(t *testing.T) {
+	tests := []struct {
+		prices []float64
+		median float64
+	}{
+		{[]float64{0, 0, 0}, 0},
+		{[]float64{1.1, 0, 0}, 1.1},
+		{[]float64{1.1, 2.1, 0}, 2.1},
+		{[]float64{2.1, 1.1}, 2.1},
+		{[]float64{1.1, 2.1, 3.1}, 2.1},
+		{[]float64{1.1, 3.1, 2.1}, 2.1},
+		{[]float64{1.1, 3.1, 2.1, 4.1}, 3.1},
+	}
+
+	for _, tt := range tests {
+		// zero prices are invalid and ignored, the deviation is wide enough
+		// to accept all the others
+		registry := NewRegistry(10)
+		require.NoError(t, registry.Register(fresh(0.5), SourceOptions{Name: "mnt", Token: MNT}))
+		for i, price := range tt.prices {
+			require.NoError(t, registry.Register(fresh(price), SourceOptions{Name: fmt.Sprint(i), Token: ETH}))
+		}
+		eth, _, err := registry.Prices(context.Background())
+		if tt.median == 0 {
+			require.ErrorIs(t, err, errNoPrices, tt.prices)
+			continue
+		}
+		require.NoError(t, err, tt.prices)
+		require.Equal(t, tt.median, eth, tt.prices)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry(0)
+	require.NoError(t, registry.Register(fresh(1), SourceOptions{Name: "a", Token: ETH}))
+	require.Error(t, registry.Register(fresh(1), SourceOptions{Name: "a", Token: MNT}))
+	require.Error(t, registry.Register(fresh(1), SourceOptions{Name: "b", Token: "BTC"}))
+	require.Error(t, registry.Register(fresh(1), SourceOptions{Name: "c", Token: ETH, Quote: ETH}))
+}
+
+func TestRESTSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"result":{"list":[{"indexPrice":"1834.5"}]},"price":0.61,"time":1700000000000}`))
+	}))
+	defer server.Close()
+
+	quote, err := NewRESTSource(server.URL, "result.list.0.indexPrice", "time").Quote(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1834.5, quote.Price)
+	require.Equal(t, time.UnixMilli(1700000000000), quote.Timestamp)
+
+	quote, err = NewRESTSource(server.URL, "price", "").Quote(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0.61, quote.Price)
+
+	_, err = NewRESTSource(server.URL, "result.list.1.indexPrice", "").Quote(context.Background())
+	require.Error(t, err)
+}
+
+func TestUniswapPoolPrices(t *testing.T) {
+	// 1 WETH (18 decimals) = 1800 USDT (6 decimals)
+	pool := PoolConfig{Decimals0: 18, Decimals1: 6}
+	reserve0, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	reserve1 := big.NewInt(1800000000000)
+	require.InDelta(t, 1800, uniswapV2Price(reserve0, reserve1, pool), 1e-6)
+
+	pool.Invert = true
+	require.InDelta(t, 1.0/1800, uniswapV2Price(reserve0, reserve1, pool), 1e-12)
+
+	// sqrt(1800e6 / 1e18) * 2^96
+	sqrtPriceX96, _ := new(big.Int).SetString("3361366258487168395123916", 10)
+	pool.Invert = false
+	require.InDelta(t, 1800, uniswapV3Price(sqrtPriceX96, pool), 0.01)
+	require.Zero(t, uniswapV3Price(big.NewInt(0), pool))
+}
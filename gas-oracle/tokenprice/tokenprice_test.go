@@ -1,6 +1,7 @@
 package tokenprice
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -17,7 +18,8 @@ func TestGetTokenPrice(t *testing.T) {
 	t.Logf("BIT price:%v", bitPrice)
 
 	t.Logf("ratio:%v", ethPrice/bitPrice)
-	bitPrice, ethPrice = tokenPricer.getTokenPricesFromCex()
+	ethPrice, bitPrice, err = tokenPricer.Sources().Prices(context.Background())
+	require.NoError(t, err)
 	t.Logf("ETH price:%v", ethPrice)
 	t.Logf("BIT price:%v", bitPrice)
 	t.Logf("ratio:%v", ethPrice/bitPrice)
@@ -54,8 +56,8 @@ func TestGetTokenPriceWithOneDollarTokenRatioMode(t *testing.T) {
 func TestGetTokenPriceWithOneDollarTokenRatioMode2(t *testing.T) {
 	tokenPricer := NewClient("", "https://mainnet.infura.io/v3/4f4692085f1340c2a645ae04d36c2321", 3, 1, false)
 
-	_, ethPrice := tokenPricer.getTokenPricesFromUniswap()
-	t.Logf("ETH price:%v", ethPrice)
+	ethPrice, _, err := tokenPricer.Sources().Prices(context.Background())
+	t.Logf("ETH price:%v, err:%v", ethPrice, err)
 
 	ratio, err := tokenPricer.PriceRatioWithMode()
 	require.NoError(t, err)
@@ -117,29 +119,6 @@ func TestGetTokenPriceWithMNT(t *testing.T) {
 	t.Logf("ratio:%v", ratio)
 }
 
-func Test_getMedian(t *testing.T) {
-	result := getMedian([]float64{0, 0, 0})
-	require.Equal(t, float64(0), result)
-
-	result = getMedian([]float64{1.1, 0, 0})
-	require.Equal(t, 1.1, result)
-
-	result = getMedian([]float64{1.1, 2.1, 0})
-	require.Equal(t, 2.1, result)
-
-	result = getMedian([]float64{2.1, 1.1})
-	require.Equal(t, 2.1, result)
-
-	result = getMedian([]float64{1.1, 2.1, 3.1})
-	require.Equal(t, 2.1, result)
-
-	result = getMedian([]float64{1.1, 3.1, 2.1})
-	require.Equal(t, 2.1, result)
-
-	result = getMedian([]float64{1.1, 3.1, 2.1, 4.1})
-	require.Equal(t, 3.1, result)
-}
-
 func Test_getMax(t *testing.T) {
 	result := getMax(1.1, 2.1)
 	require.Equal(t, 2.1, result)
@@ -1,12 +1,14 @@
 package tokenprice
 
 import (
+	"context"
 	"math"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
 )
@@ -63,33 +65,40 @@ func newUniswapClient(uniswapURL string, tokenPairMNTMode bool) (*uniswapClient,
 	return quoterClient, nil
 }
 
-func (c *Client) getTokenPricesFromUniswap() (float64, float64) {
+// uniswapQuoterSource quotes 1 from_token in to_token through the Uniswap V3
+// quoter, Invert quotes 1 to_token in from_token instead
+type uniswapQuoterSource struct {
+	c         *Client
+	fromToken common.Address
+	toToken   common.Address
+	decimals  *big.Float
+	invert    bool
+}
 
-	eth2mntPrice, err := c.getTokenPriceFromUniswap(c.uniswapQuoterClient.ethAddress,
-		c.uniswapQuoterClient.mntAddress, c.uniswapQuoterClient.mntDecimals)
+func (s *uniswapQuoterSource) Quote(ctx context.Context) (Quote, error) {
+	price, err := s.c.getTokenPriceFromUniswapContext(ctx, s.fromToken, s.toToken, s.decimals)
 	if err != nil {
-		log.Warn("get token prices from dex", "query eth/mnt error", err)
-		return 0, 0
+		return Quote{}, err
 	}
-	eth2usdtPrice, err := c.getTokenPriceFromUniswap(c.uniswapQuoterClient.ethAddress,
-		c.uniswapQuoterClient.usdttAddress, c.uniswapQuoterClient.usdtDecimals)
-	if err != nil {
-		log.Warn("get token prices from dex", "query eth/usdt error", err)
-		return 0, eth2mntPrice
+	if s.invert && price != 0 {
+		price = 1 / price
 	}
-
-	return eth2usdtPrice / eth2mntPrice, eth2usdtPrice
+	return Quote{Price: price, Timestamp: time.Now()}, nil
 }
 
 // getTokenPriceFromUniswap estimate to execute swapping from_token to to_token to get token price
 func (c *Client) getTokenPriceFromUniswap(fromToken, toToken common.Address, decimals *big.Float) (float64, error) {
+	return c.getTokenPriceFromUniswapContext(context.Background(), fromToken, toToken, decimals)
+}
+
+func (c *Client) getTokenPriceFromUniswapContext(ctx context.Context, fromToken, toToken common.Address, decimals *big.Float) (float64, error) {
 	fee := big.NewInt(3000)
 	fromAmount := floatStringToBigInt("1.00", 18)
 	sqrtPriceLimitX96 := big.NewInt(0)
 
 	var out []interface{}
 	rawCaller := &bindings.Uniswapv3QuoterRaw{Contract: c.uniswapQuoterClient.uniswapV3Quoter}
-	err := rawCaller.Call(nil, &out, "quoteExactInputSingle", fromToken, toToken,
+	err := rawCaller.Call(&bind.CallOpts{Context: ctx}, &out, "quoteExactInputSingle", fromToken, toToken,
 		fee, fromAmount, sqrtPriceLimitX96)
 	if err != nil {
 		return 0, err
@@ -1,8 +1,10 @@
 package tokenprice
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"time"
 )
 
 var (
@@ -28,8 +30,27 @@ type Item struct {
 	IndexPrice string `json:"indexPrice"`
 }
 
+// v5Source quotes a symbol through the v5 ticker endpoint of the price backend
+type v5Source struct {
+	c      *Client
+	symbol string
+}
+
+func (s *v5Source) Quote(ctx context.Context) (Quote, error) {
+	price, err := s.c.queryV5Context(ctx, s.symbol)
+	if err != nil {
+		return Quote{}, err
+	}
+	return Quote{Price: price, Timestamp: time.Now()}, nil
+}
+
 func (c *Client) queryV5(symbol string) (float64, error) {
+	return c.queryV5Context(context.Background(), symbol)
+}
+
+func (c *Client) queryV5Context(ctx context.Context, symbol string) (float64, error) {
 	response, err := c.client.R().
+		SetContext(ctx).
 		SetResult(&Response{}).
 		SetQueryParams(map[string]string{
 			"symbol": symbol,
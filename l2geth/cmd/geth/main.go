@@ -268,6 +268,7 @@ func init() {
 	app.Flags = append(app.Flags, nodeFlags...)
 	// UsingBVM
 	app.Flags = append(app.Flags, mantleFlags...)
+	app.Flags = append(app.Flags, fpcmd.SignerFlags...)
 	app.Flags = append(app.Flags, rpcFlags...)
 	app.Flags = append(app.Flags, consoleFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/Layr-Labs/datalayr/common/logging"
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/mt-batcher/flags"
 	"github.com/urfave/cli"
 )
@@ -69,6 +70,8 @@ type Config struct {
 	HsmFeeAddress             string
 	MinTimeoutRollupTxn       uint64
 	RollupTimeout             time.Duration
+	Signer                    signer.Config
+	FeeSigner                 signer.Config
 }
 
 func NewConfig(ctx *cli.Context) (Config, error) {
@@ -132,6 +135,8 @@ func NewConfig(ctx *cli.Context) (Config, error) {
 		HsmFeeAddress:             ctx.GlobalString(flags.HsmFeeAddressFlag.Name),
 		MinTimeoutRollupTxn:       ctx.GlobalUint64(flags.MinTimeoutRollupTxnFlag.Name),
 		RollupTimeout:             ctx.GlobalDuration(flags.RollupTimeoutFlag.Name),
+		Signer:                    signer.ReadCLIConfig(ctx),
+		FeeSigner:                 signer.ReadPrefixedCLIConfig(ctx, "fee."),
 	}
 	return cfg, nil
 }
@@ -3,6 +3,7 @@ package flags
 import (
 	"time"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/urfave/cli"
 )
 
@@ -69,10 +70,9 @@ var (
 		EnvVar:   prefixEnvVar(envVarPrefix, "GRAPH_PROVIDER"),
 	}
 	PrivateKeyFlag = cli.StringFlag{
-		Name:     "private",
-		Usage:    "Ethereum private key for node operator",
-		Required: true,
-		EnvVar:   prefixEnvVar(envVarPrefix, "PRIVATE_KEY"),
+		Name:   "private",
+		Usage:  "Ethereum private key for node operator, unused if signer.type is set",
+		EnvVar: prefixEnvVar(envVarPrefix, "PRIVATE_KEY"),
 	}
 	MnemonicFlag = cli.StringFlag{
 		Name: "mnemonic",
@@ -87,10 +87,9 @@ var (
 		EnvVar: prefixEnvVar(envVarPrefix, "SEQUENCER_HD_PATH"),
 	}
 	FeePrivateKeyFlag = cli.StringFlag{
-		Name:     "fee-private",
-		Usage:    "Ethereum private key for mt fee",
-		Required: true,
-		EnvVar:   prefixEnvVar(envVarPrefix, "FEE_PRIVATE_KEY"),
+		Name:   "fee-private",
+		Usage:  "Ethereum private key for mt fee, unused if fee.signer.type is set",
+		EnvVar: prefixEnvVar(envVarPrefix, "FEE_PRIVATE_KEY"),
 	}
 	FeeMnemonicFlag = cli.StringFlag{
 		Name:   "fee-mnemonic",
@@ -418,8 +417,15 @@ var optionalFlags = []cli.Flag{
 	HsmFeeAPINameFlag,
 }
 
+// signerFlags configure the sequencer and fee signers, they take precedence
+// over the private key, mnemonic and hsm flags above.
+var signerFlags = append(
+	signer.CLIFlags(envVarPrefix),
+	signer.PrefixedCLIFlags("fee.", prefixEnvVar(envVarPrefix, "FEE"))...,
+)
+
 func init() {
-	Flags = append(requiredFlags, optionalFlags...)
+	Flags = append(append(requiredFlags, optionalFlags...), signerFlags...)
 }
 
 var Flags []cli.Flag
@@ -10,6 +10,7 @@ import (
 	ethc "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/l2geth/common"
 	"github.com/mantlenetworkio/mantle/mt-batcher/bindings"
 	"github.com/mantlenetworkio/mantle/mt-batcher/l1l2client"
@@ -57,18 +58,12 @@ type MantleBatch struct {
 func NewMantleBatch(cfg Config) (*MantleBatch, error) {
 	ctx := context.Background()
 
-	sequencerPrivKey, _, err := common2.ParseWalletPrivKeyAndContractAddr(
-		"MtBatcher", cfg.Mnemonic, cfg.SequencerHDPath,
-		cfg.PrivateKey, cfg.EigenContractAddress, cfg.Passphrase,
-	)
+	sequencerSigner, err := cfg.newSequencerSigner(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	mtFeePrivateKey, _, err := common2.ParseWalletPrivKeyAndContractAddr(
-		"MtBatcher", cfg.FeeMnemonic, cfg.FeeHDPath,
-		cfg.FeePrivateKey, cfg.EigenFeeContractAddress, cfg.Passphrase,
-	)
+	feeSigner, err := cfg.newFeeSigner(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -147,8 +142,8 @@ func NewMantleBatch(cfg Config) (*MantleBatch, error) {
 		FeeSizeSec:                cfg.FeeSizeSec,
 		FeePerBytePerTime:         cfg.FeePerBytePerTime,
 		Logger:                    logger,
-		PrivKey:                   sequencerPrivKey,
-		FeePrivKey:                mtFeePrivateKey,
+		Signer:                    sequencerSigner,
+		FeeSigner:                 feeSigner,
 		BlockOffset:               cfg.BlockOffset,
 		RollUpMinTxn:              cfg.RollUpMinTxn,
 		RollUpMaxSize:             cfg.RollUpMaxSize,
@@ -168,12 +163,6 @@ func NewMantleBatch(cfg Config) (*MantleBatch, error) {
 		NumConfirmations:          cfg.NumConfirmations,
 		SafeAbortNonceTooLowCount: cfg.SafeAbortNonceTooLowCount,
 		Metrics:                   metrics.NewMtBatchBase(),
-		EnableHsm:                 cfg.EnableHsm,
-		HsmAddress:                cfg.HsmAddress,
-		HsmAPIName:                cfg.HsmAPIName,
-		HsmCreden:                 cfg.HsmCreden,
-		HsmFeeAPIName:             cfg.HsmFeeAPIName,
-		HsmFeeAddress:             cfg.HsmFeeAddress,
 		MinTimeoutRollupTxn:       cfg.MinTimeoutRollupTxn,
 		RollupTimeout:             cfg.RollupTimeout,
 	}
@@ -181,9 +170,10 @@ func NewMantleBatch(cfg Config) (*MantleBatch, error) {
 		log.Error("new driver fail", "err", "config value error : MinTimeoutRollupTxn should less than RollUpMinTxn  MinTimeoutRollupTxn(%v)>RollUpMinTxn(%v)", cfg.MinTimeoutRollupTxn, cfg.RollUpMinTxn)
 		return nil, errors.New("config value error : MinTimeoutRollupTxn should less than RollUpMinTxn")
 	}
-	log.Debug("hsm",
-		"enablehsm", driverConfig.EnableHsm, "hsmaddress", driverConfig.HsmAddress,
-		"hsmapiname", driverConfig.HsmAPIName, "HsmFeeAPIName", driverConfig.HsmFeeAPIName, "HsmFeeAddress", driverConfig.HsmFeeAddress)
+	log.Debug("signers",
+		"address", sequencerSigner.Address(), "type", cfg.Signer.Type,
+		"fee_address", feeSigner.Address(), "fee_type", cfg.FeeSigner.Type,
+		"enablehsm", cfg.EnableHsm)
 	driver, err := sequencer.NewDriver(ctx, driverConfig)
 	if err != nil {
 		log.Error("new driver fail", "err", err)
@@ -231,3 +221,42 @@ func (mb *MantleBatch) Stop() {
 	mb.daService.Stop()
 	mb.sequencerDriver.Stop()
 }
+
+// newSequencerSigner returns the signer used for data store and confirm
+// transactions. The signer flags take precedence over the hsm and private key
+// flags, which are kept for existing deployments.
+func (c Config) newSequencerSigner(ctx context.Context) (signer.Signer, error) {
+	switch {
+	case c.Signer.Enabled():
+		return signer.NewSigner(ctx, c.Signer)
+	case c.EnableHsm:
+		return signer.NewKMSSigner(ctx, c.HsmAPIName, c.HsmAddress, c.HsmCreden)
+	}
+	privKey, _, err := common2.ParseWalletPrivKeyAndContractAddr(
+		"MtBatcher", c.Mnemonic, c.SequencerHDPath,
+		c.PrivateKey, c.EigenContractAddress, c.Passphrase,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(privKey), nil
+}
+
+// newFeeSigner returns the signer used for rollup fee transactions, see
+// newSequencerSigner.
+func (c Config) newFeeSigner(ctx context.Context) (signer.Signer, error) {
+	switch {
+	case c.FeeSigner.Enabled():
+		return signer.NewSigner(ctx, c.FeeSigner)
+	case c.EnableHsm:
+		return signer.NewKMSSigner(ctx, c.HsmFeeAPIName, c.HsmFeeAddress, c.HsmCreden)
+	}
+	privKey, _, err := common2.ParseWalletPrivKeyAndContractAddr(
+		"MtBatcher", c.FeeMnemonic, c.FeeHDPath,
+		c.FeePrivateKey, c.EigenFeeContractAddress, c.Passphrase,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(privKey), nil
+}
@@ -1,18 +1,14 @@
 package common
 
 import (
-	"context"
 	"crypto/ecdsa"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 
-	kms "cloud.google.com/go/kms/apiv1"
 	"github.com/decred/dcrd/hdkeychain/v3"
 	"github.com/tyler-smith/go-bip39"
-	"google.golang.org/api/option"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -20,8 +16,6 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
-
-	bsscore "github.com/mantlenetworkio/mantle/bss-core"
 )
 
 var (
@@ -132,19 +126,3 @@ func PrivateKeySignerFn(key *ecdsa.PrivateKey, chainID *big.Int) bind.SignerFn {
 		return tx.WithSignature(signer, signature)
 	}
 }
-
-func NewHSMTransactOpts(ctx context.Context, hsmAPIName string, hsmAddress string, chainID *big.Int, hsmCreden string) (*bind.TransactOpts, error) {
-	proBytes, err := hex.DecodeString(hsmCreden)
-	apikey := option.WithCredentialsJSON(proBytes)
-	client, err := kms.NewKeyManagementClient(ctx, apikey)
-	if err != nil {
-		return nil, err
-	}
-	mk := &bsscore.ManagedKey{
-		KeyName:      hsmAPIName,
-		EthereumAddr: common.HexToAddress(hsmAddress),
-		Gclient:      client,
-	}
-	opts, err := mk.NewEthereumTransactorrWithChainID(ctx, chainID)
-	return opts, nil
-}
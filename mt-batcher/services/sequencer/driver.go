@@ -3,7 +3,6 @@ package sequencer
 import (
 	"bytes"
 	"context"
-	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -22,10 +21,10 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	l2gethcommon "github.com/mantlenetworkio/mantle/l2geth/common"
 	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
 	l2rlp "github.com/mantlenetworkio/mantle/l2geth/rlp"
@@ -56,8 +55,8 @@ type DriverConfig struct {
 	EigenFeeContract          *bindings.BVMEigenDataLayrFee
 	RawEigenFeeContract       *bind.BoundContract
 	Logger                    *logging.Logger
-	PrivKey                   *ecdsa.PrivateKey
-	FeePrivKey                *ecdsa.PrivateKey
+	Signer                    signer.Signer
+	FeeSigner                 signer.Signer
 	BlockOffset               uint64
 	RollUpMinTxn              uint64
 	RollUpMaxSize             uint64
@@ -83,13 +82,6 @@ type DriverConfig struct {
 	MinTimeoutRollupTxn       uint64
 	RollupTimeout             time.Duration
 	Metrics                   metrics.MtBatchMetrics
-
-	EnableHsm     bool
-	HsmAddress    string
-	HsmFeeAddress string
-	HsmAPIName    string
-	HsmFeeAPIName string
-	HsmCreden     string
 }
 
 type FeePipline struct {
@@ -133,19 +125,11 @@ func NewDriver(ctx context.Context, cfg *DriverConfig) (*Driver, error) {
 		return nil, err
 	}
 	dtlClient := client.NewDtlClient(cfg.DtlClientUrl)
-	var walletAddr, feeWalletAddr common.Address
-	if cfg.EnableHsm {
-		walletAddr = common.HexToAddress(cfg.HsmAddress)
-		feeWalletAddr = common.HexToAddress(cfg.HsmFeeAddress)
-	} else {
-		walletAddr = crypto.PubkeyToAddress(cfg.PrivKey.PublicKey)
-		feeWalletAddr = crypto.PubkeyToAddress(cfg.FeePrivKey.PublicKey)
-	}
 	return &Driver{
 		Cfg:           cfg,
 		Ctx:           ctx,
-		WalletAddr:    walletAddr,
-		FeeWalletAddr: feeWalletAddr,
+		WalletAddr:    cfg.Signer.Address(),
+		FeeWalletAddr: cfg.FeeSigner.Address(),
 		GraphClient:   graphClient,
 		DtlClient:     dtlClient,
 		txMgr:         txMgr,
@@ -160,14 +144,7 @@ func (d *Driver) UpdateGasPrice(ctx context.Context, tx *types.Transaction, feeM
 	var err error
 	var opts *bind.TransactOpts
 	if feeModelEnable {
-		if !d.Cfg.EnableHsm {
-			opts, err = bind.NewKeyedTransactorWithChainID(
-				d.Cfg.FeePrivKey, d.Cfg.L1ChainID,
-			)
-		} else {
-			opts, err = common4.NewHSMTransactOpts(ctx, d.Cfg.HsmFeeAPIName,
-				d.Cfg.HsmFeeAddress, d.Cfg.L1ChainID, d.Cfg.HsmCreden)
-		}
+		opts, err = signer.NewTransactOpts(ctx, d.Cfg.FeeSigner, d.Cfg.L1ChainID)
 		if err != nil {
 			return nil, err
 		}
@@ -175,14 +152,7 @@ func (d *Driver) UpdateGasPrice(ctx context.Context, tx *types.Transaction, feeM
 		opts.Nonce = new(big.Int).SetUint64(tx.Nonce())
 		opts.NoSend = true
 	} else {
-		if !d.Cfg.EnableHsm {
-			opts, err = bind.NewKeyedTransactorWithChainID(
-				d.Cfg.PrivKey, d.Cfg.L1ChainID,
-			)
-		} else {
-			opts, err = common4.NewHSMTransactOpts(ctx, d.Cfg.HsmAPIName,
-				d.Cfg.HsmAddress, d.Cfg.L1ChainID, d.Cfg.HsmCreden)
-		}
+		opts, err = signer.NewTransactOpts(ctx, d.Cfg.Signer, d.Cfg.L1ChainID)
 		if err != nil {
 			return nil, err
 		}
@@ -340,15 +310,7 @@ func (d *Driver) StoreData(ctx context.Context, uploadHeader []byte, duration ui
 		return nil, err
 	}
 	nonce := new(big.Int).SetUint64(nonce64)
-	var opts *bind.TransactOpts
-	if !d.Cfg.EnableHsm {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			d.Cfg.PrivKey, d.Cfg.L1ChainID,
-		)
-	} else {
-		opts, err = common4.NewHSMTransactOpts(ctx, d.Cfg.HsmAPIName,
-			d.Cfg.HsmAddress, d.Cfg.L1ChainID, d.Cfg.HsmCreden)
-	}
+	opts, err := signer.NewTransactOpts(ctx, d.Cfg.Signer, d.Cfg.L1ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -389,15 +351,7 @@ func (d *Driver) ConfirmData(ctx context.Context, callData []byte, searchData rc
 	}
 	d.Cfg.Metrics.MtBatchNonce().Set(float64(nonce64))
 	nonce := new(big.Int).SetUint64(nonce64)
-	var opts *bind.TransactOpts
-	if !d.Cfg.EnableHsm {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			d.Cfg.PrivKey, d.Cfg.L1ChainID,
-		)
-	} else {
-		opts, err = common4.NewHSMTransactOpts(ctx, d.Cfg.HsmAPIName,
-			d.Cfg.HsmAddress, d.Cfg.L1ChainID, d.Cfg.HsmCreden)
-	}
+	opts, err := signer.NewTransactOpts(ctx, d.Cfg.Signer, d.Cfg.L1ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -635,15 +589,7 @@ func (d *Driver) UpdateFee(ctx context.Context, l2Block, daFee *big.Int) (*types
 	}
 	d.Cfg.Metrics.MtFeeNonce().Set(float64(nonce64))
 	nonce := new(big.Int).SetUint64(nonce64)
-	var opts *bind.TransactOpts
-	if !d.Cfg.EnableHsm {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			d.Cfg.FeePrivKey, d.Cfg.L1ChainID,
-		)
-	} else {
-		opts, err = common4.NewHSMTransactOpts(ctx, d.Cfg.HsmFeeAPIName,
-			d.Cfg.HsmFeeAddress, d.Cfg.L1ChainID, d.Cfg.HsmCreden)
-	}
+	opts, err := signer.NewTransactOpts(ctx, d.Cfg.FeeSigner, d.Cfg.L1ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -1,10 +1,7 @@
 package challenger
 
 import (
-	"bytes"
 	"context"
-	"crypto/ecdsa"
-	"encoding/hex"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -27,16 +24,12 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethc "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/rlp"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/l2geth/common"
-	l2types "github.com/mantlenetworkio/mantle/l2geth/core/types"
 	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
-	l2rlp "github.com/mantlenetworkio/mantle/l2geth/rlp"
-	"github.com/mantlenetworkio/mantle/l2geth/rollup/eigenda"
 	common4 "github.com/mantlenetworkio/mantle/mt-batcher/services/common"
 	"github.com/mantlenetworkio/mantle/mt-batcher/txmgr"
 	"github.com/mantlenetworkio/mantle/mt-challenger/bindings"
@@ -46,8 +39,6 @@ import (
 	"github.com/mantlenetworkio/mantle/mt-challenger/metrics"
 )
 
-const fraudString = "2d5f2860204f2060295f2d202d5f2860206f2060295f2d202d5f286020512060295f2d2042495444414f204a5553542052454b5420594f55207c5f2860204f2060295f7c202d207c5f2860206f2060295f7c202d207c5f286020512060295f7c"
-
 type SignerFn func(context.Context, ethc.Address, *types.Transaction) (*types.Transaction, error)
 
 var (
@@ -67,10 +58,6 @@ type KzgConfig struct {
 	Order     uint64 // Order is the total size of SRS
 }
 
-type Fraud struct {
-	StartingIndex int
-}
-
 type DataLayrDisclosureProof struct {
 	Header                    []byte
 	Polys                     [][]byte
@@ -89,8 +76,9 @@ type ChallengerConfig struct {
 	L2Client                  *l2ethclient.Client
 	L1ChainID                 *big.Int
 	EigenContractAddr         ethc.Address
+	CTCAddr                   ethc.Address
 	Logger                    *logging.Logger
-	PrivKey                   *ecdsa.PrivateKey
+	Signer                    signer.Signer
 	GraphProvider             string
 	RetrieverSocket           string
 	DtlClientUrl              string
@@ -111,11 +99,6 @@ type ChallengerConfig struct {
 	NumConfirmations          uint64
 	SafeAbortNonceTooLowCount uint64
 	Metrics                   metrics.ChallengerMetrics
-
-	EnableHsm  bool
-	HsmAPIName string
-	HsmCreden  string
-	HsmAddress string
 }
 
 type Challenger struct {
@@ -128,6 +111,7 @@ type Challenger struct {
 	GraphClient      *graphView.GraphClient
 	GraphqlClient    *graphql.Client
 	DtlEigenClient   client.DtlClient
+	Validator        *BatchValidator
 	LevelDBStore     *db.Store
 	txMgr            txmgr.TxManager
 	cancel           func()
@@ -172,12 +156,6 @@ func NewChallenger(ctx context.Context, cfg *ChallengerConfig) (*Challenger, err
 
 	graphClient := graphView.NewGraphClient(cfg.GraphProvider, cfg.Logger)
 	graphqlClient := graphql.NewClient(graphClient.GetEndpoint(), nil)
-	var walletAddr ethc.Address
-	if cfg.EnableHsm {
-		walletAddr = ethc.HexToAddress(cfg.HsmAddress)
-	} else {
-		walletAddr = crypto.PubkeyToAddress(cfg.PrivKey.PublicKey)
-	}
 
 	levelDBStore, err := db.NewStore(cfg.DbPath)
 	if err != nil {
@@ -188,16 +166,22 @@ func NewChallenger(ctx context.Context, cfg *ChallengerConfig) (*Challenger, err
 	if dtlEigenClient == nil {
 		return nil, fmt.Errorf("MtChallenger new eigen client fail")
 	}
+	ctcQueue, err := NewCTCQueue(cfg.CTCAddr, cfg.L1Client)
+	if err != nil {
+		log.Error("MtChallenger init ctc queue fail", "err", err)
+		return nil, err
+	}
 	return &Challenger{
 		Cfg:              cfg,
 		Ctx:              ctx,
 		EigenDaContract:  eigenContract,
 		RawEigenContract: rawEigenContract,
-		WalletAddr:       walletAddr,
+		WalletAddr:       cfg.Signer.Address(),
 		EigenABI:         eignenABI,
 		GraphClient:      graphClient,
 		GraphqlClient:    graphqlClient,
 		DtlEigenClient:   dtlEigenClient,
+		Validator:        NewBatchValidator(cfg.L2Client, ctcQueue),
 		LevelDBStore:     levelDBStore,
 		txMgr:            txMgr,
 		cancel:           cancel,
@@ -262,14 +246,14 @@ func (c *Challenger) callRetrieve(store *graphView.DataStore) ([]byte, []datalay
 	return data, frames, nil
 }
 
-// check if the fraud string exists within the data
-func (c *Challenger) checkForFraud(store *graphView.DataStore, data []byte) (*Fraud, bool) {
-	dataString := hex.EncodeToString(data)
-	index := strings.Index(dataString, fraudString)
-	if index != -1 {
-		return &Fraud{StartingIndex: index / 2}, true
+// checkForFraud validates the data of a store against the canonical L2 chain
+// over the L2 block range it was stored for.
+func (c *Challenger) checkForFraud(store *graphView.DataStore, data []byte) (*Fraud, error) {
+	rollupBlock, err := c.EigenDaContract.DataStoreIdToL2RollUpBlock(&bind.CallOpts{}, store.StoreNumber)
+	if err != nil {
+		return nil, err
 	}
-	return nil, false
+	return c.Validator.Validate(c.Ctx, data, rollupBlock.StartL2BlockNumber, rollupBlock.EndBL2BlockNumber)
 }
 
 func (c *Challenger) constructFraudProof(store *graphView.DataStore, data []byte, fraud *Fraud, frames []datalayr.Frame) (*FraudProof, error) {
@@ -289,8 +273,8 @@ func (c *Challenger) constructFraudProof(store *graphView.DataStore, data []byte
 	//there are 31 bytes per fr so there are 31*chunkLenE bytes in each chunk
 	//so the i'th byte starts at the (i/(31*encoder.EncodingParams.ChunkLenE))'th chunk
 	startingChunkIndex := fraud.StartingIndex / int(31*header.Degree)
-	//the fraud string ends len(fraudString)/2 bytes later
-	endingChunkIndex := (fraud.StartingIndex + len(fraudString)/2) / int(31*header.Degree)
+	//the fraud ends fraud.Length bytes later
+	endingChunkIndex := (fraud.StartingIndex + fraud.Length - 1) / int(31*header.Degree)
 	startingSymbolIndex := fraud.StartingIndex % int(31*header.Degree)
 	//do some math to shift this over by the correct number of bytes
 	//there are 32 bytes in the actual poly for every 31 bytes in the data, hence (startingSymbolIndex/31)*32
@@ -301,6 +285,9 @@ func (c *Challenger) constructFraudProof(store *graphView.DataStore, data []byte
 	if startingChunkIndex > len(frames) {
 		return nil, fmt.Errorf("startingChunkIndex is out of frames range, startingChunkIndex: %d, len(frames): %d", startingChunkIndex, len(frames))
 	}
+	if endingChunkIndex >= len(frames) {
+		return nil, fmt.Errorf("endingChunkIndex is out of frames range, endingChunkIndex: %d, len(frames): %d", endingChunkIndex, len(frames))
+	}
 
 	//generate parameters for proving data on chain
 	//this is
@@ -352,16 +339,7 @@ func (fp *DataLayrDisclosureProof) ToDisclosureProofs() rc.BVMEigenDataLayrChain
 }
 
 func (c *Challenger) UpdateGasPrice(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
-	var opts *bind.TransactOpts
-	var err error
-	if !c.Cfg.EnableHsm {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			c.Cfg.PrivKey, c.Cfg.L1ChainID,
-		)
-	} else {
-		opts, err = common4.NewHSMTransactOpts(ctx, c.Cfg.HsmAPIName,
-			c.Cfg.HsmAddress, c.Cfg.L1ChainID, c.Cfg.HsmCreden)
-	}
+	opts, err := signer.NewTransactOpts(ctx, c.Cfg.Signer, c.Cfg.L1ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -411,15 +389,7 @@ func (c *Challenger) ChallengeProveFraud(ctx context.Context, fraudStoreNumber *
 		return nil, err
 	}
 	nonce := new(big.Int).SetUint64(nonce64)
-	var opts *bind.TransactOpts
-	if !c.Cfg.EnableHsm {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			c.Cfg.PrivKey, c.Cfg.L1ChainID,
-		)
-	} else {
-		opts, err = common4.NewHSMTransactOpts(ctx, c.Cfg.HsmAPIName,
-			c.Cfg.HsmAddress, c.Cfg.L1ChainID, c.Cfg.HsmCreden)
-	}
+	opts, err := signer.NewTransactOpts(ctx, c.Cfg.Signer, c.Cfg.L1ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -499,15 +469,7 @@ func (c *Challenger) makeReRollupBatchTx(ctx context.Context, batchIndex *big.In
 	}
 	c.Cfg.Metrics.NonceETH().Inc()
 	nonce := new(big.Int).SetUint64(nonce64)
-	var opts *bind.TransactOpts
-	if !c.Cfg.EnableHsm {
-		opts, err = bind.NewKeyedTransactorWithChainID(
-			c.Cfg.PrivKey, c.Cfg.L1ChainID,
-		)
-	} else {
-		opts, err = common4.NewHSMTransactOpts(ctx, c.Cfg.HsmAPIName,
-			c.Cfg.HsmAddress, c.Cfg.L1ChainID, c.Cfg.HsmCreden)
-	}
+	opts, err := signer.NewTransactOpts(ctx, c.Cfg.Signer, c.Cfg.L1ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -682,42 +644,34 @@ func (c *Challenger) eventLoop() {
 						log.Error("MtChallenger error getting data", "err", err)
 						continue
 					}
-					batchTxn := new([]eigenda.BatchTx)
-					batchRlpStream := rlp.NewStream(bytes.NewBuffer(data), uint64(len(data)))
-					err = batchRlpStream.Decode(batchTxn)
+					// check the data against the l2 chain, and check this batch
+					// again on the next tick if the l1 or l2 node can not be reached
+					fraud, err := c.checkForFraud(store, data)
 					if err != nil {
-						log.Error("MtChallenger decode batch txn fail", "err", err)
-						continue
-					}
-					newBatchTxn := *batchTxn
-					for i := 0; i < len(newBatchTxn); i++ {
-						l2Tx := new(l2types.Transaction)
-						rlpStream := l2rlp.NewStream(bytes.NewBuffer(newBatchTxn[i].RawTx), 0)
-						if err := l2Tx.DecodeRLP(rlpStream); err != nil {
-							c.Cfg.Logger.Error().Err(err).Msg("Decode RLP fail")
-						}
-
-						// tx check for tmp, will remove in future
-						l2Transaction, _, err := c.Cfg.L2Client.TransactionByHash(c.Ctx, l2Tx.Hash())
-						if err != nil {
-							log.Error("MtChallenger no this transaction", "err", err)
-							continue
-						}
-						log.Info("MtChallenger fond transaction", "hash", l2Transaction.Hash().Hex())
+						log.Error("MtChallenger error checking for fraud", "batchIndex", i, "err", err)
+						break
 					}
-
-					// check if the fraud string exists within the data
-					fraud, exists := c.checkForFraud(store, data)
-					if !exists {
+					if fraud == nil {
 						log.Info("MtChallenger no fraud")
 						c.LevelDBStore.SetLatestBatchIndex(i)
 						continue
 					}
+					c.Cfg.Metrics.FraudFindings().Inc()
+					log.Warn("MtChallenger found fraud", "batchIndex", i, "dataStoreId", store.StoreNumber,
+						"kind", fraud.Kind, "txIndex", fraud.TxIndex, "l2BlockNumber", fraud.BlockNumber,
+						"startingIndex", fraud.StartingIndex, "length", fraud.Length, "reason", fraud.Reason)
 					proof, err := c.constructFraudProof(store, data, fraud, frames)
 					if err != nil {
 						log.Error("MtChallenger error constructing fraud", "err", err)
 						continue
 					}
+					if !fraud.Provable() {
+						log.Error("MtChallenger fraud can not be proven to the rollup contract", "batchIndex", i,
+							"kind", fraud.Kind, "startingChunkIndex", proof.StartingChunkIndex,
+							"startingSymbolIndex", proof.StartingSymbolIndex)
+						c.LevelDBStore.SetLatestBatchIndex(i)
+						continue
+					}
 					tx, err := c.postFraudProof(store, proof)
 					if err != nil {
 						log.Error("MtChallenger error posting fraud proof", "err", err)
@@ -0,0 +1,45 @@
+package challenger
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethc "github.com/ethereum/go-ethereum/common"
+)
+
+// ctcQueueABI is the part of the CanonicalTransactionChain ABI the challenger
+// calls.
+const ctcQueueABI = `[{"inputs":[{"internalType":"uint256","name":"_index","type":"uint256"}],"name":"getQueueElement","outputs":[{"components":[{"internalType":"bytes32","name":"transactionHash","type":"bytes32"},{"internalType":"uint40","name":"timestamp","type":"uint40"},{"internalType":"uint40","name":"blockNumber","type":"uint40"}],"internalType":"struct Lib_BVMCodec.QueueElement","name":"_element","type":"tuple"}],"stateMutability":"view","type":"function"}]`
+
+// QueueElement is an element of the CTC queue, Lib_BVMCodec.QueueElement.
+type QueueElement struct {
+	TransactionHash [32]byte
+	Timestamp       *big.Int
+	BlockNumber     *big.Int
+}
+
+// CTCQueue reads the queue of the CanonicalTransactionChain.
+type CTCQueue struct {
+	contract *bind.BoundContract
+}
+
+func NewCTCQueue(address ethc.Address, caller bind.ContractCaller) (*CTCQueue, error) {
+	parsed, err := abi.JSON(strings.NewReader(ctcQueueABI))
+	if err != nil {
+		return nil, err
+	}
+	return &CTCQueue{
+		contract: bind.NewBoundContract(address, parsed, caller, nil, nil),
+	}, nil
+}
+
+// GetQueueElement returns the element of the queue at the given index.
+func (q *CTCQueue) GetQueueElement(opts *bind.CallOpts, index *big.Int) (QueueElement, error) {
+	var out []interface{}
+	if err := q.contract.Call(opts, &out, "getQueueElement", index); err != nil {
+		return QueueElement{}, err
+	}
+	return *abi.ConvertType(out[0], new(QueueElement)).(*QueueElement), nil
+}
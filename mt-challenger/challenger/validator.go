@@ -0,0 +1,411 @@
+package challenger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethc "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	l2types "github.com/mantlenetworkio/mantle/l2geth/core/types"
+	"github.com/mantlenetworkio/mantle/l2geth/rollup/eigenda"
+)
+
+var bigOne = big.NewInt(1)
+
+const fraudString = "2d5f2860204f2060295f2d202d5f2860206f2060295f2d202d5f286020512060295f2d2042495444414f204a5553542052454b5420594f55207c5f2860204f2060295f7c202d207c5f2860206f2060295f7c202d207c5f286020512060295f7c"
+
+// FraudKind is the kind of invalidity found in the data of a DA batch.
+type FraudKind uint8
+
+const (
+	// FraudKindFraudString is data holding the fraud string, the only fraud
+	// the EigenDA rollup contract verifies.
+	FraudKindFraudString FraudKind = iota
+
+	// FraudKindMalformedBatch is data that does not decode as a list of batch
+	// txs.
+	FraudKindMalformedBatch
+
+	// FraudKindBlockRange is a batch that does not cover the L2 block range it
+	// was stored for.
+	FraudKindBlockRange
+
+	// FraudKindBlockNumber is a batch tx whose block does not follow that of
+	// the batch tx before it.
+	FraudKindBlockNumber
+
+	// FraudKindRawTx is a batch tx whose raw tx differs from the one of its L2
+	// block.
+	FraudKindRawTx
+
+	// FraudKindTxMeta is a batch tx whose metadata differs from the one of its
+	// L2 block.
+	FraudKindTxMeta
+
+	// FraudKindEnqueue is an L1 to L2 batch tx that does not match its element
+	// of the CTC queue.
+	FraudKindEnqueue
+
+	// FraudKindBlockTxs is a batch tx whose L2 block does not hold exactly one
+	// tx, so the block can not be represented by a single batch tx.
+	FraudKindBlockTxs
+)
+
+func (k FraudKind) String() string {
+	switch k {
+	case FraudKindFraudString:
+		return "fraud_string"
+	case FraudKindMalformedBatch:
+		return "malformed_batch"
+	case FraudKindBlockRange:
+		return "block_range"
+	case FraudKindBlockNumber:
+		return "block_number"
+	case FraudKindRawTx:
+		return "raw_tx"
+	case FraudKindTxMeta:
+		return "tx_meta"
+	case FraudKindEnqueue:
+		return "enqueue"
+	case FraudKindBlockTxs:
+		return "block_txs"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(k))
+	}
+}
+
+// Fraud is an invalid span of the data of a DA batch.
+type Fraud struct {
+	Kind FraudKind
+
+	// StartingIndex and Length locate the invalid span in the data, in bytes.
+	StartingIndex int
+	Length        int
+
+	// TxIndex is the index of the invalid batch tx in the batch, or -1 if the
+	// fraud is not about a single batch tx.
+	TxIndex int
+
+	// BlockNumber is the L2 block number of the invalid batch tx, if any.
+	BlockNumber *big.Int
+
+	Reason string
+}
+
+// Provable reports whether the fraud can be proven to the EigenDA rollup
+// contract, which only accepts disclosures of the fraud string.
+func (f *Fraud) Provable() bool {
+	return f.Kind == FraudKindFraudString
+}
+
+// L2BlockFetcher fetches the blocks of the canonical L2 chain.
+type L2BlockFetcher interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*l2types.Block, error)
+}
+
+// QueueElementFetcher fetches the elements of the CTC queue.
+type QueueElementFetcher interface {
+	GetQueueElement(opts *bind.CallOpts, index *big.Int) (QueueElement, error)
+}
+
+// BatchValidator checks the data of DA batches against the canonical L2 chain
+// and the CTC queue.
+type BatchValidator struct {
+	l2Client L2BlockFetcher
+	queue    QueueElementFetcher
+}
+
+func NewBatchValidator(l2Client L2BlockFetcher, queue QueueElementFetcher) *BatchValidator {
+	return &BatchValidator{
+		l2Client: l2Client,
+		queue:    queue,
+	}
+}
+
+// span locates an RLP item in the data of a DA batch.
+type span struct {
+	offset int
+	length int
+}
+
+// batchTx is a decoded batch tx along with the spans of its fields.
+type batchTx struct {
+	eigenda.BatchTx
+	span
+	blockNumber span
+	txMeta      span
+	rawTx       span
+}
+
+// Validate checks the data of a DA batch stored for the L2 blocks from start
+// up to, but excluding, end, and returns the first fraud found in it. Errors
+// are failures to query L1 or L2, after which the batch must be checked again.
+func (v *BatchValidator) Validate(ctx context.Context, data []byte, start, end *big.Int) (*Fraud, error) {
+	if fraud := checkFraudString(data); fraud != nil {
+		return fraud, nil
+	}
+	txs, list, fraud := decodeBatch(data)
+	if fraud != nil {
+		return fraud, nil
+	}
+
+	for i, tx := range txs {
+		blockNumber := new(big.Int).SetBytes(tx.BlockNumber)
+		expected := new(big.Int).Add(start, big.NewInt(int64(i)))
+		switch {
+		case i == 0 && blockNumber.Cmp(start) != 0:
+			return tx.fraud(FraudKindBlockRange, i, tx.blockNumber, fmt.Sprintf(
+				"batch starts at block %v instead of %v", blockNumber, start)), nil
+		case blockNumber.Cmp(expected) != 0:
+			return tx.fraud(FraudKindBlockNumber, i, tx.blockNumber, fmt.Sprintf(
+				"block %v follows block %v", blockNumber, new(big.Int).Sub(expected, bigOne))), nil
+		case blockNumber.Cmp(end) >= 0:
+			return tx.fraud(FraudKindBlockRange, i, tx.blockNumber, fmt.Sprintf(
+				"block %v is past the end of the batch at %v", blockNumber, end)), nil
+		}
+
+		fraud, err := v.validateTx(ctx, i, tx)
+		if err != nil {
+			return nil, err
+		} else if fraud != nil {
+			return fraud, nil
+		}
+	}
+
+	numBlocks := new(big.Int).Sub(end, start)
+	if numBlocks.Cmp(big.NewInt(int64(len(txs)))) != 0 {
+		return &Fraud{
+			Kind:          FraudKindBlockRange,
+			StartingIndex: list.offset,
+			Length:        list.length,
+			TxIndex:       -1,
+			Reason: fmt.Sprintf("batch holds %d blocks instead of %v",
+				len(txs), numBlocks),
+		}, nil
+	}
+	return nil, nil
+}
+
+// validateTx checks a batch tx byte for byte against its L2 block, and L1 to
+// L2 txs against the CTC queue.
+func (v *BatchValidator) validateTx(ctx context.Context, i int, tx *batchTx) (*Fraud, error) {
+	blockNumber := new(big.Int).SetBytes(tx.BlockNumber)
+	block, err := v.l2Client.BlockByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get l2 block %v: %w", blockNumber, err)
+	}
+	txs := block.Transactions()
+	if len(txs) != 1 {
+		return tx.fraud(FraudKindBlockTxs, i, tx.span, fmt.Sprintf(
+			"l2 block has %d txs instead of 1", len(txs))), nil
+	}
+	l2Tx := txs[0]
+
+	var rawTx bytes.Buffer
+	if err := l2Tx.EncodeRLP(&rawTx); err != nil {
+		return nil, fmt.Errorf("cannot encode tx of l2 block %v: %w",
+			blockNumber, err)
+	}
+	if !bytes.Equal(tx.RawTx, rawTx.Bytes()) {
+		return tx.fraud(FraudKindRawTx, i, tx.rawTx, fmt.Sprintf(
+			"raw tx differs from tx %v of l2 block", l2Tx.Hash().Hex())), nil
+	}
+
+	var txMeta eigenda.TransactionMeta
+	if err := json.Unmarshal(tx.TxMeta, &txMeta); err != nil {
+		return tx.fraud(FraudKindTxMeta, i, tx.txMeta, fmt.Sprintf(
+			"cannot decode tx meta: %v", err)), nil
+	}
+
+	// The batcher takes the L1 message sender of L1 to L2 txs from the DTL,
+	// so it is checked against the CTC queue instead.
+	l2TxMeta := l2Tx.GetMeta()
+	l1MessageSender := l2TxMeta.L1MessageSender
+	if l2TxMeta.QueueIndex != nil {
+		l1MessageSender = txMeta.L1MessageSender
+	}
+	expectedTxMeta, err := json.Marshal(&eigenda.TransactionMeta{
+		L1BlockNumber:   l2TxMeta.L1BlockNumber,
+		L1Timestamp:     l2TxMeta.L1Timestamp,
+		L1MessageSender: l1MessageSender,
+		Index:           l2TxMeta.Index,
+		QueueIndex:      l2TxMeta.QueueIndex,
+		RawTransaction:  l2TxMeta.RawTransaction,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode tx meta of l2 block %v: %w",
+			blockNumber, err)
+	}
+	if !bytes.Equal(tx.TxMeta, expectedTxMeta) {
+		return tx.fraud(FraudKindTxMeta, i, tx.txMeta,
+			"tx meta differs from that of l2 block"), nil
+	}
+
+	if l2TxMeta.QueueIndex == nil {
+		return nil, nil
+	}
+	reason, err := v.checkEnqueue(ctx, l2Tx, &txMeta)
+	if err != nil {
+		return nil, err
+	} else if reason != "" {
+		return tx.fraud(FraudKindEnqueue, i, tx.txMeta, reason), nil
+	}
+	return nil, nil
+}
+
+// checkEnqueue checks an L1 to L2 tx against its element of the CTC queue, and
+// returns the reason it does not match, if any.
+func (v *BatchValidator) checkEnqueue(ctx context.Context, l2Tx *l2types.Transaction, txMeta *eigenda.TransactionMeta) (string, error) {
+	queueIndex := new(big.Int).SetUint64(*txMeta.QueueIndex)
+	element, err := v.queue.GetQueueElement(&bind.CallOpts{Context: ctx}, queueIndex)
+	if err != nil {
+		return "", fmt.Errorf("cannot get queue element %v: %w", queueIndex, err)
+	}
+
+	if txMeta.L1MessageSender == nil || l2Tx.To() == nil {
+		return fmt.Sprintf("queue element %v has no sender or target",
+			queueIndex), nil
+	}
+	transactionHash, err := enqueueHash(
+		ethc.Address(*txMeta.L1MessageSender), ethc.Address(*l2Tx.To()),
+		l2Tx.Gas(), l2Tx.Data(),
+	)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case transactionHash != element.TransactionHash:
+		return fmt.Sprintf("queue element %v has tx hash %x instead of %x",
+			queueIndex, element.TransactionHash, transactionHash), nil
+	case txMeta.L1BlockNumber == nil ||
+		element.BlockNumber.Cmp(txMeta.L1BlockNumber) != 0:
+		return fmt.Sprintf("queue element %v has l1 block number %v instead "+
+			"of %v", queueIndex, element.BlockNumber, txMeta.L1BlockNumber), nil
+	case !element.Timestamp.IsUint64() ||
+		element.Timestamp.Uint64() != txMeta.L1Timestamp:
+		return fmt.Sprintf("queue element %v has l1 timestamp %v instead of "+
+			"%d", queueIndex, element.Timestamp, txMeta.L1Timestamp), nil
+	}
+	return "", nil
+}
+
+func mustNewType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+var enqueueArguments = abi.Arguments{
+	{Type: mustNewType("address")},
+	{Type: mustNewType("address")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("bytes")},
+}
+
+// enqueueHash computes the transaction hash the CTC stores in the queue for an
+// L1 to L2 tx, keccak256(abi.encode(sender, target, gasLimit, data)).
+func enqueueHash(sender, target ethc.Address, gasLimit uint64, data []byte) (ethc.Hash, error) {
+	encoded, err := enqueueArguments.Pack(
+		sender, target, new(big.Int).SetUint64(gasLimit), data,
+	)
+	if err != nil {
+		return ethc.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// fraud returns the fraud of the given kind on a field of the batch tx at
+// index i.
+func (tx *batchTx) fraud(kind FraudKind, i int, field span, reason string) *Fraud {
+	return &Fraud{
+		Kind:          kind,
+		StartingIndex: field.offset,
+		Length:        field.length,
+		TxIndex:       i,
+		BlockNumber:   new(big.Int).SetBytes(tx.BlockNumber),
+		Reason:        reason,
+	}
+}
+
+// checkFraudString returns the fraud of the data holding the fraud string, if
+// any.
+func checkFraudString(data []byte) *Fraud {
+	index := strings.Index(hex.EncodeToString(data), fraudString)
+	if index == -1 || index%2 != 0 {
+		return nil
+	}
+	return &Fraud{
+		Kind:          FraudKindFraudString,
+		StartingIndex: index / 2,
+		Length:        len(fraudString) / 2,
+		TxIndex:       -1,
+		Reason:        "data holds the fraud string",
+	}
+}
+
+// decodeBatch decodes the RLP list of batch txs at the start of the data of a
+// DA batch, which is followed by padding, along with the spans of the list and
+// of each batch tx and its fields.
+func decodeBatch(data []byte) ([]*batchTx, span, *Fraud) {
+	malformed := func(offset, length int, err error) *Fraud {
+		if length == 0 {
+			length = 1
+		}
+		return &Fraud{
+			Kind:          FraudKindMalformedBatch,
+			StartingIndex: offset,
+			Length:        length,
+			TxIndex:       -1,
+			Reason:        err.Error(),
+		}
+	}
+
+	content, rest, err := rlp.SplitList(data)
+	if err != nil {
+		return nil, span{}, malformed(0, len(data), err)
+	}
+	list := span{offset: 0, length: len(data) - len(rest)}
+	offset := len(data) - len(rest) - len(content)
+
+	var txs []*batchTx
+	for len(content) > 0 {
+		fields, next, err := rlp.SplitList(content)
+		if err != nil {
+			return nil, span{}, malformed(offset, len(content), err)
+		}
+		tx := &batchTx{
+			span: span{offset: offset, length: len(content) - len(next)},
+		}
+		if err := rlp.DecodeBytes(content[:tx.length], &tx.BatchTx); err != nil {
+			return nil, span{}, malformed(offset, tx.length, err)
+		}
+
+		// The batch tx decoded, so its fields are exactly three strings.
+		fieldOffset := offset + tx.length - len(fields)
+		for _, field := range []*span{&tx.blockNumber, &tx.txMeta, &tx.rawTx} {
+			_, _, fieldsRest, err := rlp.Split(fields)
+			if err != nil {
+				return nil, span{}, malformed(fieldOffset, len(fields), err)
+			}
+			*field = span{offset: fieldOffset, length: len(fields) - len(fieldsRest)}
+			fieldOffset += field.length
+			fields = fieldsRest
+		}
+
+		txs = append(txs, tx)
+		offset += tx.length
+		content = next
+	}
+	return txs, list, nil
+}
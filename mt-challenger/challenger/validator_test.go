@@ -0,0 +1,383 @@
+package challenger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethc "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mantlenetworkio/mantle/l2geth/common"
+	l2types "github.com/mantlenetworkio/mantle/l2geth/core/types"
+	l2rlp "github.com/mantlenetworkio/mantle/l2geth/rlp"
+	"github.com/mantlenetworkio/mantle/l2geth/rollup/eigenda"
+)
+
+var (
+	testSender = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testTarget = common.HexToAddress("0x4200000000000000000000000000000000000007")
+)
+
+type mockL2Chain struct {
+	blocks map[uint64]*l2types.Block
+	err    error
+}
+
+func (m *mockL2Chain) BlockByNumber(_ context.Context, number *big.Int) (*l2types.Block, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	block, ok := m.blocks[number.Uint64()]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return block, nil
+}
+
+type mockQueue struct {
+	elements map[uint64]QueueElement
+}
+
+func (m *mockQueue) GetQueueElement(_ *bind.CallOpts, index *big.Int) (QueueElement, error) {
+	element, ok := m.elements[index.Uint64()]
+	if !ok {
+		return QueueElement{
+			Timestamp:   new(big.Int),
+			BlockNumber: new(big.Int),
+		}, nil
+	}
+	return element, nil
+}
+
+// mockCTC answers getQueueElement calls with a packed queue element.
+type mockCTC struct {
+	output []byte
+	input  []byte
+}
+
+func (m *mockCTC) CodeAt(context.Context, ethc.Address, *big.Int) ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func (m *mockCTC) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	m.input = call.Data
+	return m.output, nil
+}
+
+func TestCTCQueueGetQueueElement(t *testing.T) {
+	hash := ethc.HexToHash("0x1234")
+	output := make([]byte, 96)
+	copy(output, hash[:])
+	big.NewInt(1000).FillBytes(output[32:64])
+	big.NewInt(100).FillBytes(output[64:96])
+	ctc := &mockCTC{output: output}
+
+	queue, err := NewCTCQueue(ethc.HexToAddress("0x01"), ctc)
+	require.NoError(t, err)
+	element, err := queue.GetQueueElement(&bind.CallOpts{}, big.NewInt(7))
+	require.NoError(t, err)
+	require.Equal(t, [32]byte(hash), element.TransactionHash)
+	require.Equal(t, big.NewInt(1000), element.Timestamp)
+	require.Equal(t, big.NewInt(100), element.BlockNumber)
+
+	// getQueueElement(uint256) is 0x2a7f18be
+	require.Equal(t, "2a7f18be", hex.EncodeToString(ctc.input[:4]))
+	require.Equal(t, big.NewInt(7), new(big.Int).SetBytes(ctc.input[4:]))
+}
+
+// newTestChain returns an L2 chain of the blocks from 1 to numBlocks, along
+// with the CTC queue of its L1 to L2 txs, every third block holding one.
+func newTestChain(t *testing.T, numBlocks int) (*mockL2Chain, *mockQueue) {
+	chain := &mockL2Chain{blocks: make(map[uint64]*l2types.Block)}
+	queue := &mockQueue{elements: make(map[uint64]QueueElement)}
+	var queueIndex uint64
+	for i := 1; i <= numBlocks; i++ {
+		index := uint64(i - 1)
+		l1BlockNumber := big.NewInt(int64(100 + i))
+		l1Timestamp := uint64(1000 + i)
+		data := []byte{byte(i)}
+
+		var meta *l2types.TransactionMeta
+		var tx *l2types.Transaction
+		if i%3 == 0 {
+			txQueueIndex := queueIndex
+			tx = l2types.NewTransaction(0, testTarget, new(big.Int), 21000, new(big.Int), data)
+			meta = l2types.NewTransactionMeta(
+				l1BlockNumber, l1Timestamp, &testSender, l2types.QueueOriginL1ToL2,
+				&index, &txQueueIndex, nil,
+			)
+
+			hash, err := enqueueHash(ethc.Address(testSender), ethc.Address(testTarget), 21000, data)
+			require.NoError(t, err)
+			queue.elements[queueIndex] = QueueElement{
+				TransactionHash: hash,
+				Timestamp:       new(big.Int).SetUint64(l1Timestamp),
+				BlockNumber:     l1BlockNumber,
+			}
+			queueIndex++
+		} else {
+			tx = l2types.NewTransaction(uint64(i), testTarget, big.NewInt(1), 21000, big.NewInt(1), data)
+			meta = l2types.NewTransactionMeta(
+				l1BlockNumber, l1Timestamp, nil, l2types.QueueOriginSequencer,
+				&index, nil, []byte{byte(i), byte(i)},
+			)
+		}
+		tx.SetTransactionMeta(meta)
+
+		header := &l2types.Header{Number: big.NewInt(int64(i))}
+		chain.blocks[uint64(i)] = l2types.NewBlock(header, []*l2types.Transaction{tx}, nil, nil)
+	}
+	return chain, queue
+}
+
+// encodeBatch encodes the batch txs of the given blocks, followed by padding,
+// the way the batcher does.
+func encodeBatch(t *testing.T, chain *mockL2Chain, blockNumbers []uint64, mutate func(*eigenda.BatchTx, *eigenda.TransactionMeta)) []byte {
+	var batchTxs []eigenda.BatchTx
+	for _, number := range blockNumbers {
+		tx := chain.blocks[number].Transactions()[0]
+		var rawTx bytes.Buffer
+		require.NoError(t, tx.EncodeRLP(&rawTx))
+		meta := tx.GetMeta()
+		txMeta := &eigenda.TransactionMeta{
+			L1BlockNumber:   meta.L1BlockNumber,
+			L1Timestamp:     meta.L1Timestamp,
+			L1MessageSender: meta.L1MessageSender,
+			Index:           meta.Index,
+			QueueIndex:      meta.QueueIndex,
+			RawTransaction:  meta.RawTransaction,
+		}
+		batchTx := eigenda.BatchTx{
+			BlockNumber: new(big.Int).SetUint64(number).Bytes(),
+			RawTx:       rawTx.Bytes(),
+		}
+		if mutate != nil {
+			mutate(&batchTx, txMeta)
+		}
+		txMetaBytes, err := json.Marshal(txMeta)
+		require.NoError(t, err)
+		if batchTx.TxMeta == nil {
+			batchTx.TxMeta = txMetaBytes
+		}
+		batchTxs = append(batchTxs, batchTx)
+	}
+	data, err := l2rlp.EncodeToBytes(batchTxs)
+	require.NoError(t, err)
+	return append(data, make([]byte, 64)...)
+}
+
+func blockRange(start, end uint64) []uint64 {
+	var numbers []uint64
+	for i := start; i < end; i++ {
+		numbers = append(numbers, i)
+	}
+	return numbers
+}
+
+// mutateBlock returns a mutation of the batch tx of the given block only.
+func mutateBlock(number uint64, mutate func(*eigenda.BatchTx, *eigenda.TransactionMeta)) func(*eigenda.BatchTx, *eigenda.TransactionMeta) {
+	return func(batchTx *eigenda.BatchTx, txMeta *eigenda.TransactionMeta) {
+		if new(big.Int).SetBytes(batchTx.BlockNumber).Uint64() == number {
+			mutate(batchTx, txMeta)
+		}
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	chain, queue := newTestChain(t, 10)
+	fraudBytes, err := hex.DecodeString(fraudString)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		data    func() []byte
+		kind    FraudKind
+		txIndex int
+	}{
+		{
+			name: "fraud string",
+			data: func() []byte {
+				return append([]byte{0x01}, fraudBytes...)
+			},
+			kind:    FraudKindFraudString,
+			txIndex: -1,
+		},
+		{
+			name: "malformed",
+			data: func() []byte {
+				return []byte{0xc5, 0xc3, 0x01}
+			},
+			kind:    FraudKindMalformedBatch,
+			txIndex: -1,
+		},
+		{
+			name: "wrong start",
+			data: func() []byte {
+				return encodeBatch(t, chain, blockRange(2, 7), nil)
+			},
+			kind:    FraudKindBlockRange,
+			txIndex: 0,
+		},
+		{
+			name: "gap",
+			data: func() []byte {
+				return encodeBatch(t, chain, []uint64{1, 2, 4, 5, 6}, nil)
+			},
+			kind:    FraudKindBlockNumber,
+			txIndex: 2,
+		},
+		{
+			name: "too long",
+			data: func() []byte {
+				return encodeBatch(t, chain, blockRange(1, 7), nil)
+			},
+			kind:    FraudKindBlockRange,
+			txIndex: 5,
+		},
+		{
+			name: "too short",
+			data: func() []byte {
+				return encodeBatch(t, chain, blockRange(1, 5), nil)
+			},
+			kind:    FraudKindBlockRange,
+			txIndex: -1,
+		},
+		{
+			name: "raw tx",
+			data: func() []byte {
+				other := chain.blocks[5].Transactions()[0]
+				return encodeBatch(t, chain, blockRange(1, 6), mutateBlock(4,
+					func(batchTx *eigenda.BatchTx, _ *eigenda.TransactionMeta) {
+						var rawTx bytes.Buffer
+						require.NoError(t, other.EncodeRLP(&rawTx))
+						batchTx.RawTx = rawTx.Bytes()
+					}))
+			},
+			kind:    FraudKindRawTx,
+			txIndex: 3,
+		},
+		{
+			name: "tx meta",
+			data: func() []byte {
+				return encodeBatch(t, chain, blockRange(1, 6), mutateBlock(2,
+					func(_ *eigenda.BatchTx, txMeta *eigenda.TransactionMeta) {
+						txMeta.L1Timestamp++
+					}))
+			},
+			kind:    FraudKindTxMeta,
+			txIndex: 1,
+		},
+		{
+			name: "tx meta encoding",
+			data: func() []byte {
+				return encodeBatch(t, chain, blockRange(1, 6), mutateBlock(5,
+					func(batchTx *eigenda.BatchTx, _ *eigenda.TransactionMeta) {
+						batchTx.TxMeta = []byte("{}")
+					}))
+			},
+			kind:    FraudKindTxMeta,
+			txIndex: 4,
+		},
+		{
+			name: "enqueue sender",
+			data: func() []byte {
+				return encodeBatch(t, chain, blockRange(1, 6), mutateBlock(3,
+					func(_ *eigenda.BatchTx, txMeta *eigenda.TransactionMeta) {
+						sender := common.HexToAddress("0x2222222222222222222222222222222222222222")
+						txMeta.L1MessageSender = &sender
+					}))
+			},
+			kind:    FraudKindEnqueue,
+			txIndex: 2,
+		},
+	}
+
+	validator := NewBatchValidator(chain, queue)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := test.data()
+			fraud, err := validator.Validate(context.Background(), data, big.NewInt(1), big.NewInt(6))
+			require.NoError(t, err)
+			require.NotNil(t, fraud)
+			require.Equal(t, test.kind, fraud.Kind, fraud.Reason)
+			require.Equal(t, test.txIndex, fraud.TxIndex)
+			require.Positive(t, fraud.Length)
+			require.LessOrEqual(t, fraud.StartingIndex+fraud.Length, len(data))
+			require.Equal(t, test.kind == FraudKindFraudString, fraud.Provable())
+		})
+	}
+}
+
+func TestValidateBatchValid(t *testing.T) {
+	chain, queue := newTestChain(t, 10)
+	validator := NewBatchValidator(chain, queue)
+
+	data := encodeBatch(t, chain, blockRange(1, 10), nil)
+	fraud, err := validator.Validate(context.Background(), data, big.NewInt(1), big.NewInt(10))
+	require.NoError(t, err)
+	require.Nil(t, fraud)
+}
+
+// TestValidateBatchFraudSpans asserts that frauds locate the invalid field of
+// the invalid batch tx in the data.
+func TestValidateBatchFraudSpans(t *testing.T) {
+	chain, queue := newTestChain(t, 10)
+	validator := NewBatchValidator(chain, queue)
+
+	other := chain.blocks[8].Transactions()[0]
+	var rawTx bytes.Buffer
+	require.NoError(t, other.EncodeRLP(&rawTx))
+	data := encodeBatch(t, chain, blockRange(1, 10), mutateBlock(7,
+		func(batchTx *eigenda.BatchTx, _ *eigenda.TransactionMeta) {
+			batchTx.RawTx = rawTx.Bytes()
+		}))
+
+	fraud, err := validator.Validate(context.Background(), data, big.NewInt(1), big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, FraudKindRawTx, fraud.Kind)
+	require.Equal(t, big.NewInt(7), fraud.BlockNumber)
+
+	var field []byte
+	require.NoError(t, l2rlp.DecodeBytes(
+		data[fraud.StartingIndex:fraud.StartingIndex+fraud.Length], &field))
+	require.Equal(t, rawTx.Bytes(), field)
+}
+
+// TestValidateBatchMultiTxBlock asserts that an L2 block holding more than one
+// tx is reported instead of checking the batch again forever.
+func TestValidateBatchMultiTxBlock(t *testing.T) {
+	chain, queue := newTestChain(t, 10)
+	data := encodeBatch(t, chain, blockRange(1, 6), nil)
+
+	block := chain.blocks[4]
+	txs := append(block.Transactions(), chain.blocks[5].Transactions()[0])
+	chain.blocks[4] = l2types.NewBlock(block.Header(), txs, nil, nil)
+
+	validator := NewBatchValidator(chain, queue)
+	fraud, err := validator.Validate(context.Background(), data, big.NewInt(1), big.NewInt(6))
+	require.NoError(t, err)
+	require.Equal(t, FraudKindBlockTxs, fraud.Kind, fraud.Reason)
+	require.Equal(t, 3, fraud.TxIndex)
+	require.Equal(t, big.NewInt(4), fraud.BlockNumber)
+	require.False(t, fraud.Provable())
+}
+
+// TestValidateBatchRetriesL2Errors asserts that failures to query L2 are
+// returned as errors rather than frauds.
+func TestValidateBatchRetriesL2Errors(t *testing.T) {
+	chain, queue := newTestChain(t, 10)
+	data := encodeBatch(t, chain, blockRange(1, 10), nil)
+
+	chain.err = errors.New("connection refused")
+	validator := NewBatchValidator(chain, queue)
+	fraud, err := validator.Validate(context.Background(), data, big.NewInt(1), big.NewInt(10))
+	require.Error(t, err)
+	require.Nil(t, fraud)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Layr-Labs/datalayr/common/logging"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/mt-challenger/challenger"
 	"github.com/mantlenetworkio/mantle/mt-challenger/flags"
 )
@@ -19,6 +20,7 @@ type Config struct {
 	Mnemonic                  string
 	SequencerHDPath           string
 	EigenContractAddress      string
+	CTCAddress                string
 	GraphProvider             string
 	RetrieverSocket           string
 	DtlClientUrl              string
@@ -46,6 +48,7 @@ type Config struct {
 	HsmAPIName                string
 	HsmCreden                 string
 	HsmAddress                string
+	Signer                    signer.Config
 }
 
 func NewConfig(ctx *cli.Context) (Config, error) {
@@ -59,6 +62,7 @@ func NewConfig(ctx *cli.Context) (Config, error) {
 		Passphrase:           ctx.GlobalString(flags.PassphraseFlag.Name),
 		SequencerHDPath:      ctx.GlobalString(flags.SequencerHDPathFlag.Name),
 		EigenContractAddress: ctx.GlobalString(flags.EigenContractAddressFlag.Name),
+		CTCAddress:           ctx.GlobalString(flags.CTCAddressFlag.Name),
 		RetrieverSocket:      ctx.GlobalString(flags.RetrieverSocketFlag.Name),
 		DtlClientUrl:         ctx.GlobalString(flags.DtlClientUrlFlag.Name),
 		KzgConfig: challenger.KzgConfig{
@@ -90,6 +94,7 @@ func NewConfig(ctx *cli.Context) (Config, error) {
 		HsmAddress:                ctx.GlobalString(flags.HsmAddressFlag.Name),
 		HsmAPIName:                ctx.GlobalString(flags.HsmAPINameFlag.Name),
 		HsmCreden:                 ctx.GlobalString(flags.HsmCredenFlag.Name),
+		Signer:                    signer.ReadCLIConfig(ctx),
 	}
 	return cfg, nil
 }
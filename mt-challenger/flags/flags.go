@@ -4,6 +4,8 @@ import (
 	"github.com/urfave/cli"
 	
 	"github.com/Layr-Labs/datalayr/common/logging"
+
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 )
 
 const envVarPrefix = "DA_CHALLENGER"
@@ -38,10 +40,9 @@ var (
 		EnvVar:   prefixEnvVar("GRAPH_PROVIDER"),
 	}
 	PrivateKeyFlag = cli.StringFlag{
-		Name:     "private-key",
-		Usage:    "Ethereum private key for node operator",
-		Required: true,
-		EnvVar:   prefixEnvVar("PRIVATE_KEY"),
+		Name:   "private-key",
+		Usage:  "Ethereum private key for node operator, unused if signer.type is set",
+		EnvVar: prefixEnvVar("PRIVATE_KEY"),
 	}
 	MnemonicFlag = cli.StringFlag{
 		Name: "mnemonic",
@@ -66,6 +67,12 @@ var (
 		Required: true,
 		EnvVar:   prefixEnvVar("EIGEN_CONTRACT_ADDRESS"),
 	}
+	CTCAddressFlag = cli.StringFlag{
+		Name:     "ctc-address",
+		Usage:    "Address of the CTC contract, whose queue L1 to L2 transactions are checked against",
+		Required: true,
+		EnvVar:   prefixEnvVar("CTC_ADDRESS"),
+	}
 	RetrieverSocketFlag = cli.StringFlag{
 		Name:     "retriever-socket",
 		Usage:    "Address of the datalayr repository contract",
@@ -240,6 +247,7 @@ var requiredFlags = []cli.Flag{
 	GraphProviderFlag,
 	PrivateKeyFlag,
 	EigenContractAddressFlag,
+	CTCAddressFlag,
 	G1PathFlag,
 	G2PathFlag,
 	SrsTablePathFlag,
@@ -275,6 +283,7 @@ var optionalFlags = []cli.Flag{
 func init() {
 	Flags = append(requiredFlags, optionalFlags...)
 	Flags = append(Flags, logging.CLIFlags(envVarPrefix)...)
+	Flags = append(Flags, signer.CLIFlags(envVarPrefix)...)
 }
 
 var Flags []cli.Flag
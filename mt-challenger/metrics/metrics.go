@@ -11,6 +11,7 @@ type ChallengerBase struct {
 	reRollupBatchIndex prometheus.Gauge
 	checkBatchIndex    prometheus.Gauge
 	dataStoreId        prometheus.Gauge
+	fraudFindings      prometheus.Counter
 }
 
 func NewChallengerBase() *ChallengerBase {
@@ -43,6 +44,11 @@ func NewChallengerBase() *ChallengerBase {
 			Help:      "current rollup da data_store_id",
 			Subsystem: "mtbatcher",
 		}),
+		fraudFindings: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "fraud_findings",
+			Help:      "number of da batches found invalid against the l2 chain",
+			Subsystem: "challenger",
+		}),
 	}
 }
 
@@ -65,3 +71,7 @@ func (cb *ChallengerBase) CheckBatchIndex() prometheus.Gauge {
 func (cb *ChallengerBase) DataStoreId() prometheus.Gauge {
 	return cb.dataStoreId
 }
+
+func (cb *ChallengerBase) FraudFindings() prometheus.Counter {
+	return cb.fraudFindings
+}
@@ -10,6 +10,7 @@ import (
 	ethc "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/mantlenetworkio/mantle/bss-core/signer"
 	"github.com/mantlenetworkio/mantle/l2geth/common"
 	"github.com/mantlenetworkio/mantle/mt-batcher/l1l2client"
 	common2 "github.com/mantlenetworkio/mantle/mt-batcher/services/common"
@@ -30,10 +31,7 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		challengerPrivKey, _, err := common2.ParseWalletPrivKeyAndContractAddr(
-			"MtChallenger", cfg.Mnemonic, cfg.SequencerHDPath,
-			cfg.PrivateKey, cfg.EigenContractAddress, cfg.Passphrase,
-		)
+		challengerSigner, err := cfg.newSigner(ctx)
 		if err != nil {
 			return err
 		}
@@ -65,8 +63,9 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 			L2Client:                  l2Client,
 			L1ChainID:                 chainID,
 			EigenContractAddr:         ethc.Address(common.HexToAddress(cfg.EigenContractAddress)),
+			CTCAddr:                   ethc.Address(common.HexToAddress(cfg.CTCAddress)),
 			Logger:                    logger,
-			PrivKey:                   challengerPrivKey,
+			Signer:                    challengerSigner,
 			GraphProvider:             cfg.GraphProvider,
 			RetrieverSocket:           cfg.RetrieverSocket,
 			DtlClientUrl:              cfg.DtlClientUrl,
@@ -86,12 +85,8 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 			NumConfirmations:          cfg.NumConfirmations,
 			SafeAbortNonceTooLowCount: cfg.SafeAbortNonceTooLowCount,
 			Metrics:                   metrics.NewChallengerBase(),
-			EnableHsm:                 cfg.EnableHsm,
-			HsmCreden:                 cfg.HsmCreden,
-			HsmAPIName:                cfg.HsmAPIName,
-			HsmAddress:                cfg.HsmAddress,
 		}
-		log.Info("challenger hsm", "EnableHsm", cfg.EnableHsm, "HsmAPIName", cfg.HsmAPIName, "HsmAddress", cfg.HsmAddress)
+		log.Info("challenger signer", "address", challengerSigner.Address(), "type", cfg.Signer.Type, "EnableHsm", cfg.EnableHsm)
 		cLager, err := challenger.NewChallenger(ctx, challengerConfig)
 		if err != nil {
 			return err
@@ -104,3 +99,23 @@ func Main(gitVersion string) func(ctx *cli.Context) error {
 		return nil
 	}
 }
+
+// newSigner returns the challenger transaction signer. The signer flags take
+// precedence over the hsm and private key flags, which are kept for existing
+// deployments.
+func (c Config) newSigner(ctx context.Context) (signer.Signer, error) {
+	switch {
+	case c.Signer.Enabled():
+		return signer.NewSigner(ctx, c.Signer)
+	case c.EnableHsm:
+		return signer.NewKMSSigner(ctx, c.HsmAPIName, c.HsmAddress, c.HsmCreden)
+	}
+	privKey, _, err := common2.ParseWalletPrivKeyAndContractAddr(
+		"MtChallenger", c.Mnemonic, c.SequencerHDPath,
+		c.PrivateKey, c.EigenContractAddress, c.Passphrase,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(privKey), nil
+}
@@ -0,0 +1,167 @@
+package proxyd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/mux"
+)
+
+// adminAuthMiddleware only lets through requests carrying the admin token
+// as a bearer token.
+func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			log.Info("blocked unauthorized admin request", "path", r.URL.Path)
+			httpResponseCodesTotal.WithLabelValues("401").Inc()
+			w.WriteHeader(401)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) HandleAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeyPolicies == nil {
+		writeAdminJSON(w, 200, []*APIKeyUsage{})
+		return
+	}
+	usage, err := s.apiKeyPolicies.Usage()
+	if err != nil {
+		log.Error("error getting api key usage", "err", err)
+		writeAdminJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+	writeAdminJSON(w, 200, usage)
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("error writing admin response", "err", err)
+	}
+	httpResponseCodesTotal.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+type BackendStatus struct {
+	Name          string                    `json:"name"`
+	BackendGroups []string                  `json:"backend_groups"`
+	Online        bool                      `json:"online"`
+	Draining      bool                      `json:"draining"`
+	InFlight      int64                     `json:"in_flight"`
+	LatencyEWMA   float64                   `json:"latency_ewma_seconds"`
+	Weight        int                       `json:"weight"`
+	Consensus     []*BackendConsensusStatus `json:"consensus,omitempty"`
+
+	// ShadowBackendGroups lists the groups the backend shadows.
+	ShadowBackendGroups []string `json:"shadow_backend_groups,omitempty"`
+}
+
+type BackendConsensusStatus struct {
+	BackendGroup    string         `json:"backend_group"`
+	InConsensus     bool           `json:"in_consensus"`
+	Banned          bool           `json:"banned"`
+	LatestBlock     hexutil.Uint64 `json:"latest_block"`
+	LatestBlockHash string         `json:"latest_block_hash"`
+	LastUpdate      time.Time      `json:"last_update"`
+}
+
+func (s *Server) HandleBackends(w http.ResponseWriter, r *http.Request) {
+	rc := s.runtime()
+	out := make([]*BackendStatus, 0, len(rc.backends))
+	for _, name := range rc.backendNames() {
+		out = append(out, rc.backendStatus(rc.backends[name]))
+	}
+	writeAdminJSON(w, 200, out)
+}
+
+func (s *Server) HandleBackend(w http.ResponseWriter, r *http.Request) {
+	rc := s.runtime()
+	be := rc.backends[mux.Vars(r)["name"]]
+	if be == nil {
+		writeAdminJSON(w, 404, map[string]string{"error": "backend not found"})
+		return
+	}
+	writeAdminJSON(w, 200, rc.backendStatus(be))
+}
+
+func (s *Server) HandleDrainBackend(w http.ResponseWriter, r *http.Request) {
+	s.setBackendDraining(w, r, true)
+}
+
+func (s *Server) HandleUndrainBackend(w http.ResponseWriter, r *http.Request) {
+	s.setBackendDraining(w, r, false)
+}
+
+func (s *Server) setBackendDraining(w http.ResponseWriter, r *http.Request, draining bool) {
+	rc := s.runtime()
+	be := rc.backends[mux.Vars(r)["name"]]
+	if be == nil {
+		writeAdminJSON(w, 404, map[string]string{"error": "backend not found"})
+		return
+	}
+	if draining {
+		be.Drain()
+	} else {
+		be.Undrain()
+	}
+	log.Info("updated backend drain state", "name", be.Name, "draining", draining)
+	writeAdminJSON(w, 200, rc.backendStatus(be))
+}
+
+func (rc *runtimeConfig) backendStatus(be *Backend) *BackendStatus {
+	status := &BackendStatus{
+		Name:          be.Name,
+		BackendGroups: make([]string, 0),
+		Online:        be.Online(),
+		Draining:      be.Draining(),
+		InFlight:      be.InFlight(),
+		LatencyEWMA:   be.LatencyEWMA(),
+		Weight:        be.weight,
+	}
+	for _, bg := range rc.backendGroups {
+		for _, shadow := range bg.shadowBackends() {
+			if shadow == be {
+				status.ShadowBackendGroups = append(status.ShadowBackendGroups, bg.Name)
+			}
+		}
+		if !bg.hasBackend(be) {
+			continue
+		}
+		status.BackendGroups = append(status.BackendGroups, bg.Name)
+		if bg.Consensus == nil {
+			continue
+		}
+		var inConsensus bool
+		for _, member := range bg.Consensus.GetConsensusGroup() {
+			if member == be {
+				inConsensus = true
+				break
+			}
+		}
+		latestBlock, latestBlockHash, lastUpdate, _ := bg.Consensus.getBackendState(be)
+		status.Consensus = append(status.Consensus, &BackendConsensusStatus{
+			BackendGroup:    bg.Name,
+			InConsensus:     inConsensus,
+			Banned:          bg.Consensus.IsBanned(be),
+			LatestBlock:     latestBlock,
+			LatestBlockHash: latestBlockHash,
+			LastUpdate:      lastUpdate,
+		})
+	}
+	sort.Strings(status.BackendGroups)
+	sort.Strings(status.ShadowBackendGroups)
+	sort.Slice(status.Consensus, func(i, j int) bool {
+		return status.Consensus[i].BackendGroup < status.Consensus[j].BackendGroup
+	})
+	return status
+}
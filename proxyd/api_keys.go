@@ -0,0 +1,208 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	APIKeyStatusAllowed       = "allowed"
+	APIKeyStatusMethodDenied  = "method_denied"
+	APIKeyStatusRateLimited   = "rate_limited"
+	APIKeyStatusQuotaExceeded = "quota_exceeded"
+
+	dailyQuotaDateFormat = "2006-01-02"
+)
+
+// APIKeyPolicy restricts what a single authenticated key is allowed to do.
+// Keys are identified by their alias from the authentication section so
+// that secrets never show up in logs, metrics or the admin API.
+type APIKeyPolicy struct {
+	Alias          string
+	allowedMethods []string
+	deniedMethods  []string
+	ratePerSecond  int
+	dailyQuota     int
+
+	maxLogsBlockRange uint64
+}
+
+func NewAPIKeyPolicy(alias string, cfg *APIKeyConfig) *APIKeyPolicy {
+	return &APIKeyPolicy{
+		Alias:          alias,
+		allowedMethods: cfg.AllowedMethods,
+		deniedMethods:  cfg.DeniedMethods,
+		ratePerSecond:  cfg.RatePerSecond,
+		dailyQuota:     cfg.DailyQuota,
+
+		maxLogsBlockRange: cfg.MaxLogsBlockRange,
+	}
+}
+
+// MethodAllowed checks a method against the deny list first and then
+// against the allow list. An empty allow list allows every method.
+func (p *APIKeyPolicy) MethodAllowed(method string) bool {
+	for _, pattern := range p.deniedMethods {
+		if MatchMethod(pattern, method) {
+			return false
+		}
+	}
+	if len(p.allowedMethods) == 0 {
+		return true
+	}
+	for _, pattern := range p.allowedMethods {
+		if MatchMethod(pattern, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchMethod matches a method name against either an exact name or a
+// pattern ending with a `*` wildcard, such as `debug_*`.
+func MatchMethod(pattern, method string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(method, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == method
+}
+
+type APIKeyUsage struct {
+	Alias          string   `json:"alias"`
+	AllowedMethods []string `json:"allowed_methods"`
+	DeniedMethods  []string `json:"denied_methods"`
+	RatePerSecond  int      `json:"rate_per_second"`
+	DailyQuota     int      `json:"daily_quota"`
+	DailyUsage     int      `json:"daily_usage"`
+
+	MaxLogsBlockRange uint64 `json:"max_logs_block_range,omitempty"`
+}
+
+// APIKeyPolicies enforces the per-key policies. Rate limits and quotas are
+// kept in the backend rate limiter so that they are shared between proxyd
+// instances when Redis is configured.
+type APIKeyPolicies struct {
+	mtx      sync.RWMutex
+	policies map[string]*APIKeyPolicy
+	lim      BackendRateLimiter
+}
+
+func NewAPIKeyPolicies(cfg APIKeysConfig, lim BackendRateLimiter) *APIKeyPolicies {
+	return &APIKeyPolicies{
+		policies: newAPIKeyPolicyMap(cfg),
+		lim:      lim,
+	}
+}
+
+func newAPIKeyPolicyMap(cfg APIKeysConfig) map[string]*APIKeyPolicy {
+	policies := make(map[string]*APIKeyPolicy, len(cfg))
+	for alias, keyCfg := range cfg {
+		policies[alias] = NewAPIKeyPolicy(alias, keyCfg)
+	}
+	return policies
+}
+
+// Update replaces the policies. Usage is tracked by alias in the rate
+// limiter, so quotas carry over for keys that keep their alias.
+func (a *APIKeyPolicies) Update(cfg APIKeysConfig) {
+	policies := newAPIKeyPolicyMap(cfg)
+	a.mtx.Lock()
+	a.policies = policies
+	a.mtx.Unlock()
+}
+
+func (a *APIKeyPolicies) policy(alias string) *APIKeyPolicy {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+	return a.policies[alias]
+}
+
+// Check returns an error if the authenticated key in the context may not
+// call the method right now. Requests of keys without a policy are allowed.
+func (a *APIKeyPolicies) Check(ctx context.Context, method string) error {
+	alias := GetAuthCtx(ctx)
+	policy := a.policy(alias)
+	if policy == nil {
+		return nil
+	}
+
+	if !policy.MethodAllowed(method) {
+		RecordAPIKeyRequest(ctx, method, APIKeyStatusMethodDenied)
+		return ErrMethodNotWhitelisted
+	}
+
+	// Limiter errors fail open, an unavailable Redis must not take
+	// down every partner key with it.
+	if policy.ratePerSecond > 0 {
+		rps, err := a.lim.IncKeyRPS(alias)
+		if err != nil {
+			log.Warn("error incrementing api key rate limit", "auth", alias, "err", err)
+		} else if rps > policy.ratePerSecond {
+			RecordAPIKeyRequest(ctx, method, APIKeyStatusRateLimited)
+			return ErrOverRateLimit
+		}
+	}
+
+	used, err := a.lim.IncKeyDailyUsage(alias, today())
+	if err != nil {
+		log.Warn("error incrementing api key daily usage", "auth", alias, "err", err)
+	} else {
+		RecordAPIKeyDailyUsage(alias, used)
+		if policy.dailyQuota > 0 && used > policy.dailyQuota {
+			RecordAPIKeyRequest(ctx, method, APIKeyStatusQuotaExceeded)
+			return ErrOverQuota
+		}
+	}
+
+	RecordAPIKeyRequest(ctx, method, APIKeyStatusAllowed)
+	return nil
+}
+
+// MaxLogsBlockRange returns the eth_getLogs range limit of the key in the
+// context, if its policy overrides the global limit.
+func (a *APIKeyPolicies) MaxLogsBlockRange(ctx context.Context) (uint64, bool) {
+	policy := a.policy(GetAuthCtx(ctx))
+	if policy == nil || policy.maxLogsBlockRange == 0 {
+		return 0, false
+	}
+	return policy.maxLogsBlockRange, true
+}
+
+// Usage returns the policy and the usage of today for every key.
+func (a *APIKeyPolicies) Usage() ([]*APIKeyUsage, error) {
+	a.mtx.RLock()
+	policies := a.policies
+	a.mtx.RUnlock()
+
+	out := make([]*APIKeyUsage, 0, len(policies))
+	for alias, policy := range policies {
+		used, err := a.lim.KeyDailyUsage(alias, today())
+		if err != nil {
+			return nil, fmt.Errorf("error getting daily usage of %s: %w", alias, err)
+		}
+		out = append(out, &APIKeyUsage{
+			Alias:          alias,
+			AllowedMethods: policy.allowedMethods,
+			DeniedMethods:  policy.deniedMethods,
+			RatePerSecond:  policy.ratePerSecond,
+			DailyQuota:     policy.dailyQuota,
+			DailyUsage:     used,
+
+			MaxLogsBlockRange: policy.maxLogsBlockRange,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Alias < out[j].Alias
+	})
+	return out, nil
+}
+
+func today() string {
+	return time.Now().UTC().Format(dailyQuotaDateFormat)
+}
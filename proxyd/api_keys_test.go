@@ -0,0 +1,60 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyPolicyMethodAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *APIKeyConfig
+		method   string
+		expected bool
+	}{
+		{"no lists", &APIKeyConfig{}, "debug_traceTransaction", true},
+		{"exact allow", &APIKeyConfig{AllowedMethods: []string{"eth_call"}}, "eth_call", true},
+		{"not in allow list", &APIKeyConfig{AllowedMethods: []string{"eth_call"}}, "eth_getLogs", false},
+		{"wildcard allow", &APIKeyConfig{AllowedMethods: []string{"eth_*"}}, "eth_getLogs", true},
+		{"wildcard deny", &APIKeyConfig{DeniedMethods: []string{"debug_*"}}, "debug_traceTransaction", false},
+		{"deny wins over allow", &APIKeyConfig{AllowedMethods: []string{"eth_*"}, DeniedMethods: []string{"eth_getLogs"}}, "eth_getLogs", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewAPIKeyPolicy("test", tt.cfg)
+			require.Equal(t, tt.expected, policy.MethodAllowed(tt.method))
+		})
+	}
+}
+
+func TestAPIKeyPoliciesLocalLimiter(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	policies := NewAPIKeyPolicies(APIKeysConfig{
+		"partner": {DailyQuota: 2},
+	}, lim)
+
+	ctx := contextWithAuth("partner")
+	require.NoError(t, policies.Check(ctx, "eth_chainId"))
+	require.NoError(t, policies.Check(ctx, "eth_chainId"))
+	require.Equal(t, ErrOverQuota, policies.Check(ctx, "eth_chainId"))
+
+	// keys without a policy are not accounted
+	require.NoError(t, policies.Check(contextWithAuth("other"), "eth_chainId"))
+
+	usage, err := policies.Usage()
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	require.Equal(t, 3, usage[0].DailyUsage)
+
+	// usage of a previous day is not carried over
+	used, err := lim.IncKeyDailyUsage("partner", "1970-01-01")
+	require.NoError(t, err)
+	require.Equal(t, 1, used)
+}
+
+func contextWithAuth(alias string) context.Context {
+	return context.WithValue(context.Background(), ContextKeyAuth, alias) // nolint:staticcheck
+}
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -27,6 +28,9 @@ import (
 const (
 	JSONRPCVersion       = "2.0"
 	JSONRPCErrorInternal = -32000
+
+	// weight given to the most recent sample in the backend latency EWMA
+	latencyEWMADecay = 0.2
 )
 
 var (
@@ -79,6 +83,31 @@ var (
 		Message:       "rate limited",
 		HTTPErrorCode: 429,
 	}
+	ErrBlockOutOfRange = &RPCErr{
+		Code:          JSONRPCErrorInternal - 17,
+		Message:       "block is out of range",
+		HTTPErrorCode: 400,
+	}
+	ErrOverQuota = &RPCErr{
+		Code:          JSONRPCErrorInternal - 18,
+		Message:       "daily quota exceeded",
+		HTTPErrorCode: 429,
+	}
+	ErrOverSenderRateLimit = &RPCErr{
+		Code:          JSONRPCErrorInternal - 19,
+		Message:       "sender is over rate limit",
+		HTTPErrorCode: 429,
+	}
+	ErrBackendDraining = &RPCErr{
+		Code:          JSONRPCErrorInternal - 20,
+		Message:       "backend is draining",
+		HTTPErrorCode: 503,
+	}
+	ErrTransactionAlreadyKnown = &RPCErr{
+		Code:          JSONRPCErrorInternal,
+		Message:       "already known",
+		HTTPErrorCode: 400,
+	}
 
 	ErrBackendUnexpectedJSONRPC = errors.New("backend returned an unexpected JSON-RPC response")
 )
@@ -91,6 +120,14 @@ func ErrInvalidRequest(msg string) *RPCErr {
 	}
 }
 
+func ErrInvalidParams(msg string) *RPCErr {
+	return &RPCErr{
+		Code:          -32602,
+		Message:       msg,
+		HTTPErrorCode: 400,
+	}
+}
+
 type Backend struct {
 	Name                 string
 	rpcURL               string
@@ -107,6 +144,12 @@ type Backend struct {
 	outOfServiceInterval time.Duration
 	stripTrailingXFF     bool
 	proxydIP             string
+	weight               int
+
+	inFlight    int64
+	draining    int32
+	latencyMtx  sync.Mutex
+	latencyEWMA float64
 }
 
 type BackendOpt func(b *Backend)
@@ -175,6 +218,12 @@ func WithProxydIP(ip string) BackendOpt {
 	}
 }
 
+func WithWeight(weight int) BackendOpt {
+	return func(b *Backend) {
+		b.weight = weight
+	}
+}
+
 func NewBackend(
 	name string,
 	rpcURL string,
@@ -189,6 +238,7 @@ func NewBackend(
 		wsURL:           wsURL,
 		rateLimiter:     rateLimiter,
 		maxResponseSize: math.MaxInt64,
+		weight:          defaultBackendWeight,
 		client: &LimitedHTTPClient{
 			Client:      http.Client{Timeout: 5 * time.Second},
 			sem:         rpcSemaphore,
@@ -209,6 +259,9 @@ func NewBackend(
 }
 
 func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]*RPCRes, error) {
+	if b.Draining() {
+		return nil, ErrBackendDraining
+	}
 	if !b.Online() {
 		RecordBatchRPCError(ctx, b.Name, reqs, ErrBackendOffline)
 		return nil, ErrBackendOffline
@@ -218,6 +271,9 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 		return nil, ErrBackendOverCapacity
 	}
 
+	atomic.AddInt64(&b.inFlight, 1)
+	defer atomic.AddInt64(&b.inFlight, -1)
+
 	var lastError error
 	// <= to account for the first attempt not technically being
 	// a retry
@@ -235,6 +291,7 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 			),
 		)
 
+		start := time.Now()
 		res, err := b.doForward(ctx, reqs, isBatch)
 		switch err {
 		case nil: // do nothing
@@ -258,11 +315,13 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 				"err", err,
 			)
 			timer.ObserveDuration()
+			b.observeFailureLatency(ctx, time.Since(start))
 			RecordBatchRPCError(ctx, b.Name, reqs, err)
 			sleepContext(ctx, calcBackoff(i))
 			continue
 		}
 		timer.ObserveDuration()
+		b.observeLatency(time.Since(start))
 
 		MaybeRecordErrorsInRPCRes(ctx, b.Name, reqs, res)
 		return res, err
@@ -272,7 +331,50 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 	return nil, wrapErr(lastError, "permanent error forwarding request")
 }
 
+// ForwardRPC makes a call directly to a backend and populate the response into `res`
+func (b *Backend) ForwardRPC(ctx context.Context, res *RPCRes, id string, method string, params ...interface{}) error {
+	jsonParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	rpcReq := RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  jsonParams,
+		ID:      []byte(id),
+	}
+
+	slicedRes, err := b.doForward(ctx, []*RPCReq{&rpcReq}, false)
+	if err != nil {
+		return err
+	}
+
+	if len(slicedRes) != 1 {
+		return fmt.Errorf("unexpected response len for non-batched request (len != 1)")
+	}
+	if slicedRes[0].IsError() {
+		return slicedRes[0].Error
+	}
+
+	*res = *(slicedRes[0])
+	return nil
+}
+
 func (b *Backend) ProxyWS(clientConn *websocket.Conn, methodWhitelist *StringSet) (*WSProxier, error) {
+	backendConn, err := b.DialWS()
+	if err != nil {
+		return nil, err
+	}
+	return NewWSProxier(b, clientConn, backendConn, methodWhitelist), nil
+}
+
+// DialWS opens a websocket connection to the backend. The connection counts
+// towards MaxWSConns until it is released with releaseWSConn.
+func (b *Backend) DialWS() (*websocket.Conn, error) {
+	if b.Draining() {
+		return nil, ErrBackendDraining
+	}
 	if !b.Online() {
 		return nil, ErrBackendOffline
 	}
@@ -290,7 +392,14 @@ func (b *Backend) ProxyWS(clientConn *websocket.Conn, methodWhitelist *StringSet
 	}
 
 	activeBackendWsConnsGauge.WithLabelValues(b.Name).Inc()
-	return NewWSProxier(b, clientConn, backendConn, methodWhitelist), nil
+	return backendConn, nil
+}
+
+func (b *Backend) releaseWSConn() {
+	if err := b.rateLimiter.DecBackendWSConns(b.Name); err != nil {
+		log.Error("error decrementing backend ws conns", "name", b.Name, "err", err)
+	}
+	activeBackendWsConnsGauge.WithLabelValues(b.Name).Dec()
 }
 
 func (b *Backend) Online() bool {
@@ -342,6 +451,61 @@ func (b *Backend) IsWSSaturated() bool {
 	return !incremented
 }
 
+// Drain stops new requests and websocket connections from being routed to
+// the backend. Requests and connections already in flight are not affected.
+func (b *Backend) Drain() {
+	atomic.StoreInt32(&b.draining, 1)
+	RecordBackendDraining(b, true)
+}
+
+func (b *Backend) Undrain() {
+	atomic.StoreInt32(&b.draining, 0)
+	RecordBackendDraining(b, false)
+}
+
+func (b *Backend) Draining() bool {
+	return atomic.LoadInt32(&b.draining) == 1
+}
+
+// InFlight returns the number of requests currently being forwarded to the backend.
+func (b *Backend) InFlight() int64 {
+	return atomic.LoadInt64(&b.inFlight)
+}
+
+// LatencyEWMA returns the exponentially weighted moving average of the
+// backend response time, in seconds.
+func (b *Backend) LatencyEWMA() float64 {
+	b.latencyMtx.Lock()
+	defer b.latencyMtx.Unlock()
+	return b.latencyEWMA
+}
+
+func (b *Backend) observeLatency(d time.Duration) {
+	b.latencyMtx.Lock()
+	if b.latencyEWMA == 0 {
+		b.latencyEWMA = d.Seconds()
+	} else {
+		b.latencyEWMA = latencyEWMADecay*d.Seconds() + (1-latencyEWMADecay)*b.latencyEWMA
+	}
+	latency := b.latencyEWMA
+	b.latencyMtx.Unlock()
+	RecordBackendLatencyEWMA(b, latency)
+}
+
+// observeFailureLatency penalizes a failed request as if it took the whole
+// response timeout, so that failing backends are not preferred for answering
+// fast.
+func (b *Backend) observeFailureLatency(ctx context.Context, d time.Duration) {
+	timeout := b.client.Timeout
+	if override, ok := ctx.Value(ContextKeyResponseTimeout).(time.Duration); ok {
+		timeout = override
+	}
+	if d < timeout {
+		d = timeout
+	}
+	b.observeLatency(d)
+}
+
 func (b *Backend) setOffline() {
 	err := b.rateLimiter.SetBackendOffline(b.Name, b.outOfServiceInterval)
 	if err != nil {
@@ -472,8 +636,27 @@ func sortBatchRPCResponse(req []*RPCReq, res []*RPCRes) {
 }
 
 type BackendGroup struct {
-	Name     string
-	Backends []*Backend
+	Name      string
+	Backends  []*Backend
+	Consensus *ConsensusPoller
+	Selector  BackendSelector
+	Shadow    *ShadowMirror
+}
+
+func (b *BackendGroup) hasBackend(be *Backend) bool {
+	for _, member := range b.Backends {
+		if member == be {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *BackendGroup) shadowBackends() []*Backend {
+	if b.Shadow == nil {
+		return nil
+	}
+	return b.Shadow.backends
 }
 
 func (b *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool) ([]*RPCRes, error) {
@@ -483,11 +666,74 @@ func (b *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch b
 
 	rpcRequestsTotal.Inc()
 
-	for _, back := range b.Backends {
+	backends := b.Backends
+
+	overriddenResponses := make([]*indexedReqRes, 0)
+	rewrittenReqs := make([]*RPCReq, 0, len(rpcReqs))
+
+	if b.Consensus != nil {
+		// When `consensus_aware` is set to `true`, the backend group acts as a load balancer
+		// serving traffic only from backends that agree on the same block hash
+		backends = b.Consensus.GetConsensusGroup()
+
+		rctx := RewriteContext{
+			latest: b.Consensus.GetLatestBlockNumber(),
+			safe:   b.Consensus.GetSafeBlockNumber(),
+		}
+
+		for i, req := range rpcReqs {
+			// rewrite a copy so that the original request can still be used as a cache key
+			rwReq := *req
+			res := RPCRes{JSONRPC: JSONRPCVersion, ID: req.ID}
+			result, err := RewriteTags(rctx, &rwReq, &res)
+			switch result {
+			case RewriteOverrideError:
+				if errors.Is(err, ErrRewriteBlockOutOfRange) {
+					res.Error = ErrBlockOutOfRange
+				} else {
+					res.Error = ErrInvalidRequest(err.Error())
+				}
+				RecordRPCError(ctx, BackendProxyd, req.Method, res.Error)
+				overriddenResponses = append(overriddenResponses, &indexedReqRes{
+					index: i,
+					req:   req,
+					res:   &res,
+				})
+			case RewriteOverrideResponse:
+				overriddenResponses = append(overriddenResponses, &indexedReqRes{
+					index: i,
+					req:   req,
+					res:   &res,
+				})
+			case RewriteOverrideRequest, RewriteNone:
+				rewrittenReqs = append(rewrittenReqs, &rwReq)
+			}
+		}
+		rpcReqs = rewrittenReqs
+	}
+
+	if len(rpcReqs) == 0 {
+		return mergeOverriddenResponses(nil, overriddenResponses), nil
+	}
+
+	if b.Selector != nil {
+		backends = b.Selector.Order(backends)
+	}
+
+	for _, back := range backends {
 		res, err := back.Forward(ctx, rpcReqs, isBatch)
 		if errors.Is(err, ErrMethodNotWhitelisted) {
 			return nil, err
 		}
+		if errors.Is(err, ErrBackendDraining) {
+			log.Debug(
+				"skipping draining backend",
+				"name", back.Name,
+				"auth", GetAuthCtx(ctx),
+				"req_id", GetReqID(ctx),
+			)
+			continue
+		}
 		if errors.Is(err, ErrBackendOffline) {
 			log.Warn(
 				"skipping offline backend",
@@ -516,16 +762,60 @@ func (b *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch b
 			)
 			continue
 		}
-		return res, nil
+		RecordBackendGroupServed(b, back)
+		if b.Shadow != nil {
+			// mirror the requests as rewritten for the primary backend
+			b.Shadow.Mirror(ctx, rpcReqs, res, isBatch)
+		}
+		return mergeOverriddenResponses(res, overriddenResponses), nil
 	}
 
 	RecordUnserviceableRequest(ctx, RPCRequestSourceHTTP)
 	return nil, ErrNoBackends
 }
 
+type indexedReqRes struct {
+	index int
+	req   *RPCReq
+	res   *RPCRes
+}
+
+// mergeOverriddenResponses puts the responses served by proxyd itself back
+// at the position of their requests in the original batch
+func mergeOverriddenResponses(res []*RPCRes, overridden []*indexedReqRes) []*RPCRes {
+	if len(overridden) == 0 {
+		return res
+	}
+
+	out := make([]*RPCRes, 0, len(res)+len(overridden))
+	var next int
+	for _, ov := range overridden {
+		for len(out) < ov.index && next < len(res) {
+			out = append(out, res[next])
+			next++
+		}
+		out = append(out, ov.res)
+	}
+	return append(out, res[next:]...)
+}
+
 func (b *BackendGroup) ProxyWS(ctx context.Context, clientConn *websocket.Conn, methodWhitelist *StringSet) (*WSProxier, error) {
-	for _, back := range b.Backends {
+	backends := b.Backends
+	if b.Selector != nil {
+		backends = b.Selector.Order(backends)
+	}
+
+	for _, back := range backends {
 		proxier, err := back.ProxyWS(clientConn, methodWhitelist)
+		if errors.Is(err, ErrBackendDraining) {
+			log.Debug(
+				"skipping draining backend",
+				"name", back.Name,
+				"req_id", GetReqID(ctx),
+				"auth", GetAuthCtx(ctx),
+			)
+			continue
+		}
 		if errors.Is(err, ErrBackendOffline) {
 			log.Warn(
 				"skipping offline backend",
@@ -738,10 +1028,7 @@ func (w *WSProxier) backendPump(ctx context.Context, errC chan error) {
 func (w *WSProxier) close() {
 	w.clientConn.Close()
 	w.backendConn.Close()
-	if err := w.backend.rateLimiter.DecBackendWSConns(w.backend.Name); err != nil {
-		log.Error("error decrementing backend ws conns", "name", w.backend.Name, "err", err)
-	}
-	activeBackendWsConnsGauge.WithLabelValues(w.backend.Name).Dec()
+	w.backend.releaseWSConn()
 }
 
 func (w *WSProxier) prepareClientMsg(msg []byte) (*RPCReq, error) {
@@ -814,6 +1101,11 @@ func (c *LimitedHTTPClient) DoLimited(req *http.Request) (*http.Response, error)
 		return nil, wrapErr(err, "too many requests")
 	}
 	defer c.sem.Release(1)
+	if timeout, ok := req.Context().Value(ContextKeyResponseTimeout).(time.Duration); ok {
+		client := c.Client
+		client.Timeout = timeout
+		return client.Do(req)
+	}
 	return c.Do(req)
 }
 
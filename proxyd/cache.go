@@ -2,6 +2,8 @@ package proxyd
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -12,6 +14,11 @@ import (
 type Cache interface {
 	Get(ctx context.Context, key string) (string, error)
 	Put(ctx context.Context, key string, value string) error
+	// Tag associates a key with a tag, so that all the keys of the tag can
+	// be purged at once.
+	Tag(ctx context.Context, tag string, key string) error
+	// Purge deletes all the keys of a tag along with the tag itself.
+	Purge(ctx context.Context, tag string) error
 }
 
 const (
@@ -21,32 +28,88 @@ const (
 	redisTTL = 30 * 7 * 24 * time.Hour
 )
 
+type memoryCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// cache is a bounded in-memory LRU cache. Entries expire after the ttl if it
+// is set.
 type cache struct {
-	lru *lru.Cache
+	lru  *lru.Cache
+	tags *lru.Cache
+	ttl  time.Duration
+	// guards the key sets stored in tags
+	mtx sync.Mutex
 }
 
-func newMemoryCache() *cache {
-	rep, _ := lru.New(memoryCacheLimit)
-	return &cache{rep}
+func newMemoryCache(maxEntries int, ttl time.Duration) *cache {
+	if maxEntries <= 0 {
+		maxEntries = memoryCacheLimit
+	}
+	rep, _ := lru.New(maxEntries)
+	tags, _ := lru.New(maxEntries)
+	return &cache{
+		lru:  rep,
+		tags: tags,
+		ttl:  ttl,
+	}
 }
 
 func (c *cache) Get(ctx context.Context, key string) (string, error) {
-	if val, ok := c.lru.Get(key); ok {
-		return val.(string), nil
+	val, ok := c.lru.Get(key)
+	if !ok {
+		return "", nil
 	}
-	return "", nil
+	entry := val.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.lru.Remove(key)
+		return "", nil
+	}
+	return entry.value, nil
 }
 
 func (c *cache) Put(ctx context.Context, key string, value string) error {
-	c.lru.Add(key, value)
+	entry := &memoryCacheEntry{value: value}
+	if c.ttl > 0 {
+		entry.expiresAt = time.Now().Add(c.ttl)
+	}
+	c.lru.Add(key, entry)
+	return nil
+}
+
+func (c *cache) Tag(ctx context.Context, tag string, key string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	keys, ok := c.tags.Get(tag)
+	if !ok {
+		keys = make(map[string]struct{})
+		c.tags.Add(tag, keys)
+	}
+	keys.(map[string]struct{})[key] = struct{}{}
+	return nil
+}
+
+func (c *cache) Purge(ctx context.Context, tag string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	keys, ok := c.tags.Get(tag)
+	if !ok {
+		return nil
+	}
+	for key := range keys.(map[string]struct{}) {
+		c.lru.Remove(key)
+	}
+	c.tags.Remove(tag)
 	return nil
 }
 
 type redisCache struct {
 	rdb *redis.Client
+	ttl time.Duration
 }
 
-func newRedisCache(url string) (*redisCache, error) {
+func newRedisCache(url string, ttl time.Duration) (*redisCache, error) {
 	opts, err := redis.ParseURL(url)
 	if err != nil {
 		return nil, err
@@ -55,7 +118,10 @@ func newRedisCache(url string) (*redisCache, error) {
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		return nil, wrapErr(err, "error connecting to redis")
 	}
-	return &redisCache{rdb}, nil
+	if ttl <= 0 {
+		ttl = redisTTL
+	}
+	return &redisCache{rdb, ttl}, nil
 }
 
 func (c *redisCache) Get(ctx context.Context, key string) (string, error) {
@@ -74,7 +140,7 @@ func (c *redisCache) Get(ctx context.Context, key string) (string, error) {
 
 func (c *redisCache) Put(ctx context.Context, key string, value string) error {
 	start := time.Now()
-	err := c.rdb.SetEX(ctx, key, value, redisTTL).Err()
+	err := c.rdb.SetEX(ctx, key, value, c.ttl).Err()
 	redisCacheDurationSumm.WithLabelValues("SETEX").Observe(float64(time.Since(start).Milliseconds()))
 
 	if err != nil {
@@ -83,6 +149,35 @@ func (c *redisCache) Put(ctx context.Context, key string, value string) error {
 	return err
 }
 
+func (c *redisCache) Tag(ctx context.Context, tag string, key string) error {
+	start := time.Now()
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, tag, key)
+		pipe.Expire(ctx, tag, c.ttl)
+		return nil
+	})
+	redisCacheDurationSumm.WithLabelValues("SADD").Observe(float64(time.Since(start).Milliseconds()))
+
+	if err != nil {
+		RecordRedisError("CacheTag")
+	}
+	return err
+}
+
+func (c *redisCache) Purge(ctx context.Context, tag string) error {
+	start := time.Now()
+	keys, err := c.rdb.SMembers(ctx, tag).Result()
+	if err == nil {
+		err = c.rdb.Del(ctx, append(keys, tag)...).Err()
+	}
+	redisCacheDurationSumm.WithLabelValues("DEL").Observe(float64(time.Since(start).Milliseconds()))
+
+	if err != nil {
+		RecordRedisError("CachePurge")
+	}
+	return err
+}
+
 type cacheWithCompression struct {
 	cache Cache
 }
@@ -111,6 +206,25 @@ func (c *cacheWithCompression) Put(ctx context.Context, key string, value string
 	return c.cache.Put(ctx, key, string(encodedVal))
 }
 
+func (c *cacheWithCompression) Tag(ctx context.Context, tag string, key string) error {
+	return c.cache.Tag(ctx, tag, key)
+}
+
+func (c *cacheWithCompression) Purge(ctx context.Context, tag string) error {
+	return c.cache.Purge(ctx, tag)
+}
+
+// blockCacheTag is the tag of the cache entries holding data of a block.
+func blockCacheTag(blockNum uint64) string {
+	return fmt.Sprintf("block:%d", blockNum)
+}
+
+// InvalidateBlock purges the cache entries holding data of a block, once the
+// block has been rolled back.
+func InvalidateBlock(ctx context.Context, cache Cache, blockNum uint64) error {
+	return cache.Purge(ctx, blockCacheTag(blockNum))
+}
+
 type GetLatestBlockNumFn func(ctx context.Context) (uint64, error)
 type GetLatestGasPriceFn func(ctx context.Context) (uint64, error)
 
@@ -126,13 +240,16 @@ type rpcCache struct {
 
 func newRPCCache(cache Cache, getLatestBlockNumFn GetLatestBlockNumFn, getLatestGasPriceFn GetLatestGasPriceFn, numBlockConfirmations int) RPCCache {
 	handlers := map[string]RPCMethodHandler{
-		"eth_chainId":          &StaticMethodHandler{},
-		"net_version":          &StaticMethodHandler{},
-		"eth_getBlockByNumber": &EthGetBlockByNumberMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
-		"eth_getBlockRange":    &EthGetBlockRangeMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
-		"eth_blockNumber":      &EthBlockNumberMethodHandler{getLatestBlockNumFn},
-		"eth_gasPrice":         &EthGasPriceMethodHandler{getLatestGasPriceFn},
-		"eth_call":             &EthCallMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_chainId":               &StaticMethodHandler{},
+		"net_version":               &StaticMethodHandler{},
+		"eth_getBlockByNumber":      &EthGetBlockByNumberMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_getBlockRange":         &EthGetBlockRangeMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_blockNumber":           &EthBlockNumberMethodHandler{getLatestBlockNumFn},
+		"eth_gasPrice":              &EthGasPriceMethodHandler{getLatestGasPriceFn},
+		"eth_call":                  &EthCallMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_getCode":               &EthGetCodeMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_getStorageAt":          &EthGetStorageAtMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_getTransactionReceipt": &EthGetTransactionReceiptMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
 	}
 	return &rpcCache{
 		cache:    cache,
@@ -2,10 +2,13 @@ package proxyd
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,7 +21,7 @@ func TestRPCCacheImmutableRPCs(t *testing.T) {
 	getBlockNum := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), getBlockNum, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), getBlockNum, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -134,7 +137,7 @@ func TestRPCCacheBlockNumber(t *testing.T) {
 	getBlockNum := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), getBlockNum, getGasPrice, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), getBlockNum, getGasPrice, numBlockConfirmations)
 
 	req := &RPCReq{
 		JSONRPC: "2.0",
@@ -172,7 +175,7 @@ func TestRPCCacheGasPrice(t *testing.T) {
 	getBlockNum := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), getBlockNum, getGasPrice, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), getBlockNum, getGasPrice, numBlockConfirmations)
 
 	req := &RPCReq{
 		JSONRPC: "2.0",
@@ -205,7 +208,7 @@ func TestRPCCacheUnsupportedMethod(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -234,7 +237,9 @@ func TestRPCCacheEthGetBlockByNumber(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations) }
+	makeCache := func() RPCCache {
+		return newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+	}
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -290,7 +295,7 @@ func TestRPCCacheEthGetBlockByNumberForRecentBlocks(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -347,7 +352,7 @@ func TestRPCCacheEthGetBlockByNumberInvalidRequest(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -377,7 +382,9 @@ func TestRPCCacheEthGetBlockRange(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations) }
+	makeCache := func() RPCCache {
+		return newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+	}
 	ID := []byte(strconv.Itoa(1))
 
 	t.Run("finalized block", func(t *testing.T) {
@@ -427,7 +434,7 @@ func TestRPCCacheEthGetBlockRangeForRecentBlocks(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -498,7 +505,7 @@ func TestRPCCacheEthGetBlockRangeInvalidRequest(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -556,7 +563,9 @@ func TestRPCCacheEthCall(t *testing.T) {
 		return blockHead, nil
 	}
 
-	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations) }
+	makeCache := func() RPCCache {
+		return newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+	}
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -620,3 +629,200 @@ func TestRPCCacheEthCall(t *testing.T) {
 		require.Nil(t, cachedRes)
 	})
 }
+
+func TestRPCCacheBlockState(t *testing.T) {
+	ctx := context.Background()
+
+	var blockHead uint64
+	fn := func(ctx context.Context) (uint64, error) {
+		return blockHead, nil
+	}
+	ID := []byte(strconv.Itoa(1))
+
+	tests := []struct {
+		name      string
+		method    string
+		params    string
+		blockHead uint64
+		cached    bool
+	}{
+		{"eth_getCode finalized block", "eth_getCode", `["0xDEADBEEF", "0x10"]`, 0x100, true},
+		{"eth_getCode earliest", "eth_getCode", `["0xDEADBEEF", "earliest"]`, 0x100, true},
+		{"eth_getCode unconfirmed block", "eth_getCode", `["0xDEADBEEF", "0x10"]`, 0x10, false},
+		{"eth_getCode latest", "eth_getCode", `["0xDEADBEEF", "latest"]`, 0x100, false},
+		{"eth_getStorageAt finalized block", "eth_getStorageAt", `["0xDEADBEEF", "0x0", "0x10"]`, 0x100, true},
+		{"eth_getStorageAt unconfirmed block", "eth_getStorageAt", `["0xDEADBEEF", "0x0", "0x10"]`, 0x10, false},
+		{"eth_getStorageAt pending", "eth_getStorageAt", `["0xDEADBEEF", "0x0", "pending"]`, 0x100, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockHead = tt.blockHead
+			cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+			req := &RPCReq{
+				JSONRPC: "2.0",
+				Method:  tt.method,
+				Params:  []byte(tt.params),
+				ID:      ID,
+			}
+			res := &RPCRes{
+				JSONRPC: "2.0",
+				Result:  "0x1",
+				ID:      ID,
+			}
+			require.NoError(t, cache.PutRPC(ctx, req, res))
+			cachedRes, err := cache.GetRPC(ctx, req)
+			require.NoError(t, err)
+			if tt.cached {
+				require.Equal(t, res, cachedRes)
+			} else {
+				require.Nil(t, cachedRes)
+			}
+		})
+	}
+}
+
+func TestRPCCacheEthGetTransactionReceipt(t *testing.T) {
+	ctx := context.Background()
+
+	var blockHead uint64
+	fn := func(ctx context.Context) (uint64, error) {
+		return blockHead, nil
+	}
+	ID := []byte(strconv.Itoa(1))
+
+	req := &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getTransactionReceipt",
+		Params:  []byte(`["0xABCD"]`),
+		ID:      ID,
+	}
+	res := &RPCRes{
+		JSONRPC: "2.0",
+		Result:  map[string]interface{}{"blockNumber": "0x10", "status": "0x1"},
+		ID:      ID,
+	}
+
+	t.Run("confirmed receipt", func(t *testing.T) {
+		blockHead = 0x100
+		cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, res, cachedRes)
+	})
+
+	t.Run("unconfirmed receipt", func(t *testing.T) {
+		blockHead = 0x10
+		cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+
+	t.Run("pending transaction", func(t *testing.T) {
+		blockHead = 0x100
+		cache := newRPCCache(newMemoryCache(memoryCacheLimit, 0), fn, nil, numBlockConfirmations)
+		require.NoError(t, cache.PutRPC(ctx, req, &RPCRes{JSONRPC: "2.0", ID: ID}))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+}
+
+func TestRPCCacheInvalidateBlock(t *testing.T) {
+	ctx := context.Background()
+	fn := func(ctx context.Context) (uint64, error) {
+		return 0x100, nil
+	}
+	ID := []byte(strconv.Itoa(1))
+
+	store := newMemoryCache(memoryCacheLimit, 0)
+	cache := newRPCCache(store, fn, nil, numBlockConfirmations)
+	reqs := []*RPCReq{
+		{JSONRPC: "2.0", Method: "eth_getBlockByNumber", Params: []byte(`["0x10", false]`), ID: ID},
+		{JSONRPC: "2.0", Method: "eth_getBlockRange", Params: []byte(`["0x1", "0x10", false]`), ID: ID},
+		{JSONRPC: "2.0", Method: "eth_call", Params: []byte(`[{"to": "0xDEADBEEF"}, "0x10"]`), ID: ID},
+		{JSONRPC: "2.0", Method: "eth_getCode", Params: []byte(`["0xDEADBEEF", "0x10"]`), ID: ID},
+	}
+	other := &RPCReq{JSONRPC: "2.0", Method: "eth_getBlockByNumber", Params: []byte(`["0x11", false]`), ID: ID}
+	res := &RPCRes{JSONRPC: "2.0", Result: "0x1", ID: ID}
+
+	for _, req := range append(reqs, other) {
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+	}
+	require.NoError(t, InvalidateBlock(ctx, store, 0x10))
+
+	for _, req := range reqs {
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes, req.Method)
+	}
+	cachedRes, err := cache.GetRPC(ctx, other)
+	require.NoError(t, err)
+	require.Equal(t, res, cachedRes)
+}
+
+func TestCacheBackends(t *testing.T) {
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+	redisCache, err := newRedisCache(fmt.Sprintf("redis://%s", redis.Addr()), time.Minute)
+	require.NoError(t, err)
+
+	backends := []struct {
+		name    string
+		cache   Cache
+		advance func(d time.Duration)
+	}{
+		{"memory", newMemoryCache(memoryCacheLimit, time.Minute), nil},
+		{"redis", redisCache, redis.FastForward},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newCacheWithCompression(backend.cache)
+
+			require.NoError(t, c.Put(ctx, "a", "1"))
+			require.NoError(t, c.Put(ctx, "b", "2"))
+			require.NoError(t, c.Put(ctx, "c", "3"))
+			require.NoError(t, c.Tag(ctx, "tag", "a"))
+			require.NoError(t, c.Tag(ctx, "tag", "b"))
+			require.NoError(t, c.Purge(ctx, "tag"))
+			require.NoError(t, c.Purge(ctx, "unknown"))
+
+			for key, want := range map[string]string{"a": "", "b": "", "c": "3"} {
+				val, err := c.Get(ctx, key)
+				require.NoError(t, err)
+				require.Equal(t, want, val, key)
+			}
+
+			if backend.advance != nil {
+				backend.advance(2 * time.Minute)
+			} else {
+				entry, _ := backend.cache.(*cache).lru.Get("c")
+				entry.(*memoryCacheEntry).expiresAt = time.Now().Add(-time.Second)
+			}
+			val, err := c.Get(ctx, "c")
+			require.NoError(t, err)
+			require.Empty(t, val)
+		})
+	}
+}
+
+func TestMemoryCacheLimit(t *testing.T) {
+	ctx := context.Background()
+	cache := newMemoryCache(2, 0)
+	require.NoError(t, cache.Put(ctx, "a", "1"))
+	require.NoError(t, cache.Put(ctx, "b", "2"))
+	require.NoError(t, cache.Put(ctx, "c", "3"))
+
+	val, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Empty(t, val)
+	val, err = cache.Get(ctx, "c")
+	require.NoError(t, err)
+	require.Equal(t, "3", val)
+}
@@ -5,7 +5,6 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/BurntSushi/toml"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mantlenetworkio/mantle/proxyd"
 )
@@ -32,19 +31,30 @@ func main() {
 		log.Crit("must specify a config file on the command line")
 	}
 
-	config := new(proxyd.Config)
-	if _, err := toml.DecodeFile(os.Args[1], config); err != nil {
+	config, err := proxyd.ReadConfigFile(os.Args[1])
+	if err != nil {
 		log.Crit("error reading config file", "err", err)
 	}
 
-	shutdown, err := proxyd.Start(config)
+	srv, shutdown, err := proxyd.Start(config)
 	if err != nil {
 		log.Crit("error starting proxyd", "err", err)
 	}
 
+	watcher := proxyd.NewConfigWatcher(os.Args[1], srv, config.Reload)
+	watcher.Start()
+
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	recvSig := <-sig
-	log.Info("caught signal, shutting down", "signal", recvSig)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for recvSig := range sig {
+		if recvSig == syscall.SIGHUP {
+			log.Info("caught signal, reloading config", "signal", recvSig)
+			watcher.Reload()
+			continue
+		}
+		log.Info("caught signal, shutting down", "signal", recvSig)
+		break
+	}
+	watcher.Stop()
 	shutdown()
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/BurntSushi/toml"
 )
 
 type ServerConfig struct {
@@ -27,6 +29,9 @@ type CacheConfig struct {
 	Enabled               bool   `toml:"enabled"`
 	BlockSyncRPCURL       string `toml:"block_sync_rpc_url"`
 	NumBlockConfirmations int    `toml:"num_block_confirmations"`
+	TTLSeconds            int    `toml:"ttl_seconds"`
+	MemoryMaxEntries      int    `toml:"memory_max_entries"`
+	ReorgWindow           int    `toml:"reorg_window"`
 }
 
 type RedisConfig struct {
@@ -46,6 +51,24 @@ type RateLimitConfig struct {
 	ErrorMessage     string   `toml:"error_message"`
 }
 
+type TxValidationConfig struct {
+	Enabled                   bool   `toml:"enabled"`
+	ChainID                   uint64 `toml:"chain_id"`
+	MaxCalldataBytes          int    `toml:"max_calldata_bytes"`
+	MinGasPriceWei            uint64 `toml:"min_gas_price_wei"`
+	SenderRatePerInterval     int    `toml:"sender_rate_per_interval"`
+	SenderRateIntervalSeconds int    `toml:"sender_rate_interval_seconds"`
+	DedupeWindowSeconds       int    `toml:"dedupe_window_seconds"`
+}
+
+type GetLogsConfig struct {
+	Enabled        bool   `toml:"enabled"`
+	MaxBlockRange  uint64 `toml:"max_block_range"`
+	SplitRanges    bool   `toml:"split_ranges"`
+	ChunkSize      uint64 `toml:"chunk_size"`
+	MaxConcurrency int    `toml:"max_concurrency"`
+}
+
 type BackendOptions struct {
 	ResponseTimeoutSeconds int   `toml:"response_timeout_seconds"`
 	MaxResponseSizeBytes   int64 `toml:"max_response_size_bytes"`
@@ -64,18 +87,73 @@ type BackendConfig struct {
 	ClientCertFile   string `toml:"client_cert_file"`
 	ClientKeyFile    string `toml:"client_key_file"`
 	StripTrailingXFF bool   `toml:"strip_trailing_xff"`
+	Weight           int    `toml:"weight"`
 }
 
 type BackendsConfig map[string]*BackendConfig
 
 type BackendGroupConfig struct {
-	Backends []string `toml:"backends"`
+	Backends        []string `toml:"backends"`
+	RoutingStrategy string   `toml:"routing_strategy"`
+
+	ConsensusAware                     bool   `toml:"consensus_aware"`
+	ConsensusPollIntervalSeconds       int    `toml:"consensus_poll_interval_seconds"`
+	ConsensusBanPeriodSeconds          int    `toml:"consensus_ban_period_seconds"`
+	ConsensusMaxUpdateThresholdSeconds int    `toml:"consensus_max_update_threshold_seconds"`
+	ConsensusMaxBlockLag               uint64 `toml:"consensus_max_block_lag"`
+	ConsensusSafeBlockDepth            uint64 `toml:"consensus_safe_block_depth"`
+
+	// ShadowBackends receive a sampled copy of the requests served by the
+	// group. Their responses are only compared against the primary ones.
+	ShadowBackends    []string `toml:"shadow_backends"`
+	ShadowSampleRate  float64  `toml:"shadow_sample_rate"`
+	ShadowMaxInFlight int      `toml:"shadow_max_in_flight"`
+	// ShadowMethods replaces the default allowlist of read-only methods
+	// mirrored to the shadow backends.
+	ShadowMethods []string `toml:"shadow_methods"`
 }
 
 type BackendGroupsConfig map[string]*BackendGroupConfig
 
 type MethodMappingsConfig map[string]string
 
+type MethodRouteConfig struct {
+	BackendGroup string `toml:"backend_group"`
+	// FallbackBackendGroups are tried in order when no backend of the
+	// previous group is available.
+	FallbackBackendGroups []string `toml:"fallback_backend_groups"`
+	// TimeoutSeconds overrides backend.response_timeout_seconds.
+	TimeoutSeconds int `toml:"timeout_seconds"`
+}
+
+// MethodRoutesConfig maps method names or patterns ending with a `*`
+// wildcard, such as `debug_*`, to their route.
+type MethodRoutesConfig map[string]*MethodRouteConfig
+
+type APIKeyConfig struct {
+	AllowedMethods []string `toml:"allowed_methods"`
+	DeniedMethods  []string `toml:"denied_methods"`
+	RatePerSecond  int      `toml:"rate_per_second"`
+	DailyQuota     int      `toml:"daily_quota"`
+
+	// MaxLogsBlockRange overrides the global eth_getLogs range limit if set.
+	MaxLogsBlockRange uint64 `toml:"max_logs_block_range"`
+}
+
+// APIKeysConfig maps authentication aliases to their policy.
+type APIKeysConfig map[string]*APIKeyConfig
+
+type AdminConfig struct {
+	Token string `toml:"token"`
+}
+
+type ReloadConfig struct {
+	// WatchFile reloads the config whenever the file changes. SIGHUP
+	// always triggers a reload.
+	WatchFile           bool `toml:"watch_file"`
+	PollIntervalSeconds int  `toml:"poll_interval_seconds"`
+}
+
 type Config struct {
 	WSBackendGroup    string              `toml:"ws_backend_group"`
 	Server            ServerConfig        `toml:"server"`
@@ -83,12 +161,23 @@ type Config struct {
 	Redis             RedisConfig         `toml:"redis"`
 	Metrics           MetricsConfig       `toml:"metrics"`
 	RateLimit         RateLimitConfig     `toml:"rate_limit"`
+	TxValidation      TxValidationConfig  `toml:"tx_validation"`
+	GetLogs           GetLogsConfig       `toml:"get_logs"`
 	BackendOptions    BackendOptions      `toml:"backend"`
 	Backends          BackendsConfig      `toml:"backends"`
 	Authentication    map[string]string   `toml:"authentication"`
+	APIKeys           APIKeysConfig       `toml:"api_keys"`
+	Admin             AdminConfig         `toml:"admin"`
+	Reload            ReloadConfig        `toml:"reload"`
 	BackendGroups     BackendGroupsConfig `toml:"backend_groups"`
 	RPCMethodMappings map[string]string   `toml:"rpc_method_mappings"`
+	RPCMethodRoutes   MethodRoutesConfig  `toml:"rpc_method_routes"`
 	WSMethodWhitelist []string            `toml:"ws_method_whitelist"`
+
+	// WSMultiplexSubscriptions makes proxyd terminate eth_subscribe calls
+	// and share upstream subscriptions between clients.
+	WSMultiplexSubscriptions bool   `toml:"ws_multiplex_subscriptions"`
+	WSMaxGapFillBlocks       uint64 `toml:"ws_max_gap_fill_blocks"`
 }
 
 func ReadFromEnvOrConfig(value string) (string, error) {
@@ -106,3 +195,11 @@ func ReadFromEnvOrConfig(value string) (string, error) {
 
 	return value, nil
 }
+
+func ReadConfigFile(path string) (*Config, error) {
+	config := new(Config)
+	if _, err := toml.DecodeFile(path, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
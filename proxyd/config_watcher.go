@@ -0,0 +1,120 @@
+package proxyd
+
+import (
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultConfigPollInterval = 5 * time.Second
+
+// ConfigWatcher reloads the config file into the server when Reload is
+// called, for example on SIGHUP, and, if enabled, whenever the file changes.
+// Changes are detected by polling so that editors and config management
+// tools that replace the file instead of writing it in place are supported.
+type ConfigWatcher struct {
+	path         string
+	srv          *Server
+	watchFile    bool
+	pollInterval time.Duration
+
+	modTime time.Time
+	size    int64
+
+	reloadC chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func NewConfigWatcher(path string, srv *Server, cfg ReloadConfig) *ConfigWatcher {
+	pollInterval := secondsToDuration(cfg.PollIntervalSeconds)
+	if pollInterval == 0 {
+		pollInterval = defaultConfigPollInterval
+	}
+	return &ConfigWatcher{
+		path:         path,
+		srv:          srv,
+		watchFile:    cfg.WatchFile,
+		pollInterval: pollInterval,
+		reloadC:      make(chan struct{}, 1),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+func (w *ConfigWatcher) Start() {
+	w.modTime, w.size, _ = w.stat()
+	go w.loop()
+}
+
+func (w *ConfigWatcher) Stop() {
+	close(w.quit)
+	<-w.done
+}
+
+// Reload requests a reload of the config file. Requests made while a reload
+// is pending are coalesced.
+func (w *ConfigWatcher) Reload() {
+	select {
+	case w.reloadC <- struct{}{}:
+	default:
+	}
+}
+
+func (w *ConfigWatcher) loop() {
+	defer close(w.done)
+
+	var tickC <-chan time.Time
+	if w.watchFile {
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+		tickC = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-w.reloadC:
+			w.reload()
+		case <-tickC:
+			modTime, size, err := w.stat()
+			if err != nil {
+				log.Warn("error checking config file", "path", w.path, "err", err)
+				continue
+			}
+			if modTime.Equal(w.modTime) && size == w.size {
+				continue
+			}
+			log.Info("config file changed", "path", w.path)
+			w.reload()
+		}
+	}
+}
+
+func (w *ConfigWatcher) reload() {
+	// record the file state first so that a broken file is not reloaded on
+	// every tick
+	if modTime, size, err := w.stat(); err == nil {
+		w.modTime, w.size = modTime, size
+	}
+
+	config, err := ReadConfigFile(w.path)
+	if err != nil {
+		RecordConfigReload(err)
+		log.Error("error reading config file, keeping the current config", "path", w.path, "err", err)
+		return
+	}
+	if err := w.srv.Reload(config); err != nil {
+		log.Error("error reloading config, keeping the current config", "path", w.path, "err", err)
+	}
+}
+
+func (w *ConfigWatcher) stat() (time.Time, int64, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return info.ModTime(), info.Size(), nil
+}
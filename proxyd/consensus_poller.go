@@ -0,0 +1,445 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultConsensusPollInterval       = 1 * time.Second
+	defaultConsensusBanPeriod          = 5 * time.Minute
+	defaultConsensusMaxUpdateThreshold = 30 * time.Second
+)
+
+// ConsensusPoller polls the head of every backend in a group, agrees on a
+// latest block that all healthy backends share and keeps track of which
+// backends are currently part of that consensus.
+type ConsensusPoller struct {
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+
+	backendGroup *BackendGroup
+	backendState map[*Backend]*backendState
+
+	consensusGroupMux sync.RWMutex
+	consensusGroup    []*Backend
+	latestBlockNumber hexutil.Uint64
+	latestBlockHash   string
+	safeBlockNumber   hexutil.Uint64
+
+	pollInterval       time.Duration
+	banPeriod          time.Duration
+	maxUpdateThreshold time.Duration
+	maxBlockLag        uint64
+	safeBlockDepth     uint64
+}
+
+type backendState struct {
+	backendStateMux sync.Mutex
+
+	latestBlockNumber hexutil.Uint64
+	latestBlockHash   string
+
+	lastUpdate  time.Time
+	bannedUntil time.Time
+}
+
+type ConsensusOpt func(cp *ConsensusPoller)
+
+func WithConsensusPollInterval(interval time.Duration) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.pollInterval = interval
+	}
+}
+
+func WithConsensusBanPeriod(banPeriod time.Duration) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.banPeriod = banPeriod
+	}
+}
+
+func WithConsensusMaxUpdateThreshold(maxUpdateThreshold time.Duration) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.maxUpdateThreshold = maxUpdateThreshold
+	}
+}
+
+func WithConsensusMaxBlockLag(maxBlockLag uint64) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.maxBlockLag = maxBlockLag
+	}
+}
+
+func WithConsensusSafeBlockDepth(depth uint64) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.safeBlockDepth = depth
+	}
+}
+
+func NewConsensusPoller(bg *BackendGroup, opts ...ConsensusOpt) *ConsensusPoller {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	state := make(map[*Backend]*backendState, len(bg.Backends))
+	for _, be := range bg.Backends {
+		state[be] = &backendState{}
+	}
+
+	cp := &ConsensusPoller{
+		ctx:                ctx,
+		cancelFunc:         cancelFunc,
+		backendGroup:       bg,
+		backendState:       state,
+		pollInterval:       defaultConsensusPollInterval,
+		banPeriod:          defaultConsensusBanPeriod,
+		maxUpdateThreshold: defaultConsensusMaxUpdateThreshold,
+	}
+
+	for _, opt := range opts {
+		opt(cp)
+	}
+
+	return cp
+}
+
+// Start polls the backends once, so that the group has a consensus before it
+// serves any request, then keeps polling them in the background until Stop is
+// called.
+func (cp *ConsensusPoller) Start() {
+	cp.poll(cp.ctx)
+
+	go func() {
+		ticker := time.NewTicker(cp.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-cp.ctx.Done():
+				return
+			}
+
+			cp.poll(cp.ctx)
+		}
+	}()
+}
+
+func (cp *ConsensusPoller) Stop() {
+	cp.cancelFunc()
+}
+
+func (cp *ConsensusPoller) poll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, be := range cp.backendGroup.Backends {
+		wg.Add(1)
+		go func(be *Backend) {
+			defer wg.Done()
+			cp.UpdateBackend(ctx, be)
+		}(be)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+	cp.UpdateBackendGroupConsensus(ctx)
+}
+
+// GetConsensusGroup returns the backends that currently agree on the consensus block.
+func (cp *ConsensusPoller) GetConsensusGroup() []*Backend {
+	cp.consensusGroupMux.RLock()
+	defer cp.consensusGroupMux.RUnlock()
+
+	out := make([]*Backend, len(cp.consensusGroup))
+	copy(out, cp.consensusGroup)
+	return out
+}
+
+func (cp *ConsensusPoller) GetLatestBlockNumber() hexutil.Uint64 {
+	cp.consensusGroupMux.RLock()
+	defer cp.consensusGroupMux.RUnlock()
+	return cp.latestBlockNumber
+}
+
+func (cp *ConsensusPoller) GetSafeBlockNumber() hexutil.Uint64 {
+	cp.consensusGroupMux.RLock()
+	defer cp.consensusGroupMux.RUnlock()
+	return cp.safeBlockNumber
+}
+
+// UpdateBackend refreshes the latest block of a single backend.
+func (cp *ConsensusPoller) UpdateBackend(ctx context.Context, be *Backend) {
+	if cp.IsBanned(be) {
+		log.Debug("skipping banned backend", "backend_group", cp.backendGroup.Name, "name", be.Name)
+		return
+	}
+
+	if !be.Online() {
+		log.Warn("backend is offline, banning it from consensus", "backend_group", cp.backendGroup.Name, "name", be.Name)
+		cp.Ban(be)
+		return
+	}
+
+	latestBlockNumber, latestBlockHash, err := cp.fetchBlock(ctx, be, "latest")
+	if err != nil {
+		log.Warn(
+			"error updating backend",
+			"backend_group", cp.backendGroup.Name,
+			"name", be.Name,
+			"err", err,
+		)
+		return
+	}
+
+	changed := cp.setBackendState(be, latestBlockNumber, latestBlockHash)
+	RecordBackendLatestBlock(be, latestBlockNumber)
+
+	if changed {
+		log.Debug(
+			"backend state updated",
+			"backend_group", cp.backendGroup.Name,
+			"name", be.Name,
+			"latest_block_number", latestBlockNumber,
+			"latest_block_hash", latestBlockHash,
+		)
+	}
+}
+
+// UpdateBackendGroupConsensus walks back from the lowest head among the eligible
+// backends until every one of them agrees on the block hash at that height.
+// Backends that disagree on a block the group already agreed on are banned
+// instead, so that they don't drag the consensus back.
+func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
+	var highestBlock hexutil.Uint64
+	for _, be := range cp.backendGroup.Backends {
+		latestBlockNumber, _, lastUpdate, _ := cp.getBackendState(be)
+		if cp.IsBanned(be) || cp.isStale(lastUpdate) {
+			continue
+		}
+		if latestBlockNumber > highestBlock {
+			highestBlock = latestBlockNumber
+		}
+	}
+
+	var lowestBlock hexutil.Uint64
+	var lowestBlockHash string
+	for _, be := range cp.backendGroup.Backends {
+		latestBlockNumber, latestBlockHash, lastUpdate, _ := cp.getBackendState(be)
+		if cp.IsBanned(be) || cp.isStale(lastUpdate) || cp.isLagging(latestBlockNumber, highestBlock) {
+			continue
+		}
+		if lowestBlock == 0 || latestBlockNumber < lowestBlock {
+			lowestBlock = latestBlockNumber
+			lowestBlockHash = latestBlockHash
+		}
+	}
+
+	// no block to propose, either the poller is still initializing
+	// or none of the backends answered within the update threshold
+	if lowestBlock == 0 {
+		cp.consensusGroupMux.Lock()
+		cp.consensusGroup = nil
+		cp.consensusGroupMux.Unlock()
+		RecordGroupConsensusCount(cp.backendGroup, 0)
+		return
+	}
+
+	cp.consensusGroupMux.RLock()
+	currentConsensusBlockNumber := cp.latestBlockNumber
+	currentConsensusBlockHash := cp.latestBlockHash
+	cp.consensusGroupMux.RUnlock()
+
+	proposedBlock := lowestBlock
+	proposedBlockHash := lowestBlockHash
+	// the agreed block wins over whatever the lowest backend reports now
+	if proposedBlock == currentConsensusBlockNumber && currentConsensusBlockHash != "" {
+		proposedBlockHash = currentConsensusBlockHash
+	}
+	consensusBackends := make([]*Backend, 0, len(cp.backendGroup.Backends))
+	broken := false
+
+	for {
+		allAgreed := true
+		consensusBackends = consensusBackends[:0]
+
+		for _, be := range cp.backendGroup.Backends {
+			// a serving backend needs to be online, not banned,
+			// recently updated and not lagging behind the group
+			latestBlockNumber, _, lastUpdate, bannedUntil := cp.getBackendState(be)
+			if time.Now().Before(bannedUntil) ||
+				cp.isStale(lastUpdate) ||
+				cp.isLagging(latestBlockNumber, highestBlock) ||
+				latestBlockNumber < proposedBlock ||
+				!be.Online() {
+				continue
+			}
+
+			actualBlockNumber, actualBlockHash, err := cp.fetchBlock(ctx, be, proposedBlock.String())
+			if err != nil {
+				log.Warn(
+					"error fetching proposed block",
+					"backend_group", cp.backendGroup.Name,
+					"name", be.Name,
+					"proposed_block", proposedBlock,
+					"err", err,
+				)
+				continue
+			}
+			if proposedBlockHash == "" {
+				proposedBlockHash = actualBlockHash
+			}
+
+			if actualBlockNumber != proposedBlock || actualBlockHash != proposedBlockHash {
+				if currentConsensusBlockNumber >= actualBlockNumber {
+					log.Warn(
+						"backend broke consensus, banning it",
+						"backend_group", cp.backendGroup.Name,
+						"name", be.Name,
+						"block_number", actualBlockNumber,
+						"block_hash", actualBlockHash,
+						"proposed_block_hash", proposedBlockHash,
+					)
+					cp.Ban(be)
+					broken = true
+					continue
+				}
+				allAgreed = false
+				break
+			}
+
+			consensusBackends = append(consensusBackends, be)
+		}
+
+		if allAgreed || proposedBlock == 0 {
+			break
+		}
+
+		// walk one block behind and try again
+		proposedBlock -= 1
+		proposedBlockHash = ""
+		log.Debug("no consensus, now trying", "backend_group", cp.backendGroup.Name, "block", proposedBlock)
+	}
+
+	if broken {
+		RecordGroupConsensusBroken(cp.backendGroup)
+	}
+
+	var safeBlock hexutil.Uint64
+	if uint64(proposedBlock) > cp.safeBlockDepth {
+		safeBlock = proposedBlock - hexutil.Uint64(cp.safeBlockDepth)
+	}
+
+	cp.consensusGroupMux.Lock()
+	cp.consensusGroup = consensusBackends
+	cp.latestBlockNumber = proposedBlock
+	cp.latestBlockHash = proposedBlockHash
+	cp.safeBlockNumber = safeBlock
+	cp.consensusGroupMux.Unlock()
+
+	inConsensus := make(map[*Backend]bool, len(consensusBackends))
+	names := make([]string, 0, len(consensusBackends))
+	for _, be := range consensusBackends {
+		inConsensus[be] = true
+		names = append(names, be.Name)
+	}
+	for _, be := range cp.backendGroup.Backends {
+		latestBlockNumber, _, _, _ := cp.getBackendState(be)
+		RecordBackendBlockLag(cp.backendGroup, be, highestBlock, latestBlockNumber)
+		RecordBackendInConsensus(cp.backendGroup, be, inConsensus[be])
+		RecordBackendBanned(cp.backendGroup, be, cp.IsBanned(be))
+	}
+	RecordGroupConsensusLatestBlock(cp.backendGroup, proposedBlock)
+	RecordGroupConsensusSafeBlock(cp.backendGroup, safeBlock)
+	RecordGroupConsensusCount(cp.backendGroup, len(consensusBackends))
+
+	log.Debug(
+		"group state",
+		"backend_group", cp.backendGroup.Name,
+		"proposed_block", proposedBlock,
+		"safe_block", safeBlock,
+		"consensus_backends", strings.Join(names, ", "),
+	)
+}
+
+// IsBanned checks if a specific backend is banned
+func (cp *ConsensusPoller) IsBanned(be *Backend) bool {
+	bs := cp.backendState[be]
+	defer bs.backendStateMux.Unlock()
+	bs.backendStateMux.Lock()
+	return time.Now().Before(bs.bannedUntil)
+}
+
+// Ban bans a specific backend
+func (cp *ConsensusPoller) Ban(be *Backend) {
+	bs := cp.backendState[be]
+	defer bs.backendStateMux.Unlock()
+	bs.backendStateMux.Lock()
+	bs.bannedUntil = time.Now().Add(cp.banPeriod)
+}
+
+// Unban removes any bans from a specific backend
+func (cp *ConsensusPoller) Unban(be *Backend) {
+	bs := cp.backendState[be]
+	defer bs.backendStateMux.Unlock()
+	bs.backendStateMux.Lock()
+	bs.bannedUntil = time.Now().Add(-10 * time.Hour)
+}
+
+func (cp *ConsensusPoller) isStale(lastUpdate time.Time) bool {
+	return lastUpdate.Add(cp.maxUpdateThreshold).Before(time.Now())
+}
+
+func (cp *ConsensusPoller) isLagging(blockNumber, highestBlock hexutil.Uint64) bool {
+	return cp.maxBlockLag > 0 && uint64(blockNumber)+cp.maxBlockLag < uint64(highestBlock)
+}
+
+// fetchBlock is a convenient wrapper to make a request to get a block directly from the backend
+func (cp *ConsensusPoller) fetchBlock(ctx context.Context, be *Backend, block string) (hexutil.Uint64, string, error) {
+	var rpcRes RPCRes
+	err := be.ForwardRPC(ctx, &rpcRes, "67", "eth_getBlockByNumber", block, false)
+	if err != nil {
+		return 0, "", err
+	}
+
+	jsonMap, ok := rpcRes.Result.(map[string]interface{})
+	if !ok {
+		return 0, "", fmt.Errorf("unexpected response to eth_getBlockByNumber on backend %s", be.Name)
+	}
+	number, ok := jsonMap["number"].(string)
+	if !ok {
+		return 0, "", fmt.Errorf("block number missing in response from backend %s", be.Name)
+	}
+	blockNumber, err := hexutil.DecodeUint64(number)
+	if err != nil {
+		return 0, "", wrapErr(err, "error decoding block number")
+	}
+	hash, ok := jsonMap["hash"].(string)
+	if !ok {
+		return 0, "", fmt.Errorf("block hash missing in response from backend %s", be.Name)
+	}
+
+	return hexutil.Uint64(blockNumber), hash, nil
+}
+
+func (cp *ConsensusPoller) getBackendState(be *Backend) (hexutil.Uint64, string, time.Time, time.Time) {
+	bs := cp.backendState[be]
+	bs.backendStateMux.Lock()
+	defer bs.backendStateMux.Unlock()
+	return bs.latestBlockNumber, bs.latestBlockHash, bs.lastUpdate, bs.bannedUntil
+}
+
+func (cp *ConsensusPoller) setBackendState(be *Backend, blockNumber hexutil.Uint64, blockHash string) bool {
+	bs := cp.backendState[be]
+	bs.backendStateMux.Lock()
+	defer bs.backendStateMux.Unlock()
+	changed := bs.latestBlockHash != blockHash
+	bs.latestBlockNumber = blockNumber
+	bs.latestBlockHash = blockHash
+	bs.lastUpdate = time.Now()
+	return changed
+}
@@ -0,0 +1,277 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultGetLogsChunkSize      = 1000
+	defaultGetLogsMaxConcurrency = 4
+)
+
+// GetLogsHandler validates eth_getLogs ranges before they are forwarded.
+// Ranges above the allowed maximum are rejected, and ranges above the chunk
+// size can be split into chunks that fan out over the route and are merged
+// back in order.
+type GetLogsHandler struct {
+	maxBlockRange         uint64
+	split                 bool
+	chunkSize             uint64
+	maxConcurrency        int
+	getLatestBlockNumFn   GetLatestBlockNumFn
+	cache                 Cache
+	numBlockConfirmations int
+	apiKeyPolicies        *APIKeyPolicies
+}
+
+func NewGetLogsHandler(
+	cfg GetLogsConfig,
+	getLatestBlockNumFn GetLatestBlockNumFn,
+	cache Cache,
+	numBlockConfirmations int,
+	apiKeyPolicies *APIKeyPolicies,
+) *GetLogsHandler {
+	chunkSize := cfg.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultGetLogsChunkSize
+	}
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = defaultGetLogsMaxConcurrency
+	}
+	return &GetLogsHandler{
+		maxBlockRange:         cfg.MaxBlockRange,
+		split:                 cfg.SplitRanges,
+		chunkSize:             chunkSize,
+		maxConcurrency:        maxConcurrency,
+		getLatestBlockNumFn:   getLatestBlockNumFn,
+		cache:                 cache,
+		numBlockConfirmations: numBlockConfirmations,
+		apiKeyPolicies:        apiKeyPolicies,
+	}
+}
+
+// logsFilter keeps every field of the filter object so that chunk requests
+// only differ from the original request in their range.
+type logsFilter map[string]json.RawMessage
+
+// Handle returns a response if proxyd served the request itself, nil if the
+// request should be forwarded as-is, or an error if it must be rejected.
+func (h *GetLogsHandler) Handle(ctx context.Context, route *MethodRoute, req *RPCReq) (*RPCRes, error) {
+	filter, err := decodeGetLogsParams(req.Params)
+	if err != nil {
+		return nil, ErrInvalidParams(err.Error())
+	}
+
+	_, hasHash := filter["blockHash"]
+	_, hasFrom := filter["fromBlock"]
+	_, hasTo := filter["toBlock"]
+	if hasHash {
+		if hasFrom || hasTo {
+			return nil, ErrInvalidParams("cannot specify both blockHash and fromBlock/toBlock")
+		}
+		// block hash queries cover a single block
+		return nil, nil
+	}
+
+	latest, err := h.getLatestBlockNumFn(ctx)
+	if err != nil {
+		log.Error("error getting latest block number", "req_id", GetReqID(ctx), "err", err)
+		return nil, ErrInternal
+	}
+
+	from, err := resolveBlockTag(filter["fromBlock"], latest)
+	if err != nil {
+		return nil, ErrInvalidParams("invalid fromBlock")
+	}
+	to, err := resolveBlockTag(filter["toBlock"], latest)
+	if err != nil {
+		return nil, ErrInvalidParams("invalid toBlock")
+	}
+	// nothing exists above the head, don't count it against the range
+	if to > latest {
+		to = latest
+	}
+	// ranges starting past the head are left to the backends, which may
+	// have seen blocks the poller hasn't yet
+	if from > latest {
+		return nil, nil
+	}
+	if from > to {
+		return nil, ErrInvalidParams("invalid block range")
+	}
+
+	rangeSize := to - from + 1
+	maxBlockRange := h.maxBlockRangeFor(ctx)
+	if maxBlockRange > 0 && rangeSize > maxBlockRange {
+		RecordGetLogsRejection(ctx)
+		return nil, ErrInvalidParams(fmt.Sprintf("block range too large, maximum is %d blocks", maxBlockRange))
+	}
+
+	if !h.split || rangeSize <= h.chunkSize {
+		return nil, nil
+	}
+	return h.forwardChunks(ctx, route, req, filter, from, to, latest)
+}
+
+func (h *GetLogsHandler) maxBlockRangeFor(ctx context.Context) uint64 {
+	if h.apiKeyPolicies != nil {
+		if maxBlockRange, ok := h.apiKeyPolicies.MaxLogsBlockRange(ctx); ok {
+			return maxBlockRange
+		}
+	}
+	return h.maxBlockRange
+}
+
+type logsChunk struct {
+	from uint64
+	to   uint64
+}
+
+// chunkRange splits [from, to] into chunks aligned to multiples of the chunk
+// size, so that the same confirmed chunks are reused across requests.
+func chunkRange(from, to, size uint64) []logsChunk {
+	var chunks []logsChunk
+	for start := from; start <= to; {
+		end := (start/size+1)*size - 1
+		if end > to {
+			end = to
+		}
+		chunks = append(chunks, logsChunk{start, end})
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+	return chunks
+}
+
+func (h *GetLogsHandler) forwardChunks(ctx context.Context, route *MethodRoute, req *RPCReq, filter logsFilter, from, to, latest uint64) (*RPCRes, error) {
+	chunks := chunkRange(from, to, h.chunkSize)
+	RecordGetLogsSplit(ctx, len(chunks))
+
+	results := make([]*RPCRes, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, h.maxConcurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk logsChunk) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = h.forwardChunk(ctx, route, req, filter, chunk, latest)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	logs := make([]interface{}, 0)
+	for i, res := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if res.IsError() {
+			return NewRPCErrorRes(req.ID, res.Error), nil
+		}
+		if res.Result == nil {
+			continue
+		}
+		chunkLogs, ok := res.Result.([]interface{})
+		if !ok {
+			log.Warn("unexpected eth_getLogs result", "req_id", GetReqID(ctx), "from", chunks[i].from, "to", chunks[i].to)
+			return nil, ErrBackendBadResponse
+		}
+		logs = append(logs, chunkLogs...)
+	}
+	return makeRPCRes(req, logs), nil
+}
+
+func (h *GetLogsHandler) forwardChunk(ctx context.Context, route *MethodRoute, req *RPCReq, filter logsFilter, chunk logsChunk, latest uint64) (*RPCRes, error) {
+	chunkFilter := make(logsFilter, len(filter))
+	for k, v := range filter {
+		chunkFilter[k] = v
+	}
+	chunkFilter["fromBlock"] = mustMarshalJSON(hexutil.Uint64(chunk.from))
+	chunkFilter["toBlock"] = mustMarshalJSON(hexutil.Uint64(chunk.to))
+	chunkReq := &RPCReq{
+		JSONRPC: req.JSONRPC,
+		Method:  req.Method,
+		Params:  mustMarshalJSON([]logsFilter{chunkFilter}),
+		ID:      req.ID,
+	}
+
+	// only chunks below the confirmation depth are immutable
+	cacheable := h.cache != nil && latest > chunk.to+uint64(h.numBlockConfirmations)
+	var key string
+	if cacheable {
+		key = getLogsCacheKey(chunkFilter)
+		res, err := getImmutableRPCResponse(ctx, h.cache, key, chunkReq)
+		if err != nil {
+			log.Warn("cache get error", "req_id", GetReqID(ctx), "err", err)
+		} else if res != nil {
+			RecordCacheHit(req.Method)
+			return res, nil
+		}
+		RecordCacheMiss(req.Method)
+	}
+
+	// chunks fall back and time out like any other request to the route
+	res, err := route.Forward(ctx, []*RPCReq{chunkReq}, false)
+	if err != nil {
+		return nil, err
+	}
+	if cacheable && !res[0].IsError() && res[0].Result != nil {
+		if err := putBlockRPCResponse(ctx, h.cache, key, chunk.to, chunkReq, res[0]); err != nil {
+			log.Warn("cache put error", "req_id", GetReqID(ctx), "err", err)
+		}
+	}
+	return res[0], nil
+}
+
+func getLogsCacheKey(filter logsFilter) string {
+	// encoding/json sorts map keys, so equal filters hash the same
+	sum := sha256.Sum256(mustMarshalJSON(filter))
+	return fmt.Sprintf("method:eth_getLogs:%s", hex.EncodeToString(sum[:]))
+}
+
+func decodeGetLogsParams(params json.RawMessage) (logsFilter, error) {
+	var list []logsFilter
+	if err := json.Unmarshal(params, &list); err != nil {
+		return nil, errInvalidRPCParams
+	}
+	if len(list) != 1 || list[0] == nil {
+		return nil, errInvalidRPCParams
+	}
+	return list[0], nil
+}
+
+// resolveBlockTag turns a block parameter into a number. Missing values and
+// tags that follow the head resolve to the latest block.
+func resolveBlockTag(raw json.RawMessage, latest uint64) (uint64, error) {
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == `""` {
+		return latest, nil
+	}
+	var bn rpc.BlockNumber
+	if err := bn.UnmarshalJSON(raw); err != nil {
+		return 0, err
+	}
+	switch bn {
+	case rpc.EarliestBlockNumber:
+		return 0, nil
+	case rpc.LatestBlockNumber,
+		rpc.PendingBlockNumber,
+		rpc.SafeBlockNumber,
+		rpc.FinalizedBlockNumber:
+		return latest, nil
+	}
+	return uint64(bn.Int64()), nil
+}
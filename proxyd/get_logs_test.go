@@ -0,0 +1,90 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     uint64
+		to       uint64
+		size     uint64
+		expected []logsChunk
+	}{
+		{"single block", 5, 5, 10, []logsChunk{{5, 5}}},
+		{"within one chunk", 10, 19, 10, []logsChunk{{10, 19}}},
+		{"aligned", 0, 29, 10, []logsChunk{{0, 9}, {10, 19}, {20, 29}}},
+		{"unaligned", 5, 25, 10, []logsChunk{{5, 9}, {10, 19}, {20, 25}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, chunkRange(tt.from, tt.to, tt.size))
+		})
+	}
+}
+
+func TestGetLogsHandlerValidation(t *testing.T) {
+	latestFn := func(ctx context.Context) (uint64, error) {
+		return 1000, nil
+	}
+	policies := NewAPIKeyPolicies(APIKeysConfig{
+		"partner": {MaxLogsBlockRange: 10},
+	}, NewLocalBackendRateLimiter())
+	h := NewGetLogsHandler(GetLogsConfig{
+		Enabled:       true,
+		MaxBlockRange: 100,
+	}, latestFn, nil, 0, policies)
+
+	tests := []struct {
+		name    string
+		auth    string
+		filter  map[string]interface{}
+		errCode int
+		errMsg  string
+	}{
+		{"no range", "", map[string]interface{}{}, 0, ""},
+		{"within limit", "", map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x64"}, 0, ""},
+		{"over limit", "", map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x65"}, -32602, "block range too large, maximum is 100 blocks"},
+		{"earliest to latest", "", map[string]interface{}{"fromBlock": "earliest", "toBlock": "latest"}, -32602, "block range too large, maximum is 100 blocks"},
+		{"range above head is clamped", "", map[string]interface{}{"fromBlock": "0x3e0", "toBlock": "0xffff"}, 0, ""},
+		{"range past head", "", map[string]interface{}{"fromBlock": "0x3e9", "toBlock": "latest"}, 0, ""},
+		{"inverted range past head", "", map[string]interface{}{"fromBlock": "0x7d0", "toBlock": "0x3e9"}, 0, ""},
+		{"block hash", "", map[string]interface{}{"blockHash": "0x1234"}, 0, ""},
+		{"block hash and range", "", map[string]interface{}{"blockHash": "0x1234", "fromBlock": "0x1"}, -32602, "cannot specify both blockHash and fromBlock/toBlock"},
+		{"inverted range", "", map[string]interface{}{"fromBlock": "0x10", "toBlock": "0x1"}, -32602, "invalid block range"},
+		{"bad tag", "", map[string]interface{}{"fromBlock": "foo"}, -32602, "invalid fromBlock"},
+		{"key override", "partner", map[string]interface{}{"fromBlock": "0x1", "toBlock": "0xb"}, -32602, "block range too large, maximum is 10 blocks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.auth != "" {
+				ctx = contextWithAuth(tt.auth)
+			}
+			params, err := json.Marshal([]interface{}{tt.filter})
+			require.NoError(t, err)
+			res, err := h.Handle(ctx, nil, &RPCReq{
+				JSONRPC: JSONRPCVersion,
+				Method:  "eth_getLogs",
+				Params:  params,
+				ID:      []byte("1"),
+			})
+			require.Nil(t, res)
+			if tt.errCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+			rpcErr, ok := err.(*RPCErr)
+			require.True(t, ok)
+			require.Equal(t, tt.errCode, rpcErr.Code)
+			require.Equal(t, tt.errMsg, rpcErr.Message)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	keyMethodNotWhitelistedResponse = `{"jsonrpc":"2.0","error":{"code":-32001,"message":"rpc method is not whitelisted"},"id":999}`
+	keyOverRateLimitResponse        = `{"jsonrpc":"2.0","error":{"code":-32016,"message":"rate limited"},"id":999}`
+	keyOverQuotaResponse            = `{"jsonrpc":"2.0","error":{"code":-32018,"message":"daily quota exceeded"},"id":999}`
+)
+
+func TestAPIKeyPolicies(t *testing.T) {
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+
+	goodBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redis.Port())))
+
+	config := ReadConfig("api_keys")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	partner := NewProxydClient("http://127.0.0.1:8545/partner_secret")
+	limited := NewProxydClient("http://127.0.0.1:8545/limited_secret")
+	internal := NewProxydClient("http://127.0.0.1:8545/internal_secret")
+
+	t.Run("denied methods are rejected", func(t *testing.T) {
+		res, statusCode, err := partner.SendRPC("debug_traceTransaction", nil)
+		require.NoError(t, err)
+		require.Equal(t, 403, statusCode)
+		RequireEqualJSON(t, []byte(keyMethodNotWhitelistedResponse), res)
+	})
+
+	t.Run("keys without a policy are unrestricted", func(t *testing.T) {
+		res, statusCode, err := internal.SendRPC("debug_traceTransaction", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+	})
+
+	t.Run("authenticated requests keep the client address", func(t *testing.T) {
+		goodBackend.Reset()
+		_, statusCode, err := internal.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		require.Len(t, goodBackend.Requests(), 1)
+		require.Equal(t, "127.0.0.1", goodBackend.Requests()[0].Headers.Get("X-Forwarded-For"))
+	})
+
+	t.Run("daily quota is enforced", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			res, statusCode, err := partner.SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			require.Equal(t, 200, statusCode)
+			RequireEqualJSON(t, []byte(goodResponse), res)
+		}
+		res, statusCode, err := partner.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 429, statusCode)
+		RequireEqualJSON(t, []byte(keyOverQuotaResponse), res)
+	})
+
+	t.Run("per-key rate limit is enforced", func(t *testing.T) {
+		limitedRes, codes := spamReqs(t, limited, 429)
+		require.Equal(t, 2, codes[429])
+		require.Equal(t, 1, codes[200])
+		RequireEqualJSON(t, []byte(keyOverRateLimitResponse), limitedRes)
+	})
+
+	t.Run("admin endpoint reports usage", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8545/admin/api_keys", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin_secret")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, 200, res.StatusCode)
+
+		var usage []*proxyd.APIKeyUsage
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&usage))
+		require.Len(t, usage, 2)
+		require.Equal(t, "limited", usage[0].Alias)
+		require.Equal(t, 1, usage[0].RatePerSecond)
+		require.Equal(t, "partner", usage[1].Alias)
+		require.Equal(t, 4, usage[1].DailyQuota)
+		require.Equal(t, 5, usage[1].DailyUsage)
+	})
+
+	t.Run("admin endpoint requires the admin token", func(t *testing.T) {
+		res, err := http.Get("http://127.0.0.1:8545/admin/api_keys")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		_, _ = ioutil.ReadAll(res.Body)
+		require.Equal(t, 401, res.StatusCode)
+	})
+}
@@ -23,6 +23,8 @@ func TestCaching(t *testing.T) {
 	hdlr.SetRoute("eth_blockNumber", "999", "0x64")
 	hdlr.SetRoute("eth_getBlockByNumber", "999", "dummy_block")
 	hdlr.SetRoute("eth_call", "999", "dummy_call")
+	hdlr.SetRoute("eth_getCode", "999", "dummy_code")
+	hdlr.SetRoute("eth_getTransactionReceipt", "999", map[string]interface{}{"blockNumber": "0x1"})
 
 	// mock LVC requests
 	hdlr.SetFallbackRoute("eth_blockNumber", "0x64")
@@ -35,7 +37,7 @@ func TestCaching(t *testing.T) {
 	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redis.Port())))
 	config := ReadConfig("caching")
 	client := NewProxydClient("http://127.0.0.1:8545")
-	shutdown, err := proxyd.Start(config)
+	_, shutdown, err := proxyd.Start(config)
 	require.NoError(t, err)
 	defer shutdown()
 
@@ -114,6 +116,23 @@ func TestCaching(t *testing.T) {
 			"{\"id\":999,\"jsonrpc\":\"2.0\",\"result\":\"dummy_call\"}",
 			2,
 		},
+		{
+			"eth_getCode",
+			[]interface{}{
+				"0x1234",
+				"0x1",
+			},
+			"{\"id\":999,\"jsonrpc\":\"2.0\",\"result\":\"dummy_code\"}",
+			1,
+		},
+		{
+			"eth_getTransactionReceipt",
+			[]interface{}{
+				"0x5678",
+			},
+			"{\"id\":999,\"jsonrpc\":\"2.0\",\"result\":{\"blockNumber\":\"0x1\"}}",
+			1,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.method, func(t *testing.T) {
@@ -171,7 +190,7 @@ func TestBatchCaching(t *testing.T) {
 
 	config := ReadConfig("caching")
 	client := NewProxydClient("http://127.0.0.1:8545")
-	shutdown, err := proxyd.Start(config)
+	_, shutdown, err := proxyd.Start(config)
 	require.NoError(t, err)
 	defer shutdown()
 
@@ -0,0 +1,252 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+// MockChainHandler serves eth_getBlockByNumber from a linear chain whose
+// block hashes are derived from the fork name, so that two handlers on the
+// same fork agree on every block they have in common.
+type MockChainHandler struct {
+	mtx       sync.Mutex
+	head      uint64
+	fork      string
+	forkBlock uint64
+}
+
+func NewMockChainHandler(head uint64) *MockChainHandler {
+	return &MockChainHandler{head: head}
+}
+
+func (h *MockChainHandler) SetHead(head uint64) {
+	h.mtx.Lock()
+	h.head = head
+	h.mtx.Unlock()
+}
+
+// Fork makes every block from forkBlock onwards hash differently.
+func (h *MockChainHandler) Fork(name string, forkBlock uint64) {
+	h.mtx.Lock()
+	h.fork = name
+	h.forkBlock = forkBlock
+	h.mtx.Unlock()
+}
+
+func (h *MockChainHandler) blockHash(number uint64) string {
+	if h.fork != "" && number >= h.forkBlock {
+		return fmt.Sprintf("0x%s%x", h.fork, number)
+	}
+	return fmt.Sprintf("0xcanonical%x", number)
+}
+
+func (h *MockChainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		panic(err)
+	}
+	req, err := proxyd.ParseRPCReq(body)
+	if err != nil {
+		panic(err)
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "eth_getBlockByNumber":
+		var params []interface{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			panic(err)
+		}
+		number := h.head
+		if tag := params[0].(string); tag != "latest" {
+			number = hexutil.MustDecodeUint64(tag)
+		}
+		if number <= h.head {
+			result = map[string]interface{}{
+				"number": hexutil.EncodeUint64(number),
+				"hash":   h.blockHash(number),
+			}
+		}
+	default:
+		result = "ok"
+	}
+
+	out := &proxyd.RPCRes{
+		JSONRPC: proxyd.JSONRPCVersion,
+		Result:  result,
+		ID:      req.ID,
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		panic(err)
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	node1Chain := NewMockChainHandler(0x10)
+	node2Chain := NewMockChainHandler(0x10)
+	node1 := NewMockBackend(node1Chain)
+	defer node1.Close()
+	node2 := NewMockBackend(node2Chain)
+	defer node2.Close()
+
+	require.NoError(t, os.Setenv("NODE1_URL", node1.URL()))
+	require.NoError(t, os.Setenv("NODE2_URL", node2.URL()))
+
+	config := ReadConfig("consensus")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	requireBlockNumber := func(t *testing.T, expected string) {
+		require.Eventually(t, func() bool {
+			res, _, err := client.SendRPC("eth_blockNumber", nil)
+			require.NoError(t, err)
+			return string(res) == fmt.Sprintf(`{"jsonrpc":"2.0","result":"%s","id":999}`+"\n", expected)
+		}, 5*time.Second, 100*time.Millisecond)
+	}
+
+	resetRequests := func() {
+		node1.Reset()
+		node2.Reset()
+	}
+
+	t.Run("serves the agreed latest block without hitting a backend", func(t *testing.T) {
+		requireBlockNumber(t, "0x10")
+	})
+
+	t.Run("rewrites latest and safe tags to the consensus block", func(t *testing.T) {
+		res, statusCode, err := client.SendRPC("eth_getBlockByNumber", []interface{}{"latest", false})
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":{"number":"0x10","hash":"0xcanonical10"},"id":999}`), res)
+
+		res, statusCode, err = client.SendRPC("eth_getBlockByNumber", []interface{}{"safe", false})
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":{"number":"0xe","hash":"0xcanonicale"},"id":999}`), res)
+	})
+
+	t.Run("rejects blocks above the consensus block", func(t *testing.T) {
+		res, statusCode, err := client.SendRPC("eth_getBlockByNumber", []interface{}{"0x11", false})
+		require.NoError(t, err)
+		require.Equal(t, 400, statusCode)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32017,"message":"block is out of range"},"id":999}`), res)
+	})
+
+	t.Run("agrees on the highest common block when a backend is ahead", func(t *testing.T) {
+		node1Chain.SetHead(0x12)
+		requireBlockNumber(t, "0x10")
+
+		node2Chain.SetHead(0x12)
+		requireBlockNumber(t, "0x12")
+	})
+
+	t.Run("walks back to the last agreed block when new blocks disagree", func(t *testing.T) {
+		node2Chain.Fork("fork", 0x13)
+		node1Chain.SetHead(0x14)
+		node2Chain.SetHead(0x14)
+		requireBlockNumber(t, "0x12")
+
+		node2Chain.Fork("", 0)
+		requireBlockNumber(t, "0x14")
+	})
+
+	t.Run("bans a backend that breaks consensus", func(t *testing.T) {
+		consensusStatus := func(name string) *proxyd.BackendConsensusStatus {
+			var status proxyd.BackendStatus
+			require.Equal(t, 200, adminRequest(t, "GET", "/admin/backends/"+name, &status))
+			require.Len(t, status.Consensus, 1)
+			return status.Consensus[0]
+		}
+
+		node2Chain.Fork("fork", 0x14)
+		require.Eventually(t, func() bool {
+			return consensusStatus("node2").Banned
+		}, 5*time.Second, 100*time.Millisecond)
+		// the group keeps the agreed block instead of walking back
+		requireBlockNumber(t, "0x14")
+		require.False(t, consensusStatus("node2").InConsensus)
+		require.True(t, consensusStatus("node1").InConsensus)
+
+		// once the ban is over the backend rejoins if it agrees again
+		node2Chain.Fork("", 0)
+		require.Eventually(t, func() bool {
+			status := consensusStatus("node2")
+			return !status.Banned && status.InConsensus
+		}, 10*time.Second, 100*time.Millisecond)
+	})
+
+	t.Run("evicts a lagging backend", func(t *testing.T) {
+		node1Chain.SetHead(0x20)
+		requireBlockNumber(t, "0x20")
+
+		resetRequests()
+		for i := 0; i < 5; i++ {
+			_, statusCode, err := client.SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			require.Equal(t, 200, statusCode)
+		}
+		// node2 is still polled for its head, but serves no client traffic
+		for _, req := range node2.Requests() {
+			require.NotContains(t, string(req.Body), "eth_chainId")
+		}
+		var served int
+		for _, req := range node1.Requests() {
+			var rpcReq proxyd.RPCReq
+			require.NoError(t, json.Unmarshal(req.Body, &rpcReq))
+			if rpcReq.Method == "eth_chainId" {
+				served++
+			}
+		}
+		require.Equal(t, 5, served)
+	})
+}
+
+// TestConsensusAtStartup asserts that a consensus aware group serves requests
+// as soon as proxyd has started, even if its backends are slow to report their
+// head.
+func TestConsensusAtStartup(t *testing.T) {
+	slow := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+			h.ServeHTTP(w, r)
+		})
+	}
+	node1 := NewMockBackend(slow(NewMockChainHandler(0x10)))
+	defer node1.Close()
+	node2 := NewMockBackend(slow(NewMockChainHandler(0x10)))
+	defer node2.Close()
+
+	require.NoError(t, os.Setenv("NODE1_URL", node1.URL()))
+	require.NoError(t, os.Setenv("NODE2_URL", node2.URL()))
+
+	config := ReadConfig("consensus")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	res, statusCode, err := client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, statusCode)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"ok","id":999}`), res)
+
+	res, statusCode, err = client.SendRPC("eth_blockNumber", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, statusCode)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0x10","id":999}`), res)
+}
@@ -0,0 +1,139 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLogsHandler returns one log per eth_getLogs call, tagged with the
+// range it was asked for, on a chain whose head is at block 1000.
+func mockLogsHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		panic(err)
+	}
+	req, err := proxyd.ParseRPCReq(body)
+	if err != nil {
+		panic(err)
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "eth_blockNumber":
+		result = hexutil.EncodeUint64(1000)
+	case "eth_getLogs":
+		var params []map[string]interface{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			panic(err)
+		}
+		result = []interface{}{
+			map[string]interface{}{
+				"fromBlock": params[0]["fromBlock"],
+				"toBlock":   params[0]["toBlock"],
+			},
+		}
+	}
+
+	out := &proxyd.RPCRes{
+		JSONRPC: proxyd.JSONRPCVersion,
+		Result:  result,
+		ID:      req.ID,
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		panic(err)
+	}
+}
+
+func countGetLogsCalls(backend *MockBackend) int {
+	var count int
+	for _, req := range backend.Requests() {
+		parsed, err := proxyd.ParseRPCReq(req.Body)
+		if err == nil && parsed.Method == "eth_getLogs" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestGetLogs(t *testing.T) {
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+
+	backend := NewMockBackend(http.HandlerFunc(mockLogsHandler))
+	defer backend.Close()
+
+	badBackend := NewMockBackend(SingleResponseHandler(503, "unavailable"))
+	defer badBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("BAD_BACKEND_RPC_URL", badBackend.URL()))
+	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redis.Port())))
+
+	config := ReadConfig("get_logs")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	// allow time for the block number fetcher to fire
+	time.Sleep(1500 * time.Millisecond)
+
+	t.Run("small ranges are forwarded as-is", func(t *testing.T) {
+		backend.Reset()
+		res, code, err := client.SendRPC("eth_getLogs", []interface{}{
+			map[string]interface{}{"fromBlock": "0x10", "toBlock": "0x20"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":[{"fromBlock":"0x10","toBlock":"0x20"}],"id":999}`), res)
+		require.Equal(t, 1, countGetLogsCalls(backend))
+	})
+
+	t.Run("ranges over the limit are rejected", func(t *testing.T) {
+		backend.Reset()
+		res, code, err := client.SendRPC("eth_getLogs", []interface{}{
+			map[string]interface{}{"fromBlock": "earliest", "toBlock": "latest"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 400, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"block range too large, maximum is 500 blocks"},"id":999}`), res)
+		require.Equal(t, 0, countGetLogsCalls(backend))
+	})
+
+	t.Run("large ranges are split and merged in order", func(t *testing.T) {
+		backend.Reset()
+		expected := `{"jsonrpc":"2.0","result":[
+			{"fromBlock":"0x32","toBlock":"0x63"},
+			{"fromBlock":"0x64","toBlock":"0xc7"},
+			{"fromBlock":"0xc8","toBlock":"0xfa"}
+		],"id":999}`
+		res, code, err := client.SendRPC("eth_getLogs", []interface{}{
+			map[string]interface{}{"fromBlock": "0x32", "toBlock": "0xfa"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(expected), res)
+		require.Equal(t, 3, countGetLogsCalls(backend))
+
+		// confirmed chunks are served from the cache
+		backend.Reset()
+		res, code, err = client.SendRPC("eth_getLogs", []interface{}{
+			map[string]interface{}{"fromBlock": "0x32", "toBlock": "0xfa"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(expected), res)
+		require.Equal(t, 0, countGetLogsCalls(backend))
+	})
+}
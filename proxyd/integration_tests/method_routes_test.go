@@ -0,0 +1,96 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodRoutes(t *testing.T) {
+	sequencer := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer sequencer.Close()
+	replica := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer replica.Close()
+	archive := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer archive.Close()
+
+	require.NoError(t, os.Setenv("SEQUENCER_URL", sequencer.URL()))
+	require.NoError(t, os.Setenv("REPLICA_URL", replica.URL()))
+	require.NoError(t, os.Setenv("ARCHIVE_URL", archive.URL()))
+
+	config := ReadConfig("method_routes")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	reset := func() {
+		sequencer.Reset()
+		replica.Reset()
+		archive.Reset()
+	}
+
+	requireServedBy := func(t *testing.T, method string, be *MockBackend) {
+		reset()
+		res, statusCode, err := client.SendRPC(method, nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+		require.Equal(t, 1, len(be.Requests()))
+	}
+
+	t.Run("methods are routed by name and pattern", func(t *testing.T) {
+		requireServedBy(t, "eth_sendRawTransaction", sequencer)
+		requireServedBy(t, "rollup_getInfo", replica)
+		requireServedBy(t, "debug_traceTransaction", archive)
+	})
+
+	t.Run("unrouted methods are rejected", func(t *testing.T) {
+		res, statusCode, err := client.SendRPC("eth_call", nil)
+		require.NoError(t, err)
+		require.Equal(t, 403, statusCode)
+		RequireEqualJSON(t, []byte(notWhitelistedResponse), res)
+	})
+
+	t.Run("batches are split by route", func(t *testing.T) {
+		reset()
+		res, statusCode, err := client.SendBatchRPC(
+			NewRPCReq("1", "eth_sendRawTransaction", nil),
+			NewRPCReq("2", "debug_traceTransaction", nil),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		require.NotEmpty(t, res)
+		require.Equal(t, 1, len(sequencer.Requests()))
+		require.Equal(t, 1, len(archive.Requests()))
+	})
+
+	t.Run("route timeout overrides the backend and server timeouts", func(t *testing.T) {
+		slowHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(1500 * time.Millisecond)
+			BatchedResponseHandler(200, goodResponse)(w, r)
+		})
+		archive.SetHandler(slowHandler)
+		sequencer.SetHandler(slowHandler)
+		defer archive.SetHandler(BatchedResponseHandler(200, goodResponse))
+		defer sequencer.SetHandler(BatchedResponseHandler(200, goodResponse))
+
+		requireServedBy(t, "debug_traceTransaction", archive)
+
+		res, statusCode, err := client.SendRPC("eth_sendRawTransaction", nil)
+		require.NoError(t, err)
+		require.Equal(t, 503, statusCode)
+		RequireEqualJSON(t, []byte(noBackendsResponse), res)
+	})
+
+	t.Run("falls back when no backend of the group is available", func(t *testing.T) {
+		archive.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(503)
+		}))
+		requireServedBy(t, "debug_traceTransaction", replica)
+	})
+}
@@ -77,6 +77,7 @@ func (h *BatchRPCResponseRouter) SetRoute(method string, id string, result inter
 
 	switch result.(type) {
 	case string:
+	case map[string]interface{}:
 	case nil:
 		break
 	default:
@@ -0,0 +1,136 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReload(t *testing.T) {
+	node1 := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer node1.Close()
+	node2 := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer node2.Close()
+
+	require.NoError(t, os.Setenv("NODE1_URL", node1.URL()))
+	require.NoError(t, os.Setenv("NODE2_URL", node2.URL()))
+
+	configPath := filepath.Join(t.TempDir(), "proxyd.toml")
+	config := ReadConfig("reload")
+	writeConfigFile(t, configPath, config)
+
+	srv, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+	watcher := proxyd.NewConfigWatcher(configPath, srv, config.Reload)
+	watcher.Start()
+	defer watcher.Stop()
+
+	client := NewProxydClient("http://127.0.0.1:8545/secret")
+	newClient := NewProxydClient("http://127.0.0.1:8545/new_secret")
+
+	requireServedBy := func(t *testing.T, client *ProxydHTTPClient, be *MockBackend) {
+		node1.Reset()
+		node2.Reset()
+		res, statusCode, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+		require.Equal(t, 1, len(be.Requests()))
+	}
+
+	t.Run("drained backends are skipped", func(t *testing.T) {
+		requireServedBy(t, client, node1)
+
+		var status proxyd.BackendStatus
+		require.Equal(t, 200, adminRequest(t, "POST", "/admin/backends/node1/drain", &status))
+		require.True(t, status.Draining)
+		require.Equal(t, []string{"node"}, status.BackendGroups)
+		requireServedBy(t, client, node2)
+
+		require.Equal(t, 200, adminRequest(t, "POST", "/admin/backends/node1/undrain", &status))
+		require.False(t, status.Draining)
+		requireServedBy(t, client, node1)
+	})
+
+	t.Run("admin endpoints list backends", func(t *testing.T) {
+		var statuses []*proxyd.BackendStatus
+		require.Equal(t, 200, adminRequest(t, "GET", "/admin/backends", &statuses))
+		require.Len(t, statuses, 2)
+		require.Equal(t, "node1", statuses[0].Name)
+		require.True(t, statuses[0].Online)
+		require.Equal(t, "node2", statuses[1].Name)
+
+		require.Equal(t, 404, adminRequest(t, "GET", "/admin/backends/node3", nil))
+		require.Equal(t, 404, adminRequest(t, "POST", "/admin/backends/node3/drain", nil))
+
+		res, err := http.Get("http://127.0.0.1:8545/admin/backends")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, 401, res.StatusCode)
+	})
+
+	t.Run("reload swaps routing and auth keys", func(t *testing.T) {
+		require.Equal(t, 200, adminRequest(t, "POST", "/admin/backends/node1/drain", nil))
+
+		_, statusCode, err := newClient.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 401, statusCode)
+
+		reloaded := ReadConfig("reload")
+		reloaded.Authentication["new_secret"] = "new_alias"
+		reloaded.BackendGroups["node"].Backends = []string{"node2", "node1"}
+		writeConfigFile(t, configPath, reloaded)
+		watcher.Reload()
+
+		require.Eventually(t, func() bool {
+			_, statusCode, err := newClient.SendRPC("eth_chainId", nil)
+			return err == nil && statusCode == 200
+		}, 5*time.Second, 50*time.Millisecond)
+
+		// node1 is carried over and stays drained
+		var status proxyd.BackendStatus
+		require.Equal(t, 200, adminRequest(t, "GET", "/admin/backends/node1", &status))
+		require.True(t, status.Draining)
+
+		require.Equal(t, 200, adminRequest(t, "POST", "/admin/backends/node1/undrain", nil))
+		requireServedBy(t, client, node2)
+	})
+
+	t.Run("invalid configs are not applied", func(t *testing.T) {
+		invalid := ReadConfig("reload")
+		invalid.RPCMethodMappings["eth_chainId"] = "missing"
+		require.Error(t, srv.Reload(invalid))
+		requireServedBy(t, newClient, node2)
+	})
+}
+
+func writeConfigFile(t *testing.T, path string, config *proxyd.Config) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, toml.NewEncoder(f).Encode(config))
+}
+
+func adminRequest(t *testing.T, method, path string, out interface{}) int {
+	req, err := http.NewRequest(method, "http://127.0.0.1:8545"+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	if out != nil && res.StatusCode == 200 {
+		require.NoError(t, json.Unmarshal(body, out))
+	}
+	return res.StatusCode
+}
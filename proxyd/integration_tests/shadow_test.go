@@ -0,0 +1,130 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowBackends(t *testing.T) {
+	primary := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer primary.Close()
+	canary := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer canary.Close()
+
+	require.NoError(t, os.Setenv("PRIMARY_BACKEND_RPC_URL", primary.URL()))
+	require.NoError(t, os.Setenv("CANARY_BACKEND_RPC_URL", canary.URL()))
+
+	config := ReadConfig("shadow")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	t.Run("matching responses are counted", func(t *testing.T) {
+		before := shadowRequests(t, "eth_chainId", proxyd.ShadowOutcomeMatch)
+		res, statusCode, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+
+		require.Eventually(t, func() bool {
+			return shadowRequests(t, "eth_chainId", proxyd.ShadowOutcomeMatch) == before+1
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, 1, len(canary.Requests()))
+	})
+
+	t.Run("writes are not mirrored", func(t *testing.T) {
+		canary.Reset()
+		res, statusCode, err := client.SendRPC("eth_sendRawTransaction", []interface{}{"0x00"})
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+
+		time.Sleep(100 * time.Millisecond)
+		require.Empty(t, canary.Requests())
+	})
+
+	t.Run("mismatches are counted by method", func(t *testing.T) {
+		canary.SetHandler(BatchedResponseHandler(200, `{"jsonrpc": "2.0", "result": "bye", "id": 999}`))
+		before := shadowRequests(t, "eth_blockNumber", proxyd.ShadowOutcomeMismatch)
+		res, statusCode, err := client.SendRPC("eth_blockNumber", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+
+		require.Eventually(t, func() bool {
+			return shadowRequests(t, "eth_blockNumber", proxyd.ShadowOutcomeMismatch) == before+1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("slow or failing shadows do not affect clients", func(t *testing.T) {
+		canary.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(2 * time.Second)
+			w.WriteHeader(500)
+		}))
+		start := time.Now()
+		res, statusCode, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
+
+// TestShadowBackendsConsensus asserts that shadows of a consensus aware group
+// receive the requests as rewritten for the primary backend.
+func TestShadowBackendsConsensus(t *testing.T) {
+	primary := NewMockBackend(NewMockChainHandler(0x10))
+	defer primary.Close()
+	canary := NewMockBackend(NewMockChainHandler(0x10))
+	defer canary.Close()
+
+	require.NoError(t, os.Setenv("PRIMARY_BACKEND_RPC_URL", primary.URL()))
+	require.NoError(t, os.Setenv("CANARY_BACKEND_RPC_URL", canary.URL()))
+
+	config := ReadConfig("shadow_consensus")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	res, statusCode, err := client.SendRPC("eth_getBlockByNumber", []interface{}{"latest", false})
+	require.NoError(t, err)
+	require.Equal(t, 200, statusCode)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":{"hash":"0xcanonical10","number":"0x10"},"id":999}`), res)
+
+	require.Eventually(t, func() bool {
+		return len(canary.Requests()) == 1
+	}, time.Second, 10*time.Millisecond)
+	var req proxyd.RPCReq
+	require.NoError(t, json.Unmarshal(canary.Requests()[0].Body, &req))
+	require.Equal(t, "eth_getBlockByNumber", req.Method)
+	require.JSONEq(t, `["0x10",false]`, string(req.Params))
+}
+
+func shadowRequests(t *testing.T, method, outcome string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "proxyd_shadow_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["backend_name"] == "canary" && labels["method_name"] == method && labels["outcome"] == outcome {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,237 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/websocket"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSubscriptionBackend answers eth_subscribe calls and lets the test push
+// newHeads notifications over the subscribed connection.
+type MockSubscriptionBackend struct {
+	*MockWSBackend
+	subID      string
+	mtx        sync.Mutex
+	conn       *websocket.Conn
+	subscribes int
+}
+
+func NewMockSubscriptionBackend(subID string) *MockSubscriptionBackend {
+	b := &MockSubscriptionBackend{subID: subID}
+	b.MockWSBackend = NewMockWSBackend(nil, func(conn *websocket.Conn, msgType int, data []byte) {
+		req, err := proxyd.ParseRPCReq(data)
+		if err != nil {
+			panic(err)
+		}
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		if req.Method == "eth_subscribe" {
+			b.conn = conn
+			b.subscribes++
+		}
+		res := &proxyd.RPCRes{
+			JSONRPC: proxyd.JSONRPCVersion,
+			Result:  b.subID,
+			ID:      req.ID,
+		}
+		if err := conn.WriteJSON(res); err != nil {
+			panic(err)
+		}
+	}, nil)
+	return b
+}
+
+func (b *MockSubscriptionBackend) Subscribes() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.subscribes
+}
+
+func (b *MockSubscriptionBackend) PushHead(number uint64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	msg := fmt.Sprintf(
+		`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":%s}}`,
+		b.subID,
+		mockHead(number),
+	)
+	if err := b.conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		panic(err)
+	}
+}
+
+func mockHead(number uint64) string {
+	return fmt.Sprintf(`{"number":"0x%x","hash":"0x%064x"}`, number, number)
+}
+
+// mockBlocksHandler serves batched eth_getBlockByNumber calls with blocks
+// matching mockHead.
+func mockBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		panic(err)
+	}
+	reqs, err := proxyd.ParseBatchRPCReq(body)
+	if err != nil {
+		panic(err)
+	}
+	out := make([]*proxyd.RPCRes, 0, len(reqs))
+	for _, raw := range reqs {
+		req, err := proxyd.ParseRPCReq(raw)
+		if err != nil {
+			panic(err)
+		}
+		var params []interface{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			panic(err)
+		}
+		var block map[string]interface{}
+		if err := json.Unmarshal([]byte(mockHead(hexutil.MustDecodeUint64(params[0].(string)))), &block); err != nil {
+			panic(err)
+		}
+		block["transactions"] = []interface{}{}
+		out = append(out, &proxyd.RPCRes{
+			JSONRPC: proxyd.JSONRPCVersion,
+			Result:  block,
+			ID:      req.ID,
+		})
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		panic(err)
+	}
+}
+
+type subscriptionClient struct {
+	*ProxydWSClient
+	msgs chan []byte
+}
+
+func newSubscriptionClient(t *testing.T) *subscriptionClient {
+	c := &subscriptionClient{msgs: make(chan []byte, 16)}
+	client, err := NewProxydWSClient("ws://127.0.0.1:8546", func(msgType int, data []byte) {
+		c.msgs <- data
+	}, nil)
+	require.NoError(t, err)
+	c.ProxydWSClient = client
+	return c
+}
+
+func (c *subscriptionClient) next(t *testing.T) []byte {
+	select {
+	case msg := <-c.msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ws message")
+		return nil
+	}
+}
+
+func (c *subscriptionClient) subscribe(t *testing.T) string {
+	require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`)))
+	var res struct {
+		Result string `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(c.next(t), &res))
+	require.NotEmpty(t, res.Result)
+	return res.Result
+}
+
+func (c *subscriptionClient) requireHead(t *testing.T, subID string, number uint64) {
+	expected := fmt.Sprintf(
+		`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":%s}}`,
+		subID,
+		mockHead(number),
+	)
+	RequireEqualJSON(t, []byte(expected), c.next(t))
+}
+
+func TestWSSubscriptionMultiplexing(t *testing.T) {
+	first := NewMockSubscriptionBackend("0xa")
+	defer first.Close()
+	second := NewMockSubscriptionBackend("0xb")
+	defer second.Close()
+
+	firstRPC := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer firstRPC.Close()
+	secondRPC := NewMockBackend(http.HandlerFunc(mockBlocksHandler))
+	defer secondRPC.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", firstRPC.URL()))
+	require.NoError(t, os.Setenv("FIRST_BACKEND_WS_URL", first.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", secondRPC.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_WS_URL", second.URL()))
+
+	config := ReadConfig("ws_subscriptions")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client1 := newSubscriptionClient(t)
+	defer client1.HardClose()
+	client2 := newSubscriptionClient(t)
+	defer client2.HardClose()
+
+	sub1 := client1.subscribe(t)
+	sub2 := client2.subscribe(t)
+	require.NotEqual(t, sub1, sub2)
+
+	// both clients share a single upstream subscription
+	require.Eventually(t, func() bool {
+		return first.Subscribes() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	first.PushHead(1)
+	client1.requireHead(t, sub1, 1)
+	client2.requireHead(t, sub2, 1)
+
+	// calls other than subscriptions are forwarded over HTTP
+	require.NoError(t, client1.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":999,"method":"eth_chainId","params":[]}`)))
+	RequireEqualJSON(t, []byte(goodResponse), client1.next(t))
+
+	// the subscription moves to the second backend and the missed heads are filled in
+	first.Close()
+	require.Eventually(t, func() bool {
+		return second.Subscribes() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	second.PushHead(4)
+	for _, number := range []uint64{2, 3, 4} {
+		client1.requireHead(t, sub1, number)
+		client2.requireHead(t, sub2, number)
+	}
+	require.Equal(t, 1, len(secondRPC.Requests()))
+}
+
+func TestWSSubscriptionUnsupported(t *testing.T) {
+	backend := NewMockSubscriptionBackend("0xa")
+	defer backend.Close()
+	rpc := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer rpc.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", rpc.URL()))
+	require.NoError(t, os.Setenv("FIRST_BACKEND_WS_URL", backend.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", rpc.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_WS_URL", backend.URL()))
+
+	config := ReadConfig("ws_subscriptions")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := newSubscriptionClient(t)
+	defer client.HardClose()
+
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newPendingTransactions"]}`)))
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"unsupported subscription type newPendingTransactions"},"id":1}`), client.next(t))
+	require.Equal(t, 0, backend.Subscribes())
+}
@@ -0,0 +1,162 @@
+package proxyd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// MethodRoute sends the methods matching its pattern to a backend group,
+// falling back to the next group when no backend of a group is available.
+type MethodRoute struct {
+	Pattern       string
+	BackendGroups []*BackendGroup
+	// Timeout overrides the backend response timeout if set, and extends
+	// the server timeout of the requests to the route if longer.
+	Timeout time.Duration
+
+	// batchKey is shared by routes that forward the same way, so that
+	// their requests can be sent upstream in the same batch.
+	batchKey string
+}
+
+// RequestTimeout returns the deadline of the requests to the route, the server
+// timeout unless the route timeout is longer.
+func (r *MethodRoute) RequestTimeout(serverTimeout time.Duration) time.Duration {
+	if r.Timeout > serverTimeout {
+		return r.Timeout
+	}
+	return serverTimeout
+}
+
+// Forward forwards the requests to the first backend group that can serve
+// them.
+func (r *MethodRoute) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]*RPCRes, error) {
+	if r.Timeout != 0 {
+		ctx = context.WithValue(ctx, ContextKeyResponseTimeout, r.Timeout) // nolint:staticcheck
+	}
+
+	var err error
+	for i, bg := range r.BackendGroups {
+		var res []*RPCRes
+		res, err = bg.Forward(ctx, reqs, isBatch)
+		if errors.Is(err, ErrNoBackends) && i < len(r.BackendGroups)-1 {
+			log.Warn(
+				"no backends available, falling back to the next backend group",
+				"route", r.Pattern,
+				"backend_group", bg.Name,
+				"req_id", GetReqID(ctx),
+				"auth", GetAuthCtx(ctx),
+			)
+			RecordMethodRouteFallback(r.Pattern, bg)
+			continue
+		}
+		return res, err
+	}
+	return nil, err
+}
+
+// MethodRouter resolves the route of a method. Exact method names take
+// precedence over patterns, and longer patterns over shorter ones.
+type MethodRouter struct {
+	exact      map[string]*MethodRoute
+	patterns   []*MethodRoute
+	maxTimeout time.Duration
+}
+
+// NewMethodRouter merges the plain method mappings and the routes into a
+// router. A method or pattern may only be defined once.
+func NewMethodRouter(mappings map[string]string, routes MethodRoutesConfig, backendGroups map[string]*BackendGroup) (*MethodRouter, error) {
+	all := make([]*MethodRoute, 0, len(mappings)+len(routes))
+	for pattern, bgName := range mappings {
+		bg := backendGroups[bgName]
+		if bg == nil {
+			return nil, fmt.Errorf("undefined backend group %s", bgName)
+		}
+		all = append(all, &MethodRoute{
+			Pattern:       pattern,
+			BackendGroups: []*BackendGroup{bg},
+		})
+	}
+	for pattern, cfg := range routes {
+		if _, ok := mappings[pattern]; ok {
+			return nil, fmt.Errorf("method %s is defined in both rpc_method_mappings and rpc_method_routes", pattern)
+		}
+		route := &MethodRoute{
+			Pattern: pattern,
+			Timeout: secondsToDuration(cfg.TimeoutSeconds),
+		}
+		for _, bgName := range append([]string{cfg.BackendGroup}, cfg.FallbackBackendGroups...) {
+			bg := backendGroups[bgName]
+			if bg == nil {
+				return nil, fmt.Errorf("undefined backend group %s in route %s", bgName, pattern)
+			}
+			route.BackendGroups = append(route.BackendGroups, bg)
+		}
+		all = append(all, route)
+	}
+
+	r := &MethodRouter{
+		exact: make(map[string]*MethodRoute),
+	}
+	for _, route := range all {
+		names := make([]string, 0, len(route.BackendGroups))
+		for _, bg := range route.BackendGroups {
+			names = append(names, bg.Name)
+		}
+		route.batchKey = fmt.Sprintf("%s/%s", strings.Join(names, ","), route.Timeout)
+		if route.Timeout > r.maxTimeout {
+			r.maxTimeout = route.Timeout
+		}
+
+		idx := strings.Index(route.Pattern, "*")
+		if idx == -1 {
+			r.exact[route.Pattern] = route
+			continue
+		}
+		if idx != len(route.Pattern)-1 {
+			return nil, fmt.Errorf("invalid method pattern %s, wildcards are only supported at the end", route.Pattern)
+		}
+		r.patterns = append(r.patterns, route)
+	}
+	sort.Slice(r.patterns, func(i, j int) bool {
+		if len(r.patterns[i].Pattern) != len(r.patterns[j].Pattern) {
+			return len(r.patterns[i].Pattern) > len(r.patterns[j].Pattern)
+		}
+		return r.patterns[i].Pattern < r.patterns[j].Pattern
+	})
+	return r, nil
+}
+
+// Route returns the route of a method, or nil if the method is not
+// whitelisted.
+func (r *MethodRouter) Route(method string) *MethodRoute {
+	if route := r.exact[method]; route != nil {
+		return route
+	}
+	for _, route := range r.patterns {
+		if MatchMethod(route.Pattern, method) {
+			return route
+		}
+	}
+	return nil
+}
+
+// RequestTimeout returns the deadline of an incoming request, which is not
+// known to target any route yet: the server timeout, extended to the longest
+// route timeout.
+func (r *MethodRouter) RequestTimeout(serverTimeout time.Duration) time.Duration {
+	if r.maxTimeout > serverTimeout {
+		return r.maxTimeout
+	}
+	return serverTimeout
+}
+
+func (r *MethodRouter) Len() int {
+	return len(r.exact) + len(r.patterns)
+}
@@ -0,0 +1,96 @@
+package proxyd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodRouter(t *testing.T) {
+	groups := map[string]*BackendGroup{
+		"sequencer": {Name: "sequencer"},
+		"replicas":  {Name: "replicas"},
+		"archive":   {Name: "archive"},
+	}
+	router, err := NewMethodRouter(
+		map[string]string{
+			"eth_sendRawTransaction": "sequencer",
+			"eth_*":                  "replicas",
+		},
+		MethodRoutesConfig{
+			"debug_*": {
+				BackendGroup:          "archive",
+				FallbackBackendGroups: []string{"replicas"},
+				TimeoutSeconds:        60,
+			},
+			"debug_traceBlock*": {BackendGroup: "replicas"},
+		},
+		groups,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 4, router.Len())
+
+	tests := []struct {
+		method  string
+		pattern string
+		groups  []string
+	}{
+		{"eth_sendRawTransaction", "eth_sendRawTransaction", []string{"sequencer"}},
+		{"eth_call", "eth_*", []string{"replicas"}},
+		{"debug_traceTransaction", "debug_*", []string{"archive", "replicas"}},
+		{"debug_traceBlockByNumber", "debug_traceBlock*", []string{"replicas"}},
+		{"rollup_getInfo", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			route := router.Route(tt.method)
+			if tt.pattern == "" {
+				require.Nil(t, route)
+				return
+			}
+			require.NotNil(t, route)
+			require.Equal(t, tt.pattern, route.Pattern)
+			var names []string
+			for _, bg := range route.BackendGroups {
+				names = append(names, bg.Name)
+			}
+			require.Equal(t, tt.groups, names)
+		})
+	}
+
+	require.Equal(t, 60*time.Second, router.Route("debug_traceCall").Timeout)
+	require.Zero(t, router.Route("eth_call").Timeout)
+
+	require.Equal(t, 60*time.Second, router.RequestTimeout(5*time.Second))
+	require.Equal(t, 90*time.Second, router.RequestTimeout(90*time.Second))
+	require.Equal(t, 60*time.Second, router.Route("debug_traceCall").RequestTimeout(5*time.Second))
+	require.Equal(t, 5*time.Second, router.Route("eth_call").RequestTimeout(5*time.Second))
+}
+
+func TestMethodRouterValidation(t *testing.T) {
+	groups := map[string]*BackendGroup{
+		"main": {Name: "main"},
+	}
+	tests := []struct {
+		name     string
+		mappings map[string]string
+		routes   MethodRoutesConfig
+		err      string
+	}{
+		{"undefined mapping group", map[string]string{"eth_call": "other"}, nil, "undefined backend group other"},
+		{"undefined fallback group", nil, MethodRoutesConfig{
+			"debug_*": {BackendGroup: "main", FallbackBackendGroups: []string{"other"}},
+		}, "undefined backend group other in route debug_*"},
+		{"duplicate method", map[string]string{"eth_call": "main"}, MethodRoutesConfig{
+			"eth_call": {BackendGroup: "main"},
+		}, "method eth_call is defined in both rpc_method_mappings and rpc_method_routes"},
+		{"wildcard in the middle", map[string]string{"eth_*Block": "main"}, nil, "invalid method pattern eth_*Block, wildcards are only supported at the end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMethodRouter(tt.mappings, tt.routes, groups)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -105,7 +106,14 @@ func (e *EthGetBlockByNumberMethodHandler) PutRPCMethod(ctx context.Context, req
 	}
 
 	key := e.cacheKey(req)
-	return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+	if blockInput == "earliest" {
+		return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+	}
+	blockNum, err := decodeBlockInput(blockInput)
+	if err != nil {
+		return err
+	}
+	return putBlockRPCResponse(ctx, e.cache, key, blockNum, req, res)
 }
 
 type EthGetBlockRangeMethodHandler struct {
@@ -161,18 +169,20 @@ func (e *EthGetBlockRangeMethodHandler) PutRPCMethod(ctx context.Context, req *R
 			return nil
 		}
 	}
-	if end != "earliest" {
-		endNum, err := decodeBlockInput(end)
-		if err != nil {
-			return err
-		}
-		if curBlock <= endNum+uint64(e.numBlockConfirmations) {
-			return nil
-		}
-	}
-
 	key := e.cacheKey(req)
-	return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+	if end == "earliest" {
+		return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+	}
+	endNum, err := decodeBlockInput(end)
+	if err != nil {
+		return err
+	}
+	if curBlock <= endNum+uint64(e.numBlockConfirmations) {
+		return nil
+	}
+	// reorgs roll back every block past the fork point, so tagging the last
+	// block of the range is enough
+	return putBlockRPCResponse(ctx, e.cache, key, endNum, req, res)
 }
 
 type EthCallMethodHandler struct {
@@ -220,22 +230,108 @@ func (e *EthCallMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, re
 		return nil
 	}
 
-	if blockTag != "earliest" {
-		curBlock, err := e.getLatestBlockNumFn(ctx)
-		if err != nil {
-			return err
-		}
-		blockNum, err := decodeBlockInput(blockTag)
-		if err != nil {
-			return err
-		}
-		if curBlock <= blockNum+uint64(e.numBlockConfirmations) {
-			return nil
-		}
+	key := e.cacheKey(params, blockTag)
+	if blockTag == "earliest" {
+		return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+	}
+	curBlock, err := e.getLatestBlockNumFn(ctx)
+	if err != nil {
+		return err
 	}
+	blockNum, err := decodeBlockInput(blockTag)
+	if err != nil {
+		return err
+	}
+	if curBlock <= blockNum+uint64(e.numBlockConfirmations) {
+		return nil
+	}
+	return putBlockRPCResponse(ctx, e.cache, key, blockNum, req, res)
+}
 
-	key := e.cacheKey(params, blockTag)
-	return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+// EthGetCodeMethodHandler caches the code of an account at a confirmed block.
+type EthGetCodeMethodHandler struct {
+	cache                 Cache
+	getLatestBlockNumFn   GetLatestBlockNumFn
+	numBlockConfirmations int
+}
+
+func (e *EthGetCodeMethodHandler) GetRPCMethod(ctx context.Context, req *RPCReq) (*RPCRes, error) {
+	return getBlockStateRPCResponse(ctx, e.cache, req, 2)
+}
+
+func (e *EthGetCodeMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, res *RPCRes) error {
+	return putBlockStateRPCResponse(ctx, e.cache, e.getLatestBlockNumFn, e.numBlockConfirmations, req, res, 2)
+}
+
+// EthGetStorageAtMethodHandler caches a storage slot of an account at a
+// confirmed block.
+type EthGetStorageAtMethodHandler struct {
+	cache                 Cache
+	getLatestBlockNumFn   GetLatestBlockNumFn
+	numBlockConfirmations int
+}
+
+func (e *EthGetStorageAtMethodHandler) GetRPCMethod(ctx context.Context, req *RPCReq) (*RPCRes, error) {
+	return getBlockStateRPCResponse(ctx, e.cache, req, 3)
+}
+
+func (e *EthGetStorageAtMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, res *RPCRes) error {
+	return putBlockStateRPCResponse(ctx, e.cache, e.getLatestBlockNumFn, e.numBlockConfirmations, req, res, 3)
+}
+
+// EthGetTransactionReceiptMethodHandler caches the receipts of transactions
+// included in confirmed blocks.
+type EthGetTransactionReceiptMethodHandler struct {
+	cache                 Cache
+	getLatestBlockNumFn   GetLatestBlockNumFn
+	numBlockConfirmations int
+}
+
+func (e *EthGetTransactionReceiptMethodHandler) cacheKey(req *RPCReq) (string, error) {
+	var list []string
+	if err := json.Unmarshal(req.Params, &list); err != nil {
+		return "", err
+	}
+	if len(list) != 1 {
+		return "", errInvalidRPCParams
+	}
+	return fmt.Sprintf("method:eth_getTransactionReceipt:%s", strings.ToLower(list[0])), nil
+}
+
+func (e *EthGetTransactionReceiptMethodHandler) GetRPCMethod(ctx context.Context, req *RPCReq) (*RPCRes, error) {
+	key, err := e.cacheKey(req)
+	if err != nil {
+		return nil, err
+	}
+	return getImmutableRPCResponse(ctx, e.cache, key, req)
+}
+
+func (e *EthGetTransactionReceiptMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, res *RPCRes) error {
+	key, err := e.cacheKey(req)
+	if err != nil {
+		return err
+	}
+	// receipts of pending or unknown transactions are null
+	receipt, ok := res.Result.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	blockInput, ok := receipt["blockNumber"].(string)
+	if !ok {
+		return nil
+	}
+	blockNum, err := decodeBlockInput(blockInput)
+	if err != nil {
+		return err
+	}
+	curBlock, err := e.getLatestBlockNumFn(ctx)
+	if err != nil {
+		return err
+	}
+	if curBlock <= blockNum+uint64(e.numBlockConfirmations) {
+		return nil
+	}
+	return putBlockRPCResponse(ctx, e.cache, key, blockNum, req, res)
 }
 
 type EthBlockNumberMethodHandler struct {
@@ -354,6 +450,63 @@ func decodeEthCallParams(req *RPCReq) (*ethCallParams, string, error) {
 	return params, blockTag, nil
 }
 
+// decodeBlockStateParams decodes the params of a state query, such as
+// eth_getCode, whose last param is the block. It returns the cache key and
+// the block.
+func decodeBlockStateParams(req *RPCReq, numParams int) (string, string, error) {
+	var list []string
+	if err := json.Unmarshal(req.Params, &list); err != nil {
+		return "", "", err
+	}
+	if len(list) != numParams {
+		return "", "", errInvalidRPCParams
+	}
+	blockTag := list[numParams-1]
+	if !validBlockInput(blockTag) {
+		return "", "", errInvalidRPCParams
+	}
+	for i := range list {
+		list[i] = strings.ToLower(list[i])
+	}
+	return fmt.Sprintf("method:%s:%s", req.Method, strings.Join(list, ":")), blockTag, nil
+}
+
+func getBlockStateRPCResponse(ctx context.Context, cache Cache, req *RPCReq, numParams int) (*RPCRes, error) {
+	key, blockTag, err := decodeBlockStateParams(req, numParams)
+	if err != nil {
+		return nil, err
+	}
+	if isBlockDependentParam(blockTag) {
+		return nil, nil
+	}
+	return getImmutableRPCResponse(ctx, cache, key, req)
+}
+
+func putBlockStateRPCResponse(ctx context.Context, cache Cache, getLatestBlockNumFn GetLatestBlockNumFn, numBlockConfirmations int, req *RPCReq, res *RPCRes, numParams int) error {
+	key, blockTag, err := decodeBlockStateParams(req, numParams)
+	if err != nil {
+		return err
+	}
+	if isBlockDependentParam(blockTag) {
+		return nil
+	}
+	if blockTag == "earliest" {
+		return putImmutableRPCResponse(ctx, cache, key, req, res)
+	}
+	curBlock, err := getLatestBlockNumFn(ctx)
+	if err != nil {
+		return err
+	}
+	blockNum, err := decodeBlockInput(blockTag)
+	if err != nil {
+		return err
+	}
+	if curBlock <= blockNum+uint64(numBlockConfirmations) {
+		return nil
+	}
+	return putBlockRPCResponse(ctx, cache, key, blockNum, req, res)
+}
+
 func validBlockInput(input string) bool {
 	if input == "earliest" || input == "pending" || input == "latest" {
 		return true
@@ -397,3 +550,15 @@ func putImmutableRPCResponse(ctx context.Context, cache Cache, key string, req *
 	val := mustMarshalJSON(res.Result)
 	return cache.Put(ctx, key, string(val))
 }
+
+// putBlockRPCResponse caches the response of a request for the data of a
+// block, so that it is purged if the block is rolled back.
+func putBlockRPCResponse(ctx context.Context, cache Cache, key string, blockNum uint64, req *RPCReq, res *RPCRes) error {
+	if key == "" {
+		return nil
+	}
+	if err := cache.Tag(ctx, blockCacheTag(blockNum), key); err != nil {
+		return err
+	}
+	return putImmutableRPCResponse(ctx, cache, key, req, res)
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -179,6 +180,12 @@ var (
 		"method",
 	})
 
+	blockRollbacksTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "block_rollbacks_total",
+		Help:      "Count of tracked blocks rolled back by a reorg.",
+	})
+
 	lvcErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: MetricsNamespace,
 		Name:      "lvc_errors_total",
@@ -222,6 +229,237 @@ var (
 	}, []string{
 		"backend_name",
 	})
+
+	backendLatestBlockBackend = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_latest_block",
+		Help:      "Current latest block observed per backend",
+	}, []string{
+		"backend_name",
+	})
+
+	backendBlockLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_block_lag",
+		Help:      "Number of blocks a backend is behind the highest block observed in its group",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+	})
+
+	backendInConsensus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_in_consensus",
+		Help:      "Bool gauge for if a backend is part of its group consensus",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+	})
+
+	backendBanned = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_banned",
+		Help:      "Bool gauge for if a backend is banned from its group consensus",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+	})
+
+	consensusLatestBlock = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "group_consensus_latest_block",
+		Help:      "Consensus latest block",
+	}, []string{
+		"backend_group_name",
+	})
+
+	consensusSafeBlock = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "group_consensus_safe_block",
+		Help:      "Consensus safe block",
+	}, []string{
+		"backend_group_name",
+	})
+
+	consensusGroupCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "group_consensus_count",
+		Help:      "Number of backends serving in the consensus group",
+	}, []string{
+		"backend_group_name",
+	})
+
+	backendGroupRoutingStrategy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_group_routing_strategy",
+		Help:      "Bool gauge of the routing strategy used by a backend group",
+	}, []string{
+		"backend_group_name",
+		"strategy",
+	})
+
+	backendGroupServedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_group_served_total",
+		Help:      "Count of requests served by each backend of a group, used to compute the share of each backend.",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+	})
+
+	backendWeightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_weight",
+		Help:      "Configured routing weight of a backend",
+	}, []string{
+		"backend_name",
+	})
+
+	backendLatencyEWMAGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_latency_ewma_seconds",
+		Help:      "Exponentially weighted moving average of backend response times, in seconds.",
+	}, []string{
+		"backend_name",
+	})
+
+	apiKeyRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "api_key_requests_total",
+		Help:      "Count of RPC calls made with an API key that has a policy, by outcome.",
+	}, []string{
+		"auth",
+		"method_name",
+		"status",
+	})
+
+	apiKeyDailyUsageGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "api_key_daily_usage",
+		Help:      "Number of RPC calls made with an API key since midnight UTC.",
+	}, []string{
+		"auth",
+	})
+
+	txRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tx_rejections_total",
+		Help:      "Count of eth_sendRawTransaction calls rejected by proxyd, by reason.",
+	}, []string{
+		"auth",
+		"reason",
+	})
+
+	getLogsRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "get_logs_rejections_total",
+		Help:      "Count of eth_getLogs calls rejected for exceeding the maximum block range.",
+	}, []string{
+		"auth",
+	})
+
+	getLogsSplitChunks = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "get_logs_split_chunks",
+		Help:      "Histogram of the number of chunks split eth_getLogs calls are fanned out into.",
+		Buckets:   []float64{2, 5, 10, 20, 50, 100},
+	}, []string{
+		"auth",
+	})
+
+	wsUpstreamSubscriptionsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_upstream_subscriptions",
+		Help:      "Number of multiplexed subscriptions held on the upstream connection.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	wsClientSubscriptionsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_client_subscriptions",
+		Help:      "Number of client subscriptions served by proxyd.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	wsSubscriptionNotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_subscription_notifications_total",
+		Help:      "Count of subscription notifications fanned out to clients, before fan-out.",
+	}, []string{
+		"backend_group_name",
+		"kind",
+	})
+
+	wsSubscriptionFailoversTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_subscription_failovers_total",
+		Help:      "Count of times the upstream subscription connection was lost.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	wsGapFillHeadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_gap_fill_heads_total",
+		Help:      "Count of heads missed during a failover that were filled in.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	backendDrainingGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_draining",
+		Help:      "Whether a backend has been drained through the admin API",
+	}, []string{
+		"backend_name",
+	})
+
+	configReloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "config_reloads_total",
+		Help:      "Count of config reloads, by outcome",
+	}, []string{
+		"status",
+	})
+
+	shadowRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "shadow_requests_total",
+		Help:      "Count of requests mirrored to shadow backends, by how the response compared to the primary response",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+		"method_name",
+		"outcome",
+	})
+
+	shadowDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "shadow_dropped_total",
+		Help:      "Count of sampled requests not mirrored because too many were in flight",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+	})
+
+	methodRouteFallbacksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "method_route_fallbacks_total",
+		Help:      "Count of requests that fell back from a backend group without available backends",
+	}, []string{
+		"route",
+		"backend_group_name",
+	})
+
+	consensusBrokenTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "group_consensus_broken_total",
+		Help:      "Count of times a backend disagreed on an already agreed block",
+	}, []string{
+		"backend_group_name",
+	})
 )
 
 func RecordRedisError(source string) {
@@ -278,3 +516,127 @@ func RecordCacheHit(method string) {
 func RecordCacheMiss(method string) {
 	cacheMissesTotal.WithLabelValues(method).Inc()
 }
+
+func RecordBlockRollback() {
+	blockRollbacksTotal.Inc()
+}
+
+func RecordBackendLatestBlock(b *Backend, blockNumber hexutil.Uint64) {
+	backendLatestBlockBackend.WithLabelValues(b.Name).Set(float64(blockNumber))
+}
+
+func RecordBackendBlockLag(bg *BackendGroup, b *Backend, highestBlock, blockNumber hexutil.Uint64) {
+	var lag float64
+	if highestBlock > blockNumber {
+		lag = float64(highestBlock - blockNumber)
+	}
+	backendBlockLag.WithLabelValues(bg.Name, b.Name).Set(lag)
+}
+
+func RecordBackendInConsensus(bg *BackendGroup, b *Backend, inConsensus bool) {
+	backendInConsensus.WithLabelValues(bg.Name, b.Name).Set(boolToFloat64(inConsensus))
+}
+
+func RecordBackendBanned(bg *BackendGroup, b *Backend, banned bool) {
+	backendBanned.WithLabelValues(bg.Name, b.Name).Set(boolToFloat64(banned))
+}
+
+func RecordGroupConsensusLatestBlock(bg *BackendGroup, blockNumber hexutil.Uint64) {
+	consensusLatestBlock.WithLabelValues(bg.Name).Set(float64(blockNumber))
+}
+
+func RecordGroupConsensusSafeBlock(bg *BackendGroup, blockNumber hexutil.Uint64) {
+	consensusSafeBlock.WithLabelValues(bg.Name).Set(float64(blockNumber))
+}
+
+func RecordGroupConsensusCount(bg *BackendGroup, count int) {
+	consensusGroupCount.WithLabelValues(bg.Name).Set(float64(count))
+}
+
+func RecordGroupConsensusBroken(bg *BackendGroup) {
+	consensusBrokenTotal.WithLabelValues(bg.Name).Inc()
+}
+
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func RecordBackendGroupRoutingStrategy(bg *BackendGroup, strategy string) {
+	backendGroupRoutingStrategy.WithLabelValues(bg.Name, strategy).Set(1)
+}
+
+func RecordBackendGroupServed(bg *BackendGroup, b *Backend) {
+	backendGroupServedTotal.WithLabelValues(bg.Name, b.Name).Inc()
+}
+
+func RecordBackendWeight(b *Backend) {
+	backendWeightGauge.WithLabelValues(b.Name).Set(float64(b.weight))
+}
+
+func RecordBackendLatencyEWMA(b *Backend, seconds float64) {
+	backendLatencyEWMAGauge.WithLabelValues(b.Name).Set(seconds)
+}
+
+func RecordBackendDraining(b *Backend, draining bool) {
+	backendDrainingGauge.WithLabelValues(b.Name).Set(boolToFloat64(draining))
+}
+
+func RecordConfigReload(err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	configReloadsTotal.WithLabelValues(status).Inc()
+}
+
+func RecordShadowRequest(backendGroup string, b *Backend, method, outcome string) {
+	shadowRequestsTotal.WithLabelValues(backendGroup, b.Name, method, outcome).Inc()
+}
+
+func RecordShadowDropped(backendGroup string, b *Backend) {
+	shadowDroppedTotal.WithLabelValues(backendGroup, b.Name).Inc()
+}
+
+func RecordMethodRouteFallback(route string, bg *BackendGroup) {
+	methodRouteFallbacksTotal.WithLabelValues(route, bg.Name).Inc()
+}
+
+func RecordAPIKeyRequest(ctx context.Context, method, status string) {
+	apiKeyRequestsTotal.WithLabelValues(GetAuthCtx(ctx), method, status).Inc()
+}
+
+func RecordAPIKeyDailyUsage(alias string, used int) {
+	apiKeyDailyUsageGauge.WithLabelValues(alias).Set(float64(used))
+}
+
+func RecordTxRejection(ctx context.Context, reason string) {
+	txRejectionsTotal.WithLabelValues(GetAuthCtx(ctx), reason).Inc()
+}
+
+func RecordWSSubscriptions(backendGroup string, upstream, clients int) {
+	wsUpstreamSubscriptionsGauge.WithLabelValues(backendGroup).Set(float64(upstream))
+	wsClientSubscriptionsGauge.WithLabelValues(backendGroup).Set(float64(clients))
+}
+
+func RecordWSSubscriptionNotifications(backendGroup, kind string, count int) {
+	wsSubscriptionNotificationsTotal.WithLabelValues(backendGroup, kind).Add(float64(count))
+}
+
+func RecordWSSubscriptionFailover(backendGroup string) {
+	wsSubscriptionFailoversTotal.WithLabelValues(backendGroup).Inc()
+}
+
+func RecordWSGapFill(backendGroup string, heads int) {
+	wsGapFillHeadsTotal.WithLabelValues(backendGroup).Add(float64(heads))
+}
+
+func RecordGetLogsRejection(ctx context.Context) {
+	getLogsRejectionsTotal.WithLabelValues(GetAuthCtx(ctx)).Inc()
+}
+
+func RecordGetLogsSplit(ctx context.Context, chunks int) {
+	getLogsSplitChunks.WithLabelValues(GetAuthCtx(ctx)).Observe(float64(chunks))
+}
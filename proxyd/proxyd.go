@@ -6,39 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/semaphore"
 )
 
-func Start(config *Config) (func(), error) {
-	if len(config.Backends) == 0 {
-		return nil, errors.New("must define at least one backend")
-	}
-	if len(config.BackendGroups) == 0 {
-		return nil, errors.New("must define at least one backend group")
-	}
-	if len(config.RPCMethodMappings) == 0 {
-		return nil, errors.New("must define at least one RPC method mapping")
-	}
-
-	for authKey := range config.Authentication {
-		if authKey == "none" {
-			return nil, errors.New("cannot use none as an auth key")
-		}
-	}
-
+func Start(config *Config) (*Server, func(), error) {
 	var redisURL string
 	if config.Redis.URL != "" {
 		rURL, err := ReadFromEnvOrConfig(config.Redis.URL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		redisURL = rURL
 	}
@@ -51,7 +36,7 @@ func Start(config *Config) (func(), error) {
 	} else {
 		lim, err = NewRedisRateLimiter(redisURL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -61,174 +46,120 @@ func Start(config *Config) (func(), error) {
 	}
 	rpcRequestSemaphore := semaphore.NewWeighted(maxConcurrentRPCs)
 
-	backendNames := make([]string, 0)
-	backendsByName := make(map[string]*Backend)
-	for name, cfg := range config.Backends {
-		opts := make([]BackendOpt, 0)
-
-		rpcURL, err := ReadFromEnvOrConfig(cfg.RPCURL)
-		if err != nil {
-			return nil, err
-		}
-		wsURL, err := ReadFromEnvOrConfig(cfg.WSURL)
-		if err != nil {
-			return nil, err
-		}
-		if rpcURL == "" {
-			return nil, fmt.Errorf("must define an RPC URL for backend %s", name)
-		}
-		if wsURL == "" {
-			return nil, fmt.Errorf("must define a WS URL for backend %s", name)
-		}
-
-		if config.BackendOptions.ResponseTimeoutSeconds != 0 {
-			timeout := secondsToDuration(config.BackendOptions.ResponseTimeoutSeconds)
-			opts = append(opts, WithTimeout(timeout))
-		}
-		if config.BackendOptions.MaxRetries != 0 {
-			opts = append(opts, WithMaxRetries(config.BackendOptions.MaxRetries))
-		}
-		if config.BackendOptions.MaxResponseSizeBytes != 0 {
-			opts = append(opts, WithMaxResponseSize(config.BackendOptions.MaxResponseSizeBytes))
-		}
-		if config.BackendOptions.OutOfServiceSeconds != 0 {
-			opts = append(opts, WithOutOfServiceDuration(secondsToDuration(config.BackendOptions.OutOfServiceSeconds)))
-		}
-		if cfg.MaxRPS != 0 {
-			opts = append(opts, WithMaxRPS(cfg.MaxRPS))
-		}
-		if cfg.MaxWSConns != 0 {
-			opts = append(opts, WithMaxWSConns(cfg.MaxWSConns))
-		}
-		if cfg.Password != "" {
-			passwordVal, err := ReadFromEnvOrConfig(cfg.Password)
-			if err != nil {
-				return nil, err
-			}
-			opts = append(opts, WithBasicAuth(cfg.Username, passwordVal))
-		}
-		tlsConfig, err := configureBackendTLS(cfg)
-		if err != nil {
-			return nil, err
-		}
-		if tlsConfig != nil {
-			log.Info("using custom TLS config for backend", "name", name)
-			opts = append(opts, WithTLSConfig(tlsConfig))
-		}
-		if cfg.StripTrailingXFF {
-			opts = append(opts, WithStrippedTrailingXFF())
-		}
-		opts = append(opts, WithProxydIP(os.Getenv("PROXYD_IP")))
-		back := NewBackend(name, rpcURL, wsURL, lim, rpcRequestSemaphore, opts...)
-		backendNames = append(backendNames, name)
-		backendsByName[name] = back
-		log.Info("configured backend", "name", name, "rpc_url", rpcURL, "ws_url", wsURL)
+	rc, err := buildRuntimeConfig(config, lim, rpcRequestSemaphore, nil)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	backendGroups := make(map[string]*BackendGroup)
-	for bgName, bg := range config.BackendGroups {
-		backends := make([]*Backend, 0)
-		for _, bName := range bg.Backends {
-			if backendsByName[bName] == nil {
-				return nil, fmt.Errorf("backend %s is not defined", bName)
-			}
-			backends = append(backends, backendsByName[bName])
+	var wsSubscriptions *SubscriptionManager
+	if config.WSMultiplexSubscriptions {
+		if rc.wsBackendGroup == nil {
+			return nil, nil, fmt.Errorf("ws subscription multiplexing requires a ws group")
 		}
-		group := &BackendGroup{
-			Name:     bgName,
-			Backends: backends,
+		var opts []SubscriptionManagerOpt
+		if config.WSMaxGapFillBlocks != 0 {
+			opts = append(opts, WithMaxGapFillBlocks(config.WSMaxGapFillBlocks))
 		}
-		backendGroups[bgName] = group
+		wsSubscriptions = NewSubscriptionManager(rc.wsBackendGroup, opts...)
 	}
 
-	var wsBackendGroup *BackendGroup
-	if config.WSBackendGroup != "" {
-		wsBackendGroup = backendGroups[config.WSBackendGroup]
-		if wsBackendGroup == nil {
-			return nil, fmt.Errorf("ws backend group %s does not exist", config.WSBackendGroup)
-		}
-	}
+	// policies are always created so that keys can be added on reload
+	apiKeyPolicies := NewAPIKeyPolicies(config.APIKeys, lim)
 
-	if wsBackendGroup == nil && config.Server.WSPort != 0 {
-		return nil, fmt.Errorf("a ws port was defined, but no ws group was defined")
-	}
-
-	for _, bg := range config.RPCMethodMappings {
-		if backendGroups[bg] == nil {
-			return nil, fmt.Errorf("undefined backend group %s", bg)
+	var txGate *TxGate
+	if config.TxValidation.Enabled {
+		txGate, err = NewTxGate(config.TxValidation)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	var resolvedAuth map[string]string
-
-	if config.Authentication != nil {
-		resolvedAuth = make(map[string]string)
-		for secret, alias := range config.Authentication {
-			resolvedSecret, err := ReadFromEnvOrConfig(secret)
-			if err != nil {
-				return nil, err
-			}
-			resolvedAuth[resolvedSecret] = alias
+	var adminToken string
+	if config.Admin.Token != "" {
+		adminToken, err = ReadFromEnvOrConfig(config.Admin.Token)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
 	var (
-		rpcCache    RPCCache
-		blockNumLVC *EthLastValueCache
-		gasPriceLVC *EthLastValueCache
+		rpcCache     RPCCache
+		getLogs      *GetLogsHandler
+		blockNumLVC  *EthLastValueCache
+		gasPriceLVC  *EthLastValueCache
+		reorgTracker *ReorgTracker
 	)
-	if config.Cache.Enabled {
+	if config.Cache.Enabled || config.GetLogs.Enabled {
 		var (
 			cache      Cache
 			blockNumFn GetLatestBlockNumFn
-			gasPriceFn GetLatestGasPriceFn
 		)
 
 		if config.Cache.BlockSyncRPCURL == "" {
-			return nil, fmt.Errorf("block sync node required for caching")
+			return nil, nil, fmt.Errorf("block sync node required for caching and eth_getLogs limits")
 		}
 		blockSyncRPCURL, err := ReadFromEnvOrConfig(config.Cache.BlockSyncRPCURL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
+		cacheTTL := secondsToDuration(config.Cache.TTLSeconds)
 		if redisURL != "" {
-			if cache, err = newRedisCache(redisURL); err != nil {
-				return nil, err
+			if cache, err = newRedisCache(redisURL, cacheTTL); err != nil {
+				return nil, nil, err
 			}
 		} else {
 			log.Warn("redis is not configured, using in-memory cache")
-			cache = newMemoryCache()
+			cache = newMemoryCache(config.Cache.MemoryMaxEntries, cacheTTL)
 		}
 		// Ideally, the BlocKSyncRPCURL should be the sequencer or a HA replica that's not far behind
-		ethClient, err := ethclient.Dial(blockSyncRPCURL)
+		rpcClient, err := rpc.Dial(blockSyncRPCURL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		ethClient := ethclient.NewClient(rpcClient)
 		defer ethClient.Close()
 
 		blockNumLVC, blockNumFn = makeGetLatestBlockNumFn(ethClient, cache)
-		gasPriceLVC, gasPriceFn = makeGetLatestGasPriceFn(ethClient, cache)
-		rpcCache = newRPCCache(newCacheWithCompression(cache), blockNumFn, gasPriceFn, config.Cache.NumBlockConfirmations)
+
+		// confirmed eth_getLogs chunks are only cached if caching is enabled
+		var rpcCacheStore Cache
+		if config.Cache.Enabled {
+			var gasPriceFn GetLatestGasPriceFn
+			gasPriceLVC, gasPriceFn = makeGetLatestGasPriceFn(ethClient, cache)
+			rpcCacheStore = newCacheWithCompression(cache)
+			rpcCache = newRPCCache(rpcCacheStore, blockNumFn, gasPriceFn, config.Cache.NumBlockConfirmations)
+
+			if config.Cache.ReorgWindow > 0 {
+				reorgTracker = NewReorgTracker(rpcClient, uint64(config.Cache.ReorgWindow), func(ctx context.Context, blockNum uint64, hash common.Hash) {
+					if err := InvalidateBlock(ctx, rpcCacheStore, blockNum); err != nil {
+						log.Error("error purging cache entries of rolled back block", "number", blockNum, "hash", hash, "err", err)
+					}
+				})
+				reorgTracker.Start()
+			}
+		}
+		if config.GetLogs.Enabled {
+			getLogs = NewGetLogsHandler(config.GetLogs, blockNumFn, rpcCacheStore, config.Cache.NumBlockConfirmations, apiKeyPolicies)
+		}
 	}
 
-	srv, err := NewServer(
-		backendGroups,
-		wsBackendGroup,
-		NewStringSetFromStrings(config.WSMethodWhitelist),
-		config.RPCMethodMappings,
+	srv := NewServer(
+		rc,
+		lim,
+		rpcRequestSemaphore,
+		wsSubscriptions,
 		config.Server.MaxBodySizeBytes,
-		resolvedAuth,
 		secondsToDuration(config.Server.TimeoutSeconds),
 		config.Server.MaxUpstreamBatchSize,
 		rpcCache,
-		config.RateLimit,
 		config.Server.EnableRequestLog,
 		config.Server.MaxRequestBodyLogLen,
+		apiKeyPolicies,
+		txGate,
+		getLogs,
+		adminToken,
 	)
-	if err != nil {
-		return nil, fmt.Errorf("error creating server: %w", err)
-	}
 
 	if config.Metrics.Enabled {
 		addr := fmt.Sprintf("%s:%d", config.Metrics.Host, config.Metrics.Port)
@@ -240,6 +171,10 @@ func Start(config *Config) (func(), error) {
 		}()
 	}
 
+	// Consensus aware groups agree on a block before the servers accept
+	// requests, otherwise they would have no backend to serve them.
+	rc.startConsensus(nil)
+
 	// To allow integration tests to cleanly come up, wait
 	// 10ms to give the below goroutines enough time to
 	// encounter an error creating their servers
@@ -269,10 +204,14 @@ func Start(config *Config) (func(), error) {
 		}()
 	}
 
+	if wsSubscriptions != nil {
+		wsSubscriptions.Start()
+	}
+
 	<-errTimer.C
 	log.Info("started proxyd")
 
-	return func() {
+	return srv, func() {
 		log.Info("shutting down proxyd")
 		if blockNumLVC != nil {
 			blockNumLVC.Stop()
@@ -280,8 +219,16 @@ func Start(config *Config) (func(), error) {
 		if gasPriceLVC != nil {
 			gasPriceLVC.Stop()
 		}
+		if reorgTracker != nil {
+			reorgTracker.Stop()
+		}
+		rc := srv.runtime()
+		rc.stopConsensus(nil)
+		if wsSubscriptions != nil {
+			wsSubscriptions.Stop()
+		}
 		srv.Shutdown()
-		if err := lim.FlushBackendWSConns(backendNames); err != nil {
+		if err := lim.FlushBackendWSConns(rc.backendNames()); err != nil {
 			log.Error("error flushing backend ws conns", "err", err)
 		}
 		log.Info("goodbye")
@@ -292,6 +239,40 @@ func secondsToDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
 }
 
+func consensusOptsFromConfig(cfg *BackendGroupConfig) []ConsensusOpt {
+	opts := make([]ConsensusOpt, 0)
+	if cfg.ConsensusPollIntervalSeconds != 0 {
+		opts = append(opts, WithConsensusPollInterval(secondsToDuration(cfg.ConsensusPollIntervalSeconds)))
+	}
+	if cfg.ConsensusBanPeriodSeconds != 0 {
+		opts = append(opts, WithConsensusBanPeriod(secondsToDuration(cfg.ConsensusBanPeriodSeconds)))
+	}
+	if cfg.ConsensusMaxUpdateThresholdSeconds != 0 {
+		opts = append(opts, WithConsensusMaxUpdateThreshold(secondsToDuration(cfg.ConsensusMaxUpdateThresholdSeconds)))
+	}
+	if cfg.ConsensusMaxBlockLag != 0 {
+		opts = append(opts, WithConsensusMaxBlockLag(cfg.ConsensusMaxBlockLag))
+	}
+	if cfg.ConsensusSafeBlockDepth != 0 {
+		opts = append(opts, WithConsensusSafeBlockDepth(cfg.ConsensusSafeBlockDepth))
+	}
+	return opts
+}
+
+func shadowOptsFromConfig(cfg *BackendGroupConfig) []ShadowOpt {
+	opts := make([]ShadowOpt, 0)
+	if cfg.ShadowSampleRate != 0 {
+		opts = append(opts, WithShadowSampleRate(cfg.ShadowSampleRate))
+	}
+	if cfg.ShadowMaxInFlight != 0 {
+		opts = append(opts, WithShadowMaxInFlight(cfg.ShadowMaxInFlight))
+	}
+	if len(cfg.ShadowMethods) > 0 {
+		opts = append(opts, WithShadowMethods(cfg.ShadowMethods))
+	}
+	return opts
+}
+
 func configureBackendTLS(cfg *BackendConfig) (*tls.Config, error) {
 	if cfg.CAFile == "" {
 		return nil, nil
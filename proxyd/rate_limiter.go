@@ -21,6 +21,19 @@ end
 return current
 `
 
+const IncrWithExpiryScript = `
+local current
+current = redis.call("incr", KEYS[1])
+if current == 1 then
+    redis.call("expire", KEYS[1], ARGV[1])
+end
+return current
+`
+
+// keyDailyUsageTTL keeps yesterday's usage around so it can still be
+// inspected shortly after midnight UTC.
+const keyDailyUsageTTL = 48 * time.Hour
+
 const MaxConcurrentWSConnsScript = `
 redis.call("sadd", KEYS[1], KEYS[2])
 local total = 0
@@ -48,6 +61,9 @@ type BackendRateLimiter interface {
 	IncBackendWSConns(name string, max int) (bool, error)
 	DecBackendWSConns(name string) error
 	FlushBackendWSConns(names []string) error
+	IncKeyRPS(alias string) (int, error)
+	IncKeyDailyUsage(alias string, day string) (int, error)
+	KeyDailyUsage(alias string, day string) (int, error)
 }
 
 type RedisBackendRateLimiter struct {
@@ -172,6 +188,47 @@ func (r *RedisBackendRateLimiter) FlushBackendWSConns(names []string) error {
 	return nil
 }
 
+func (r *RedisBackendRateLimiter) IncKeyRPS(alias string) (int, error) {
+	cmd := r.rdb.Eval(
+		context.Background(),
+		MaxRPSScript,
+		[]string{fmt.Sprintf("apikey:%s:ratelimit", alias)},
+	)
+	rps, err := cmd.Int()
+	if err != nil {
+		RecordRedisError("IncKeyRPS")
+		return -1, wrapErr(err, "error upserting api key rate limit")
+	}
+	return rps, nil
+}
+
+func (r *RedisBackendRateLimiter) IncKeyDailyUsage(alias string, day string) (int, error) {
+	cmd := r.rdb.Eval(
+		context.Background(),
+		IncrWithExpiryScript,
+		[]string{fmt.Sprintf("apikey:%s:usage:%s", alias, day)},
+		int(keyDailyUsageTTL.Seconds()),
+	)
+	used, err := cmd.Int()
+	if err != nil {
+		RecordRedisError("IncKeyDailyUsage")
+		return -1, wrapErr(err, "error upserting api key daily usage")
+	}
+	return used, nil
+}
+
+func (r *RedisBackendRateLimiter) KeyDailyUsage(alias string, day string) (int, error) {
+	used, err := r.rdb.Get(context.Background(), fmt.Sprintf("apikey:%s:usage:%s", alias, day)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		RecordRedisError("KeyDailyUsage")
+		return -1, wrapErr(err, "error getting api key daily usage")
+	}
+	return used, nil
+}
+
 func (r *RedisBackendRateLimiter) touch() {
 	for {
 		r.tkMtx.Lock()
@@ -190,14 +247,23 @@ type LocalBackendRateLimiter struct {
 	deadBackends   map[string]time.Time
 	backendRPS     map[string]int
 	backendWSConns map[string]int
+	keyRPS         map[string]int
+	keyDailyUsage  map[string]*localDailyUsage
 	mtx            sync.RWMutex
 }
 
+type localDailyUsage struct {
+	day   string
+	count int
+}
+
 func NewLocalBackendRateLimiter() *LocalBackendRateLimiter {
 	out := &LocalBackendRateLimiter{
 		deadBackends:   make(map[string]time.Time),
 		backendRPS:     make(map[string]int),
 		backendWSConns: make(map[string]int),
+		keyRPS:         make(map[string]int),
+		keyDailyUsage:  make(map[string]*localDailyUsage),
 	}
 	go out.clear()
 	return out
@@ -247,11 +313,41 @@ func (l *LocalBackendRateLimiter) FlushBackendWSConns(names []string) error {
 	return nil
 }
 
+func (l *LocalBackendRateLimiter) IncKeyRPS(alias string) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.keyRPS[alias] += 1
+	return l.keyRPS[alias], nil
+}
+
+func (l *LocalBackendRateLimiter) IncKeyDailyUsage(alias string, day string) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	usage := l.keyDailyUsage[alias]
+	if usage == nil || usage.day != day {
+		usage = &localDailyUsage{day: day}
+		l.keyDailyUsage[alias] = usage
+	}
+	usage.count += 1
+	return usage.count, nil
+}
+
+func (l *LocalBackendRateLimiter) KeyDailyUsage(alias string, day string) (int, error) {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	usage := l.keyDailyUsage[alias]
+	if usage == nil || usage.day != day {
+		return 0, nil
+	}
+	return usage.count, nil
+}
+
 func (l *LocalBackendRateLimiter) clear() {
 	for {
 		time.Sleep(time.Second)
 		l.mtx.Lock()
 		l.backendRPS = make(map[string]int)
+		l.keyRPS = make(map[string]int)
 		l.mtx.Unlock()
 	}
 }
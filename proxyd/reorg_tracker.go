@@ -0,0 +1,152 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// OnBlockRolledBack is called for every tracked block that is no longer part
+// of the canonical chain, with the hash the block used to have.
+type OnBlockRolledBack func(ctx context.Context, blockNum uint64, hash common.Hash)
+
+type blockHeader struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+}
+
+// ReorgTracker follows the canonical chain of a node by remembering the hashes
+// of the most recent blocks. Whenever the node reports a different hash for a
+// remembered block, or a head below it, the block has been rolled back and the
+// listener is notified, so that data derived from the block can be purged.
+type ReorgTracker struct {
+	client     *rpc.Client
+	window     uint64
+	onRollback OnBlockRolledBack
+
+	hashes   map[uint64]common.Hash
+	lowest   uint64
+	tracking bool
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+func NewReorgTracker(client *rpc.Client, window uint64, onRollback OnBlockRolledBack) *ReorgTracker {
+	return &ReorgTracker{
+		client:     client,
+		window:     window,
+		onRollback: onRollback,
+		hashes:     make(map[uint64]common.Hash),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (t *ReorgTracker) Start() {
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(cacheSyncRate)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), cacheSyncRate)
+				if err := t.update(ctx); err != nil {
+					log.Warn("error checking for reorgs", "err", err)
+				}
+				cancel()
+			case <-t.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (t *ReorgTracker) Stop() {
+	close(t.quit)
+	<-t.done
+}
+
+// update fetches the head and walks back its ancestors until it reaches a
+// remembered block with the same hash, the start of the tracked window or
+// a block that has never been tracked.
+func (t *ReorgTracker) update(ctx context.Context) error {
+	head, err := t.fetchHeader(ctx, "latest")
+	if err != nil {
+		return err
+	}
+	if hash, ok := t.hashes[uint64(head.Number)]; ok && hash == head.Hash {
+		return nil
+	}
+
+	headers := []*blockHeader{head}
+	for cur := head; cur.Number > 0 && uint64(head.Number-cur.Number) < t.window; {
+		parentNum := uint64(cur.Number) - 1
+		hash, ok := t.hashes[parentNum]
+		if ok && hash == cur.ParentHash {
+			break
+		}
+		// only fill gaps between remembered blocks
+		if !ok && (!t.tracking || parentNum < t.lowest) {
+			break
+		}
+		if cur, err = t.fetchHeader(ctx, hexutil.EncodeUint64(parentNum)); err != nil {
+			return err
+		}
+		headers = append(headers, cur)
+	}
+
+	// blocks past the new head are gone
+	for num, hash := range t.hashes {
+		if num > uint64(head.Number) {
+			t.rollback(ctx, num, hash)
+		}
+	}
+	for _, header := range headers {
+		num := uint64(header.Number)
+		if hash, ok := t.hashes[num]; ok && hash != header.Hash {
+			t.rollback(ctx, num, hash)
+		}
+		t.hashes[num] = header.Hash
+	}
+
+	if !t.tracking || t.lowest > uint64(head.Number) {
+		t.lowest = uint64(head.Number)
+		t.tracking = true
+	}
+	if uint64(head.Number) >= t.window && t.lowest < uint64(head.Number)-t.window {
+		t.lowest = uint64(head.Number) - t.window
+	}
+	for num := range t.hashes {
+		if num < t.lowest {
+			delete(t.hashes, num)
+		}
+	}
+	return nil
+}
+
+func (t *ReorgTracker) rollback(ctx context.Context, num uint64, hash common.Hash) {
+	log.Warn("block rolled back", "number", num, "hash", hash)
+	RecordBlockRollback()
+	delete(t.hashes, num)
+	t.onRollback(ctx, num, hash)
+}
+
+func (t *ReorgTracker) fetchHeader(ctx context.Context, block string) (*blockHeader, error) {
+	var header *blockHeader
+	if err := t.client.CallContext(ctx, &header, "eth_getBlockByNumber", block, false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %s not found", block)
+	}
+	return header, nil
+}
@@ -0,0 +1,85 @@
+package proxyd
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+type testChain struct {
+	blocks []*blockHeader
+}
+
+// extend replaces the blocks past the given number with new ones up to the
+// head. fork distinguishes the hashes of different forks.
+func (c *testChain) extend(from, head uint64, fork byte) {
+	c.blocks = c.blocks[:from]
+	for num := from; num <= head; num++ {
+		header := &blockHeader{
+			Number: hexutil.Uint64(num),
+			Hash:   common.Hash{fork, byte(num)},
+		}
+		if num > 0 {
+			header.ParentHash = c.blocks[num-1].Hash
+		}
+		c.blocks = append(c.blocks, header)
+	}
+}
+
+func (c *testChain) GetBlockByNumber(tag string, full bool) (*blockHeader, error) {
+	if tag == "latest" {
+		return c.blocks[len(c.blocks)-1], nil
+	}
+	num, err := hexutil.DecodeUint64(tag)
+	if err != nil {
+		return nil, err
+	}
+	if num >= uint64(len(c.blocks)) {
+		return nil, nil
+	}
+	return c.blocks[num], nil
+}
+
+func TestReorgTracker(t *testing.T) {
+	chain := new(testChain)
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", chain))
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var rolledBack []uint64
+	tracker := NewReorgTracker(client, 5, func(ctx context.Context, blockNum uint64, hash common.Hash) {
+		rolledBack = append(rolledBack, blockNum)
+	})
+	update := func() []uint64 {
+		rolledBack = nil
+		require.NoError(t, tracker.update(context.Background()))
+		sort.Slice(rolledBack, func(i, j int) bool { return rolledBack[i] < rolledBack[j] })
+		return rolledBack
+	}
+
+	chain.extend(0, 10, 0)
+	require.Empty(t, update())
+	chain.extend(11, 14, 0)
+	require.Empty(t, update())
+
+	// the gap between heads is tracked too
+	chain.extend(12, 14, 1)
+	require.Equal(t, []uint64{12, 13, 14}, update())
+
+	// a shorter fork rolls back the blocks past its head
+	chain.extend(13, 13, 2)
+	require.Equal(t, []uint64{13, 14}, update())
+
+	// blocks outside of the window are forgotten
+	chain.extend(14, 30, 2)
+	require.Empty(t, update())
+	chain.extend(20, 30, 3)
+	require.Equal(t, []uint64{25, 26, 27, 28, 29, 30}, update())
+}
@@ -0,0 +1,246 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type RewriteContext struct {
+	latest hexutil.Uint64
+	safe   hexutil.Uint64
+}
+
+type RewriteResult uint8
+
+const (
+	// RewriteNone means request should be forwarded as-is
+	RewriteNone RewriteResult = iota
+
+	// RewriteOverrideError means there was an error attempting to rewrite
+	RewriteOverrideError
+
+	// RewriteOverrideRequest means the modified request should be forwarded to the backend
+	RewriteOverrideRequest
+
+	// RewriteOverrideResponse means to skip calling the backend and serve the overridden response
+	RewriteOverrideResponse
+)
+
+var (
+	ErrRewriteBlockOutOfRange = errors.New("block is out of range")
+)
+
+// RewriteTags modifies the request and the response based on block tags
+func RewriteTags(rctx RewriteContext, req *RPCReq, res *RPCRes) (RewriteResult, error) {
+	rw, err := RewriteResponse(rctx, req, res)
+	if rw == RewriteOverrideResponse {
+		return rw, err
+	}
+	return RewriteRequest(rctx, req, res)
+}
+
+// RewriteResponse modifies the response object to comply with the rewrite context
+// after the method has been called at the backend
+// RewriteResult informs the decision of the rewrite
+func RewriteResponse(rctx RewriteContext, req *RPCReq, res *RPCRes) (RewriteResult, error) {
+	switch req.Method {
+	case "eth_blockNumber":
+		res.Result = rctx.latest
+		return RewriteOverrideResponse, nil
+	}
+	return RewriteNone, nil
+}
+
+// RewriteRequest modifies the request object to comply with the rewrite context
+// before the method has been called at the backend
+// it returns false if nothing was changed
+func RewriteRequest(rctx RewriteContext, req *RPCReq, res *RPCRes) (RewriteResult, error) {
+	switch req.Method {
+	case "eth_getLogs",
+		"eth_newFilter":
+		return rewriteRange(rctx, req, res, 0)
+	case "eth_getBlockRange":
+		return rewriteParams(rctx, req, res, 0, 1)
+	case "eth_getBalance",
+		"eth_getCode",
+		"eth_getTransactionCount",
+		"eth_call":
+		return rewriteParam(rctx, req, res, 1)
+	case "eth_getStorageAt":
+		return rewriteParam(rctx, req, res, 2)
+	case "eth_getBlockTransactionCountByNumber",
+		"eth_getUncleCountByBlockNumber",
+		"eth_getBlockByNumber",
+		"eth_getTransactionByBlockNumberAndIndex",
+		"eth_getUncleByBlockNumberAndIndex":
+		return rewriteParam(rctx, req, res, 0)
+	}
+	return RewriteNone, nil
+}
+
+func rewriteParam(rctx RewriteContext, req *RPCReq, res *RPCRes, pos int) (RewriteResult, error) {
+	var p []interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+
+	// we assume latest if the param is missing,
+	// and we don't rewrite if there is not enough params
+	if len(p) == pos {
+		p = append(p, "latest")
+	} else if len(p) < pos {
+		return RewriteNone, nil
+	}
+
+	rw, err := rewriteTagAt(rctx, p, pos)
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+	if !rw {
+		return RewriteNone, nil
+	}
+	return remarshalParams(req, p)
+}
+
+func rewriteParams(rctx RewriteContext, req *RPCReq, res *RPCRes, positions ...int) (RewriteResult, error) {
+	var p []interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+
+	modified := false
+	for _, pos := range positions {
+		if len(p) <= pos {
+			return RewriteNone, nil
+		}
+		rw, err := rewriteTagAt(rctx, p, pos)
+		if err != nil {
+			return RewriteOverrideError, err
+		}
+		modified = modified || rw
+	}
+	if !modified {
+		return RewriteNone, nil
+	}
+	return remarshalParams(req, p)
+}
+
+func rewriteRange(rctx RewriteContext, req *RPCReq, res *RPCRes, pos int) (RewriteResult, error) {
+	var p []map[string]interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+	if len(p) <= pos {
+		return RewriteNone, nil
+	}
+
+	// block hash queries are not bound to a height
+	if _, ok := p[pos]["blockHash"]; ok {
+		return RewriteNone, nil
+	}
+
+	// an unset range means latest on both ends
+	if p[pos]["fromBlock"] == nil || p[pos]["fromBlock"] == "" {
+		p[pos]["fromBlock"] = "latest"
+	}
+	if p[pos]["toBlock"] == nil || p[pos]["toBlock"] == "" {
+		p[pos]["toBlock"] = "latest"
+	}
+
+	modifiedFrom, err := rewriteTagMap(rctx, p[pos], "fromBlock")
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+	modifiedTo, err := rewriteTagMap(rctx, p[pos], "toBlock")
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+
+	// if any of the fields the request have been changed, re-marshal the params
+	if modifiedFrom || modifiedTo {
+		paramsRaw, err := json.Marshal(p)
+		if err != nil {
+			return RewriteOverrideError, err
+		}
+		req.Params = paramsRaw
+		return RewriteOverrideRequest, nil
+	}
+
+	return RewriteNone, nil
+}
+
+func rewriteTagAt(rctx RewriteContext, p []interface{}, pos int) (bool, error) {
+	s, ok := p[pos].(string)
+	if !ok {
+		// EIP-1898 block number or hash objects are left untouched
+		if _, isObj := p[pos].(map[string]interface{}); isObj {
+			return false, nil
+		}
+		return false, errors.New("expected string")
+	}
+	val, rw, err := rewriteTag(rctx, s)
+	if err != nil {
+		return false, err
+	}
+	if rw {
+		p[pos] = val
+	}
+	return rw, nil
+}
+
+func rewriteTagMap(rctx RewriteContext, m map[string]interface{}, key string) (bool, error) {
+	s, ok := m[key].(string)
+	if !ok {
+		return false, errors.New("expected string")
+	}
+
+	val, rw, err := rewriteTag(rctx, s)
+	if err != nil {
+		return false, err
+	}
+	if rw {
+		m[key] = val
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func rewriteTag(rctx RewriteContext, current string) (string, bool, error) {
+	var bn rpc.BlockNumber
+	if err := bn.UnmarshalJSON([]byte(`"` + current + `"`)); err != nil {
+		return "", false, err
+	}
+
+	switch bn {
+	case rpc.PendingBlockNumber,
+		rpc.EarliestBlockNumber,
+		rpc.FinalizedBlockNumber:
+		return current, false, nil
+	case rpc.SafeBlockNumber:
+		return rctx.safe.String(), true, nil
+	case rpc.LatestBlockNumber:
+		return rctx.latest.String(), true, nil
+	default:
+		if bn.Int64() > int64(rctx.latest) {
+			return "", false, ErrRewriteBlockOutOfRange
+		}
+	}
+
+	return current, false, nil
+}
+
+func remarshalParams(req *RPCReq, p []interface{}) (RewriteResult, error) {
+	paramsRaw, err := json.Marshal(p)
+	if err != nil {
+		return RewriteOverrideError, err
+	}
+	req.Params = paramsRaw
+	return RewriteOverrideRequest, nil
+}
@@ -0,0 +1,164 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteRequest(t *testing.T) {
+	rctx := RewriteContext{
+		latest: hexutil.Uint64(100),
+		safe:   hexutil.Uint64(90),
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		params         []interface{}
+		expectedResult RewriteResult
+		expectedParams string
+		expectedErr    error
+	}{
+		{
+			"eth_getBlockByNumber latest",
+			"eth_getBlockByNumber",
+			[]interface{}{"latest", false},
+			RewriteOverrideRequest,
+			`["0x64",false]`,
+			nil,
+		},
+		{
+			"eth_getBlockByNumber safe",
+			"eth_getBlockByNumber",
+			[]interface{}{"safe", true},
+			RewriteOverrideRequest,
+			`["0x5a",true]`,
+			nil,
+		},
+		{
+			"eth_getBlockByNumber within range",
+			"eth_getBlockByNumber",
+			[]interface{}{"0x10", false},
+			RewriteNone,
+			`["0x10",false]`,
+			nil,
+		},
+		{
+			"eth_getBlockByNumber out of range",
+			"eth_getBlockByNumber",
+			[]interface{}{"0x65", false},
+			RewriteOverrideError,
+			"",
+			ErrRewriteBlockOutOfRange,
+		},
+		{
+			"eth_getBlockByNumber pending",
+			"eth_getBlockByNumber",
+			[]interface{}{"pending", false},
+			RewriteNone,
+			`["pending",false]`,
+			nil,
+		},
+		{
+			"eth_getBalance missing block defaults to latest",
+			"eth_getBalance",
+			[]interface{}{"0x123"},
+			RewriteOverrideRequest,
+			`["0x123","0x64"]`,
+			nil,
+		},
+		{
+			"eth_call with block hash",
+			"eth_call",
+			[]interface{}{map[string]interface{}{"to": "0x123"}, map[string]interface{}{"blockHash": "0xabc"}},
+			RewriteNone,
+			`[{"to":"0x123"},{"blockHash":"0xabc"}]`,
+			nil,
+		},
+		{
+			"eth_getStorageAt latest",
+			"eth_getStorageAt",
+			[]interface{}{"0x123", "0x0", "latest"},
+			RewriteOverrideRequest,
+			`["0x123","0x0","0x64"]`,
+			nil,
+		},
+		{
+			"eth_getLogs without range",
+			"eth_getLogs",
+			[]interface{}{map[string]interface{}{"address": "0x123"}},
+			RewriteOverrideRequest,
+			`[{"address":"0x123","fromBlock":"0x64","toBlock":"0x64"}]`,
+			nil,
+		},
+		{
+			"eth_getLogs with block hash",
+			"eth_getLogs",
+			[]interface{}{map[string]interface{}{"blockHash": "0xabc"}},
+			RewriteNone,
+			`[{"blockHash":"0xabc"}]`,
+			nil,
+		},
+		{
+			"eth_getLogs out of range",
+			"eth_getLogs",
+			[]interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x100"}},
+			RewriteOverrideError,
+			"",
+			ErrRewriteBlockOutOfRange,
+		},
+		{
+			"eth_getBlockRange latest",
+			"eth_getBlockRange",
+			[]interface{}{"0x1", "latest", false},
+			RewriteOverrideRequest,
+			`["0x1","0x64",false]`,
+			nil,
+		},
+		{
+			"eth_chainId is untouched",
+			"eth_chainId",
+			nil,
+			RewriteNone,
+			`null`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &RPCReq{
+				JSONRPC: JSONRPCVersion,
+				Method:  tt.method,
+				Params:  mustMarshalJSON(tt.params),
+				ID:      []byte("1"),
+			}
+			res := &RPCRes{JSONRPC: JSONRPCVersion, ID: req.ID}
+			result, err := RewriteTags(rctx, req, res)
+			require.Equal(t, tt.expectedResult, result)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedParams, string(req.Params))
+		})
+	}
+}
+
+func TestRewriteResponse(t *testing.T) {
+	rctx := RewriteContext{latest: hexutil.Uint64(100)}
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_blockNumber", ID: []byte("1")}
+	res := &RPCRes{JSONRPC: JSONRPCVersion, ID: req.ID}
+
+	result, err := RewriteTags(rctx, req, res)
+	require.NoError(t, err)
+	require.Equal(t, RewriteOverrideResponse, result)
+
+	out, err := json.Marshal(res)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x64","id":1}`, string(out))
+}
@@ -0,0 +1,149 @@
+package proxyd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	RoutingStrategyFallback      = "fallback"
+	RoutingStrategyRoundRobin    = "round_robin"
+	RoutingStrategyWeighted      = "weighted"
+	RoutingStrategyLatency       = "latency"
+	RoutingStrategyLeastInFlight = "least_in_flight"
+
+	defaultBackendWeight = 1
+
+	// latencyExploreRate is the share of requests that try the unmeasured
+	// backends of a latency routed group first.
+	latencyExploreRate = 0.05
+)
+
+// BackendSelector decides in which order the backends of a group are tried.
+// Backends later in the order are only used when the earlier ones are
+// offline, over capacity or fail to answer.
+type BackendSelector interface {
+	Name() string
+	Order(backends []*Backend) []*Backend
+}
+
+func NewBackendSelector(strategy string) (BackendSelector, error) {
+	switch strategy {
+	case "", RoutingStrategyFallback:
+		return &fallbackSelector{}, nil
+	case RoutingStrategyRoundRobin:
+		return &roundRobinSelector{}, nil
+	case RoutingStrategyWeighted:
+		return &weightedSelector{}, nil
+	case RoutingStrategyLatency:
+		return &latencySelector{}, nil
+	case RoutingStrategyLeastInFlight:
+		return &leastInFlightSelector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown routing strategy %s", strategy)
+	}
+}
+
+// fallbackSelector always tries the backends in the configured order.
+type fallbackSelector struct{}
+
+func (s *fallbackSelector) Name() string {
+	return RoutingStrategyFallback
+}
+
+func (s *fallbackSelector) Order(backends []*Backend) []*Backend {
+	return backends
+}
+
+// roundRobinSelector rotates the first backend to try on every request.
+type roundRobinSelector struct {
+	next uint64
+}
+
+func (s *roundRobinSelector) Name() string {
+	return RoutingStrategyRoundRobin
+}
+
+func (s *roundRobinSelector) Order(backends []*Backend) []*Backend {
+	if len(backends) == 0 {
+		return backends
+	}
+	start := int(atomic.AddUint64(&s.next, 1)-1) % len(backends)
+	out := make([]*Backend, 0, len(backends))
+	out = append(out, backends[start:]...)
+	return append(out, backends[:start]...)
+}
+
+// weightedSelector picks backends at random, proportionally to their weight,
+// using the Efraimidis-Spirakis weighted shuffle.
+type weightedSelector struct{}
+
+func (s *weightedSelector) Name() string {
+	return RoutingStrategyWeighted
+}
+
+func (s *weightedSelector) Order(backends []*Backend) []*Backend {
+	keys := make(map[*Backend]float64, len(backends))
+	for _, be := range backends {
+		keys[be] = math.Pow(rand.Float64(), 1/float64(be.weight))
+	}
+	out := make([]*Backend, len(backends))
+	copy(out, backends)
+	sort.SliceStable(out, func(i, j int) bool {
+		return keys[out[i]] > keys[out[j]]
+	})
+	return out
+}
+
+// latencySelector prefers the backend with the lowest EWMA response time.
+// Backends without any samples yet are tried first on a small share of the
+// requests so that they get measured, and last otherwise.
+type latencySelector struct{}
+
+func (s *latencySelector) Name() string {
+	return RoutingStrategyLatency
+}
+
+func (s *latencySelector) Order(backends []*Backend) []*Backend {
+	unmeasured := math.Inf(1)
+	if rand.Float64() < latencyExploreRate {
+		unmeasured = math.Inf(-1)
+	}
+	latencies := make(map[*Backend]float64, len(backends))
+	for _, be := range backends {
+		latency := be.LatencyEWMA()
+		if latency <= 0 {
+			latency = unmeasured
+		}
+		latencies[be] = latency
+	}
+	out := make([]*Backend, len(backends))
+	copy(out, backends)
+	sort.SliceStable(out, func(i, j int) bool {
+		return latencies[out[i]] < latencies[out[j]]
+	})
+	return out
+}
+
+// leastInFlightSelector prefers the backend with the fewest outstanding requests.
+type leastInFlightSelector struct{}
+
+func (s *leastInFlightSelector) Name() string {
+	return RoutingStrategyLeastInFlight
+}
+
+func (s *leastInFlightSelector) Order(backends []*Backend) []*Backend {
+	inFlight := make(map[*Backend]int64, len(backends))
+	for _, be := range backends {
+		inFlight[be] = be.InFlight()
+	}
+	out := make([]*Backend, len(backends))
+	copy(out, backends)
+	sort.SliceStable(out, func(i, j int) bool {
+		return inFlight[out[i]] < inFlight[out[j]]
+	})
+	return out
+}
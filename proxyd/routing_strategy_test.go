@@ -0,0 +1,129 @@
+package proxyd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBackends(names ...string) []*Backend {
+	backends := make([]*Backend, len(names))
+	for i, name := range names {
+		backends[i] = &Backend{Name: name, weight: defaultBackendWeight}
+	}
+	return backends
+}
+
+func backendNames(backends []*Backend) []string {
+	out := make([]string, len(backends))
+	for i, be := range backends {
+		out[i] = be.Name
+	}
+	return out
+}
+
+func TestNewBackendSelector(t *testing.T) {
+	for _, strategy := range []string{
+		RoutingStrategyFallback,
+		RoutingStrategyRoundRobin,
+		RoutingStrategyWeighted,
+		RoutingStrategyLatency,
+		RoutingStrategyLeastInFlight,
+	} {
+		selector, err := NewBackendSelector(strategy)
+		require.NoError(t, err)
+		require.Equal(t, strategy, selector.Name())
+	}
+
+	selector, err := NewBackendSelector("")
+	require.NoError(t, err)
+	require.Equal(t, RoutingStrategyFallback, selector.Name())
+
+	_, err = NewBackendSelector("random")
+	require.Error(t, err)
+}
+
+func TestRoundRobinSelector(t *testing.T) {
+	backends := newTestBackends("a", "b", "c")
+	selector := &roundRobinSelector{}
+
+	require.Equal(t, []string{"a", "b", "c"}, backendNames(selector.Order(backends)))
+	require.Equal(t, []string{"b", "c", "a"}, backendNames(selector.Order(backends)))
+	require.Equal(t, []string{"c", "a", "b"}, backendNames(selector.Order(backends)))
+	require.Equal(t, []string{"a", "b", "c"}, backendNames(selector.Order(backends)))
+	require.Empty(t, selector.Order(nil))
+}
+
+func TestWeightedSelector(t *testing.T) {
+	backends := newTestBackends("heavy", "light")
+	backends[0].weight = 9
+	backends[1].weight = 1
+	selector := &weightedSelector{}
+
+	const rounds = 10000
+	first := make(map[string]int)
+	for i := 0; i < rounds; i++ {
+		order := selector.Order(backends)
+		require.Len(t, order, 2)
+		first[order[0].Name]++
+	}
+	require.InDelta(t, 0.9, float64(first["heavy"])/rounds, 0.03)
+	require.InDelta(t, 0.1, float64(first["light"])/rounds, 0.03)
+}
+
+func TestLatencySelector(t *testing.T) {
+	backends := newTestBackends("slow", "fast", "unmeasured")
+	backends[0].observeLatency(300 * time.Millisecond)
+	backends[1].observeLatency(100 * time.Millisecond)
+	selector := &latencySelector{}
+
+	const rounds = 10000
+	orders := make(map[string]int)
+	for i := 0; i < rounds; i++ {
+		orders[strings.Join(backendNames(selector.Order(backends)), ",")]++
+	}
+	require.Len(t, orders, 2)
+	require.InDelta(t, 1-latencyExploreRate, float64(orders["fast,slow,unmeasured"])/rounds, 0.01)
+	require.InDelta(t, latencyExploreRate, float64(orders["unmeasured,fast,slow"])/rounds, 0.01)
+
+	// the fast backend degrades and is moved behind the slow one
+	backends = backends[:2]
+	for i := 0; i < 20; i++ {
+		backends[1].observeLatency(time.Second)
+	}
+	require.Equal(t, []string{"slow", "fast"}, backendNames(selector.Order(backends)))
+}
+
+func TestLatencySelectorPenalizesFailures(t *testing.T) {
+	backends := newTestBackends("slow", "failing")
+	for _, be := range backends {
+		be.client = &LimitedHTTPClient{Client: http.Client{Timeout: 5 * time.Second}}
+	}
+	backends[0].observeLatency(300 * time.Millisecond)
+	backends[1].observeLatency(100 * time.Millisecond)
+	selector := &latencySelector{}
+
+	// failing fast counts as the whole response timeout
+	backends[1].observeFailureLatency(context.Background(), 10*time.Millisecond)
+	require.Equal(t, []string{"slow", "failing"}, backendNames(selector.Order(backends)))
+	require.InDelta(t, 0.2*5+0.8*0.1, backends[1].LatencyEWMA(), 1e-9)
+
+	// the response timeout of the route is used as the penalty when set
+	ctx := context.WithValue(context.Background(), ContextKeyResponseTimeout, 10*time.Second)
+	backends[0].observeFailureLatency(ctx, 10*time.Millisecond)
+	require.InDelta(t, 0.2*10+0.8*0.3, backends[0].LatencyEWMA(), 1e-9)
+}
+
+func TestLeastInFlightSelector(t *testing.T) {
+	backends := newTestBackends("a", "b", "c")
+	backends[0].inFlight = 3
+	backends[1].inFlight = 1
+	backends[2].inFlight = 1
+	selector := &leastInFlightSelector{}
+
+	require.Equal(t, []string{"b", "c", "a"}, backendNames(selector.Order(backends)))
+}
@@ -0,0 +1,373 @@
+package proxyd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/sethvargo/go-limiter"
+	"github.com/sethvargo/go-limiter/memorystore"
+	"github.com/sethvargo/go-limiter/noopstore"
+	"golang.org/x/sync/semaphore"
+)
+
+// runtimeConfig holds everything that is rebuilt when the config is
+// reloaded. The server reads it once per request, so requests and websocket
+// connections in flight keep using the backends they started with.
+type runtimeConfig struct {
+	config *Config
+
+	backends            map[string]*Backend
+	backendGroups       map[string]*BackendGroup
+	wsBackendGroup      *BackendGroup
+	wsMethodWhitelist   *StringSet
+	router              *MethodRouter
+	authenticatedPaths  map[string]string
+	lim                 limiter.Store
+	limExemptOrigins    map[string]bool
+	limExemptUserAgents map[string]bool
+}
+
+// buildRuntimeConfig builds the reloadable part of the config. Backends and
+// groups whose config did not change are carried over from prev, so that
+// their health, drain state and consensus are kept across reloads.
+func buildRuntimeConfig(config *Config, lim BackendRateLimiter, sem *semaphore.Weighted, prev *runtimeConfig) (*runtimeConfig, error) {
+	if len(config.Backends) == 0 {
+		return nil, errors.New("must define at least one backend")
+	}
+	if len(config.BackendGroups) == 0 {
+		return nil, errors.New("must define at least one backend group")
+	}
+	if len(config.RPCMethodMappings) == 0 && len(config.RPCMethodRoutes) == 0 {
+		return nil, errors.New("must define at least one RPC method mapping or route")
+	}
+
+	for authKey := range config.Authentication {
+		if authKey == "none" {
+			return nil, errors.New("cannot use none as an auth key")
+		}
+	}
+
+	rc := &runtimeConfig{
+		config:            config,
+		backends:          make(map[string]*Backend),
+		backendGroups:     make(map[string]*BackendGroup),
+		wsMethodWhitelist: NewStringSetFromStrings(config.WSMethodWhitelist),
+	}
+
+	for name, cfg := range config.Backends {
+		if prev != nil && prev.backends[name] != nil &&
+			reflect.DeepEqual(prev.config.Backends[name], cfg) &&
+			prev.config.BackendOptions == config.BackendOptions {
+			rc.backends[name] = prev.backends[name]
+			continue
+		}
+		back, err := newBackendFromConfig(name, cfg, config.BackendOptions, lim, sem)
+		if err != nil {
+			return nil, err
+		}
+		// draining is set through the admin API, not the config, so it
+		// outlives the rebuild
+		if prev != nil && prev.backends[name] != nil && prev.backends[name].Draining() {
+			back.Drain()
+		}
+		rc.backends[name] = back
+		RecordBackendWeight(back)
+		log.Info("configured backend", "name", name, "rpc_url", back.rpcURL, "ws_url", back.wsURL)
+	}
+
+	for bgName, bg := range config.BackendGroups {
+		backends := make([]*Backend, 0)
+		for _, bName := range bg.Backends {
+			if rc.backends[bName] == nil {
+				return nil, fmt.Errorf("backend %s is not defined", bName)
+			}
+			backends = append(backends, rc.backends[bName])
+		}
+		shadows := make([]*Backend, 0)
+		for _, bName := range bg.ShadowBackends {
+			if rc.backends[bName] == nil {
+				return nil, fmt.Errorf("shadow backend %s is not defined", bName)
+			}
+			shadows = append(shadows, rc.backends[bName])
+		}
+		if prev != nil && prev.backendGroups[bgName] != nil &&
+			reflect.DeepEqual(prev.config.BackendGroups[bgName], bg) &&
+			sameBackends(prev.backendGroups[bgName].Backends, backends) &&
+			sameBackends(prev.backendGroups[bgName].shadowBackends(), shadows) {
+			rc.backendGroups[bgName] = prev.backendGroups[bgName]
+			continue
+		}
+		selector, err := NewBackendSelector(bg.RoutingStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("backend group %s: %w", bgName, err)
+		}
+		group := &BackendGroup{
+			Name:     bgName,
+			Backends: backends,
+			Selector: selector,
+		}
+		RecordBackendGroupRoutingStrategy(group, selector.Name())
+		if bg.ConsensusAware {
+			group.Consensus = NewConsensusPoller(group, consensusOptsFromConfig(bg)...)
+			log.Info("configured consensus aware backend group", "name", bgName)
+		}
+		if len(shadows) > 0 {
+			if bg.ShadowSampleRate < 0 || bg.ShadowSampleRate > 1 {
+				return nil, fmt.Errorf("shadow sample rate of backend group %s must be between 0 and 1", bgName)
+			}
+			maxInFlight := bg.ShadowMaxInFlight
+			if maxInFlight == 0 {
+				maxInFlight = defaultShadowMaxInFlight
+			}
+			clients := make(map[*Backend]*Backend, len(shadows))
+			for _, be := range shadows {
+				client, err := newShadowClient(be.Name, config.Backends[be.Name], config.BackendOptions, lim, maxInFlight)
+				if err != nil {
+					return nil, err
+				}
+				clients[be] = client
+			}
+			opts := append(shadowOptsFromConfig(bg), withShadowClients(clients))
+			group.Shadow = NewShadowMirror(bgName, shadows, opts...)
+			log.Info("configured shadow backends", "name", bgName, "shadow_backends", strings.Join(bg.ShadowBackends, ", "))
+		}
+		rc.backendGroups[bgName] = group
+	}
+
+	if config.WSBackendGroup != "" {
+		rc.wsBackendGroup = rc.backendGroups[config.WSBackendGroup]
+		if rc.wsBackendGroup == nil {
+			return nil, fmt.Errorf("ws backend group %s does not exist", config.WSBackendGroup)
+		}
+	}
+
+	if rc.wsBackendGroup == nil && config.Server.WSPort != 0 {
+		return nil, fmt.Errorf("a ws port was defined, but no ws group was defined")
+	}
+
+	router, err := NewMethodRouter(config.RPCMethodMappings, config.RPCMethodRoutes, rc.backendGroups)
+	if err != nil {
+		return nil, err
+	}
+	rc.router = router
+
+	if config.Authentication != nil {
+		rc.authenticatedPaths = make(map[string]string)
+		for secret, alias := range config.Authentication {
+			resolvedSecret, err := ReadFromEnvOrConfig(secret)
+			if err != nil {
+				return nil, err
+			}
+			rc.authenticatedPaths[resolvedSecret] = alias
+		}
+	}
+
+	if len(config.APIKeys) > 0 {
+		aliases := make(map[string]bool, len(rc.authenticatedPaths))
+		for _, alias := range rc.authenticatedPaths {
+			aliases[alias] = true
+		}
+		for alias := range config.APIKeys {
+			if !aliases[alias] {
+				return nil, fmt.Errorf("api key policy %s does not match any authentication alias", alias)
+			}
+		}
+	}
+
+	if prev != nil && reflect.DeepEqual(prev.config.RateLimit, config.RateLimit) {
+		rc.lim = prev.lim
+		rc.limExemptOrigins = prev.limExemptOrigins
+		rc.limExemptUserAgents = prev.limExemptUserAgents
+	} else {
+		rc.lim, rc.limExemptOrigins, rc.limExemptUserAgents, err = newFrontendRateLimiter(config.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rc, nil
+}
+
+func sameBackends(a, b []*Backend) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func newBackendFromConfig(name string, cfg *BackendConfig, backendOpts BackendOptions, lim BackendRateLimiter, sem *semaphore.Weighted) (*Backend, error) {
+	opts := make([]BackendOpt, 0)
+
+	rpcURL, err := ReadFromEnvOrConfig(cfg.RPCURL)
+	if err != nil {
+		return nil, err
+	}
+	wsURL, err := ReadFromEnvOrConfig(cfg.WSURL)
+	if err != nil {
+		return nil, err
+	}
+	if rpcURL == "" {
+		return nil, fmt.Errorf("must define an RPC URL for backend %s", name)
+	}
+	if wsURL == "" {
+		return nil, fmt.Errorf("must define a WS URL for backend %s", name)
+	}
+
+	if backendOpts.ResponseTimeoutSeconds != 0 {
+		timeout := secondsToDuration(backendOpts.ResponseTimeoutSeconds)
+		opts = append(opts, WithTimeout(timeout))
+	}
+	if backendOpts.MaxRetries != 0 {
+		opts = append(opts, WithMaxRetries(backendOpts.MaxRetries))
+	}
+	if backendOpts.MaxResponseSizeBytes != 0 {
+		opts = append(opts, WithMaxResponseSize(backendOpts.MaxResponseSizeBytes))
+	}
+	if backendOpts.OutOfServiceSeconds != 0 {
+		opts = append(opts, WithOutOfServiceDuration(secondsToDuration(backendOpts.OutOfServiceSeconds)))
+	}
+	if cfg.MaxRPS != 0 {
+		opts = append(opts, WithMaxRPS(cfg.MaxRPS))
+	}
+	if cfg.MaxWSConns != 0 {
+		opts = append(opts, WithMaxWSConns(cfg.MaxWSConns))
+	}
+	if cfg.Password != "" {
+		passwordVal, err := ReadFromEnvOrConfig(cfg.Password)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithBasicAuth(cfg.Username, passwordVal))
+	}
+	tlsConfig, err := configureBackendTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		log.Info("using custom TLS config for backend", "name", name)
+		opts = append(opts, WithTLSConfig(tlsConfig))
+	}
+	if cfg.StripTrailingXFF {
+		opts = append(opts, WithStrippedTrailingXFF())
+	}
+	if cfg.Weight < 0 {
+		return nil, fmt.Errorf("weight of backend %s must not be negative", name)
+	}
+	if cfg.Weight != 0 {
+		opts = append(opts, WithWeight(cfg.Weight))
+	}
+	opts = append(opts, WithProxydIP(os.Getenv("PROXYD_IP")))
+	return NewBackend(name, rpcURL, wsURL, lim, sem, opts...), nil
+}
+
+func newFrontendRateLimiter(cfg RateLimitConfig) (limiter.Store, map[string]bool, map[string]bool, error) {
+	limExemptOrigins := make(map[string]bool)
+	limExemptUserAgents := make(map[string]bool)
+	if cfg.RatePerSecond <= 0 {
+		lim, _ := noopstore.New()
+		return lim, limExemptOrigins, limExemptUserAgents, nil
+	}
+
+	lim, err := memorystore.New(&memorystore.Config{
+		Tokens:   uint64(cfg.RatePerSecond),
+		Interval: time.Second,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, origin := range cfg.ExemptOrigins {
+		limExemptOrigins[strings.ToLower(origin)] = true
+	}
+	for _, agent := range cfg.ExemptUserAgents {
+		limExemptUserAgents[strings.ToLower(agent)] = true
+	}
+	return lim, limExemptOrigins, limExemptUserAgents, nil
+}
+
+// startConsensus starts the consensus pollers of the groups that are not
+// carried over from prev.
+func (rc *runtimeConfig) startConsensus(prev *runtimeConfig) {
+	for _, bg := range rc.backendGroups {
+		if bg.Consensus != nil && !prev.hasBackendGroup(bg) {
+			bg.Consensus.Start()
+		}
+	}
+}
+
+// stopConsensus stops the consensus pollers of the groups that are not
+// carried over to next.
+func (rc *runtimeConfig) stopConsensus(next *runtimeConfig) {
+	for _, bg := range rc.backendGroups {
+		if bg.Consensus != nil && !next.hasBackendGroup(bg) {
+			bg.Consensus.Stop()
+		}
+	}
+}
+
+// releaseRateLimiter closes the frontend rate limiter if next replaced it.
+// It is closed after the grace period so that requests in flight can still
+// take from it.
+func (rc *runtimeConfig) releaseRateLimiter(next *runtimeConfig, grace time.Duration) {
+	if rc.lim == next.lim {
+		return
+	}
+	lim := rc.lim
+	time.AfterFunc(grace, func() {
+		_ = lim.Close(context.Background())
+	})
+}
+
+func (rc *runtimeConfig) hasBackendGroup(bg *BackendGroup) bool {
+	if rc == nil {
+		return false
+	}
+	return rc.backendGroups[bg.Name] == bg
+}
+
+func (rc *runtimeConfig) backendNames() []string {
+	names := make([]string, 0, len(rc.backends))
+	for name := range rc.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restartRequiredChanges lists the config sections that changed but are
+// only read when proxyd starts.
+func restartRequiredChanges(prev, next *Config) []string {
+	sections := []struct {
+		name       string
+		prev, next interface{}
+	}{
+		{"server", prev.Server, next.Server},
+		{"cache", prev.Cache, next.Cache},
+		{"redis", prev.Redis, next.Redis},
+		{"metrics", prev.Metrics, next.Metrics},
+		{"tx_validation", prev.TxValidation, next.TxValidation},
+		{"get_logs", prev.GetLogs, next.GetLogs},
+		{"admin", prev.Admin, next.Admin},
+		{"reload", prev.Reload, next.Reload},
+		{"ws_multiplex_subscriptions", prev.WSMultiplexSubscriptions, next.WSMultiplexSubscriptions},
+		{"ws_max_gap_fill_blocks", prev.WSMaxGapFillBlocks, next.WSMaxGapFillBlocks},
+	}
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.prev, section.next) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,105 @@
+package proxyd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func testRuntimeConfig() *Config {
+	return &Config{
+		Backends: BackendsConfig{
+			"a": {RPCURL: "http://a", WSURL: "ws://a"},
+			"b": {RPCURL: "http://b", WSURL: "ws://b"},
+		},
+		BackendGroups: BackendGroupsConfig{
+			"first":  {Backends: []string{"a"}},
+			"second": {Backends: []string{"b"}},
+		},
+		RPCMethodMappings: map[string]string{
+			"eth_chainId": "first",
+		},
+	}
+}
+
+func TestBuildRuntimeConfigReuse(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	sem := semaphore.NewWeighted(100)
+
+	prev, err := buildRuntimeConfig(testRuntimeConfig(), lim, sem, nil)
+	require.NoError(t, err)
+
+	config := testRuntimeConfig()
+	config.Backends["b"].MaxRPS = 10
+	config.RPCMethodMappings["eth_chainId"] = "second"
+	next, err := buildRuntimeConfig(config, lim, sem, prev)
+	require.NoError(t, err)
+
+	require.Same(t, prev.backends["a"], next.backends["a"])
+	require.NotSame(t, prev.backends["b"], next.backends["b"])
+	require.Same(t, prev.backendGroups["first"], next.backendGroups["first"])
+	require.NotSame(t, prev.backendGroups["second"], next.backendGroups["second"])
+	require.Same(t, prev.lim, next.lim)
+	require.Same(t, next.backendGroups["second"], next.router.Route("eth_chainId").BackendGroups[0])
+
+	// drained backends stay drained when they are rebuilt
+	next.backends["b"].Drain()
+	config = testRuntimeConfig()
+	config.Backends["b"].MaxRPS = 20
+	drained, err := buildRuntimeConfig(config, lim, sem, next)
+	require.NoError(t, err)
+	require.NotSame(t, next.backends["b"], drained.backends["b"])
+	require.True(t, drained.backends["b"].Draining())
+	require.False(t, drained.backends["a"].Draining())
+
+	config = testRuntimeConfig()
+	config.BackendOptions.MaxRetries = 5
+	next, err = buildRuntimeConfig(config, lim, sem, prev)
+	require.NoError(t, err)
+	require.NotSame(t, prev.backends["a"], next.backends["a"])
+	require.NotSame(t, prev.backendGroups["first"], next.backendGroups["first"])
+}
+
+func TestBuildRuntimeConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(config *Config)
+		err    string
+	}{
+		{"undefined backend", func(config *Config) {
+			config.BackendGroups["first"].Backends = []string{"c"}
+		}, "backend c is not defined"},
+		{"undefined group", func(config *Config) {
+			config.RPCMethodMappings["eth_call"] = "third"
+		}, "undefined backend group third"},
+		{"none auth key", func(config *Config) {
+			config.Authentication = map[string]string{"none": "alias"}
+		}, "cannot use none as an auth key"},
+		{"policy without alias", func(config *Config) {
+			config.Authentication = map[string]string{"secret": "alias"}
+			config.APIKeys = APIKeysConfig{"other": {}}
+		}, "api key policy other does not match any authentication alias"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testRuntimeConfig()
+			tt.modify(config)
+			_, err := buildRuntimeConfig(config, NewLocalBackendRateLimiter(), semaphore.NewWeighted(100), nil)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestRestartRequiredChanges(t *testing.T) {
+	prev := testRuntimeConfig()
+	next := testRuntimeConfig()
+	next.Backends["a"].MaxRPS = 10
+	next.RateLimit.RatePerSecond = 5
+	require.Empty(t, restartRequiredChanges(prev, next))
+
+	next.Server.RPCPort = 9000
+	next.Cache.Enabled = true
+	require.Equal(t, []string{"server", "cache"}, restartRequiredChanges(prev, next))
+}
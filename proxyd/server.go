@@ -14,21 +14,19 @@ import (
 	"sync"
 	"time"
 
-	"github.com/sethvargo/go-limiter"
-	"github.com/sethvargo/go-limiter/memorystore"
-	"github.com/sethvargo/go-limiter/noopstore"
-
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/cors"
+	"golang.org/x/sync/semaphore"
 )
 
 const (
 	ContextKeyAuth              = "authorization"
 	ContextKeyReqID             = "req_id"
 	ContextKeyXForwardedFor     = "x_forwarded_for"
+	ContextKeyResponseTimeout   = "response_timeout"
 	MaxBatchRPCCalls            = 100
 	cacheStatusHdr              = "X-Proxyd-Cache-Status"
 	defaultServerTimeout        = time.Second * 10
@@ -39,41 +37,45 @@ const (
 var emptyArrayResponse = json.RawMessage("[]")
 
 type Server struct {
-	backendGroups        map[string]*BackendGroup
-	wsBackendGroup       *BackendGroup
-	wsMethodWhitelist    *StringSet
-	rpcMethodMappings    map[string]string
+	wsSubscriptions      *SubscriptionManager
 	maxBodySize          int64
 	enableRequestLog     bool
 	maxRequestBodyLogLen int
-	authenticatedPaths   map[string]string
+	apiKeyPolicies       *APIKeyPolicies
+	txGate               *TxGate
+	getLogs              *GetLogsHandler
+	adminToken           string
 	timeout              time.Duration
 	maxUpstreamBatchSize int
 	upgrader             *websocket.Upgrader
-	lim                  limiter.Store
-	limConfig            RateLimitConfig
-	limExemptOrigins     map[string]bool
-	limExemptUserAgents  map[string]bool
 	rpcServer            *http.Server
 	wsServer             *http.Server
 	cache                RPCCache
 	srvMu                sync.Mutex
+
+	backendRateLimiter BackendRateLimiter
+	rpcSemaphore       *semaphore.Weighted
+	reloadMu           sync.Mutex
+	runtimeMu          sync.RWMutex
+	rc                 *runtimeConfig
 }
 
 func NewServer(
-	backendGroups map[string]*BackendGroup,
-	wsBackendGroup *BackendGroup,
-	wsMethodWhitelist *StringSet,
-	rpcMethodMappings map[string]string,
+	rc *runtimeConfig,
+	backendRateLimiter BackendRateLimiter,
+	rpcSemaphore *semaphore.Weighted,
+	wsSubscriptions *SubscriptionManager,
 	maxBodySize int64,
-	authenticatedPaths map[string]string,
 	timeout time.Duration,
 	maxUpstreamBatchSize int,
 	cache RPCCache,
-	rateLimitConfig RateLimitConfig,
 	enableRequestLog bool,
 	maxRequestBodyLogLen int,
-) (*Server, error) {
+	apiKeyPolicies *APIKeyPolicies,
+	txGate *TxGate,
+	getLogs *GetLogsHandler,
+	adminToken string,
+) *Server {
 	if cache == nil {
 		cache = &NoopRPCCache{}
 	}
@@ -90,36 +92,16 @@ func NewServer(
 		maxUpstreamBatchSize = defaultMaxUpstreamBatchSize
 	}
 
-	var lim limiter.Store
-	limExemptOrigins := make(map[string]bool)
-	limExemptUserAgents := make(map[string]bool)
-	if rateLimitConfig.RatePerSecond > 0 {
-		var err error
-		lim, err = memorystore.New(&memorystore.Config{
-			Tokens:   uint64(rateLimitConfig.RatePerSecond),
-			Interval: time.Second,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		for _, origin := range rateLimitConfig.ExemptOrigins {
-			limExemptOrigins[strings.ToLower(origin)] = true
-		}
-		for _, agent := range rateLimitConfig.ExemptUserAgents {
-			limExemptUserAgents[strings.ToLower(agent)] = true
-		}
-	} else {
-		lim, _ = noopstore.New()
-	}
-
 	return &Server{
-		backendGroups:        backendGroups,
-		wsBackendGroup:       wsBackendGroup,
-		wsMethodWhitelist:    wsMethodWhitelist,
-		rpcMethodMappings:    rpcMethodMappings,
+		rc:                   rc,
+		backendRateLimiter:   backendRateLimiter,
+		rpcSemaphore:         rpcSemaphore,
+		wsSubscriptions:      wsSubscriptions,
 		maxBodySize:          maxBodySize,
-		authenticatedPaths:   authenticatedPaths,
+		apiKeyPolicies:       apiKeyPolicies,
+		txGate:               txGate,
+		getLogs:              getLogs,
+		adminToken:           adminToken,
 		timeout:              timeout,
 		maxUpstreamBatchSize: maxUpstreamBatchSize,
 		cache:                cache,
@@ -128,17 +110,74 @@ func NewServer(
 		upgrader: &websocket.Upgrader{
 			HandshakeTimeout: 5 * time.Second,
 		},
-		lim:                 lim,
-		limConfig:           rateLimitConfig,
-		limExemptOrigins:    limExemptOrigins,
-		limExemptUserAgents: limExemptUserAgents,
-	}, nil
+	}
+}
+
+func (s *Server) runtime() *runtimeConfig {
+	s.runtimeMu.RLock()
+	defer s.runtimeMu.RUnlock()
+	return s.rc
+}
+
+// Reload swaps in the backends, backend groups, method mappings,
+// authentication keys, API key policies and rate limits of the config.
+// Requests and websocket connections in flight are not interrupted. Other
+// sections are only read on startup.
+func (s *Server) Reload(config *Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	err := s.reload(config)
+	RecordConfigReload(err)
+	return err
+}
+
+func (s *Server) reload(config *Config) error {
+	prev := s.runtime()
+	if s.wsSubscriptions != nil && config.WSBackendGroup != prev.config.WSBackendGroup {
+		return errors.New("cannot change the ws backend group while subscriptions are multiplexed")
+	}
+
+	next, err := buildRuntimeConfig(config, s.backendRateLimiter, s.rpcSemaphore, prev)
+	if err != nil {
+		return err
+	}
+	if changed := restartRequiredChanges(prev.config, config); len(changed) > 0 {
+		log.Warn("config changes require a restart to take effect", "sections", strings.Join(changed, ", "))
+	}
+
+	next.startConsensus(prev)
+	s.apiKeyPolicies.Update(config.APIKeys)
+	s.runtimeMu.Lock()
+	s.rc = next
+	s.runtimeMu.Unlock()
+	if s.wsSubscriptions != nil {
+		s.wsSubscriptions.SetBackendGroup(next.wsBackendGroup)
+	}
+	prev.stopConsensus(next)
+	prev.releaseRateLimiter(next, s.timeout)
+
+	log.Info(
+		"reloaded config",
+		"backends", strings.Join(next.backendNames(), ", "),
+		"backend_groups", len(next.backendGroups),
+		"rpc_method_routes", next.router.Len(),
+	)
+	return nil
 }
 
 func (s *Server) RPCListenAndServe(host string, port int) error {
 	s.srvMu.Lock()
 	hdlr := mux.NewRouter()
 	hdlr.HandleFunc("/healthz", s.HandleHealthz).Methods("GET")
+	if s.adminToken != "" {
+		admin := hdlr.PathPrefix("/admin").Subrouter()
+		admin.Use(s.adminAuthMiddleware)
+		admin.HandleFunc("/api_keys", s.HandleAPIKeyUsage).Methods("GET")
+		admin.HandleFunc("/backends", s.HandleBackends).Methods("GET")
+		admin.HandleFunc("/backends/{name}", s.HandleBackend).Methods("GET")
+		admin.HandleFunc("/backends/{name}/drain", s.HandleDrainBackend).Methods("POST")
+		admin.HandleFunc("/backends/{name}/undrain", s.HandleUndrainBackend).Methods("POST")
+	}
 	hdlr.HandleFunc("/", s.HandleRPC).Methods("POST")
 	hdlr.HandleFunc("/{authorization}", s.HandleRPC).Methods("POST")
 	c := cors.New(cors.Options{
@@ -188,16 +227,19 @@ func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
-	ctx := s.populateContext(w, r)
+	rc := s.runtime()
+	ctx := s.populateContext(rc, w, r)
 	if ctx == nil {
 		return
 	}
+	// The server timeout is applied to each route once the requests are
+	// parsed, routes with a longer timeout extend it.
 	var cancel context.CancelFunc
-	ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	ctx, cancel = context.WithTimeout(ctx, rc.router.RequestTimeout(s.timeout))
 	defer cancel()
 
-	exemptOrigin := s.limExemptOrigins[strings.ToLower(r.Header.Get("Origin"))]
-	exemptUserAgent := s.limExemptUserAgents[strings.ToLower(r.Header.Get("User-Agent"))]
+	exemptOrigin := rc.limExemptOrigins[strings.ToLower(r.Header.Get("Origin"))]
+	exemptUserAgent := rc.limExemptUserAgents[strings.ToLower(r.Header.Get("User-Agent"))]
 	var ok bool
 	if exemptOrigin || exemptUserAgent {
 		ok = true
@@ -208,12 +250,12 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 			log.Warn("rejecting request without XFF or remote IP")
 			ok = false
 		} else {
-			_, _, _, ok, _ = s.lim.Take(ctx, xff)
+			_, _, _, ok, _ = rc.lim.Take(ctx, xff)
 		}
 	}
 	if !ok {
 		rpcErr := ErrOverRateLimit.Clone()
-		rpcErr.Message = s.limConfig.ErrorMessage
+		rpcErr.Message = rc.config.RateLimit.ErrorMessage
 		writeRPCError(ctx, w, nil, rpcErr)
 		return
 	}
@@ -261,7 +303,7 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		batchRes, batchContainsCached, err := s.handleBatchRPC(ctx, reqs, true)
+		batchRes, batchContainsCached, err := s.handleBatchRPC(ctx, rc, reqs, true)
 		if err == context.DeadlineExceeded {
 			writeRPCError(ctx, w, nil, ErrGatewayTimeout)
 			return
@@ -277,7 +319,7 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rawBody := json.RawMessage(body)
-	backendRes, cached, err := s.handleBatchRPC(ctx, []json.RawMessage{rawBody}, false)
+	backendRes, cached, err := s.handleBatchRPC(ctx, rc, []json.RawMessage{rawBody}, false)
 	if err != nil {
 		writeRPCError(ctx, w, nil, ErrInternal)
 		return
@@ -286,7 +328,7 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 	writeRPCRes(ctx, w, backendRes[0])
 }
 
-func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isBatch bool) ([]*RPCRes, bool, error) {
+func (s *Server) handleBatchRPC(ctx context.Context, rc *runtimeConfig, reqs []json.RawMessage, isBatch bool) ([]*RPCRes, bool, error) {
 	// A request set is transformed into groups of batches.
 	// Each batch group maps to a forwarded JSON-RPC batch request (subject to maxUpstreamBatchSize constraints)
 	// A groupID is used to decouple Requests that have duplicate ID so they're not part of the same batch that's
@@ -294,12 +336,13 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 	// as the backend MAY return Responses out of order.
 	// NOTE: Duplicate request ids induces 1-sized JSON-RPC batches
 	type batchGroup struct {
-		groupID      int
-		backendGroup string
+		groupID  int
+		batchKey string
 	}
 
 	responses := make([]*RPCRes, len(reqs))
 	batches := make(map[batchGroup][]batchElem)
+	routes := make(map[string]*MethodRoute)
 	ids := make(map[string]int, len(reqs))
 
 	for i := range reqs {
@@ -322,8 +365,8 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			continue
 		}
 
-		group := s.rpcMethodMappings[parsedReq.Method]
-		if group == "" {
+		route := rc.router.Route(parsedReq.Method)
+		if route == nil {
 			// use unknown below to prevent DOS vector that fills up memory
 			// with arbitrary method names.
 			log.Info(
@@ -337,11 +380,49 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			continue
 		}
 
+		if err := s.apiKeyPolicies.Check(ctx, parsedReq.Method); err != nil {
+			log.Info(
+				"blocked request by api key policy",
+				"source", "rpc",
+				"req_id", GetReqID(ctx),
+				"auth", GetAuthCtx(ctx),
+				"method", parsedReq.Method,
+				"err", err,
+			)
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
+
+		if s.txGate != nil && parsedReq.Method == "eth_sendRawTransaction" {
+			if err := s.txGate.Validate(ctx, parsedReq); err != nil {
+				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+		}
+
+		if s.getLogs != nil && parsedReq.Method == "eth_getLogs" {
+			routeCtx, cancel := context.WithTimeout(ctx, route.RequestTimeout(s.timeout))
+			res, err := s.getLogs.Handle(routeCtx, route, parsedReq)
+			cancel()
+			if err != nil {
+				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			if res != nil {
+				responses[i] = res
+				continue
+			}
+		}
+
 		id := string(parsedReq.ID)
 		// If this is a duplicate Request ID, move the Request to a new batchGroup
 		ids[id]++
 		batchGroupID := ids[id]
-		batchGroup := batchGroup{groupID: batchGroupID, backendGroup: group}
+		batchGroup := batchGroup{groupID: batchGroupID, batchKey: route.batchKey}
+		routes[route.batchKey] = route
 		batches[batchGroup] = append(batches[batchGroup], batchElem{parsedReq, i})
 	}
 
@@ -375,12 +456,16 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			start := i * s.maxUpstreamBatchSize
 			end := int(math.Min(float64(start+s.maxUpstreamBatchSize), float64(len(cacheMisses))))
 			elems := cacheMisses[start:end]
-			res, err := s.backendGroups[group.backendGroup].Forward(ctx, createBatchRequest(elems), isBatch)
+			batchReqs := createBatchRequest(elems)
+			route := routes[group.batchKey]
+			routeCtx, cancel := context.WithTimeout(ctx, route.RequestTimeout(s.timeout))
+			res, err := route.Forward(routeCtx, batchReqs, isBatch)
+			cancel()
 			if err != nil {
 				log.Error(
 					"error forwarding RPC batch",
 					"batch_size", len(elems),
-					"backend_group", group,
+					"route", route.Pattern,
 					"err", err,
 				)
 				res = nil
@@ -392,6 +477,10 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			for i := range elems {
 				responses[elems[i].Index] = res[i]
 
+				if s.txGate != nil && elems[i].Req.Method == "eth_sendRawTransaction" && !res[i].IsError() {
+					s.txGate.Remember(elems[i].Req)
+				}
+
 				// TODO(inphi): batch put these
 				if res[i].Error == nil && res[i].Result != nil {
 					if err := s.cache.PutRPC(ctx, elems[i].Req, res[i]); err != nil {
@@ -410,7 +499,8 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 }
 
 func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
-	ctx := s.populateContext(w, r)
+	rc := s.runtime()
+	ctx := s.populateContext(rc, w, r)
 	if ctx == nil {
 		return
 	}
@@ -423,14 +513,20 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	proxier, err := s.wsBackendGroup.ProxyWS(ctx, clientConn, s.wsMethodWhitelist)
-	if err != nil {
-		if errors.Is(err, ErrNoBackends) {
-			RecordUnserviceableRequest(ctx, RPCRequestSourceWS)
+	var proxier wsProxier
+	if s.wsSubscriptions != nil {
+		proxier = NewMultiplexedWSProxier(rc.wsBackendGroup, s.wsSubscriptions, clientConn, rc.wsMethodWhitelist)
+	} else {
+		backendProxier, err := rc.wsBackendGroup.ProxyWS(ctx, clientConn, rc.wsMethodWhitelist)
+		if err != nil {
+			if errors.Is(err, ErrNoBackends) {
+				RecordUnserviceableRequest(ctx, RPCRequestSourceWS)
+			}
+			log.Error("error dialing ws backend", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx), "err", err)
+			clientConn.Close()
+			return
 		}
-		log.Error("error dialing ws backend", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx), "err", err)
-		clientConn.Close()
-		return
+		proxier = backendProxier
 	}
 
 	activeClientWsConnsGauge.WithLabelValues(GetAuthCtx(ctx)).Inc()
@@ -445,7 +541,7 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 	log.Info("accepted WS connection", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx))
 }
 
-func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context.Context {
+func (s *Server) populateContext(rc *runtimeConfig, w http.ResponseWriter, r *http.Request) context.Context {
 	vars := mux.Vars(r)
 	authorization := vars["authorization"]
 	xff := r.Header.Get("X-Forwarded-For")
@@ -457,7 +553,7 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 	}
 	ctx := context.WithValue(r.Context(), ContextKeyXForwardedFor, xff) // nolint:staticcheck
 
-	if s.authenticatedPaths == nil {
+	if rc.authenticatedPaths == nil {
 		// handle the edge case where auth is disabled
 		// but someone sends in an auth key anyway
 		if authorization != "" {
@@ -467,14 +563,16 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 			return nil
 		}
 	} else {
-		if authorization == "" || s.authenticatedPaths[authorization] == "" {
+		if authorization == "" || rc.authenticatedPaths[authorization] == "" {
 			log.Info("blocked unauthorized request", "authorization", authorization)
 			httpResponseCodesTotal.WithLabelValues("401").Inc()
 			w.WriteHeader(401)
 			return nil
 		}
 
-		ctx = context.WithValue(r.Context(), ContextKeyAuth, s.authenticatedPaths[authorization]) // nolint:staticcheck
+		// keep the client address, authenticated requests are rate limited
+		// and forwarded with it like any other request
+		ctx = context.WithValue(ctx, ContextKeyAuth, rc.authenticatedPaths[authorization]) // nolint:staticcheck
 	}
 
 	return context.WithValue(
@@ -600,6 +698,10 @@ func truncate(str string, maxLen int) string {
 	}
 }
 
+type wsProxier interface {
+	Proxy(ctx context.Context) error
+}
+
 type batchElem struct {
 	Req   *RPCReq
 	Index int
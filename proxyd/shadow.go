@@ -0,0 +1,225 @@
+package proxyd
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	defaultShadowMaxInFlight = 64
+	defaultShadowTimeout     = 10 * time.Second
+
+	ShadowOutcomeMatch    = "match"
+	ShadowOutcomeMismatch = "mismatch"
+	ShadowOutcomeError    = "error"
+)
+
+// defaultShadowMethods are the read-only methods mirrored to shadow backends.
+// Writes such as eth_sendRawTransaction are never mirrored, so that shadows
+// don't broadcast transactions a second time.
+var defaultShadowMethods = []string{
+	"eth_blockNumber",
+	"eth_call",
+	"eth_chainId",
+	"eth_estimateGas",
+	"eth_feeHistory",
+	"eth_gasPrice",
+	"eth_getBalance",
+	"eth_getBlockByHash",
+	"eth_getBlockByNumber",
+	"eth_getBlockTransactionCountByHash",
+	"eth_getBlockTransactionCountByNumber",
+	"eth_getCode",
+	"eth_getLogs",
+	"eth_getStorageAt",
+	"eth_getTransactionByBlockHashAndIndex",
+	"eth_getTransactionByBlockNumberAndIndex",
+	"eth_getTransactionByHash",
+	"eth_getTransactionCount",
+	"eth_getTransactionReceipt",
+	"eth_maxPriorityFeePerGas",
+	"net_version",
+}
+
+// ShadowMirror sends a sample of the requests served by a backend group to
+// shadow backends, such as a node running a new build, and diffs their
+// responses against the primary ones. Mirroring happens after the client
+// has been answered and in the background: requests are dropped instead of
+// queued when too many are in flight, and shadow responses are discarded.
+// Only read-only methods are mirrored.
+type ShadowMirror struct {
+	backendGroup string
+	backends     []*Backend
+	// clients are the copies of the shadow backends the requests are
+	// mirrored through, so that mirrored traffic has its own connections
+	// and request slots instead of those of client requests.
+	clients    map[*Backend]*Backend
+	methods    *StringSet
+	sampleRate float64
+	timeout    time.Duration
+	inFlight   *semaphore.Weighted
+}
+
+type ShadowOpt func(s *ShadowMirror)
+
+func WithShadowSampleRate(rate float64) ShadowOpt {
+	return func(s *ShadowMirror) {
+		s.sampleRate = rate
+	}
+}
+
+// WithShadowMethods replaces the default allowlist of mirrored methods.
+func WithShadowMethods(methods []string) ShadowOpt {
+	return func(s *ShadowMirror) {
+		s.methods = NewStringSetFromStrings(methods)
+	}
+}
+
+func WithShadowMaxInFlight(max int) ShadowOpt {
+	return func(s *ShadowMirror) {
+		s.inFlight = semaphore.NewWeighted(int64(max))
+	}
+}
+
+// withShadowClients mirrors the requests to each shadow backend through its
+// copy in clients.
+func withShadowClients(clients map[*Backend]*Backend) ShadowOpt {
+	return func(s *ShadowMirror) {
+		s.clients = clients
+	}
+}
+
+// newShadowClient builds a copy of a shadow backend with its own HTTP
+// transport and request semaphore, so that mirrored requests never wait on
+// the connections or the request slots of client requests.
+func newShadowClient(name string, cfg *BackendConfig, backendOpts BackendOptions, lim BackendRateLimiter, maxInFlight int) (*Backend, error) {
+	be, err := newBackendFromConfig(name, cfg, backendOpts, lim, semaphore.NewWeighted(int64(maxInFlight)))
+	if err != nil {
+		return nil, err
+	}
+	if be.client.Transport == nil {
+		be.client.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	return be, nil
+}
+
+// client returns the backend the requests to a shadow backend are sent
+// through.
+func (s *ShadowMirror) client(be *Backend) *Backend {
+	if client := s.clients[be]; client != nil {
+		return client
+	}
+	return be
+}
+
+func NewShadowMirror(backendGroup string, backends []*Backend, opts ...ShadowOpt) *ShadowMirror {
+	s := &ShadowMirror{
+		backendGroup: backendGroup,
+		backends:     backends,
+		methods:      NewStringSetFromStrings(defaultShadowMethods),
+		sampleRate:   1,
+		timeout:      defaultShadowTimeout,
+		inFlight:     semaphore.NewWeighted(defaultShadowMaxInFlight),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Mirror samples a forwarded batch and replays its allowed requests against
+// every shadow backend in the background. The requests must be the ones sent
+// to the primary backend, after any rewrite. It never blocks.
+func (s *ShadowMirror) Mirror(ctx context.Context, reqs []*RPCReq, primary []*RPCRes, isBatch bool) {
+	reqs, primary = s.allowed(reqs, primary)
+	if len(reqs) == 0 || rand.Float64() >= s.sampleRate {
+		return
+	}
+	for _, be := range s.backends {
+		if be.Draining() {
+			continue
+		}
+		if !s.inFlight.TryAcquire(1) {
+			RecordShadowDropped(s.backendGroup, be)
+			continue
+		}
+		go func(be *Backend) {
+			defer s.inFlight.Release(1)
+			defer func() {
+				if err := recover(); err != nil {
+					log.Error("recovered from panic while mirroring requests", "backend", be.Name, "err", err)
+				}
+			}()
+			shadowCtx, cancel := context.WithTimeout(detachContext(ctx), s.timeout)
+			defer cancel()
+			s.compare(shadowCtx, s.client(be), reqs, primary, isBatch)
+		}(be)
+	}
+}
+
+// allowed returns the requests whose method is mirrored, along with their
+// primary responses.
+func (s *ShadowMirror) allowed(reqs []*RPCReq, primary []*RPCRes) ([]*RPCReq, []*RPCRes) {
+	if len(reqs) != len(primary) {
+		return nil, nil
+	}
+	allowedReqs := make([]*RPCReq, 0, len(reqs))
+	allowedRes := make([]*RPCRes, 0, len(reqs))
+	for i, req := range reqs {
+		if s.methods.Has(req.Method) {
+			allowedReqs = append(allowedReqs, req)
+			allowedRes = append(allowedRes, primary[i])
+		}
+	}
+	return allowedReqs, allowedRes
+}
+
+func (s *ShadowMirror) compare(ctx context.Context, be *Backend, reqs []*RPCReq, primary []*RPCRes, isBatch bool) {
+	res, err := be.Forward(ctx, reqs, isBatch)
+	if err != nil || len(res) != len(reqs) {
+		log.Debug(
+			"error forwarding to shadow backend",
+			"backend_group", s.backendGroup,
+			"name", be.Name,
+			"req_id", GetReqID(ctx),
+			"err", err,
+		)
+		for _, req := range reqs {
+			RecordShadowRequest(s.backendGroup, be, req.Method, ShadowOutcomeError)
+		}
+		return
+	}
+
+	for i, req := range reqs {
+		if shadowResponsesMatch(primary[i], res[i]) {
+			RecordShadowRequest(s.backendGroup, be, req.Method, ShadowOutcomeMatch)
+			continue
+		}
+		log.Warn(
+			"shadow backend response mismatch",
+			"backend_group", s.backendGroup,
+			"name", be.Name,
+			"req_id", GetReqID(ctx),
+			"method", req.Method,
+			"params", truncate(string(req.Params), 0),
+			"primary", truncate(string(mustMarshalJSON(primary[i])), 0),
+			"shadow", truncate(string(mustMarshalJSON(res[i])), 0),
+		)
+		RecordShadowRequest(s.backendGroup, be, req.Method, ShadowOutcomeMismatch)
+	}
+}
+
+// shadowResponsesMatch compares results, or error codes if both responses
+// are errors. Error messages are free form and differ between builds.
+func shadowResponsesMatch(primary, shadow *RPCRes) bool {
+	if primary.IsError() || shadow.IsError() {
+		return primary.IsError() && shadow.IsError() && primary.Error.Code == shadow.Error.Code
+	}
+	return reflect.DeepEqual(primary.Result, shadow.Result)
+}
@@ -0,0 +1,102 @@
+package proxyd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestShadowResponsesMatch(t *testing.T) {
+	result := func(v interface{}) *RPCRes {
+		return &RPCRes{JSONRPC: JSONRPCVersion, Result: v, ID: []byte("1")}
+	}
+	rpcErr := func(code int, msg string) *RPCRes {
+		return &RPCRes{JSONRPC: JSONRPCVersion, Error: &RPCErr{Code: code, Message: msg}, ID: []byte("1")}
+	}
+
+	tests := []struct {
+		name    string
+		primary *RPCRes
+		shadow  *RPCRes
+		match   bool
+	}{
+		{"equal results", result("0x1"), result("0x1"), true},
+		{"different results", result("0x1"), result("0x2"), false},
+		{"equal objects", result(map[string]interface{}{"a": []interface{}{"b"}}), result(map[string]interface{}{"a": []interface{}{"b"}}), true},
+		{"different objects", result(map[string]interface{}{"a": "b"}), result(map[string]interface{}{"a": "c"}), false},
+		{"null results", result(nil), result(nil), true},
+		{"same error code", rpcErr(-32000, "header not found"), rpcErr(-32000, "unknown block"), true},
+		{"different error code", rpcErr(-32000, "execution reverted"), rpcErr(-32602, "invalid argument"), false},
+		{"shadow error", result("0x1"), rpcErr(-32000, "execution reverted"), false},
+		{"primary error", rpcErr(-32000, "execution reverted"), result("0x1"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.match, shadowResponsesMatch(tt.primary, tt.shadow))
+		})
+	}
+}
+
+func TestShadowMirrorAllowed(t *testing.T) {
+	req := func(method string) *RPCReq {
+		return &RPCReq{JSONRPC: JSONRPCVersion, Method: method, ID: []byte("1")}
+	}
+	res := func(v interface{}) *RPCRes {
+		return &RPCRes{JSONRPC: JSONRPCVersion, Result: v, ID: []byte("1")}
+	}
+
+	s := NewShadowMirror("main", nil)
+	reqs, primary := s.allowed(
+		[]*RPCReq{req("eth_call"), req("eth_sendRawTransaction"), req("eth_getBalance")},
+		[]*RPCRes{res("0x1"), res("0x2"), res("0x3")},
+	)
+	require.Equal(t, []*RPCReq{req("eth_call"), req("eth_getBalance")}, reqs)
+	require.Equal(t, []*RPCRes{res("0x1"), res("0x3")}, primary)
+
+	s = NewShadowMirror("main", nil, WithShadowMethods([]string{"eth_sendRawTransaction"}))
+	reqs, _ = s.allowed([]*RPCReq{req("eth_call"), req("eth_sendRawTransaction")}, []*RPCRes{res("0x1"), res("0x2")})
+	require.Equal(t, []*RPCReq{req("eth_sendRawTransaction")}, reqs)
+}
+
+// TestShadowMirrorOwnClient asserts that mirrored requests don't take the
+// request slots of client requests.
+func TestShadowMirrorOwnClient(t *testing.T) {
+	mirrored := make(chan struct{}, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrored <- struct{}{}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"0x1","id":1}`))
+	}))
+	defer shadow.Close()
+
+	config := testRuntimeConfig()
+	config.Backends["b"].RPCURL = shadow.URL
+	config.BackendGroups["first"].ShadowBackends = []string{"b"}
+	sem := semaphore.NewWeighted(1)
+	rc, err := buildRuntimeConfig(config, NewLocalBackendRateLimiter(), sem, nil)
+	require.NoError(t, err)
+
+	mirror := rc.backendGroups["first"].Shadow
+	client := mirror.client(rc.backends["b"])
+	require.NotSame(t, rc.backends["b"], client)
+	require.NotSame(t, rc.backends["b"].client.sem, client.client.sem)
+	require.NotNil(t, client.client.Transport)
+
+	// client requests hold every slot
+	require.True(t, sem.TryAcquire(1))
+	defer sem.Release(1)
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_chainId", ID: []byte("1")}
+	res := &RPCRes{JSONRPC: JSONRPCVersion, Result: "0x1", ID: []byte("1")}
+	mirror.Mirror(context.Background(), []*RPCReq{req}, []*RPCRes{res}, false)
+
+	select {
+	case <-mirrored:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+}
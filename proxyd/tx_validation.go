@@ -0,0 +1,188 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sethvargo/go-limiter"
+	"github.com/sethvargo/go-limiter/memorystore"
+)
+
+const (
+	TxRejectInvalidTx         = "invalid_tx"
+	TxRejectWrongChainID      = "wrong_chain_id"
+	TxRejectCalldataTooLarge  = "calldata_too_large"
+	TxRejectUnderpriced       = "underpriced"
+	TxRejectInvalidSender     = "invalid_sender"
+	TxRejectSenderRateLimited = "sender_rate_limited"
+	TxRejectDuplicate         = "duplicate"
+
+	// number of tx hashes remembered for deduplication
+	txDedupeCacheSize = 65536
+)
+
+// TxGate validates eth_sendRawTransaction calls before they are forwarded
+// so that obviously invalid or spammy transactions never reach a backend.
+type TxGate struct {
+	chainID          *big.Int
+	signer           types.Signer
+	maxCalldataBytes int
+	minGasPrice      *big.Int
+	senderLim        limiter.Store
+	dedupeWindow     time.Duration
+	dedupe           *lru.Cache
+	dedupeMtx        sync.Mutex
+}
+
+func NewTxGate(cfg TxValidationConfig) (*TxGate, error) {
+	gate := &TxGate{
+		maxCalldataBytes: cfg.MaxCalldataBytes,
+		dedupeWindow:     secondsToDuration(cfg.DedupeWindowSeconds),
+	}
+
+	if cfg.ChainID != 0 {
+		gate.chainID = new(big.Int).SetUint64(cfg.ChainID)
+		gate.signer = types.LatestSignerForChainID(gate.chainID)
+	}
+	if cfg.MinGasPriceWei != 0 {
+		gate.minGasPrice = new(big.Int).SetUint64(cfg.MinGasPriceWei)
+	}
+
+	if cfg.SenderRatePerInterval > 0 {
+		interval := secondsToDuration(cfg.SenderRateIntervalSeconds)
+		if interval == 0 {
+			interval = time.Second
+		}
+		senderLim, err := memorystore.New(&memorystore.Config{
+			Tokens:   uint64(cfg.SenderRatePerInterval),
+			Interval: interval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		gate.senderLim = senderLim
+	}
+
+	if gate.dedupeWindow > 0 {
+		dedupe, err := lru.New(txDedupeCacheSize)
+		if err != nil {
+			return nil, err
+		}
+		gate.dedupe = dedupe
+	}
+
+	return gate, nil
+}
+
+// Validate checks a single eth_sendRawTransaction request.
+func (g *TxGate) Validate(ctx context.Context, req *RPCReq) error {
+	tx, err := decodeRawTransaction(req)
+	if err != nil {
+		return g.reject(ctx, TxRejectInvalidTx, ErrInvalidParams(err.Error()))
+	}
+
+	if g.chainID != nil && tx.ChainId().Cmp(g.chainID) != 0 {
+		return g.reject(ctx, TxRejectWrongChainID, ErrInvalidParams("invalid chain id"))
+	}
+
+	if g.maxCalldataBytes > 0 && len(tx.Data()) > g.maxCalldataBytes {
+		return g.reject(ctx, TxRejectCalldataTooLarge, ErrInvalidParams("oversized data"))
+	}
+
+	if g.minGasPrice != nil && tx.GasFeeCap().Cmp(g.minGasPrice) < 0 {
+		return g.reject(ctx, TxRejectUnderpriced, ErrInvalidParams("transaction underpriced"))
+	}
+
+	if g.isDuplicate(tx.Hash()) {
+		return g.reject(ctx, TxRejectDuplicate, ErrTransactionAlreadyKnown)
+	}
+
+	if g.senderLim != nil {
+		sender, err := g.sender(tx)
+		if err != nil {
+			return g.reject(ctx, TxRejectInvalidSender, ErrInvalidParams("invalid sender"))
+		}
+		_, _, _, ok, _ := g.senderLim.Take(ctx, sender.Hex())
+		if !ok {
+			return g.reject(ctx, TxRejectSenderRateLimited, ErrOverSenderRateLimit)
+		}
+	}
+
+	return nil
+}
+
+// Remember records a transaction that was accepted by a backend so that
+// resubmissions within the dedupe window are answered by proxyd.
+func (g *TxGate) Remember(req *RPCReq) {
+	if g.dedupe == nil {
+		return
+	}
+	tx, err := decodeRawTransaction(req)
+	if err != nil {
+		return
+	}
+	g.dedupe.Add(tx.Hash(), time.Now().Add(g.dedupeWindow))
+}
+
+func (g *TxGate) isDuplicate(hash common.Hash) bool {
+	if g.dedupe == nil {
+		return false
+	}
+	g.dedupeMtx.Lock()
+	defer g.dedupeMtx.Unlock()
+	expiry, ok := g.dedupe.Get(hash)
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry.(time.Time)) {
+		g.dedupe.Remove(hash)
+		return false
+	}
+	return true
+}
+
+func (g *TxGate) sender(tx *types.Transaction) (common.Address, error) {
+	signer := g.signer
+	if signer == nil {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	return types.Sender(signer, tx)
+}
+
+func (g *TxGate) reject(ctx context.Context, reason string, err *RPCErr) error {
+	log.Info(
+		"rejected raw transaction",
+		"reason", reason,
+		"req_id", GetReqID(ctx),
+		"auth", GetAuthCtx(ctx),
+	)
+	RecordTxRejection(ctx, reason)
+	return err
+}
+
+func decodeRawTransaction(req *RPCReq) (*types.Transaction, error) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, errInvalidRPCParams
+	}
+	if len(params) != 1 {
+		return nil, errInvalidRPCParams
+	}
+	data, err := hexutil.Decode(params[0])
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,135 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const testChainID = 5000
+
+func signedRawTxReq(t *testing.T, key *ecdsa.PrivateKey, chainID int64, nonce uint64, gasPrice int64, data []byte) *RPCReq {
+	to := common.HexToAddress("0x1234")
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: big.NewInt(gasPrice),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1),
+		Data:     data,
+	})
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(chainID)), key)
+	require.NoError(t, err)
+	raw, err := signed.MarshalBinary()
+	require.NoError(t, err)
+	return &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_sendRawTransaction",
+		Params:  mustMarshalJSON([]string{hexutil.Encode(raw)}),
+		ID:      []byte("1"),
+	}
+}
+
+func TestTxGateValidate(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	gate, err := NewTxGate(TxValidationConfig{
+		Enabled:          true,
+		ChainID:          testChainID,
+		MaxCalldataBytes: 32,
+		MinGasPriceWei:   1000,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		req  *RPCReq
+		err  string
+	}{
+		{"valid", signedRawTxReq(t, key, testChainID, 0, 1000, nil), ""},
+		{"wrong chain id", signedRawTxReq(t, key, 1, 0, 1000, nil), "invalid chain id"},
+		{"oversized calldata", signedRawTxReq(t, key, testChainID, 0, 1000, make([]byte, 33)), "oversized data"},
+		{"underpriced", signedRawTxReq(t, key, testChainID, 0, 999, nil), "transaction underpriced"},
+		{
+			"not a transaction",
+			&RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_sendRawTransaction", Params: []byte(`["0x1234"]`), ID: []byte("1")},
+			"transaction type not supported",
+		},
+		{
+			"bad params",
+			&RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_sendRawTransaction", Params: []byte(`[1]`), ID: []byte("1")},
+			"invalid RPC params",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gate.Validate(ctx, tt.req)
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Equal(t, -32602, err.(*RPCErr).Code)
+			require.Equal(t, tt.err, err.Error())
+		})
+	}
+}
+
+func TestTxGateSenderRateLimit(t *testing.T) {
+	key1, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	key2, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	gate, err := NewTxGate(TxValidationConfig{
+		Enabled:                   true,
+		ChainID:                   testChainID,
+		SenderRatePerInterval:     2,
+		SenderRateIntervalSeconds: 60,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, gate.Validate(ctx, signedRawTxReq(t, key1, testChainID, 0, 1, nil)))
+	require.NoError(t, gate.Validate(ctx, signedRawTxReq(t, key1, testChainID, 1, 1, nil)))
+	require.Equal(t, ErrOverSenderRateLimit, gate.Validate(ctx, signedRawTxReq(t, key1, testChainID, 2, 1, nil)))
+
+	// the limit is per sender, not global
+	require.NoError(t, gate.Validate(ctx, signedRawTxReq(t, key2, testChainID, 0, 1, nil)))
+}
+
+func TestTxGateDedupe(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	gate, err := NewTxGate(TxValidationConfig{
+		Enabled:             true,
+		DedupeWindowSeconds: 60,
+	})
+	require.NoError(t, err)
+
+	req := signedRawTxReq(t, key, testChainID, 0, 1, nil)
+	require.NoError(t, gate.Validate(ctx, req))
+	// only transactions accepted by a backend are remembered
+	require.NoError(t, gate.Validate(ctx, req))
+
+	gate.Remember(req)
+	require.Equal(t, ErrTransactionAlreadyKnown, gate.Validate(ctx, req))
+	require.NoError(t, gate.Validate(ctx, signedRawTxReq(t, key, testChainID, 1, 1, nil)))
+
+	gate.dedupeWindow = -1
+	other := signedRawTxReq(t, key, testChainID, 2, 1, nil)
+	gate.Remember(other)
+	require.NoError(t, gate.Validate(ctx, other))
+}
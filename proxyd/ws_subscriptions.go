@@ -0,0 +1,888 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	SubscriptionNewHeads = "newHeads"
+	SubscriptionLogs     = "logs"
+
+	defaultMaxGapFillBlocks = 64
+
+	wsClientSendBufferSize   = 256
+	wsUpstreamRequestTimeout = 5 * time.Second
+	wsUpstreamPingInterval   = 30 * time.Second
+	wsUpstreamReadTimeout    = 2 * wsUpstreamPingInterval
+
+	// number of delivered heads remembered to drop duplicates after a failover
+	recentHeadsCacheSize = 256
+)
+
+var (
+	errUpstreamConnLost    = errors.New("upstream subscription connection lost")
+	errUpstreamTimeout     = errors.New("timed out waiting for upstream response")
+	errUnknownSubscription = errors.New("unknown subscription")
+	errClientConnClosed    = errors.New("client connection closed")
+)
+
+// SubscriptionManager terminates eth_subscribe calls in proxyd. Every distinct
+// subscription is made once, on a single upstream connection of the backend
+// group, and fanned out to all clients. When the upstream connection drops,
+// the subscriptions are moved to the next available backend and the heads
+// missed in between are filled in from that backend.
+type SubscriptionManager struct {
+	name             string
+	maxGapFillBlocks uint64
+
+	mtx           sync.Mutex
+	bg            *BackendGroup
+	conn          *websocket.Conn
+	gen           uint64
+	topics        map[string]*subscriptionTopic
+	byUpstreamID  map[string]*subscriptionTopic
+	subscriptions map[string]*subscriptionTopic
+	pending       map[uint64]*pendingRequest
+	nextReqID     uint64
+
+	writeMtx sync.Mutex
+
+	headMtx     sync.Mutex
+	lastHead    uint64
+	haveHead    bool
+	needsFill   bool
+	recentHeads *lru.Cache
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// subscriptionTopic is a single upstream subscription shared by all clients
+// subscribing with the same parameters.
+type subscriptionTopic struct {
+	key         string
+	kind        string
+	params      json.RawMessage
+	gen         uint64
+	upstreamID  string
+	subscribers map[string]*subscriber
+}
+
+// pendingRequest is a call on the upstream connection awaiting its response.
+// onResponse runs on the read loop before any later message is handled.
+type pendingRequest struct {
+	resC       chan *RPCRes
+	onResponse func(res *RPCRes)
+}
+
+type subscriber struct {
+	sink   subscriptionSink
+	active bool
+}
+
+type subscriptionSink interface {
+	deliver(msg []byte)
+}
+
+type SubscriptionManagerOpt func(m *SubscriptionManager)
+
+func WithMaxGapFillBlocks(blocks uint64) SubscriptionManagerOpt {
+	return func(m *SubscriptionManager) {
+		m.maxGapFillBlocks = blocks
+	}
+}
+
+func NewSubscriptionManager(bg *BackendGroup, opts ...SubscriptionManagerOpt) *SubscriptionManager {
+	recentHeads, _ := lru.New(recentHeadsCacheSize)
+	m := &SubscriptionManager{
+		name:             bg.Name,
+		bg:               bg,
+		maxGapFillBlocks: defaultMaxGapFillBlocks,
+		topics:           make(map[string]*subscriptionTopic),
+		byUpstreamID:     make(map[string]*subscriptionTopic),
+		subscriptions:    make(map[string]*subscriptionTopic),
+		pending:          make(map[uint64]*pendingRequest),
+		recentHeads:      recentHeads,
+		quit:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *SubscriptionManager) Start() {
+	go m.run()
+}
+
+func (m *SubscriptionManager) Stop() {
+	close(m.quit)
+	<-m.done
+}
+
+// Subscribe registers a client subscription and returns its id. The
+// subscription only receives notifications once it has been activated, so
+// that the client sees the subscription id before the first notification.
+func (m *SubscriptionManager) Subscribe(params json.RawMessage, sink subscriptionSink) (string, error) {
+	kind, key, err := subscriptionKey(params)
+	if err != nil {
+		return "", err
+	}
+	id := newSubscriptionID()
+
+	m.mtx.Lock()
+	topic := m.topics[key]
+	if topic == nil {
+		topic = &subscriptionTopic{
+			key:         key,
+			kind:        kind,
+			params:      params,
+			subscribers: make(map[string]*subscriber),
+		}
+		m.topics[key] = topic
+	}
+	topic.subscribers[id] = &subscriber{sink: sink}
+	m.subscriptions[id] = topic
+	gen, connected := m.gen, m.conn != nil
+	m.recordSubscriptionCounts()
+	m.mtx.Unlock()
+
+	if !connected {
+		// subscribed once an upstream connection is available
+		return id, nil
+	}
+	if err := m.subscribeUpstream(topic, gen); err != nil {
+		if rpcErr, ok := err.(*RPCErr); ok {
+			m.Unsubscribe(id)
+			return "", rpcErr
+		}
+		log.Warn(
+			"error subscribing upstream, retrying on reconnect",
+			"backend_group", m.name,
+			"kind", kind,
+			"err", err,
+		)
+	}
+	return id, nil
+}
+
+// Activate starts delivering notifications to a subscription.
+func (m *SubscriptionManager) Activate(id string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if topic := m.subscriptions[id]; topic != nil {
+		topic.subscribers[id].active = true
+	}
+}
+
+// Unsubscribe removes a client subscription. The upstream subscription is
+// cancelled once its last subscriber is gone.
+func (m *SubscriptionManager) Unsubscribe(id string) bool {
+	m.mtx.Lock()
+	topic := m.subscriptions[id]
+	if topic == nil {
+		m.mtx.Unlock()
+		return false
+	}
+	delete(m.subscriptions, id)
+	delete(topic.subscribers, id)
+
+	var upstreamID string
+	gen := m.gen
+	if len(topic.subscribers) == 0 {
+		delete(m.topics, topic.key)
+		if topic.upstreamID != "" {
+			delete(m.byUpstreamID, topic.upstreamID)
+			upstreamID = topic.upstreamID
+		}
+	}
+	m.recordSubscriptionCounts()
+	m.mtx.Unlock()
+
+	if upstreamID != "" {
+		go m.unsubscribeUpstream(gen, upstreamID)
+	}
+	return true
+}
+
+func (m *SubscriptionManager) recordSubscriptionCounts() {
+	RecordWSSubscriptions(m.name, len(m.topics), len(m.subscriptions))
+}
+
+func (m *SubscriptionManager) run() {
+	defer close(m.done)
+
+	var attempts int
+	for {
+		select {
+		case <-m.quit:
+			return
+		default:
+		}
+
+		conn, be, err := m.dial()
+		if err != nil {
+			attempts++
+			log.Warn(
+				"error connecting subscription upstream",
+				"backend_group", m.name,
+				"err", err,
+			)
+			select {
+			case <-m.quit:
+				return
+			case <-time.After(calcBackoff(attempts)):
+			}
+			continue
+		}
+		attempts = 0
+
+		if !m.serve(conn, be) {
+			return
+		}
+		RecordWSSubscriptionFailover(m.name)
+	}
+}
+
+// SetBackendGroup replaces the backends subscriptions are made on after a
+// config reload. The current upstream connection is kept until it is lost.
+func (m *SubscriptionManager) SetBackendGroup(bg *BackendGroup) {
+	m.mtx.Lock()
+	m.bg = bg
+	m.mtx.Unlock()
+}
+
+func (m *SubscriptionManager) dial() (*websocket.Conn, *Backend, error) {
+	m.mtx.Lock()
+	bg := m.bg
+	m.mtx.Unlock()
+
+	backends := bg.Backends
+	if bg.Selector != nil {
+		backends = bg.Selector.Order(backends)
+	}
+	for _, be := range backends {
+		conn, err := be.DialWS()
+		if err != nil {
+			log.Warn(
+				"skipping ws backend for subscriptions",
+				"name", be.Name,
+				"backend_group", m.name,
+				"err", err,
+			)
+			continue
+		}
+		return conn, be, nil
+	}
+	return nil, nil, ErrNoBackends
+}
+
+// serve moves all subscriptions to the connection and blocks until the
+// connection is lost. It returns false if the manager is stopping.
+func (m *SubscriptionManager) serve(conn *websocket.Conn, be *Backend) bool {
+	m.mtx.Lock()
+	m.gen++
+	gen := m.gen
+	m.conn = conn
+	m.byUpstreamID = make(map[string]*subscriptionTopic)
+	topics := make([]*subscriptionTopic, 0, len(m.topics))
+	for _, topic := range m.topics {
+		topic.upstreamID = ""
+		topics = append(topics, topic)
+	}
+	m.mtx.Unlock()
+
+	m.headMtx.Lock()
+	m.needsFill = m.haveHead
+	m.headMtx.Unlock()
+
+	log.Info(
+		"connected subscription upstream",
+		"name", be.Name,
+		"backend_group", m.name,
+		"subscriptions", len(topics),
+	)
+
+	_ = conn.SetReadDeadline(time.Now().Add(wsUpstreamReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsUpstreamReadTimeout))
+	})
+	errC := make(chan error, 1)
+	go m.readLoop(conn, be, errC)
+
+	go func() {
+		for _, topic := range topics {
+			if err := m.subscribeUpstream(topic, gen); err != nil {
+				log.Warn(
+					"error resubscribing upstream",
+					"name", be.Name,
+					"backend_group", m.name,
+					"kind", topic.kind,
+					"err", err,
+				)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsUpstreamPingInterval)
+	defer ticker.Stop()
+
+	var err error
+	quitting := false
+loop:
+	for {
+		select {
+		case err = <-errC:
+			break loop
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsUpstreamRequestTimeout)); err != nil {
+				log.Warn("error pinging subscription upstream", "name", be.Name, "err", err)
+			}
+		case <-m.quit:
+			quitting = true
+			conn.Close()
+			<-errC
+			break loop
+		}
+	}
+
+	m.mtx.Lock()
+	m.conn = nil
+	for id, req := range m.pending {
+		close(req.resC)
+		delete(m.pending, id)
+	}
+	m.mtx.Unlock()
+
+	conn.Close()
+	be.releaseWSConn()
+	if !quitting {
+		log.Warn(
+			"lost subscription upstream",
+			"name", be.Name,
+			"backend_group", m.name,
+			"err", err,
+		)
+	}
+	return !quitting
+}
+
+func (m *SubscriptionManager) readLoop(conn *websocket.Conn, be *Backend, errC chan error) {
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			errC <- err
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(wsUpstreamReadTimeout))
+
+		var parsed struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &parsed); err != nil {
+			log.Warn("error parsing upstream subscription message", "name", be.Name, "err", err)
+			continue
+		}
+
+		if parsed.Method == "eth_subscription" {
+			m.dispatch(be, parsed.Params)
+			continue
+		}
+
+		id, err := strconv.ParseUint(string(parsed.ID), 10, 64)
+		if err != nil {
+			log.Warn("unexpected upstream subscription message", "name", be.Name, "id", string(parsed.ID))
+			continue
+		}
+		res, err := ParseRPCRes(bytes.NewReader(msg))
+		if err != nil {
+			log.Warn("error parsing upstream subscription response", "name", be.Name, "err", err)
+			continue
+		}
+		m.mtx.Lock()
+		req := m.pending[id]
+		m.mtx.Unlock()
+		if req != nil {
+			if req.onResponse != nil {
+				req.onResponse(res)
+			}
+			req.resC <- res
+		}
+	}
+}
+
+func (m *SubscriptionManager) request(gen uint64, method string, params json.RawMessage, onResponse func(*RPCRes)) (*RPCRes, error) {
+	m.mtx.Lock()
+	if m.conn == nil || m.gen != gen {
+		m.mtx.Unlock()
+		return nil, errUpstreamConnLost
+	}
+	conn := m.conn
+	m.nextReqID++
+	id := m.nextReqID
+	resC := make(chan *RPCRes, 1)
+	m.pending[id] = &pendingRequest{resC: resC, onResponse: onResponse}
+	m.mtx.Unlock()
+
+	defer func() {
+		m.mtx.Lock()
+		delete(m.pending, id)
+		m.mtx.Unlock()
+	}()
+
+	req := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+		ID:      json.RawMessage(strconv.FormatUint(id, 10)),
+	}
+	m.writeMtx.Lock()
+	err := conn.WriteJSON(req)
+	m.writeMtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res, ok := <-resC:
+		if !ok {
+			return nil, errUpstreamConnLost
+		}
+		return res, nil
+	case <-time.After(wsUpstreamRequestTimeout):
+		// an upstream that stops answering is treated as dead
+		conn.Close()
+		return nil, errUpstreamTimeout
+	}
+}
+
+func (m *SubscriptionManager) subscribeUpstream(topic *subscriptionTopic, gen uint64) error {
+	m.mtx.Lock()
+	if topic.gen == gen {
+		// already subscribed, or being subscribed, on this connection
+		m.mtx.Unlock()
+		return nil
+	}
+	topic.gen = gen
+	m.mtx.Unlock()
+
+	// the subscription is registered on the read loop, so that notifications
+	// following the response right away are not dropped
+	res, err := m.request(gen, "eth_subscribe", topic.params, func(res *RPCRes) {
+		upstreamID, ok := res.Result.(string)
+		if res.IsError() || !ok {
+			return
+		}
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+		if m.gen != gen {
+			return
+		}
+		if m.topics[topic.key] != topic {
+			// every subscriber left while subscribing
+			go m.unsubscribeUpstream(gen, upstreamID)
+			return
+		}
+		topic.upstreamID = upstreamID
+		m.byUpstreamID[upstreamID] = topic
+	})
+	if err == nil && res.IsError() {
+		err = res.Error
+	}
+	if err == nil {
+		if _, ok := res.Result.(string); !ok {
+			err = ErrBackendBadResponse
+		}
+	}
+	if err != nil {
+		m.mtx.Lock()
+		if topic.gen == gen {
+			topic.gen = 0
+		}
+		m.mtx.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *SubscriptionManager) unsubscribeUpstream(gen uint64, upstreamID string) {
+	res, err := m.request(gen, "eth_unsubscribe", mustMarshalJSON([]string{upstreamID}), nil)
+	if err == nil && res.IsError() {
+		err = res.Error
+	}
+	if err != nil && err != errUpstreamConnLost {
+		log.Warn("error unsubscribing upstream", "backend_group", m.name, "err", err)
+	}
+}
+
+type subscriptionNotification struct {
+	JSONRPC string                   `json:"jsonrpc"`
+	Method  string                   `json:"method"`
+	Params  subscriptionNotifyParams `json:"params"`
+}
+
+type subscriptionNotifyParams struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+func (m *SubscriptionManager) dispatch(be *Backend, params json.RawMessage) {
+	var notification subscriptionNotifyParams
+	if err := json.Unmarshal(params, &notification); err != nil {
+		log.Warn("error parsing upstream notification", "name", be.Name, "err", err)
+		return
+	}
+
+	m.mtx.Lock()
+	topic := m.byUpstreamID[notification.Subscription]
+	if topic == nil {
+		m.mtx.Unlock()
+		return
+	}
+	kind := topic.kind
+	sinks := make(map[string]subscriptionSink, len(topic.subscribers))
+	for id, sub := range topic.subscribers {
+		if sub.active {
+			sinks[id] = sub.sink
+		}
+	}
+	m.mtx.Unlock()
+
+	results := []json.RawMessage{notification.Result}
+	if kind == SubscriptionNewHeads {
+		results = m.processHead(be, notification.Result)
+	}
+	RecordWSSubscriptionNotifications(m.name, kind, len(results))
+
+	for _, result := range results {
+		for id, sink := range sinks {
+			sink.deliver(mustMarshalJSON(&subscriptionNotification{
+				JSONRPC: JSONRPCVersion,
+				Method:  "eth_subscription",
+				Params: subscriptionNotifyParams{
+					Subscription: id,
+					Result:       result,
+				},
+			}))
+		}
+	}
+}
+
+// processHead drops heads that were already delivered through a previous
+// upstream and, right after a failover, prepends the heads that were missed.
+func (m *SubscriptionManager) processHead(be *Backend, result json.RawMessage) []json.RawMessage {
+	var head struct {
+		Number hexutil.Uint64 `json:"number"`
+		Hash   common.Hash    `json:"hash"`
+	}
+	if err := json.Unmarshal(result, &head); err != nil {
+		log.Warn("error parsing upstream head", "name", be.Name, "err", err)
+		return []json.RawMessage{result}
+	}
+
+	m.headMtx.Lock()
+	defer m.headMtx.Unlock()
+	if m.recentHeads.Contains(head.Hash) {
+		return nil
+	}
+
+	var out []json.RawMessage
+	if m.needsFill {
+		m.needsFill = false
+		out = m.fillHeads(be, m.lastHead+1, uint64(head.Number))
+	}
+	out = append(out, result)
+	m.recentHeads.Add(head.Hash, nil)
+	m.lastHead = uint64(head.Number)
+	m.haveHead = true
+	return out
+}
+
+// fillHeads fetches the headers of the blocks in [from, to) from the backend.
+func (m *SubscriptionManager) fillHeads(be *Backend, from, to uint64) []json.RawMessage {
+	if from >= to {
+		return nil
+	}
+	if to-from > m.maxGapFillBlocks {
+		from = to - m.maxGapFillBlocks
+	}
+
+	reqs := make([]*RPCReq, 0, to-from)
+	for number := from; number < to; number++ {
+		reqs = append(reqs, &RPCReq{
+			JSONRPC: JSONRPCVersion,
+			Method:  "eth_getBlockByNumber",
+			Params:  mustMarshalJSON([]interface{}{hexutil.Uint64(number), false}),
+			ID:      json.RawMessage(strconv.FormatUint(number, 10)),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wsUpstreamRequestTimeout)
+	defer cancel()
+	res, err := be.Forward(ctx, reqs, true)
+	if err != nil {
+		log.Warn("error filling missed heads", "name", be.Name, "from", from, "to", to, "err", err)
+		return nil
+	}
+
+	out := make([]json.RawMessage, 0, len(res))
+	for _, r := range res {
+		header, ok := r.Result.(map[string]interface{})
+		if r.IsError() || !ok {
+			log.Warn("missing block while filling missed heads", "name", be.Name, "id", string(r.ID))
+			continue
+		}
+		hashStr, _ := header["hash"].(string)
+		hash := common.HexToHash(hashStr)
+		if m.recentHeads.Contains(hash) {
+			continue
+		}
+		// turn the block into what a newHeads notification carries
+		for _, field := range []string{"transactions", "uncles", "size", "totalDifficulty"} {
+			delete(header, field)
+		}
+		out = append(out, mustMarshalJSON(header))
+		m.recentHeads.Add(hash, nil)
+	}
+	RecordWSGapFill(m.name, len(out))
+	return out
+}
+
+// subscriptionKey identifies subscriptions that can share an upstream
+// subscription. Only newHeads and logs subscriptions are multiplexed.
+func subscriptionKey(params json.RawMessage) (string, string, error) {
+	var p []json.RawMessage
+	if err := json.Unmarshal(params, &p); err != nil || len(p) == 0 {
+		return "", "", ErrInvalidParams(errInvalidRPCParams.Error())
+	}
+	var kind string
+	if err := json.Unmarshal(p[0], &kind); err != nil {
+		return "", "", ErrInvalidParams(errInvalidRPCParams.Error())
+	}
+
+	switch kind {
+	case SubscriptionNewHeads:
+		if len(p) != 1 {
+			return "", "", ErrInvalidParams(errInvalidRPCParams.Error())
+		}
+		return kind, kind, nil
+	case SubscriptionLogs:
+		if len(p) > 2 {
+			return "", "", ErrInvalidParams(errInvalidRPCParams.Error())
+		}
+		if len(p) == 1 {
+			return kind, kind, nil
+		}
+		// re-marshal the filter so that equal filters share a key
+		var filter interface{}
+		if err := json.Unmarshal(p[1], &filter); err != nil {
+			return "", "", ErrInvalidParams(errInvalidRPCParams.Error())
+		}
+		return kind, kind + ":" + string(mustMarshalJSON(filter)), nil
+	default:
+		return "", "", ErrInvalidParams("unsupported subscription type " + kind)
+	}
+}
+
+func newSubscriptionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hexutil.Encode(b)
+}
+
+// MultiplexedWSProxier serves a client websocket connection without a
+// dedicated backend connection. Subscriptions go through the subscription
+// manager and every other call is forwarded over HTTP.
+type MultiplexedWSProxier struct {
+	bg              *BackendGroup
+	subs            *SubscriptionManager
+	clientConn      *websocket.Conn
+	methodWhitelist *StringSet
+	subscriptionIDs map[string]bool
+	sendC           chan []byte
+	closed          chan struct{}
+	closeOnce       sync.Once
+}
+
+func NewMultiplexedWSProxier(bg *BackendGroup, subs *SubscriptionManager, clientConn *websocket.Conn, methodWhitelist *StringSet) *MultiplexedWSProxier {
+	return &MultiplexedWSProxier{
+		bg:              bg,
+		subs:            subs,
+		clientConn:      clientConn,
+		methodWhitelist: methodWhitelist,
+		subscriptionIDs: make(map[string]bool),
+		sendC:           make(chan []byte, wsClientSendBufferSize),
+		closed:          make(chan struct{}),
+	}
+}
+
+func (w *MultiplexedWSProxier) Proxy(ctx context.Context) error {
+	go w.writePump()
+	err := w.readPump(ctx)
+	w.close()
+	for id := range w.subscriptionIDs {
+		w.subs.Unsubscribe(id)
+	}
+	return err
+}
+
+func (w *MultiplexedWSProxier) readPump(ctx context.Context) error {
+	for {
+		msgType, msg, err := w.clientConn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		RecordWSMessage(ctx, BackendProxyd, SourceClient)
+
+		// Control messages are answered by the websocket library.
+		if msgType != websocket.TextMessage && msgType != websocket.BinaryMessage {
+			continue
+		}
+
+		rpcRequestsTotal.Inc()
+
+		res, activate := w.handleClientMsg(ctx, msg)
+		if !w.send(mustMarshalJSON(res)) {
+			return errClientConnClosed
+		}
+		if activate != "" {
+			w.subs.Activate(activate)
+		}
+	}
+}
+
+// handleClientMsg returns the response to a client message, and the id of
+// a new subscription that must be activated once the response is queued.
+func (w *MultiplexedWSProxier) handleClientMsg(ctx context.Context, msg []byte) (*RPCRes, string) {
+	req, err := ParseRPCReq(msg)
+	if err != nil {
+		log.Info(
+			"error preparing client message",
+			"auth", GetAuthCtx(ctx),
+			"req_id", GetReqID(ctx),
+			"err", err,
+		)
+		RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+		return NewRPCErrorRes(nil, err), ""
+	}
+
+	// unsubscribing is allowed wherever subscribing is
+	whitelistMethod := req.Method
+	if whitelistMethod == "eth_unsubscribe" {
+		whitelistMethod = "eth_subscribe"
+	}
+	if !w.methodWhitelist.Has(whitelistMethod) {
+		RecordRPCError(ctx, BackendProxyd, req.Method, ErrMethodNotWhitelisted)
+		return NewRPCErrorRes(req.ID, ErrMethodNotWhitelisted), ""
+	}
+
+	switch req.Method {
+	case "eth_accounts":
+		RecordRPCForward(ctx, BackendProxyd, req.Method, RPCRequestSourceWS)
+		return NewRPCRes(req.ID, emptyArrayResponse), ""
+	case "eth_subscribe":
+		id, err := w.subs.Subscribe(req.Params, w)
+		if err != nil {
+			RecordRPCError(ctx, BackendProxyd, req.Method, err)
+			return NewRPCErrorRes(req.ID, err), ""
+		}
+		w.subscriptionIDs[id] = true
+		RecordRPCForward(ctx, BackendProxyd, req.Method, RPCRequestSourceWS)
+		log.Info(
+			"subscribed ws client",
+			"auth", GetAuthCtx(ctx),
+			"req_id", GetReqID(ctx),
+		)
+		return NewRPCRes(req.ID, id), id
+	case "eth_unsubscribe":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+			return NewRPCErrorRes(req.ID, ErrInvalidParams(errInvalidRPCParams.Error())), ""
+		}
+		RecordRPCForward(ctx, BackendProxyd, req.Method, RPCRequestSourceWS)
+		if !w.subscriptionIDs[params[0]] {
+			return NewRPCErrorRes(req.ID, ErrInvalidParams(errUnknownSubscription.Error())), ""
+		}
+		delete(w.subscriptionIDs, params[0])
+		return NewRPCRes(req.ID, w.subs.Unsubscribe(params[0])), ""
+	}
+
+	fwdCtx, cancel := context.WithTimeout(detachContext(ctx), defaultServerTimeout)
+	defer cancel()
+	res, err := w.bg.Forward(fwdCtx, []*RPCReq{req}, false)
+	if err != nil {
+		return NewRPCErrorRes(req.ID, err), ""
+	}
+	return res[0], ""
+}
+
+// detachContext keeps the values of the upgraded HTTP request's context,
+// which is cancelled as soon as the websocket handler returns.
+func detachContext(ctx context.Context) context.Context {
+	out := context.Background()
+	for _, key := range []string{ContextKeyAuth, ContextKeyReqID, ContextKeyXForwardedFor} {
+		out = context.WithValue(out, key, ctx.Value(key)) // nolint:staticcheck
+	}
+	return out
+}
+
+func (w *MultiplexedWSProxier) send(msg []byte) bool {
+	select {
+	case w.sendC <- msg:
+		return true
+	case <-w.closed:
+		return false
+	}
+}
+
+// deliver queues a notification without blocking the upstream connection.
+// Clients that fall too far behind are disconnected.
+func (w *MultiplexedWSProxier) deliver(msg []byte) {
+	select {
+	case w.sendC <- msg:
+	case <-w.closed:
+	default:
+		log.Warn("disconnecting slow ws client", "backend_group", w.bg.Name)
+		w.close()
+	}
+}
+
+func (w *MultiplexedWSProxier) writePump() {
+	for {
+		select {
+		case msg := <-w.sendC:
+			if err := w.clientConn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				w.close()
+				return
+			}
+		case <-w.closed:
+			return
+		}
+	}
+}
+
+func (w *MultiplexedWSProxier) close() {
+	w.closeOnce.Do(func() {
+		close(w.closed)
+		w.clientConn.Close()
+	})
+}
@@ -0,0 +1,39 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		kind   string
+		key    string
+		errMsg string
+	}{
+		{"new heads", `["newHeads"]`, "newHeads", "newHeads", ""},
+		{"logs without filter", `["logs"]`, "logs", "logs", ""},
+		{"logs filter is canonicalized", `["logs",{"topics":[],"address":"0x1"}]`, "logs", `logs:{"address":"0x1","topics":[]}`, ""},
+		{"unsupported kind", `["newPendingTransactions"]`, "", "", "unsupported subscription type newPendingTransactions"},
+		{"extra new heads param", `["newHeads",{}]`, "", "", "invalid RPC params"},
+		{"no params", `[]`, "", "", "invalid RPC params"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, key, err := subscriptionKey(json.RawMessage(tt.params))
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				require.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.kind, kind)
+			require.Equal(t, tt.key, key)
+		})
+	}
+}